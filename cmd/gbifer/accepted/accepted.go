@@ -0,0 +1,233 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package accepted implements a command to label
+// the records of a GBIF occurrence table
+// with their accepted species.
+package accepted
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/config"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/taxonomy"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `accepted --tax <file>
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "label records with accepted species",
+	Long: `
+Command accepted reads a GBIF occurrence table from the standard input and
+adds the accepted species of each record, as defined in a taxonomy file. The
+taxonomy file is required and must be defined with the flag --tax, or as
+the default taxonomy of the configuration (see "gbifer help").
+
+The accepted species is resolved by following the synonyms in the taxonomy,
+and records of infraspecific taxa use the species that contains the taxon.
+The following columns will be added:
+
+	- acceptedSpecies: the name of the accepted species.
+	- acceptedSpeciesKey: the GBIF ID of the accepted species.
+
+The original columns are kept untouched. If the occurrence table already has
+the added columns (for example, from a previous run), its values will be
+replaced. Records of taxa not in the taxonomy, or taxa above species level,
+will have empty values.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+var taxFile string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().StringVar(&taxFile, "tax", config.Taxonomy(), "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if taxFile == "" {
+		return c.UsageError("flag --tax undefined")
+	}
+	tx, err := readTaxonomy()
+	if err != nil {
+		return err
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := label(in, out, tx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func readTaxonomy() (*taxonomy.Taxonomy, error) {
+	f, err := os.Open(taxFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tx, err := taxonomy.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", taxFile, err)
+	}
+	return tx, nil
+}
+
+func label(r io.Reader, w io.Writer, tx *taxonomy.Taxonomy) error {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return fmt.Errorf("when reading %q header: %v", input, err)
+	}
+
+	keyCol := -1
+	taxCol := -1
+	spCol := -1
+	spKeyCol := -1
+	for i, h := range header {
+		h = strings.ToLower(h)
+		if h == "specieskey" {
+			keyCol = i
+		}
+		if h == "taxonkey" {
+			taxCol = i
+		}
+		if h == "acceptedspecies" {
+			spCol = i
+		}
+		if h == "acceptedspecieskey" {
+			spKeyCol = i
+		}
+	}
+	if keyCol < 0 && taxCol < 0 {
+		return fmt.Errorf("input data %q without %q or %q fields", input, "speciesKey", "taxonKey")
+	}
+	if spCol < 0 {
+		spCol = len(header)
+		header = append(header, "acceptedSpecies")
+	}
+	if spKeyCol < 0 {
+		spKeyCol = len(header)
+		header = append(header, "acceptedSpeciesKey")
+	}
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		for len(row) < len(header) {
+			row = append(row, "")
+		}
+		row[spCol] = ""
+		row[spKeyCol] = ""
+
+		if id := rowKey(row, keyCol, taxCol); id != 0 {
+			sp := tx.AcceptedAndRanked(id)
+			if sp.ID != 0 && sp.Rank == taxonomy.Species {
+				row[spCol] = sp.Name
+				row[spKeyCol] = strconv.FormatInt(sp.ID, 10)
+			}
+		}
+
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+// rowKey returns the taxon ID of an occurrence row,
+// or 0 if the row has no valid ID.
+func rowKey(row []string, keyCol, taxCol int) int64 {
+	var key string
+	if keyCol >= 0 {
+		key = row[keyCol]
+		if key == "" {
+			return 0
+		}
+	}
+	if taxCol >= 0 {
+		key = row[taxCol]
+	}
+	id, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
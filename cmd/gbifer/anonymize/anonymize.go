@@ -0,0 +1,390 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package anonymize implements a command to generalize
+// the localities of sensitive taxa
+// in a GBIF occurrence table.
+package anonymize
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/taxonomy"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `anonymize --tax <file> [--grid <value>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "generalize localities of sensitive taxa",
+	Long: `
+Command anonymize reads a GBIF occurrence table from the standard input and
+generalizes the localities of the records of sensitive taxa, so the resulting
+table can be shared without exposing the exact localities of these taxa.
+
+The flag --tax is required, and defines a taxonomy file with the sensitive
+taxa. A record is from a sensitive taxon if either its "taxonKey", its
+"speciesKey", or its "acceptedTaxonKey" is in the taxonomy. A taxon of the
+taxonomy without accepted children (for example, a genus added to the
+taxonomy by itself, and not as the parent of a sensitive species) is
+sensitive with all its descendants, so a record is also from a sensitive
+taxon if any of its "kingdomKey", "phylumKey", "classKey", "orderKey",
+"familyKey", "genusKey", or "subgenusKey" is one of these taxa. If the
+taxonomy has such a taxon above the species rank, the input table must have
+the key column of the rank of the taxon (for example, "genusKey" for a
+genus), otherwise the command ends with an error.
+
+In the records of sensitive taxa, the coordinates are rounded to the center
+of a grid cell. By default the size of the cell is 0.1 degrees; use the flag
+--grid to define a different size (in degrees). The columns "locality",
+"verbatimLocality", "verbatimCoordinates", "verbatimLatitude", and
+"verbatimLongitude" are blanked.
+
+The applied generalization is recorded in the column "dataGeneralizations"
+(it will be added to the table, if it is not already present). If the record
+already has a value in that column, the generalization is appended to it.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+var taxFile string
+var grid float64
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().StringVar(&taxFile, "tax", "", "")
+	c.Flags().Float64Var(&grid, "grid", 0.1, "")
+}
+
+// keyCols are the columns
+// with the keys of the taxon of a record.
+var keyCols = []string{
+	"taxonKey",
+	"speciesKey",
+	"acceptedTaxonKey",
+}
+
+// higherCols are the columns
+// with the keys of the higher taxa of a record.
+var higherCols = []string{
+	"kingdomKey",
+	"phylumKey",
+	"classKey",
+	"orderKey",
+	"familyKey",
+	"genusKey",
+	"subgenusKey",
+}
+
+// rankCols are the key columns
+// of each rank.
+var rankCols = map[taxonomy.Rank]string{
+	taxonomy.Kingdom: "kingdomKey",
+	taxonomy.Phylum:  "phylumKey",
+	taxonomy.Class:   "classKey",
+	taxonomy.Order:   "orderKey",
+	taxonomy.Family:  "familyKey",
+	taxonomy.Genus:   "genusKey",
+}
+
+// blanked are the columns
+// removed from sensitive records.
+var blanked = []string{
+	"locality",
+	"verbatimLocality",
+	"verbatimCoordinates",
+	"verbatimLatitude",
+	"verbatimLongitude",
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if taxFile == "" {
+		return c.UsageError("flag --tax must be defined")
+	}
+	if grid <= 0 || grid > 90 {
+		return c.UsageError(fmt.Sprintf("invalid grid size: %.6f", grid))
+	}
+
+	tx, err := readTaxonomy()
+	if err != nil {
+		return err
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := anonymize(in, out, tx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func readTaxonomy() (*taxonomy.Taxonomy, error) {
+	f, err := os.Open(taxFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tx, err := taxonomy.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", taxFile, err)
+	}
+	return tx, nil
+}
+
+// inclusive returns the taxa of a taxonomy
+// without accepted children,
+// i.e., the taxa that are sensitive
+// with all of their descendants.
+func inclusive(tx *taxonomy.Taxonomy) map[int64]taxonomy.Taxon {
+	inc := make(map[int64]taxonomy.Taxon)
+	for _, id := range tx.IDs() {
+		tax := tx.Taxon(id)
+		if !strings.EqualFold(tax.Status, "accepted") {
+			continue
+		}
+		hasChildren := false
+		for _, c := range tx.Children(id) {
+			if strings.EqualFold(tx.Taxon(c).Status, "accepted") {
+				hasChildren = true
+				break
+			}
+		}
+		if !hasChildren {
+			inc[id] = tax
+		}
+	}
+	return inc
+}
+
+func anonymize(r io.Reader, w io.Writer, tx *taxonomy.Taxonomy) error {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return fmt.Errorf("when reading %q header: %v", input, err)
+	}
+
+	latCol := -1
+	lonCol := -1
+	genCol := -1
+	fields := make(map[string]int, len(header))
+	for i, h := range header {
+		h = strings.ToLower(h)
+		fields[h] = i
+		switch h {
+		case "decimallatitude":
+			latCol = i
+		case "decimallongitude":
+			lonCol = i
+		case "datageneralizations":
+			genCol = i
+		}
+	}
+	var ownCols []int
+	for _, k := range keyCols {
+		if i, ok := fields[strings.ToLower(k)]; ok {
+			ownCols = append(ownCols, i)
+		}
+	}
+	if len(ownCols) == 0 {
+		return fmt.Errorf("input data %q without %q or %q fields", input, "speciesKey", "taxonKey")
+	}
+
+	inc := inclusive(tx)
+	var incCols []int
+	for _, k := range higherCols {
+		if i, ok := fields[strings.ToLower(k)]; ok {
+			incCols = append(incCols, i)
+		}
+	}
+	for _, id := range tx.IDs() {
+		tax, ok := inc[id]
+		if !ok {
+			continue
+		}
+		col, ok := rankCols[tax.Rank]
+		if !ok {
+			continue
+		}
+		if _, ok := fields[strings.ToLower(col)]; !ok {
+			return fmt.Errorf("input data %q without %q field, required by the sensitive %s %q", input, col, tax.Rank, tax.Name)
+		}
+	}
+	if latCol < 0 || lonCol < 0 {
+		return fmt.Errorf("input data %q without %q or %q fields", input, "decimalLatitude", "decimalLongitude")
+	}
+	var blankCols []int
+	for _, b := range blanked {
+		if i, ok := fields[strings.ToLower(b)]; ok {
+			blankCols = append(blankCols, i)
+		}
+	}
+	nCols := len(header)
+	if genCol < 0 {
+		genCol = nCols
+		header = append(header, "dataGeneralizations")
+	}
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	// write header
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	gen := fmt.Sprintf("coordinates rounded to a %s degree grid; locality removed", strconv.FormatFloat(grid, 'f', -1, 64))
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+		if genCol == nCols {
+			row = append(row[:nCols:nCols], "")
+		}
+
+		sensitive := false
+		for _, col := range ownCols {
+			id, err := rowKey(row, col)
+			if err != nil {
+				return fmt.Errorf("table %q: row %d: field %q: %v", input, ln, header[col], err)
+			}
+			if id != 0 && tx.Taxon(id).ID == id {
+				sensitive = true
+			}
+		}
+		for _, col := range incCols {
+			id, err := rowKey(row, col)
+			if err != nil {
+				return fmt.Errorf("table %q: row %d: field %q: %v", input, ln, header[col], err)
+			}
+			if _, ok := inc[id]; ok && id != 0 {
+				sensitive = true
+			}
+		}
+		if sensitive {
+			if err := generalize(row, latCol, lonCol); err != nil {
+				return fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			}
+			for _, i := range blankCols {
+				row[i] = ""
+			}
+			if v := strings.TrimSpace(row[genCol]); v != "" && !strings.Contains(v, gen) {
+				row[genCol] = v + "; " + gen
+			} else {
+				row[genCol] = gen
+			}
+		}
+
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+// rowKey returns the key
+// stored in a column of a row,
+// or 0 if the column is empty.
+func rowKey(row []string, col int) (int64, error) {
+	if row[col] == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(row[col], 10, 64)
+}
+
+// generalize rounds the coordinates of a row
+// to the center of a grid cell.
+func generalize(row []string, latCol, lonCol int) error {
+	if row[latCol] == "" || row[lonCol] == "" {
+		return nil
+	}
+	lat, err := strconv.ParseFloat(row[latCol], 64)
+	if err != nil {
+		return fmt.Errorf("field %q: %v", "decimalLatitude", err)
+	}
+	if lat < -90 || lat > 90 {
+		return fmt.Errorf("field %q: invalid latitude: %.6f", "decimalLatitude", lat)
+	}
+	lon, err := strconv.ParseFloat(row[lonCol], 64)
+	if err != nil {
+		return fmt.Errorf("field %q: %v", "decimalLongitude", err)
+	}
+	if lon < -180 || lon > 180 {
+		return fmt.Errorf("field %q: invalid longitude: %.6f", "decimalLongitude", lon)
+	}
+
+	lat = math.Min(roundGrid(lat), 90)
+	lon = math.Min(roundGrid(lon), 180)
+	row[latCol] = strconv.FormatFloat(lat, 'f', 6, 64)
+	row[lonCol] = strconv.FormatFloat(lon, 'f', 6, 64)
+	return nil
+}
+
+func roundGrid(v float64) float64 {
+	return math.Floor(v/grid)*grid + grid/2
+}
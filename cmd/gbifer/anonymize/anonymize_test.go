@@ -0,0 +1,64 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package anonymize_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/anonymize"
+)
+
+// sensitive is a taxonomy
+// with a sensitive species
+// (and its genus, as its parent)
+// and a sensitive genus.
+var sensitive = "name\tauthor\tyear\ttaxonKey\trank\tstatus\tparent\r\n" +
+	"Puma\t\t\t2435098\tgenus\taccepted\t\r\n" +
+	"Puma concolor\t\t\t2435099\tspecies\taccepted\t2435098\r\n" +
+	"Harpia\t\t\t2480434\tgenus\taccepted\t\r\n"
+
+func TestAnonymize(t *testing.T) {
+	txFile := filepath.Join(t.TempDir(), "sensitive.tab")
+	if err := os.WriteFile(txFile, []byte(sensitive), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tab := "gbifID\tspeciesKey\tgenusKey\tdecimalLatitude\tdecimalLongitude\tlocality\tdataGeneralizations\r\n" +
+		"1\t2435099\t2435098\t-34.52\t-58.43\tRío Luján\t\r\n" +
+		"2\t2435100\t2435098\t-34.52\t-58.43\tRío Luján\t\r\n" +
+		"3\t2480435\t2480434\t-26.12\t-54.61\tIguazú\tdatum converted\r\n" +
+		"4\t5\t6\t-26.12\t-54.61\tIguazú\t\r\n"
+
+	var buf bytes.Buffer
+	anonymize.Command.SetStdin(strings.NewReader(tab))
+	anonymize.Command.SetStdout(&buf)
+	if err := anonymize.Command.Execute([]string{"--tax", txFile, "--grid", "1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	gen := "coordinates rounded to a 1 degree grid; locality removed"
+	want := "gbifID\tspeciesKey\tgenusKey\tdecimalLatitude\tdecimalLongitude\tlocality\tdataGeneralizations\r\n" +
+		"1\t2435099\t2435098\t-34.500000\t-58.500000\t\t" + gen + "\r\n" +
+		"2\t2435100\t2435098\t-34.52\t-58.43\tRío Luján\t\r\n" +
+		"3\t2480435\t2480434\t-26.500000\t-54.500000\t\tdatum converted; " + gen + "\r\n" +
+		"4\t5\t6\t-26.12\t-54.61\tIguazú\t\r\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// a sensitive genus requires the genusKey column
+	noGenus := "gbifID\tspeciesKey\tdecimalLatitude\tdecimalLongitude\r\n" +
+		"3\t2480435\t-26.12\t-54.61\r\n"
+	anonymize.Command.SetStdin(strings.NewReader(noGenus))
+	anonymize.Command.SetStdout(&bytes.Buffer{})
+	err := anonymize.Command.Execute([]string{"--tax", txFile})
+	if err == nil || !strings.Contains(err.Error(), "genusKey") {
+		t.Errorf("got error %v, want a missing %q error", err, "genusKey")
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package apply implements a command to transform
+// the rows of a GBIF occurrence table
+// with an external program.
+package apply
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `apply --exec <program>
+	[-i|--input <file>] [-o|--output <file>]
+	[<argument>...]`,
+	Short: "transform rows with an external program",
+	Long: `
+Command apply reads a GBIF occurrence table from the standard input, and
+transforms its rows using an external program, so custom logic, written in any
+language, can be used as part of a GBIFer pipeline.
+
+The flag --exec is required and defines the program to be executed. Any
+argument after the flags will be passed as an argument of the program (use
+"--" before the arguments if the first argument starts with a dash).
+
+The program receives the table, starting with the header, in its standard
+input, as a tab-delimited file with one row per line (lines end with "\r\n",
+as in any table written by GBIFer). The program must write the transformed
+table, starting with the header, in its standard output, using the same format
+(lines can end with "\n" or "\r\n"). The program can add, remove, or reorder
+columns, and it can drop or add rows, but all the rows of the output must have
+the same number of fields as its header. The standard error of the program is
+shown in the standard error of GBIFer.
+
+The rows are sent to the program while the output of the program is read, so
+the program can process the rows one at a time, without reading the whole
+table.
+
+For example, a Python script that keeps only the records with a year
+before 1950 can be:
+
+	import sys
+
+	header = sys.stdin.readline().rstrip("\r\n").split("\t")
+	year = header.index("year")
+	sys.stdout.write("\t".join(header) + "\n")
+	for line in sys.stdin:
+	    row = line.rstrip("\r\n").split("\t")
+	    if row[year] and int(row[year]) < 1950:
+	        sys.stdout.write("\t".join(row) + "\n")
+
+that can be used as:
+
+	gbifer apply --exec python3 -i occurrences.tab old.py
+
+If the program fails, the command will fail. The program can stop reading its
+input before the end of the table (for example, to keep only the first rows).
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+// errNoHeader is returned
+// when the program output is empty.
+var errNoHeader = errors.New("without header")
+
+var execFlag string
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&execFlag, "exec", "", "")
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if execFlag == "" {
+		return c.UsageError("flag --exec must be defined")
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := apply(in, out, c.Stderr(), args); err != nil {
+		return err
+	}
+	return nil
+}
+
+func apply(r io.Reader, w, stderr io.Writer, args []string) error {
+	logger.Verbosef("apply: %s %s", execFlag, strings.Join(args, " "))
+	cmd := exec.Command(execFlag, args...)
+	cmd.Stderr = stderr
+	pIn, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("program %q: %v", execFlag, err)
+	}
+	pOut, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("program %q: %v", execFlag, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("program %q: %v", execFlag, err)
+	}
+
+	sent := make(chan error, 1)
+	go func() {
+		err := send(r, pIn)
+		if e := pIn.Close(); e != nil && err == nil {
+			err = e
+		}
+		sent <- err
+	}()
+
+	rows, rErr := receive(pOut, w)
+	killed := false
+	if rErr != nil && !errors.Is(rErr, errNoHeader) {
+		// the program output is invalid,
+		// so there is no need to wait
+		cmd.Process.Kill()
+		killed = true
+	}
+	sErr := <-sent
+	wErr := cmd.Wait()
+
+	if killed {
+		return rErr
+	}
+	if wErr != nil {
+		return fmt.Errorf("program %q: %v", execFlag, wErr)
+	}
+	if rErr != nil {
+		return rErr
+	}
+	// the program can close its input
+	// before reading the whole table
+	if sErr != nil && !errors.Is(sErr, syscall.EPIPE) {
+		return sErr
+	}
+	logger.Verbosef("apply: %d rows written", rows)
+	return nil
+}
+
+// send sends the input table
+// to the program.
+func send(r io.Reader, w io.Writer) error {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return fmt.Errorf("when reading %q header: %v", input, err)
+	}
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("program %q: input: %w", execFlag, err)
+	}
+
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("program %q: input: %w", execFlag, err)
+		}
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("program %q: input: %w", execFlag, err)
+	}
+	return nil
+}
+
+// receive reads the table produced by the program
+// and writes it on w.
+// It returns the number of rows written.
+func receive(r io.Reader, w io.Writer) (int, error) {
+	tab := tsv.NewReader(r)
+	tab.Comma = '\t'
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	header, err := tab.Read()
+	if errors.Is(err, io.EOF) {
+		return 0, fmt.Errorf("program %q: output: %w", execFlag, errNoHeader)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("program %q: output: header: %v", execFlag, err)
+	}
+	if err := out.Write(header); err != nil {
+		return 0, fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	var rows int
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return rows, fmt.Errorf("program %q: output: row %d: %v", execFlag, ln, err)
+		}
+		if err := out.Write(row); err != nil {
+			return rows, fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+		rows++
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return rows, fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,145 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package build implements a command to build
+// a columnar cache file
+// from an occurrence table.
+package build
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/columnar"
+)
+
+var Command = &command.Command{
+	Usage: `build [-i|--input <file>] [-o|--output <file>]`,
+	Short: "build a columnar cache file",
+	Long: `
+Command build reads a GBIF occurrence table from the standard input and writes
+it as a columnar cache file.
+
+A columnar cache file is a binary file in which the values of each column are
+stored together, using a dictionary for columns with few distinct values (for
+example, "countryCode" or "basisOfRecord"), and the difference between
+consecutive values for integer columns (for example, "gbifID"). A cache file
+is usually smaller than the text table, and it is faster to read, as the text
+of the table is not parsed.
+
+Any command that reads an occurrence table from a file can read a cache file
+instead of the text table, for example:
+
+	gbifer cache build -i occurrences.tab -o occurrences.gbc
+	gbifer filter --tax felidae.tab -i occurrences.gbc
+
+Commands that only use some columns of the table (for example, count,
+coverage, temporal, or unique), only read those columns from the cache file.
+Use the command "cache read" to read only some columns of the cache file.
+
+Cache files can only be read from a file, not from the standard input.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the cache will be written in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f *os.File
+		f, err = os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	n, err := build(in, out)
+	if err != nil {
+		return err
+	}
+	logger.Printf("%d rows written in cache %q", n, output)
+	return nil
+}
+
+func build(r io.Reader, w io.Writer) (int, error) {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return 0, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+
+	cw, err := columnar.NewWriter(w, header)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			cw.Abort()
+			return 0, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+		if err := cw.Write(row); err != nil {
+			cw.Abort()
+			return 0, fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+		n++
+	}
+
+	if err := cw.Close(); err != nil {
+		return 0, fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,23 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package cache is a metapackage for commands
+// that dealt with columnar cache files.
+package cache
+
+import (
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/cache/build"
+	"github.com/js-arias/gbifer/cmd/gbifer/cache/read"
+)
+
+var Command = &command.Command{
+	Usage: "cache <command> [<argument>...]",
+	Short: "commands for columnar cache files",
+}
+
+func init() {
+	Command.Add(build.Command)
+	Command.Add(read.Command)
+}
@@ -0,0 +1,131 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package read implements a command to read
+// the columns of a columnar cache file.
+package read
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/columnar"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `read [--cols <column>[,<column>...]]
+	[-o|--output <file>] <cache-file>`,
+	Short: "read columns of a columnar cache file",
+	Long: `
+Command read reads a columnar cache file, as created by the command "cache
+build", and prints it as a GBIF occurrence table.
+
+The argument of the command is the name of the cache file.
+
+By default, all the columns are printed. Use the flag --cols to define the
+columns to be printed, separated by commas, in the indicated order. Only the
+indicated columns are read from the cache file, so reading a few columns of a
+large table is fast.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var colsFlag string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&colsFlag, "cols", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if len(args) < 1 {
+		return c.UsageError("expecting cache file")
+	}
+	name := args[0]
+
+	var cols []string
+	for _, v := range strings.Split(colsFlag, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		cols = append(cols, v)
+	}
+
+	cf, err := columnar.Open(name)
+	if err != nil {
+		return err
+	}
+	defer cf.Close()
+
+	rd, err := cf.Reader(cols...)
+	if err != nil {
+		return fmt.Errorf("on file %q: %v", name, err)
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := write(out, name, rd); err != nil {
+		return err
+	}
+	return nil
+}
+
+func write(w io.Writer, name string, rd *columnar.Reader) error {
+	tab := tsv.NewWriter(w)
+	tab.Comma = '\t'
+	tab.UseCRLF = true
+
+	if err := tab.Write(rd.Header()); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	for {
+		row, err := rd.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("on file %q: %v", name, err)
+		}
+		logger.ReadRows(1)
+		if err := tab.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	tab.Flush()
+	if err := tab.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
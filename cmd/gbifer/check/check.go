@@ -0,0 +1,514 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package check implements a command to validate
+// the structure and values
+// of a GBIF occurrence table.
+package check
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/country"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/schema"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `check [--schema <file>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "validate an occurrence table",
+	Long: `
+Command check reads a GBIF occurrence table from the standard input and
+validates the whole table. The following problems are reported:
+
+	- fields: the row has a wrong number of fields.
+	- integer: the value of an integer column (for example, "gbifID",
+	  "taxonKey", or "year") is not an integer.
+	- float: the value of a numeric column (for example,
+	  "decimalLatitude", or "coordinateUncertaintyInMeters") is not a
+	  number.
+	- date: the value of a date column (for example, "eventDate") is not
+	  an ISO 8601 date, or date range.
+	- range: the value is out of the valid range (for example, a latitude
+	  greater than 90, or a month greater than 12).
+	- coordinates: only one of the coordinates is defined.
+	- country: the country code is not a valid ISO 3166-1 alpha-2 code.
+	- duplicate: the gbifID is repeated.
+
+If the flag --schema is defined with a schema file (as written by the command
+schema), the columns defined in the schema are validated with the types and
+limits of the schema, instead of the types and limits of the known columns,
+and the following problems are also reported:
+
+	- column: a column of the schema is not in the table (reported in
+	  the header row).
+	- null: the value is empty, but the column is not nullable.
+	- enum: the value of an enum column is not one of the valid values.
+	- length: the length of a text, or enum, value is out of the limits.
+
+In a schema, the limits of date columns are compared as strings. An empty
+limit is not checked.
+
+The problems are printed as a TSV table with the following columns:
+
+	- row: the line number of the row.
+	- field: the column with the problem.
+	- problem: the type of problem.
+	- value: the value with the problem.
+
+If any problem is found, the number of problems is reported, and the command
+ends with an error (i.e., a non-zero exit code), so it can be used to stop a
+pipeline.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+var schemaFile string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&schemaFile, "schema", "", "")
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	var sc map[string]schema.Column
+	if schemaFile != "" {
+		sc, err = readSchema()
+		if err != nil {
+			return err
+		}
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f *os.File
+		f, err = os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	n, err := check(in, out, sc)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("table %q: %d problems found", input, n)
+	}
+	return nil
+}
+
+func readSchema() (map[string]schema.Column, error) {
+	f, err := os.Open(schemaFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc, err := schema.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", schemaFile, err)
+	}
+	return sc, nil
+}
+
+// A kind is the kind of values
+// of a column.
+type kind int
+
+const (
+	integerKind kind = iota + 1
+	floatKind
+	dateKind
+)
+
+// kinds are the kinds of the known columns.
+var kinds = map[string]kind{
+	"gbifid":                        integerKind,
+	"taxonkey":                      integerKind,
+	"specieskey":                    integerKind,
+	"acceptedtaxonkey":              integerKind,
+	"kingdomkey":                    integerKind,
+	"phylumkey":                     integerKind,
+	"classkey":                      integerKind,
+	"orderkey":                      integerKind,
+	"familykey":                     integerKind,
+	"genuskey":                      integerKind,
+	"subgenuskey":                   integerKind,
+	"year":                          integerKind,
+	"month":                         integerKind,
+	"day":                           integerKind,
+	"individualcount":               integerKind,
+	"decimallatitude":               floatKind,
+	"decimallongitude":              floatKind,
+	"coordinateuncertaintyinmeters": floatKind,
+	"coordinateprecision":           floatKind,
+	"elevation":                     floatKind,
+	"elevationaccuracy":             floatKind,
+	"depth":                         floatKind,
+	"depthaccuracy":                 floatKind,
+	"eventdate":                     dateKind,
+	"dateidentified":                dateKind,
+	"lastinterpreted":               dateKind,
+}
+
+// limits are the valid ranges
+// of numeric columns.
+var limits = map[string][2]float64{
+	"decimallatitude":               {-90, 90},
+	"decimallongitude":              {-180, 180},
+	"coordinateuncertaintyinmeters": {0, 20_037_509},
+	"coordinateprecision":           {0, 1},
+	"month":                         {1, 12},
+	"day":                           {1, 31},
+}
+
+// A report writes the found problems.
+type report struct {
+	w *tsv.Writer
+	n int
+}
+
+func (rp *report) add(row int, field, problem, value string) error {
+	rp.n++
+	logger.Add("problem-"+problem, 1)
+	if err := rp.w.Write([]string{strconv.Itoa(row), field, problem, value}); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	logger.WriteRows(1)
+	return nil
+}
+
+func check(r io.Reader, w io.Writer, sc map[string]schema.Column) (int, error) {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return 0, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+	idCol := -1
+	latCol := -1
+	lonCol := -1
+	ccCol := -1
+	for i, h := range header {
+		switch strings.ToLower(h) {
+		case "gbifid":
+			idCol = i
+		case "decimallatitude":
+			latCol = i
+		case "decimallongitude":
+			lonCol = i
+		case "countrycode":
+			ccCol = i
+		}
+	}
+
+	rp := &report{w: tsv.NewWriter(w)}
+	rp.w.Comma = '\t'
+	rp.w.UseCRLF = true
+	if err := rp.w.Write([]string{"row", "field", "problem", "value"}); err != nil {
+		return 0, fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	rules := make([]*rule, len(header))
+	if sc != nil {
+		inTable := make(map[string]bool, len(header))
+		for i, h := range header {
+			h = strings.ToLower(h)
+			inTable[h] = true
+			c, ok := sc[h]
+			if !ok {
+				continue
+			}
+			rl, err := newRule(c)
+			if err != nil {
+				return 0, fmt.Errorf("on file %q: %v", schemaFile, err)
+			}
+			rules[i] = rl
+		}
+
+		var missing []string
+		for h, c := range sc {
+			if !inTable[h] {
+				missing = append(missing, c.Name)
+			}
+		}
+		slices.Sort(missing)
+		for _, m := range missing {
+			if err := rp.add(1, m, "column", ""); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	ids := make(map[string]int)
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if errors.Is(err, tsv.ErrFieldCount) {
+			logger.ReadRows(1)
+			v := fmt.Sprintf("got %d fields, want %d", len(row), len(header))
+			if err := rp.add(ln, "", "fields", v); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		for i, h := range header {
+			if rules[i] != nil {
+				if err := rules[i].check(rp, ln, h, row[i]); err != nil {
+					return 0, err
+				}
+				continue
+			}
+			if err := checkValue(rp, ln, h, row[i]); err != nil {
+				return 0, err
+			}
+		}
+
+		if latCol >= 0 && lonCol >= 0 && (row[latCol] == "") != (row[lonCol] == "") {
+			if err := rp.add(ln, header[latCol], "coordinates", row[latCol]+" "+row[lonCol]); err != nil {
+				return 0, err
+			}
+		}
+		if ccCol >= 0 && row[ccCol] != "" && country.Name(row[ccCol]) == "" {
+			if err := rp.add(ln, header[ccCol], "country", row[ccCol]); err != nil {
+				return 0, err
+			}
+		}
+		if idCol >= 0 && row[idCol] != "" {
+			if prev, ok := ids[row[idCol]]; ok {
+				v := fmt.Sprintf("%s (row %d)", row[idCol], prev)
+				if err := rp.add(ln, header[idCol], "duplicate", v); err != nil {
+					return 0, err
+				}
+			} else {
+				if err := memory.Add(int64(len(row[idCol]) + 24)); err != nil {
+					return 0, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+				}
+				ids[row[idCol]] = ln
+			}
+		}
+	}
+
+	rp.w.Flush()
+	if err := rp.w.Error(); err != nil {
+		return 0, fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return rp.n, nil
+}
+
+// checkValue checks the value of a field
+// of a known column.
+func checkValue(rp *report, ln int, field, v string) error {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil
+	}
+	name := strings.ToLower(field)
+
+	var num float64
+	switch kinds[name] {
+	case integerKind:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return rp.add(ln, field, "integer", v)
+		}
+		num = float64(n)
+	case floatKind:
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return rp.add(ln, field, "float", v)
+		}
+		num = n
+	case dateKind:
+		if !isDate(v) {
+			return rp.add(ln, field, "date", v)
+		}
+		return nil
+	default:
+		return nil
+	}
+
+	if lim, ok := limits[name]; ok {
+		if num < lim[0] || num > lim[1] {
+			return rp.add(ln, field, "range", v)
+		}
+	}
+	return nil
+}
+
+// A rule is a column of a schema
+// with its limits already parsed.
+type rule struct {
+	col schema.Column
+
+	hasMin, hasMax bool
+	min, max       float64
+}
+
+func newRule(c schema.Column) (*rule, error) {
+	rl := &rule{col: c}
+	if c.Type == "date" {
+		// dates are compared as strings
+		return rl, nil
+	}
+	if c.Min != "" {
+		n, err := strconv.ParseFloat(c.Min, 64)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: min: %v", c.Name, err)
+		}
+		rl.hasMin = true
+		rl.min = n
+	}
+	if c.Max != "" {
+		n, err := strconv.ParseFloat(c.Max, 64)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: max: %v", c.Name, err)
+		}
+		rl.hasMax = true
+		rl.max = n
+	}
+	return rl, nil
+}
+
+// check checks the value of a field
+// using the rule of a schema.
+func (rl *rule) check(rp *report, ln int, field, v string) error {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		if !rl.col.Nullable {
+			return rp.add(ln, field, "null", v)
+		}
+		return nil
+	}
+
+	switch rl.col.Type {
+	case "integer":
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return rp.add(ln, field, "integer", v)
+		}
+		if rl.out(float64(n)) {
+			return rp.add(ln, field, "range", v)
+		}
+	case "float":
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return rp.add(ln, field, "float", v)
+		}
+		if rl.out(n) {
+			return rp.add(ln, field, "range", v)
+		}
+	case "date":
+		if !isDate(v) {
+			return rp.add(ln, field, "date", v)
+		}
+		if (rl.col.Min != "" && v < rl.col.Min) || (rl.col.Max != "" && v > rl.col.Max) {
+			return rp.add(ln, field, "range", v)
+		}
+	case "enum":
+		if len(rl.col.Values) > 0 && !slices.Contains(rl.col.Values, v) {
+			return rp.add(ln, field, "enum", v)
+		}
+		if rl.out(float64(utf8.RuneCountInString(v))) {
+			return rp.add(ln, field, "length", v)
+		}
+	default:
+		if rl.out(float64(utf8.RuneCountInString(v))) {
+			return rp.add(ln, field, "length", v)
+		}
+	}
+	return nil
+}
+
+// out returns true if a value
+// is out of the limits of the rule.
+func (rl *rule) out(n float64) bool {
+	return (rl.hasMin && n < rl.min) || (rl.hasMax && n > rl.max)
+}
+
+// dateLayouts are the accepted layouts
+// for ISO 8601 dates.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// isDate returns true
+// if the value is an ISO 8601 date,
+// or a date range.
+func isDate(v string) bool {
+	for _, p := range strings.Split(v, "/") {
+		ok := false
+		for _, l := range dateLayouts {
+			if _, err := time.Parse(l, p); err == nil {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
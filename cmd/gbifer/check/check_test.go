@@ -0,0 +1,58 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package check_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/check"
+)
+
+func TestCheckSchema(t *testing.T) {
+	sc := "column\ttype\tnullable\tmin\tmax\tvalues\r\n" +
+		"gbifID\ttext\tfalse\t1\t3\t\r\n" +
+		"year\tinteger\ttrue\t1900\t2000\t\r\n" +
+		"basisOfRecord\tenum\tfalse\t\t\tHUMAN_OBSERVATION|PRESERVED_SPECIMEN\r\n" +
+		"eventDate\tdate\ttrue\t1950\t1999-12-31\t\r\n" +
+		"license\ttext\ttrue\t\t\t\r\n"
+	scFile := filepath.Join(t.TempDir(), "schema.tab")
+	if err := os.WriteFile(scFile, []byte(sc), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tab := "gbifID\tyear\tbasisOfRecord\teventDate\r\n" +
+		"a1\t1950\tHUMAN_OBSERVATION\t1950-03-01\r\n" +
+		"a2\t2010\tFOSSIL\t2010-01-01\r\n" +
+		"a333\t\tHUMAN_OBSERVATION\t\r\n" +
+		"\tx\t\t1990\r\n"
+
+	var buf bytes.Buffer
+	check.Command.SetStdin(strings.NewReader(tab))
+	check.Command.SetStdout(&buf)
+	err := check.Command.Execute([]string{"--schema", scFile})
+	if err == nil {
+		t.Fatalf("expecting problems")
+	}
+	if !strings.Contains(err.Error(), "8 problems found") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	want := "row\tfield\tproblem\tvalue\r\n" +
+		"1\tlicense\tcolumn\t\r\n" +
+		"3\tyear\trange\t2010\r\n" +
+		"3\tbasisOfRecord\tenum\tFOSSIL\r\n" +
+		"3\teventDate\trange\t2010-01-01\r\n" +
+		"4\tgbifID\tlength\ta333\r\n" +
+		"5\tgbifID\tnull\t\r\n" +
+		"5\tyear\tinteger\tx\r\n" +
+		"5\tbasisOfRecord\tnull\t\r\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
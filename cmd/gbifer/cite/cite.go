@@ -0,0 +1,307 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package cite implements a command to build
+// the citation list
+// of the datasets of a GBIF occurrence table.
+package cite
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `cite [--format <format>] [--counts <file>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "build a citation list of datasets",
+	Long: `
+Command cite reads a GBIF occurrence table from the standard input, counts the
+records of each dataset (as defined in the "datasetKey" column), and retrieves
+the citation and DOI of each dataset from GBIF. Then it prints the list of
+citations, sorted by the number of records.
+
+By default the citations are printed as plain text. Use the flag --format to
+define a different format. Valid formats are:
+
+	text      plain text, a citation per line.
+	markdown  a Markdown list.
+	bibtex    BibTeX entries.
+
+If the flag --counts is defined, the number of records of each dataset will be
+written in the indicated file, as a TSV file with the columns "datasetKey" and
+"count". This is the file required to register a derived dataset in GBIF.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+
+This command requires an internet connection.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+var format string
+var countsFile string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().StringVar(&format, "format", "text", "")
+	c.Flags().StringVar(&countsFile, "counts", "", "")
+}
+
+// A dataset is a dataset
+// with its number of records.
+type dataset struct {
+	key     string
+	records int
+	ds      *gbif.Dataset
+}
+
+func run(c *command.Command, args []string) (err error) {
+	format = strings.ToLower(format)
+	switch format {
+	case "text", "markdown", "bibtex":
+	case "md":
+		format = "markdown"
+	default:
+		return c.UsageError(fmt.Sprintf("unknown format %q", format))
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	dsLs, err := countRecords(in)
+	if err != nil {
+		return err
+	}
+
+	if countsFile != "" {
+		if err := writeCounts(dsLs); err != nil {
+			return err
+		}
+	}
+
+	gbif.Open()
+	for _, d := range dsLs {
+		ds, err := gbif.DatasetKey(d.key)
+		if errors.Is(err, gbif.ErrNotFound) {
+			logger.Printf("dataset %s not found", d.key)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		d.ds = ds
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f *os.File
+		f, err = os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := writeCitations(out, dsLs); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+func countRecords(r io.Reader) ([]*dataset, error) {
+	tab := tabfile.NewReader(r, "datasetKey")
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+	dsCol := -1
+	for i, h := range header {
+		if strings.ToLower(h) == "datasetkey" {
+			dsCol = i
+		}
+	}
+	if dsCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q field", input, "datasetKey")
+	}
+
+	datasets := make(map[string]*dataset)
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		key := strings.TrimSpace(row[dsCol])
+		if key == "" {
+			continue
+		}
+		d, ok := datasets[key]
+		if !ok {
+			if err := memory.Add(int64(len(key)) + 64); err != nil {
+				return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			}
+			d = &dataset{key: key}
+			datasets[key] = d
+		}
+		d.records++
+	}
+
+	dsLs := make([]*dataset, 0, len(datasets))
+	for _, d := range datasets {
+		dsLs = append(dsLs, d)
+	}
+	slices.SortFunc(dsLs, func(a, b *dataset) int {
+		if a.records != b.records {
+			return b.records - a.records
+		}
+		return strings.Compare(a.key, b.key)
+	})
+	return dsLs, nil
+}
+
+func writeCounts(dsLs []*dataset) (err error) {
+	f, err := tabfile.Create(countsFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		e := f.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	w := tsv.NewWriter(f)
+	w.Comma = '\t'
+	w.UseCRLF = true
+
+	if err := w.Write([]string{"datasetKey", "count"}); err != nil {
+		return fmt.Errorf("when writing on %q: %v", countsFile, err)
+	}
+	for _, d := range dsLs {
+		row := []string{d.key, strconv.Itoa(d.records)}
+		if err := w.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", countsFile, err)
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", countsFile, err)
+	}
+	return nil
+}
+
+func writeCitations(w io.Writer, dsLs []*dataset) error {
+	for _, d := range dsLs {
+		if d.ds == nil {
+			continue
+		}
+		var err error
+		switch format {
+		case "text":
+			_, err = fmt.Fprintf(w, "%s [%d records]\n", citation(d.ds), d.records)
+		case "markdown":
+			_, err = fmt.Fprintf(w, "- %s [%d records]\n", citation(d.ds), d.records)
+		case "bibtex":
+			err = writeBibTeX(w, d)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// citation returns the citation text of a dataset,
+// including the DOI,
+// if it is not already in the citation.
+func citation(ds *gbif.Dataset) string {
+	c := strings.Join(strings.Fields(ds.Citation.Text), " ")
+	if c == "" {
+		c = strings.Join(strings.Fields(ds.Title), " ")
+	}
+	if ds.DOI != "" && !strings.Contains(c, ds.DOI) {
+		c += " https://doi.org/" + ds.DOI
+	}
+	return c
+}
+
+func writeBibTeX(w io.Writer, d *dataset) error {
+	fmt.Fprintf(w, "@misc{gbif:%s,\n", d.key)
+	fmt.Fprintf(w, "\ttitle = {%s},\n", bibEscape(strings.Join(strings.Fields(d.ds.Title), " ")))
+	if len(d.ds.PubDate) >= 4 {
+		fmt.Fprintf(w, "\tyear = {%s},\n", d.ds.PubDate[:4])
+	}
+	fmt.Fprintf(w, "\thowpublished = {GBIF dataset},\n")
+	if d.ds.DOI != "" {
+		fmt.Fprintf(w, "\tdoi = {%s},\n", d.ds.DOI)
+	}
+	fmt.Fprintf(w, "\turl = {https://www.gbif.org/dataset/%s},\n", d.key)
+	fmt.Fprintf(w, "\tnote = {%s [%d records]},\n", bibEscape(citation(d.ds)), d.records)
+	_, err := fmt.Fprintf(w, "}\n\n")
+	return err
+}
+
+var bibReplacer = strings.NewReplacer(
+	"{", "\\{",
+	"}", "\\}",
+	"&", "\\&",
+	"%", "\\%",
+	"_", "\\_",
+	"#", "\\#",
+	"$", "\\$",
+)
+
+// bibEscape escapes the BibTeX special characters.
+func bibEscape(s string) string {
+	return bibReplacer.Replace(s)
+}
@@ -0,0 +1,352 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package collectors implements a command to tabulate
+// the collectors of the records
+// of a GBIF occurrence table.
+package collectors
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `collectors [--min <number>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "tabulate the collectors of the records",
+	Long: `
+Command collectors reads a GBIF occurrence table from the standard input and
+prints the number of records of each collector, as defined in the "recordedBy"
+column of the table.
+
+As the same collector is usually written in different ways, the names are
+normalized before counting:
+
+	- Multiple collectors in a single value are split (collectors are
+	  separated by "|", ";", "&", "and", "y", "e", or by commas between full
+	  names).
+	- Expressions such as "et al.", "leg.", or "s.n." are removed.
+	- Each name is reduced to the surname and the initials of the given
+	  names, so "John A. Smith", "J.A. Smith", and "SMITH, J. A." are all
+	  counted as "Smith, J.A.". The surname is compared ignoring case and
+	  common diacritics.
+
+A record with several collectors is counted for each of its collectors.
+
+The output is a TSV table with the following columns:
+
+	- collector: the normalized name of the collector.
+	- records: the number of records of the collector.
+	- first: the date of the oldest record of the collector.
+	- last: the date of the most recent record of the collector.
+	- variants: the different ways in which the name of the collector is
+	  written in the table, separated by semicolons.
+
+Dates are taken from the "eventDate" column, or, if the date is empty, from
+the "year" column. Rows are sorted from the collector with more records to the
+collector with fewer records.
+
+If the flag --min is defined, only the collectors with at least the indicated
+number of records will be printed.
+
+The number of records without collectors is reported in the standard error.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var minFlag int
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().IntVar(&minFlag, "min", 0, "")
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if minFlag < 0 {
+		return c.UsageError(fmt.Sprintf("invalid --min value %d", minFlag))
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	ls, err := readTable(in)
+	if err != nil {
+		return err
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := writeCollectors(out, ls); err != nil {
+		return err
+	}
+	return nil
+}
+
+// A collector stores the records
+// of a collector.
+type collector struct {
+	key      string
+	records  int
+	first    string
+	last     string
+	names    map[string]int // normalized names
+	variants map[string]bool
+}
+
+// name returns the most frequent normalized name
+// of the collector.
+func (cl *collector) name() string {
+	var name string
+	n := 0
+	for nm, c := range cl.names {
+		if c > n || (c == n && nm < name) {
+			name = nm
+			n = c
+		}
+	}
+	return name
+}
+
+func (cl *collector) addDate(date string) {
+	if date == "" {
+		return
+	}
+	if cl.first == "" || date < cl.first {
+		cl.first = date
+	}
+	if date > cl.last {
+		cl.last = date
+	}
+}
+
+func readTable(r io.Reader) ([]*collector, error) {
+	tab := tabfile.NewReader(r, "recordedBy", "eventDate", "year")
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+
+	colCol := -1
+	dCol := -1
+	yCol := -1
+	for i, h := range header {
+		switch strings.ToLower(h) {
+		case "recordedby":
+			colCol = i
+		case "eventdate":
+			dCol = i
+		case "year":
+			yCol = i
+		}
+	}
+	if colCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q field", input, "recordedBy")
+	}
+
+	cls := make(map[string]*collector)
+	var noCollector int
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		names := splitCollectors(row[colCol])
+		if len(names) == 0 {
+			noCollector++
+			continue
+		}
+
+		var date string
+		if dCol >= 0 {
+			date = eventDate(row[dCol])
+		}
+		if date == "" && yCol >= 0 {
+			if y, err := strconv.Atoi(strings.TrimSpace(row[yCol])); err == nil && y > 0 {
+				date = strconv.Itoa(y)
+			}
+		}
+
+		// a collector can be repeated
+		// in the same record
+		seen := make(map[string]bool, len(names))
+		for _, raw := range names {
+			nm, key := normalize(raw)
+			if key == "" {
+				continue
+			}
+
+			cl, ok := cls[key]
+			if !ok {
+				if err := memory.Add(int64(len(key)) + 128); err != nil {
+					return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+				}
+				cl = &collector{
+					key:      key,
+					names:    make(map[string]int),
+					variants: make(map[string]bool),
+				}
+				cls[key] = cl
+			}
+			if !cl.variants[raw] {
+				if err := memory.Add(int64(len(raw)) + 24); err != nil {
+					return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+				}
+				cl.variants[raw] = true
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			cl.records++
+			if _, ok := cl.names[nm]; !ok {
+				if err := memory.Add(int64(len(nm)) + 24); err != nil {
+					return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+				}
+			}
+			cl.names[nm]++
+			cl.addDate(date)
+		}
+	}
+	if noCollector > 0 {
+		logger.Printf("%d records without collector", noCollector)
+	}
+
+	ls := make([]*collector, 0, len(cls))
+	for _, cl := range cls {
+		ls = append(ls, cl)
+	}
+	slices.SortFunc(ls, func(a, b *collector) int {
+		if c := cmp.Compare(b.records, a.records); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.key, b.key)
+	})
+	return ls, nil
+}
+
+// eventDate returns the date of an ISO 8601 event date
+// (the first date of an interval)
+// without the time,
+// or an empty string if the date is not valid.
+func eventDate(date string) string {
+	date = strings.TrimSpace(date)
+	if i := strings.IndexAny(date, "/T "); i >= 0 {
+		date = date[:i]
+	}
+	if len(date) < 4 {
+		return ""
+	}
+	if _, err := strconv.Atoi(date[:4]); err != nil {
+		return ""
+	}
+	if len(date) > 4 && date[4] != '-' {
+		return ""
+	}
+	return date
+}
+
+var headerCols = []string{
+	"collector",
+	"records",
+	"first",
+	"last",
+	"variants",
+}
+
+func writeCollectors(w io.Writer, ls []*collector) error {
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	if err := out.Write(headerCols); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	for _, cl := range ls {
+		if cl.records < minFlag {
+			continue
+		}
+		vs := make([]string, 0, len(cl.variants))
+		for v := range cl.variants {
+			vs = append(vs, v)
+		}
+		slices.Sort(vs)
+
+		row := []string{
+			cl.name(),
+			strconv.Itoa(cl.records),
+			cl.first,
+			cl.last,
+			strings.Join(vs, ";"),
+		}
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
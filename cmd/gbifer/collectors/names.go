@@ -0,0 +1,261 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package collectors
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// separators are the words
+// that separate collectors
+// in a recordedBy value.
+// They must be in lower case,
+// so they are not confused with initials.
+var separators = map[string]bool{
+	"and": true,
+	"y":   true,
+	"e":   true,
+	"und": true,
+}
+
+// noise are the words
+// removed from a recordedBy value.
+var noise = map[string]bool{
+	"leg":       true,
+	"leg.":      true,
+	"coll":      true,
+	"coll.":     true,
+	"col":       true,
+	"col.":      true,
+	"s.n.":      true,
+	"sn":        true,
+	"unknown":   true,
+	"anon":      true,
+	"anon.":     true,
+	"anonymous": true,
+	"collector": true,
+}
+
+// particles are the lower case words
+// that are part of a surname.
+var particles = map[string]bool{
+	"da":  true,
+	"das": true,
+	"de":  true,
+	"del": true,
+	"der": true,
+	"di":  true,
+	"do":  true,
+	"dos": true,
+	"du":  true,
+	"la":  true,
+	"van": true,
+	"von": true,
+}
+
+// splitCollectors returns the names
+// of the collectors
+// in a recordedBy value.
+func splitCollectors(s string) []string {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case '|', '&':
+			return ';'
+		case '[', ']', '(', ')', '?', '"':
+			return ' '
+		}
+		return r
+	}, s)
+
+	var parts []string
+	for _, p := range strings.Split(s, ";") {
+		var words []string
+		etAl := false
+		for _, w := range strings.Fields(p) {
+			lw := strings.ToLower(w)
+			if etAl && strings.HasPrefix(lw, "al") {
+				etAl = false
+				if strings.HasSuffix(w, ",") {
+					words = append(words, ",")
+				}
+				continue
+			}
+			etAl = false
+			if lw == "et" || lw == "et." {
+				etAl = true
+				continue
+			}
+			if separators[w] {
+				parts = append(parts, strings.Join(words, " "))
+				words = words[:0]
+				continue
+			}
+			if noise[strings.TrimSuffix(lw, ",")] {
+				if strings.HasSuffix(w, ",") {
+					words = append(words, ",")
+				}
+				continue
+			}
+			words = append(words, w)
+		}
+		parts = append(parts, strings.Join(words, " "))
+	}
+
+	var names []string
+	for _, p := range parts {
+		// commas can separate collectors,
+		// or the surname from the given names
+		merge := false
+		for _, c := range strings.Split(p, ",") {
+			c = strings.Join(strings.Fields(c), " ")
+			if !hasLetter(c) {
+				continue
+			}
+			if merge {
+				names[len(names)-1] += ", " + c
+				merge = false
+				continue
+			}
+			names = append(names, c)
+			merge = len(strings.Fields(c)) == 1
+		}
+	}
+	return names
+}
+
+// normalize returns the normalized name
+// of a collector,
+// in the form "Surname, I.N.",
+// and the key used to compare the names.
+func normalize(name string) (norm, key string) {
+	var surname, given []string
+	if i := strings.IndexByte(name, ','); i >= 0 {
+		surname = strings.Fields(name[:i])
+		given = strings.Fields(name[i+1:])
+	} else {
+		words := strings.Fields(name)
+		if len(words) == 0 {
+			return "", ""
+		}
+
+		// "Smith J.A."
+		j := len(words)
+		for j > 1 && isInitials(words[j-1]) {
+			j--
+		}
+		if j < len(words) && !isInitials(words[0]) {
+			surname = words[:j]
+			given = words[j:]
+		} else {
+			j = len(words) - 1
+			for j > 0 && particles[strings.ToLower(words[j-1])] {
+				j--
+			}
+			surname = words[j:]
+			given = words[:j]
+		}
+	}
+	if len(surname) == 0 {
+		return "", ""
+	}
+
+	for i, w := range surname {
+		surname[i] = titleCase(w)
+	}
+	sn := strings.Join(surname, " ")
+
+	var ini strings.Builder
+	for _, w := range given {
+		if particles[strings.ToLower(w)] {
+			continue
+		}
+		for _, p := range strings.FieldsFunc(w, func(r rune) bool { return r == '.' || r == '-' }) {
+			if isInitials(p) {
+				// "JA" as initials
+				for _, r := range p {
+					ini.WriteRune(unicode.ToUpper(r))
+					ini.WriteByte('.')
+				}
+				continue
+			}
+			r, _ := utf8.DecodeRuneInString(p)
+			ini.WriteRune(unicode.ToUpper(r))
+			ini.WriteByte('.')
+		}
+	}
+
+	norm = sn
+	if ini.Len() > 0 {
+		norm += ", " + ini.String()
+	}
+	return norm, strings.ToLower(fold(sn)) + "," + ini.String()
+}
+
+// isInitials returns true if a word
+// is made only of initials,
+// for example "J.", "J.A.", or "JA".
+func isInitials(w string) bool {
+	w = strings.ReplaceAll(w, ".", "")
+	w = strings.ReplaceAll(w, "-", "")
+	if w == "" || utf8.RuneCountInString(w) > 3 {
+		return false
+	}
+	for _, r := range w {
+		if !unicode.IsUpper(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// titleCase returns a word
+// with the first letter in upper case,
+// if the word is all in upper or lower case.
+// Surname particles are kept in lower case.
+func titleCase(w string) string {
+	lw := strings.ToLower(w)
+	if particles[lw] {
+		return lw
+	}
+	if w != lw && w != strings.ToUpper(w) {
+		return w
+	}
+	r, n := utf8.DecodeRuneInString(lw)
+	return string(unicode.ToUpper(r)) + lw[n:]
+}
+
+func hasLetter(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// diacritics maps common letters with diacritics
+// to the base letter.
+var diacritics = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o", "ø", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ñ", "n", "ç", "c",
+	"Á", "A", "À", "A", "Â", "A", "Ä", "A", "Ã", "A", "Å", "A",
+	"É", "E", "È", "E", "Ê", "E", "Ë", "E",
+	"Í", "I", "Ì", "I", "Î", "I", "Ï", "I",
+	"Ó", "O", "Ò", "O", "Ô", "O", "Ö", "O", "Õ", "O", "Ø", "O",
+	"Ú", "U", "Ù", "U", "Û", "U", "Ü", "U",
+	"Ñ", "N", "Ç", "C",
+)
+
+// fold returns a string
+// without common diacritics.
+func fold(s string) string {
+	return diacritics.Replace(s)
+}
@@ -15,11 +15,14 @@ import (
 	"strings"
 
 	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/parallel"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/occurrence"
 	"github.com/js-arias/gbifer/tsv"
 )
 
 var Command = &command.Command{
-	Usage: `cols [--del] [--file <file>]
+	Usage: `cols [--del] [--file <file>] [--jobs <number>]
 	[-i|--input <file>] [-o|--output <file>]
 	[<name>...]`,
 	Short: "display and select columns",
@@ -38,6 +41,10 @@ standard output.
 If the flag --del is given, instead of selecting the given columns, it will
 remove the indicated columns.
 
+If the flag --jobs is defined with a value greater than 1, the rows will be
+processed by the indicated number of concurrent jobs; with 0, it will use the
+number of available CPUs. The order of the rows is always preserved.
+
 By default, it will read the data from the standard input; use the flag
 --input, or -i, to select a particular file.
 
@@ -52,6 +59,7 @@ var delFlag bool
 var colFile string
 var input string
 var output string
+var jobs int
 
 func setFlags(c *command.Command) {
 	c.Flags().BoolVar(&delFlag, "del", false, "")
@@ -60,6 +68,7 @@ func setFlags(c *command.Command) {
 	c.Flags().StringVar(&input, "i", "", "")
 	c.Flags().StringVar(&output, "output", "", "")
 	c.Flags().StringVar(&input, "o", "", "")
+	c.Flags().IntVar(&jobs, "jobs", 1, "")
 }
 
 func run(c *command.Command, args []string) (err error) {
@@ -77,8 +86,8 @@ func run(c *command.Command, args []string) (err error) {
 
 	out := c.Stdout()
 	if output != "" {
-		var f *os.File
-		f, err = os.Create(output)
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
 		if err != nil {
 			return err
 		}
@@ -115,7 +124,7 @@ func run(c *command.Command, args []string) (err error) {
 }
 
 func readTable(r io.Reader, w io.Writer, cols map[string]bool) error {
-	tab := tsv.NewReader(r)
+	tab := tabfile.NewReader(r)
 	tab.Comma = '\t'
 
 	header, err := tab.Read()
@@ -130,57 +139,27 @@ func readTable(r io.Reader, w io.Writer, cols map[string]bool) error {
 		return nil
 	}
 
-	keep := make([]int, 0, len(header))
-	if delFlag {
-		for i, h := range header {
-			h = strings.ToLower(h)
-			if cols[h] {
-				continue
-			}
-			keep = append(keep, i)
-		}
-	} else {
-		for i, h := range header {
-			h = strings.ToLower(h)
-			if !cols[h] {
-				continue
-			}
-			keep = append(keep, i)
-		}
+	names := make([]string, 0, len(cols))
+	for c := range cols {
+		names = append(names, c)
 	}
+	tr := occurrence.Columns(header, names, delFlag)
 
 	out := tsv.NewWriter(w)
 	out.Comma = '\t'
 	out.UseCRLF = true
 
 	// write header
-	nh := make([]string, len(keep))
-	for i := range nh {
-		nh[i] = header[keep[i]]
-	}
-	if err := out.Write(nh); err != nil {
+	if err := out.Write(tr.Header()); err != nil {
 		return fmt.Errorf("when writing on %q: %v", output, err)
 	}
 
 	// write data
-	for {
-		row, err := tab.Read()
-		if errors.Is(err, io.EOF) {
-			break
-		}
-		ln, _ := tab.FieldPos(0)
-		if err != nil {
-			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
-		}
-
-		nr := make([]string, len(keep))
-		for i := range nr {
-			nr[i] = row[keep[i]]
-		}
-
-		if err := out.Write(nr); err != nil {
-			return fmt.Errorf("when writing on %q: %v", output, err)
-		}
+	fn := func(row []string, ln int) ([]string, error) {
+		return tr.Transform(row)
+	}
+	if err := parallel.Rows(tab, input, out, output, jobs, fn); err != nil {
+		return err
 	}
 	out.Flush()
 	if err := out.Error(); err != nil {
@@ -0,0 +1,201 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package concat implements a command to merge
+// several GBIF occurrence tables.
+package concat
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `concat [-o|--output <file>]
+	<file>...`,
+	Short: "merge occurrence tables",
+	Long: `
+Command concat reads one or more GBIF occurrence tables, given as arguments,
+and merges them into a single table, so downloads made at different times can
+be combined.
+
+The tables can have different columns. The header of the merged table is the
+union of the headers of the tables (column names are compared ignoring case),
+in the order in which they are first found. Fields of columns missing in a
+table are left empty. The columns missing in each table are reported in the
+standard error.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if len(args) == 0 {
+		return c.UsageError("expecting one or more table files")
+	}
+
+	header, cols, err := readHeaders(args)
+	if err != nil {
+		return err
+	}
+	reportMismatches(args, header, cols)
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	w := tsv.NewWriter(out)
+	w.Comma = '\t'
+	w.UseCRLF = true
+
+	if err := w.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	for i, a := range args {
+		if err := copyTable(w, a, cols[i], len(header)); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+// readHeaders reads the headers of the tables
+// and returns the merged header,
+// and for each table,
+// the index of each of its columns
+// in the merged header.
+func readHeaders(names []string) ([]string, [][]int, error) {
+	var header []string
+	fields := make(map[string]int)
+	cols := make([][]int, len(names))
+	for i, name := range names {
+		h, err := readHeader(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		cols[i] = make([]int, len(h))
+		for j, c := range h {
+			lc := strings.ToLower(c)
+			p, ok := fields[lc]
+			if !ok {
+				p = len(header)
+				fields[lc] = p
+				header = append(header, c)
+			}
+			cols[i][j] = p
+		}
+	}
+	return header, cols, nil
+}
+
+func readHeader(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tab := tabfile.NewReader(f)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", name, err)
+	}
+	return header, nil
+}
+
+func reportMismatches(names []string, header []string, cols [][]int) {
+	for i, name := range names {
+		in := make([]bool, len(header))
+		for _, p := range cols[i] {
+			in[p] = true
+		}
+		var missing []string
+		for p, h := range header {
+			if !in[p] {
+				missing = append(missing, h)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		logger.Printf("table %q: missing columns: %s", name, strings.Join(missing, ", "))
+	}
+}
+
+func copyTable(w *tsv.Writer, name string, cols []int, size int) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tab := tabfile.NewReader(f)
+	tab.Comma = '\t'
+
+	// skip header
+	if _, err := tab.Read(); err != nil {
+		return fmt.Errorf("when reading %q header: %v", name, err)
+	}
+
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return fmt.Errorf("table %q: row %d: %v", name, ln, err)
+		}
+		logger.ReadRows(1)
+
+		nr := make([]string, size)
+		for i, v := range row {
+			nr[cols[i]] = v
+		}
+		if err := w.Write(nr); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+	return nil
+}
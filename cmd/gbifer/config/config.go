@@ -0,0 +1,336 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package config implements the reading
+// of the default values
+// used by the commands.
+//
+// The default values are read from a configuration file,
+// by default "gbifer/config.toml"
+// in the user configuration directory
+// (for example "~/.config/gbifer/config.toml"),
+// and from environment variables,
+// that take precedence over the values in the file.
+//
+// The configuration file uses a small subset of TOML:
+// key-value pairs,
+// tables (sections),
+// and comments.
+// For example:
+//
+//	# default taxonomy file
+//	taxonomy = "/home/user/data/taxonomy.tab"
+//	dataset-cache = "/home/user/data/datasets.tab"
+//
+//	[gbif]
+//	wait = "500ms"
+//	retry = 3
+//	timeout = "30s"
+//	proxy = "http://proxy.example.org:3128"
+//	user = "gbif-user"
+//	password = "secret"
+//
+//	[output]
+//	compression = "gzip"
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/gbif"
+)
+
+// EnvFile is the environment variable
+// used to define the configuration file.
+const EnvFile = "GBIFER_CONFIG"
+
+// A key is a valid configuration key.
+type key struct {
+	env string
+	set func(v string) error
+}
+
+var taxFile string
+var cacheFile string
+
+var keys = map[string]key{
+	"taxonomy": {
+		env: "GBIFER_TAXONOMY",
+		set: func(v string) error {
+			taxFile = v
+			return nil
+		},
+	},
+	"dataset-cache": {
+		env: "GBIFER_DATASET_CACHE",
+		set: func(v string) error {
+			cacheFile = v
+			return nil
+		},
+	},
+	"gbif.wait": {
+		env: "GBIFER_GBIF_WAIT",
+		set: func(v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return err
+			}
+			gbif.Wait = d
+			return nil
+		},
+	},
+	"gbif.retry": {
+		env: "GBIFER_GBIF_RETRY",
+		set: func(v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return err
+			}
+			if n < 1 {
+				return fmt.Errorf("invalid number of retries: %d", n)
+			}
+			gbif.Retry = n
+			return nil
+		},
+	},
+	"gbif.timeout": {
+		env: "GBIFER_GBIF_TIMEOUT",
+		set: func(v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return err
+			}
+			gbif.Timeout = d
+			return nil
+		},
+	},
+	"gbif.max-records": {
+		env: "GBIFER_GBIF_MAX_RECORDS",
+		set: func(v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return err
+			}
+			if n < 0 {
+				return fmt.Errorf("invalid number of records: %d", n)
+			}
+			gbif.MaxRecords = n
+			return nil
+		},
+	},
+	"gbif.proxy": {
+		env: "GBIFER_GBIF_PROXY",
+		set: func(v string) error {
+			if v == "" {
+				gbif.Proxy = nil
+				return nil
+			}
+			u, err := url.Parse(v)
+			if err != nil {
+				return err
+			}
+			switch u.Scheme {
+			case "http", "https", "socks5":
+			default:
+				return fmt.Errorf("invalid proxy scheme %q", u.Scheme)
+			}
+			if u.Host == "" {
+				return fmt.Errorf("proxy %q without host", v)
+			}
+			gbif.Proxy = u
+			return nil
+		},
+	},
+	"gbif.user": {
+		env: "GBIFER_GBIF_USER",
+		set: func(v string) error {
+			gbif.User = v
+			return nil
+		},
+	},
+	"gbif.password": {
+		env: "GBIFER_GBIF_PASSWORD",
+		set: func(v string) error {
+			gbif.Password = v
+			return nil
+		},
+	},
+	"gbif.record": {
+		env: "GBIFER_GBIF_RECORD",
+		set: func(v string) error {
+			if _, ok := gbif.Transport.(*gbif.Replayer); ok {
+				return errors.New("can not record while replaying")
+			}
+			gbif.Transport = gbif.NewRecorder(v, nil)
+			return nil
+		},
+	},
+	"gbif.replay": {
+		env: "GBIFER_GBIF_REPLAY",
+		set: func(v string) error {
+			if _, ok := gbif.Transport.(*gbif.Recorder); ok {
+				return errors.New("can not replay while recording")
+			}
+			gbif.Transport = gbif.NewReplayer(v)
+			return nil
+		},
+	},
+	"output.compression": {
+		env: "GBIFER_OUTPUT_COMPRESSION",
+		set: func(v string) error {
+			switch strings.ToLower(v) {
+			case "", "none":
+				tabfile.Gzip = false
+			case "gzip":
+				tabfile.Gzip = true
+			default:
+				return fmt.Errorf("invalid compression %q", v)
+			}
+			return nil
+		},
+	},
+}
+
+// Load reads the configuration file
+// and the environment variables.
+// If the configuration file does not exist,
+// only the environment variables will be read.
+func Load() error {
+	name := os.Getenv(EnvFile)
+	if name == "" {
+		dir, err := os.UserConfigDir()
+		if err == nil {
+			name = filepath.Join(dir, "gbifer", "config.toml")
+		}
+	}
+	if name != "" {
+		if err := readFile(name); err != nil {
+			return err
+		}
+	}
+
+	for k, v := range keys {
+		e, ok := os.LookupEnv(v.env)
+		if !ok {
+			continue
+		}
+		if err := v.set(e); err != nil {
+			return fmt.Errorf("environment variable %s: key %q: %v", v.env, k, err)
+		}
+	}
+	return nil
+}
+
+// Taxonomy returns the default taxonomy file.
+func Taxonomy() string {
+	return taxFile
+}
+
+// DatasetCache returns the default dataset cache file.
+func DatasetCache() string {
+	return cacheFile
+}
+
+func readFile(name string) error {
+	f, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var section string
+	s := bufio.NewScanner(f)
+	for ln := 1; s.Scan(); ln++ {
+		line := strings.TrimSpace(stripComment(s.Text()))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return fmt.Errorf("config %q: line %d: invalid table header %q", name, ln, line)
+			}
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("config %q: line %d: expecting key-value pair", name, ln)
+		}
+		k = strings.ToLower(strings.TrimSpace(k))
+		if section != "" {
+			k = section + "." + k
+		}
+		v, err := value(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("config %q: line %d: key %q: %v", name, ln, k, err)
+		}
+
+		key, ok := keys[k]
+		if !ok {
+			return fmt.Errorf("config %q: line %d: unknown key %q", name, ln, k)
+		}
+		if err := key.set(v); err != nil {
+			return fmt.Errorf("config %q: line %d: key %q: %v", name, ln, k, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("config %q: %v", name, err)
+	}
+	return nil
+}
+
+// stripComment removes a comment
+// that is not inside a string.
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"', r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// value returns the value of a TOML string,
+// integer,
+// or boolean.
+func value(v string) (string, error) {
+	if v == "" {
+		return "", errors.New("empty value")
+	}
+	switch v[0] {
+	case '"':
+		s, err := strconv.Unquote(v)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", v)
+		}
+		return s, nil
+	case '\'':
+		if len(v) < 2 || v[len(v)-1] != '\'' {
+			return "", fmt.Errorf("invalid string %s", v)
+		}
+		return v[1 : len(v)-1], nil
+	}
+	return v, nil
+}
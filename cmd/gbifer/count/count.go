@@ -0,0 +1,366 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package count implements a command to count
+// the records of each taxon
+// in a GBIF occurrence table.
+package count
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/config"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/taxonomy"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `count [--by <rank>] [--tax <file>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "count records per taxon",
+	Long: `
+Command count reads a GBIF occurrence table from the standard input and prints
+the number of records of each taxon, sorted from the taxon with more records
+to the taxon with fewer records.
+
+By default, the records are counted by species. Use the flag --by to define a
+different rank. Valid ranks are: kingdom, phylum, class, order, family, genus,
+and species.
+
+By default, the taxon of each record is taken from the column of the rank in
+the occurrence table (for example, the "family" column). If the flag --tax is
+defined, the indicated taxonomy file will be used to resolve the taxon of each
+record (using the "speciesKey" and "taxonKey" columns): synonyms are resolved
+to their accepted names, and the taxon of the indicated rank that contains
+the accepted taxon is used. If a default taxonomy is defined in the
+configuration (see "gbifer help"), it will be used as the value of --tax.
+
+The output is a TSV table with the following columns:
+
+	- taxon: the name of the taxon.
+	- taxonKey: the GBIF ID of the taxon (only when using a taxonomy).
+	- records: the number of records of the taxon.
+
+The number of records without a taxon of the indicated rank is reported in
+the standard error.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+var byFlag string
+var taxFile string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().StringVar(&byFlag, "by", "species", "")
+	c.Flags().StringVar(&taxFile, "tax", config.Taxonomy(), "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	rank := taxonomy.GetRank(byFlag)
+	if rank == taxonomy.Unranked {
+		return c.UsageError(fmt.Sprintf("invalid rank %q", byFlag))
+	}
+
+	var tx *taxonomy.Taxonomy
+	if taxFile != "" {
+		tx, err = readTaxonomy()
+		if err != nil {
+			return err
+		}
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	var counts []*taxCount
+	var missing int
+	if tx != nil {
+		counts, missing, err = countByTaxonomy(in, tx, rank)
+	} else {
+		counts, missing, err = countByColumn(in, rank)
+	}
+	if err != nil {
+		return err
+	}
+	if missing > 0 {
+		logger.Printf("%d records without %s", missing, rank)
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := writeCounts(out, counts, tx != nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+func readTaxonomy() (*taxonomy.Taxonomy, error) {
+	f, err := os.Open(taxFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tx, err := taxonomy.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", taxFile, err)
+	}
+	return tx, nil
+}
+
+// A taxCount is the number of records
+// of a taxon.
+type taxCount struct {
+	name    string
+	id      int64
+	records int
+}
+
+func countByColumn(r io.Reader, rank taxonomy.Rank) ([]*taxCount, int, error) {
+	tab := tabfile.NewReader(r, rank.String())
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, 0, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+	col := -1
+	for i, h := range header {
+		if strings.ToLower(h) == rank.String() {
+			col = i
+		}
+	}
+	if col < 0 {
+		return nil, 0, fmt.Errorf("input data %q without %q field", input, rank.String())
+	}
+
+	counts := make(map[string]*taxCount)
+	missing := 0
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, 0, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		name := strings.Join(strings.Fields(row[col]), " ")
+		if name == "" {
+			missing++
+			continue
+		}
+		tc, ok := counts[name]
+		if !ok {
+			if err := memory.Add(int64(len(name)) + 64); err != nil {
+				return nil, 0, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			}
+			tc = &taxCount{name: name}
+			counts[name] = tc
+		}
+		tc.records++
+	}
+	return sortCounts(counts), missing, nil
+}
+
+func countByTaxonomy(r io.Reader, tx *taxonomy.Taxonomy, rank taxonomy.Rank) ([]*taxCount, int, error) {
+	tab := tabfile.NewReader(r, "speciesKey", "taxonKey")
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, 0, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+	keyCol := -1
+	taxCol := -1
+	for i, h := range header {
+		h = strings.ToLower(h)
+		if h == "specieskey" {
+			keyCol = i
+		}
+		if h == "taxonkey" {
+			taxCol = i
+		}
+	}
+	if keyCol < 0 && taxCol < 0 {
+		return nil, 0, fmt.Errorf("input data %q without %q or %q fields", input, "speciesKey", "taxonKey")
+	}
+
+	counts := make(map[string]*taxCount)
+	cache := make(map[int64]taxonomy.Taxon)
+	missing := 0
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, 0, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		id := rowKey(row, keyCol, taxCol)
+		tax, ok := cache[id]
+		if !ok {
+			if err := memory.Add(64); err != nil {
+				return nil, 0, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			}
+			tax = ranked(tx, id, rank)
+			cache[id] = tax
+		}
+		if tax.ID == 0 {
+			missing++
+			continue
+		}
+
+		key := strconv.FormatInt(tax.ID, 10)
+		tc, ok := counts[key]
+		if !ok {
+			if err := memory.Add(int64(len(key)+len(tax.Name)) + 64); err != nil {
+				return nil, 0, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			}
+			tc = &taxCount{name: tax.Name, id: tax.ID}
+			counts[key] = tc
+		}
+		tc.records++
+	}
+	return sortCounts(counts), missing, nil
+}
+
+// ranked returns the taxon of the given rank
+// that contains the accepted taxon
+// of the given ID.
+func ranked(tx *taxonomy.Taxonomy, id int64, rank taxonomy.Rank) taxonomy.Taxon {
+	if id == 0 {
+		return taxonomy.Taxon{}
+	}
+	tax := tx.AcceptedAndRanked(id)
+	if tax.ID == 0 || tax.Rank < rank {
+		return taxonomy.Taxon{}
+	}
+	if tax.Rank == rank {
+		return tax
+	}
+	for _, p := range tx.Parents(tax.ID) {
+		if p.Rank == rank {
+			return p
+		}
+	}
+	return taxonomy.Taxon{}
+}
+
+func sortCounts(counts map[string]*taxCount) []*taxCount {
+	ls := make([]*taxCount, 0, len(counts))
+	for _, tc := range counts {
+		ls = append(ls, tc)
+	}
+	slices.SortFunc(ls, func(a, b *taxCount) int {
+		if a.records != b.records {
+			return b.records - a.records
+		}
+		return strings.Compare(a.name, b.name)
+	})
+	return ls
+}
+
+func writeCounts(w io.Writer, counts []*taxCount, withKey bool) error {
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	header := []string{"taxon", "records"}
+	if withKey {
+		header = []string{"taxon", "taxonKey", "records"}
+	}
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	for _, tc := range counts {
+		row := []string{tc.name, strconv.Itoa(tc.records)}
+		if withKey {
+			row = []string{tc.name, strconv.FormatInt(tc.id, 10), strconv.Itoa(tc.records)}
+		}
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+// rowKey returns the taxon ID of an occurrence row,
+// or 0 if the row has no valid ID.
+func rowKey(row []string, keyCol, taxCol int) int64 {
+	var key string
+	if keyCol >= 0 {
+		key = row[keyCol]
+		if key == "" {
+			return 0
+		}
+	}
+	if taxCol >= 0 {
+		key = row[taxCol]
+	}
+	id, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
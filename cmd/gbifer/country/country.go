@@ -18,12 +18,15 @@ import (
 	"strings"
 
 	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/config"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
 	"github.com/js-arias/gbifer/taxonomy"
 	"github.com/js-arias/gbifer/tsv"
 )
 
 var Command = &command.Command{
-	Usage: `country [--tax <file>]
+	Usage: `country [--tax <file>] [--dwca <file>] [--native]
 	[-i|--input <file>] [-o|--output <file>]`,
 	Short: "create a taxon-country table",
 	Long: `
@@ -38,7 +41,22 @@ A country table has the following columns:
 	- country: name of the country
 
 If the flag --tax is given with a file, a taxonomy will be read from the file,
-and only the records that match the taxonomy will be selected.
+and only the records that match the taxonomy will be selected. If a default
+taxonomy is defined in the configuration (see "gbifer help"), it will be used
+as the value of --tax.
+
+If the flag --dwca is given with a checklist Darwin Core Archive (either a zip
+file, or a directory with the uncompressed archive), the table will be built
+from the distribution extension of the archive, instead of an occurrence
+table. The names of the taxa are taken from the taxon core of the archive, and
+the countries from the "countryCode" field of the distribution, or, if it is
+empty, from a "locationID" field with the form "ISO:<code>" (or
+"ISO3166-1:<code>"). Distributions in which the taxon is absent, doubtful, or
+excluded (as defined in the "occurrenceStatus" field) are ignored. If the flag
+--native is defined, only the distributions in which the taxon is native (as
+defined in the "establishmentMeans" field) will be used. If a taxonomy is
+given, names of the archive are mapped to the ranked and accepted names of the
+taxonomy, and names not in the taxonomy are ignored.
 
 By default, it will read the data from the standard input; use the flag
 --input, or -i, to select a particular file.
@@ -53,16 +71,24 @@ By default, the results will be printed in the standard output; use the flag
 var input string
 var output string
 var taxFile string
+var dwcaFile string
+var nativeFlag bool
 
 func setFlags(c *command.Command) {
 	c.Flags().StringVar(&input, "input", "", "")
 	c.Flags().StringVar(&input, "i", "", "")
 	c.Flags().StringVar(&output, "output", "", "")
 	c.Flags().StringVar(&output, "o", "", "")
-	c.Flags().StringVar(&taxFile, "tax", "", "")
+	c.Flags().StringVar(&taxFile, "tax", config.Taxonomy(), "")
+	c.Flags().StringVar(&dwcaFile, "dwca", "", "")
+	c.Flags().BoolVar(&nativeFlag, "native", false, "")
 }
 
 func run(c *command.Command, args []string) (err error) {
+	if nativeFlag && dwcaFile == "" {
+		return c.UsageError("flag --native requires --dwca")
+	}
+
 	in := c.Stdin()
 	if input != "" {
 		f, err := os.Open(input)
@@ -84,15 +110,20 @@ func run(c *command.Command, args []string) (err error) {
 		}
 	}
 
-	tc, err := readTable(in, tx)
+	var tc map[int64]*taxCountry
+	if dwcaFile != "" {
+		tc, err = readDistribution(dwcaFile, tx)
+	} else {
+		tc, err = readTable(in, tx)
+	}
 	if err != nil {
 		return err
 	}
 
 	out := c.Stdout()
 	if output != "" {
-		var f *os.File
-		f, err = os.Create(output)
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
 		if err != nil {
 			return err
 		}
@@ -133,7 +164,7 @@ type taxCountry struct {
 }
 
 func readTable(r io.Reader, tx *taxonomy.Taxonomy) (map[int64]*taxCountry, error) {
-	tab := tsv.NewReader(r)
+	tab := tabfile.NewReader(r, "speciesKey", "taxonKey", "countryCode", "species")
 	tab.Comma = '\t'
 
 	header, err := tab.Read()
@@ -177,6 +208,7 @@ func readTable(r io.Reader, tx *taxonomy.Taxonomy) (map[int64]*taxCountry, error
 		if err != nil {
 			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
 		}
+		logger.ReadRows(1)
 
 		var key string
 		if keyCol >= 0 {
@@ -290,6 +322,7 @@ func writeCountryTable(w io.Writer, cTax map[int64]*taxCountry) error {
 			if err := out.Write(row); err != nil {
 				return err
 			}
+			logger.WriteRows(1)
 		}
 	}
 
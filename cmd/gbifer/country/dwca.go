@@ -0,0 +1,362 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package country
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/taxonomy"
+)
+
+// Row types of a Darwin Core Archive.
+const (
+	taxonRowType        = "Taxon"
+	distributionRowType = "Distribution"
+)
+
+// An archive is a Darwin Core Archive,
+// either a zip file,
+// or a directory with the uncompressed archive.
+type archive struct {
+	path string
+	z    *zip.ReadCloser
+}
+
+func openArchive(name string) (*archive, error) {
+	st, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if st.IsDir() {
+		return &archive{path: name}, nil
+	}
+	z, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+	return &archive{path: name, z: z}, nil
+}
+
+func (a *archive) Close() error {
+	if a.z == nil {
+		return nil
+	}
+	return a.z.Close()
+}
+
+// open opens a file of the archive.
+func (a *archive) open(name string) (io.ReadCloser, error) {
+	if a.z == nil {
+		return os.Open(filepath.Join(a.path, filepath.FromSlash(name)))
+	}
+	for _, f := range a.z.File {
+		if f.Name == name || strings.EqualFold(path.Base(f.Name), path.Base(name)) {
+			return f.Open()
+		}
+	}
+	return nil, fmt.Errorf("file %q not found in archive", name)
+}
+
+// xmlArchive is the content
+// of the meta.xml file
+// of a Darwin Core Archive.
+type xmlArchive struct {
+	Core       xmlFile   `xml:"core"`
+	Extensions []xmlFile `xml:"extension"`
+}
+
+type xmlFile struct {
+	RowType    string     `xml:"rowType,attr"`
+	Terminated string     `xml:"fieldsTerminatedBy,attr"`
+	Enclosed   string     `xml:"fieldsEnclosedBy,attr"`
+	Ignore     int        `xml:"ignoreHeaderLines,attr"`
+	Location   string     `xml:"files>location"`
+	ID         *xmlIndex  `xml:"id"`
+	CoreID     *xmlIndex  `xml:"coreid"`
+	Fields     []xmlField `xml:"field"`
+}
+
+type xmlIndex struct {
+	Index int `xml:"index,attr"`
+}
+
+type xmlField struct {
+	Index   *int   `xml:"index,attr"`
+	Term    string `xml:"term,attr"`
+	Default string `xml:"default,attr"`
+}
+
+// termName returns the name of a term,
+// i.e., the last element of the term URI,
+// in lower case.
+func termName(term string) string {
+	if i := strings.LastIndexAny(term, "/#"); i >= 0 {
+		term = term[i+1:]
+	}
+	return strings.ToLower(term)
+}
+
+// readMeta reads the meta.xml file of an archive.
+func (a *archive) readMeta() (*xmlArchive, error) {
+	f, err := a.open("meta.xml")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var meta xmlArchive
+	if err := xml.NewDecoder(f).Decode(&meta); err != nil {
+		return nil, fmt.Errorf("meta.xml: %v", err)
+	}
+	return &meta, nil
+}
+
+// readRows reads the rows of a file of the archive
+// calling fn with each row,
+// and a function that returns the value of a term.
+// Key is the index of the ID column
+// (either the id of the core,
+// or the coreid of an extension).
+func (a *archive) readRows(xf xmlFile, key int, fn func(id string, val func(term string) string) error) error {
+	f, err := a.open(xf.Location)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tab := csv.NewReader(f)
+	tab.Comma = '\t'
+	switch t := strings.ReplaceAll(xf.Terminated, `\t`, "\t"); {
+	case t == "":
+	case utf8.RuneCountInString(t) == 1:
+		tab.Comma, _ = utf8.DecodeRuneInString(t)
+	default:
+		return fmt.Errorf("file %q: invalid field delimiter %q", xf.Location, xf.Terminated)
+	}
+	tab.FieldsPerRecord = -1
+	tab.LazyQuotes = true
+
+	fields := make(map[string]xmlField, len(xf.Fields))
+	for _, fd := range xf.Fields {
+		fields[termName(fd.Term)] = fd
+	}
+	var row []string
+	val := func(term string) string {
+		fd, ok := fields[term]
+		if !ok {
+			return ""
+		}
+		if fd.Index != nil && *fd.Index < len(row) {
+			if v := strings.TrimSpace(row[*fd.Index]); v != "" {
+				return v
+			}
+		}
+		return fd.Default
+	}
+
+	for i := 0; ; i++ {
+		row, err = tab.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return fmt.Errorf("file %q: row %d: %v", xf.Location, ln, err)
+		}
+		if i < xf.Ignore {
+			continue
+		}
+		if key >= len(row) {
+			continue
+		}
+		if err := fn(strings.TrimSpace(row[key]), val); err != nil {
+			return fmt.Errorf("file %q: row %d: %v", xf.Location, ln, err)
+		}
+	}
+}
+
+// absentStatus are the values of occurrenceStatus
+// of a distribution
+// in which the taxon is not present.
+var absentStatus = map[string]bool{
+	"absent":   true,
+	"doubtful": true,
+	"excluded": true,
+}
+
+// readDistribution reads the distribution extension
+// of a checklist Darwin Core Archive.
+func readDistribution(name string, tx *taxonomy.Taxonomy) (map[int64]*taxCountry, error) {
+	a, err := openArchive(name)
+	if err != nil {
+		return nil, err
+	}
+	defer a.Close()
+
+	meta, err := a.readMeta()
+	if err != nil {
+		return nil, fmt.Errorf("archive %q: %v", name, err)
+	}
+	if termName(meta.Core.RowType) != strings.ToLower(taxonRowType) || meta.Core.ID == nil {
+		return nil, fmt.Errorf("archive %q: not a checklist archive", name)
+	}
+	var dist *xmlFile
+	for i, ext := range meta.Extensions {
+		if termName(ext.RowType) == strings.ToLower(distributionRowType) && ext.CoreID != nil {
+			dist = &meta.Extensions[i]
+			break
+		}
+	}
+	if dist == nil {
+		return nil, fmt.Errorf("archive %q: without a distribution extension", name)
+	}
+
+	// names of the taxa in the checklist
+	names := make(map[string]string)
+	if err := a.readRows(meta.Core, meta.Core.ID.Index, func(id string, val func(string) string) error {
+		n := val("canonicalname")
+		if n == "" {
+			n = val("scientificname")
+			if au := val("scientificnameauthorship"); au != "" {
+				n = strings.TrimSuffix(n, au)
+			}
+			n = canonName(n)
+		}
+		names[id] = taxonomy.Canon(n)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("archive %q: %v", name, err)
+	}
+
+	// IDs of the names
+	// when there is no taxonomy
+	nameIDs := make(map[string]int64)
+
+	cTax := make(map[int64]*taxCountry)
+	if err := a.readRows(*dist, dist.CoreID.Index, func(id string, val func(string) string) error {
+		n := names[id]
+		if n == "" {
+			return nil
+		}
+		if absentStatus[strings.ToLower(val("occurrencestatus"))] {
+			return nil
+		}
+		if nativeFlag && !strings.HasPrefix(strings.ToLower(val("establishmentmeans")), "native") {
+			return nil
+		}
+
+		cc := strings.ToUpper(val("countrycode"))
+		if cc == "" {
+			cc = locationCode(val("locationid"))
+		}
+		if cc == "" {
+			return nil
+		}
+		if _, ok := iso3166[cc]; !ok {
+			logger.Printf("archive %q: taxon %q: invalid country code %q", name, n, cc)
+			return nil
+		}
+
+		var tid int64
+		if tx != nil {
+			tid = acceptedID(tx, n)
+			if tid == 0 {
+				return nil
+			}
+			n = tx.Taxon(tid).Name
+		} else {
+			var ok bool
+			tid, ok = nameIDs[n]
+			if !ok {
+				tid = int64(len(nameIDs) + 1)
+				nameIDs[n] = tid
+			}
+		}
+
+		tc, ok := cTax[tid]
+		if !ok {
+			tc = &taxCountry{
+				name:      n,
+				id:        tid,
+				countries: make(map[string]bool),
+			}
+			cTax[tid] = tc
+		}
+		tc.countries[cc] = true
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("archive %q: %v", name, err)
+	}
+	return cTax, nil
+}
+
+// acceptedID returns the ID
+// of the accepted and ranked taxon
+// of a name in a taxonomy.
+// It returns 0 if the name is not in the taxonomy,
+// or if the name is ambiguous.
+func acceptedID(tx *taxonomy.Taxonomy, name string) int64 {
+	var id int64
+	for _, v := range tx.ByName(name) {
+		a := tx.AcceptedAndRanked(v).ID
+		if a == 0 {
+			continue
+		}
+		if id != 0 && a != id {
+			logger.Printf("ambiguous taxon name %q", name)
+			return 0
+		}
+		id = a
+	}
+	return id
+}
+
+// locationCode returns the country code
+// of a location ID
+// in the form "ISO:XX",
+// or "ISO3166-1:XX".
+func locationCode(loc string) string {
+	i := strings.IndexByte(loc, ':')
+	if i < 0 || !strings.HasPrefix(strings.ToUpper(loc), "ISO") {
+		return ""
+	}
+	cc := strings.ToUpper(strings.TrimSpace(loc[i+1:]))
+	if len(cc) != 2 {
+		return ""
+	}
+	return cc
+}
+
+// canonName returns the canonical name
+// of a scientific name with authorship,
+// i.e., the genus,
+// and the following words in lower case.
+func canonName(name string) string {
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return ""
+	}
+	cn := words[:1]
+	for _, w := range words[1:] {
+		r, _ := utf8.DecodeRuneInString(w)
+		if !unicode.IsLower(r) {
+			break
+		}
+		cn = append(cn, w)
+	}
+	return strings.Join(cn, " ")
+}
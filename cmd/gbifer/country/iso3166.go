@@ -4,6 +4,8 @@
 
 package country
 
+import "strings"
+
 // Map of country codes
 // to country names.
 var iso3166 = map[string]string{
@@ -257,3 +259,11 @@ var iso3166 = map[string]string{
 	"ZM": "Zambia",
 	"ZW": "Zimbabwe",
 }
+
+// Name returns the name of a country
+// from its ISO 3166-1 alpha-2 code.
+// It returns an empty string
+// if the code is not a valid code.
+func Name(code string) string {
+	return iso3166[strings.ToUpper(code)]
+}
@@ -0,0 +1,364 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package coverage implements a command to compare
+// a taxonomy with a GBIF occurrence table.
+package coverage
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/config"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/taxonomy"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `coverage [--tax <file>] [--gaps]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "report the coverage of a taxonomy",
+	Long: `
+Command coverage reads a GBIF occurrence table from the standard input and
+compares it with a taxonomy, to report the accepted species of the taxonomy
+without records, and the taxa of the occurrence table that are not in the
+taxonomy.
+
+The taxonomy file is required and must be defined with the flag --tax, or as
+the default taxonomy of the configuration (see "gbifer help"). The taxon of
+each record is taken from the "speciesKey" and "taxonKey" columns, and
+synonyms are resolved to their accepted names.
+
+The output is a TSV table with the following columns:
+
+	type      either "species", for an accepted species of the taxonomy,
+	          or "absent", for a key of the occurrence table that is not
+	          in the taxonomy
+	taxonKey  the GBIF ID of the taxon
+	name      the name of the taxon (for absent keys, taken from the
+	          "species", or "scientificName", columns of the occurrence
+	          table)
+	records   the number of records of the taxon
+
+Species are sorted by name, and absent keys by the number of records. If the
+flag --gaps is defined, only species without records, and absent keys, will
+be printed.
+
+A summary with the number of species without records, and the number of
+records of absent keys, is reported in the standard error.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var gapsFlag bool
+var input string
+var output string
+var taxFile string
+
+func setFlags(c *command.Command) {
+	c.Flags().BoolVar(&gapsFlag, "gaps", false, "")
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().StringVar(&taxFile, "tax", config.Taxonomy(), "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if taxFile == "" {
+		return c.UsageError("flag --tax undefined")
+	}
+	tx, err := readTaxonomy()
+	if err != nil {
+		return err
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	cv, err := readTable(in, tx)
+	if err != nil {
+		return err
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := writeCoverage(out, tx, cv); err != nil {
+		return err
+	}
+	return nil
+}
+
+func readTaxonomy() (*taxonomy.Taxonomy, error) {
+	f, err := os.Open(taxFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tx, err := taxonomy.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", taxFile, err)
+	}
+	return tx, nil
+}
+
+// An absent is an occurrence key
+// that is not in the taxonomy.
+type absent struct {
+	key     int64
+	name    string
+	records int
+}
+
+// coverage stores the records
+// of each species of the taxonomy,
+// and the absent keys.
+type coverage struct {
+	species map[int64]int
+	absent  map[int64]*absent
+
+	// records of taxa
+	// without an accepted species
+	// (for example, records identified to genus)
+	noSpecies int
+}
+
+func readTable(r io.Reader, tx *taxonomy.Taxonomy) (*coverage, error) {
+	tab := tabfile.NewReader(r, "speciesKey", "taxonKey", "species", "scientificName")
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+
+	keyCol := -1
+	taxCol := -1
+	spCol := -1
+	nameCol := -1
+	for i, h := range header {
+		switch strings.ToLower(h) {
+		case "specieskey":
+			keyCol = i
+		case "taxonkey":
+			taxCol = i
+		case "species":
+			spCol = i
+		case "scientificname":
+			nameCol = i
+		}
+	}
+	if keyCol < 0 && taxCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q or %q fields", input, "speciesKey", "taxonKey")
+	}
+	if taxCol >= 0 || spCol < 0 {
+		spCol = nameCol
+	}
+
+	cv := &coverage{
+		species: make(map[int64]int),
+		absent:  make(map[int64]*absent),
+	}
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		id := rowKey(row, keyCol, taxCol)
+		if id == 0 {
+			continue
+		}
+
+		if tx.Taxon(id).ID != id {
+			a, ok := cv.absent[id]
+			if !ok {
+				if err := memory.Add(96); err != nil {
+					return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+				}
+				a = &absent{key: id}
+				cv.absent[id] = a
+			}
+			if a.name == "" && spCol >= 0 {
+				a.name = strings.Join(strings.Fields(row[spCol]), " ")
+				if err := memory.Add(int64(len(a.name))); err != nil {
+					return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+				}
+			}
+			a.records++
+			continue
+		}
+
+		tax := tx.AcceptedAndRanked(id)
+		if tax.ID == 0 || tax.Rank != taxonomy.Species {
+			cv.noSpecies++
+			continue
+		}
+		if _, ok := cv.species[tax.ID]; !ok {
+			if err := memory.Add(16); err != nil {
+				return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			}
+		}
+		cv.species[tax.ID]++
+	}
+	return cv, nil
+}
+
+// rowKey returns the taxon ID of an occurrence row,
+// or 0 if the row has no valid ID.
+func rowKey(row []string, keyCol, taxCol int) int64 {
+	var key string
+	if keyCol >= 0 {
+		key = row[keyCol]
+		if key == "" {
+			return 0
+		}
+	}
+	if taxCol >= 0 {
+		key = row[taxCol]
+	}
+	id, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+func writeCoverage(w io.Writer, tx *taxonomy.Taxonomy, cv *coverage) error {
+	var spp []taxonomy.Taxon
+	for _, id := range tx.IDs() {
+		tax := tx.Taxon(id)
+		if tax.Status != "accepted" || tax.Rank != taxonomy.Species {
+			continue
+		}
+		spp = append(spp, tax)
+	}
+	slices.SortFunc(spp, func(a, b taxonomy.Taxon) int {
+		if c := cmp.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	abs := make([]*absent, 0, len(cv.absent))
+	var absRecs int
+	for _, a := range cv.absent {
+		abs = append(abs, a)
+		absRecs += a.records
+	}
+	slices.SortFunc(abs, func(a, b *absent) int {
+		if c := cmp.Compare(b.records, a.records); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.key, b.key)
+	})
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	header := []string{
+		"type",
+		"taxonKey",
+		"name",
+		"records",
+	}
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	var zero int
+	for _, sp := range spp {
+		n := cv.species[sp.ID]
+		if n == 0 {
+			zero++
+		} else if gapsFlag {
+			continue
+		}
+		row := []string{
+			"species",
+			strconv.FormatInt(sp.ID, 10),
+			sp.Name,
+			strconv.Itoa(n),
+		}
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+	for _, a := range abs {
+		row := []string{
+			"absent",
+			strconv.FormatInt(a.key, 10),
+			a.name,
+			strconv.Itoa(a.records),
+		}
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	logger.Printf("%d of %d species without records", zero, len(spp))
+	if len(abs) > 0 {
+		logger.Printf("%d keys not in taxonomy, with %d records", len(abs), absRecs)
+	}
+	if cv.noSpecies > 0 {
+		logger.Printf("%d records without an accepted species", cv.noSpecies)
+	}
+	return nil
+}
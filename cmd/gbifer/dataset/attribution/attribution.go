@@ -0,0 +1,344 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package attribution implements a command to add
+// the dataset attribution
+// to each record of a GBIF occurrence table.
+package attribution
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/config"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `attribution [--cache <file>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "add dataset attribution columns",
+	Long: `
+Command attribution reads a GBIF occurrence table from the standard input,
+and for each distinct dataset (as defined in the "datasetKey" column)
+retrieves the dataset information from GBIF. Then it adds the following
+columns to each record:
+
+	- datasetTitle: the title of the dataset.
+	- datasetLicense: the license of the dataset.
+	- datasetCitation: the citation of the dataset.
+	- datasetDOI: the DOI of the dataset.
+
+If the flag --cache is defined, the indicated file will be used to store the
+retrieved datasets, so the next runs will only query the datasets not found in
+the cache. The cache is a TSV file with the columns "datasetKey", "title",
+"license", "citation", and "doi". If a default dataset cache is defined in
+the configuration (see "gbifer help"), it will be used as the value of
+--cache.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+
+This command requires an internet connection.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+var cacheFile string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().StringVar(&cacheFile, "cache", config.DatasetCache(), "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	dc, err := readCache()
+	if err != nil {
+		return err
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	gbif.Open()
+	if err := addDatasets(in, out, dc); err != nil {
+		return err
+	}
+
+	if err := dc.write(); err != nil {
+		return err
+	}
+	return nil
+}
+
+var cols = []string{
+	"datasetTitle",
+	"datasetLicense",
+	"datasetCitation",
+	"datasetDOI",
+}
+
+func addDatasets(r io.Reader, w io.Writer, dc *cache) error {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return fmt.Errorf("when reading %q header: %v", input, err)
+	}
+	dsCol := -1
+	for i, h := range header {
+		if strings.ToLower(h) == "datasetkey" {
+			dsCol = i
+		}
+	}
+	if dsCol < 0 {
+		return fmt.Errorf("input data %q without %q field", input, "datasetKey")
+	}
+	nCols := len(header)
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	header = append(header, cols...)
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		vals := make([]string, len(cols))
+		if key := strings.TrimSpace(row[dsCol]); key != "" {
+			ds, err := dc.dataset(key)
+			if err != nil {
+				return err
+			}
+			vals = ds.values()
+		}
+		row = append(row[:nCols:nCols], vals...)
+
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+// A dataset is the attribution information
+// of a dataset.
+type dataset struct {
+	title    string
+	license  string
+	citation string
+	doi      string
+}
+
+func (ds dataset) values() []string {
+	return []string{
+		ds.title,
+		ds.license,
+		ds.citation,
+		ds.doi,
+	}
+}
+
+// A cache is a cache of datasets.
+type cache struct {
+	ds      map[string]dataset
+	changed bool
+}
+
+// dataset returns a dataset from the cache,
+// or from GBIF,
+// if it is not in the cache.
+func (dc *cache) dataset(key string) (dataset, error) {
+	if ds, ok := dc.ds[key]; ok {
+		return ds, nil
+	}
+
+	var ds dataset
+	gd, err := gbif.DatasetKey(key)
+	if err != nil && !errors.Is(err, gbif.ErrNotFound) {
+		return ds, err
+	}
+	if gd != nil {
+		ds = dataset{
+			title:    strings.Join(strings.Fields(gd.Title), " "),
+			license:  gd.License,
+			citation: strings.Join(strings.Fields(gd.Citation.Text), " "),
+			doi:      gd.DOI,
+		}
+	}
+	dc.ds[key] = ds
+	dc.changed = true
+	return ds, nil
+}
+
+var cacheCols = []string{
+	"datasetKey",
+	"title",
+	"license",
+	"citation",
+	"doi",
+}
+
+func readCache() (*cache, error) {
+	dc := &cache{
+		ds: make(map[string]dataset),
+	}
+	if cacheFile == "" {
+		return dc, nil
+	}
+
+	f, err := os.Open(cacheFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return dc, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tab := tsv.NewReader(f)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cache file %q: header: %v", cacheFile, err)
+	}
+	fields := make(map[string]int, len(header))
+	for i, h := range header {
+		fields[strings.ToLower(h)] = i
+	}
+	for _, h := range cacheCols {
+		if _, ok := fields[strings.ToLower(h)]; !ok {
+			return nil, fmt.Errorf("cache file %q: without %q field", cacheFile, h)
+		}
+	}
+
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("cache file %q: row %d: %v", cacheFile, ln, err)
+		}
+
+		key := strings.TrimSpace(row[fields["datasetkey"]])
+		if key == "" {
+			continue
+		}
+		dc.ds[key] = dataset{
+			title:    row[fields["title"]],
+			license:  row[fields["license"]],
+			citation: row[fields["citation"]],
+			doi:      row[fields["doi"]],
+		}
+	}
+	return dc, nil
+}
+
+func (dc *cache) write() (err error) {
+	if cacheFile == "" || !dc.changed {
+		return nil
+	}
+
+	f, err := os.Create(cacheFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		e := f.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	w := tsv.NewWriter(f)
+	w.Comma = '\t'
+	w.UseCRLF = true
+
+	if err := w.Write(cacheCols); err != nil {
+		return fmt.Errorf("when writing on %q: %v", cacheFile, err)
+	}
+	keys := make([]string, 0, len(dc.ds))
+	for k := range dc.ds {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		row := append([]string{k}, dc.ds[k].values()...)
+		if err := w.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", cacheFile, err)
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", cacheFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package dataset is a metapackage for commands
+// that dealt with GBIF datasets.
+package dataset
+
+import (
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/dataset/attribution"
+	"github.com/js-arias/gbifer/cmd/gbifer/dataset/search"
+	"github.com/js-arias/gbifer/cmd/gbifer/dataset/summary"
+)
+
+var Command = &command.Command{
+	Usage: "dataset <command> [<argument>...]",
+	Short: "commands for GBIF datasets",
+}
+
+func init() {
+	Command.Add(attribution.Command)
+	Command.Add(search.Command)
+	Command.Add(summary.Command)
+}
@@ -0,0 +1,134 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package search implements a command to search
+// datasets in GBIF.
+package search
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `search [--type <type>] [--publisher <key>]
+	[-o|--output <file>] [<text>...]`,
+	Short: "search datasets in GBIF",
+	Long: `
+Command search retrieves from GBIF the datasets that match a search, and
+prints them as a TSV file with the columns "datasetKey", "title", "type",
+"publisher", "publisherKey", "doi", and "license". The dataset keys can be
+used, for example, to select the records of particular datasets.
+
+The arguments of the command define a full text search (for example, the
+name of a herbarium). The flag --type defines the type of the datasets
+(OCCURRENCE, CHECKLIST, METADATA, or SAMPLING_EVENT), and the flag
+--publisher defines the key of the publishing organization. At least a search
+text, or one of the flags, is required.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+
+This command requires an internet connection.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var output string
+var dsType string
+var publisher string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().StringVar(&dsType, "type", "", "")
+	c.Flags().StringVar(&publisher, "publisher", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	search := strings.Join(args, " ")
+	if search == "" && dsType == "" && publisher == "" {
+		return c.UsageError("expecting a search text, or flags --type or --publisher")
+	}
+	switch strings.ToUpper(dsType) {
+	case "", "OCCURRENCE", "CHECKLIST", "METADATA", "SAMPLING_EVENT":
+	default:
+		return c.UsageError(fmt.Sprintf("unknown dataset type %q", dsType))
+	}
+
+	gbif.Open()
+	ls, err := gbif.DatasetSearch(search, dsType, publisher)
+	if err != nil {
+		return err
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := writeSearch(out, ls); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+var searchCols = []string{
+	"datasetKey",
+	"title",
+	"type",
+	"publisher",
+	"publisherKey",
+	"doi",
+	"license",
+}
+
+func writeSearch(w io.Writer, ls []*gbif.Dataset) error {
+	tab := tsv.NewWriter(w)
+	tab.Comma = '\t'
+	tab.UseCRLF = true
+
+	if err := tab.Write(searchCols); err != nil {
+		return err
+	}
+	for _, ds := range ls {
+		row := []string{
+			ds.Key,
+			strings.Join(strings.Fields(ds.Title), " "),
+			ds.Type,
+			strings.Join(strings.Fields(ds.PublishingOrganizationTitle), " "),
+			ds.PublishingOrganizationKey,
+			ds.DOI,
+			ds.License,
+		}
+		if err := tab.Write(row); err != nil {
+			return err
+		}
+		logger.WriteRows(1)
+	}
+
+	tab.Flush()
+	return tab.Error()
+}
@@ -0,0 +1,312 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package summary implements a command to summarize
+// the records of each dataset
+// of a GBIF occurrence table.
+package summary
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `summary [--titles] [-i|--input <file>] [-o|--output <file>]`,
+	Short: "summarize the records by dataset",
+	Long: `
+Command summary reads a GBIF occurrence table from the standard input, and
+summarizes the records by dataset (as defined in the "datasetKey" column). It
+prints a TSV file with the columns "datasetKey", "records" (the number of
+records), "species" (the number of distinct species, using the "speciesKey"
+or "species" column), "license" (the distinct licenses of the records,
+separated by semicolons), and "basisOfRecord" (the number of records of each
+basis of record, for example, "PRESERVED_SPECIMEN:10;HUMAN_OBSERVATION:3").
+The datasets are sorted by the number of records.
+
+If the flag --titles is defined, the title of the dataset, and the title of
+its publishing organization, will be retrieved from GBIF and added as the
+columns "title" and "publisher".
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+
+Only with the flag --titles, this command requires an internet connection.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+var titles bool
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().BoolVar(&titles, "titles", false, "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	ds, err := readSummary(in)
+	if err != nil {
+		return err
+	}
+
+	if titles {
+		gbif.Open()
+		if err := addTitles(ds); err != nil {
+			return err
+		}
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := writeSummary(out, ds); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+// A dsSummary is the summary
+// of the records of a dataset.
+type dsSummary struct {
+	key       string
+	records   int
+	species   map[string]bool
+	licenses  map[string]bool
+	basis     map[string]int
+	title     string
+	publisher string
+}
+
+func readSummary(r io.Reader) ([]*dsSummary, error) {
+	tab := tabfile.NewReader(r, "datasetKey", "speciesKey", "species", "license", "basisOfRecord")
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+	fields := make(map[string]int, len(header))
+	for i, h := range header {
+		fields[strings.ToLower(h)] = i
+	}
+	dsCol, ok := fields["datasetkey"]
+	if !ok {
+		return nil, fmt.Errorf("input data %q without %q field", input, "datasetKey")
+	}
+	spCol, ok := fields["specieskey"]
+	if !ok {
+		spCol, ok = fields["species"]
+		if !ok {
+			spCol = -1
+		}
+	}
+	licCol, ok := fields["license"]
+	if !ok {
+		licCol = -1
+	}
+	basisCol, ok := fields["basisofrecord"]
+	if !ok {
+		basisCol = -1
+	}
+
+	val := func(row []string, col int) string {
+		if col < 0 || col >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[col])
+	}
+
+	datasets := make(map[string]*dsSummary)
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		key := val(row, dsCol)
+		if key == "" {
+			continue
+		}
+		ds, ok := datasets[key]
+		if !ok {
+			ds = &dsSummary{
+				key:      key,
+				species:  make(map[string]bool),
+				licenses: make(map[string]bool),
+				basis:    make(map[string]int),
+			}
+			datasets[key] = ds
+		}
+		ds.records++
+		if sp := val(row, spCol); sp != "" {
+			ds.species[sp] = true
+		}
+		if lic := val(row, licCol); lic != "" {
+			ds.licenses[lic] = true
+		}
+		if b := val(row, basisCol); b != "" {
+			ds.basis[strings.ToUpper(b)]++
+		}
+	}
+
+	ls := make([]*dsSummary, 0, len(datasets))
+	for _, ds := range datasets {
+		ls = append(ls, ds)
+	}
+	slices.SortFunc(ls, func(a, b *dsSummary) int {
+		if c := cmp.Compare(b.records, a.records); c != 0 {
+			return c
+		}
+		return strings.Compare(a.key, b.key)
+	})
+	return ls, nil
+}
+
+// addTitles retrieves from GBIF
+// the title and publisher of each dataset.
+func addTitles(ls []*dsSummary) error {
+	orgs := make(map[string]string)
+	for _, ds := range ls {
+		gd, err := gbif.DatasetKey(ds.key)
+		if errors.Is(err, gbif.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		ds.title = strings.Join(strings.Fields(gd.Title), " ")
+
+		pk := gd.PublishingOrganizationKey
+		if pk == "" {
+			continue
+		}
+		if p, ok := orgs[pk]; ok {
+			ds.publisher = p
+			continue
+		}
+		org, err := gbif.OrganizationKey(pk)
+		if err != nil && !errors.Is(err, gbif.ErrNotFound) {
+			return err
+		}
+		if org != nil {
+			orgs[pk] = strings.Join(strings.Fields(org.Title), " ")
+		}
+		ds.publisher = orgs[pk]
+	}
+	return nil
+}
+
+var summaryCols = []string{
+	"datasetKey",
+	"records",
+	"species",
+	"license",
+	"basisOfRecord",
+}
+
+func writeSummary(w io.Writer, ls []*dsSummary) error {
+	tab := tsv.NewWriter(w)
+	tab.Comma = '\t'
+	tab.UseCRLF = true
+
+	header := summaryCols
+	if titles {
+		header = append(slices.Clip(header), "title", "publisher")
+	}
+	if err := tab.Write(header); err != nil {
+		return err
+	}
+	for _, ds := range ls {
+		lic := make([]string, 0, len(ds.licenses))
+		for l := range ds.licenses {
+			lic = append(lic, l)
+		}
+		slices.Sort(lic)
+
+		basis := make([]string, 0, len(ds.basis))
+		for b := range ds.basis {
+			basis = append(basis, b)
+		}
+		slices.SortFunc(basis, func(a, b string) int {
+			if c := cmp.Compare(ds.basis[b], ds.basis[a]); c != 0 {
+				return c
+			}
+			return strings.Compare(a, b)
+		})
+		for i, b := range basis {
+			basis[i] = b + ":" + strconv.Itoa(ds.basis[b])
+		}
+
+		row := []string{
+			ds.key,
+			strconv.Itoa(ds.records),
+			strconv.Itoa(len(ds.species)),
+			strings.Join(lic, ";"),
+			strings.Join(basis, ";"),
+		}
+		if titles {
+			row = append(row, ds.title, ds.publisher)
+		}
+		if err := tab.Write(row); err != nil {
+			return err
+		}
+		logger.WriteRows(1)
+	}
+
+	tab.Flush()
+	return tab.Error()
+}
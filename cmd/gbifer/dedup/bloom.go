@@ -0,0 +1,142 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package dedup
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+)
+
+// A bloom is a Bloom filter.
+type bloom struct {
+	bits []uint64
+	m    uint64 // number of bits
+	k    int    // number of hash functions
+}
+
+// newBloom returns a Bloom filter
+// for n elements
+// with a false positive rate of about 1%.
+func newBloom(n int) *bloom {
+	const p = 0.01
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := int(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &bloom{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// add adds a key to the filter,
+// and returns true
+// if the key was possibly already in the filter.
+func (b *bloom) add(k key) bool {
+	// double hashing
+	h1 := binary.LittleEndian.Uint64(k[:8])
+	h2 := binary.LittleEndian.Uint64(k[8:])
+	found := true
+	for i := 0; i < b.k; i++ {
+		bit := (h1 + uint64(i)*h2) % b.m
+		w, mask := bit/64, uint64(1)<<(bit%64)
+		if b.bits[w]&mask == 0 {
+			found = false
+			b.bits[w] |= mask
+		}
+	}
+	return found
+}
+
+func dedupBloom(r io.Reader, w io.Writer, cols []string) (int64, error) {
+	// first reading:
+	// find the possible duplicates
+	t, _, err := newTable(r, cols)
+	if err != nil {
+		return 0, err
+	}
+	b := newBloom(bloomFlag)
+	if err := memory.Add(int64(len(b.bits) * 8)); err != nil {
+		return 0, fmt.Errorf("bloom filter: %v", err)
+	}
+	candidates := make(map[key]bool)
+	for {
+		_, k, err := t.read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		logger.ReadRows(1)
+		if !b.add(k) {
+			continue
+		}
+		if _, ok := candidates[k]; ok {
+			continue
+		}
+		candidates[k] = false
+		if err := memory.Add(keySize); err != nil {
+			ln, _ := t.tab.FieldPos(0)
+			return 0, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+	}
+	b = nil
+
+	// second reading:
+	// remove the duplicates
+	f, err := os.Open(input)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	t, header, err := newTable(f, cols)
+	if err != nil {
+		return 0, err
+	}
+	out, err := newWriter(w, header)
+	if err != nil {
+		return 0, err
+	}
+
+	var dropped int64
+	for {
+		row, k, err := t.read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		if seen, ok := candidates[k]; ok {
+			if seen {
+				dropped++
+				continue
+			}
+			candidates[k] = true
+		}
+		if err := out.Write(row); err != nil {
+			return 0, fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+	if err := flush(out); err != nil {
+		return 0, err
+	}
+	return dropped, nil
+}
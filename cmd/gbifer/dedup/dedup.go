@@ -0,0 +1,279 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package dedup implements a command to remove duplicated rows
+// of a GBIF occurrence table.
+package dedup
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `dedup [--cols <list>] [--bloom <number>] [--spill <directory>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "remove duplicated rows",
+	Long: `
+Command dedup reads a GBIF occurrence table from the standard input and
+removes the duplicated rows, keeping the first row of each set of duplicates.
+The order of the rows is preserved.
+
+By default, two rows are duplicates if all of their values are equal. Use
+the flag --cols, with a comma separated list of columns, to compare only the
+values of the indicated columns (for example, --cols gbifID, to remove the
+records repeated in an aggregated table). Column names are case insensitive.
+
+Rows are compared using a 128-bit hash of their values, so only the hashes
+are kept in memory. By default, the hashes of all the distinct rows are kept
+in memory (about 50 bytes for each distinct row).
+
+If the flag --bloom is defined with the expected number of rows of the table,
+the table will be read twice. In the first reading, the hashes are added to a
+Bloom filter (about 1.2 bytes for each row), and only the hashes of the rows
+that are possible duplicates are kept in memory. In the second reading, the
+duplicated rows are removed. This option is useful if the table has few
+duplicates. It requires an input file.
+
+If the flag --spill is defined with a directory, the hashes will be stored in
+temporary files in that directory, and the memory used will be constant,
+regardless of the size of the table. The table will be read twice. This
+option requires an input file, and it can not be used with --bloom.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+var colsFlag string
+var bloomFlag int
+var spillDir string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().StringVar(&colsFlag, "cols", "", "")
+	c.Flags().IntVar(&bloomFlag, "bloom", 0, "")
+	c.Flags().StringVar(&spillDir, "spill", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if bloomFlag > 0 && spillDir != "" {
+		return c.UsageError("flags --bloom and --spill can not be used together")
+	}
+	if (bloomFlag > 0 || spillDir != "") && input == "" {
+		return c.UsageError("flags --bloom and --spill require an input file")
+	}
+
+	var cols []string
+	for _, v := range strings.Split(colsFlag, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		cols = append(cols, v)
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	var dropped int64
+	switch {
+	case bloomFlag > 0:
+		dropped, err = dedupBloom(in, out, cols)
+	case spillDir != "":
+		dropped, err = dedupSpill(in, out, cols)
+	default:
+		dropped, err = dedupMemory(in, out, cols)
+	}
+	if err != nil {
+		return err
+	}
+	logger.Add("dropped-duplicated", dropped)
+	return nil
+}
+
+// A key is the hash of the compared values
+// of a row.
+type key [16]byte
+
+// A table is a table
+// with the columns used to compare the rows.
+type table struct {
+	tab  *tsv.Reader
+	cols []int
+	h    hash.Hash
+	buf  []byte
+	rows int
+}
+
+func newTable(r io.Reader, names []string) (*table, []string, error) {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+
+	t := &table{tab: tab, h: fnv.New128a()}
+	for _, n := range names {
+		col := -1
+		for i, h := range header {
+			if strings.EqualFold(h, n) {
+				col = i
+				break
+			}
+		}
+		if col < 0 {
+			return nil, nil, fmt.Errorf("input data %q without %q field", input, n)
+		}
+		t.cols = append(t.cols, col)
+	}
+	if len(t.cols) == 0 {
+		for i := range header {
+			t.cols = append(t.cols, i)
+		}
+	}
+	return t, header, nil
+}
+
+// read returns the next row,
+// and the key of the row.
+func (t *table) read() ([]string, key, error) {
+	row, err := t.tab.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, key{}, io.EOF
+	}
+	if err != nil {
+		ln, _ := t.tab.FieldPos(0)
+		return nil, key{}, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+	}
+	t.rows++
+
+	// values are prefixed with its length
+	// so the key of "a", "bc"
+	// is different from the key of "ab", "c"
+	t.h.Reset()
+	for _, c := range t.cols {
+		t.buf = binary.AppendUvarint(t.buf[:0], uint64(len(row[c])))
+		t.buf = append(t.buf, row[c]...)
+		t.h.Write(t.buf)
+	}
+	var k key
+	t.h.Sum(k[:0])
+	return row, k, nil
+}
+
+func newWriter(w io.Writer, header []string) (*tsv.Writer, error) {
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	if err := out.Write(header); err != nil {
+		return nil, fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return out, nil
+}
+
+func flush(out *tsv.Writer) error {
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+// keySize is the approximate memory used
+// by a key stored in a map.
+const keySize = 48
+
+func dedupMemory(r io.Reader, w io.Writer, cols []string) (int64, error) {
+	t, header, err := newTable(r, cols)
+	if err != nil {
+		return 0, err
+	}
+	out, err := newWriter(w, header)
+	if err != nil {
+		return 0, err
+	}
+
+	var dropped int64
+	seen := make(map[key]bool)
+	for {
+		row, k, err := t.read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		logger.ReadRows(1)
+		if seen[k] {
+			dropped++
+			continue
+		}
+		seen[k] = true
+		if err := memory.Add(keySize); err != nil {
+			ln, _ := t.tab.FieldPos(0)
+			return 0, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		if err := out.Write(row); err != nil {
+			return 0, fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+	if err := flush(out); err != nil {
+		return 0, err
+	}
+	return dropped, nil
+}
@@ -0,0 +1,97 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package dedup_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/dedup"
+)
+
+// table returns a table of n rows
+// with many duplicated gbifIDs,
+// and the table expected
+// after removing the duplicated gbifIDs.
+func table(n int) (tab, want string) {
+	var in, out strings.Builder
+	header := "gbifID\tspecies\tlocality\r\n"
+	in.WriteString(header)
+	out.WriteString(header)
+
+	seen := make(map[int]bool)
+	for i := 0; i < n; i++ {
+		// a sequence that repeats the IDs
+		// far away from its first occurrence
+		id := (i * 7919) % (n / 3)
+		row := fmt.Sprintf("%d\tSpecies %d\tLocality %d\r\n", id, id%13, i)
+		in.WriteString(row)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		out.WriteString(row)
+	}
+	return in.String(), out.String()
+}
+
+func TestDedup(t *testing.T) {
+	tab, want := table(3000)
+	dir := t.TempDir()
+	input := filepath.Join(dir, "occ.tab")
+	if err := os.WriteFile(input, []byte(tab), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spillDir := filepath.Join(dir, "spill")
+	if err := os.Mkdir(spillDir, 0o755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := map[string][]string{
+		"memory": {"--cols", "gbifID"},
+		"spill":  {"--cols", "gbifID", "--spill", spillDir},
+		"bloom":  {"--cols", "gbifID", "--bloom", "3000"},
+
+		// a small filter has many false positives
+		"bloom false positives": {"--cols", "gbifID", "--bloom", "10"},
+	}
+
+	// force several runs
+	// in the spill files
+	defer dedup.SetRunSize(97)()
+
+	for name, args := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			dedup.Command.SetStdout(&buf)
+			if err := dedup.Command.Execute(append(args, "--input", input)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != want {
+				t.Errorf("output: got %d bytes, want %d bytes", len(got), len(want))
+				gl := strings.Split(got, "\r\n")
+				wl := strings.Split(want, "\r\n")
+				for i := range wl {
+					if i >= len(gl) || gl[i] != wl[i] {
+						t.Fatalf("line %d: got %q, want %q", i+1, gl[min(i, len(gl)-1)], wl[i])
+					}
+				}
+			}
+		})
+	}
+
+	files, err := os.ReadDir(spillDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) > 0 {
+		t.Errorf("spill directory: got %d files, want none", len(files))
+	}
+}
@@ -0,0 +1,15 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package dedup
+
+// SetRunSize sets the number of entries
+// of a sorted run of the spill files,
+// and returns a function
+// that restores the previous size.
+func SetRunSize(n int) (restore func()) {
+	prev := runSize
+	runSize = n
+	return func() { runSize = prev }
+}
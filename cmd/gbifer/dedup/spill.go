@@ -0,0 +1,303 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package dedup
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+)
+
+// runSize is the number of entries
+// sorted in memory
+// before they are written in a temporary file.
+var runSize = 1 << 20
+
+// An entry is the key of a row,
+// with the number of the row.
+type entry struct {
+	k   key
+	row uint64
+}
+
+const entrySize = 24
+
+func encodeEntry(b []byte, e entry) {
+	copy(b, e.k[:])
+	binary.LittleEndian.PutUint64(b[16:], e.row)
+}
+
+func decodeEntry(b []byte) entry {
+	var e entry
+	copy(e.k[:], b)
+	e.row = binary.LittleEndian.Uint64(b[16:])
+	return e
+}
+
+func compareEntry(a, b entry) int {
+	if c := bytes.Compare(a.k[:], b.k[:]); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.row, b.row)
+}
+
+func encodeRow(b []byte, row uint64) {
+	binary.LittleEndian.PutUint64(b, row)
+}
+
+func decodeRow(b []byte) uint64 {
+	return binary.LittleEndian.Uint64(b)
+}
+
+// spill stores sorted runs
+// in temporary files.
+type spill struct {
+	files []*os.File
+}
+
+// writeRun sorts a run,
+// and writes it in a new temporary file.
+func writeRun[T any](s *spill, run []T, size int, encode func([]byte, T), compare func(a, b T) int) error {
+	slices.SortFunc(run, compare)
+
+	f, err := os.CreateTemp(spillDir, "gbifer-dedup-*")
+	if err != nil {
+		return err
+	}
+	s.files = append(s.files, f)
+
+	w := bufio.NewWriter(f)
+	b := make([]byte, size)
+	for _, v := range run {
+		encode(b, v)
+		if _, err := w.Write(b); err != nil {
+			return fmt.Errorf("on file %q: %v", f.Name(), err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("on file %q: %v", f.Name(), err)
+	}
+	return nil
+}
+
+// remove removes the temporary files.
+func (s *spill) remove() {
+	for _, f := range s.files {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	s.files = nil
+}
+
+// A merger reads the values
+// of a set of sorted runs
+// in order.
+type merger[T any] struct {
+	runs    []*runReader[T]
+	compare func(a, b T) int
+}
+
+type runReader[T any] struct {
+	r   *bufio.Reader
+	b   []byte
+	v   T
+	dec func([]byte) T
+}
+
+func (r *runReader[T]) next() (bool, error) {
+	if _, err := io.ReadFull(r.r, r.b); err != nil {
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, err
+	}
+	r.v = r.dec(r.b)
+	return true, nil
+}
+
+func newMerger[T any](s *spill, size int, decode func([]byte) T, compare func(a, b T) int) (*merger[T], error) {
+	m := &merger[T]{compare: compare}
+	for _, f := range s.files {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("on file %q: %v", f.Name(), err)
+		}
+		r := &runReader[T]{
+			r:   bufio.NewReader(f),
+			b:   make([]byte, size),
+			dec: decode,
+		}
+		ok, err := r.next()
+		if err != nil {
+			return nil, fmt.Errorf("on file %q: %v", f.Name(), err)
+		}
+		if ok {
+			m.runs = append(m.runs, r)
+		}
+	}
+	heap.Init(m)
+	return m, nil
+}
+
+// next returns the next value.
+func (m *merger[T]) next() (T, bool, error) {
+	var v T
+	if len(m.runs) == 0 {
+		return v, false, nil
+	}
+	r := m.runs[0]
+	v = r.v
+	ok, err := r.next()
+	if err != nil {
+		return v, false, err
+	}
+	if ok {
+		heap.Fix(m, 0)
+	} else {
+		heap.Pop(m)
+	}
+	return v, true, nil
+}
+
+func (m *merger[T]) Len() int           { return len(m.runs) }
+func (m *merger[T]) Less(i, j int) bool { return m.compare(m.runs[i].v, m.runs[j].v) < 0 }
+func (m *merger[T]) Swap(i, j int)      { m.runs[i], m.runs[j] = m.runs[j], m.runs[i] }
+func (m *merger[T]) Push(x any)         { m.runs = append(m.runs, x.(*runReader[T])) }
+func (m *merger[T]) Pop() any {
+	r := m.runs[len(m.runs)-1]
+	m.runs = m.runs[:len(m.runs)-1]
+	return r
+}
+
+func dedupSpill(r io.Reader, w io.Writer, cols []string) (int64, error) {
+	// first reading:
+	// store the sorted keys
+	t, _, err := newTable(r, cols)
+	if err != nil {
+		return 0, err
+	}
+	keys := &spill{}
+	defer keys.remove()
+
+	run := make([]entry, 0, runSize)
+	for {
+		_, k, err := t.read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		logger.ReadRows(1)
+		run = append(run, entry{k: k, row: uint64(t.rows)})
+		if len(run) < runSize {
+			continue
+		}
+		if err := writeRun(keys, run, entrySize, encodeEntry, compareEntry); err != nil {
+			return 0, err
+		}
+		run = run[:0]
+	}
+	if err := writeRun(keys, run, entrySize, encodeEntry, compareEntry); err != nil {
+		return 0, err
+	}
+	run = nil
+
+	// find the duplicated rows
+	dups := &spill{}
+	defer dups.remove()
+
+	m, err := newMerger(keys, entrySize, decodeEntry, compareEntry)
+	if err != nil {
+		return 0, err
+	}
+	var prev entry
+	var dupRows []uint64
+	for i := 0; ; i++ {
+		e, ok, err := m.next()
+		if err != nil {
+			return 0, fmt.Errorf("when reading keys: %v", err)
+		}
+		if !ok {
+			break
+		}
+		if i > 0 && e.k == prev.k {
+			dupRows = append(dupRows, e.row)
+			if len(dupRows) >= runSize {
+				if err := writeRun(dups, dupRows, 8, encodeRow, cmp.Compare[uint64]); err != nil {
+					return 0, err
+				}
+				dupRows = dupRows[:0]
+			}
+		}
+		prev = e
+	}
+	if err := writeRun(dups, dupRows, 8, encodeRow, cmp.Compare[uint64]); err != nil {
+		return 0, err
+	}
+	dupRows = nil
+	keys.remove()
+
+	// second reading:
+	// remove the duplicates
+	f, err := os.Open(input)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	t, header, err := newTable(f, cols)
+	if err != nil {
+		return 0, err
+	}
+	out, err := newWriter(w, header)
+	if err != nil {
+		return 0, err
+	}
+
+	dm, err := newMerger(dups, 8, decodeRow, cmp.Compare[uint64])
+	if err != nil {
+		return 0, err
+	}
+	nextDup, ok, err := dm.next()
+	if err != nil {
+		return 0, fmt.Errorf("when reading duplicates: %v", err)
+	}
+
+	var dropped int64
+	for {
+		row, _, err := t.read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		if ok && uint64(t.rows) == nextDup {
+			dropped++
+			nextDup, ok, err = dm.next()
+			if err != nil {
+				return 0, fmt.Errorf("when reading duplicates: %v", err)
+			}
+			continue
+		}
+		if err := out.Write(row); err != nil {
+			return 0, fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+	if err := flush(out); err != nil {
+		return 0, err
+	}
+	return dropped, nil
+}
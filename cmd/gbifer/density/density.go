@@ -0,0 +1,432 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package density implements a command to aggregate
+// the records of a GBIF occurrence table
+// into square or hexagonal bins.
+package density
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+)
+
+var Command = &command.Command{
+	Usage: `density [--size <value>] [--hex] [--format <format>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "summarize record density in spatial bins",
+	Long: `
+Command density reads a GBIF occurrence table from the standard input and
+counts the number of records and species in each spatial bin.
+
+By default, the bins are squares of 1 degree of side. Use the flag --size to
+define a different size (in degrees). If the size does not divide the globe,
+the square bins of the last row, and the last column, are clipped to the
+limits of the globe. If the flag --hex is defined, hexagonal bins will be
+used, and the size will be the distance (in degrees) from the center of the
+hexagon to any of its vertices.
+
+Records without coordinates are ignored.
+
+By default the output is a CSV table with the following columns:
+
+	- bin: the identifier of the bin.
+	- longitude: the longitude of the center of the bin.
+	- latitude: the latitude of the center of the bin.
+	- records: the number of records in the bin.
+	- species: the number of species in the bin (as defined by the
+	  speciesKey column).
+
+Use the flag --format to define a different output format. Valid formats are:
+
+	csv      a comma separated table (the default)
+	geojson  a GeoJSON feature collection with the bin polygons
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var hexFlag bool
+var sizeFlag float64
+var formatFlag string
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().BoolVar(&hexFlag, "hex", false, "")
+	c.Flags().Float64Var(&sizeFlag, "size", 1, "")
+	c.Flags().StringVar(&formatFlag, "format", "csv", "")
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if sizeFlag <= 0 || sizeFlag > 90 {
+		return c.UsageError(fmt.Sprintf("invalid bin size %.6f", sizeFlag))
+	}
+	formatFlag = strings.ToLower(formatFlag)
+	if formatFlag != "csv" && formatFlag != "geojson" {
+		return c.UsageError(fmt.Sprintf("unknown format %q", formatFlag))
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	var grid binner = square{size: sizeFlag}
+	if hexFlag {
+		grid = hexagon{size: sizeFlag}
+	}
+	bins, err := readTable(in, grid)
+	if err != nil {
+		return err
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f *os.File
+		f, err = os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if formatFlag == "geojson" {
+		return writeGeoJSON(out, grid, bins)
+	}
+	return writeCSV(out, grid, bins)
+}
+
+// A binner assigns points to bins.
+type binner interface {
+	// bin returns the bin coordinates of a point.
+	bin(lat, lon float64) [2]int
+
+	// center returns the center of a bin.
+	center(b [2]int) (lat, lon float64)
+
+	// polygon returns the vertices of a bin
+	// as longitude, latitude pairs.
+	polygon(b [2]int) [][2]float64
+}
+
+// Square is a grid of squares of a given size in degrees.
+type square struct {
+	size float64
+}
+
+// gridSize returns the number of cells
+// of a given size
+// needed to cover a span of degrees.
+func gridSize(span, size float64) int {
+	// ignore the rounding errors of sizes
+	// that divide the span
+	return int(math.Ceil(span/size - 1e-9))
+}
+
+func (s square) bin(lat, lon float64) [2]int {
+	// the points at longitude 180,
+	// or at latitude -90,
+	// are in the last column,
+	// or row,
+	// of the grid
+	col := min(int(math.Floor((lon+180)/s.size)), gridSize(360, s.size)-1)
+	row := min(int(math.Floor((90-lat)/s.size)), gridSize(180, s.size)-1)
+	return [2]int{col, row}
+}
+
+// bounds returns the limits of a bin.
+// If the size does not divide the globe,
+// the bins of the last row and column
+// are clipped to the limits of the globe.
+func (s square) bounds(b [2]int) (north, south, west, east float64) {
+	west = -180 + float64(b[0])*s.size
+	north = 90 - float64(b[1])*s.size
+	east = math.Min(west+s.size, 180)
+	south = math.Max(north-s.size, -90)
+	return north, south, west, east
+}
+
+func (s square) center(b [2]int) (lat, lon float64) {
+	north, south, west, east := s.bounds(b)
+	return (north + south) / 2, (west + east) / 2
+}
+
+func (s square) polygon(b [2]int) [][2]float64 {
+	north, south, west, east := s.bounds(b)
+	return [][2]float64{
+		{west, north},
+		{west, south},
+		{east, south},
+		{east, north},
+		{west, north},
+	}
+}
+
+// Hexagon is a grid of pointy-top hexagons,
+// in axial coordinates,
+// with a given distance from the center to a vertex.
+type hexagon struct {
+	size float64
+}
+
+func (h hexagon) bin(lat, lon float64) [2]int {
+	q := (math.Sqrt(3)/3*lon - lat/3) / h.size
+	r := (2.0 / 3 * lat) / h.size
+
+	// cube rounding
+	x, z := q, r
+	y := -x - z
+	rx, ry, rz := math.Round(x), math.Round(y), math.Round(z)
+	dx, dy, dz := math.Abs(rx-x), math.Abs(ry-y), math.Abs(rz-z)
+	if dx > dy && dx > dz {
+		rx = -ry - rz
+	} else if dy <= dz {
+		rz = -rx - ry
+	}
+	return [2]int{int(rx), int(rz)}
+}
+
+func (h hexagon) center(b [2]int) (lat, lon float64) {
+	q, r := float64(b[0]), float64(b[1])
+	lon = h.size * math.Sqrt(3) * (q + r/2)
+	lat = h.size * 3 / 2 * r
+	return lat, lon
+}
+
+func (h hexagon) polygon(b [2]int) [][2]float64 {
+	lat, lon := h.center(b)
+	p := make([][2]float64, 0, 7)
+	for i := 0; i < 6; i++ {
+		a := (60*float64(i) + 30) * math.Pi / 180
+		p = append(p, [2]float64{lon + h.size*math.Cos(a), lat + h.size*math.Sin(a)})
+	}
+	p = append(p, p[0])
+	return p
+}
+
+type binData struct {
+	bin     [2]int
+	records int
+	species map[string]bool
+}
+
+func readTable(r io.Reader, grid binner) ([]*binData, error) {
+	tab := tabfile.NewReader(r, "decimalLatitude", "decimalLongitude", "speciesKey")
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+
+	latCol := -1
+	lonCol := -1
+	spCol := -1
+	for i, h := range header {
+		h = strings.ToLower(h)
+		if h == "decimallatitude" {
+			latCol = i
+		}
+		if h == "decimallongitude" {
+			lonCol = i
+		}
+		if h == "specieskey" {
+			spCol = i
+		}
+	}
+	if latCol < 0 || lonCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q or %q fields", input, "decimalLatitude", "decimalLongitude")
+	}
+
+	bins := make(map[[2]int]*binData)
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		if row[latCol] == "" || row[lonCol] == "" {
+			continue
+		}
+		lat, err := strconv.ParseFloat(row[latCol], 64)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: field %q: %v", input, ln, "decimalLatitude", err)
+		}
+		if lat < -90 || lat > 90 {
+			return nil, fmt.Errorf("table %q: row %d: field %q: invalid latitude: %.6f", input, ln, "decimalLatitude", lat)
+		}
+		lon, err := strconv.ParseFloat(row[lonCol], 64)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: field %q: %v", input, ln, "decimalLongitude", err)
+		}
+		if lon < -180 || lon > 180 {
+			return nil, fmt.Errorf("table %q: row %d: field %q: invalid longitude: %.6f", input, ln, "decimalLongitude", lon)
+		}
+
+		b := grid.bin(lat, lon)
+		bd, ok := bins[b]
+		if !ok {
+			if err := memory.Add(96); err != nil {
+				return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			}
+			bd = &binData{
+				bin:     b,
+				species: make(map[string]bool),
+			}
+			bins[b] = bd
+		}
+		bd.records++
+		if spCol >= 0 && row[spCol] != "" && !bd.species[row[spCol]] {
+			if err := memory.Add(int64(len(row[spCol])) + 24); err != nil {
+				return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			}
+			bd.species[row[spCol]] = true
+		}
+	}
+
+	ls := make([]*binData, 0, len(bins))
+	for _, bd := range bins {
+		ls = append(ls, bd)
+	}
+	slices.SortFunc(ls, func(a, b *binData) int {
+		if c := cmp.Compare(a.bin[1], b.bin[1]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.bin[0], b.bin[0])
+	})
+	return ls, nil
+}
+
+func binID(b [2]int) string {
+	return strconv.Itoa(b[0]) + ":" + strconv.Itoa(b[1])
+}
+
+func writeCSV(w io.Writer, grid binner, bins []*binData) error {
+	out := csv.NewWriter(w)
+	out.UseCRLF = true
+
+	header := []string{
+		"bin",
+		"longitude",
+		"latitude",
+		"records",
+		"species",
+	}
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	for _, bd := range bins {
+		lat, lon := grid.center(bd.bin)
+		row := []string{
+			binID(bd.bin),
+			strconv.FormatFloat(lon, 'f', 6, 64),
+			strconv.FormatFloat(lat, 'f', 6, 64),
+			strconv.Itoa(bd.records),
+			strconv.Itoa(len(bd.species)),
+		}
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string         `json:"type"`
+	Geometry   geometry       `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type geometry struct {
+	Type        string         `json:"type"`
+	Coordinates [][][2]float64 `json:"coordinates"`
+}
+
+func writeGeoJSON(w io.Writer, grid binner, bins []*binData) error {
+	fc := featureCollection{
+		Type:     "FeatureCollection",
+		Features: make([]feature, 0, len(bins)),
+	}
+	for _, bd := range bins {
+		lat, lon := grid.center(bd.bin)
+		fc.Features = append(fc.Features, feature{
+			Type: "Feature",
+			Geometry: geometry{
+				Type:        "Polygon",
+				Coordinates: [][][2]float64{grid.polygon(bd.bin)},
+			},
+			Properties: map[string]any{
+				"bin":       binID(bd.bin),
+				"longitude": lon,
+				"latitude":  lat,
+				"records":   bd.records,
+				"species":   len(bd.species),
+			},
+		})
+	}
+
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	if err := e.Encode(fc); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package density_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/density"
+)
+
+// edges is a table with records
+// at the edges of the globe.
+var edges = "gbifID\tspeciesKey\tdecimalLatitude\tdecimalLongitude\r\n" +
+	"1\t10\t90\t-180\r\n" +
+	"2\t10\t-90\t180\r\n" +
+	"3\t20\t-90\t-180\r\n" +
+	"4\t20\t90\t180\r\n" +
+	"5\t20\t-89.5\t179.5\r\n"
+
+func TestDensityEdges(t *testing.T) {
+	tests := map[string]struct {
+		size string
+		want string
+	}{
+		"one degree": {
+			size: "1",
+			want: "bin,longitude,latitude,records,species\r\n" +
+				"0:0,-179.500000,89.500000,1,1\r\n" +
+				"359:0,179.500000,89.500000,1,1\r\n" +
+				"0:179,-179.500000,-89.500000,1,1\r\n" +
+				"359:179,179.500000,-89.500000,2,2\r\n",
+		},
+		"clipped": {
+			// 180/7 and 360/7 are not integers,
+			// so the last bins are clipped
+			size: "7",
+			want: "bin,longitude,latitude,records,species\r\n" +
+				"0:0,-176.500000,86.500000,1,1\r\n" +
+				"51:0,178.500000,86.500000,1,1\r\n" +
+				"0:25,-176.500000,-87.500000,1,1\r\n" +
+				"51:25,178.500000,-87.500000,2,2\r\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			density.Command.SetStdin(strings.NewReader(edges))
+			density.Command.SetStdout(&buf)
+			if err := density.Command.Execute([]string{"--size", test.size}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDensityPolygons(t *testing.T) {
+	for _, size := range []string{"1", "7", "0.3"} {
+		var buf bytes.Buffer
+		density.Command.SetStdin(strings.NewReader(edges))
+		density.Command.SetStdout(&buf)
+		if err := density.Command.Execute([]string{"--size", size, "--format", "geojson"}); err != nil {
+			t.Fatalf("size %s: unexpected error: %v", size, err)
+		}
+
+		var fc struct {
+			Features []struct {
+				Geometry struct {
+					Coordinates [][][2]float64
+				}
+				Properties struct {
+					Bin       string
+					Longitude float64
+					Latitude  float64
+				}
+			}
+		}
+		if err := json.Unmarshal(buf.Bytes(), &fc); err != nil {
+			t.Fatalf("size %s: unexpected error: %v", size, err)
+		}
+		for _, f := range fc.Features {
+			p := f.Properties
+			if p.Latitude < -90 || p.Latitude > 90 || p.Longitude < -180 || p.Longitude > 180 {
+				t.Errorf("size %s: bin %s: center %.6f %.6f outside the globe", size, p.Bin, p.Latitude, p.Longitude)
+			}
+			for _, v := range f.Geometry.Coordinates[0] {
+				if v[1] < -90 || v[1] > 90 || v[0] < -180 || v[0] > 180 {
+					t.Errorf("size %s: bin %s: vertex %.6f %.6f outside the globe", size, p.Bin, v[1], v[0])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,356 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package diff implements a command to compare
+// two GBIF occurrence tables.
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `diff [--key <column>] [--tsv] [-o|--output <file>]
+	<old-table> <new-table>`,
+	Short: "compare two occurrence tables",
+	Long: `
+Command diff reads two GBIF occurrence tables and prints the differences
+between them. Records are compared using the values of a key column; by
+default it uses the "gbifID" column, use the flag --key to define a different
+column. The reported changes are:
+
+	- added: the record is only in the new table.
+	- removed: the record is only in the old table.
+	- changed: the record has different values in one or more columns.
+
+Only the columns present in both tables are compared. Columns present in only
+one of the tables are reported in the standard error.
+
+The first argument is the old table, and the second argument is the new table.
+The old table is kept in memory.
+
+By default, the changes are printed in a human readable form, listing the
+changed columns of each changed record. If the flag --tsv is defined, the
+changes will be printed as a TSV table with the following columns:
+
+	- change: the type of change.
+	- key: the value of the key column.
+	- field: the changed column (for changed records).
+	- old: the old value (for changed records).
+	- new: the new value (for changed records).
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var keyFlag string
+var tsvFlag bool
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&keyFlag, "key", "gbifID", "")
+	c.Flags().BoolVar(&tsvFlag, "tsv", false, "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if len(args) < 2 {
+		return c.UsageError("expecting old and new table files")
+	}
+
+	old, err := readTable(args[0])
+	if err != nil {
+		return err
+	}
+
+	changes, err := compare(old, args[1])
+	if err != nil {
+		return err
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if tsvFlag {
+		return writeTSV(out, changes)
+	}
+	for _, ch := range changes {
+		fmt.Fprintf(out, "%s\n", ch)
+	}
+	return nil
+}
+
+// A table is an occurrence table
+// stored in memory.
+type table struct {
+	name   string
+	header []string
+	fields map[string]int
+	keys   []string
+	rows   map[string][]string
+}
+
+func readTable(name string) (*table, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if st, err := f.Stat(); err == nil {
+		if err := memory.Check(st.Size()); err != nil {
+			return nil, fmt.Errorf("table %q: %v", name, err)
+		}
+	}
+
+	tab := tabfile.NewReader(f)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", name, err)
+	}
+	t := &table{
+		name:   name,
+		header: header,
+		fields: make(map[string]int, len(header)),
+		rows:   make(map[string][]string),
+	}
+	for i, h := range header {
+		t.fields[strings.ToLower(h)] = i
+	}
+	keyCol, ok := t.fields[strings.ToLower(keyFlag)]
+	if !ok {
+		return nil, fmt.Errorf("input data %q without %q field", name, keyFlag)
+	}
+
+	// repeated values are interned
+	// to reduce the memory used by the table.
+	dict := tsv.NewDict()
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", name, ln, err)
+		}
+		logger.ReadRows(1)
+
+		key := strings.TrimSpace(row[keyCol])
+		if key == "" {
+			continue
+		}
+		if _, dup := t.rows[key]; dup {
+			return nil, fmt.Errorf("table %q: row %d: repeated key %q", name, ln, key)
+		}
+		size := memory.Row(row)
+		row, saved := dict.Row(row)
+		if err := memory.Add(size - int64(saved)); err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", name, ln, err)
+		}
+		key = strings.TrimSpace(row[keyCol])
+		t.keys = append(t.keys, key)
+		t.rows[key] = row
+	}
+	return t, nil
+}
+
+type change struct {
+	kind  string
+	key   string
+	field []string
+	old   []string
+	new   []string
+}
+
+func (ch change) String() string {
+	if ch.kind != "changed" {
+		return fmt.Sprintf("%s: %s", ch.kind, ch.key)
+	}
+	return fmt.Sprintf("%s: %s: %s", ch.kind, ch.key, strings.Join(ch.field, ", "))
+}
+
+// compare compares the old table
+// with a new table
+// read from a file.
+func compare(old *table, name string) ([]change, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tab := tabfile.NewReader(f)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", name, err)
+	}
+	keyCol := -1
+	fields := make(map[string]int, len(header))
+	for i, h := range header {
+		h = strings.ToLower(h)
+		fields[h] = i
+		if h == strings.ToLower(keyFlag) {
+			keyCol = i
+		}
+	}
+	if keyCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q field", name, keyFlag)
+	}
+
+	// columns in both tables
+	type column struct {
+		name   string
+		oldCol int
+		newCol int
+	}
+	var cols []column
+	var onlyNew []string
+	for i, h := range header {
+		o, ok := old.fields[strings.ToLower(h)]
+		if !ok {
+			onlyNew = append(onlyNew, h)
+			continue
+		}
+		cols = append(cols, column{name: h, oldCol: o, newCol: i})
+	}
+	var onlyOld []string
+	for _, h := range old.header {
+		if _, ok := fields[strings.ToLower(h)]; !ok {
+			onlyOld = append(onlyOld, h)
+		}
+	}
+	if len(onlyOld) > 0 {
+		logger.Printf("columns only in %q: %s", old.name, strings.Join(onlyOld, ", "))
+	}
+	if len(onlyNew) > 0 {
+		logger.Printf("columns only in %q: %s", name, strings.Join(onlyNew, ", "))
+	}
+
+	var changes []change
+	seen := make(map[string]bool, len(old.rows))
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", name, ln, err)
+		}
+		logger.ReadRows(1)
+
+		key := strings.TrimSpace(row[keyCol])
+		if key == "" {
+			continue
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("table %q: row %d: repeated key %q", name, ln, key)
+		}
+		seen[key] = true
+
+		or, ok := old.rows[key]
+		if !ok {
+			changes = append(changes, change{kind: "added", key: key})
+			continue
+		}
+		ch := change{kind: "changed", key: key}
+		for _, c := range cols {
+			if or[c.oldCol] == row[c.newCol] {
+				continue
+			}
+			ch.field = append(ch.field, c.name)
+			ch.old = append(ch.old, or[c.oldCol])
+			ch.new = append(ch.new, row[c.newCol])
+		}
+		if len(ch.field) > 0 {
+			changes = append(changes, ch)
+		}
+	}
+
+	for _, key := range old.keys {
+		if seen[key] {
+			continue
+		}
+		changes = append(changes, change{kind: "removed", key: key})
+	}
+	return changes, nil
+}
+
+func writeTSV(w io.Writer, changes []change) error {
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	header := []string{
+		"change",
+		"key",
+		"field",
+		"old",
+		"new",
+	}
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	for _, ch := range changes {
+		if ch.kind != "changed" {
+			if err := out.Write([]string{ch.kind, ch.key, "", "", ""}); err != nil {
+				return fmt.Errorf("when writing on %q: %v", output, err)
+			}
+			logger.WriteRows(1)
+			continue
+		}
+		for i, f := range ch.field {
+			row := []string{
+				ch.kind,
+				ch.key,
+				f,
+				ch.old[i],
+				ch.new[i],
+			}
+			if err := out.Write(row); err != nil {
+				return fmt.Errorf("when writing on %q: %v", output, err)
+			}
+			logger.WriteRows(1)
+		}
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
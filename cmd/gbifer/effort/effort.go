@@ -0,0 +1,444 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package effort implements a command to build
+// a sampling effort raster
+// from the records of a GBIF occurrence table.
+package effort
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+)
+
+var Command = &command.Command{
+	Usage: `effort [--size <value>] [--extent <bounds>] [--format <format>]
+	[--taxon <name>] [--rank <rank>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "build a sampling effort raster",
+	Long: `
+Command effort reads a GBIF occurrence table from the standard input and
+counts the number of records in each cell of a raster, in an equirectangular
+projection (WGS84). The raster can be used as a bias layer of sampling
+effort, for example, to select the background points of a target-group
+niche model.
+
+By default, the cells are of 0.5 degrees. Use the flag --size to define a
+different size (in degrees).
+
+By default, the raster covers the whole world. Use the flag --extent to
+define a different extent, as a comma separated list with the west, south,
+east, and north bounds, in degrees (for example, --extent -82,-56,-34,13 for
+South America). The bounds are adjusted to be multiples of the cell size.
+Records outside the extent are ignored.
+
+By default the output is an ESRI ASCII grid. Use the flag --format to define a
+different format. Valid formats are:
+
+	ascii    an ESRI ASCII grid (the default)
+	geotiff  a GeoTIFF file, with 32 bit unsigned integer values
+
+Cells without records have a value of 0.
+
+By default, all the records are counted. Use the flag --taxon to count only
+the records of a higher taxon. The name is searched in the columns "kingdom",
+"phylum", "class", "order", "family", and "genus" (the comparison is case
+insensitive).
+
+If the flag --rank is defined with a rank (for example, "class" or "order"),
+a raster will be built for each taxon of the indicated rank, using the name of
+the taxon in the corresponding column. In this case, the flag --output is
+required, and it is used as the prefix of the output files. For example, with
+--output effort.asc, the raster of the class Aves will be written in the file
+"effort-Aves.asc". Records without a value for the rank are ignored.
+
+Records without coordinates are ignored.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var sizeFlag float64
+var extentFlag string
+var formatFlag string
+var taxonFlag string
+var rankFlag string
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().Float64Var(&sizeFlag, "size", 0.5, "")
+	c.Flags().StringVar(&extentFlag, "extent", "", "")
+	c.Flags().StringVar(&formatFlag, "format", "ascii", "")
+	c.Flags().StringVar(&taxonFlag, "taxon", "", "")
+	c.Flags().StringVar(&rankFlag, "rank", "", "")
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+// ranks are the columns
+// with the names of the higher taxa.
+var ranks = []string{
+	"kingdom",
+	"phylum",
+	"class",
+	"order",
+	"family",
+	"genus",
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if sizeFlag <= 0 || sizeFlag > 90 {
+		return c.UsageError(fmt.Sprintf("invalid cell size %.6f", sizeFlag))
+	}
+	formatFlag = strings.ToLower(formatFlag)
+	if formatFlag != "ascii" && formatFlag != "geotiff" {
+		return c.UsageError(fmt.Sprintf("unknown format %q", formatFlag))
+	}
+	rankFlag = strings.ToLower(rankFlag)
+	if rankFlag != "" {
+		if !slices.Contains(ranks, rankFlag) {
+			return c.UsageError(fmt.Sprintf("invalid rank %q", rankFlag))
+		}
+		if output == "" {
+			return c.UsageError("flag --rank requires an output file")
+		}
+	}
+	ext, err := parseExtent(extentFlag, sizeFlag)
+	if err != nil {
+		return c.UsageError(err.Error())
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	rs, err := readTable(in, ext)
+	if err != nil {
+		return err
+	}
+
+	if rankFlag != "" {
+		return writeRasters(rs)
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f *os.File
+		f, err = os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	r, ok := rs[""]
+	if !ok {
+		r, err = newRaster(ext)
+		if err != nil {
+			return err
+		}
+	}
+	if err := writeRaster(out, r); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+// An extent is the bounding box of a raster.
+type extent struct {
+	west, south, east, north float64
+	size                     float64
+	cols, rows               int
+}
+
+func parseExtent(s string, size float64) (extent, error) {
+	ext := extent{west: -180, south: -90, east: 180, north: 90, size: size}
+	if s != "" {
+		v := strings.Split(s, ",")
+		if len(v) != 4 {
+			return ext, fmt.Errorf("invalid extent %q", s)
+		}
+		var b [4]float64
+		for i, x := range v {
+			f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+			if err != nil {
+				return ext, fmt.Errorf("invalid extent %q", s)
+			}
+			b[i] = f
+		}
+		ext.west, ext.south, ext.east, ext.north = b[0], b[1], b[2], b[3]
+		if ext.west < -180 || ext.east > 180 || ext.south < -90 || ext.north > 90 || ext.west >= ext.east || ext.south >= ext.north {
+			return ext, fmt.Errorf("invalid extent %q", s)
+		}
+	}
+
+	// adjust the bounds to the cell size
+	ext.west = math.Floor(ext.west/size) * size
+	ext.south = math.Floor(ext.south/size) * size
+	ext.cols = int(math.Ceil((ext.east - ext.west) / size))
+	ext.rows = int(math.Ceil((ext.north - ext.south) / size))
+	ext.east = ext.west + float64(ext.cols)*size
+	ext.north = ext.south + float64(ext.rows)*size
+	return ext, nil
+}
+
+// cell returns the column and row of a point,
+// with rows starting at the north.
+func (ext extent) cell(lat, lon float64) (col, row int, ok bool) {
+	if lon < ext.west || lon > ext.east || lat < ext.south || lat > ext.north {
+		return 0, 0, false
+	}
+	col = int((lon - ext.west) / ext.size)
+	row = int((ext.north - lat) / ext.size)
+	return min(col, ext.cols-1), min(row, ext.rows-1), true
+}
+
+// A raster stores the number of records
+// in each cell.
+type raster struct {
+	ext   extent
+	cells []uint32
+}
+
+func newRaster(ext extent) (*raster, error) {
+	n := ext.cols * ext.rows
+	if err := memory.Add(int64(n) * 4); err != nil {
+		return nil, err
+	}
+	return &raster{ext: ext, cells: make([]uint32, n)}, nil
+}
+
+// readTable returns the rasters
+// of each taxon.
+// If no rank is defined,
+// the only raster is stored with an empty name.
+func readTable(r io.Reader, ext extent) (map[string]*raster, error) {
+	tab := tabfile.NewReader(r, append([]string{"decimalLatitude", "decimalLongitude", rankFlag}, ranks...)...)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+
+	latCol := -1
+	lonCol := -1
+	rankCol := -1
+	var taxCols []int
+	for i, h := range header {
+		h = strings.ToLower(h)
+		if h == "decimallatitude" {
+			latCol = i
+		}
+		if h == "decimallongitude" {
+			lonCol = i
+		}
+		if h == rankFlag {
+			rankCol = i
+		}
+		if slices.Contains(ranks, h) {
+			taxCols = append(taxCols, i)
+		}
+	}
+	if latCol < 0 || lonCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q or %q fields", input, "decimalLatitude", "decimalLongitude")
+	}
+	if rankFlag != "" && rankCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q field", input, rankFlag)
+	}
+	if taxonFlag != "" && len(taxCols) == 0 {
+		return nil, fmt.Errorf("input data %q without taxonomic rank fields", input)
+	}
+
+	rs := make(map[string]*raster)
+	var outside, noRank int64
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		if row[latCol] == "" || row[lonCol] == "" {
+			continue
+		}
+		lat, err := strconv.ParseFloat(row[latCol], 64)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: field %q: %v", input, ln, "decimalLatitude", err)
+		}
+		if lat < -90 || lat > 90 {
+			return nil, fmt.Errorf("table %q: row %d: field %q: invalid latitude: %.6f", input, ln, "decimalLatitude", lat)
+		}
+		lon, err := strconv.ParseFloat(row[lonCol], 64)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: field %q: %v", input, ln, "decimalLongitude", err)
+		}
+		if lon < -180 || lon > 180 {
+			return nil, fmt.Errorf("table %q: row %d: field %q: invalid longitude: %.6f", input, ln, "decimalLongitude", lon)
+		}
+
+		if taxonFlag != "" {
+			found := false
+			for _, c := range taxCols {
+				if strings.EqualFold(strings.TrimSpace(row[c]), taxonFlag) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+
+		var name string
+		if rankCol >= 0 {
+			name = strings.TrimSpace(row[rankCol])
+			if name == "" {
+				noRank++
+				continue
+			}
+		}
+
+		col, rw, ok := ext.cell(lat, lon)
+		if !ok {
+			outside++
+			continue
+		}
+
+		rast, ok := rs[name]
+		if !ok {
+			rast, err = newRaster(ext)
+			if err != nil {
+				return nil, err
+			}
+			rs[name] = rast
+		}
+		rast.cells[rw*ext.cols+col]++
+	}
+
+	logger.Add("ignored-outside-extent", outside)
+	logger.Add("ignored-no-rank", noRank)
+	return rs, nil
+}
+
+// writeRasters writes the raster of each taxon
+// in a different file.
+func writeRasters(rs map[string]*raster) error {
+	prefix := output
+	ext := ".asc"
+	if formatFlag == "geotiff" {
+		ext = ".tif"
+	}
+	if i := strings.LastIndex(prefix, "."); i > strings.LastIndexAny(prefix, `/\`) {
+		ext = prefix[i:]
+		prefix = prefix[:i]
+	}
+
+	names := make([]string, 0, len(rs))
+	for n := range rs {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	for _, n := range names {
+		name := prefix + "-" + strings.Join(strings.Fields(n), "_") + ext
+		if err := writeFile(name, rs[n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(name string, r *raster) (err error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		e := f.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	if err := writeRaster(f, r); err != nil {
+		return fmt.Errorf("when writing on %q: %v", name, err)
+	}
+	return nil
+}
+
+func writeRaster(w io.Writer, r *raster) error {
+	if formatFlag == "geotiff" {
+		return writeGeoTIFF(w, r)
+	}
+	return writeASCII(w, r)
+}
+
+// writeASCII writes a raster
+// as an ESRI ASCII grid.
+func writeASCII(w io.Writer, r *raster) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "ncols %d\n", r.ext.cols)
+	fmt.Fprintf(bw, "nrows %d\n", r.ext.rows)
+	fmt.Fprintf(bw, "xllcorner %s\n", strconv.FormatFloat(r.ext.west, 'f', -1, 64))
+	fmt.Fprintf(bw, "yllcorner %s\n", strconv.FormatFloat(r.ext.south, 'f', -1, 64))
+	fmt.Fprintf(bw, "cellsize %s\n", strconv.FormatFloat(r.ext.size, 'f', -1, 64))
+	fmt.Fprintf(bw, "NODATA_value -9999\n")
+
+	var buf []byte
+	for row := 0; row < r.ext.rows; row++ {
+		buf = buf[:0]
+		for col := 0; col < r.ext.cols; col++ {
+			if col > 0 {
+				buf = append(buf, ' ')
+			}
+			buf = strconv.AppendUint(buf, uint64(r.cells[row*r.ext.cols+col]), 10)
+		}
+		buf = append(buf, '\n')
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,158 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package effort
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"slices"
+)
+
+// TIFF field types.
+const (
+	tiffShort  = 3
+	tiffLong   = 4
+	tiffDouble = 12
+)
+
+var errRasterSize = errors.New("raster too large for a GeoTIFF file")
+
+// A tiffEntry is an entry of a TIFF image file directory.
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte // little endian values
+}
+
+func shorts(v ...uint16) []byte {
+	var b []byte
+	for _, x := range v {
+		b = binary.LittleEndian.AppendUint16(b, x)
+	}
+	return b
+}
+
+func longs(v ...uint32) []byte {
+	var b []byte
+	for _, x := range v {
+		b = binary.LittleEndian.AppendUint32(b, x)
+	}
+	return b
+}
+
+func doubles(v ...float64) []byte {
+	var b []byte
+	for _, x := range v {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(x))
+	}
+	return b
+}
+
+// writeGeoTIFF writes a raster
+// as an uncompressed GeoTIFF,
+// with a strip for each row.
+func writeGeoTIFF(w io.Writer, r *raster) error {
+	cols, rows := r.ext.cols, r.ext.rows
+	rowSize := uint32(cols) * 4
+
+	// the image data is written after the header
+	offsets := make([]uint32, rows)
+	counts := make([]uint32, rows)
+	for i := range offsets {
+		offsets[i] = 8 + uint32(i)*rowSize
+		counts[i] = rowSize
+	}
+
+	entries := []tiffEntry{
+		{tag: 256, typ: tiffLong, count: 1, data: longs(uint32(cols))},          // ImageWidth
+		{tag: 257, typ: tiffLong, count: 1, data: longs(uint32(rows))},          // ImageLength
+		{tag: 258, typ: tiffShort, count: 1, data: shorts(32)},                  // BitsPerSample
+		{tag: 259, typ: tiffShort, count: 1, data: shorts(1)},                   // Compression: none
+		{tag: 262, typ: tiffShort, count: 1, data: shorts(1)},                   // Photometric: black is zero
+		{tag: 273, typ: tiffLong, count: uint32(rows), data: longs(offsets...)}, // StripOffsets
+		{tag: 277, typ: tiffShort, count: 1, data: shorts(1)},                   // SamplesPerPixel
+		{tag: 278, typ: tiffLong, count: 1, data: longs(1)},                     // RowsPerStrip
+		{tag: 279, typ: tiffLong, count: uint32(rows), data: longs(counts...)},  // StripByteCounts
+		{tag: 284, typ: tiffShort, count: 1, data: shorts(1)},                   // PlanarConfiguration
+		{tag: 339, typ: tiffShort, count: 1, data: shorts(1)},                   // SampleFormat: unsigned integer
+
+		// ModelPixelScale
+		{tag: 33550, typ: tiffDouble, count: 3, data: doubles(r.ext.size, r.ext.size, 0)},
+		// ModelTiepoint: the north-west corner
+		{tag: 33922, typ: tiffDouble, count: 6, data: doubles(0, 0, 0, r.ext.west, r.ext.north, 0)},
+		// GeoKeyDirectory
+		{tag: 34735, typ: tiffShort, count: 16, data: shorts(
+			1, 1, 0, 3, // version, revision, minor revision, number of keys
+			1024, 0, 1, 2, // GTModelType: geographic
+			1025, 0, 1, 1, // GTRasterType: pixel is area
+			2048, 0, 1, 4326, // GeographicType: WGS84
+		)},
+	}
+	slices.SortFunc(entries, func(a, b tiffEntry) int {
+		return int(a.tag) - int(b.tag)
+	})
+
+	// values larger than 4 bytes
+	// are written after the image data
+	next := 8 + uint64(rowSize)*uint64(rows)
+	var extra []byte
+	for _, e := range entries {
+		if len(e.data) > 4 {
+			extra = append(extra, e.data...)
+			if len(extra)%2 != 0 {
+				extra = append(extra, 0)
+			}
+		}
+	}
+	ifd := next + uint64(len(extra))
+	if ifd%2 != 0 {
+		ifd++
+	}
+	if ifd+2+12*uint64(len(entries))+4 > math.MaxUint32 {
+		return errRasterSize
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("II")
+	bw.Write(shorts(42))
+	bw.Write(longs(uint32(ifd)))
+
+	buf := make([]byte, 0, rowSize)
+	for row := 0; row < rows; row++ {
+		buf = buf[:0]
+		for _, v := range r.cells[row*cols : (row+1)*cols] {
+			buf = binary.LittleEndian.AppendUint32(buf, v)
+		}
+		bw.Write(buf)
+	}
+	bw.Write(extra)
+	if (next+uint64(len(extra)))%2 != 0 {
+		bw.WriteByte(0)
+	}
+
+	bw.Write(shorts(uint16(len(entries))))
+	off := next
+	for _, e := range entries {
+		bw.Write(shorts(e.tag, e.typ))
+		bw.Write(longs(e.count))
+		if len(e.data) > 4 {
+			bw.Write(longs(uint32(off)))
+			off += uint64(len(e.data))
+			if off%2 != 0 {
+				off++
+			}
+			continue
+		}
+		v := make([]byte, 4)
+		copy(v, e.data)
+		bw.Write(v)
+	}
+	bw.Write(longs(0)) // no more directories
+	return bw.Flush()
+}
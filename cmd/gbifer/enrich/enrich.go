@@ -0,0 +1,264 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package enrich implements a command to add
+// the higher taxonomy of each record
+// of a GBIF occurrence table.
+package enrich
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/config"
+	"github.com/js-arias/gbifer/cmd/gbifer/parallel"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/taxonomy"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `enrich --tax <file> [--jobs <number>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "add higher taxonomy columns",
+	Long: `
+Command enrich reads a GBIF occurrence table from the standard input and adds
+the higher taxonomy of each record, as defined in a taxonomy file. The
+taxonomy file is required and must be defined with the flag --tax, or as
+the default taxonomy of the configuration (see "gbifer help").
+
+The following columns will be added:
+
+	- kingdom
+	- phylum
+	- class
+	- order
+	- family
+	- genus
+	- acceptedScientificName: the accepted name (with its author) of the
+	  taxon of the record.
+
+If the occurrence table already has any of these columns, its values will be
+replaced with the values from the taxonomy. Records of taxa not in the
+taxonomy will have empty values.
+
+If the flag --jobs is defined with a value greater than 1, the rows will be
+processed by the indicated number of concurrent jobs; with 0, it will use the
+number of available CPUs. The order of the rows is always preserved.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+var jobs int
+var taxFile string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().StringVar(&taxFile, "tax", config.Taxonomy(), "")
+	c.Flags().IntVar(&jobs, "jobs", 1, "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if taxFile == "" {
+		return c.UsageError("flag --tax undefined")
+	}
+	tx, err := readTaxonomy()
+	if err != nil {
+		return err
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := enrich(in, out, tx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func readTaxonomy() (*taxonomy.Taxonomy, error) {
+	f, err := os.Open(taxFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tx, err := taxonomy.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", taxFile, err)
+	}
+	return tx, nil
+}
+
+// ranks are the ranks added to the table.
+var ranks = []taxonomy.Rank{
+	taxonomy.Kingdom,
+	taxonomy.Phylum,
+	taxonomy.Class,
+	taxonomy.Order,
+	taxonomy.Family,
+	taxonomy.Genus,
+}
+
+func enrich(r io.Reader, w io.Writer, tx *taxonomy.Taxonomy) error {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return fmt.Errorf("when reading %q header: %v", input, err)
+	}
+
+	keyCol := -1
+	taxCol := -1
+	fields := make(map[string]int, len(header))
+	for i, h := range header {
+		h = strings.ToLower(h)
+		if h == "specieskey" {
+			keyCol = i
+		}
+		if h == "taxonkey" {
+			taxCol = i
+		}
+		fields[h] = i
+	}
+	if keyCol < 0 && taxCol < 0 {
+		return fmt.Errorf("input data %q without %q or %q fields", input, "speciesKey", "taxonKey")
+	}
+
+	// columns of the added values
+	names := make([]string, 0, len(ranks)+1)
+	for _, r := range ranks {
+		names = append(names, r.String())
+	}
+	names = append(names, "acceptedScientificName")
+	cols := make([]int, len(names))
+	for i, n := range names {
+		if c, ok := fields[strings.ToLower(n)]; ok {
+			cols[i] = c
+			continue
+		}
+		cols[i] = len(header)
+		header = append(header, n)
+	}
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	fn := func(row []string, ln int) ([]string, error) {
+		for len(row) < len(header) {
+			row = append(row, "")
+		}
+
+		vals := lineage(tx, rowKey(row, keyCol, taxCol))
+		for i, c := range cols {
+			row[c] = vals[i]
+		}
+		return row, nil
+	}
+	if err := parallel.Rows(tab, input, out, output, jobs, fn); err != nil {
+		return err
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+// rowKey returns the taxon ID of an occurrence row,
+// or 0 if the row has no valid ID.
+func rowKey(row []string, keyCol, taxCol int) int64 {
+	var key string
+	if keyCol >= 0 {
+		key = row[keyCol]
+		if key == "" {
+			return 0
+		}
+	}
+	if taxCol >= 0 {
+		key = row[taxCol]
+	}
+	id, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// lineage returns the names of the higher taxa
+// of a taxon,
+// and its accepted name.
+func lineage(tx *taxonomy.Taxonomy, id int64) []string {
+	vals := make([]string, len(ranks)+1)
+	acc := tx.Accepted(id)
+	if acc.ID == 0 {
+		return vals
+	}
+
+	name := acc.Name
+	if a := acc.Authorship(); a != "" {
+		name += " " + a
+	}
+	vals[len(ranks)] = name
+
+	// the lineage is cached in the taxonomy,
+	// so there is no need to walk the tree
+	lin := tx.Lineage(acc.ID)
+	for i, r := range ranks {
+		if lin[r] != 0 {
+			vals[i] = tx.Taxon(lin[r]).Name
+		}
+	}
+	return vals
+}
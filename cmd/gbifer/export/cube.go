@@ -0,0 +1,194 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package export
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+// cubeFields are the columns
+// of an occurrence cube.
+var cubeFields = []string{
+	"year",
+	"qdgccode",
+	"specieskey",
+	"species",
+	"occurrences",
+	"mincoordinateuncertaintyinmeters",
+}
+
+// defCubeUncertainty is the uncertainty,
+// in meters,
+// used for the records without uncertainty.
+const defCubeUncertainty = 1000
+
+// A cubeKey is the key of a cell
+// of an occurrence cube.
+type cubeKey struct {
+	year    int
+	cell    string
+	species string
+}
+
+type cubeCell struct {
+	spName      string
+	count       int64
+	uncertainty int64
+}
+
+// A cubeWriter aggregates the records
+// into an occurrence cube.
+type cubeWriter struct {
+	w     io.Writer
+	cells map[cubeKey]*cubeCell
+}
+
+func newCubeWriter(w io.Writer) recordWriter {
+	return &cubeWriter{
+		w:     w,
+		cells: make(map[cubeKey]*cubeCell),
+	}
+}
+
+func (cw *cubeWriter) write(rec []string) error {
+	year, err := strconv.Atoi(rec[fDate][:4])
+	if err != nil || year <= 1700 {
+		// records without a valid date
+		return nil
+	}
+	lat, err := strconv.ParseFloat(rec[fLatitude], 64)
+	if err != nil {
+		return err
+	}
+	lon, err := strconv.ParseFloat(rec[fLongitude], 64)
+	if err != nil {
+		return err
+	}
+	unc, _ := strconv.ParseInt(rec[fUncertainty], 10, 64)
+	if unc <= 0 {
+		unc = defCubeUncertainty
+	}
+
+	k := cubeKey{
+		year:    year,
+		cell:    qdgc(lat, lon, qdgcLevel),
+		species: rec[fSpeciesID],
+	}
+	c, ok := cw.cells[k]
+	if !ok {
+		if err := memory.Add(int64(len(k.cell)+len(k.species)+len(rec[fSpecies])) + 96); err != nil {
+			return err
+		}
+		c = &cubeCell{spName: rec[fSpecies], uncertainty: unc}
+		cw.cells[k] = c
+	}
+	c.count++
+	if unc < c.uncertainty {
+		c.uncertainty = unc
+	}
+	return nil
+}
+
+func (cw *cubeWriter) flush() error {
+	keys := make([]cubeKey, 0, len(cw.cells))
+	for k := range cw.cells {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b cubeKey) int {
+		if c := cmp.Compare(a.year, b.year); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.cell, b.cell); c != 0 {
+			return c
+		}
+		return cmp.Compare(cw.cells[a].spName, cw.cells[b].spName)
+	})
+
+	out := tsv.NewWriter(cw.w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+	if err := out.Write(cubeFields); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		c := cw.cells[k]
+		row := []string{
+			strconv.Itoa(k.year),
+			k.cell,
+			k.species,
+			c.spName,
+			strconv.FormatInt(c.count, 10),
+			strconv.FormatInt(c.uncertainty, 10),
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// qdgc returns the Quarter Degree Grid Cell code
+// of a point,
+// at the given level.
+// At level 0 the cells are of 1 degree,
+// and each level divides a cell in four quadrants
+// (A: north-west, B: north-east, C: south-west, D: south-east).
+func qdgc(lat, lon float64, level int) string {
+	// the cells include the south and west borders
+	// so the poles and the antimeridian
+	// are moved inside the grid
+	lat = math.Min(lat, 90-1e-9)
+	lon = math.Min(lon, 180-1e-9)
+
+	ew, ns := 'E', 'N'
+	if lon < 0 {
+		ew = 'W'
+	}
+	if lat < 0 {
+		ns = 'S'
+	}
+	aLon := math.Floor(math.Abs(lon))
+	aLat := math.Floor(math.Abs(lat))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%c%03d%c%02d", ew, int(aLon), ns, int(aLat))
+
+	// bounds of the degree square
+	south := math.Floor(lat)
+	west := math.Floor(lon)
+	size := 1.0
+	for i := 0; i < level; i++ {
+		size /= 2
+		north := lat >= south+size
+		east := lon >= west+size
+		switch {
+		case north && !east:
+			b.WriteByte('A')
+		case north && east:
+			b.WriteByte('B')
+		case !north && !east:
+			b.WriteByte('C')
+		default:
+			b.WriteByte('D')
+		}
+		if north {
+			south += size
+		}
+		if east {
+			west += size
+		}
+	}
+	return b.String()
+}
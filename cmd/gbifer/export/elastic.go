@@ -0,0 +1,85 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// An elasticWriter writes the records
+// as the NDJSON body of a request
+// to the bulk API of Elasticsearch.
+type elasticWriter struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+func newElasticWriter(w io.Writer) recordWriter {
+	return &elasticWriter{w: bufio.NewWriter(w)}
+}
+
+func (ew *elasticWriter) write(rec []string) error {
+	// index names are always in lower case
+	b := append(ew.buf[:0], `{"index":{"_index":`...)
+	b = appendJSONString(b, strings.ToLower(tableName))
+	if id := rec[fGBIFID]; id != "" {
+		b = append(b, `,"_id":`...)
+		b = appendJSONString(b, id)
+	}
+	b = append(b, "}}\n{"...)
+
+	first := true
+	for i, v := range withNulls(rec) {
+		if v == "" {
+			continue
+		}
+		if !first {
+			b = append(b, ',')
+		}
+		first = false
+		b = appendJSONString(b, outFields[i])
+		b = append(b, ':')
+
+		switch pgTypes[i] {
+		case "bigint":
+			if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+				b = append(b, v...)
+				continue
+			}
+		case "double precision":
+			// coordinates are always formatted as decimals
+			b = append(b, v...)
+			continue
+		}
+		b = appendJSONString(b, v)
+	}
+
+	// a geo_point field
+	if !first {
+		b = append(b, ',')
+	}
+	b = append(b, `"location":{"lat":`...)
+	b = append(b, rec[fLatitude]...)
+	b = append(b, `,"lon":`...)
+	b = append(b, rec[fLongitude]...)
+	b = append(b, "}}\n"...)
+
+	ew.buf = b
+	_, err := ew.w.Write(b)
+	return err
+}
+
+func (ew *elasticWriter) flush() error {
+	return ew.w.Flush()
+}
+
+func appendJSONString(b []byte, s string) []byte {
+	v, _ := json.Marshal(s)
+	return append(b, v...)
+}
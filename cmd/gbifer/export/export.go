@@ -18,12 +18,16 @@ import (
 	"time"
 
 	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/config"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/schema"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
 	"github.com/js-arias/gbifer/taxonomy"
-	"github.com/js-arias/gbifer/tsv"
 )
 
 var Command = &command.Command{
-	Usage: `export [-tax <file>]
+	Usage: `export [-tax <file>] [--format <format>] [--qdgc <level>]
+	[--table <name>] [--schema <file>] [--grid <value>] [--wkt] [--progress]
 	[-i|--input <file>] [-o|--output <file>]`,
 	Short: "export to TSV RFC 4180 file",
 	Long: `
@@ -37,9 +41,104 @@ the column names "latitude" and "longitude" instead of "DecimalLatitude" and
 will preserve GBIF ID fields, so it will be possible to trace the origin of
 each occurrence.
 
+By default, the output is a TSV file with the following columns: species,
+speciesID, latitude, longitude, geoRefUncertainty, gbifID, catalog,
+occurrenceID, date, country, province, county, locality, taxon, taxonID,
+dataset, datasetID, publisher, reference, and license. The date is written
+as a timestamp (for example, "2002-05-17T00:00:00Z"). If a record has only a
+year, or a year and month, the missing month and day are filled with 1 (so a
+record with only a year is written as January 1 of that year), and if the
+record does not have a valid year, the year 1700 is used. Use the flag
+--format to define a different output format. Valid formats are:
+
+	- tsv      the default format.
+	- wallace  a CSV file with the columns "scientific_name", "longitude",
+	           and "latitude", as expected by the occurrence upload of
+	           Wallace, and the occurrence data frames of ENMeval and other
+	           R packages for niche modeling. Coordinates are in decimal
+	           degrees (WGS84), using a dot as decimal separator.
+	- cube     an occurrence cube (as defined by the B-Cubed project), a
+	           TSV file with the number of records of each species, in
+	           each cell of a grid, in each year. The columns are "year",
+	           "qdgccode", "specieskey", "species", "occurrences", and
+	           "mincoordinateuncertaintyinmeters". Records without a valid
+	           year are ignored, and records without uncertainty are assumed
+	           to have an uncertainty of 1000 meters.
+	- gpkg     a GeoPackage file (an SQLite database), with a points layer
+	           named "occurrences", in WGS84 (EPSG:4326), with the columns
+	           of the default format (without truncation of the column
+	           names), with numeric columns stored as numbers, the date as
+	           a DATETIME value, and missing values (for example, the date
+	           of a record without a valid year) stored as NULL.
+	- postgis  an SQL script for PostgreSQL, with the PostGIS extension,
+	           that creates a table with the columns of the default format
+	           (with typed columns, and missing values, for example, the
+	           date of a record without a valid year, stored as NULL), and
+	           a "geom" column with the points (in WGS84, EPSG:4326), and
+	           loads the data with a COPY statement. A spatial index is
+	           created after loading the data. The script can be loaded
+	           with psql, for example: "psql -d mydb -f occurrences.sql".
+	- elastic  an NDJSON file for the bulk API of Elasticsearch, with an
+	           index action (using the gbifID as the document ID), and the
+	           document of each record, with the columns of the default
+	           format (missing values, for example, the date of a record
+	           without a valid year, or the uncertainty of a record without
+	           uncertainty, are omitted), and a "location" field with the
+	           coordinates, that can be mapped as a geo_point. The file can
+	           be loaded with:
+	           "curl -H 'Content-Type: application/x-ndjson'
+	           -XPOST 'localhost:9200/_bulk' --data-binary @occ.ndjson".
+	- html     a self-contained HTML report, with summary tables (the
+	           number of records per species, per country, and per year),
+	           quality control statistics (records without date, country,
+	           or coordinate uncertainty, and the median uncertainty), and
+	           a map of the records (as an SVG image, in an
+	           equirectangular projection). The report is useful to share
+	           the data with collaborators that do not use GBIFer.
+	- ndm      the xydata format used by NDM/VNDM for the analysis of
+	           endemicity, in which each species (with spaces replaced by
+	           underscores) is followed by the list of its distinct points
+	           (longitude and latitude).
+
+In the gpkg and postgis formats, the name of the table is "occurrences"; use
+the flag --table to define a different name. In the elastic format, the
+table name is used as the name of the index (in lower case).
+
+In the gpkg and postgis formats, use the flag --schema to define a schema
+file (as written by the command schema) with the types of the columns of the
+input table. The columns copied without changes from the input table
+("gbifID", "occurrenceID", "countryCode", "stateProvince", "county",
+"verbatimLocality", "datasetName", "datasetKey", "publisher",
+"bibliographicCitation", and "license") will be exported with the type
+defined in the schema: integer columns as integers, float columns as
+floating point numbers, and any other type as text. The types of the other
+columns are always defined by the export.
+
+In the ndm format, use the flag --grid to define the size of the grid cells
+(in degrees) used by NDM/VNDM. The grid starts at the north-west corner of the
+records.
+
+In the cube format, the grid is the Quarter Degree Grid Cells (QDGC); by
+default, at level 2 (cells of 0.25 degrees). Use the flag --qdgc to define a
+different level (0 for cells of 1 degree, and each level divides a cell in
+four).
+
+In the tsv and wallace formats, use the flag --wkt to add a column named
+"geometry" with the coordinates of each record as a point in the well-known
+text (WKT) format (for example, "POINT (-58.4 -34.5)"), so the file can be
+read as a layer by GIS tools and databases. If the output is a file, a .prj
+file with the same name will be written with the definition of the spatial
+reference of the coordinates (WGS84, EPSG:4326).
+
 By default, it will use the species name from the occurrence file. If the flag
 --tax is defined, the indicated file will be used to retrieve the accepted
-species name from the taxonomy.
+species name from the taxonomy. If a default taxonomy is defined in the
+configuration (see "gbifer help"), it will be used as the value of --tax.
+
+If flag --progress is defined, it will print the progress of the export in
+the standard error. If the input is a file, the progress is reported as a
+percentage of the file size, otherwise, only the number of read rows is
+reported.
 
 By default, it will read the data from the standard input; use the flag
 --input, or -i, to select a particular file.
@@ -54,16 +153,60 @@ By default, the results will be printed in the standard output; use the flag
 var input string
 var output string
 var taxFile string
+var progress bool
+var format string
+var qdgcLevel int
+var tableName string
+var schemaFile string
+var gridSize float64
+var wktGeom bool
 
 func setFlags(c *command.Command) {
 	c.Flags().StringVar(&input, "input", "", "")
 	c.Flags().StringVar(&input, "i", "", "")
 	c.Flags().StringVar(&output, "output", "", "")
 	c.Flags().StringVar(&output, "o", "", "")
-	c.Flags().StringVar(&taxFile, "tax", "", "")
+	c.Flags().StringVar(&taxFile, "tax", config.Taxonomy(), "")
+	c.Flags().StringVar(&format, "format", "tsv", "")
+	c.Flags().IntVar(&qdgcLevel, "qdgc", 2, "")
+	c.Flags().StringVar(&tableName, "table", "occurrences", "")
+	c.Flags().StringVar(&schemaFile, "schema", "", "")
+	c.Flags().Float64Var(&gridSize, "grid", 0, "")
+	c.Flags().BoolVar(&wktGeom, "wkt", false, "")
+	c.Flags().BoolVar(&progress, "progress", false, "")
 }
 
 func run(c *command.Command, args []string) (err error) {
+	format = strings.ToLower(format)
+	newWriter, ok := formats[format]
+	if !ok {
+		return c.UsageError(fmt.Sprintf("unknown format %q", format))
+	}
+	if qdgcLevel < 0 || qdgcLevel > 10 {
+		return c.UsageError(fmt.Sprintf("invalid QDGC level %d", qdgcLevel))
+	}
+	if gridSize < 0 || gridSize > 90 {
+		return c.UsageError(fmt.Sprintf("invalid grid size %.3f", gridSize))
+	}
+	if tableName == "" {
+		return c.UsageError("flag --table without a name")
+	}
+	if wktGeom && format != "tsv" && format != "wallace" {
+		return c.UsageError(fmt.Sprintf("flag --wkt not valid for format %q", format))
+	}
+	if schemaFile != "" && format != "gpkg" && format != "postgis" {
+		return c.UsageError(fmt.Sprintf("flag --schema not valid for format %q", format))
+	}
+
+	schemaTypes = nil
+	if schemaFile != "" {
+		var err error
+		schemaTypes, err = readSchema()
+		if err != nil {
+			return err
+		}
+	}
+
 	in := c.Stdin()
 	if input != "" {
 		f, err := os.Open(input)
@@ -102,9 +245,24 @@ func run(c *command.Command, args []string) (err error) {
 		}
 	}
 
-	if err := readTable(in, out, tx); err != nil {
+	if progress {
+		p := logger.NewProgress(in)
+		defer p.Stop()
+		in = p
+	}
+
+	rw := newWriter(out)
+	if err := readTable(in, rw, tx); err != nil {
 		return err
 	}
+	if err := rw.flush(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	if wktGeom && output != "stdout" {
+		if err := writePRJ(output); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -122,6 +280,53 @@ func readTaxonomy() (*taxonomy.Taxonomy, error) {
 	return tx, nil
 }
 
+func readSchema() (map[int]string, error) {
+	f, err := os.Open(schemaFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc, err := schema.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", schemaFile, err)
+	}
+
+	tps := make(map[int]string)
+	for fld, col := range copiedFields {
+		c, ok := sc[col]
+		if !ok {
+			continue
+		}
+		tps[fld] = c.Type
+	}
+	return tps, nil
+}
+
+// Fields of an exported record.
+const (
+	fSpecies = iota
+	fSpeciesID
+	fLatitude
+	fLongitude
+	fUncertainty
+	fGBIFID
+	fCatalog
+	fOccurrenceID
+	fDate
+	fCountry
+	fProvince
+	fCounty
+	fLocality
+	fTaxon
+	fTaxonID
+	fDataset
+	fDatasetID
+	fPublisher
+	fReference
+	fLicense
+)
+
 var outFields = []string{
 	"species",
 	"speciesID",
@@ -145,8 +350,77 @@ var outFields = []string{
 	"license",
 }
 
-func readTable(r io.Reader, w io.Writer, tx *taxonomy.Taxonomy) error {
-	tab := tsv.NewReader(r)
+// copiedFields are the input columns
+// of the fields of an exported record
+// that are copied without changes.
+var copiedFields = map[int]string{
+	fGBIFID:       "gbifid",
+	fOccurrenceID: "occurrenceid",
+	fCountry:      "countrycode",
+	fProvince:     "stateprovince",
+	fCounty:       "county",
+	fLocality:     "verbatimlocality",
+	fDataset:      "datasetname",
+	fDatasetID:    "datasetkey",
+	fPublisher:    "publisher",
+	fReference:    "bibliographiccitation",
+	fLicense:      "license",
+}
+
+// schemaTypes are the schema types
+// of the copied fields,
+// as read from a schema file.
+var schemaTypes map[int]string
+
+// A recordWriter writes the exported records
+// in a given format.
+type recordWriter interface {
+	// write writes a record,
+	// with the fields of outFields.
+	write(rec []string) error
+
+	// flush completes the writing of the records.
+	flush() error
+}
+
+// formats are the output formats.
+var formats = map[string]func(w io.Writer) recordWriter{
+	"tsv":     newTSVWriter,
+	"wallace": newWallaceWriter,
+	"cube":    newCubeWriter,
+	"gpkg":    newGPKGWriter,
+	"postgis": newPostGISWriter,
+	"elastic": newElasticWriter,
+	"html":    newHTMLWriter,
+	"ndm":     newNDMWriter,
+}
+
+// withNulls returns a copy of a record
+// in which the placeholders used for missing values
+// (a date with a year at or before 1700,
+// and an uncertainty or a key of 0)
+// are replaced by empty strings,
+// so they can be stored as null values.
+func withNulls(rec []string) []string {
+	nr := append([]string{}, rec...)
+	if year, err := strconv.Atoi(nr[fDate][:4]); err != nil || year <= 1700 {
+		nr[fDate] = ""
+	}
+	for _, f := range []int{fUncertainty, fSpeciesID, fTaxonID} {
+		if nr[f] == "0" {
+			nr[f] = ""
+		}
+	}
+	return nr
+}
+
+// quoteIdent returns a quoted SQL identifier.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func readTable(r io.Reader, out recordWriter, tx *taxonomy.Taxonomy) error {
+	tab := tabfile.NewReader(r)
 	tab.Comma = '\t'
 
 	header, err := tab.Read()
@@ -159,15 +433,6 @@ func readTable(r io.Reader, w io.Writer, tx *taxonomy.Taxonomy) error {
 		fields[h] = i
 	}
 
-	out := csv.NewWriter(w)
-	out.Comma = '\t'
-	out.UseCRLF = true
-
-	// write outfield header
-	if err := out.Write(outFields); err != nil {
-		return fmt.Errorf("when writing on %q: %v", output, err)
-	}
-
 	for {
 		row, err := tab.Read()
 		if errors.Is(err, io.EOF) {
@@ -177,6 +442,7 @@ func readTable(r io.Reader, w io.Writer, tx *taxonomy.Taxonomy) error {
 		if err != nil {
 			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
 		}
+		logger.ReadRows(1)
 
 		var species, taxon string
 		if f, ok := fields["species"]; ok {
@@ -312,17 +578,17 @@ func readTable(r io.Reader, w io.Writer, tx *taxonomy.Taxonomy) error {
 					year = 1700
 				}
 			}
-			var month int
+			month := 1
 			if f, ok := fields["month"]; ok {
 				month, err = strconv.Atoi(row[f])
-				if err != nil || month > 12 {
+				if err != nil || month < 1 || month > 12 {
 					month = 1
 				}
 			}
-			var day int
+			day := 1
 			if f, ok := fields["day"]; ok {
 				day, err = strconv.Atoi(row[f])
-				if err != nil || day > 31 {
+				if err != nil || day < 1 || day > 31 {
 					day = 1
 				}
 			}
@@ -408,14 +674,53 @@ func readTable(r io.Reader, w io.Writer, tx *taxonomy.Taxonomy) error {
 			reference,
 			license,
 		}
-		if err := out.Write(nr); err != nil {
+		if err := out.write(nr); err != nil {
 			return fmt.Errorf("when writing on %q: %v", output, err)
 		}
+		logger.WriteRows(1)
+	}
+	return nil
+}
+
+// A tsvWriter writes the records
+// in a TSV file compatible with RFC 4180.
+type tsvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newTSVWriter(w io.Writer) recordWriter {
+	out := csv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+	return &tsvWriter{w: out}
+}
+
+func (tw *tsvWriter) writeHeader() error {
+	if tw.header {
+		return nil
 	}
+	tw.header = true
+	if wktGeom {
+		return tw.w.Write(append(outFields[:len(outFields):len(outFields)], geomField))
+	}
+	return tw.w.Write(outFields)
+}
 
-	out.Flush()
-	if err := out.Error(); err != nil {
-		return fmt.Errorf("when writing on %q: %v", output, err)
+func (tw *tsvWriter) write(rec []string) error {
+	if err := tw.writeHeader(); err != nil {
+		return err
 	}
-	return nil
+	if wktGeom {
+		rec = append(rec, wktPoint(rec))
+	}
+	return tw.w.Write(rec)
+}
+
+func (tw *tsvWriter) flush() error {
+	if err := tw.writeHeader(); err != nil {
+		return err
+	}
+	tw.w.Flush()
+	return tw.w.Error()
 }
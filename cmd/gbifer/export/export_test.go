@@ -0,0 +1,37 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package export
+
+import "io"
+
+// Fields of an exported record.
+var OutFields = outFields
+
+const (
+	FSpecies     = fSpecies
+	FLatitude    = fLatitude
+	FLongitude   = fLongitude
+	FUncertainty = fUncertainty
+	FDate        = fDate
+	FLocality    = fLocality
+)
+
+// Record encodes a row
+// in the SQLite record format.
+var Record = record
+
+// WriteGPKG writes the records
+// as a points layer of a GeoPackage,
+// in a table with the given name.
+func WriteGPKG(w io.Writer, table string, recs [][]string) error {
+	tableName = table
+	gw := newGPKGWriter(w)
+	for _, r := range recs {
+		if err := gw.write(r); err != nil {
+			return err
+		}
+	}
+	return gw.flush()
+}
@@ -0,0 +1,277 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package export
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gpkgTime is the format of the DATETIME values
+// of a GeoPackage.
+const gpkgTime = "2006-01-02T15:04:05.000Z"
+
+// wgs84 is the definition of the WGS 84 spatial reference system.
+const wgs84 = `GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563,AUTHORITY["EPSG","7030"]],AUTHORITY["EPSG","6326"]],PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],AXIS["Latitude",NORTH],AXIS["Longitude",EAST],AUTHORITY["EPSG","4326"]]`
+
+// Tables required by the GeoPackage specification
+// (version 1.2).
+const (
+	srsSQL = `CREATE TABLE gpkg_spatial_ref_sys (srs_name TEXT NOT NULL, srs_id INTEGER NOT NULL PRIMARY KEY, organization TEXT NOT NULL, organization_coordsys_id INTEGER NOT NULL, definition  TEXT NOT NULL, description TEXT)`
+
+	contentsSQL = `CREATE TABLE gpkg_contents (table_name TEXT NOT NULL PRIMARY KEY, data_type TEXT NOT NULL, identifier TEXT UNIQUE, description TEXT DEFAULT '', last_change DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')), min_x DOUBLE, min_y DOUBLE, max_x DOUBLE, max_y DOUBLE, srs_id INTEGER, CONSTRAINT fk_gc_r_srs_id FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys(srs_id))`
+
+	geomColsSQL = `CREATE TABLE gpkg_geometry_columns (table_name TEXT NOT NULL, column_name TEXT NOT NULL, geometry_type_name TEXT NOT NULL, srs_id INTEGER NOT NULL, z TINYINT NOT NULL, m TINYINT NOT NULL, CONSTRAINT pk_geom_cols PRIMARY KEY (table_name, column_name), CONSTRAINT uk_gc_table_name UNIQUE (table_name), CONSTRAINT fk_gc_tn FOREIGN KEY (table_name) REFERENCES gpkg_contents(table_name), CONSTRAINT fk_gc_srs FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys (srs_id))`
+
+	sequenceSQL = `CREATE TABLE sqlite_sequence(name,seq)`
+)
+
+// gpkgTypes are the SQL types
+// of the fields of an exported record.
+var gpkgTypes = [...]string{
+	fSpecies:      "TEXT",
+	fSpeciesID:    "INTEGER",
+	fLatitude:     "DOUBLE",
+	fLongitude:    "DOUBLE",
+	fUncertainty:  "INTEGER",
+	fGBIFID:       "INTEGER",
+	fCatalog:      "TEXT",
+	fOccurrenceID: "TEXT",
+	fDate:         "DATETIME",
+	fCountry:      "TEXT",
+	fProvince:     "TEXT",
+	fCounty:       "TEXT",
+	fLocality:     "TEXT",
+	fTaxon:        "TEXT",
+	fTaxonID:      "INTEGER",
+	fDataset:      "TEXT",
+	fDatasetID:    "TEXT",
+	fPublisher:    "TEXT",
+	fReference:    "TEXT",
+	fLicense:      "TEXT",
+}
+
+// gpkgType returns the SQL type
+// of a field of an exported record.
+func gpkgType(f int) string {
+	tp, ok := schemaTypes[f]
+	if !ok {
+		return gpkgTypes[f]
+	}
+	switch tp {
+	case "integer":
+		return "INTEGER"
+	case "float":
+		return "DOUBLE"
+	}
+	return "TEXT"
+}
+
+// A gpkgWriter writes the records
+// as a points layer of a GeoPackage.
+type gpkgWriter struct {
+	w   io.Writer
+	db  *sqliteFile
+	tb  *tableBuilder
+	err error
+
+	rows                   int64
+	minX, minY, maxX, maxY float64
+}
+
+func newGPKGWriter(w io.Writer) recordWriter {
+	gw := &gpkgWriter{
+		w:    w,
+		minX: math.Inf(1),
+		minY: math.Inf(1),
+		maxX: math.Inf(-1),
+		maxY: math.Inf(-1),
+	}
+	gw.db, gw.err = newSQLiteFile()
+	if gw.err == nil {
+		gw.tb = newTableBuilder(gw.db)
+	}
+	return gw
+}
+
+func (gw *gpkgWriter) write(rec []string) error {
+	if gw.err != nil {
+		return gw.err
+	}
+
+	lat, err := strconv.ParseFloat(rec[fLatitude], 64)
+	if err != nil {
+		return err
+	}
+	lon, err := strconv.ParseFloat(rec[fLongitude], 64)
+	if err != nil {
+		return err
+	}
+	gw.minX = math.Min(gw.minX, lon)
+	gw.maxX = math.Max(gw.maxX, lon)
+	gw.minY = math.Min(gw.minY, lat)
+	gw.maxY = math.Max(gw.maxY, lat)
+
+	// the fid is the rowid
+	vals := []any{nil, gpkgPoint(lon, lat)}
+	for i, v := range withNulls(rec) {
+		vals = append(vals, gpkgValue(gpkgType(i), v))
+	}
+	gw.rows++
+	return gw.tb.insert(gw.rows, record(vals...))
+}
+
+func (gw *gpkgWriter) flush() error {
+	if gw.err != nil {
+		return gw.err
+	}
+	db := gw.db
+	db.userVersion = 10200
+	db.appID = 0x47504B47 // "GPKG"
+
+	fRoot, err := gw.tb.finish()
+	if err != nil {
+		db.close()
+		return err
+	}
+	if err := gw.writeTables(fRoot); err != nil {
+		db.close()
+		return err
+	}
+	return db.writeTo(gw.w)
+}
+
+// writeTables writes the GeoPackage tables
+// and the schema of the database.
+func (gw *gpkgWriter) writeTables(fRoot uint32) error {
+	db := gw.db
+
+	// spatial reference systems
+	srs := newTableBuilder(db)
+	if err := srs.insert(-1, record("Undefined cartesian SRS", nil, "NONE", int64(-1), "undefined", "undefined cartesian coordinate reference system")); err != nil {
+		return err
+	}
+	if err := srs.insert(0, record("Undefined geographic SRS", nil, "NONE", int64(0), "undefined", "undefined geographic coordinate reference system")); err != nil {
+		return err
+	}
+	if err := srs.insert(4326, record("WGS 84 geodetic", nil, "EPSG", int64(4326), wgs84, "longitude/latitude coordinates in decimal degrees on the WGS 84 spheroid")); err != nil {
+		return err
+	}
+	srsRoot, err := srs.finish()
+	if err != nil {
+		return err
+	}
+	db.addSchema("table", "gpkg_spatial_ref_sys", "gpkg_spatial_ref_sys", srsRoot, srsSQL)
+
+	// contents
+	var minX, minY, maxX, maxY any
+	if gw.rows > 0 {
+		minX, minY, maxX, maxY = gw.minX, gw.minY, gw.maxX, gw.maxY
+	}
+	now := time.Now().UTC().Format(gpkgTime)
+	cont := newTableBuilder(db)
+	if err := cont.insert(1, record(tableName, "features", tableName, "GBIF occurrences", now, minX, minY, maxX, maxY, int64(4326))); err != nil {
+		return err
+	}
+	contRoot, err := cont.finish()
+	if err != nil {
+		return err
+	}
+	contPK, err := db.writeIndex(record(tableName, int64(1)))
+	if err != nil {
+		return err
+	}
+	contID, err := db.writeIndex(record(tableName, int64(1)))
+	if err != nil {
+		return err
+	}
+	db.addSchema("table", "gpkg_contents", "gpkg_contents", contRoot, contentsSQL)
+	db.addSchema("index", "sqlite_autoindex_gpkg_contents_1", "gpkg_contents", contPK, "")
+	db.addSchema("index", "sqlite_autoindex_gpkg_contents_2", "gpkg_contents", contID, "")
+
+	// geometry columns
+	gc := newTableBuilder(db)
+	if err := gc.insert(1, record(tableName, "geom", "POINT", int64(4326), int64(0), int64(0))); err != nil {
+		return err
+	}
+	gcRoot, err := gc.finish()
+	if err != nil {
+		return err
+	}
+	gcPK, err := db.writeIndex(record(tableName, "geom", int64(1)))
+	if err != nil {
+		return err
+	}
+	gcTable, err := db.writeIndex(record(tableName, int64(1)))
+	if err != nil {
+		return err
+	}
+	db.addSchema("table", "gpkg_geometry_columns", "gpkg_geometry_columns", gcRoot, geomColsSQL)
+	db.addSchema("index", "sqlite_autoindex_gpkg_geometry_columns_1", "gpkg_geometry_columns", gcPK, "")
+	db.addSchema("index", "sqlite_autoindex_gpkg_geometry_columns_2", "gpkg_geometry_columns", gcTable, "")
+
+	// features
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (fid INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, geom POINT", quoteIdent(tableName))
+	for i, f := range outFields {
+		fmt.Fprintf(&b, ", %s %s", quoteIdent(f), gpkgType(i))
+	}
+	b.WriteString(")")
+	db.addSchema("table", tableName, tableName, fRoot, b.String())
+
+	// the sequence of the autoincrement fid
+	seq := newTableBuilder(db)
+	if err := seq.insert(1, record(tableName, gw.rows)); err != nil {
+		return err
+	}
+	seqRoot, err := seq.finish()
+	if err != nil {
+		return err
+	}
+	db.addSchema("table", "sqlite_sequence", "sqlite_sequence", seqRoot, sequenceSQL)
+	return nil
+}
+
+// gpkgValue returns the value of a field
+// of the given SQL type.
+func gpkgValue(tp, v string) any {
+	if v == "" {
+		return nil
+	}
+	switch tp {
+	case "INTEGER":
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	case "DOUBLE":
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	case "DATETIME":
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t.UTC().Format(gpkgTime)
+		}
+	}
+	return v
+}
+
+// gpkgPoint returns a point
+// in the GeoPackage binary geometry format.
+func gpkgPoint(x, y float64) []byte {
+	b := []byte{'G', 'P', 0, 1} // version 0, little endian, no envelope
+	b = binary.LittleEndian.AppendUint32(b, 4326)
+
+	// WKB point
+	b = append(b, 1)
+	b = binary.LittleEndian.AppendUint32(b, 1)
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(x))
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(y))
+	return b
+}
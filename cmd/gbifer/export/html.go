@@ -0,0 +1,372 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package export
+
+import (
+	"cmp"
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+)
+
+// Size of the map of the HTML report,
+// in pixels.
+const (
+	mapWidth  = 1080
+	mapHeight = mapWidth / 2
+)
+
+// maxUncertainty is the uncertainty,
+// in meters,
+// above which a record is reported
+// as having a large uncertainty.
+const maxUncertainty = 10_000
+
+// A count is a row of a summary table.
+type count struct {
+	Name    string
+	Records int64
+	Other   int // number of species or countries
+
+	set map[string]bool
+}
+
+// An htmlWriter writes a summary report
+// of the records
+// as a self-contained HTML file.
+type htmlWriter struct {
+	w io.Writer
+
+	records   int64
+	species   map[string]*count
+	countries map[string]*count
+	years     map[int]*count
+	datasets  map[string]bool
+
+	noDate        int64
+	noCountry     int64
+	noUncertainty int64
+	largeUnc      int64
+	unc           []int64
+	coords        map[[2]float64]bool
+
+	// points of the map
+	points map[[2]int]bool
+}
+
+func newHTMLWriter(w io.Writer) recordWriter {
+	return &htmlWriter{
+		w:         w,
+		species:   make(map[string]*count),
+		countries: make(map[string]*count),
+		years:     make(map[int]*count),
+		datasets:  make(map[string]bool),
+		coords:    make(map[[2]float64]bool),
+		points:    make(map[[2]int]bool),
+	}
+}
+
+func (hw *htmlWriter) write(rec []string) error {
+	lat, err := strconv.ParseFloat(rec[fLatitude], 64)
+	if err != nil {
+		return err
+	}
+	lon, err := strconv.ParseFloat(rec[fLongitude], 64)
+	if err != nil {
+		return err
+	}
+	hw.records++
+
+	sp := rec[fSpecies]
+	country := rec[fCountry]
+	s, ok := hw.species[sp]
+	if !ok {
+		if err := memory.Add(int64(len(sp)) + 128); err != nil {
+			return err
+		}
+		s = &count{Name: sp, set: make(map[string]bool)}
+		hw.species[sp] = s
+	}
+	s.Records++
+	if country != "" && !s.set[country] {
+		s.set[country] = true
+		s.Other++
+	}
+
+	if country == "" {
+		hw.noCountry++
+	} else {
+		c, ok := hw.countries[country]
+		if !ok {
+			c = &count{Name: country, set: make(map[string]bool)}
+			hw.countries[country] = c
+		}
+		c.Records++
+		if !c.set[sp] {
+			if err := memory.Add(int64(len(sp)) + 16); err != nil {
+				return err
+			}
+			c.set[sp] = true
+			c.Other++
+		}
+	}
+
+	// dates without a valid year
+	// are set at 1700
+	year, err := strconv.Atoi(rec[fDate][:4])
+	if err != nil || year <= 1700 {
+		hw.noDate++
+	} else {
+		y, ok := hw.years[year]
+		if !ok {
+			y = &count{Name: strconv.Itoa(year)}
+			hw.years[year] = y
+		}
+		y.Records++
+	}
+
+	if ds := rec[fDatasetID]; ds != "" && !hw.datasets[ds] {
+		if err := memory.Add(int64(len(ds)) + 16); err != nil {
+			return err
+		}
+		hw.datasets[ds] = true
+	}
+
+	unc, _ := strconv.ParseInt(rec[fUncertainty], 10, 64)
+	if unc <= 0 {
+		hw.noUncertainty++
+	} else {
+		if err := memory.Add(8); err != nil {
+			return err
+		}
+		hw.unc = append(hw.unc, unc)
+		if unc > maxUncertainty {
+			hw.largeUnc++
+		}
+	}
+
+	if k := [2]float64{lat, lon}; !hw.coords[k] {
+		if err := memory.Add(32); err != nil {
+			return err
+		}
+		hw.coords[k] = true
+	}
+
+	x := int((lon + 180) / 360 * mapWidth)
+	y := int((90 - lat) / 180 * mapHeight)
+	hw.points[[2]int{min(x, mapWidth-1), min(y, mapHeight-1)}] = true
+	return nil
+}
+
+// An htmlReport is the data
+// of an HTML report.
+type htmlReport struct {
+	Input     string
+	Date      string
+	Summary   []stat
+	QC        []stat
+	Species   []*count
+	Countries []*count
+	Years     []*count
+	Map       template.HTML
+}
+
+// A stat is a row of the statistics tables.
+type stat struct {
+	Name  string
+	Value string
+}
+
+func (hw *htmlWriter) flush() error {
+	rep := htmlReport{
+		Input: input,
+		Date:  time.Now().Format(time.DateOnly),
+		Map:   template.HTML(hw.svgMap()),
+	}
+
+	years := "-"
+	if len(hw.years) > 0 {
+		var first, last int
+		for y := range hw.years {
+			if first == 0 || y < first {
+				first = y
+			}
+			if y > last {
+				last = y
+			}
+		}
+		years = fmt.Sprintf("%d–%d", first, last)
+	}
+	rep.Summary = []stat{
+		{"Records", strconv.FormatInt(hw.records, 10)},
+		{"Species", strconv.Itoa(len(hw.species))},
+		{"Countries", strconv.Itoa(len(hw.countries))},
+		{"Datasets", strconv.Itoa(len(hw.datasets))},
+		{"Years", years},
+		{"Distinct coordinates", strconv.Itoa(len(hw.coords))},
+	}
+
+	median := "-"
+	if len(hw.unc) > 0 {
+		slices.Sort(hw.unc)
+		median = fmt.Sprintf("%d m", hw.unc[len(hw.unc)/2])
+	}
+	rep.QC = []stat{
+		{"Records without a date", percent(hw.noDate, hw.records)},
+		{"Records without a country", percent(hw.noCountry, hw.records)},
+		{"Records without coordinate uncertainty", percent(hw.noUncertainty, hw.records)},
+		{fmt.Sprintf("Records with uncertainty above %d km", maxUncertainty/1000), percent(hw.largeUnc, hw.records)},
+		{"Median coordinate uncertainty", median},
+	}
+
+	rep.Species = sortCounts(hw.species)
+	rep.Countries = sortCounts(hw.countries)
+	for _, y := range hw.years {
+		rep.Years = append(rep.Years, y)
+	}
+	slices.SortFunc(rep.Years, func(a, b *count) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return htmlTmpl.Execute(hw.w, rep)
+}
+
+// sortCounts returns the counts
+// sorted by the number of records.
+func sortCounts[K comparable](m map[K]*count) []*count {
+	ls := make([]*count, 0, len(m))
+	for _, c := range m {
+		ls = append(ls, c)
+	}
+	slices.SortFunc(ls, func(a, b *count) int {
+		if c := cmp.Compare(b.Records, a.Records); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return ls
+}
+
+func percent(n, total int64) string {
+	if total == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%d (%.1f%%)", n, float64(n)*100/float64(total))
+}
+
+// svgMap returns a map of the records
+// as an SVG image
+// in an equirectangular projection.
+func (hw *htmlWriter) svgMap() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="100%%">`, mapWidth, mapHeight)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#f4f8fb" stroke="#888"/>`, mapWidth, mapHeight)
+
+	// graticule every 30 degrees
+	b.WriteString(`<g stroke="#ccc" stroke-width="1">`)
+	for lon := -150; lon < 180; lon += 30 {
+		x := float64(lon+180) / 360 * mapWidth
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="0" x2="%.1f" y2="%d"/>`, x, x, mapHeight)
+	}
+	for lat := -60; lat < 90; lat += 30 {
+		y := float64(90-lat) / 180 * mapHeight
+		fmt.Fprintf(&b, `<line x1="0" y1="%.1f" x2="%d" y2="%.1f"/>`, y, mapWidth, y)
+	}
+	b.WriteString(`</g>`)
+
+	pts := make([][2]int, 0, len(hw.points))
+	for p := range hw.points {
+		pts = append(pts, p)
+	}
+	slices.SortFunc(pts, func(a, b [2]int) int {
+		if c := cmp.Compare(a[1], b[1]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a[0], b[0])
+	})
+	b.WriteString(`<g fill="#c0392b" fill-opacity="0.7">`)
+	for _, p := range pts {
+		fmt.Fprintf(&b, `<circle cx="%d" cy="%d" r="1.5"/>`, p[0], p[1])
+	}
+	b.WriteString(`</g></svg>`)
+	return b.String()
+}
+
+var htmlTmpl = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>GBIF occurrences: {{.Input}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 1100px; color: #222; }
+h1 { font-size: 1.6em; }
+h2 { font-size: 1.2em; margin-top: 2em; border-bottom: 1px solid #ccc; }
+table { border-collapse: collapse; margin: 0.5em 0; }
+th, td { padding: 0.2em 0.8em; border-bottom: 1px solid #eee; text-align: left; }
+td.n { text-align: right; }
+.tables { display: flex; flex-wrap: wrap; gap: 2em; align-items: flex-start; }
+.scroll { max-height: 30em; overflow-y: auto; }
+</style>
+</head>
+<body>
+<h1>GBIF occurrences</h1>
+<p>Input: <code>{{.Input}}</code>. Report generated on {{.Date}}.</p>
+
+<div class="tables">
+<div>
+<h2>Summary</h2>
+<table>
+{{range .Summary}}<tr><th>{{.Name}}</th><td class="n">{{.Value}}</td></tr>
+{{end}}</table>
+</div>
+<div>
+<h2>Quality control</h2>
+<table>
+{{range .QC}}<tr><th>{{.Name}}</th><td class="n">{{.Value}}</td></tr>
+{{end}}</table>
+</div>
+</div>
+
+<h2>Map</h2>
+{{.Map}}
+
+<div class="tables">
+<div>
+<h2>Records per species</h2>
+<div class="scroll">
+<table>
+<tr><th>Species</th><th>Records</th><th>Countries</th></tr>
+{{range .Species}}<tr><td><i>{{.Name}}</i></td><td class="n">{{.Records}}</td><td class="n">{{.Other}}</td></tr>
+{{end}}</table>
+</div>
+</div>
+<div>
+<h2>Records per country</h2>
+<div class="scroll">
+<table>
+<tr><th>Country</th><th>Records</th><th>Species</th></tr>
+{{range .Countries}}<tr><td>{{.Name}}</td><td class="n">{{.Records}}</td><td class="n">{{.Other}}</td></tr>
+{{end}}</table>
+</div>
+</div>
+<div>
+<h2>Records per year</h2>
+<div class="scroll">
+<table>
+<tr><th>Year</th><th>Records</th></tr>
+{{range .Years}}<tr><td>{{.Name}}</td><td class="n">{{.Records}}</td></tr>
+{{end}}</table>
+</div>
+</div>
+</div>
+</body>
+</html>
+`))
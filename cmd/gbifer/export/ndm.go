@@ -0,0 +1,120 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package export
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+)
+
+// An ndmSpecies is a species
+// with its points.
+type ndmSpecies struct {
+	name   string
+	points map[[2]float64]bool
+}
+
+// An ndmWriter writes the records
+// in the xydata format of NDM/VNDM.
+type ndmWriter struct {
+	w    io.Writer
+	spp  map[string]*ndmSpecies
+	rows int
+
+	minX, maxY float64
+}
+
+func newNDMWriter(w io.Writer) recordWriter {
+	return &ndmWriter{
+		w:    w,
+		spp:  make(map[string]*ndmSpecies),
+		minX: math.Inf(1),
+		maxY: math.Inf(-1),
+	}
+}
+
+func (nw *ndmWriter) write(rec []string) error {
+	lat, err := strconv.ParseFloat(rec[fLatitude], 64)
+	if err != nil {
+		return err
+	}
+	lon, err := strconv.ParseFloat(rec[fLongitude], 64)
+	if err != nil {
+		return err
+	}
+
+	name := strings.Join(strings.Fields(rec[fSpecies]), "_")
+	sp, ok := nw.spp[name]
+	if !ok {
+		if err := memory.Add(int64(len(name)) + 64); err != nil {
+			return err
+		}
+		sp = &ndmSpecies{name: name, points: make(map[[2]float64]bool)}
+		nw.spp[name] = sp
+	}
+	p := [2]float64{lon, lat}
+	if sp.points[p] {
+		return nil
+	}
+	if err := memory.Add(32); err != nil {
+		return err
+	}
+	sp.points[p] = true
+	nw.minX = math.Min(nw.minX, lon)
+	nw.maxY = math.Max(nw.maxY, lat)
+	return nil
+}
+
+func (nw *ndmWriter) flush() error {
+	spp := make([]*ndmSpecies, 0, len(nw.spp))
+	for _, sp := range nw.spp {
+		spp = append(spp, sp)
+	}
+	slices.SortFunc(spp, func(a, b *ndmSpecies) int {
+		return cmp.Compare(a.name, b.name)
+	})
+
+	w := bufio.NewWriter(nw.w)
+	fmt.Fprintf(w, "spp %d\n", len(spp))
+	if gridSize > 0 && len(spp) > 0 {
+		// the grid starts at the north-west corner
+		// of the records
+		x := math.Floor(nw.minX/gridSize) * gridSize
+		y := math.Ceil(nw.maxY/gridSize) * gridSize
+		fmt.Fprintf(w, "gridx %s %s\n", ndmFloat(x), ndmFloat(gridSize))
+		fmt.Fprintf(w, "gridy %s %s\n", ndmFloat(y), ndmFloat(gridSize))
+	}
+	fmt.Fprintf(w, "xydata\n")
+	for i, sp := range spp {
+		fmt.Fprintf(w, "sp %d [%s]\n", i, sp.name)
+
+		pts := make([][2]float64, 0, len(sp.points))
+		for p := range sp.points {
+			pts = append(pts, p)
+		}
+		slices.SortFunc(pts, func(a, b [2]float64) int {
+			if c := cmp.Compare(a[0], b[0]); c != 0 {
+				return c
+			}
+			return cmp.Compare(a[1], b[1])
+		})
+		for _, p := range pts {
+			fmt.Fprintf(w, "%s %s\n", ndmFloat(p[0]), ndmFloat(p[1]))
+		}
+	}
+	return w.Flush()
+}
+
+func ndmFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,127 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// pgTypes are the PostgreSQL types
+// of the fields of an exported record.
+var pgTypes = [...]string{
+	fSpecies:      "text",
+	fSpeciesID:    "bigint",
+	fLatitude:     "double precision",
+	fLongitude:    "double precision",
+	fUncertainty:  "bigint",
+	fGBIFID:       "bigint",
+	fCatalog:      "text",
+	fOccurrenceID: "text",
+	fDate:         "timestamptz",
+	fCountry:      "text",
+	fProvince:     "text",
+	fCounty:       "text",
+	fLocality:     "text",
+	fTaxon:        "text",
+	fTaxonID:      "bigint",
+	fDataset:      "text",
+	fDatasetID:    "text",
+	fPublisher:    "text",
+	fReference:    "text",
+	fLicense:      "text",
+}
+
+// pgType returns the PostgreSQL type
+// of a field of an exported record.
+func pgType(f int) string {
+	tp, ok := schemaTypes[f]
+	if !ok {
+		return pgTypes[f]
+	}
+	switch tp {
+	case "integer":
+		return "bigint"
+	case "float":
+		return "double precision"
+	}
+	return "text"
+}
+
+// A postGISWriter writes the records
+// as an SQL script for PostGIS.
+type postGISWriter struct {
+	w      *bufio.Writer
+	header bool
+	rows   int64
+}
+
+func newPostGISWriter(w io.Writer) recordWriter {
+	return &postGISWriter{w: bufio.NewWriter(w)}
+}
+
+func (pw *postGISWriter) writeHeader() {
+	if pw.header {
+		return
+	}
+	pw.header = true
+
+	tab := quoteIdent(tableName)
+	fmt.Fprintf(pw.w, "BEGIN;\n\n")
+	fmt.Fprintf(pw.w, "CREATE EXTENSION IF NOT EXISTS postgis;\n\n")
+	fmt.Fprintf(pw.w, "CREATE TABLE %s (\n", tab)
+	fmt.Fprintf(pw.w, "\tfid bigint PRIMARY KEY,\n")
+	for i, f := range outFields {
+		fmt.Fprintf(pw.w, "\t%s %s,\n", quoteIdent(f), pgType(i))
+	}
+	fmt.Fprintf(pw.w, "\tgeom geometry(Point, 4326)\n);\n\n")
+
+	cols := []string{"fid"}
+	for _, f := range outFields {
+		cols = append(cols, quoteIdent(f))
+	}
+	cols = append(cols, "geom")
+	fmt.Fprintf(pw.w, "COPY %s (%s) FROM stdin;\n", tab, strings.Join(cols, ", "))
+}
+
+func (pw *postGISWriter) write(rec []string) error {
+	pw.writeHeader()
+	pw.rows++
+
+	fmt.Fprintf(pw.w, "%d", pw.rows)
+	for _, v := range withNulls(rec) {
+		pw.w.WriteByte('\t')
+		if v == "" {
+			pw.w.WriteString(`\N`)
+			continue
+		}
+		pw.w.WriteString(copyEscape.Replace(v))
+	}
+	_, err := fmt.Fprintf(pw.w, "\tSRID=4326;POINT(%s %s)\n", rec[fLongitude], rec[fLatitude])
+	return err
+}
+
+func (pw *postGISWriter) flush() error {
+	pw.writeHeader()
+	tab := quoteIdent(tableName)
+	fmt.Fprintf(pw.w, "\\.\n\n")
+
+	idx := quoteIdent(tableName + "_geom_idx")
+	fmt.Fprintf(pw.w, "CREATE INDEX %s ON %s USING GIST (geom);\n\n", idx, tab)
+	fmt.Fprintf(pw.w, "COMMIT;\n\n")
+	fmt.Fprintf(pw.w, "ANALYZE %s;\n", tab)
+	return pw.w.Flush()
+}
+
+// copyEscape escapes the values
+// of the text format of the COPY statement.
+var copyEscape = strings.NewReplacer(
+	`\`, `\\`,
+	"\t", `\t`,
+	"\n", `\n`,
+	"\r", `\r`,
+)
@@ -0,0 +1,68 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package export_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/export"
+)
+
+func TestSchema(t *testing.T) {
+	sc := "column\ttype\tnullable\tmin\tmax\tvalues\r\n" +
+		"gbifID\ttext\tfalse\t2\t2\t\r\n" +
+		"county\tinteger\ttrue\t1\t9\t\r\n" +
+		"decimalLatitude\ttext\tfalse\t\t\t\r\n"
+	scFile := filepath.Join(t.TempDir(), "schema.tab")
+	if err := os.WriteFile(scFile, []byte(sc), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tab := "gbifID\tspecies\tspeciesKey\tdecimalLatitude\tdecimalLongitude\tcounty\r\n" +
+		"a1\tPuma concolor\t2435099\t-34.5\t-58.4\t3\r\n"
+
+	tests := map[string][]string{
+		"postgis": {
+			"\t\"gbifID\" text,\n",
+			"\t\"county\" bigint,\n",
+			// the latitude is not copied
+			// so it is not changed by the schema
+			"\t\"latitude\" double precision,\n",
+		},
+		"gpkg": {
+			`"gbifID" TEXT`,
+			`"county" INTEGER`,
+			`"latitude" DOUBLE`,
+		},
+	}
+	for format, want := range tests {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			export.Command.SetStdin(strings.NewReader(tab))
+			export.Command.SetStdout(&buf)
+			args := []string{"--tax", "", "--format", format, "--schema", scFile}
+			if err := export.Command.Execute(args); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := buf.String()
+			for _, w := range want {
+				if !strings.Contains(got, w) {
+					t.Errorf("output without %q", w)
+				}
+			}
+		})
+	}
+
+	// the schema is only valid in SQL formats
+	export.Command.SetStdin(strings.NewReader(tab))
+	export.Command.SetStdout(&bytes.Buffer{})
+	if err := export.Command.Execute([]string{"--tax", "", "--schema", scFile}); err == nil {
+		t.Errorf("expecting error for --schema in tsv format")
+	}
+}
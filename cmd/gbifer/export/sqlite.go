@@ -0,0 +1,446 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package export
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// This file implements a minimal writer
+// of SQLite database files,
+// as described in <https://www.sqlite.org/fileformat.html>.
+// Tables are written in rowid order,
+// and the database is written only once,
+// so there are no free pages.
+
+// pageSize is the size of a database page.
+const pageSize = 4096
+
+// lockPage is the page that contains
+// the lock bytes of the database
+// (at offset 1 GiB),
+// and that must not be used.
+const lockPage = 1<<30/pageSize + 1
+
+// B-tree page types.
+const (
+	indexLeaf     = 0x0a
+	tableInterior = 0x05
+	tableLeaf     = 0x0d
+)
+
+// A sqliteFile is a SQLite database
+// in construction.
+// Except for the first page,
+// that has the database header and the schema,
+// the pages are written in a temporary file,
+// in order.
+type sqliteFile struct {
+	tmp  *os.File
+	last uint32 // last written page
+	buf  []byte
+
+	schema [][]byte // records of the schema table
+
+	// header values
+	userVersion uint32
+	appID       uint32
+}
+
+func newSQLiteFile() (*sqliteFile, error) {
+	f, err := os.CreateTemp("", "gbifer-*.sqlite")
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteFile{
+		tmp:  f,
+		last: 1,
+		buf:  make([]byte, pageSize),
+	}, nil
+}
+
+// next returns the number of the next page
+// to be written.
+func (db *sqliteFile) next() uint32 {
+	n := db.last + 1
+	if n == lockPage {
+		n++
+	}
+	return n
+}
+
+// writePage writes a page
+// and returns its page number.
+func (db *sqliteFile) writePage(p []byte) (uint32, error) {
+	n := db.next()
+	if n != db.last+1 {
+		// skip the lock page
+		clear(db.buf)
+		if _, err := db.tmp.Write(db.buf); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := db.tmp.Write(p); err != nil {
+		return 0, err
+	}
+	db.last = n
+	return n, nil
+}
+
+// addSchema adds an object to the schema table.
+// The sql value of the automatic indexes is empty.
+func (db *sqliteFile) addSchema(tp, name, table string, root uint32, sql string) {
+	var s any
+	if sql != "" {
+		s = sql
+	}
+	db.schema = append(db.schema, record(tp, name, table, int64(root), s))
+}
+
+// writeTo writes the database
+// and removes the temporary file.
+func (db *sqliteFile) writeTo(w io.Writer) error {
+	defer db.close()
+
+	// the first page
+	// has the header of the database
+	// and the schema table
+	p := make([]byte, pageSize)
+	copy(p, "SQLite format 3\x00")
+	binary.BigEndian.PutUint16(p[16:], pageSize)
+	p[18] = 1 // write version
+	p[19] = 1 // read version
+	p[21] = 64
+	p[22] = 32
+	p[23] = 32
+	binary.BigEndian.PutUint32(p[24:], 1) // change counter
+	binary.BigEndian.PutUint32(p[28:], db.last)
+	binary.BigEndian.PutUint32(p[40:], 1) // schema cookie
+	binary.BigEndian.PutUint32(p[44:], 4) // schema format
+	binary.BigEndian.PutUint32(p[56:], 1) // UTF-8
+	binary.BigEndian.PutUint32(p[60:], db.userVersion)
+	binary.BigEndian.PutUint32(p[68:], db.appID)
+	binary.BigEndian.PutUint32(p[92:], 1) // version valid for
+	binary.BigEndian.PutUint32(p[96:], 3040001)
+
+	var cells [][]byte
+	for i, r := range db.schema {
+		c, err := db.tableCell(int64(i+1), r)
+		if err != nil {
+			return err
+		}
+		cells = append(cells, c)
+	}
+	if !fits(100, 8, cells) {
+		return errors.New("database schema too large")
+	}
+	buildPage(p, 100, tableLeaf, cells, 0)
+	if _, err := w.Write(p); err != nil {
+		return err
+	}
+
+	if _, err := db.tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, db.tmp); err != nil {
+		return err
+	}
+	return nil
+}
+
+// close closes and removes the temporary file.
+func (db *sqliteFile) close() {
+	db.tmp.Close()
+	os.Remove(db.tmp.Name())
+}
+
+// tableCell returns a cell of a table leaf page,
+// writing the overflow pages if the record is too large.
+func (db *sqliteFile) tableCell(rowid int64, rec []byte) ([]byte, error) {
+	c := appendVarint(nil, uint64(len(rec)))
+	c = appendVarint(c, uint64(rowid))
+
+	const u = pageSize
+	maxLocal := u - 35
+	if len(rec) <= maxLocal {
+		return append(c, rec...), nil
+	}
+
+	minLocal := (u-12)*32/255 - 23
+	local := minLocal + (len(rec)-minLocal)%(u-4)
+	if local > maxLocal {
+		local = minLocal
+	}
+	c = append(c, rec[:local]...)
+	c = binary.BigEndian.AppendUint32(c, db.next())
+
+	rest := rec[local:]
+	p := make([]byte, pageSize)
+	for len(rest) > 0 {
+		clear(p)
+		n := copy(p[4:], rest)
+		rest = rest[n:]
+		if len(rest) > 0 {
+			// the next overflow page
+			// is the page after this one
+			nx := db.next() + 1
+			if nx == lockPage {
+				nx++
+			}
+			binary.BigEndian.PutUint32(p, nx)
+		}
+		if _, err := db.writePage(p); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// A tableBuilder writes the pages of a table B-tree.
+// Rows must be inserted in rowid order.
+type tableBuilder struct {
+	db     *sqliteFile
+	cells  [][]byte
+	maxKey int64
+
+	// children are the pages
+	// of the level being built
+	children []child
+}
+
+// A child is a page of a B-tree
+// and its largest key.
+type child struct {
+	page uint32
+	key  int64
+}
+
+func newTableBuilder(db *sqliteFile) *tableBuilder {
+	return &tableBuilder{db: db}
+}
+
+// insert adds a row to the table.
+func (tb *tableBuilder) insert(rowid int64, rec []byte) error {
+	c, err := tb.db.tableCell(rowid, rec)
+	if err != nil {
+		return err
+	}
+	if !fits(0, 8, append(tb.cells, c)) {
+		if err := tb.flushLeaf(); err != nil {
+			return err
+		}
+	}
+	tb.cells = append(tb.cells, c)
+	tb.maxKey = rowid
+	return nil
+}
+
+func (tb *tableBuilder) flushLeaf() error {
+	p := make([]byte, pageSize)
+	buildPage(p, 0, tableLeaf, tb.cells, 0)
+	n, err := tb.db.writePage(p)
+	if err != nil {
+		return err
+	}
+	tb.children = append(tb.children, child{page: n, key: tb.maxKey})
+	tb.cells = tb.cells[:0]
+	return nil
+}
+
+// finish writes the interior pages of the table
+// and returns the root page.
+func (tb *tableBuilder) finish() (uint32, error) {
+	if len(tb.cells) > 0 || len(tb.children) == 0 {
+		if err := tb.flushLeaf(); err != nil {
+			return 0, err
+		}
+	}
+
+	level := tb.children
+	for len(level) > 1 {
+		var up []child
+		for len(level) > 0 {
+			// the last child of the page
+			// is the right-most pointer
+			var cells [][]byte
+			i := 0
+			for ; i < len(level)-1; i++ {
+				c := binary.BigEndian.AppendUint32(nil, level[i].page)
+				c = appendVarint(c, uint64(level[i].key))
+				if !fits(0, 12, append(cells, c)) {
+					break
+				}
+				cells = append(cells, c)
+			}
+			if len(level)-i == 2 && i > 0 {
+				// keep at least two children
+				// in the last page
+				i--
+				cells = cells[:i]
+			}
+			right := level[i]
+			p := make([]byte, pageSize)
+			buildPage(p, 0, tableInterior, cells, right.page)
+			n, err := tb.db.writePage(p)
+			if err != nil {
+				return 0, err
+			}
+			up = append(up, child{page: n, key: right.key})
+			level = level[i+1:]
+		}
+		level = up
+	}
+	return level[0].page, nil
+}
+
+// writeIndex writes an index
+// that fits in a single page,
+// from records sorted by key.
+func (db *sqliteFile) writeIndex(recs ...[]byte) (uint32, error) {
+	maxLocal := (pageSize-12)*64/255 - 23
+	var cells [][]byte
+	for _, r := range recs {
+		if len(r) > maxLocal {
+			return 0, fmt.Errorf("index record too large")
+		}
+		c := appendVarint(nil, uint64(len(r)))
+		cells = append(cells, append(c, r...))
+	}
+	if !fits(0, 8, cells) {
+		return 0, fmt.Errorf("index too large")
+	}
+	p := make([]byte, pageSize)
+	buildPage(p, 0, indexLeaf, cells, 0)
+	return db.writePage(p)
+}
+
+// fits returns true if the cells fit in a page
+// with a header of the given size,
+// at the given offset.
+func fits(off, header int, cells [][]byte) bool {
+	size := off + header
+	for _, c := range cells {
+		size += len(c) + 2
+	}
+	return size <= pageSize
+}
+
+// buildPage builds a B-tree page.
+func buildPage(p []byte, off int, tp byte, cells [][]byte, right uint32) {
+	p[off] = tp
+	binary.BigEndian.PutUint16(p[off+3:], uint16(len(cells)))
+	ptr := off + 8
+	if tp == tableInterior {
+		binary.BigEndian.PutUint32(p[off+8:], right)
+		ptr += 4
+	}
+
+	end := pageSize
+	for _, c := range cells {
+		end -= len(c)
+		copy(p[end:], c)
+		binary.BigEndian.PutUint16(p[ptr:], uint16(end))
+		ptr += 2
+	}
+	if end == pageSize {
+		// 0 is interpreted as 65536
+		end = 0
+	}
+	binary.BigEndian.PutUint16(p[off+5:], uint16(end))
+}
+
+// record encodes a row
+// in the SQLite record format.
+// Values can be nil (NULL),
+// int64, float64, string, or []byte.
+func record(vals ...any) []byte {
+	var types []byte
+	var body []byte
+	for _, v := range vals {
+		switch x := v.(type) {
+		case nil:
+			types = appendVarint(types, 0)
+		case int64:
+			t, n := intType(x)
+			types = appendVarint(types, t)
+			for i := n - 1; i >= 0; i-- {
+				body = append(body, byte(x>>(8*i)))
+			}
+		case float64:
+			types = appendVarint(types, 7)
+			body = binary.BigEndian.AppendUint64(body, math.Float64bits(x))
+		case string:
+			types = appendVarint(types, uint64(len(x))*2+13)
+			body = append(body, x...)
+		case []byte:
+			types = appendVarint(types, uint64(len(x))*2+12)
+			body = append(body, x...)
+		default:
+			panic(fmt.Sprintf("invalid SQLite value type %T", v))
+		}
+	}
+
+	// the header size includes itself
+	hs := len(types) + 1
+	if len(appendVarint(nil, uint64(hs))) > 1 {
+		hs++
+	}
+	r := appendVarint(nil, uint64(hs))
+	r = append(r, types...)
+	return append(r, body...)
+}
+
+// intType returns the serial type of an integer
+// and the number of bytes used to store it.
+func intType(x int64) (uint64, int) {
+	switch {
+	case x == 0:
+		return 8, 0
+	case x == 1:
+		return 9, 0
+	case x >= math.MinInt8 && x <= math.MaxInt8:
+		return 1, 1
+	case x >= math.MinInt16 && x <= math.MaxInt16:
+		return 2, 2
+	case x >= -1<<23 && x < 1<<23:
+		return 3, 3
+	case x >= math.MinInt32 && x <= math.MaxInt32:
+		return 4, 4
+	case x >= -1<<47 && x < 1<<47:
+		return 5, 6
+	}
+	return 6, 8
+}
+
+// appendVarint appends a SQLite variable length integer.
+func appendVarint(b []byte, v uint64) []byte {
+	if v>>56 != 0 {
+		// the ninth byte uses all the bits
+		var buf [9]byte
+		buf[8] = byte(v)
+		v >>= 8
+		for i := 7; i >= 0; i-- {
+			buf[i] = byte(v&0x7f) | 0x80
+			v >>= 7
+		}
+		return append(b, buf[:]...)
+	}
+
+	var buf [8]byte
+	i := len(buf) - 1
+	buf[i] = byte(v & 0x7f)
+	v >>= 7
+	for v != 0 {
+		i--
+		buf[i] = byte(v&0x7f) | 0x80
+		v >>= 7
+	}
+	return append(b, buf[i:]...)
+}
@@ -0,0 +1,317 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package export_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/export"
+)
+
+func TestRecord(t *testing.T) {
+	tests := map[string][]any{
+		"null":    {nil},
+		"small":   {int64(0), int64(1), int64(-1), int64(127), int64(-128)},
+		"large":   {int64(300), int64(-40000), int64(1 << 23), int64(math.MinInt32), int64(1 << 40), int64(math.MaxInt64), int64(math.MinInt64)},
+		"float":   {1.5, -0.0001, math.MaxFloat64},
+		"text":    {"", "Puma concolor", "ñandú"},
+		"blob":    {[]byte{}, []byte{0, 1, 2, 0xff}},
+		"mixed":   {nil, "a", int64(4326), 2.5, []byte("GP"), nil},
+		"long":    {strings.Repeat("x", 70000), int64(7)},
+		"columns": make([]any, 200),
+	}
+
+	for name, vals := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := decodeRecord(export.Record(vals...))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, vals) {
+				t.Errorf("got %v, want %v", got, vals)
+			}
+		})
+	}
+}
+
+func TestGPKG(t *testing.T) {
+	const rows = 3000
+	var recs [][]string
+	for i := 0; i < rows; i++ {
+		rec := make([]string, len(export.OutFields))
+		rec[export.FSpecies] = fmt.Sprintf("Species %d", i%17)
+		rec[export.FLatitude] = strconv.FormatFloat(-float64(i%90), 'f', 7, 64)
+		rec[export.FLongitude] = strconv.FormatFloat(float64(i%180), 'f', 7, 64)
+		rec[export.FUncertainty] = strconv.Itoa(i % 3 * 10)
+		rec[export.FDate] = "1999-05-10T00:00:00Z"
+		if i%5 == 0 {
+			rec[export.FDate] = "1700-01-01T00:00:00Z"
+		}
+		rec[export.FLocality] = fmt.Sprintf("locality %d", i)
+		if i%500 == 0 {
+			// records with overflow pages
+			rec[export.FLocality] = strings.Repeat("long locality ", 1000+i)
+		}
+		recs = append(recs, rec)
+	}
+
+	var buf bytes.Buffer
+	if err := export.WriteGPKG(&buf, "test", recs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db, err := newSQLiteReader(buf.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	schema, _, err := db.table(1)
+	if err != nil {
+		t.Fatalf("schema: unexpected error: %v", err)
+	}
+	root := -1
+	for _, r := range schema {
+		if r[0] == "table" && r[1] == "test" {
+			root = int(r[3].(int64))
+		}
+	}
+	if root < 0 {
+		t.Fatalf("table %q not found in schema", "test")
+	}
+
+	got, ids, err := db.table(root)
+	if err != nil {
+		t.Fatalf("table: unexpected error: %v", err)
+	}
+	if len(got) != rows {
+		t.Fatalf("rows: got %d, want %d", len(got), rows)
+	}
+	for i, r := range got {
+		if ids[i] != int64(i+1) {
+			t.Errorf("row %d: rowid: got %d, want %d", i, ids[i], i+1)
+		}
+		rec := recs[i]
+		// the fid and the geometry
+		// are the first columns
+		if r[0] != nil {
+			t.Errorf("row %d: fid: got %v, want NULL", i, r[0])
+		}
+		if g, ok := r[1].([]byte); !ok || string(g[:2]) != "GP" {
+			t.Errorf("row %d: geom: got %v", i, r[1])
+		}
+		if got, want := r[2+export.FSpecies], rec[export.FSpecies]; got != want {
+			t.Errorf("row %d: species: got %v, want %q", i, got, want)
+		}
+		if got, want := r[2+export.FLocality], rec[export.FLocality]; got != want {
+			t.Errorf("row %d: locality: got %v, want %q", i, got, want)
+		}
+
+		var date any = "1999-05-10T00:00:00.000Z"
+		if i%5 == 0 {
+			date = nil
+		}
+		if got := r[2+export.FDate]; got != date {
+			t.Errorf("row %d: date: got %v, want %v", i, got, date)
+		}
+		var unc any = int64(i % 3 * 10)
+		if unc == int64(0) {
+			unc = nil
+		}
+		if got := r[2+export.FUncertainty]; got != unc {
+			t.Errorf("row %d: uncertainty: got %v, want %v", i, got, unc)
+		}
+	}
+}
+
+// A sqliteReader reads the tables
+// of a SQLite database file.
+type sqliteReader struct {
+	data     []byte
+	pageSize int
+}
+
+func newSQLiteReader(data []byte) (*sqliteReader, error) {
+	if len(data) < 100 || string(data[:16]) != "SQLite format 3\x00" {
+		return nil, fmt.Errorf("invalid header")
+	}
+	db := &sqliteReader{
+		data:     data,
+		pageSize: int(binary.BigEndian.Uint16(data[16:])),
+	}
+	if db.pageSize == 1 {
+		db.pageSize = 65536
+	}
+	pages := int(binary.BigEndian.Uint32(data[28:]))
+	if pages*db.pageSize != len(data) {
+		return nil, fmt.Errorf("database size: got %d pages, want %d", len(data)/db.pageSize, pages)
+	}
+	return db, nil
+}
+
+func (db *sqliteReader) page(n int) ([]byte, error) {
+	if n < 1 || n*db.pageSize > len(db.data) {
+		return nil, fmt.Errorf("invalid page %d", n)
+	}
+	return db.data[(n-1)*db.pageSize : n*db.pageSize], nil
+}
+
+// table returns the records of a table B-tree
+// and their rowids.
+func (db *sqliteReader) table(root int) (recs [][]any, ids []int64, err error) {
+	p, err := db.page(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	off := 0
+	if root == 1 {
+		off = 100
+	}
+	n := int(binary.BigEndian.Uint16(p[off+3:]))
+
+	switch p[off] {
+	case 0x05:
+		for i := 0; i <= n; i++ {
+			var child int
+			if i == n {
+				child = int(binary.BigEndian.Uint32(p[off+8:]))
+			} else {
+				c := int(binary.BigEndian.Uint16(p[off+12+2*i:]))
+				child = int(binary.BigEndian.Uint32(p[c:]))
+			}
+			r, id, err := db.table(child)
+			if err != nil {
+				return nil, nil, err
+			}
+			recs = append(recs, r...)
+			ids = append(ids, id...)
+		}
+	case 0x0d:
+		for i := 0; i < n; i++ {
+			c := int(binary.BigEndian.Uint16(p[off+8+2*i:]))
+			size, k := varint(p[c:])
+			c += k
+			id, k := varint(p[c:])
+			c += k
+			payload, err := db.payload(p[c:], int(size))
+			if err != nil {
+				return nil, nil, err
+			}
+			r, err := decodeRecord(payload)
+			if err != nil {
+				return nil, nil, err
+			}
+			recs = append(recs, r)
+			ids = append(ids, int64(id))
+		}
+	default:
+		return nil, nil, fmt.Errorf("page %d: invalid page type %d", root, p[off])
+	}
+	return recs, ids, nil
+}
+
+// payload returns the payload of a table leaf cell,
+// following the overflow pages.
+func (db *sqliteReader) payload(c []byte, size int) ([]byte, error) {
+	u := db.pageSize
+	maxLocal := u - 35
+	if size <= maxLocal {
+		return c[:size], nil
+	}
+	minLocal := (u-12)*32/255 - 23
+	local := minLocal + (size-minLocal)%(u-4)
+	if local > maxLocal {
+		local = minLocal
+	}
+
+	b := append([]byte{}, c[:local]...)
+	next := int(binary.BigEndian.Uint32(c[local:]))
+	for len(b) < size {
+		p, err := db.page(next)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, p[4:min(u, 4+size-len(b))]...)
+		next = int(binary.BigEndian.Uint32(p))
+	}
+	return b, nil
+}
+
+// decodeRecord decodes a record
+// in the SQLite record format.
+func decodeRecord(r []byte) ([]any, error) {
+	hs, k := varint(r)
+	if int(hs) > len(r) {
+		return nil, fmt.Errorf("invalid record header")
+	}
+	var types []uint64
+	for h := r[k:hs]; len(h) > 0; {
+		t, k := varint(h)
+		types = append(types, t)
+		h = h[k:]
+	}
+
+	var vals []any
+	body := r[hs:]
+	for _, t := range types {
+		switch {
+		case t == 0:
+			vals = append(vals, nil)
+		case t >= 1 && t <= 6:
+			n := [...]int{1, 2, 3, 4, 6, 8}[t-1]
+			if len(body) < n {
+				return nil, fmt.Errorf("short record")
+			}
+			// sign extension
+			x := int64(int8(body[0]))
+			for _, b := range body[1:n] {
+				x = x<<8 | int64(b)
+			}
+			vals = append(vals, x)
+			body = body[n:]
+		case t == 7:
+			vals = append(vals, math.Float64frombits(binary.BigEndian.Uint64(body)))
+			body = body[8:]
+		case t == 8:
+			vals = append(vals, int64(0))
+		case t == 9:
+			vals = append(vals, int64(1))
+		case t >= 12:
+			n := int(t-12) / 2
+			if len(body) < n {
+				return nil, fmt.Errorf("short record")
+			}
+			if t%2 == 1 {
+				vals = append(vals, string(body[:n]))
+			} else {
+				vals = append(vals, append([]byte{}, body[:n]...))
+			}
+			body = body[n:]
+		default:
+			return nil, fmt.Errorf("invalid serial type %d", t)
+		}
+	}
+	if len(body) != 0 {
+		return nil, fmt.Errorf("%d bytes after the record", len(body))
+	}
+	return vals, nil
+}
+
+// varint decodes a SQLite variable length integer.
+func varint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 8 && i < len(b); i++ {
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return v<<8 | uint64(b[8]), 9
+}
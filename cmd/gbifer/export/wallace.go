@@ -0,0 +1,63 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package export
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// wallaceFields are the columns
+// expected by Wallace.
+var wallaceFields = []string{
+	"scientific_name",
+	"longitude",
+	"latitude",
+}
+
+// A wallaceWriter writes the records
+// as a CSV file for Wallace and ENMeval.
+type wallaceWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newWallaceWriter(w io.Writer) recordWriter {
+	return &wallaceWriter{w: csv.NewWriter(w)}
+}
+
+func (ww *wallaceWriter) writeHeader() error {
+	if ww.header {
+		return nil
+	}
+	ww.header = true
+	if wktGeom {
+		return ww.w.Write(append(wallaceFields[:len(wallaceFields):len(wallaceFields)], geomField))
+	}
+	return ww.w.Write(wallaceFields)
+}
+
+func (ww *wallaceWriter) write(rec []string) error {
+	if err := ww.writeHeader(); err != nil {
+		return err
+	}
+	row := []string{
+		rec[fSpecies],
+		rec[fLongitude],
+		rec[fLatitude],
+	}
+	if wktGeom {
+		row = append(row, wktPoint(rec))
+	}
+	return ww.w.Write(row)
+}
+
+func (ww *wallaceWriter) flush() error {
+	if err := ww.writeHeader(); err != nil {
+		return err
+	}
+	ww.w.Flush()
+	return ww.w.Error()
+}
@@ -0,0 +1,37 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// geomField is the name of the WKT geometry column.
+const geomField = "geometry"
+
+// prjWGS84 is the definition of WGS 84
+// as used in the .prj files of ESRI.
+const prjWGS84 = `GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`
+
+// wktPoint returns the point of a record
+// in the well-known text format.
+func wktPoint(rec []string) string {
+	return "POINT (" + rec[fLongitude] + " " + rec[fLatitude] + ")"
+}
+
+// prjName returns the name of the .prj file
+// of an output file.
+func prjName(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name)) + ".prj"
+}
+
+// writePRJ writes the spatial reference
+// of the output file
+// in a .prj file.
+func writePRJ(name string) error {
+	return os.WriteFile(prjName(name), []byte(prjWGS84+"\n"), 0644)
+}
@@ -0,0 +1,361 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package fetch implements a command to retrieve
+// occurrence records
+// from the GBIF occurrence search.
+package fetch
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/journal"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `fetch [--taxon <key>] [--dataset <key>] [--country <code>]
+	[--year <value>] [--geometry <wkt> | --geometry-file <file>]
+	[--georeferenced] [--progress] [--journal <file>]
+	[-o|--output <file>]`,
+	Short: "fetch records from the GBIF occurrence search",
+	Long: `
+Command fetch retrieves the occurrence records that match a query from the
+GBIF occurrence search, and prints them as a GBIF occurrence table, with the
+columns of a GBIF simple download (the "SIMPLE_CSV" format), so the table can
+be used with the other commands.
+
+The query is defined by the following flags (at least one is required):
+
+	--taxon          the GBIF key of a taxon (records of the taxon and
+	                 its descendants).
+	--dataset        the key of a dataset.
+	--country        the ISO 3166 code of a country.
+	--year           a year, or a range of years (for example
+	                 "1990,2000").
+	--geometry       a polygon, as a POLYGON or MULTIPOLYGON in WKT
+	                 format (for example,
+	                 "POLYGON((-60 -35, -58 -35, -58 -33, -60 -33, -60 -35))").
+	                 The points of the outer rings must be in
+	                 counter-clockwise order.
+	--geometry-file  a file with the polygon, in WKT format.
+
+If the flag --georeferenced is defined, only the records with coordinates,
+and without geospatial issues, will be retrieved.
+
+The occurrence search of GBIF can not retrieve more than 100 000 records, and
+by default the number of retrieved records is limited (see "gbifer help"). For
+larger queries, use a GBIF download.
+
+If the flag --progress is defined, the number of retrieved records, and the
+estimated remaining time, will be reported periodically in the standard error.
+
+If the flag --journal is defined, the retrieved records will be recorded in
+the indicated file. If the command is interrupted (for example, by a network
+failure), running the command again with the same journal file will only
+retrieve the records that were not retrieved before. A journal can only be
+used with the same query, and the journal file is removed when the command
+finishes successfully.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+
+This command requires an internet connection.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var output string
+var taxonKey int64
+var datasetKey string
+var country string
+var year string
+var geometry string
+var geometryFile string
+var georeferenced bool
+var progress bool
+var journalFile string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().Int64Var(&taxonKey, "taxon", 0, "")
+	c.Flags().StringVar(&datasetKey, "dataset", "", "")
+	c.Flags().StringVar(&country, "country", "", "")
+	c.Flags().StringVar(&year, "year", "", "")
+	c.Flags().StringVar(&geometry, "geometry", "", "")
+	c.Flags().StringVar(&geometryFile, "geometry-file", "", "")
+	c.Flags().BoolVar(&georeferenced, "georeferenced", false, "")
+	c.Flags().BoolVar(&progress, "progress", false, "")
+	c.Flags().StringVar(&journalFile, "journal", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if geometry != "" && geometryFile != "" {
+		return c.UsageError("flags --geometry and --geometry-file can not be used together")
+	}
+	if geometryFile != "" {
+		b, err := os.ReadFile(geometryFile)
+		if err != nil {
+			return err
+		}
+		geometry = strings.Join(strings.Fields(string(b)), " ")
+	}
+	q := gbif.OccurrenceQuery{
+		TaxonKey:      taxonKey,
+		DatasetKey:    datasetKey,
+		Country:       country,
+		Geometry:      geometry,
+		Year:          year,
+		HasCoordinate: georeferenced,
+	}
+	if q.TaxonKey == 0 && q.DatasetKey == "" && q.Country == "" && q.Geometry == "" && q.Year == "" {
+		return c.UsageError("undefined query")
+	}
+
+	var jr *journal.Journal
+	if journalFile != "" {
+		var hash string
+		hash, err = journal.Hash("", strconv.FormatInt(taxonKey, 10), datasetKey, country, year, geometry, strconv.FormatBool(georeferenced))
+		if err != nil {
+			return err
+		}
+		jr, err = journal.Open(journalFile, hash)
+		if err != nil {
+			return err
+		}
+		if n := jr.Len(); n > 0 {
+			logger.Printf("resuming from journal %q: %d pages already retrieved", journalFile, n)
+		}
+		defer func() {
+			// the journal is only removed
+			// if the command was successful.
+			if err != nil {
+				jr.Close()
+				return
+			}
+			err = jr.Remove()
+		}()
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	var prog *logger.Counter
+	if progress {
+		prog = logger.NewCounter("records")
+		defer prog.Stop()
+	}
+
+	gbif.Open()
+	n, err := fetch(out, q, jr, prog)
+	if err != nil {
+		return err
+	}
+	logger.Add("fetched", n)
+	return nil
+}
+
+// fetch retrieves the records of a query
+// and writes them on w.
+// It returns the number of written records.
+func fetch(w io.Writer, q gbif.OccurrenceQuery, jr *journal.Journal, prog *logger.Counter) (int64, error) {
+	tab := tsv.NewWriter(w)
+	tab.Comma = '\t'
+	tab.UseCRLF = true
+
+	if err := tab.Write(fields); err != nil {
+		return 0, fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	// pages retrieved before
+	var n, off int64
+	end := false
+	for !end {
+		key := strconv.FormatInt(off, 10)
+		values, ok := jr.Values(key)
+		if !ok {
+			break
+		}
+		var rows [][]string
+		var err error
+		off, end, rows, err = parsePage(values)
+		if err != nil {
+			return n, fmt.Errorf("journal %q: key %q: %v", journalFile, key, err)
+		}
+		for _, row := range rows {
+			if err := tab.Write(row); err != nil {
+				return n, fmt.Errorf("when writing on %q: %v", output, err)
+			}
+			logger.WriteRows(1)
+		}
+		n += int64(len(rows))
+		if prog != nil {
+			prog.Add(int64(len(rows)))
+		}
+	}
+
+	if !end {
+		err := gbif.OccurrenceSearchPages(q, off, func(p *gbif.OccurrencePage) error {
+			values := []string{
+				strconv.FormatInt(p.Next, 10),
+				strconv.FormatBool(p.End),
+			}
+			for _, rec := range p.Records {
+				row := recordRow(rec)
+				if err := tab.Write(row); err != nil {
+					return fmt.Errorf("when writing on %q: %v", output, err)
+				}
+				logger.WriteRows(1)
+				values = append(values, row...)
+			}
+			n += int64(len(p.Records))
+
+			// the output is flushed before the journal is updated
+			// so the journal never records an unwritten page
+			tab.Flush()
+			if err := tab.Error(); err != nil {
+				return fmt.Errorf("when writing on %q: %v", output, err)
+			}
+			if err := jr.Commit(strconv.FormatInt(p.Offset, 10), values...); err != nil {
+				return err
+			}
+			if prog != nil {
+				prog.SetTotal(p.Count)
+				prog.Add(int64(len(p.Records)))
+			}
+			return nil
+		})
+		if err != nil {
+			return n, err
+		}
+	}
+
+	tab.Flush()
+	if err := tab.Error(); err != nil {
+		return n, fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return n, nil
+}
+
+// parsePage returns the next offset,
+// the end flag,
+// and the rows of a page
+// stored in the journal.
+func parsePage(values []string) (next int64, end bool, rows [][]string, err error) {
+	if len(values) < 2 || (len(values)-2)%len(fields) != 0 {
+		return 0, false, nil, fmt.Errorf("invalid number of values: %d", len(values))
+	}
+	next, err = strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		return 0, false, nil, fmt.Errorf("next offset: %v", err)
+	}
+	end, err = strconv.ParseBool(values[1])
+	if err != nil {
+		return 0, false, nil, fmt.Errorf("end of records: %v", err)
+	}
+	for i := 2; i < len(values); i += len(fields) {
+		rows = append(rows, values[i:i+len(fields)])
+	}
+	return next, end, rows, nil
+}
+
+// fields are the columns
+// of a GBIF simple download.
+var fields = []string{
+	"gbifID",
+	"datasetKey",
+	"occurrenceID",
+	"kingdom",
+	"phylum",
+	"class",
+	"order",
+	"family",
+	"genus",
+	"species",
+	"infraspecificEpithet",
+	"taxonRank",
+	"scientificName",
+	"verbatimScientificName",
+	"verbatimScientificNameAuthorship",
+	"countryCode",
+	"locality",
+	"stateProvince",
+	"occurrenceStatus",
+	"individualCount",
+	"publishingOrgKey",
+	"decimalLatitude",
+	"decimalLongitude",
+	"coordinateUncertaintyInMeters",
+	"coordinatePrecision",
+	"elevation",
+	"elevationAccuracy",
+	"depth",
+	"depthAccuracy",
+	"eventDate",
+	"day",
+	"month",
+	"year",
+	"taxonKey",
+	"speciesKey",
+	"basisOfRecord",
+	"institutionCode",
+	"collectionCode",
+	"catalogNumber",
+	"recordNumber",
+	"identifiedBy",
+	"dateIdentified",
+	"license",
+	"rightsHolder",
+	"recordedBy",
+	"typeStatus",
+	"establishmentMeans",
+	"lastInterpreted",
+	"issue",
+}
+
+// apiNames are the names of the fields
+// in the answers of the GBIF API
+// that are different from the column names.
+var apiNames = map[string]string{
+	"gbifID": "key",
+	"issue":  "issues",
+}
+
+// recordRow returns the fields of a record
+// in the order of the columns of a GBIF simple download.
+func recordRow(rec map[string]string) []string {
+	row := make([]string, len(fields))
+	for i, f := range fields {
+		if n, ok := apiNames[f]; ok {
+			f = n
+		}
+		row[i] = rec[f]
+	}
+	return row
+}
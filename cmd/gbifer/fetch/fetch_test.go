@@ -0,0 +1,79 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package fetch_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/fetch"
+	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+func TestFetch(t *testing.T) {
+	gbif.Client = &http.Client{Transport: gbif.NewReplayer("testdata/gbif")}
+	gbif.Wait = 0
+
+	var buf bytes.Buffer
+	fetch.Command.SetStdout(&buf)
+	if err := fetch.Command.Execute([]string{"--country", "ar"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tab := tsv.NewReader(&buf)
+	tab.Comma = '\t'
+	header, err := tab.Read()
+	if err != nil {
+		t.Fatalf("header: unexpected error: %v", err)
+	}
+	cols := make(map[string]int, len(header))
+	for i, h := range header {
+		cols[h] = i
+	}
+
+	want := []struct {
+		id       string
+		locality string
+		lat      string
+	}{
+		{"1", "Río Luján", "-34.5"},
+		{"2", "Bariloche", "-41.1"},
+		{"3", "Ushuaia", "-54.8"},
+	}
+	var n int
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if n >= len(want) {
+			t.Fatalf("got more than %d records", len(want))
+		}
+		w := want[n]
+		if got := row[cols["gbifID"]]; got != w.id {
+			t.Errorf("record %d: gbifID: got %q, want %q", n, got, w.id)
+		}
+		if got := row[cols["locality"]]; got != w.locality {
+			t.Errorf("record %d: locality: got %q, want %q", n, got, w.locality)
+		}
+		if got := row[cols["decimalLatitude"]]; got != w.lat {
+			t.Errorf("record %d: decimalLatitude: got %q, want %q", n, got, w.lat)
+		}
+		if got := row[cols["speciesKey"]]; got != "2435099" {
+			t.Errorf("record %d: speciesKey: got %q, want %q", n, got, "2435099")
+		}
+		n++
+	}
+	if n != len(want) {
+		t.Errorf("got %d records, want %d", n, len(want))
+	}
+}
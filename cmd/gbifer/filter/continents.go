@@ -0,0 +1,275 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package filter
+
+// continents are the continents
+// as defined by GBIF.
+var continents = []string{
+	"AFRICA",
+	"ANTARCTICA",
+	"ASIA",
+	"EUROPE",
+	"NORTH_AMERICA",
+	"OCEANIA",
+	"SOUTH_AMERICA",
+}
+
+// Map of country codes
+// to continents.
+// Central America and the Caribbean
+// are in North America,
+// and subantarctic islands
+// are in Antarctica.
+var countryContinent = map[string]string{
+	"AD": "EUROPE",
+	"AE": "ASIA",
+	"AF": "ASIA",
+	"AG": "NORTH_AMERICA",
+	"AI": "NORTH_AMERICA",
+	"AL": "EUROPE",
+	"AM": "ASIA",
+	"AO": "AFRICA",
+	"AQ": "ANTARCTICA",
+	"AR": "SOUTH_AMERICA",
+	"AS": "OCEANIA",
+	"AT": "EUROPE",
+	"AU": "OCEANIA",
+	"AW": "NORTH_AMERICA",
+	"AX": "EUROPE",
+	"AZ": "ASIA",
+	"BA": "EUROPE",
+	"BB": "NORTH_AMERICA",
+	"BD": "ASIA",
+	"BE": "EUROPE",
+	"BF": "AFRICA",
+	"BG": "EUROPE",
+	"BH": "ASIA",
+	"BI": "AFRICA",
+	"BJ": "AFRICA",
+	"BL": "NORTH_AMERICA",
+	"BM": "NORTH_AMERICA",
+	"BN": "ASIA",
+	"BO": "SOUTH_AMERICA",
+	"BQ": "NORTH_AMERICA",
+	"BR": "SOUTH_AMERICA",
+	"BS": "NORTH_AMERICA",
+	"BT": "ASIA",
+	"BV": "ANTARCTICA",
+	"BW": "AFRICA",
+	"BY": "EUROPE",
+	"BZ": "NORTH_AMERICA",
+	"CA": "NORTH_AMERICA",
+	"CC": "ASIA",
+	"CD": "AFRICA",
+	"CF": "AFRICA",
+	"CG": "AFRICA",
+	"CH": "EUROPE",
+	"CI": "AFRICA",
+	"CK": "OCEANIA",
+	"CL": "SOUTH_AMERICA",
+	"CM": "AFRICA",
+	"CN": "ASIA",
+	"CO": "SOUTH_AMERICA",
+	"CR": "NORTH_AMERICA",
+	"CU": "NORTH_AMERICA",
+	"CV": "AFRICA",
+	"CW": "NORTH_AMERICA",
+	"CX": "ASIA",
+	"CY": "ASIA",
+	"CZ": "EUROPE",
+	"DE": "EUROPE",
+	"DJ": "AFRICA",
+	"DK": "EUROPE",
+	"DM": "NORTH_AMERICA",
+	"DO": "NORTH_AMERICA",
+	"DZ": "AFRICA",
+	"EC": "SOUTH_AMERICA",
+	"EE": "EUROPE",
+	"EG": "AFRICA",
+	"EH": "AFRICA",
+	"ER": "AFRICA",
+	"ES": "EUROPE",
+	"ET": "AFRICA",
+	"FI": "EUROPE",
+	"FJ": "OCEANIA",
+	"FK": "SOUTH_AMERICA",
+	"FM": "OCEANIA",
+	"FO": "EUROPE",
+	"FR": "EUROPE",
+	"GA": "AFRICA",
+	"GB": "EUROPE",
+	"GD": "NORTH_AMERICA",
+	"GE": "ASIA",
+	"GF": "SOUTH_AMERICA",
+	"GG": "EUROPE",
+	"GH": "AFRICA",
+	"GI": "EUROPE",
+	"GL": "NORTH_AMERICA",
+	"GM": "AFRICA",
+	"GN": "AFRICA",
+	"GP": "NORTH_AMERICA",
+	"GQ": "AFRICA",
+	"GR": "EUROPE",
+	"GS": "ANTARCTICA",
+	"GT": "NORTH_AMERICA",
+	"GU": "OCEANIA",
+	"GW": "AFRICA",
+	"GY": "SOUTH_AMERICA",
+	"HK": "ASIA",
+	"HM": "ANTARCTICA",
+	"HN": "NORTH_AMERICA",
+	"HR": "EUROPE",
+	"HT": "NORTH_AMERICA",
+	"HU": "EUROPE",
+	"ID": "ASIA",
+	"IE": "EUROPE",
+	"IL": "ASIA",
+	"IM": "EUROPE",
+	"IN": "ASIA",
+	"IO": "AFRICA",
+	"IQ": "ASIA",
+	"IR": "ASIA",
+	"IS": "EUROPE",
+	"IT": "EUROPE",
+	"JE": "EUROPE",
+	"JM": "NORTH_AMERICA",
+	"JO": "ASIA",
+	"JP": "ASIA",
+	"KE": "AFRICA",
+	"KG": "ASIA",
+	"KH": "ASIA",
+	"KI": "OCEANIA",
+	"KM": "AFRICA",
+	"KN": "NORTH_AMERICA",
+	"KP": "ASIA",
+	"KR": "ASIA",
+	"KW": "ASIA",
+	"KY": "NORTH_AMERICA",
+	"KZ": "ASIA",
+	"LA": "ASIA",
+	"LB": "ASIA",
+	"LC": "NORTH_AMERICA",
+	"LI": "EUROPE",
+	"LK": "ASIA",
+	"LR": "AFRICA",
+	"LS": "AFRICA",
+	"LT": "EUROPE",
+	"LU": "EUROPE",
+	"LV": "EUROPE",
+	"LY": "AFRICA",
+	"MA": "AFRICA",
+	"MC": "EUROPE",
+	"MD": "EUROPE",
+	"ME": "EUROPE",
+	"MF": "NORTH_AMERICA",
+	"MG": "AFRICA",
+	"MH": "OCEANIA",
+	"MK": "EUROPE",
+	"ML": "AFRICA",
+	"MM": "ASIA",
+	"MN": "ASIA",
+	"MO": "ASIA",
+	"MP": "OCEANIA",
+	"MQ": "NORTH_AMERICA",
+	"MR": "AFRICA",
+	"MS": "NORTH_AMERICA",
+	"MT": "EUROPE",
+	"MU": "AFRICA",
+	"MV": "ASIA",
+	"MW": "AFRICA",
+	"MX": "NORTH_AMERICA",
+	"MY": "ASIA",
+	"MZ": "AFRICA",
+	"NA": "AFRICA",
+	"NC": "OCEANIA",
+	"NE": "AFRICA",
+	"NF": "OCEANIA",
+	"NG": "AFRICA",
+	"NI": "NORTH_AMERICA",
+	"NL": "EUROPE",
+	"NO": "EUROPE",
+	"NP": "ASIA",
+	"NR": "OCEANIA",
+	"NU": "OCEANIA",
+	"NZ": "OCEANIA",
+	"OM": "ASIA",
+	"PA": "NORTH_AMERICA",
+	"PE": "SOUTH_AMERICA",
+	"PF": "OCEANIA",
+	"PG": "OCEANIA",
+	"PH": "ASIA",
+	"PK": "ASIA",
+	"PL": "EUROPE",
+	"PM": "NORTH_AMERICA",
+	"PN": "OCEANIA",
+	"PR": "NORTH_AMERICA",
+	"PS": "ASIA",
+	"PT": "EUROPE",
+	"PW": "OCEANIA",
+	"PY": "SOUTH_AMERICA",
+	"QA": "ASIA",
+	"RE": "AFRICA",
+	"RO": "EUROPE",
+	"RS": "EUROPE",
+	"RU": "EUROPE",
+	"RW": "AFRICA",
+	"SA": "ASIA",
+	"SB": "OCEANIA",
+	"SC": "AFRICA",
+	"SD": "AFRICA",
+	"SE": "EUROPE",
+	"SG": "ASIA",
+	"SH": "AFRICA",
+	"SI": "EUROPE",
+	"SJ": "EUROPE",
+	"SK": "EUROPE",
+	"SL": "AFRICA",
+	"SM": "EUROPE",
+	"SN": "AFRICA",
+	"SO": "AFRICA",
+	"SR": "SOUTH_AMERICA",
+	"SS": "AFRICA",
+	"ST": "AFRICA",
+	"SV": "NORTH_AMERICA",
+	"SX": "NORTH_AMERICA",
+	"SY": "ASIA",
+	"SZ": "AFRICA",
+	"TC": "NORTH_AMERICA",
+	"TD": "AFRICA",
+	"TF": "ANTARCTICA",
+	"TG": "AFRICA",
+	"TH": "ASIA",
+	"TJ": "ASIA",
+	"TK": "OCEANIA",
+	"TL": "ASIA",
+	"TM": "ASIA",
+	"TN": "AFRICA",
+	"TO": "OCEANIA",
+	"TR": "ASIA",
+	"TT": "NORTH_AMERICA",
+	"TV": "OCEANIA",
+	"TW": "ASIA",
+	"TZ": "AFRICA",
+	"UA": "EUROPE",
+	"UG": "AFRICA",
+	"UM": "OCEANIA",
+	"US": "NORTH_AMERICA",
+	"UY": "SOUTH_AMERICA",
+	"UZ": "ASIA",
+	"VA": "EUROPE",
+	"VC": "NORTH_AMERICA",
+	"VE": "SOUTH_AMERICA",
+	"VG": "NORTH_AMERICA",
+	"VI": "NORTH_AMERICA",
+	"VN": "ASIA",
+	"VU": "OCEANIA",
+	"WF": "OCEANIA",
+	"WS": "OCEANIA",
+	"YE": "ASIA",
+	"YT": "AFRICA",
+	"ZA": "AFRICA",
+	"ZM": "AFRICA",
+	"ZW": "AFRICA",
+}
@@ -12,21 +12,35 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/parallel"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/occurrence"
 	"github.com/js-arias/gbifer/taxonomy"
 	"github.com/js-arias/gbifer/tsv"
 )
 
 var Command = &command.Command{
 	Usage: `filter [--tax <file>] [--country <file>]
-	[-i|--input <file>] [-o|--output <file>]`,
+	[--continent <list>] [--months <list>] [--season <list>]
+	[--institution <list>] [--exclude-institution <list>]
+	[--collection <list>] [--exclude-collection <list>]
+	[--min-decimals <number>] [--no-rounded] [--no-qualifier]
+	[--protocol <list>] [--exclude-protocol <list>] [--types]
+	[--publishing-country <list>] [--exclude-publishing-country <list>]
+	[--repatriated]
+	[--identified-after <date>] [--identified-before <date>]
+	[--jobs <number>] [-i|--input <file>] [-o|--output <file>]`,
 	Short: "filter occurrence rows",
 	Long: `
 Command filter reads a GBIF occurrence table from the standard input and
-selects rows by different criteria.
+selects rows by different criteria. If more than one criterion is defined,
+only the rows that match all the criteria will be selected.
 
 If the flag --tax is given with a file, a taxonomy will be read from the file,
 and only the records that match the taxonomy will be selected.
@@ -38,7 +52,108 @@ defined in the country file. A country file should have the following columns:
 	- name: to indicate the taxon name, the name should be mapped
 	        unambiguously to a valid species in the taxonomy; otherwise,
 		it will be ignored.
-	- countryCode: an ISO 3166-1 alpha-2 code.
+	- countryCode: an ISO 3166-1 alpha-2 code. Rows with an empty code are
+	               ignored.
+
+A template of a country file can be created with the command "gbifer tax
+countries".
+
+If the flag --continent is given with a comma separated list of continents,
+only the records from the indicated continents will be selected. Valid
+continents are AFRICA, ANTARCTICA, ASIA, EUROPE, NORTH_AMERICA, OCEANIA, and
+SOUTH_AMERICA (case insensitive, and spaces or dashes can be used instead of
+the underscore). The continent of a record is taken from the "continent"
+column, or, if the column is not present or is empty, from the country of the
+record ("countryCode" column). For countries, Central America and the
+Caribbean are assigned to North America, and subantarctic islands are assigned
+to Antarctica.
+
+If the flag --months is given with a comma separated list of months (from 1
+to 12), only the records collected in the indicated months will be selected.
+Ranges of months can be used, for example "11-2" is November to February. The
+flag --season can be used to select the months of one or more seasons (comma
+separated), the valid seasons are:
+
+	- austral-summer:  December to February
+	- austral-autumn:  March to May
+	- austral-winter:  June to August
+	- austral-spring:  September to November
+	- boreal-summer:   June to August
+	- boreal-autumn:   September to November
+	- boreal-winter:   December to February
+	- boreal-spring:   March to May
+
+If both flags are given, the months of both flags will be selected. The month
+of a record is taken from the "month" column, or, if the column is not present
+or is empty, from the "eventDate" column. If the event date is an interval,
+all the months of the interval must be selected.
+
+If the flag --institution is given with a comma separated list of institution
+codes, only the records with one of the indicated codes in the
+"institutionCode" column will be selected. If the flag --exclude-institution
+is given, the records with any of the indicated codes will be removed. The
+flags --collection and --exclude-collection work in the same way with the
+"collectionCode" column. In all of these flags, a file can be used instead of
+a list, by prefixing the file name with "@" (for example,
+--institution @museums.txt), the file must have a code per line. Codes are
+case insensitive. Records without a code are removed when codes are selected,
+and kept when codes are removed.
+
+If the flag --min-decimals is given, only the records with coordinates
+("decimalLatitude" and "decimalLongitude" columns) with at least the
+indicated number of decimal places, in both latitude and longitude, will be
+selected. Trailing zeros are not counted. If the flag --no-rounded is given,
+the records in which both latitude and longitude are rounded to a whole or
+half degree (for example, -34.0 and -58.5), typical of coordinates taken from
+a map or a gazetteer with low precision, will be removed. In both cases,
+records without coordinates are removed.
+
+If the flag --no-qualifier is given, the records with an uncertain
+identification will be removed. An identification is uncertain if the
+"identificationQualifier", "scientificName", or "verbatimScientificName"
+columns include a question mark, or any of the qualifiers "cf.", "cfr.",
+"aff.", "nr.", "sp.", or "spp." (but not "sp. nov.").
+
+If the flag --protocol is given with a comma separated list of sampling
+protocols, only the records in which the "samplingProtocol" column contains
+any of the indicated protocols will be selected. For example,
+--protocol "camera trap,mist net" will select records with the protocols
+"Camera-trapping" or "mist net captures". The comparison is case insensitive,
+and dashes and underscores are equal to spaces. If the flag --exclude-protocol
+is given, the records that contain any of the indicated protocols will be
+removed. As with the institution codes, a file (prefixed with "@") can be used
+instead of a list. Records without a protocol are removed when protocols are
+selected, and kept when protocols are removed.
+
+If the flag --types is given, only the type specimens (records with a value in
+the "typeStatus" column, for example "HOLOTYPE" or "PARATYPE") will be
+selected. Records marked as "NOTATYPE" are removed.
+
+If the flag --publishing-country is given with a comma separated list of
+ISO 3166-1 alpha-2 codes, only the records published by an organization of
+the indicated countries ("publishingCountry" column) will be selected. If the
+flag --exclude-publishing-country is given, the records published by an
+organization of the indicated countries will be removed. As with the
+institution codes, a file (prefixed with "@") can be used instead of a list.
+
+If the flag --repatriated is given, only the repatriated records will be
+selected, that is, the records published by an organization from a country
+different from the country in which the record was collected ("countryCode"
+column). Records without a publishing country or a country are removed.
+
+If the flag --identified-after is given with a date, only the records
+identified in or after that date ("dateIdentified" column) will be selected.
+For example, --identified-after 2015 will select the records identified after
+a taxonomic revision published in 2015. If the flag --identified-before is
+given with a date, only the records identified before that date will be
+selected. Dates are in ISO 8601 format, and can be a year (2015), a month
+(2015-06), or a day (2015-06-21). Incomplete dates in the records, for
+example a year, are selected only if all the days of the date are in the
+range. Records without an identification date are removed.
+
+If the flag --jobs is defined with a value greater than 1, the rows will be
+processed by the indicated number of concurrent jobs; with 0, it will use the
+number of available CPUs. The order of the rows is always preserved.
 
 By default, it will read the data from the standard input; use the flag
 --input, or -i, to select a particular file.
@@ -52,8 +167,27 @@ By default, the results will be printed in the standard output; use the flag
 
 var input string
 var output string
+var jobs int
 var taxFile string
 var countryFile string
+var continentFlag string
+var monthsFlag string
+var seasonFlag string
+var institutionFlag string
+var noInstitutionFlag string
+var collectionFlag string
+var noCollectionFlag string
+var minDecimals int
+var noRounded bool
+var noQualifier bool
+var protocolFlag string
+var noProtocolFlag string
+var typesFlag bool
+var pubCountryFlag string
+var noPubCountryFlag string
+var repatriated bool
+var identifiedAfter string
+var identifiedBefore string
 
 func setFlags(c *command.Command) {
 	c.Flags().StringVar(&input, "input", "", "")
@@ -62,6 +196,25 @@ func setFlags(c *command.Command) {
 	c.Flags().StringVar(&output, "o", "", "")
 	c.Flags().StringVar(&taxFile, "tax", "", "")
 	c.Flags().StringVar(&countryFile, "country", "", "")
+	c.Flags().StringVar(&continentFlag, "continent", "", "")
+	c.Flags().StringVar(&monthsFlag, "months", "", "")
+	c.Flags().StringVar(&seasonFlag, "season", "", "")
+	c.Flags().StringVar(&institutionFlag, "institution", "", "")
+	c.Flags().StringVar(&noInstitutionFlag, "exclude-institution", "", "")
+	c.Flags().StringVar(&collectionFlag, "collection", "", "")
+	c.Flags().StringVar(&noCollectionFlag, "exclude-collection", "", "")
+	c.Flags().IntVar(&minDecimals, "min-decimals", 0, "")
+	c.Flags().BoolVar(&noRounded, "no-rounded", false, "")
+	c.Flags().BoolVar(&noQualifier, "no-qualifier", false, "")
+	c.Flags().StringVar(&protocolFlag, "protocol", "", "")
+	c.Flags().StringVar(&noProtocolFlag, "exclude-protocol", "", "")
+	c.Flags().BoolVar(&typesFlag, "types", false, "")
+	c.Flags().StringVar(&pubCountryFlag, "publishing-country", "", "")
+	c.Flags().StringVar(&noPubCountryFlag, "exclude-publishing-country", "", "")
+	c.Flags().BoolVar(&repatriated, "repatriated", false, "")
+	c.Flags().StringVar(&identifiedAfter, "identified-after", "", "")
+	c.Flags().StringVar(&identifiedBefore, "identified-before", "", "")
+	c.Flags().IntVar(&jobs, "jobs", 1, "")
 }
 
 func run(c *command.Command, args []string) (err error) {
@@ -78,8 +231,8 @@ func run(c *command.Command, args []string) (err error) {
 	}
 	out := c.Stdout()
 	if output != "" {
-		var f *os.File
-		f, err = os.Create(output)
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
 		if err != nil {
 			return err
 		}
@@ -94,53 +247,94 @@ func run(c *command.Command, args []string) (err error) {
 		output = "stdout"
 	}
 
+	var cs []builder
 	if countryFile != "" {
 		tx, err := readTaxonomy()
 		if err != nil {
 			return err
 		}
 
-		tc, err := readCountryCodes(c.Stderr(), tx)
+		tc, err := readCountryCodes(tx)
 		if err != nil {
 			return err
 		}
-		if err := filterCountries(in, out, tx, tc); err != nil {
+		cs = append(cs, taxonCriterion(tx, tc))
+	} else if taxFile != "" {
+		tx, err := readTaxonomy()
+		if err != nil {
 			return err
 		}
-
-		return nil
+		cs = append(cs, taxonCriterion(tx, nil))
 	}
-	if taxFile != "" {
-		tx, err := readTaxonomy()
+	if continentFlag != "" {
+		b, err := continentCriterion(continentFlag)
 		if err != nil {
 			return err
 		}
-
-		if err := filterTaxonomy(in, out, tx); err != nil {
+		cs = append(cs, b)
+	}
+	if monthsFlag != "" || seasonFlag != "" {
+		b, err := monthCriterion(monthsFlag, seasonFlag)
+		if err != nil {
 			return err
 		}
-		return nil
+		cs = append(cs, b)
 	}
-
-	return c.UsageError("expecting filter option")
-}
-
-func readTaxonomy() (*taxonomy.Taxonomy, error) {
-	f, err := os.Open(taxFile)
+	cs, err = addValues(cs, []valueFlag{
+		{name: "institution", value: &institutionFlag, col: "institutionCode", reason: "institution"},
+		{name: "exclude-institution", value: &noInstitutionFlag, col: "institutionCode", exclude: true, reason: "institution"},
+		{name: "collection", value: &collectionFlag, col: "collectionCode", reason: "collection"},
+		{name: "exclude-collection", value: &noCollectionFlag, col: "collectionCode", exclude: true, reason: "collection"},
+		{name: "protocol", value: &protocolFlag, col: "samplingProtocol", contains: true, reason: "protocol"},
+		{name: "exclude-protocol", value: &noProtocolFlag, col: "samplingProtocol", exclude: true, contains: true, reason: "protocol"},
+		{name: "publishing-country", value: &pubCountryFlag, col: "publishingCountry", reason: "publishing-country"},
+		{name: "exclude-publishing-country", value: &noPubCountryFlag, col: "publishingCountry", exclude: true, reason: "publishing-country"},
+	})
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if minDecimals > 0 || noRounded {
+		cs = append(cs, precisionCriterion(minDecimals, noRounded))
+	}
+	if noQualifier {
+		cs = append(cs, qualifierCriterion)
+	}
+	if typesFlag {
+		cs = append(cs, typeCriterion)
+	}
+	if repatriated {
+		cs = append(cs, repatriatedCriterion)
+	}
+	if identifiedAfter != "" || identifiedBefore != "" {
+		b, err := identifiedCriterion(identifiedAfter, identifiedBefore)
+		if err != nil {
+			return err
+		}
+		cs = append(cs, b)
 	}
-	defer f.Close()
 
-	tx, err := taxonomy.Read(f)
-	if err != nil {
-		return nil, fmt.Errorf("on file %q: %v", taxFile, err)
+	if len(cs) == 0 {
+		return c.UsageError("expecting filter option")
 	}
-	return tx, nil
+	if err := filter(in, out, cs); err != nil {
+		return err
+	}
+	return nil
 }
 
-func filterTaxonomy(r io.Reader, w io.Writer, tx *taxonomy.Taxonomy) error {
-	tab := tsv.NewReader(r)
+// A criterion is a criterion to select rows.
+// It returns the reason to reject the row,
+// or an empty string if the row is selected.
+//
+// A criterion can be called concurrently.
+type criterion func(row []string, ln int) (string, error)
+
+// A builder returns a criterion
+// for a table with the given header.
+type builder func(header []string) (criterion, error)
+
+func filter(r io.Reader, w io.Writer, cs []builder) error {
+	tab := tabfile.NewReader(r)
 	tab.Comma = '\t'
 
 	header, err := tab.Read()
@@ -148,19 +342,13 @@ func filterTaxonomy(r io.Reader, w io.Writer, tx *taxonomy.Taxonomy) error {
 		return fmt.Errorf("when reading %q header: %v", input, err)
 	}
 
-	keyCol := -1
-	taxCol := -1
-	for i, h := range header {
-		h = strings.ToLower(h)
-		if h == "specieskey" {
-			keyCol = i
-		}
-		if h == "taxonkey" {
-			taxCol = i
+	crit := make([]criterion, 0, len(cs))
+	for _, b := range cs {
+		c, err := b(header)
+		if err != nil {
+			return err
 		}
-	}
-	if keyCol < 0 && taxCol < 0 {
-		return fmt.Errorf("input data %q without %q or %q fields", input, "speciesKey", "taxonKey")
+		crit = append(crit, c)
 	}
 
 	out := tsv.NewWriter(w)
@@ -172,43 +360,21 @@ func filterTaxonomy(r io.Reader, w io.Writer, tx *taxonomy.Taxonomy) error {
 		return fmt.Errorf("when writing on %q: %v", output, err)
 	}
 
-	for {
-		row, err := tab.Read()
-		if errors.Is(err, io.EOF) {
-			break
-		}
-		ln, _ := tab.FieldPos(0)
-		if err != nil {
-			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
-		}
-
-		var key string
-		if keyCol >= 0 {
-			key = row[keyCol]
-			if key == "" {
-				continue
+	fn := func(row []string, ln int) ([]string, error) {
+		for _, c := range crit {
+			reason, err := c(row, ln)
+			if err != nil {
+				return nil, err
+			}
+			if reason != "" {
+				logger.Add("dropped-"+reason, 1)
+				return nil, nil
 			}
 		}
-		if taxCol >= 0 {
-			key = row[taxCol]
-		}
-		if key == "" {
-			continue
-		}
-		id, err := strconv.ParseInt(key, 10, 64)
-		if err != nil {
-			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
-		}
-		if tx.Taxon(id).ID != id {
-			continue
-		}
-		if tx.Rank(id) < taxonomy.Species {
-			continue
-		}
-
-		if err := out.Write(row); err != nil {
-			return fmt.Errorf("when writing on %q: %v", output, err)
-		}
+		return row, nil
+	}
+	if err := parallel.Rows(tab, input, out, output, jobs, fn); err != nil {
+		return err
 	}
 
 	out.Flush()
@@ -218,13 +384,48 @@ func filterTaxonomy(r io.Reader, w io.Writer, tx *taxonomy.Taxonomy) error {
 	return nil
 }
 
-type taxCountry struct {
-	name      string
-	id        int64
-	countries map[string]bool
+func readTaxonomy() (*taxonomy.Taxonomy, error) {
+	f, err := os.Open(taxFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tx, err := taxonomy.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", taxFile, err)
+	}
+	return tx, nil
 }
 
-func readCountryCodes(stderr io.Writer, tx *taxonomy.Taxonomy) (map[int64]*taxCountry, error) {
+// taxonCriterion returns a criterion
+// that selects the rows of the taxa in the taxonomy,
+// and if countries is not nil,
+// in the countries of each taxon.
+func taxonCriterion(tx *taxonomy.Taxonomy, countries map[int64]map[string]bool) builder {
+	return func(header []string) (criterion, error) {
+		var f *occurrence.TaxonFilter
+		var err error
+		if countries != nil {
+			f, err = occurrence.InCountries(header, tx, countries)
+		} else {
+			f, err = occurrence.InTaxonomy(header, tx)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("input data %q %v", input, err)
+		}
+
+		return func(row []string, ln int) (string, error) {
+			reason, err := f.Reason(row)
+			if err != nil {
+				return "", fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			}
+			return reason, nil
+		}, nil
+	}
+}
+
+func readCountryCodes(tx *taxonomy.Taxonomy) (map[int64]map[string]bool, error) {
 	if tx == nil {
 		return nil, errors.New("country codes require a taxonomy file")
 	}
@@ -235,7 +436,7 @@ func readCountryCodes(stderr io.Writer, tx *taxonomy.Taxonomy) (map[int64]*taxCo
 	}
 	defer f.Close()
 
-	tab := tsv.NewReader(f)
+	tab := tabfile.NewReader(f)
 	tab.Comma = '\t'
 
 	header, err := tab.Read()
@@ -258,7 +459,7 @@ func readCountryCodes(stderr io.Writer, tx *taxonomy.Taxonomy) (map[int64]*taxCo
 		return nil, fmt.Errorf("country file %q: without %q or %q fields", countryFile, "name", "countryCode")
 	}
 
-	cTax := make(map[int64]*taxCountry)
+	cTax := make(map[int64]map[string]bool)
 	for {
 		row, err := tab.Read()
 		if errors.Is(err, io.EOF) {
@@ -270,6 +471,11 @@ func readCountryCodes(stderr io.Writer, tx *taxonomy.Taxonomy) (map[int64]*taxCo
 		}
 
 		cc := strings.TrimSpace(strings.ToUpper(row[cCol]))
+		if cc == "" {
+			// a row of a template
+			// without a country
+			continue
+		}
 		if len(cc) != 2 {
 			return nil, fmt.Errorf("country file %q: row %d: invalid country code %q", countryFile, ln, cc)
 		}
@@ -293,121 +499,129 @@ func readCountryCodes(stderr io.Writer, tx *taxonomy.Taxonomy) (map[int64]*taxCo
 
 			if len(amb) > 0 {
 				amb = append([]int64{id}, amb...)
-				fmt.Fprintf(stderr, "# ambiguous taxon name: %s\n", name)
+				ls := make([]string, 0, len(ids))
 				for _, id := range ids {
-					fmt.Fprintf(stderr, "#\t%d\n", id)
+					ls = append(ls, strconv.FormatInt(id, 10))
 				}
+				logger.Printf("ambiguous taxon name %q: %s", name, strings.Join(ls, ", "))
 				continue
 			}
 		}
 
 		tax, ok := cTax[id]
 		if !ok {
-			tax = &taxCountry{
-				name:      taxonomy.Canon(name),
-				id:        id,
-				countries: make(map[string]bool),
-			}
+			tax = make(map[string]bool)
 			cTax[id] = tax
 		}
-
-		tax.countries[cc] = true
+		tax[cc] = true
 	}
 	return cTax, nil
 }
 
-func filterCountries(r io.Reader, w io.Writer, tx *taxonomy.Taxonomy, tc map[int64]*taxCountry) error {
-	tab := tsv.NewReader(r)
-	tab.Comma = '\t'
-
-	header, err := tab.Read()
-	if err != nil {
-		return fmt.Errorf("when reading %q header: %v", input, err)
-	}
-
-	keyCol := -1
-	taxCol := -1
-	cCol := -1
-	for i, h := range header {
-		h = strings.ToLower(h)
-		if h == "specieskey" {
-			keyCol = i
+func continentCriterion(list string) (builder, error) {
+	want := make(map[string]bool)
+	for _, v := range strings.Split(list, ",") {
+		v = continentName(v)
+		if v == "" {
+			continue
 		}
-		if h == "taxonkey" {
-			taxCol = i
-		}
-		if h == "countrycode" {
-			cCol = i
+		if !slices.Contains(continents, v) {
+			return nil, fmt.Errorf("flag --continent: unknown continent %q", v)
 		}
+		want[v] = true
 	}
-	if keyCol < 0 || taxCol < 0 || cCol < 0 {
-		return fmt.Errorf("input data %q without %q, %q, or %q fields", input, "speciesKey", "taxonKey", "countryCode")
+	if len(want) == 0 {
+		return nil, fmt.Errorf("flag --continent: expecting a continent")
 	}
 
-	out := tsv.NewWriter(w)
-	out.Comma = '\t'
-	out.UseCRLF = true
-
-	// write header
-	if err := out.Write(header); err != nil {
-		return fmt.Errorf("when writing on %q: %v", output, err)
-	}
-
-	for {
-		row, err := tab.Read()
-		if errors.Is(err, io.EOF) {
-			break
+	return func(header []string) (criterion, error) {
+		contCol := -1
+		cCol := -1
+		for i, h := range header {
+			h = strings.ToLower(h)
+			if h == "continent" {
+				contCol = i
+			}
+			if h == "countrycode" {
+				cCol = i
+			}
 		}
-		ln, _ := tab.FieldPos(0)
-		if err != nil {
-			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		if contCol < 0 && cCol < 0 {
+			return nil, fmt.Errorf("input data %q without %q or %q fields", input, "continent", "countryCode")
 		}
 
-		var key string
-		if keyCol >= 0 {
-			key = row[keyCol]
-			if key == "" {
-				continue
+		return func(row []string, ln int) (string, error) {
+			var cont string
+			if contCol >= 0 {
+				cont = continentName(row[contCol])
 			}
+			if cont == "" && cCol >= 0 {
+				cc := strings.TrimSpace(strings.ToUpper(row[cCol]))
+				cont = countryContinent[cc]
+			}
+			if cont == "" {
+				return "no-continent", nil
+			}
+			if !want[cont] {
+				return "continent", nil
+			}
+			return "", nil
+		}, nil
+	}, nil
+}
+
+func typeCriterion(header []string) (criterion, error) {
+	col := -1
+	for i, h := range header {
+		if strings.EqualFold(h, "typeStatus") {
+			col = i
 		}
-		if taxCol >= 0 {
-			key = row[taxCol]
-		}
-		if key == "" {
-			continue
-		}
-		id, err := strconv.ParseInt(key, 10, 64)
-		if err != nil {
-			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
-		}
-		if tx.Taxon(id).ID != id {
-			continue
-		}
-		if tx.Rank(id) < taxonomy.Species {
-			continue
+	}
+	if col < 0 {
+		return nil, fmt.Errorf("input data %q without %q field", input, "typeStatus")
+	}
+
+	return func(row []string, ln int) (string, error) {
+		v := strings.TrimSpace(row[col])
+		if v == "" || strings.EqualFold(v, "NOTATYPE") {
+			return "not-type", nil
 		}
+		return "", nil
+	}, nil
+}
 
-		v := tx.AcceptedAndRanked(id).ID
-		if v == 0 {
-			continue
+func repatriatedCriterion(header []string) (criterion, error) {
+	pCol := -1
+	cCol := -1
+	for i, h := range header {
+		h = strings.ToLower(h)
+		if h == "publishingcountry" {
+			pCol = i
 		}
-		tax, ok := tc[v]
-		if !ok {
-			continue
+		if h == "countrycode" {
+			cCol = i
 		}
+	}
+	if pCol < 0 || cCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q or %q fields", input, "publishingCountry", "countryCode")
+	}
+
+	return func(row []string, ln int) (string, error) {
+		pub := strings.TrimSpace(strings.ToUpper(row[pCol]))
 		country := strings.TrimSpace(strings.ToUpper(row[cCol]))
-		if !tax.countries[country] {
-			continue
+		if pub == "" || country == "" {
+			return "no-country", nil
 		}
-
-		if err := out.Write(row); err != nil {
-			return fmt.Errorf("when writing on %q: %v", output, err)
+		if pub == country {
+			return "not-repatriated", nil
 		}
-	}
+		return "", nil
+	}, nil
+}
 
-	out.Flush()
-	if err := out.Error(); err != nil {
-		return fmt.Errorf("when writing on %q: %v", output, err)
-	}
-	return nil
+// continentName returns the canonical name
+// of a continent.
+func continentName(s string) string {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(s)
 }
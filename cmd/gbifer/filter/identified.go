@@ -0,0 +1,115 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// identifiedCriterion returns a criterion
+// that selects the records identified
+// in or after the after date,
+// and before the before date.
+// Empty dates are not used.
+func identifiedCriterion(after, before string) (builder, error) {
+	var from, to int
+	if after != "" {
+		d, _, ok := parseDate(after)
+		if !ok {
+			return nil, fmt.Errorf("flag --identified-after: invalid date %q", after)
+		}
+		from = d
+	}
+	if before != "" {
+		d, _, ok := parseDate(before)
+		if !ok {
+			return nil, fmt.Errorf("flag --identified-before: invalid date %q", before)
+		}
+		to = d
+	}
+
+	return func(header []string) (criterion, error) {
+		col := -1
+		for i, h := range header {
+			if strings.EqualFold(h, "dateIdentified") {
+				col = i
+			}
+		}
+		if col < 0 {
+			return nil, fmt.Errorf("input data %q without %q field", input, "dateIdentified")
+		}
+
+		return func(row []string, ln int) (string, error) {
+			first, last, ok := identifiedDates(row[col])
+			if !ok {
+				return "no-date-identified", nil
+			}
+			if from > 0 && first < from {
+				return "date-identified", nil
+			}
+			if to > 0 && last >= to {
+				return "date-identified", nil
+			}
+			return "", nil
+		}, nil
+	}, nil
+}
+
+// identifiedDates returns the first
+// and last possible days
+// of an identification date,
+// as numbers in the form YYYYMMDD.
+func identifiedDates(date string) (first, last int, ok bool) {
+	start, end, isInterval := strings.Cut(strings.TrimSpace(date), "/")
+	first, last, ok = parseDate(start)
+	if !ok {
+		return 0, 0, false
+	}
+	if isInterval {
+		if _, l, ok := parseDate(end); ok && l > last {
+			last = l
+		}
+	}
+	return first, last, true
+}
+
+// parseDate parses an ISO 8601 date
+// that can be incomplete
+// (for example, "2015" or "2015-06"),
+// and returns the first and last possible days
+// as numbers in the form YYYYMMDD.
+// The time of a date is ignored.
+func parseDate(date string) (first, last int, ok bool) {
+	date, _, _ = strings.Cut(strings.TrimSpace(date), "T")
+	parts := strings.Split(date, "-")
+	if len(parts) > 3 || len(parts[0]) != 4 {
+		return 0, 0, false
+	}
+
+	y, err := strconv.Atoi(parts[0])
+	if err != nil || y < 1 {
+		return 0, 0, false
+	}
+	if len(parts) == 1 {
+		return y*10000 + 101, y*10000 + 1231, true
+	}
+
+	m, err := strconv.Atoi(parts[1])
+	if err != nil || m < 1 || m > 12 {
+		return 0, 0, false
+	}
+	if len(parts) == 2 {
+		return y*10000 + m*100 + 1, y*10000 + m*100 + 31, true
+	}
+
+	d, err := strconv.Atoi(parts[2])
+	if err != nil || d < 1 || d > 31 {
+		return 0, 0, false
+	}
+	v := y*10000 + m*100 + d
+	return v, v, true
+}
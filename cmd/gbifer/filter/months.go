@@ -0,0 +1,180 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// seasons are the months
+// of each season.
+var seasons = map[string][]int{
+	"austral-summer": {12, 1, 2},
+	"austral-autumn": {3, 4, 5},
+	"austral-winter": {6, 7, 8},
+	"austral-spring": {9, 10, 11},
+	"boreal-summer":  {6, 7, 8},
+	"boreal-autumn":  {9, 10, 11},
+	"boreal-winter":  {12, 1, 2},
+	"boreal-spring":  {3, 4, 5},
+}
+
+// parseMonths returns the selected months
+// from a list of months and a list of seasons.
+func parseMonths(months, season string) ([13]bool, error) {
+	var sel [13]bool
+	for _, v := range strings.Split(months, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(v, "-")
+		a, err := parseMonth(first)
+		if err != nil {
+			return sel, fmt.Errorf("flag --months: %v", err)
+		}
+		b := a
+		if isRange {
+			b, err = parseMonth(last)
+			if err != nil {
+				return sel, fmt.Errorf("flag --months: %v", err)
+			}
+		}
+		for m := a; ; m = m%12 + 1 {
+			sel[m] = true
+			if m == b {
+				break
+			}
+		}
+	}
+	for _, v := range strings.Split(season, ",") {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v == "" {
+			continue
+		}
+		ms, ok := seasons[v]
+		if !ok {
+			return sel, fmt.Errorf("flag --season: unknown season %q", v)
+		}
+		for _, m := range ms {
+			sel[m] = true
+		}
+	}
+	for _, ok := range sel {
+		if ok {
+			return sel, nil
+		}
+	}
+	return sel, fmt.Errorf("flag --months: expecting a month")
+}
+
+func parseMonth(s string) (int, error) {
+	m, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || m < 1 || m > 12 {
+		return 0, fmt.Errorf("invalid month %q", s)
+	}
+	return m, nil
+}
+
+func monthCriterion(months, season string) (builder, error) {
+	sel, err := parseMonths(months, season)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(header []string) (criterion, error) {
+		mCol := -1
+		dCol := -1
+		for i, h := range header {
+			h = strings.ToLower(h)
+			if h == "month" {
+				mCol = i
+			}
+			if h == "eventdate" {
+				dCol = i
+			}
+		}
+		if mCol < 0 && dCol < 0 {
+			return nil, fmt.Errorf("input data %q without %q or %q fields", input, "month", "eventDate")
+		}
+
+		return func(row []string, ln int) (string, error) {
+			if mCol >= 0 && row[mCol] != "" {
+				m, err := parseMonth(row[mCol])
+				if err != nil {
+					return "no-month", nil
+				}
+				if !sel[m] {
+					return "month", nil
+				}
+				return "", nil
+			}
+			if dCol < 0 {
+				return "no-month", nil
+			}
+			first, last, ok := eventMonths(row[dCol])
+			if !ok {
+				return "no-month", nil
+			}
+			for m := first; ; m = m%12 + 1 {
+				if !sel[m] {
+					return "month", nil
+				}
+				if m == last {
+					break
+				}
+			}
+			return "", nil
+		}, nil
+	}, nil
+}
+
+// eventMonths returns the first and last months
+// of an ISO 8601 event date,
+// that can be an interval.
+// It returns false
+// if the date does not have a month,
+// or if the interval is longer than a year.
+func eventMonths(date string) (first, last int, ok bool) {
+	start, end, isInterval := strings.Cut(strings.TrimSpace(date), "/")
+	sy, sm, ok := yearMonth(start)
+	if !ok {
+		return 0, 0, false
+	}
+	if !isInterval {
+		return sm, sm, true
+	}
+
+	ey, em, ok := yearMonth(end)
+	if !ok {
+		// abbreviated intervals,
+		// for example "2001-11-03/05",
+		// are in the same month
+		return sm, sm, true
+	}
+	if d := (ey-sy)*12 + em - sm; d < 0 || d > 11 {
+		return 0, 0, false
+	}
+	return sm, em, true
+}
+
+// yearMonth returns the year and month
+// of an ISO 8601 date.
+func yearMonth(date string) (year, month int, ok bool) {
+	if len(date) < 7 || date[4] != '-' {
+		return 0, 0, false
+	}
+	y, err := strconv.Atoi(date[:4])
+	if err != nil {
+		return 0, 0, false
+	}
+	m, err := strconv.Atoi(date[5:7])
+	if err != nil || m < 1 || m > 12 {
+		return 0, 0, false
+	}
+	return y, m, true
+}
@@ -0,0 +1,84 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package filter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// decimals returns the number of decimal places
+// of a coordinate,
+// ignoring trailing zeros.
+func decimals(s string) (int, bool) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	if strings.ContainsAny(s, "eE") {
+		// use the shortest representation
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	_, frac, ok := strings.Cut(s, ".")
+	if !ok {
+		return 0, true
+	}
+	return len(strings.TrimRight(frac, "0")), true
+}
+
+// isRounded returns true
+// if a coordinate is rounded to a whole
+// or a half degree.
+func isRounded(s string) bool {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return false
+	}
+	return v*2 == math.Trunc(v*2)
+}
+
+func precisionCriterion(minDecimals int, rounded bool) builder {
+	return func(header []string) (criterion, error) {
+		latCol := -1
+		lonCol := -1
+		for i, h := range header {
+			h = strings.ToLower(h)
+			if h == "decimallatitude" {
+				latCol = i
+			}
+			if h == "decimallongitude" {
+				lonCol = i
+			}
+		}
+		if latCol < 0 || lonCol < 0 {
+			return nil, fmt.Errorf("input data %q without %q or %q fields", input, "decimalLatitude", "decimalLongitude")
+		}
+
+		return func(row []string, ln int) (string, error) {
+			lat := strings.TrimSpace(row[latCol])
+			lon := strings.TrimSpace(row[lonCol])
+			if lat == "" || lon == "" {
+				return "no-coordinates", nil
+			}
+			latDec, ok := decimals(lat)
+			if !ok {
+				return "no-coordinates", nil
+			}
+			lonDec, ok := decimals(lon)
+			if !ok {
+				return "no-coordinates", nil
+			}
+			if latDec < minDecimals || lonDec < minDecimals {
+				return "precision", nil
+			}
+			if rounded && isRounded(lat) && isRounded(lon) {
+				return "rounded", nil
+			}
+			return "", nil
+		}, nil
+	}
+}
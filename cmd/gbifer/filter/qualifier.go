@@ -0,0 +1,75 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// qualifiers are the words
+// used to indicate an uncertain identification.
+var qualifiers = map[string]bool{
+	"aff": true,
+	"cf":  true,
+	"cfr": true,
+	"nr":  true,
+	"sp":  true,
+	"spp": true,
+}
+
+// hasQualifier returns true
+// if a name, or an identification qualifier,
+// includes a word of an uncertain identification.
+func hasQualifier(s string) bool {
+	if strings.Contains(s, "?") {
+		return true
+	}
+	ws := strings.Fields(strings.ToLower(s))
+	for i, w := range ws {
+		w = strings.TrimRight(w, ".")
+		if !qualifiers[w] {
+			continue
+		}
+		if w == "sp" && i+1 < len(ws) && strings.HasPrefix(ws[i+1], "nov") {
+			// a new species
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// qualifierCols are the columns
+// checked for qualifiers.
+var qualifierCols = []string{
+	"identificationqualifier",
+	"scientificname",
+	"verbatimscientificname",
+}
+
+func qualifierCriterion(header []string) (criterion, error) {
+	var cols []int
+	for i, h := range header {
+		h = strings.ToLower(h)
+		for _, c := range qualifierCols {
+			if h == c {
+				cols = append(cols, i)
+			}
+		}
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("input data %q without %q or %q fields", input, "identificationQualifier", "scientificName")
+	}
+
+	return func(row []string, ln int) (string, error) {
+		for _, c := range cols {
+			if hasQualifier(row[c]) {
+				return "qualifier", nil
+			}
+		}
+		return "", nil
+	}, nil
+}
@@ -0,0 +1,155 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package filter
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// parseValues returns the values of a flag
+// with a comma separated list of values.
+// If the list starts with "@",
+// the values are read from the indicated file,
+// one value per line.
+// Values are case insensitive.
+func parseValues(flag, list string) (map[string]bool, error) {
+	vals := make(map[string]bool)
+	if name, ok := strings.CutPrefix(list, "@"); ok {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("flag --%s: %v", flag, err)
+		}
+		defer f.Close()
+
+		r := bufio.NewReader(f)
+		for i := 1; ; i++ {
+			ln, err := r.ReadString('\n')
+			if err != nil && len(ln) == 0 {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				return nil, fmt.Errorf("on file %q: line %d: %v", name, i, err)
+			}
+			ln = strings.TrimSpace(ln)
+			if ln == "" || strings.HasPrefix(ln, "#") {
+				continue
+			}
+			vals[strings.ToLower(ln)] = true
+		}
+	} else {
+		for _, v := range strings.Split(list, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			vals[strings.ToLower(v)] = true
+		}
+	}
+	if len(vals) == 0 {
+		return nil, fmt.Errorf("flag --%s: expecting a value", flag)
+	}
+	return vals, nil
+}
+
+// valueCriterion returns a criterion
+// that selects the rows with a value of a column
+// in a set of values,
+// or,
+// if exclude is true,
+// the rows with a value not in the set.
+// If contains is true,
+// a value is in the set
+// if it contains any of the values of the set
+// (see normalize).
+// Rows with an empty value
+// are rejected when the values are included,
+// and selected when the values are excluded.
+func valueCriterion(col string, vals map[string]bool, exclude, contains bool, reason string) builder {
+	var terms []string
+	if contains {
+		for v := range vals {
+			terms = append(terms, normalize(v))
+		}
+	}
+
+	return func(header []string) (criterion, error) {
+		c := -1
+		for i, h := range header {
+			if strings.EqualFold(h, col) {
+				c = i
+				break
+			}
+		}
+		if c < 0 {
+			return nil, fmt.Errorf("input data %q without %q field", input, col)
+		}
+
+		return func(row []string, ln int) (string, error) {
+			v := strings.ToLower(strings.TrimSpace(row[c]))
+			if v == "" {
+				if exclude {
+					return "", nil
+				}
+				return "no-" + reason, nil
+			}
+			found := vals[v]
+			if contains {
+				v = normalize(v)
+				for _, t := range terms {
+					if strings.Contains(v, t) {
+						found = true
+						break
+					}
+				}
+			}
+			if found == exclude {
+				return reason, nil
+			}
+			return "", nil
+		}, nil
+	}
+}
+
+// addValues adds a value criterion
+// for each defined flag.
+func addValues(cs []builder, flags []valueFlag) ([]builder, error) {
+	for _, f := range flags {
+		if *f.value == "" {
+			continue
+		}
+		vals, err := parseValues(f.name, *f.value)
+		if err != nil {
+			return nil, err
+		}
+		cs = append(cs, valueCriterion(f.col, vals, f.exclude, f.contains, f.reason))
+	}
+	return cs, nil
+}
+
+// A valueFlag is a flag
+// that defines a set of values for a column.
+type valueFlag struct {
+	name     string // name of the flag
+	value    *string
+	col      string
+	exclude  bool
+	contains bool
+	reason   string
+}
+
+// normalize returns a value in lower case,
+// with dashes and underscores replaced by spaces,
+// and without repeated spaces,
+// so "Camera-trap" is equal to "camera trap".
+func normalize(s string) string {
+	s = strings.ToLower(s)
+	s = strings.NewReplacer("-", " ", "_", " ").Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
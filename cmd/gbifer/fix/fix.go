@@ -0,0 +1,242 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package fix implements a command to repair
+// common defects
+// of a GBIF occurrence table.
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `fix [-i|--input <file>] [-o|--output <file>]`,
+	Short: "repair malformed tables",
+	Long: `
+Command fix reads a GBIF occurrence table from the standard input and repairs
+the following common defects:
+
+	- A byte order mark (BOM) at the start of the file is removed.
+	- Line endings are normalized to "\r\n", and stray carriage returns
+	  inside fields are replaced by spaces.
+	- Rows with more fields than the header, caused by tab characters
+	  inside free-text columns (for example, "locality", or
+	  "occurrenceRemarks"), are repaired by joining the extra fields into
+	  the first free-text column of the table.
+	- Rows with fewer fields than the header are padded with empty
+	  fields, and rows with more fields that cannot be repaired are
+	  truncated.
+
+Each repair is reported in the standard error, with its row number.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	n, err := fix(in, out)
+	if err != nil {
+		return err
+	}
+	logger.Printf("%d repairs", n)
+	return nil
+}
+
+// freeText are the free-text columns
+// in which a stray tab can be found.
+var freeText = []string{
+	"locality",
+	"verbatimLocality",
+	"occurrenceRemarks",
+	"locationRemarks",
+	"habitat",
+	"eventRemarks",
+	"identificationRemarks",
+	"georeferenceRemarks",
+	"fieldNotes",
+	"samplingProtocol",
+	"recordedBy",
+	"identifiedBy",
+}
+
+var bom = []byte{0xEF, 0xBB, 0xBF}
+
+// gzipMagic is the start of a gzip file.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+func fix(r io.Reader, w io.Writer) (int, error) {
+	repairs := 0
+
+	br := bufio.NewReader(r)
+	if b, err := br.Peek(len(gzipMagic)); err == nil && bytes.Equal(b, gzipMagic) {
+		z, err := gzip.NewReader(br)
+		if err != nil {
+			return 0, fmt.Errorf("table %q: %v", input, err)
+		}
+		br = bufio.NewReader(z)
+	}
+	if b, err := br.Peek(len(bom)); err == nil && bytes.Equal(b, bom) {
+		br.Discard(len(bom))
+		logger.Warnf(1, "byte order mark removed")
+		repairs++
+	}
+
+	tab := tsv.NewReader(br)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return 0, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+	if cleanCR(header) {
+		logger.Warnf(1, "carriage returns replaced")
+		repairs++
+	}
+
+	textCol := -1
+	for _, t := range freeText {
+		for i, h := range header {
+			if strings.EqualFold(h, t) {
+				textCol = i
+				break
+			}
+		}
+		if textCol >= 0 {
+			break
+		}
+	}
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	if err := out.Write(header); err != nil {
+		return 0, fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil && !errors.Is(err, tsv.ErrFieldCount) {
+			return 0, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		if cleanCR(row) {
+			logger.Warnf(ln, "carriage returns replaced")
+			repairs++
+		}
+
+		if extra := len(row) - len(header); extra > 0 && textCol >= 0 {
+			joined := strings.Join(row[textCol:textCol+extra+1], " ")
+			nr := make([]string, 0, len(header))
+			nr = append(nr, row[:textCol]...)
+			nr = append(nr, joined)
+			nr = append(nr, row[textCol+extra+1:]...)
+			row = nr
+			logger.Warnf(ln, "%d stray tabs in %q removed", extra, header[textCol])
+			repairs++
+		}
+		if len(row) > len(header) {
+			logger.Warnf(ln, "truncated from %d to %d fields", len(row), len(header))
+			row = row[:len(header)]
+			repairs++
+		}
+		if len(row) < len(header) {
+			logger.Warnf(ln, "padded from %d to %d fields", len(row), len(header))
+			for len(row) < len(header) {
+				row = append(row, "")
+			}
+			repairs++
+		}
+
+		if err := out.Write(row); err != nil {
+			return 0, fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return 0, fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return repairs, nil
+}
+
+// cleanCR replaces the carriage returns
+// in the fields of a row.
+// It returns true if any field was changed.
+func cleanCR(row []string) bool {
+	changed := false
+	for i, f := range row {
+		if !strings.Contains(f, "\r") {
+			continue
+		}
+		row[i] = strings.ReplaceAll(f, "\r", " ")
+		changed = true
+	}
+	return changed
+}
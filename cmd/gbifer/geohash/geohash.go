@@ -0,0 +1,219 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package geohash implements a command to add
+// a geohash column
+// to a GBIF occurrence table.
+package geohash
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `geohash [--precision <value>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "add a geohash column",
+	Long: `
+Command geohash reads a GBIF occurrence table from the standard input and adds
+a new column, "geohash", with the geohash of the coordinates of each record.
+Records without coordinates will have an empty geohash.
+
+By default, the geohash will have 7 characters (cells of about 150 meters);
+use the flag --precision to define a different number of characters (from 1
+to 12).
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var precision int
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().IntVar(&precision, "precision", 7, "")
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if precision < 1 || precision > 12 {
+		return c.UsageError(fmt.Sprintf("invalid geohash precision %d", precision))
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := readTable(in, out); err != nil {
+		return err
+	}
+	return nil
+}
+
+func readTable(r io.Reader, w io.Writer) error {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return fmt.Errorf("when reading %q header: %v", input, err)
+	}
+
+	latCol := -1
+	lonCol := -1
+	for i, h := range header {
+		h = strings.ToLower(h)
+		if h == "decimallatitude" {
+			latCol = i
+		}
+		if h == "decimallongitude" {
+			lonCol = i
+		}
+	}
+	if latCol < 0 || lonCol < 0 {
+		return fmt.Errorf("input data %q without %q or %q fields", input, "decimalLatitude", "decimalLongitude")
+	}
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	// write header
+	nh := append(header[:len(header):len(header)], "geohash")
+	if err := out.Write(nh); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		var gh string
+		if row[latCol] != "" && row[lonCol] != "" {
+			lat, err := strconv.ParseFloat(row[latCol], 64)
+			if err != nil {
+				return fmt.Errorf("table %q: row %d: field %q: %v", input, ln, "decimalLatitude", err)
+			}
+			if lat < -90 || lat > 90 {
+				return fmt.Errorf("table %q: row %d: field %q: invalid latitude: %.6f", input, ln, "decimalLatitude", lat)
+			}
+			lon, err := strconv.ParseFloat(row[lonCol], 64)
+			if err != nil {
+				return fmt.Errorf("table %q: row %d: field %q: %v", input, ln, "decimalLongitude", err)
+			}
+			if lon < -180 || lon > 180 {
+				return fmt.Errorf("table %q: row %d: field %q: invalid longitude: %.6f", input, ln, "decimalLongitude", lon)
+			}
+			gh = encode(lat, lon, precision)
+		}
+
+		if err := out.Write(append(row, gh)); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Encode returns the geohash of a point
+// with the given number of characters.
+func encode(lat, lon float64, precision int) string {
+	minLat, maxLat := -90.0, 90.0
+	minLon, maxLon := -180.0, 180.0
+
+	var b strings.Builder
+	even := true
+	bit := 0
+	ch := 0
+	for b.Len() < precision {
+		if even {
+			mid := (minLon + maxLon) / 2
+			if lon >= mid {
+				ch = ch<<1 | 1
+				minLon = mid
+			} else {
+				ch <<= 1
+				maxLon = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if lat >= mid {
+				ch = ch<<1 | 1
+				minLat = mid
+			} else {
+				ch <<= 1
+				maxLat = mid
+			}
+		}
+		even = !even
+
+		bit++
+		if bit == 5 {
+			b.WriteByte(base32[ch])
+			bit = 0
+			ch = 0
+		}
+	}
+	return b.String()
+}
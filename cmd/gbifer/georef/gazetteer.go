@@ -0,0 +1,393 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package georef
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+// Georeferencing levels.
+const (
+	locality = "locality"
+	county   = "county"
+	state    = "stateProvince"
+)
+
+// defUncertainty is the default uncertainty,
+// in meters,
+// of a place at each level.
+var defUncertainty = map[string]float64{
+	locality: 5_000,
+	county:   50_000,
+	state:    250_000,
+}
+
+// A place is a place of the gazetteer.
+type place struct {
+	lat, lon float64
+	unc      float64 // uncertainty in meters
+	state    string  // folded name of the state
+	county   string  // folded name of the county
+
+	// admin codes of GeoNames places
+	admin1, admin2 string
+}
+
+// A gazetteer stores the places
+// by level, country, and name.
+type gazetteer struct {
+	places map[string][]*place
+}
+
+func placeKey(level, country, name string) string {
+	return level + "\x00" + strings.ToUpper(country) + "\x00" + fold(name)
+}
+
+func (g *gazetteer) add(level, country, name string, p *place) error {
+	name = fold(name)
+	if name == "" {
+		return nil
+	}
+	k := placeKey(level, country, name)
+	g.places[k] = append(g.places[k], p)
+	return memory.Add(int64(len(k)) + 96)
+}
+
+// readGazetteer reads a gazetteer
+// from a GeoNames dump,
+// or a TSV file with a header.
+func readGazetteer(name string) (*gazetteer, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tab := tsv.NewReader(f)
+	tab.Comma = '\t'
+
+	first, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("gazetteer %q: %v", name, err)
+	}
+
+	g := &gazetteer{places: make(map[string][]*place)}
+	if _, err := strconv.Atoi(first[0]); err == nil && len(first) >= 19 {
+		if err := g.readGeoNames(tab, first); err != nil {
+			return nil, fmt.Errorf("gazetteer %q: %v", name, err)
+		}
+		return g, nil
+	}
+	if err := g.readTSV(tab, first); err != nil {
+		return nil, fmt.Errorf("gazetteer %q: %v", name, err)
+	}
+	return g, nil
+}
+
+// readTSV reads a gazetteer
+// from a TSV file
+// with the columns
+// "name", "countryCode", "decimalLatitude", and "decimalLongitude",
+// and optionally,
+// "level", "stateProvince", "county",
+// and "coordinateUncertaintyInMeters".
+func (g *gazetteer) readTSV(tab *tsv.Reader, header []string) error {
+	fields := make(map[string]int)
+	for i, h := range header {
+		fields[strings.ToLower(h)] = i
+	}
+	for _, h := range []string{"name", "countryCode", "decimalLatitude", "decimalLongitude"} {
+		if _, ok := fields[strings.ToLower(h)]; !ok {
+			return fmt.Errorf("expecting %q field", h)
+		}
+	}
+	col := func(row []string, name string) string {
+		i, ok := fields[strings.ToLower(name)]
+		if !ok {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return fmt.Errorf("row %d: %v", ln, err)
+		}
+
+		level := locality
+		if v := col(row, "level"); v != "" {
+			switch strings.ToLower(v) {
+			case "locality":
+			case "county":
+				level = county
+			case "stateprovince", "state":
+				level = state
+			default:
+				return fmt.Errorf("row %d: unknown level %q", ln, v)
+			}
+		}
+
+		lat, err := strconv.ParseFloat(col(row, "decimalLatitude"), 64)
+		if err != nil || lat < -90 || lat > 90 {
+			return fmt.Errorf("row %d: invalid latitude %q", ln, col(row, "decimalLatitude"))
+		}
+		lon, err := strconv.ParseFloat(col(row, "decimalLongitude"), 64)
+		if err != nil || lon < -180 || lon > 180 {
+			return fmt.Errorf("row %d: invalid longitude %q", ln, col(row, "decimalLongitude"))
+		}
+		unc := defUncertainty[level]
+		if v := col(row, "coordinateUncertaintyInMeters"); v != "" {
+			unc, err = strconv.ParseFloat(v, 64)
+			if err != nil || unc < 0 {
+				return fmt.Errorf("row %d: invalid uncertainty %q", ln, v)
+			}
+		}
+
+		p := &place{
+			lat:    lat,
+			lon:    lon,
+			unc:    unc,
+			state:  fold(col(row, "stateProvince")),
+			county: fold(col(row, "county")),
+		}
+		if err := g.add(level, col(row, "countryCode"), col(row, "name"), p); err != nil {
+			return fmt.Errorf("row %d: %v", ln, err)
+		}
+	}
+}
+
+// GeoNames columns.
+const (
+	gnName      = 1
+	gnASCII     = 2
+	gnAlternate = 3
+	gnLat       = 4
+	gnLon       = 5
+	gnClass     = 6
+	gnCode      = 7
+	gnCountry   = 8
+	gnAdmin1    = 10
+	gnAdmin2    = 11
+)
+
+// readGeoNames reads a gazetteer
+// from a GeoNames dump
+// (for example, "AR.txt" or "allCountries.txt").
+// First-order administrative divisions (ADM1)
+// are states,
+// second-order administrative divisions (ADM2)
+// are counties,
+// and any other place is a locality.
+func (g *gazetteer) readGeoNames(tab *tsv.Reader, first []string) error {
+	admin1 := make(map[string]string)
+	admin2 := make(map[string]string)
+	var places []*place
+
+	row := first
+	for {
+		ln, _ := tab.FieldPos(0)
+		if len(row) < 19 {
+			return fmt.Errorf("row %d: invalid GeoNames row", ln)
+		}
+
+		lat, err := strconv.ParseFloat(row[gnLat], 64)
+		if err != nil {
+			return fmt.Errorf("row %d: invalid latitude %q", ln, row[gnLat])
+		}
+		lon, err := strconv.ParseFloat(row[gnLon], 64)
+		if err != nil {
+			return fmt.Errorf("row %d: invalid longitude %q", ln, row[gnLon])
+		}
+		cc := row[gnCountry]
+
+		level := locality
+		switch {
+		case row[gnClass] == "A" && row[gnCode] == "ADM1":
+			level = state
+			admin1[cc+"."+row[gnAdmin1]] = fold(row[gnName])
+		case row[gnClass] == "A" && row[gnCode] == "ADM2":
+			level = county
+			admin2[cc+"."+row[gnAdmin1]+"."+row[gnAdmin2]] = fold(row[gnName])
+		}
+		if level != locality {
+			if err := memory.Add(int64(len(row[gnName])) + 64); err != nil {
+				return fmt.Errorf("row %d: %v", ln, err)
+			}
+		}
+
+		p := &place{
+			lat:    lat,
+			lon:    lon,
+			unc:    defUncertainty[level],
+			admin1: cc + "." + row[gnAdmin1],
+			admin2: cc + "." + row[gnAdmin1] + "." + row[gnAdmin2],
+		}
+		if err := memory.Add(int64(len(p.admin1)+len(p.admin2)) + 64); err != nil {
+			return fmt.Errorf("row %d: %v", ln, err)
+		}
+		places = append(places, p)
+
+		names := []string{row[gnName], row[gnASCII]}
+		if row[gnAlternate] != "" {
+			names = append(names, strings.Split(row[gnAlternate], ",")...)
+		}
+		seen := make(map[string]bool, len(names))
+		for _, n := range names {
+			n = fold(n)
+			if seen[n] {
+				continue
+			}
+			seen[n] = true
+			if err := g.add(level, cc, n, p); err != nil {
+				return fmt.Errorf("row %d: %v", ln, err)
+			}
+		}
+
+		row, err = tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			ln, _ := tab.FieldPos(0)
+			return fmt.Errorf("row %d: %v", ln, err)
+		}
+	}
+
+	// set the names of the administrative divisions
+	for _, p := range places {
+		p.state = admin1[p.admin1]
+		p.county = admin2[p.admin2]
+		p.admin1, p.admin2 = "", ""
+	}
+	return nil
+}
+
+// A match is the result of a search
+// in the gazetteer.
+type match struct {
+	lat, lon float64
+	unc      float64
+	places   int // number of matched places
+}
+
+// search searches a place
+// at a given level.
+// If more than one place is found,
+// the result is the centroid of the places,
+// if all the places are within maxSpread meters
+// of the centroid.
+func (g *gazetteer) search(level, country, name, stateName, countyName string, maxSpread float64) (match, bool) {
+	ps := g.places[placeKey(level, country, name)]
+	if len(ps) == 0 {
+		return match{}, false
+	}
+	stateName = fold(stateName)
+	countyName = fold(countyName)
+
+	var sel []*place
+	for _, p := range ps {
+		if level != state && stateName != "" && p.state != "" && p.state != stateName {
+			continue
+		}
+		if level == locality && countyName != "" && p.county != "" && p.county != countyName {
+			continue
+		}
+		sel = append(sel, p)
+	}
+	if len(sel) == 0 {
+		return match{}, false
+	}
+
+	// centroid
+	var x, y, z float64
+	for _, p := range sel {
+		lat, lon := p.lat*math.Pi/180, p.lon*math.Pi/180
+		x += math.Cos(lat) * math.Cos(lon)
+		y += math.Cos(lat) * math.Sin(lon)
+		z += math.Sin(lat)
+	}
+	n := float64(len(sel))
+	x, y, z = x/n, y/n, z/n
+	m := match{
+		lat:    math.Atan2(z, math.Hypot(x, y)) * 180 / math.Pi,
+		lon:    math.Atan2(y, x) * 180 / math.Pi,
+		places: len(sel),
+	}
+
+	var spread float64
+	for _, p := range sel {
+		d := distance(m.lat, m.lon, p.lat, p.lon)
+		if d > spread {
+			spread = d
+		}
+		if d+p.unc > m.unc {
+			m.unc = d + p.unc
+		}
+	}
+	if spread > maxSpread {
+		return match{}, false
+	}
+	if len(sel) == 1 {
+		m.lat, m.lon = sel[0].lat, sel[0].lon
+	}
+	return m, true
+}
+
+// earthRadius is the mean radius of the Earth,
+// in meters.
+const earthRadius = 6_371_008.8
+
+// distance returns the great circle distance,
+// in meters,
+// between two points.
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLon := (lon2 - lon1) * toRad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// accents are the replacements
+// of accented characters.
+var accents = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o", "ø", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ý", "y", "ÿ", "y",
+	"ñ", "n", "ç", "c", "ß", "ss", "æ", "ae", "œ", "oe",
+)
+
+// fold returns a name in lower case,
+// without accents and punctuation,
+// and without repeated spaces.
+func fold(s string) string {
+	s = accents.Replace(strings.ToLower(s))
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case '.', ',', ';', ':', '-', '_', '\'', '"', '(', ')':
+			return ' '
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,294 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package georef implements a command to georeference
+// the records without coordinates
+// of a GBIF occurrence table
+// using a gazetteer.
+package georef
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `georef --gazetteer <file> [--levels <list>] [--max-spread <value>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "georeference records using a gazetteer",
+	Long: `
+Command georef reads a GBIF occurrence table from the standard input and, for
+the records without coordinates, searches the locality, county, or state
+province of the record in a gazetteer, and sets the coordinates of the record
+with the coordinates of the found place.
+
+The flag --gazetteer is required and defines the gazetteer file. It can be a
+GeoNames dump (for example, "AR.txt" or "allCountries.txt", downloaded from
+<https://download.geonames.org/export/dump/>), in which first-order
+administrative divisions are state provinces, second-order administrative
+divisions are counties, and any other place is a locality. All the names of a
+place (including the alternate names) are used in the search. It can also be
+a tab-delimited file with a header, and the columns "name", "countryCode",
+"decimalLatitude", and "decimalLongitude". Optional columns are "level" (one
+of "locality", "county", or "stateProvince"; the default is "locality"),
+"stateProvince", and "county" (the names of the divisions that contain the
+place), and "coordinateUncertaintyInMeters".
+
+Names are compared without case, accents, or punctuation, and must match the
+complete value of the record field. Places are searched in the country of the
+record (the "countryCode" field is required). If the record and the place have
+a state province, or a county, they must be the same. By default, the record
+locality is searched, and if not found, the record county; use the flag
+--levels, with a comma separated list, to define the searched levels and their
+order. Valid levels are "locality", "county", and "stateProvince".
+
+If a name matches more than one place, the record is georeferenced at the
+centroid of the places if all of them are within a given distance of the
+centroid; otherwise the record is not georeferenced. By default the distance
+is 10 km; use the flag --max-spread to define a different distance (in km).
+
+The uncertainty of the coordinates is the uncertainty of the place (by
+default, 5 km for localities, 50 km for counties, and 250 km for state
+provinces), plus the distance to the centroid if more than one place was
+found.
+
+Georeferenced records are flagged by the fields "georeferenceSources", with
+the name of the gazetteer, and "georeferenceRemarks", with the matched level
+and name. The fields "decimalLatitude", "decimalLongitude",
+"coordinateUncertaintyInMeters", "georeferenceSources", and
+"georeferenceRemarks" will be added to the table if they are not present.
+Records with coordinates are not modified.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var gazFile string
+var levelsFlag string
+var maxSpread float64
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&gazFile, "gazetteer", "", "")
+	c.Flags().StringVar(&levelsFlag, "levels", "locality,county", "")
+	c.Flags().Float64Var(&maxSpread, "max-spread", 10, "")
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if gazFile == "" {
+		return c.UsageError("flag --gazetteer must be defined")
+	}
+	if maxSpread < 0 {
+		return c.UsageError(fmt.Sprintf("invalid --max-spread value %.3f", maxSpread))
+	}
+	var levels []string
+	for _, v := range strings.Split(levelsFlag, ",") {
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "":
+			continue
+		case "locality":
+			levels = append(levels, locality)
+		case "county":
+			levels = append(levels, county)
+		case "stateprovince":
+			levels = append(levels, state)
+		default:
+			return c.UsageError(fmt.Sprintf("invalid level %q", v))
+		}
+	}
+	if len(levels) == 0 {
+		return c.UsageError("flag --levels without levels")
+	}
+
+	gz, err := readGazetteer(gazFile)
+	if err != nil {
+		return err
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := georef(in, out, gz, levels); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Added fields.
+const (
+	latField     = "decimalLatitude"
+	lonField     = "decimalLongitude"
+	uncField     = "coordinateUncertaintyInMeters"
+	sourceField  = "georeferenceSources"
+	remarksField = "georeferenceRemarks"
+)
+
+func georef(r io.Reader, w io.Writer, gz *gazetteer, levels []string) error {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return fmt.Errorf("when reading %q header: %v", input, err)
+	}
+
+	fields := make(map[string]int, len(header))
+	for i, h := range header {
+		fields[strings.ToLower(h)] = i
+	}
+	if _, ok := fields["countrycode"]; !ok {
+		return fmt.Errorf("input data %q without %q field", input, "countryCode")
+	}
+
+	// add missing fields
+	nh := header[:len(header):len(header)]
+	for _, f := range []string{latField, lonField, uncField, sourceField, remarksField} {
+		if _, ok := fields[strings.ToLower(f)]; ok {
+			continue
+		}
+		fields[strings.ToLower(f)] = len(nh)
+		nh = append(nh, f)
+	}
+	extra := len(nh) - len(header)
+
+	col := func(row []string, name string) string {
+		i, ok := fields[strings.ToLower(name)]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	if err := out.Write(nh); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	source := "gazetteer: " + gazFile
+	counts := make(map[string]int64)
+	var notGeoref int64
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+		for i := 0; i < extra; i++ {
+			row = append(row, "")
+		}
+
+		if col(row, latField) != "" && col(row, lonField) != "" {
+			if err := out.Write(row); err != nil {
+				return fmt.Errorf("when writing on %q: %v", output, err)
+			}
+			logger.WriteRows(1)
+			continue
+		}
+
+		cc := col(row, "countryCode")
+		st := col(row, "stateProvince")
+		ct := col(row, "county")
+		found := false
+		for _, lv := range levels {
+			name := col(row, lv)
+			if cc == "" || name == "" {
+				continue
+			}
+			m, ok := gz.search(lv, cc, name, st, ct, maxSpread*1000)
+			if !ok {
+				continue
+			}
+
+			row[fields[strings.ToLower(latField)]] = strconv.FormatFloat(m.lat, 'f', 6, 64)
+			row[fields[strings.ToLower(lonField)]] = strconv.FormatFloat(m.lon, 'f', 6, 64)
+			row[fields[strings.ToLower(uncField)]] = strconv.FormatFloat(m.unc, 'f', 0, 64)
+			row[fields[strings.ToLower(sourceField)]] = source
+			remarks := fmt.Sprintf("georeferenced from %s %q", lv, name)
+			if m.places > 1 {
+				remarks += fmt.Sprintf(" (centroid of %d places)", m.places)
+			}
+			if r := col(row, remarksField); r != "" {
+				remarks = r + "; " + remarks
+			}
+			row[fields[strings.ToLower(remarksField)]] = remarks
+
+			counts[lv]++
+			found = true
+			break
+		}
+		if !found {
+			notGeoref++
+		}
+
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	for _, lv := range levels {
+		logger.Add("georeferenced-"+lv, counts[lv])
+	}
+	logger.Add("not-georeferenced", notGeoref)
+	return nil
+}
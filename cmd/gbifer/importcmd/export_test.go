@@ -0,0 +1,12 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package importcmd
+
+// SplitDate returns the event date,
+// year,
+// month,
+// and day,
+// of an exported date.
+var SplitDate = splitDate
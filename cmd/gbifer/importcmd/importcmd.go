@@ -0,0 +1,323 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package importcmd implements a command to import
+// a file exported with the export command
+// as a GBIF occurrence table.
+package importcmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `import [-o|--output <file>] [<file>]`,
+	Short: "import an exported file",
+	Long: `
+Command import reads a file produced by the export command (a TSV file
+compatible with RFC 4180) and converts it back into a GBIF occurrence table,
+so the records edited with other tools can be processed again with GBIFer.
+
+The argument of the command is the file to import. If no file is given, it
+will read the data from the standard input. Files with comma separated values
+(for example, a file exported with the wallace format) are also accepted.
+
+The columns of the exported file are renamed with the names used by GBIF:
+
+	species            species
+	speciesID          speciesKey
+	latitude           decimalLatitude
+	longitude          decimalLongitude
+	geoRefUncertainty  coordinateUncertaintyInMeters
+	gbifID             gbifID
+	catalog            institutionCode, collectionCode, and
+	                   catalogNumber
+	occurrenceID       occurrenceID
+	date               eventDate, year, month, and day
+	country            countryCode
+	province           stateProvince
+	county             county
+	locality           verbatimLocality
+	taxon              scientificName
+	taxonID            taxonKey
+	dataset            datasetName
+	datasetID          datasetKey
+	publisher          publisher
+	reference          bibliographicCitation
+	license            license
+
+The columns "scientific_name" and "name" are imported as "species". Any other
+column is kept with its original name. The catalog column is split into the
+institution code, the collection code, and the catalog number, if it has the
+form "<institution>:<collection>:<catalog>". Dates without a known year
+(exported with a year of 1700 or before) are imported as empty dates. As the
+export command writes the date of a record with only a year as January 1 of
+that year, at midnight, a date on January 1 at midnight is imported as a date
+with only a year (i.e., without month and day), so a record collected on
+January 1, without a time, is imported without its month and day. A record
+with only a year and month is imported on the first day of the month.
+Uncertainty and identifiers with a value of 0 (a missing value in the exported
+file) are imported as empty values.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	in := c.Stdin()
+	if len(args) > 0 {
+		input = args[0]
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := importTable(in, out); err != nil {
+		return err
+	}
+	return nil
+}
+
+// gbifNames are the GBIF names
+// of the exported columns.
+var gbifNames = map[string]string{
+	"species":           "species",
+	"scientific_name":   "species",
+	"name":              "species",
+	"speciesid":         "speciesKey",
+	"latitude":          "decimalLatitude",
+	"longitude":         "decimalLongitude",
+	"georefuncertainty": "coordinateUncertaintyInMeters",
+	"gbifid":            "gbifID",
+	"occurrenceid":      "occurrenceID",
+	"country":           "countryCode",
+	"province":          "stateProvince",
+	"county":            "county",
+	"locality":          "verbatimLocality",
+	"taxon":             "scientificName",
+	"taxonid":           "taxonKey",
+	"dataset":           "datasetName",
+	"datasetid":         "datasetKey",
+	"publisher":         "publisher",
+	"reference":         "bibliographicCitation",
+	"license":           "license",
+}
+
+// Columns with special conversions.
+const (
+	catalogCol = "catalog"
+	dateCol    = "date"
+)
+
+// zeroEmpty are the columns
+// in which a 0 is a missing value.
+var zeroEmpty = map[string]bool{
+	"speciesKey":                    true,
+	"coordinateUncertaintyInMeters": true,
+	"taxonKey":                      true,
+}
+
+// unknownYear is the year used in exported files
+// for records without a date.
+const unknownYear = 1700
+
+func importTable(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	comma := '\t'
+	line, err := br.Peek(4096)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("when reading %q header: %v", input, err)
+	}
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	if !bytes.ContainsRune(line, '\t') && bytes.ContainsRune(line, ',') {
+		comma = ','
+	}
+
+	tab := csv.NewReader(br)
+	tab.Comma = comma
+	tab.FieldsPerRecord = -1
+
+	header, err := tab.Read()
+	if err != nil {
+		return fmt.Errorf("when reading %q header: %v", input, err)
+	}
+
+	// the output columns
+	// and the input column of each one
+	var nh []string
+	var cols []int
+	catalog, date := -1, -1
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		switch strings.ToLower(h) {
+		case catalogCol:
+			catalog = i
+			continue
+		case dateCol:
+			date = i
+			continue
+		}
+		if n, ok := gbifNames[strings.ToLower(h)]; ok {
+			h = n
+		}
+		nh = append(nh, h)
+		cols = append(cols, i)
+	}
+	catPos := len(nh)
+	if catalog >= 0 {
+		nh = append(nh, "institutionCode", "collectionCode", "catalogNumber")
+	}
+	datePos := len(nh)
+	if date >= 0 {
+		nh = append(nh, "eventDate", "year", "month", "day")
+	}
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	if err := out.Write(nh); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		nr := make([]string, len(nh))
+		for i, c := range cols {
+			if c >= len(row) {
+				continue
+			}
+			v := strings.TrimSpace(row[c])
+			if v == "0" && zeroEmpty[nh[i]] {
+				v = ""
+			}
+			nr[i] = v
+		}
+		if catalog >= 0 && catalog < len(row) {
+			inst, coll, cat := splitCatalog(strings.TrimSpace(row[catalog]))
+			nr[catPos] = inst
+			nr[catPos+1] = coll
+			nr[catPos+2] = cat
+		}
+		if date >= 0 && date < len(row) {
+			ed, y, m, d := splitDate(strings.TrimSpace(row[date]))
+			nr[datePos] = ed
+			nr[datePos+1] = y
+			nr[datePos+2] = m
+			nr[datePos+3] = d
+		}
+
+		if err := out.Write(nr); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+// splitCatalog returns the institution code,
+// collection code,
+// and catalog number,
+// of an exported catalog.
+func splitCatalog(v string) (inst, coll, cat string) {
+	if strings.HasPrefix(v, "gbif:") {
+		// records without a catalog number
+		return "", "", ""
+	}
+	parts := strings.SplitN(v, ":", 3)
+	if len(parts) != 3 {
+		return "", "", v
+	}
+	return parts[0], parts[1], parts[2]
+}
+
+// splitDate returns the event date,
+// year,
+// month,
+// and day,
+// of an exported date.
+func splitDate(v string) (eventDate, year, month, day string) {
+	if v == "" {
+		return "", "", "", ""
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		// keep the date as it is
+		return v, "", "", ""
+	}
+	t = t.UTC()
+	if t.Year() <= unknownYear {
+		return "", "", "", ""
+	}
+	if t.Month() == time.January && t.Day() == 1 && t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		// the date of a record with only a year
+		y := strconv.Itoa(t.Year())
+		return y, y, "", ""
+	}
+	return t.Format("2006-01-02T15:04:05"), strconv.Itoa(t.Year()), strconv.Itoa(int(t.Month())), strconv.Itoa(t.Day())
+}
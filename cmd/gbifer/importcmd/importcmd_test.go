@@ -0,0 +1,48 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package importcmd_test
+
+import (
+	"testing"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/importcmd"
+)
+
+func TestSplitDate(t *testing.T) {
+	tests := map[string]struct {
+		date  string
+		event string
+		year  string
+		month string
+		day   string
+	}{
+		"empty":            {},
+		"full date":        {"2002-05-17T10:30:00Z", "2002-05-17T10:30:00", "2002", "5", "17"},
+		"time zone":        {"2002-05-17T23:00:00-03:00", "2002-05-18T02:00:00", "2002", "5", "18"},
+		"year only":        {"2002-01-01T00:00:00Z", "2002", "2002", "", ""},
+		"january 1 time":   {"2002-01-01T08:00:00Z", "2002-01-01T08:00:00", "2002", "1", "1"},
+		"year and month":   {"2002-03-01T00:00:00Z", "2002-03-01T00:00:00", "2002", "3", "1"},
+		"unknown year":     {"1700-01-01T00:00:00Z", "", "", "", ""},
+		"not a time stamp": {"2002-05", "2002-05", "", "", ""},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			event, year, month, day := importcmd.SplitDate(test.date)
+			if event != test.event {
+				t.Errorf("eventDate: got %q, want %q", event, test.event)
+			}
+			if year != test.year {
+				t.Errorf("year: got %q, want %q", year, test.year)
+			}
+			if month != test.month {
+				t.Errorf("month: got %q, want %q", month, test.month)
+			}
+			if day != test.day {
+				t.Errorf("day: got %q, want %q", day, test.day)
+			}
+		})
+	}
+}
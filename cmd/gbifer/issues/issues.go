@@ -0,0 +1,259 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package issues implements a command to count
+// the GBIF issue flags
+// of a GBIF occurrence table.
+package issues
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `issues [--dataset]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "count the GBIF issue flags",
+	Long: `
+Command issues reads a GBIF occurrence table from the standard input and
+prints the number of records with each of the GBIF issue flags (for example,
+COUNTRY_COORDINATE_MISMATCH, or RECORDED_DATE_INVALID), as defined in the
+"issue" column (or "issues" column) of the table. The table can be used to
+decide which flags should be used to filter the records.
+
+The output is a TSV table with the following columns:
+
+	- issue: the issue flag.
+	- records: the number of records with the flag.
+	- percent: the percentage of records with the flag.
+
+Rows are sorted from the flag with more records to the flag with fewer
+records.
+
+If the flag --dataset is defined, the flags will be counted for each dataset
+(using the "datasetKey" column), and the table will have an additional
+"datasetKey" column as the first column. In this case, the percentage is the
+percentage of the records of the dataset, and rows are sorted by dataset.
+
+The number of records without issues is reported in the standard error.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var datasetFlag bool
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().BoolVar(&datasetFlag, "dataset", false, "")
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	ds, err := readTable(in)
+	if err != nil {
+		return err
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := writeIssues(out, ds); err != nil {
+		return err
+	}
+	return nil
+}
+
+// issueCount stores the number of records
+// of each issue flag
+// in a set of records.
+type issueCount struct {
+	key     string // dataset key
+	records int
+	issues  map[string]int
+}
+
+func readTable(r io.Reader) ([]*issueCount, error) {
+	tab := tabfile.NewReader(r, "issue", "issues", "datasetKey")
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+
+	iCol := -1
+	dsCol := -1
+	for i, h := range header {
+		switch strings.ToLower(h) {
+		case "issue", "issues":
+			iCol = i
+		case "datasetkey":
+			dsCol = i
+		}
+	}
+	if iCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q field", input, "issue")
+	}
+	if datasetFlag && dsCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q field", input, "datasetKey")
+	}
+
+	counts := make(map[string]*issueCount)
+	var clean, total int
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+		total++
+
+		var key string
+		if datasetFlag {
+			key = strings.TrimSpace(row[dsCol])
+		}
+		ic, ok := counts[key]
+		if !ok {
+			ic = &issueCount{
+				key:    key,
+				issues: make(map[string]int),
+			}
+			counts[key] = ic
+		}
+		ic.records++
+
+		// flags are separated by semicolons
+		// (or commas in some exports)
+		flags := strings.FieldsFunc(row[iCol], func(r rune) bool {
+			return r == ';' || r == ','
+		})
+		found := false
+		for _, fl := range flags {
+			fl = strings.ToUpper(strings.TrimSpace(fl))
+			if fl == "" {
+				continue
+			}
+			ic.issues[fl]++
+			found = true
+		}
+		if !found {
+			clean++
+		}
+	}
+	logger.Printf("%d of %d records without issues", clean, total)
+
+	ls := make([]*issueCount, 0, len(counts))
+	for _, ic := range counts {
+		ls = append(ls, ic)
+	}
+	slices.SortFunc(ls, func(a, b *issueCount) int {
+		return cmp.Compare(a.key, b.key)
+	})
+	return ls, nil
+}
+
+func writeIssues(w io.Writer, ds []*issueCount) error {
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	var header []string
+	if datasetFlag {
+		header = append(header, "datasetKey")
+	}
+	header = append(header, "issue", "records", "percent")
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	for _, ic := range ds {
+		flags := make([]string, 0, len(ic.issues))
+		for fl := range ic.issues {
+			flags = append(flags, fl)
+		}
+		slices.SortFunc(flags, func(a, b string) int {
+			if c := cmp.Compare(ic.issues[b], ic.issues[a]); c != 0 {
+				return c
+			}
+			return cmp.Compare(a, b)
+		})
+
+		for _, fl := range flags {
+			n := ic.issues[fl]
+			var row []string
+			if datasetFlag {
+				row = append(row, ic.key)
+			}
+			row = append(row,
+				fl,
+				strconv.Itoa(n),
+				strconv.FormatFloat(100*float64(n)/float64(ic.records), 'f', 2, 64),
+			)
+			if err := out.Write(row); err != nil {
+				return fmt.Errorf("when writing on %q: %v", output, err)
+			}
+			logger.WriteRows(1)
+		}
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package join implements a command to join
+// the rows of a GBIF occurrence table
+// with the rows of an attribute table.
+package join
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `join --with <file> --on <column>[=<column>]
+	[--report <file>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "join an attribute table",
+	Long: `
+Command join reads a GBIF occurrence table from the standard input and adds
+the values of an attribute table (for example, a table of species traits) to
+each row. It is a left join: all the rows of the occurrence table are kept,
+and rows without a match in the attribute table will have empty values.
+
+The attribute table is required and must be defined with the flag --with. It
+must be a TSV file, and each value of the key column must be unique.
+
+The flag --on is required and defines the column used to join the tables. If
+the column has a different name in the attribute table, use the form
+<column>=<column>, with the column of the occurrence table first, for example:
+
+	--on species=name
+
+Keys are compared after removing extra spaces, and ignoring the case, so
+"Puma  concolor" and "puma concolor" are the same key.
+
+All the columns of the attribute table, except the key column, are added to
+the occurrence table. If a column of the attribute table is already in the
+occurrence table, the command will fail.
+
+Keys of the occurrence table that do not match any row in the attribute table
+are counted and reported in the standard error. If the flag --report is
+defined, the unmatched keys and their number of records will be written in
+the indicated file.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+var withFile string
+var onFlag string
+var reportFile string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().StringVar(&withFile, "with", "", "")
+	c.Flags().StringVar(&onFlag, "on", "", "")
+	c.Flags().StringVar(&reportFile, "report", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if withFile == "" {
+		return c.UsageError("flag --with undefined")
+	}
+	if onFlag == "" {
+		return c.UsageError("flag --on undefined")
+	}
+	occKey, attrKey, ok := strings.Cut(onFlag, "=")
+	if !ok {
+		attrKey = occKey
+	}
+	occKey = strings.TrimSpace(occKey)
+	attrKey = strings.TrimSpace(attrKey)
+	if occKey == "" || attrKey == "" {
+		return c.UsageError(fmt.Sprintf("invalid --on value %q", onFlag))
+	}
+
+	at, err := readAttributes(attrKey)
+	if err != nil {
+		return err
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	unmatched, err := join(in, out, occKey, at)
+	if err != nil {
+		return err
+	}
+
+	if len(unmatched) > 0 {
+		n := 0
+		for _, v := range unmatched {
+			n += v
+		}
+		logger.Printf("%d unmatched keys (%d records)", len(unmatched), n)
+	}
+	if reportFile != "" {
+		if err := writeReport(unmatched); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// An attributes is a table of attributes.
+type attributes struct {
+	header []string
+	rows   map[string][]string
+}
+
+func readAttributes(key string) (*attributes, error) {
+	f, err := os.Open(withFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tab := tabfile.NewReader(f)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", withFile, err)
+	}
+	keyCol := -1
+	for i, h := range header {
+		if strings.EqualFold(h, key) {
+			keyCol = i
+		}
+	}
+	if keyCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q field", withFile, key)
+	}
+
+	at := &attributes{
+		header: slices.Delete(slices.Clone(header), keyCol, keyCol+1),
+		rows:   make(map[string][]string),
+	}
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", withFile, ln, err)
+		}
+
+		k := normKey(row[keyCol])
+		if k == "" {
+			continue
+		}
+		if _, dup := at.rows[k]; dup {
+			return nil, fmt.Errorf("table %q: row %d: duplicated key %q", withFile, ln, row[keyCol])
+		}
+		if err := memory.Add(memory.Row(row)); err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", withFile, ln, err)
+		}
+		at.rows[k] = slices.Delete(row, keyCol, keyCol+1)
+	}
+	return at, nil
+}
+
+// normKey returns a key
+// without extra spaces
+// and in lower case.
+func normKey(k string) string {
+	return strings.ToLower(strings.Join(strings.Fields(k), " "))
+}
+
+func join(r io.Reader, w io.Writer, key string, at *attributes) (map[string]int, error) {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+	keyCol := -1
+	fields := make(map[string]bool, len(header))
+	for i, h := range header {
+		if strings.EqualFold(h, key) {
+			keyCol = i
+		}
+		fields[strings.ToLower(h)] = true
+	}
+	if keyCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q field", input, key)
+	}
+	for _, h := range at.header {
+		if fields[strings.ToLower(h)] {
+			return nil, fmt.Errorf("input data %q: field %q already defined", input, h)
+		}
+	}
+	nCols := len(header)
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	header = append(header, at.header...)
+	if err := out.Write(header); err != nil {
+		return nil, fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	empty := make([]string, len(at.header))
+	unmatched := make(map[string]int)
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		k := normKey(row[keyCol])
+		vals, ok := at.rows[k]
+		if !ok {
+			vals = empty
+			if k != "" {
+				unmatched[k]++
+			}
+		}
+		row = append(row[:nCols:nCols], vals...)
+
+		if err := out.Write(row); err != nil {
+			return nil, fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return nil, fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return unmatched, nil
+}
+
+func writeReport(unmatched map[string]int) (err error) {
+	keys := make([]string, 0, len(unmatched))
+	for k := range unmatched {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		if c := cmp.Compare(unmatched[b], unmatched[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+
+	f, err := os.Create(reportFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		e := f.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	w := tsv.NewWriter(f)
+	w.Comma = '\t'
+	w.UseCRLF = true
+
+	if err := w.Write([]string{"key", "records"}); err != nil {
+		return fmt.Errorf("when writing on %q: %v", reportFile, err)
+	}
+	for _, k := range keys {
+		if err := w.Write([]string{k, strconv.Itoa(unmatched[k])}); err != nil {
+			return fmt.Errorf("when writing on %q: %v", reportFile, err)
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", reportFile, err)
+	}
+	return nil
+}
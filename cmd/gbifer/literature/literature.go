@@ -0,0 +1,251 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package literature implements a command to list
+// the publications that cite the datasets
+// of a GBIF occurrence table.
+package literature
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `literature [--download <key>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "list publications that cite the data",
+	Long: `
+Command literature reads a GBIF occurrence table from the standard input and
+retrieves from the GBIF literature service the publications that cite the
+datasets of the table (as defined in the "datasetKey" column). This is useful
+to report the use of the data to the data providers.
+
+If the flag --download is defined, the publications that cite the indicated
+download will be listed, and no occurrence table will be read. The download
+can be defined by its key (for example, 0001234-230810091245214) or its DOI
+(for example, 10.15468/dl.abcd12).
+
+The output is a TSV file with the following columns: "id" (the ID of the
+publication in GBIF), "title", "authors", "year", "source", "type", "doi",
+and "datasets" (the keys of the cited datasets present in the table,
+separated by ';'). Publications are sorted by year, and then by title.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+
+This command requires an internet connection.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+var download string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().StringVar(&download, "download", "", "")
+}
+
+// A citing is a publication
+// with the cited datasets.
+type citing struct {
+	pub      *gbif.Publication
+	datasets []string
+}
+
+func run(c *command.Command, args []string) (err error) {
+	gbif.Open()
+
+	pubs := make(map[string]*citing)
+	if download != "" {
+		ls, err := gbif.LiteratureDownload(download)
+		if err != nil {
+			return err
+		}
+		for _, p := range ls {
+			pubs[p.ID] = &citing{pub: p}
+		}
+	} else {
+		in := c.Stdin()
+		if input != "" {
+			f, err := os.Open(input)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		} else {
+			input = "stdin"
+		}
+
+		keys, err := readDatasets(in)
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			ls, err := gbif.LiteratureDataset(k)
+			if err != nil {
+				return err
+			}
+			if len(ls) == 0 {
+				logger.Printf("dataset %s: no publications", k)
+			}
+			for _, p := range ls {
+				ct, ok := pubs[p.ID]
+				if !ok {
+					ct = &citing{pub: p}
+					pubs[p.ID] = ct
+				}
+				ct.datasets = append(ct.datasets, k)
+			}
+		}
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f *os.File
+		f, err = os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := writePublications(out, pubs); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+// readDatasets returns the dataset keys
+// of an occurrence table.
+func readDatasets(r io.Reader) ([]string, error) {
+	tab := tabfile.NewReader(r, "datasetKey")
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+	dsCol := -1
+	for i, h := range header {
+		if strings.ToLower(h) == "datasetkey" {
+			dsCol = i
+		}
+	}
+	if dsCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q field", input, "datasetKey")
+	}
+
+	datasets := make(map[string]bool)
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		key := strings.TrimSpace(row[dsCol])
+		if key == "" {
+			continue
+		}
+		datasets[key] = true
+	}
+
+	keys := make([]string, 0, len(datasets))
+	for k := range datasets {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+func writePublications(w io.Writer, pubs map[string]*citing) error {
+	ls := make([]*citing, 0, len(pubs))
+	for _, ct := range pubs {
+		ls = append(ls, ct)
+	}
+	slices.SortFunc(ls, func(a, b *citing) int {
+		if a.pub.Year != b.pub.Year {
+			return a.pub.Year - b.pub.Year
+		}
+		if c := strings.Compare(a.pub.Title, b.pub.Title); c != 0 {
+			return c
+		}
+		return strings.Compare(a.pub.ID, b.pub.ID)
+	})
+
+	tab := tsv.NewWriter(w)
+	tab.Comma = '\t'
+	tab.UseCRLF = true
+
+	header := []string{"id", "title", "authors", "year", "source", "type", "doi", "datasets"}
+	if err := tab.Write(header); err != nil {
+		return err
+	}
+	for _, ct := range ls {
+		p := ct.pub
+		var authors []string
+		for _, a := range p.Authors {
+			name := strings.TrimSpace(a.LastName)
+			if fn := strings.TrimSpace(a.FirstName); fn != "" {
+				name += ", " + fn
+			}
+			authors = append(authors, name)
+		}
+		year := ""
+		if p.Year > 0 {
+			year = strconv.Itoa(p.Year)
+		}
+		row := []string{
+			p.ID,
+			strings.Join(strings.Fields(p.Title), " "),
+			strings.Join(authors, "; "),
+			year,
+			strings.Join(strings.Fields(p.Source), " "),
+			p.LiteratureType,
+			p.Identifiers.DOI,
+			strings.Join(ct.datasets, ";"),
+		}
+		if err := tab.Write(row); err != nil {
+			return err
+		}
+		logger.WriteRows(1)
+	}
+
+	tab.Flush()
+	return tab.Error()
+}
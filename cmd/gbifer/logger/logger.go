@@ -0,0 +1,199 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package logger implements the messages
+// that the commands print
+// in the standard error.
+//
+// Messages are printed as lines that start with '#'
+// followed by the name of the command.
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/js-arias/gbifer/gbif"
+)
+
+// Level is the verbosity level.
+type Level int
+
+// Valid verbosity levels.
+const (
+	// Quiet only prints errors.
+	Quiet Level = iota
+
+	// Normal prints errors,
+	// and the summary messages of the commands.
+	Normal
+
+	// Verbose prints errors,
+	// the summary messages of the commands,
+	// and the counters of each run.
+	Verbose
+)
+
+var (
+	mu       sync.Mutex
+	level    = Normal
+	out      = io.Writer(os.Stderr)
+	name     = "gbifer"
+	start    = time.Now()
+	counters = make(map[string]int64)
+)
+
+// rowsRead and rowsWritten are the number of data rows
+// read from the main input table,
+// and written on the main output table,
+// of the command.
+var rowsRead, rowsWritten atomic.Int64
+
+// ReadRows adds n to the number of data rows
+// read from the main input table of the command
+// (i.e., without the header,
+// or the rows of auxiliary tables).
+func ReadRows(n int64) {
+	rowsRead.Add(n)
+}
+
+// WriteRows adds n to the number of data rows
+// written on the main output table of the command
+// (i.e., without the header,
+// or the rows of auxiliary tables).
+func WriteRows(n int64) {
+	rowsWritten.Add(n)
+}
+
+// metrics are the metrics
+// of the requests to the GBIF API.
+var metrics = gbif.NewMetrics()
+
+func init() {
+	gbif.AddHook(metrics)
+}
+
+// SetLevel sets the verbosity level.
+func SetLevel(l Level) {
+	mu.Lock()
+	defer mu.Unlock()
+	level = l
+}
+
+// Verbosity returns the current verbosity level.
+func Verbosity() Level {
+	mu.Lock()
+	defer mu.Unlock()
+	return level
+}
+
+// SetOutput sets the destination of the messages.
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	out = w
+}
+
+// SetName sets the name used
+// in the prefix of each message.
+func SetName(n string) {
+	mu.Lock()
+	defer mu.Unlock()
+	name = n
+}
+
+// Printf prints a summary message,
+// unless the level is Quiet.
+func Printf(format string, a ...any) {
+	mu.Lock()
+	defer mu.Unlock()
+	msg := fmt.Sprintf(format, a...)
+	if reportFile != "" {
+		messages = append(messages, msg)
+	}
+	if level < Normal {
+		return
+	}
+	fmt.Fprintf(out, "# %s: %s\n", name, msg)
+}
+
+// Warnf prints a warning
+// about a row of the input table,
+// unless the level is Quiet.
+func Warnf(row int, format string, a ...any) {
+	mu.Lock()
+	defer mu.Unlock()
+	msg := fmt.Sprintf(format, a...)
+	if reportFile != "" {
+		if len(warnings) < MaxWarnings {
+			warnings = append(warnings, warning{Row: row, Message: msg})
+		} else {
+			lostWarnings++
+		}
+	}
+	if level < Normal {
+		return
+	}
+	fmt.Fprintf(out, "# %s: row %d: %s\n", name, row, msg)
+}
+
+// Verbosef prints a message
+// only if the level is Verbose.
+func Verbosef(format string, a ...any) {
+	mu.Lock()
+	defer mu.Unlock()
+	if level < Verbose {
+		return
+	}
+	fmt.Fprintf(out, "# %s: %s\n", name, fmt.Sprintf(format, a...))
+}
+
+// Add adds n to a counter,
+// for example,
+// the number of rows dropped by a criterion.
+func Add(counter string, n int64) {
+	mu.Lock()
+	defer mu.Unlock()
+	counters[counter] += n
+}
+
+// Summary prints,
+// if the level is Verbose,
+// the number of rows read and written,
+// the number of requests to the GBIF API
+// (and the metrics of each endpoint),
+// the elapsed time,
+// and the values of the counters.
+func Summary() {
+	mu.Lock()
+	defer mu.Unlock()
+	if level < Verbose {
+		return
+	}
+
+	fmt.Fprintf(out, "# %s: rows-read=%d rows-written=%d api-requests=%d elapsed=%s\n", name, rowsRead.Load(), rowsWritten.Load(), gbif.Requests(), time.Since(start).Round(time.Millisecond))
+	for _, ep := range metrics.Stats() {
+		fmt.Fprintf(out, "# %s: api-endpoint=%s requests=%d retries=%d failures=%d time=%s\n", name, ep.Endpoint, ep.Requests, ep.Retries, ep.Failures, ep.Time.Round(time.Millisecond))
+	}
+
+	if len(counters) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(counters))
+	for k := range counters {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	ls := make([]string, 0, len(keys))
+	for _, k := range keys {
+		ls = append(ls, fmt.Sprintf("%s=%d", k, counters[k]))
+	}
+	fmt.Fprintf(out, "# %s: %s\n", name, strings.Join(ls, " "))
+}
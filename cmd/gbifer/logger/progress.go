@@ -0,0 +1,194 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ProgressInterval is the time between
+// two progress reports.
+var ProgressInterval = 2 * time.Second
+
+// A Progress reports the progress
+// of reading an input table.
+//
+// Progress reports are always printed,
+// regardless of the verbosity level,
+// as they are explicitly requested by the user.
+type Progress struct {
+	r     io.Reader
+	size  int64
+	read  atomic.Int64
+	rows  int64
+	start time.Time
+
+	once sync.Once
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewProgress starts the report of the progress
+// of reading r.
+// If r is a file,
+// the progress is reported as a percentage of the file size,
+// otherwise,
+// only the number of read rows
+// (as counted by ReadRows)
+// is reported.
+//
+// The returned Progress must be used as the reader,
+// instead of r.
+func NewProgress(r io.Reader) *Progress {
+	p := &Progress{
+		r:     r,
+		start: time.Now(),
+		done:  make(chan struct{}),
+	}
+	if f, ok := r.(*os.File); ok {
+		if st, err := f.Stat(); err == nil && st.Mode().IsRegular() {
+			p.size = st.Size()
+		}
+	}
+	p.rows = rowsRead.Load()
+
+	p.wg.Add(1)
+	go tick(p.done, &p.wg, p.report)
+	return p
+}
+
+// tick calls report
+// at each progress interval,
+// until done is closed.
+func tick(done chan struct{}, wg *sync.WaitGroup, report func()) {
+	defer wg.Done()
+	t := time.NewTicker(ProgressInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-t.C:
+			report()
+		}
+	}
+}
+
+// Read implements the io.Reader interface.
+func (p *Progress) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read.Add(int64(n))
+	return n, err
+}
+
+// Stop stops the progress reports
+// and prints a final report.
+func (p *Progress) Stop() {
+	p.once.Do(func() {
+		close(p.done)
+		p.wg.Wait()
+		p.report()
+	})
+}
+
+func (p *Progress) report() {
+	rows := rowsRead.Load() - p.rows
+	elapsed := time.Since(p.start)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if p.size == 0 {
+		fmt.Fprintf(out, "# %s: %d rows, elapsed %s\n", name, rows, elapsed.Round(time.Second))
+		return
+	}
+
+	read := p.read.Load()
+	frac := float64(read) / float64(p.size)
+	var eta time.Duration
+	if read > 0 && read < p.size {
+		eta = time.Duration(float64(elapsed) * (1 - frac) / frac)
+	}
+	fmt.Fprintf(out, "# %s: %.1f%% read, %d rows, elapsed %s, eta %s\n", name, frac*100, rows, elapsed.Round(time.Second), eta.Round(time.Second))
+}
+
+// A Counter reports the progress
+// of a process that retrieves items
+// (for example, the records of a search),
+// when the number of items is known
+// as the process advances.
+//
+// As a Progress,
+// counter reports are always printed.
+type Counter struct {
+	unit  string
+	total atomic.Int64
+	n     atomic.Int64
+	start time.Time
+
+	once sync.Once
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewCounter starts the report of the progress
+// of a process that retrieves items
+// of the given unit
+// (for example, "records").
+func NewCounter(unit string) *Counter {
+	c := &Counter{
+		unit:  unit,
+		start: time.Now(),
+		done:  make(chan struct{}),
+	}
+	c.wg.Add(1)
+	go tick(c.done, &c.wg, c.report)
+	return c
+}
+
+// Add adds n retrieved items.
+func (c *Counter) Add(n int64) {
+	c.n.Add(n)
+}
+
+// SetTotal sets the total number of items
+// to be retrieved.
+func (c *Counter) SetTotal(total int64) {
+	c.total.Store(total)
+}
+
+// Stop stops the progress reports
+// and prints a final report.
+func (c *Counter) Stop() {
+	c.once.Do(func() {
+		close(c.done)
+		c.wg.Wait()
+		c.report()
+	})
+}
+
+func (c *Counter) report() {
+	n := c.n.Load()
+	total := c.total.Load()
+	elapsed := time.Since(c.start)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if total <= 0 {
+		fmt.Fprintf(out, "# %s: %d %s, elapsed %s\n", name, n, c.unit, elapsed.Round(time.Second))
+		return
+	}
+
+	frac := float64(n) / float64(total)
+	var eta time.Duration
+	if n > 0 && n < total {
+		eta = time.Duration(float64(elapsed) * (1 - frac) / frac)
+	}
+	fmt.Fprintf(out, "# %s: %d of %d %s (%.1f%%), elapsed %s, eta %s\n", name, n, total, c.unit, frac*100, elapsed.Round(time.Second), eta.Round(time.Second))
+}
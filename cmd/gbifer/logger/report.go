@@ -0,0 +1,123 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/js-arias/gbifer/gbif"
+)
+
+// MaxWarnings is the maximum number of warnings
+// stored in a report.
+var MaxWarnings = 10_000
+
+var (
+	reportFile   string
+	messages     []string
+	warnings     []warning
+	lostWarnings int
+)
+
+// An endpoint are the metrics
+// of an endpoint of the GBIF API.
+type endpoint struct {
+	Endpoint string  `json:"endpoint"`
+	Requests int64   `json:"requests"`
+	Retries  int64   `json:"retries"`
+	Failures int64   `json:"failures"`
+	Time     float64 `json:"seconds"`
+}
+
+type warning struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// A report is the machine-readable summary
+// of a command run.
+type report struct {
+	Command      string           `json:"command"`
+	RowsRead     int64            `json:"rowsRead"`    // data rows of the main input
+	RowsWritten  int64            `json:"rowsWritten"` // data rows of the main output
+	APIRequests  int64            `json:"apiRequests"`
+	APIEndpoints []endpoint       `json:"apiEndpoints"`
+	Elapsed      float64          `json:"elapsedSeconds"`
+	Counters     map[string]int64 `json:"counters"`
+	Messages     []string         `json:"messages"`
+	Warnings     []warning        `json:"warnings"`
+	LostWarnings int              `json:"omittedWarnings,omitempty"`
+}
+
+// SetReport sets the file
+// in which the JSON report will be written.
+func SetReport(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	reportFile = name
+}
+
+// WriteReport writes the JSON report,
+// with the number of data rows read and written
+// (as counted by ReadRows and WriteRows),
+// the number of requests to the GBIF API
+// (and the metrics of each endpoint),
+// the elapsed time,
+// the values of the counters,
+// and the messages and warnings printed by the command.
+// If no report file was set,
+// it does nothing.
+func WriteReport() (err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if reportFile == "" {
+		return nil
+	}
+
+	rep := report{
+		Command:      name,
+		RowsRead:     rowsRead.Load(),
+		RowsWritten:  rowsWritten.Load(),
+		APIRequests:  gbif.Requests(),
+		Elapsed:      time.Since(start).Seconds(),
+		Counters:     counters,
+		Messages:     messages,
+		Warnings:     warnings,
+		LostWarnings: lostWarnings,
+	}
+	rep.APIEndpoints = []endpoint{}
+	for _, ep := range metrics.Stats() {
+		rep.APIEndpoints = append(rep.APIEndpoints, endpoint{
+			Endpoint: ep.Endpoint,
+			Requests: ep.Requests,
+			Retries:  ep.Retries,
+			Failures: ep.Failures,
+			Time:     ep.Time.Seconds(),
+		})
+	}
+	if rep.Messages == nil {
+		rep.Messages = []string{}
+	}
+	if rep.Warnings == nil {
+		rep.Warnings = []warning{}
+	}
+
+	f, err := os.Create(reportFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		e := f.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
@@ -6,31 +6,305 @@
 package main
 
 import (
+	"fmt"
+	"os"
+	"strings"
+
 	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/accepted"
+	"github.com/js-arias/gbifer/cmd/gbifer/anonymize"
+	"github.com/js-arias/gbifer/cmd/gbifer/apply"
+	"github.com/js-arias/gbifer/cmd/gbifer/cache"
+	"github.com/js-arias/gbifer/cmd/gbifer/check"
+	"github.com/js-arias/gbifer/cmd/gbifer/cite"
+	"github.com/js-arias/gbifer/cmd/gbifer/collectors"
 	"github.com/js-arias/gbifer/cmd/gbifer/cols"
+	"github.com/js-arias/gbifer/cmd/gbifer/concat"
+	"github.com/js-arias/gbifer/cmd/gbifer/config"
+	"github.com/js-arias/gbifer/cmd/gbifer/count"
 	"github.com/js-arias/gbifer/cmd/gbifer/country"
+	"github.com/js-arias/gbifer/cmd/gbifer/coverage"
+	"github.com/js-arias/gbifer/cmd/gbifer/dataset"
+	"github.com/js-arias/gbifer/cmd/gbifer/dedup"
+	"github.com/js-arias/gbifer/cmd/gbifer/density"
+	"github.com/js-arias/gbifer/cmd/gbifer/diff"
+	"github.com/js-arias/gbifer/cmd/gbifer/effort"
+	"github.com/js-arias/gbifer/cmd/gbifer/enrich"
 	"github.com/js-arias/gbifer/cmd/gbifer/export"
+	"github.com/js-arias/gbifer/cmd/gbifer/fetch"
 	"github.com/js-arias/gbifer/cmd/gbifer/filter"
+	"github.com/js-arias/gbifer/cmd/gbifer/fix"
+	"github.com/js-arias/gbifer/cmd/gbifer/geohash"
+	"github.com/js-arias/gbifer/cmd/gbifer/georef"
+	"github.com/js-arias/gbifer/cmd/gbifer/head"
+	"github.com/js-arias/gbifer/cmd/gbifer/importcmd"
+	"github.com/js-arias/gbifer/cmd/gbifer/issues"
+	"github.com/js-arias/gbifer/cmd/gbifer/join"
+	"github.com/js-arias/gbifer/cmd/gbifer/literature"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/mapcmd"
+	"github.com/js-arias/gbifer/cmd/gbifer/media"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/pivot"
+	"github.com/js-arias/gbifer/cmd/gbifer/pixel"
+	"github.com/js-arias/gbifer/cmd/gbifer/run"
+	"github.com/js-arias/gbifer/cmd/gbifer/schema"
+	"github.com/js-arias/gbifer/cmd/gbifer/slice"
 	"github.com/js-arias/gbifer/cmd/gbifer/sort"
+	"github.com/js-arias/gbifer/cmd/gbifer/split"
+	"github.com/js-arias/gbifer/cmd/gbifer/sql"
+	"github.com/js-arias/gbifer/cmd/gbifer/tail"
 	"github.com/js-arias/gbifer/cmd/gbifer/tax"
+	"github.com/js-arias/gbifer/cmd/gbifer/temporal"
+	"github.com/js-arias/gbifer/cmd/gbifer/unique"
+	"github.com/js-arias/gbifer/cmd/gbifer/updaterecords"
+	"github.com/js-arias/gbifer/cmd/gbifer/verify"
 	"github.com/js-arias/gbifer/cmd/gbifer/withsp"
 )
 
 var app = &command.Command{
-	Usage: "gbifer <command> [<argument>...]",
+	Usage: `gbifer [-v|--verbose] [-q|--quiet] [--max-memory <size>]
+	[--report-json <file>] <command> [<argument>...]`,
 	Short: "a tool to manipulate GBIF occurrence tables",
+	Long: `
+GBIFer is a tool to manipulate GBIF occurrence tables.
+
+By default, the commands print errors, and a summary of their results, in the
+standard error. If the flag --quiet, or -q, is given before the command name,
+only errors will be printed. If the flag --verbose, or -v, is given before the
+command name, at the end of the command it will also print the number of data
+rows read from the input table and written to the output table (headers, and
+auxiliary tables, such as a taxonomy, are not counted), the number of requests
+to the GBIF API, the elapsed time, and, in some commands, the number of rows
+dropped by each criterion. For each endpoint of the GBIF API used by the
+command (for example, "species" or "occurrence/search"), it will also print
+the number of requests, retries, and failed requests, and the time spent on
+the requests.
+
+If the flag --report-json is given before the command name, when the command
+finishes successfully, a JSON report will be written in the indicated file.
+The report includes the name of the command ("command"), the number of data
+rows read from the input table ("rowsRead") and written to the output table
+("rowsWritten"), without headers or auxiliary tables, the number of requests
+to the GBIF API ("apiRequests"), the metrics of each endpoint of the GBIF API
+("apiEndpoints"), the elapsed time in seconds ("elapsedSeconds"), the
+counters of the command, for example, the number of rows dropped by each
+criterion ("counters"), the summary messages ("messages"), and the warnings
+about particular rows, with their row number ("warnings"). The report is
+intended to be read by automated pipelines.
+
+Most commands read the input table row by row, and use a constant amount of
+memory, regardless of the size of the table. Some commands must keep data in
+memory: sort keeps the whole table, diff keeps the old table, join keeps the
+joined table, check keeps the record IDs, media keeps the record IDs and
+catalog numbers, unique keeps the distinct values, and dedup keeps a hash of
+the distinct rows (unless it uses temporary files). The summary commands
+(such as count, pivot, cite, temporal, coverage, collectors, density, schema,
+or export with the html, ndm, or cube formats) keep a counter for each taxon,
+site, dataset, or value, tail keeps the last rows, map and pixel (with
+--taxa) keep the pixels of each taxon, georef keeps the gazetteer, and effort
+keeps a raster for each taxon. If the flag --max-memory
+is given before the command name, the commands that keep data in memory will
+fail as soon as the kept data exceeds the indicated size (for example "512M"
+or "2G"), instead of exhausting the memory of the system. The size is an
+estimation of the kept data, so the memory used by the program will be
+larger; use a size well below the available memory.
+
+A table that is read many times can be stored as a columnar cache file, with
+the command "cache build". Any command that reads an occurrence table from a
+file (for example, with the flag --input) can read a cache file instead of the
+text table. Commands that only use some columns of the table, only read those
+columns from the cache file.
+
+Default values for some flags can be defined in a configuration file. By
+default, the configuration file is "gbifer/config.toml" in the user
+configuration directory (for example, "~/.config/gbifer/config.toml" in
+Linux); use the environment variable GBIFER_CONFIG to use a different file.
+The file uses a subset of TOML, for example:
+
+	# default taxonomy file, used by the flag --tax of the commands
+	# accepted, count, country, coverage, enrich, export, sort, and
+	# temporal.
+	taxonomy = "/home/user/data/taxonomy.tab"
+
+	# default dataset cache, used by the flag --cache of the command
+	# "dataset attribution".
+	dataset-cache = "/home/user/data/datasets.tab"
+
+	[gbif]
+	# waiting time between requests to the GBIF API.
+	wait = "300ms"
+	# number of times a failed request is retried.
+	retry = 5
+	# timeout of each request.
+	timeout = "20s"
+	# maximum number of records (for example, the children of a
+	# taxon) retrieved by a request of a list. A request that
+	# exceeds the limit fails. Use 0 for no limit.
+	max-records = 10000
+	# proxy used to connect to the GBIF API. By default, the proxy
+	# is defined by the environment variables HTTPS_PROXY,
+	# HTTP_PROXY, and NO_PROXY.
+	proxy = "http://proxy.example.org:3128"
+	# credentials of a GBIF account, sent with each request to the
+	# GBIF API.
+	user = "gbif-user"
+	password = "secret"
+
+	[output]
+	# compression of the output tables, either "none" or "gzip".
+	compression = "gzip"
+
+The commands filter and anonymize do not use the default taxonomy, as in
+those commands the flag --tax defines a selection criterion (in filter) or
+the sensitive taxa (in anonymize), rather than the reference taxonomy.
+
+If the compression of the output tables is "gzip", the tables written with
+the flag --output, or -o, are compressed with gzip (the tables written in the
+standard output are never compressed). An output file whose name ends with
+".gz" is always compressed. Reports and text outputs, such as the citations
+of cite, the publications of literature, the problems found by check, the
+schema of schema, and the changes of tax diff (unless --tsv is defined), are
+never compressed; the counts table of cite --counts is compressed as any other
+output table. Compressed tables are decompressed automatically when they are
+read as input tables.
+
+The values can also be defined with the environment variables
+GBIFER_TAXONOMY, GBIFER_DATASET_CACHE, GBIFER_GBIF_WAIT, GBIFER_GBIF_RETRY,
+GBIFER_GBIF_TIMEOUT, GBIFER_GBIF_MAX_RECORDS, GBIFER_GBIF_PROXY,
+GBIFER_GBIF_USER, GBIFER_GBIF_PASSWORD, GBIFER_GBIF_RECORD,
+GBIFER_GBIF_REPLAY, and GBIFER_OUTPUT_COMPRESSION, that take precedence over
+the values of the configuration file. Flags given in the command line always
+take precedence.
+
+The answers of the GBIF API can be recorded, so a command can be run again
+later with exactly the same answers, and without an internet connection (for
+example, to test a pipeline). The key "record" of the [gbif] table (or the
+environment variable GBIFER_GBIF_RECORD) defines the directory in which the
+answers will be stored, and the key "replay" (or the environment variable
+GBIFER_GBIF_REPLAY) defines the directory from which the recorded answers
+will be read, instead of making requests to the GBIF API. In replay mode, a
+request without a recorded answer is an error.
+	`,
+	SetFlags: setFlags,
+}
+
+func setFlags(c *command.Command) {
+	verbose := func(string) error {
+		logger.SetLevel(logger.Verbose)
+		return nil
+	}
+	quiet := func(string) error {
+		logger.SetLevel(logger.Quiet)
+		return nil
+	}
+	c.Flags().BoolFunc("verbose", "", verbose)
+	c.Flags().BoolFunc("v", "", verbose)
+	c.Flags().BoolFunc("quiet", "", quiet)
+	c.Flags().BoolFunc("q", "", quiet)
+	c.Flags().Func("report-json", "", func(s string) error {
+		logger.SetReport(s)
+		return nil
+	})
+	c.Flags().Func("max-memory", "", func(s string) error {
+		n, err := memory.ParseSize(s)
+		if err != nil {
+			return err
+		}
+		memory.SetLimit(n)
+		return nil
+	})
 }
 
 func init() {
+	app.Add(accepted.Command)
+	app.Add(anonymize.Command)
+	app.Add(apply.Command)
+	app.Add(cache.Command)
+	app.Add(check.Command)
+	app.Add(cite.Command)
+	app.Add(collectors.Command)
 	app.Add(cols.Command)
+	app.Add(concat.Command)
+	app.Add(count.Command)
 	app.Add(country.Command)
+	app.Add(coverage.Command)
+	app.Add(dataset.Command)
+	app.Add(dedup.Command)
+	app.Add(density.Command)
+	app.Add(diff.Command)
+	app.Add(effort.Command)
+	app.Add(enrich.Command)
 	app.Add(export.Command)
+	app.Add(fetch.Command)
 	app.Add(filter.Command)
+	app.Add(fix.Command)
+	app.Add(geohash.Command)
+	app.Add(georef.Command)
+	app.Add(head.Command)
+	app.Add(importcmd.Command)
+	app.Add(issues.Command)
+	app.Add(join.Command)
+	app.Add(literature.Command)
+	app.Add(mapcmd.Command)
+	app.Add(media.Command)
+	app.Add(pivot.Command)
+	app.Add(pixel.Command)
+	app.Add(run.Command)
+	app.Add(schema.Command)
+	app.Add(slice.Command)
 	app.Add(sort.Command)
+	app.Add(split.Command)
+	app.Add(sql.Command)
+	app.Add(tail.Command)
 	app.Add(tax.Command)
+	app.Add(temporal.Command)
+	app.Add(unique.Command)
+	app.Add(updaterecords.Command)
+	app.Add(verify.Command)
 	app.Add(withsp.Command)
 }
 
 func main() {
+	logger.SetOutput(app.Stderr())
+	logger.SetName(cmdName(os.Args[1:]))
+	if err := config.Load(); err != nil {
+		fmt.Fprintf(app.Stderr(), "gbifer: %v.\n", err)
+		os.Exit(1)
+	}
 	app.Main()
+	logger.Summary()
+	if err := logger.WriteReport(); err != nil {
+		fmt.Fprintf(app.Stderr(), "gbifer: when writing report: %v.\n", err)
+		os.Exit(1)
+	}
+}
+
+// cmdName returns the name of the executed command.
+func cmdName(args []string) string {
+	var name []string
+	skip := false
+	for _, a := range args {
+		if skip {
+			skip = false
+			continue
+		}
+		if strings.HasPrefix(a, "-") {
+			// skip the value of the flags
+			// that require a value
+			switch strings.TrimLeft(a, "-") {
+			case "max-memory", "report-json":
+				skip = true
+			}
+			continue
+		}
+		name = append(name, a)
+		if strings.ToLower(a) != "tax" {
+			break
+		}
+	}
+	if len(name) == 0 {
+		return "gbifer"
+	}
+	return strings.Join(name, " ")
 }
@@ -0,0 +1,337 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package mapcmd
+
+// Coastline is a coarse outline
+// of the coasts of the continents,
+// the largest islands,
+// and the Black and Caspian seas,
+// as polylines of geographic points.
+// It is simplified to about a thousand points,
+// so it is only useful as a reference
+// in a quick-look map.
+var coastline = [][]point{
+	// North and South America
+	{
+		{65.6, -168.0}, {67.5, -164.0}, {71.3, -156.8}, {69.6, -141.0}, {70.0, -129.0}, {68.5, -117.0},
+		{68.0, -108.0}, {67.5, -96.0}, {68.8, -90.0}, {68.5, -82.0}, {66.0, -86.0}, {64.0, -87.0},
+		{61.0, -94.0}, {58.8, -94.0}, {57.0, -92.5}, {55.3, -85.0}, {52.9, -82.3}, {51.5, -79.0},
+		{54.6, -79.0}, {58.0, -77.5}, {60.8, -78.0}, {62.5, -77.5}, {62.0, -73.0}, {61.0, -69.5},
+		{59.5, -65.0}, {56.0, -61.5}, {53.0, -57.0}, {51.5, -56.0}, {50.2, -60.0}, {50.2, -66.5},
+		{48.8, -64.5}, {47.5, -65.0}, {45.5, -61.0}, {44.0, -66.0}, {43.5, -70.0}, {41.7, -70.0},
+		{40.5, -74.0}, {38.0, -75.5}, {35.0, -76.0}, {31.5, -81.0}, {27.0, -80.0}, {25.2, -80.5},
+		{26.0, -81.7}, {28.0, -82.7}, {30.0, -84.0}, {30.3, -88.0}, {29.0, -89.5}, {29.3, -91.0},
+		{29.6, -94.0}, {27.5, -97.3}, {24.0, -97.5}, {21.5, -97.5}, {18.7, -95.0}, {18.6, -92.0},
+		{19.5, -91.0}, {21.0, -90.4}, {21.5, -87.0}, {18.5, -87.6}, {15.7, -88.2}, {15.8, -84.0},
+		{12.0, -83.3}, {10.8, -83.6}, {9.0, -82.0}, {9.6, -79.5}, {8.6, -77.3}, {10.5, -75.5},
+		{12.0, -72.0}, {11.0, -71.5}, {10.6, -67.0}, {10.6, -62.0}, {8.5, -60.0}, {6.0, -57.0},
+		{5.0, -52.0}, {1.8, -50.0}, {0.0, -50.0}, {-1.0, -48.0}, {-2.5, -44.5}, {-2.9, -40.0},
+		{-4.8, -37.0}, {-5.5, -35.2}, {-9.0, -35.0}, {-11.0, -37.0}, {-13.5, -39.0}, {-17.8, -39.0},
+		{-20.5, -40.5}, {-23.0, -42.0}, {-23.8, -45.0}, {-26.0, -48.5}, {-28.5, -48.7}, {-30.5, -51.0},
+		{-33.7, -53.4}, {-35.0, -55.0}, {-34.6, -58.4}, {-36.3, -57.0}, {-38.2, -57.6}, {-39.0, -62.0},
+		{-40.6, -62.3}, {-41.0, -65.0}, {-42.4, -64.2}, {-45.0, -65.5}, {-46.5, -67.5}, {-48.0, -65.8},
+		{-51.5, -69.0}, {-52.3, -68.4}, {-53.8, -71.0}, {-52.0, -74.5}, {-48.0, -75.5}, {-44.0, -74.0},
+		{-42.5, -73.7}, {-39.0, -73.5}, {-37.0, -73.2}, {-33.0, -71.6}, {-30.0, -71.4}, {-23.5, -70.4},
+		{-18.5, -70.2}, {-17.3, -71.5}, {-15.3, -75.0}, {-13.8, -76.4}, {-7.5, -79.5}, {-4.7, -81.3},
+		{-2.5, -80.0}, {-1.0, -80.5}, {0.9, -80.0}, {1.5, -78.8}, {4.0, -77.5}, {6.7, -77.4},
+		{8.3, -78.0}, {7.3, -80.0}, {8.3, -83.0}, {10.0, -85.7}, {13.0, -87.5}, {14.0, -91.5},
+		{16.0, -94.0}, {15.7, -96.5}, {17.3, -101.0}, {20.0, -105.5}, {21.6, -105.3}, {23.2, -106.4},
+		{25.7, -109.0}, {29.5, -112.5}, {31.7, -114.8}, {29.0, -113.0}, {24.0, -110.0}, {22.9, -109.9},
+		{24.8, -112.0}, {27.8, -114.3}, {30.3, -115.8}, {32.5, -117.1}, {34.0, -118.5}, {34.6, -120.6},
+		{37.8, -122.5}, {40.4, -124.2}, {43.0, -124.5}, {46.2, -124.0}, {48.4, -124.7}, {49.0, -123.0},
+		{50.8, -127.5}, {54.5, -130.5}, {57.5, -133.5}, {58.8, -137.5}, {60.0, -141.0}, {60.8, -146.0},
+		{59.2, -151.5}, {57.5, -154.0}, {57.5, -156.0}, {55.0, -163.0}, {58.7, -158.0}, {58.6, -162.0},
+		{60.5, -165.0}, {62.5, -165.0}, {64.5, -161.0}, {64.6, -165.0}, {65.6, -168.0},
+	},
+	// Greenland
+	{
+		{78.5, -73.0}, {82.0, -60.0}, {82.5, -45.0}, {83.5, -30.0}, {82.0, -20.0}, {81.5, -12.0},
+		{77.0, -18.0}, {74.0, -19.0}, {70.5, -22.0}, {69.0, -25.0}, {68.0, -32.0}, {65.5, -38.0},
+		{63.0, -41.0}, {60.0, -43.0}, {61.0, -48.0}, {64.0, -51.0}, {66.5, -53.0}, {69.0, -51.0},
+		{70.5, -54.5}, {72.5, -56.0}, {75.5, -58.0}, {76.0, -66.0}, {78.5, -73.0},
+	},
+	// Baffin Island
+	{
+		{73.7, -80.0}, {71.7, -73.0}, {69.8, -67.5}, {66.5, -61.5}, {63.5, -64.0}, {62.0, -65.5},
+		{63.5, -72.0}, {64.5, -78.0}, {68.0, -73.5}, {70.0, -80.0}, {72.0, -88.0}, {73.7, -80.0},
+	},
+	// Ellesmere Island
+	{
+		{76.5, -90.0}, {76.3, -78.0}, {79.0, -74.0}, {82.0, -62.0}, {83.0, -70.0}, {81.0, -88.0},
+		{79.0, -95.0}, {76.5, -90.0},
+	},
+	// Victoria Island
+	{
+		{71.5, -119.0}, {73.5, -113.0}, {73.0, -104.0}, {70.0, -101.0}, {69.0, -105.0}, {68.5, -113.0},
+		{69.8, -118.0}, {71.5, -119.0},
+	},
+	// Newfoundland
+	{
+		{47.6, -59.3}, {51.6, -55.8}, {49.5, -55.5}, {47.5, -53.0}, {46.6, -53.5}, {47.6, -56.0},
+		{47.6, -59.3},
+	},
+	// Vancouver Island
+	{
+		{48.3, -123.4}, {48.9, -125.5}, {50.7, -128.3}, {51.0, -127.5}, {50.0, -125.0}, {48.3, -123.4},
+	},
+	// Cuba
+	{
+		{21.9, -85.0}, {23.1, -81.0}, {22.3, -77.0}, {20.2, -74.2}, {19.8, -77.7}, {21.8, -80.0},
+		{22.2, -82.5}, {21.9, -85.0},
+	},
+	// Hispaniola
+	{
+		{18.4, -74.5}, {19.9, -72.5}, {19.7, -70.0}, {18.6, -68.4}, {17.6, -71.5}, {18.4, -74.5},
+	},
+	// Jamaica
+	{
+		{18.4, -78.3}, {18.0, -76.3}, {17.7, -77.2}, {18.4, -78.3},
+	},
+	// Tierra del Fuego
+	{
+		{-52.6, -68.6}, {-54.7, -65.2}, {-55.2, -67.0}, {-54.8, -70.5}, {-53.5, -73.0}, {-53.0, -70.5},
+		{-52.6, -68.6},
+	},
+	// Falkland Islands
+	{
+		{-51.2, -61.3}, {-51.2, -58.0}, {-51.7, -57.8}, {-52.3, -59.4}, {-51.2, -61.3},
+	},
+	// Europe and northern Asia
+	{
+		{38.8, -9.5}, {42.5, -8.8}, {43.0, -9.3}, {43.7, -8.0}, {43.4, -2.0}, {46.0, -1.2}, {47.1, -2.2},
+		{48.0, -4.7}, {48.8, -3.0}, {48.6, -1.5}, {49.7, -1.9}, {49.5, 0.1}, {50.9, 1.6}, {51.4, 3.7},
+		{52.7, 4.5}, {53.4, 5.8}, {53.8, 8.5}, {55.0, 8.6}, {56.7, 8.1}, {57.7, 10.5}, {56.4, 10.9},
+		{55.2, 10.0}, {54.0, 10.9}, {54.4, 12.5}, {53.9, 14.3}, {54.8, 18.5}, {55.1, 21.0}, {56.8, 21.0},
+		{57.0, 23.5}, {57.2, 24.2}, {59.2, 23.5}, {59.5, 28.0}, {60.0, 30.0}, {60.4, 26.0}, {60.0, 22.8},
+		{60.7, 21.4}, {63.0, 21.5}, {65.0, 25.0}, {65.8, 24.5}, {65.6, 22.0}, {64.3, 21.0}, {62.8, 18.5},
+		{60.6, 17.3}, {59.8, 18.9}, {56.5, 16.5}, {55.4, 14.3}, {55.6, 12.8}, {58.0, 11.5}, {59.0, 11.2},
+		{59.3, 10.5}, {58.0, 8.0}, {58.8, 5.6}, {61.0, 5.0}, {62.0, 5.0}, {63.5, 8.0}, {64.5, 10.0},
+		{66.0, 12.5}, {67.7, 14.0}, {69.0, 16.0}, {70.0, 19.0}, {70.7, 23.0}, {71.0, 28.0}, {70.0, 31.0},
+		{69.3, 33.0}, {69.0, 36.0}, {67.5, 41.0}, {66.4, 40.0}, {66.5, 44.0}, {68.3, 44.0}, {68.0, 46.5},
+		{68.5, 53.5}, {69.0, 58.0}, {69.8, 60.5}, {69.5, 66.0}, {71.8, 68.5}, {73.0, 71.5}, {68.5, 73.0},
+		{72.0, 78.0}, {73.5, 80.0}, {74.0, 86.0}, {75.0, 87.0}, {76.0, 95.0}, {77.7, 104.0},
+		{73.7, 113.0}, {73.0, 119.0}, {72.5, 128.0}, {71.0, 130.0}, {72.5, 140.0}, {71.5, 150.0},
+		{70.0, 160.0}, {69.9, 170.0}, {69.8, 176.0}, {68.9, 180.0},
+	},
+	// Chukotka
+	{
+		{68.9, -180.0}, {67.5, -175.0}, {66.8, -171.5}, {66.1, -169.7}, {65.4, -171.0}, {64.3, -173.0},
+		{65.0, -178.0}, {65.1, -180.0},
+	},
+	// Southern Asia and Africa
+	{
+		{65.1, 180.0}, {64.7, 177.0}, {62.3, 179.0}, {60.5, 172.0}, {59.9, 164.0}, {58.0, 163.0},
+		{56.2, 162.5}, {53.0, 160.0}, {51.0, 156.6}, {57.5, 156.0}, {57.8, 156.8}, {61.0, 160.0},
+		{62.5, 163.5}, {61.5, 160.0}, {59.5, 155.0}, {59.2, 151.0}, {59.3, 143.0}, {54.0, 137.5},
+		{53.0, 141.0}, {48.5, 140.5}, {43.5, 135.0}, {43.0, 132.0}, {42.3, 130.7}, {36.0, 129.5},
+		{35.2, 129.3}, {34.5, 126.5}, {37.5, 126.3}, {39.5, 125.0}, {40.8, 121.8}, {39.2, 119.0},
+		{38.9, 117.7}, {37.5, 118.5}, {37.8, 120.5}, {37.0, 122.5}, {35.8, 120.0}, {34.8, 119.3},
+		{32.0, 120.9}, {30.8, 121.9}, {29.8, 122.0}, {27.0, 120.5}, {25.5, 119.5}, {23.0, 116.5},
+		{22.2, 113.5}, {21.0, 110.0}, {20.3, 110.3}, {21.5, 109.5}, {21.5, 108.0}, {20.2, 106.6},
+		{18.9, 106.0}, {16.7, 107.3}, {13.0, 109.0}, {11.6, 109.2}, {10.3, 106.7}, {8.6, 105.0},
+		{10.4, 104.8}, {11.5, 103.0}, {12.6, 100.9}, {13.4, 100.1}, {10.3, 99.2}, {8.0, 100.3},
+		{6.9, 101.3}, {4.8, 103.5}, {1.4, 104.2}, {1.4, 103.4}, {2.9, 101.0}, {5.5, 100.3}, {8.0, 98.4},
+		{10.0, 98.5}, {16.5, 97.7}, {15.8, 95.3}, {16.3, 94.3}, {19.0, 94.2}, {20.7, 92.3}, {22.4, 91.8},
+		{22.0, 90.5}, {21.7, 89.0}, {21.5, 87.0}, {20.5, 86.9}, {19.3, 85.0}, {16.6, 82.3}, {13.0, 80.3},
+		{10.3, 79.8}, {8.9, 78.2}, {8.1, 77.5}, {9.5, 76.3}, {12.8, 75.0}, {16.5, 73.4}, {19.0, 72.8},
+		{21.4, 72.6}, {20.9, 70.4}, {22.4, 69.0}, {23.7, 68.2}, {25.4, 66.7}, {25.2, 61.6}, {25.8, 57.3},
+		{27.1, 56.3}, {26.7, 54.0}, {27.9, 51.4}, {30.1, 50.1}, {29.9, 48.5}, {29.5, 47.9}, {28.0, 48.7},
+		{26.0, 50.3}, {26.1, 51.2}, {24.6, 51.6}, {24.2, 54.0}, {26.2, 56.3}, {24.6, 56.4}, {23.6, 58.7},
+		{22.5, 59.8}, {19.0, 57.8}, {17.6, 55.4}, {15.6, 52.2}, {14.0, 48.5}, {12.8, 45.0}, {12.7, 43.5},
+		{14.8, 42.8}, {16.4, 42.5}, {21.5, 39.2}, {24.2, 37.9}, {28.0, 35.2}, {29.5, 34.9}, {28.0, 34.5},
+		{29.9, 32.6}, {27.6, 33.6}, {23.8, 35.5}, {21.0, 37.2}, {18.7, 37.4}, {16.0, 39.0}, {15.3, 40.0},
+		{13.4, 41.7}, {11.6, 43.2}, {10.4, 44.5}, {11.8, 51.2}, {10.0, 50.8}, {6.0, 49.0}, {4.5, 47.5},
+		{0.0, 43.0}, {-2.5, 40.8}, {-5.0, 39.3}, {-8.0, 39.3}, {-10.5, 40.5}, {-14.5, 40.6},
+		{-17.5, 37.5}, {-20.0, 35.0}, {-22.0, 35.5}, {-24.0, 35.4}, {-25.9, 32.8}, {-28.0, 32.9},
+		{-29.9, 31.0}, {-32.7, 28.2}, {-34.0, 25.6}, {-34.0, 22.5}, {-34.8, 20.0}, {-34.1, 18.4},
+		{-31.5, 18.2}, {-28.6, 16.5}, {-26.5, 15.0}, {-22.9, 14.5}, {-17.3, 11.8}, {-13.5, 12.3},
+		{-9.5, 13.4}, {-6.0, 12.2}, {-5.0, 11.9}, {-2.5, 9.5}, {0.0, 9.3}, {3.5, 9.6}, {4.5, 8.5},
+		{4.3, 6.1}, {6.0, 5.0}, {6.4, 2.7}, {4.8, -2.0}, {4.4, -7.5}, {6.5, -10.5}, {8.9, -13.2},
+		{11.0, -15.0}, {12.6, -16.7}, {14.7, -17.5}, {19.5, -16.5}, {21.0, -17.0}, {24.0, -14.9},
+		{27.6, -13.0}, {29.5, -10.0}, {31.5, -9.8}, {34.0, -6.8}, {35.8, -5.9}, {35.1, -2.0},
+		{36.5, 1.0}, {36.8, 5.0}, {37.3, 9.8}, {37.0, 11.0}, {34.5, 10.5}, {33.3, 11.1}, {32.3, 15.3},
+		{30.3, 19.5}, {32.2, 20.1}, {32.6, 23.0}, {31.6, 25.0}, {31.0, 29.5}, {31.3, 32.3}, {31.3, 34.3},
+		{32.8, 35.0}, {34.6, 35.8}, {36.7, 36.0}, {36.2, 34.3}, {36.1, 32.5}, {36.4, 30.5}, {36.8, 28.3},
+		{37.8, 27.3}, {38.5, 26.4}, {40.2, 26.6}, {40.6, 26.2}, {40.8, 24.0}, {40.6, 22.9}, {39.2, 23.3},
+		{38.0, 24.0}, {36.4, 22.9}, {36.8, 21.7}, {37.8, 21.1}, {39.6, 20.0}, {41.8, 19.4}, {43.5, 16.6},
+		{45.1, 13.7}, {45.4, 12.4}, {44.3, 12.3}, {42.5, 14.0}, {41.4, 16.0}, {40.1, 18.5}, {39.0, 17.0},
+		{38.0, 16.6}, {38.0, 15.6}, {39.5, 15.9}, {40.5, 14.4}, {41.4, 12.5}, {42.9, 10.6}, {44.4, 8.9},
+		{43.8, 7.5}, {43.1, 6.0}, {43.5, 4.5}, {43.2, 3.0}, {41.9, 3.2}, {41.0, 0.8}, {39.5, -0.3},
+		{38.7, 0.2}, {37.6, -0.8}, {36.7, -2.1}, {36.7, -4.4}, {36.0, -5.6}, {36.5, -6.3}, {37.2, -7.4},
+		{37.0, -8.9}, {38.5, -8.8}, {38.8, -9.5},
+	},
+	// Black Sea
+	{
+		{41.2, 29.0}, {41.2, 31.5}, {42.0, 34.0}, {41.0, 38.0}, {41.5, 41.5}, {42.5, 41.6}, {44.6, 37.5},
+		{46.9, 38.2}, {45.0, 35.0}, {45.3, 36.5}, {44.5, 33.5}, {45.4, 32.5}, {46.5, 30.7}, {45.2, 29.6},
+		{44.0, 28.6}, {42.7, 27.9}, {41.2, 29.0},
+	},
+	// Caspian Sea
+	{
+		{44.5, 47.0}, {46.5, 49.0}, {47.0, 51.5}, {45.3, 53.0}, {44.5, 51.3}, {42.0, 52.8}, {40.8, 54.0},
+		{37.3, 53.9}, {36.7, 51.0}, {37.6, 49.0}, {39.5, 49.2}, {40.5, 49.6}, {42.0, 48.0}, {44.5, 47.0},
+	},
+	// Great Britain
+	{
+		{50.0, -5.7}, {50.6, -3.0}, {51.3, 1.4}, {52.7, 1.7}, {53.4, 0.3}, {54.2, -0.2}, {55.6, -1.6},
+		{57.0, -2.0}, {57.7, -3.5}, {58.6, -3.0}, {58.6, -5.0}, {57.5, -6.0}, {56.0, -5.6}, {55.0, -4.9},
+		{54.9, -3.2}, {53.4, -3.3}, {53.3, -4.6}, {52.5, -4.2}, {51.7, -5.2}, {51.5, -3.0}, {51.1, -4.5},
+		{50.0, -5.7},
+	},
+	// Ireland
+	{
+		{52.2, -6.0}, {53.5, -6.1}, {54.5, -5.5}, {55.2, -6.2}, {55.2, -8.3}, {54.3, -8.5},
+		{54.2, -10.0}, {53.2, -9.3}, {52.1, -10.3}, {51.5, -9.7}, {51.7, -8.0}, {52.2, -6.0},
+	},
+	// Iceland
+	{
+		{65.5, -24.0}, {66.4, -22.0}, {66.1, -18.0}, {66.3, -14.5}, {65.0, -13.6}, {64.3, -15.0},
+		{63.4, -18.7}, {63.8, -22.7}, {65.5, -24.0},
+	},
+	// Svalbard
+	{
+		{79.5, 11.0}, {80.3, 17.0}, {80.0, 27.0}, {78.0, 22.0}, {76.5, 17.0}, {77.5, 14.0}, {79.5, 11.0},
+	},
+	// Novaya Zemlya
+	{
+		{71.5, 52.0}, {73.5, 55.0}, {76.0, 60.0}, {77.0, 68.0}, {76.0, 67.0}, {74.0, 60.0}, {71.0, 57.0},
+		{71.5, 52.0},
+	},
+	// Corsica
+	{
+		{41.4, 8.6}, {42.6, 8.6}, {43.0, 9.4}, {42.0, 9.5}, {41.4, 9.2}, {41.4, 8.6},
+	},
+	// Sardinia
+	{
+		{39.0, 8.4}, {41.0, 8.2}, {41.2, 9.2}, {40.0, 9.7}, {39.1, 9.5}, {39.0, 8.4},
+	},
+	// Sicily
+	{
+		{37.8, 12.4}, {38.2, 13.4}, {38.3, 15.6}, {36.7, 15.1}, {37.8, 12.4},
+	},
+	// Crete
+	{
+		{35.3, 23.5}, {35.3, 26.3}, {35.0, 26.2}, {34.9, 24.0}, {35.3, 23.5},
+	},
+	// Cyprus
+	{
+		{34.8, 32.3}, {35.4, 33.0}, {35.7, 34.6}, {35.0, 34.0}, {34.8, 32.3},
+	},
+	// Madagascar
+	{
+		{-12.0, 49.3}, {-15.5, 50.4}, {-17.0, 49.7}, {-22.5, 48.0}, {-25.0, 47.0}, {-25.6, 45.2},
+		{-23.5, 43.7}, {-21.5, 43.5}, {-19.5, 44.4}, {-17.0, 44.0}, {-15.8, 46.0}, {-13.5, 48.0},
+		{-12.0, 49.3},
+	},
+	// Sri Lanka
+	{
+		{6.2, 79.8}, {8.5, 79.9}, {9.8, 80.1}, {8.5, 81.4}, {7.3, 81.8}, {5.9, 80.6}, {6.2, 79.8},
+	},
+	// Sakhalin
+	{
+		{46.0, 142.0}, {46.5, 143.5}, {49.3, 143.6}, {54.0, 142.9}, {54.0, 142.2}, {51.0, 142.1},
+		{48.0, 141.8}, {46.0, 142.0},
+	},
+	// Hokkaido
+	{
+		{42.0, 140.0}, {43.3, 140.2}, {45.4, 141.6}, {44.3, 145.3}, {43.4, 145.8}, {42.0, 143.5},
+		{41.8, 141.0}, {42.0, 140.0},
+	},
+	// Honshu
+	{
+		{34.0, 130.9}, {35.5, 132.0}, {35.6, 135.5}, {37.3, 136.7}, {38.5, 139.5}, {40.5, 140.0},
+		{41.4, 141.5}, {39.5, 142.0}, {38.3, 141.0}, {36.0, 140.8}, {35.0, 140.0}, {34.6, 138.5},
+		{34.3, 136.8}, {33.8, 135.2}, {34.3, 133.0}, {34.0, 130.9},
+	},
+	// Shikoku
+	{
+		{33.3, 132.6}, {34.4, 134.0}, {33.8, 134.7}, {32.8, 133.0}, {33.3, 132.6},
+	},
+	// Kyushu
+	{
+		{33.5, 129.8}, {33.6, 131.5}, {32.0, 131.7}, {31.0, 130.7}, {32.5, 130.2}, {33.5, 129.8},
+	},
+	// Taiwan
+	{
+		{23.0, 120.1}, {25.2, 121.0}, {25.0, 122.0}, {23.0, 121.5}, {21.9, 120.8}, {23.0, 120.1},
+	},
+	// Hainan
+	{
+		{19.2, 108.6}, {20.1, 110.3}, {19.6, 111.0}, {18.2, 109.5}, {19.2, 108.6},
+	},
+	// Luzon
+	{
+		{18.5, 120.6}, {18.5, 122.3}, {16.5, 122.0}, {14.2, 121.6}, {13.0, 124.0}, {12.6, 123.8},
+		{13.7, 121.0}, {14.5, 120.5}, {16.3, 119.8}, {18.5, 120.6},
+	},
+	// Mindanao
+	{
+		{7.0, 122.0}, {7.8, 123.7}, {9.0, 125.2}, {9.3, 126.5}, {6.3, 126.3}, {5.6, 125.5}, {6.2, 124.0},
+		{7.0, 122.0},
+	},
+	// Borneo
+	{
+		{1.5, 109.0}, {1.8, 111.0}, {3.2, 113.0}, {5.2, 115.5}, {7.0, 117.0}, {5.3, 119.3}, {4.3, 118.0},
+		{1.1, 117.9}, {0.9, 119.0}, {0.0, 117.5}, {-2.5, 116.5}, {-3.9, 116.0}, {-3.7, 114.5},
+		{-3.3, 111.7}, {-2.9, 110.2}, {-0.5, 109.5}, {1.5, 109.0},
+	},
+	// Sumatra
+	{
+		{5.5, 95.3}, {5.2, 97.5}, {2.2, 100.4}, {-1.0, 103.7}, {-3.2, 106.0}, {-5.8, 105.9},
+		{-5.9, 104.5}, {-4.0, 102.3}, {-2.2, 100.9}, {0.2, 99.0}, {1.8, 98.6}, {3.6, 96.9}, {5.5, 95.3},
+	},
+	// Java
+	{
+		{-6.8, 105.2}, {-6.0, 106.5}, {-6.7, 108.6}, {-6.4, 110.9}, {-6.9, 112.7}, {-7.8, 114.5},
+		{-8.7, 114.4}, {-8.2, 111.0}, {-7.8, 108.0}, {-7.4, 106.4}, {-6.8, 105.2},
+	},
+	// Sulawesi
+	{
+		{-5.5, 119.4}, {-3.0, 118.8}, {0.1, 119.8}, {1.3, 120.7}, {1.6, 124.9}, {0.4, 124.3},
+		{0.5, 121.0}, {-0.7, 120.2}, {-1.0, 121.5}, {-0.9, 123.4}, {-1.8, 121.5}, {-4.5, 122.8},
+		{-4.5, 121.6}, {-2.7, 120.4}, {-5.6, 120.5}, {-5.5, 119.4},
+	},
+	// New Guinea
+	{
+		{-1.2, 131.0}, {-0.4, 132.4}, {-3.3, 135.0}, {-1.6, 138.0}, {-2.6, 141.0}, {-3.8, 144.5},
+		{-5.5, 145.8}, {-6.1, 147.6}, {-8.0, 147.8}, {-10.3, 150.0}, {-10.0, 148.0}, {-8.9, 146.5},
+		{-7.6, 144.4}, {-9.0, 143.3}, {-9.2, 141.2}, {-8.0, 139.2}, {-8.4, 137.9}, {-6.8, 138.5},
+		{-5.0, 137.5}, {-4.4, 135.2}, {-4.0, 133.4}, {-2.8, 132.3}, {-1.2, 131.0},
+	},
+	// Australia
+	{
+		{-21.8, 114.0}, {-20.6, 116.7}, {-20.0, 120.0}, {-18.5, 121.6}, {-16.8, 123.6}, {-14.5, 125.0},
+		{-13.8, 127.0}, {-15.0, 128.3}, {-14.8, 129.9}, {-12.6, 130.3}, {-11.4, 132.6}, {-12.0, 136.0},
+		{-13.5, 136.5}, {-15.0, 135.9}, {-17.4, 139.4}, {-17.3, 140.9}, {-12.7, 141.6}, {-10.7, 142.5},
+		{-14.0, 143.5}, {-15.0, 145.3}, {-18.9, 146.2}, {-21.0, 149.0}, {-22.5, 150.8}, {-25.5, 153.2},
+		{-28.6, 153.6}, {-32.4, 152.5}, {-34.8, 150.8}, {-37.5, 150.0}, {-37.9, 147.6}, {-39.1, 146.3},
+		{-38.2, 144.6}, {-38.0, 140.6}, {-36.5, 139.5}, {-35.5, 138.0}, {-33.0, 137.7}, {-34.8, 135.9},
+		{-32.7, 134.2}, {-31.5, 131.0}, {-32.2, 128.0}, {-33.6, 124.0}, {-34.0, 119.9}, {-35.0, 118.0},
+		{-34.3, 115.0}, {-32.0, 115.7}, {-29.5, 115.0}, {-26.0, 113.5}, {-24.0, 113.6}, {-21.8, 114.0},
+	},
+	// Tasmania
+	{
+		{-40.7, 144.7}, {-40.9, 148.3}, {-43.2, 148.0}, {-43.6, 146.8}, {-42.2, 145.2}, {-40.7, 144.7},
+	},
+	// New Zealand, North Island
+	{
+		{-34.4, 172.7}, {-35.9, 174.5}, {-37.2, 175.9}, {-37.7, 178.5}, {-39.3, 177.0}, {-41.3, 176.0},
+		{-41.3, 174.8}, {-39.8, 174.6}, {-39.2, 173.8}, {-37.0, 174.6}, {-34.4, 172.7},
+	},
+	// New Zealand, South Island
+	{
+		{-40.5, 172.7}, {-41.7, 174.3}, {-43.4, 173.2}, {-44.4, 171.3}, {-46.6, 169.0}, {-46.0, 166.5},
+		{-45.0, 166.9}, {-44.0, 168.3}, {-41.8, 171.3}, {-40.5, 172.7},
+	},
+	// Antarctica
+	{
+		{-78.0, -180.0}, {-78.5, -160.0}, {-77.0, -150.0}, {-74.5, -130.0}, {-73.0, -100.0},
+		{-73.0, -80.0}, {-72.0, -68.0}, {-64.0, -62.0}, {-63.5, -57.0}, {-68.0, -60.0}, {-72.0, -62.0},
+		{-75.0, -60.0}, {-78.0, -45.0}, {-77.5, -35.0}, {-73.5, -20.0}, {-70.0, 0.0}, {-70.0, 20.0},
+		{-69.0, 40.0}, {-66.0, 55.0}, {-68.0, 70.0}, {-69.5, 75.0}, {-66.5, 88.0}, {-66.0, 100.0},
+		{-66.5, 115.0}, {-66.0, 135.0}, {-68.5, 150.0}, {-70.5, 165.0}, {-72.0, 170.0}, {-77.5, 166.0},
+		{-78.0, 180.0},
+	},
+}
@@ -0,0 +1,452 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package mapcmd implements a command to draw
+// a quick-look map
+// of the records of a GBIF occurrence table.
+package mapcmd
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+)
+
+var Command = &command.Command{
+	Usage: `map [--width <value>] [--panels] [--background <image>]
+	[-i|--input <file>] -o|--output <file>`,
+	Short: "draw a quick-look map of the records",
+	Long: `
+Command map reads a GBIF occurrence table from the standard input and draws
+the records in a PNG image, using an equirectangular projection. Each species
+(as defined by the speciesKey column, or the species column if there is no
+speciesKey) is drawn with a different color.
+
+By default the map is drawn over a coarse outline of the world coastlines
+(simplified to about a thousand points, so small islands are not shown), and a
+simple graticule (with lines every 30 degrees). Use the flag --background to
+define an image file (PNG or JPEG) to be used as the background of the map
+instead, for example a detailed world map. The image must be in an
+equirectangular projection covering the whole world.
+
+By default the image is 1440 pixels wide. Use the flag --width to set a
+different width. The height of the image is always half the width.
+
+If the flag --panels is defined, each species will be drawn in its own panel.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+The flag --output, or -o, is required and defines the name of the output
+image.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var panelsFlag bool
+var widthFlag int
+var bgFile string
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().BoolVar(&panelsFlag, "panels", false, "")
+	c.Flags().IntVar(&widthFlag, "width", 1440, "")
+	c.Flags().StringVar(&bgFile, "background", "", "")
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if output == "" {
+		return c.UsageError("expecting output file, flag --output")
+	}
+	if widthFlag < 360 {
+		return c.UsageError(fmt.Sprintf("invalid image width %d", widthFlag))
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	var bg image.Image
+	if bgFile != "" {
+		bg, err = readBackground()
+		if err != nil {
+			return err
+		}
+	}
+
+	sp, err := readTable(in)
+	if err != nil {
+		return err
+	}
+
+	img := drawMap(sp, bg)
+
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		e := f.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	if err := png.Encode(f, img); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+func readBackground() (image.Image, error) {
+	f, err := os.Open(bgFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", bgFile, err)
+	}
+	return img, nil
+}
+
+type point struct {
+	lat, lon float64
+}
+
+// A species stores the pixels
+// of the records of a species,
+// so the memory used is bounded
+// by the size of the image,
+// rather than by the number of records.
+type species struct {
+	key    string
+	pixels map[image.Point]bool
+}
+
+// points returns the points
+// at the center of each pixel of a species.
+func (sp *species) points() []point {
+	w, h := float64(widthFlag-1), float64(widthFlag/2-1)
+	pts := make([]point, 0, len(sp.pixels))
+	for px := range sp.pixels {
+		pts = append(pts, point{
+			lat: 90 - (float64(px.Y)+0.5)/h*180,
+			lon: (float64(px.X)+0.5)/w*360 - 180,
+		})
+	}
+	return pts
+}
+
+func readTable(r io.Reader) ([]*species, error) {
+	tab := tabfile.NewReader(r, "decimalLatitude", "decimalLongitude", "speciesKey", "species")
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+
+	latCol := -1
+	lonCol := -1
+	keyCol := -1
+	spCol := -1
+	for i, h := range header {
+		h = strings.ToLower(h)
+		if h == "decimallatitude" {
+			latCol = i
+		}
+		if h == "decimallongitude" {
+			lonCol = i
+		}
+		if h == "specieskey" {
+			keyCol = i
+		}
+		if h == "species" {
+			spCol = i
+		}
+	}
+	if latCol < 0 || lonCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q or %q fields", input, "decimalLatitude", "decimalLongitude")
+	}
+	if keyCol < 0 {
+		keyCol = spCol
+	}
+
+	// the pixels are taken from a map
+	// of the full width
+	full := image.Rect(0, 0, widthFlag, widthFlag/2)
+
+	var ls []*species
+	spp := make(map[string]*species)
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		if row[latCol] == "" || row[lonCol] == "" {
+			continue
+		}
+		lat, err := strconv.ParseFloat(row[latCol], 64)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: field %q: %v", input, ln, "decimalLatitude", err)
+		}
+		if lat < -90 || lat > 90 {
+			return nil, fmt.Errorf("table %q: row %d: field %q: invalid latitude: %.6f", input, ln, "decimalLatitude", lat)
+		}
+		lon, err := strconv.ParseFloat(row[lonCol], 64)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: field %q: %v", input, ln, "decimalLongitude", err)
+		}
+		if lon < -180 || lon > 180 {
+			return nil, fmt.Errorf("table %q: row %d: field %q: invalid longitude: %.6f", input, ln, "decimalLongitude", lon)
+		}
+
+		var key string
+		if keyCol >= 0 {
+			key = row[keyCol]
+		}
+		sp, ok := spp[key]
+		if !ok {
+			if err := memory.Add(int64(len(key)) + 64); err != nil {
+				return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			}
+			sp = &species{key: key, pixels: make(map[image.Point]bool)}
+			spp[key] = sp
+			ls = append(ls, sp)
+		}
+		x, y := project(full, lat, lon)
+		px := image.Pt(x, y)
+		if sp.pixels[px] {
+			continue
+		}
+		if err := memory.Add(32); err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		sp.pixels[px] = true
+	}
+	return ls, nil
+}
+
+var (
+	graticuleColor = color.RGBA{200, 200, 200, 255}
+	equatorColor   = color.RGBA{150, 150, 150, 255}
+	coastColor     = color.RGBA{90, 90, 90, 255}
+	borderColor    = color.RGBA{0, 0, 0, 255}
+)
+
+func drawMap(spp []*species, bg image.Image) image.Image {
+	cols, rows := 1, 1
+	if panelsFlag && len(spp) > 1 {
+		cols = int(math.Ceil(math.Sqrt(float64(len(spp)))))
+		rows = (len(spp) + cols - 1) / cols
+	}
+	pw := widthFlag / cols
+	ph := pw / 2
+
+	img := image.NewRGBA(image.Rect(0, 0, pw*cols, ph*rows))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	for i := 0; i < cols*rows; i++ {
+		r := image.Rect(0, 0, pw, ph).Add(image.Pt((i%cols)*pw, (i/cols)*ph))
+		if !panelsFlag || len(spp) < 2 {
+			r = img.Bounds()
+		}
+		drawBackground(img, r, bg)
+
+		if !panelsFlag || len(spp) < 2 {
+			for j, sp := range spp {
+				drawPoints(img, r, sp.points(), palette(j, len(spp)))
+			}
+			break
+		}
+		if i < len(spp) {
+			drawPoints(img, r, spp[i].points(), palette(i, len(spp)))
+		}
+	}
+	return img
+}
+
+func drawBackground(img *image.RGBA, r image.Rectangle, bg image.Image) {
+	if bg != nil {
+		b := bg.Bounds()
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			sy := b.Min.Y + (y-r.Min.Y)*b.Dy()/r.Dy()
+			for x := r.Min.X; x < r.Max.X; x++ {
+				sx := b.Min.X + (x-r.Min.X)*b.Dx()/r.Dx()
+				img.Set(x, y, bg.At(sx, sy))
+			}
+		}
+	} else {
+		for lon := -150.0; lon < 180; lon += 30 {
+			x, _ := project(r, 0, lon)
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				img.Set(x, y, graticuleColor)
+			}
+		}
+		for lat := -60.0; lat < 90; lat += 30 {
+			_, y := project(r, lat, 0)
+			c := graticuleColor
+			if lat == 0 {
+				c = equatorColor
+			}
+			for x := r.Min.X; x < r.Max.X; x++ {
+				img.Set(x, y, c)
+			}
+		}
+		for _, ln := range coastline {
+			for i := 1; i < len(ln); i++ {
+				drawLine(img, r, ln[i-1], ln[i], coastColor)
+			}
+		}
+	}
+
+	// panel border
+	for x := r.Min.X; x < r.Max.X; x++ {
+		img.Set(x, r.Min.Y, borderColor)
+		img.Set(x, r.Max.Y-1, borderColor)
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		img.Set(r.Min.X, y, borderColor)
+		img.Set(r.Max.X-1, y, borderColor)
+	}
+}
+
+// DrawLine draws a line between two geographic points
+// using the Bresenham's algorithm.
+func drawLine(img *image.RGBA, r image.Rectangle, p, q point, c color.Color) {
+	x0, y0 := project(r, p.lat, p.lon)
+	x1, y1 := project(r, q.lat, q.lon)
+
+	dx := x1 - x0
+	if dx < 0 {
+		dx = -dx
+	}
+	dy := y1 - y0
+	if dy > 0 {
+		dy = -dy
+	}
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+
+	e := dx + dy
+	for {
+		img.Set(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func drawPoints(img *image.RGBA, r image.Rectangle, pts []point, c color.Color) {
+	for _, p := range pts {
+		x, y := project(r, p.lat, p.lon)
+		for dy := -2; dy <= 2; dy++ {
+			for dx := -2; dx <= 2; dx++ {
+				if dx*dx+dy*dy > 5 {
+					continue
+				}
+				pt := image.Pt(x+dx, y+dy)
+				if !pt.In(r) {
+					continue
+				}
+				img.Set(pt.X, pt.Y, c)
+			}
+		}
+	}
+}
+
+// Project returns the pixel of a geographic point
+// in an equirectangular projection.
+func project(r image.Rectangle, lat, lon float64) (x, y int) {
+	x = r.Min.X + int((lon+180)/360*float64(r.Dx()-1))
+	y = r.Min.Y + int((90-lat)/180*float64(r.Dy()-1))
+	return x, y
+}
+
+// Palette returns a color
+// for the i-th element of a set of n elements,
+// with colors evenly spaced around the color wheel.
+func palette(i, n int) color.Color {
+	h := float64(i) / float64(n) * 6
+	s, v := 0.9, 0.8
+	if i%2 == 1 {
+		v = 0.6
+	}
+
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h, 2)-1))
+	var r, g, b float64
+	switch int(h) {
+	case 0:
+		r, g = c, x
+	case 1:
+		r, g = x, c
+	case 2:
+		g, b = c, x
+	case 3:
+		g, b = x, c
+	case 4:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+	m := v - c
+	return color.RGBA{
+		R: uint8((r + m) * 255),
+		G: uint8((g + m) * 255),
+		B: uint8((b + m) * 255),
+		A: 255,
+	}
+}
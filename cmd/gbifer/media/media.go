@@ -0,0 +1,405 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package media implements a command to list
+// and download the media files
+// of the records of a GBIF occurrence table.
+package media
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `media [--multimedia <file>] [--download <directory>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "list and download occurrence media",
+	Long: `
+Command media reads a GBIF occurrence table from the standard input and lists
+the media files (for example, specimen images) associated with each record.
+The list is printed as a TSV file with the following columns:
+
+	- gbifID: the GBIF ID of the record.
+	- catalogNumber: the catalog number of the record (if defined in the
+	  occurrence table).
+	- type: the type of the media (for example, "StillImage").
+	- format: the MIME type of the media file.
+	- identifier: the URL of the media file.
+	- license: the license of the media file.
+	- rightsHolder: the owner of the rights of the media file.
+
+By default, the media files are retrieved from the GBIF API, using the
+"gbifID" column. If the flag --multimedia is defined, the indicated file will
+be read as the multimedia extension of a GBIF download (the multimedia.txt
+file of a Darwin Core Archive download), and no internet connection is
+required for the listing.
+
+If the flag --download is defined, the images (media of type "StillImage")
+will be downloaded into the indicated directory. Each file will be named
+using the GBIF ID of the record, its catalog number (if any), and the number
+of the image in the record, for example:
+
+	1234567890-MACN-Ma-123-1.jpg
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+var mmFile string
+var dlDir string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().StringVar(&mmFile, "multimedia", "", "")
+	c.Flags().StringVar(&dlDir, "download", "", "")
+}
+
+// A record is an occurrence record.
+type record struct {
+	id      string
+	catalog string
+	media   []*gbif.Media
+}
+
+func run(c *command.Command, args []string) (err error) {
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	recs, err := readRecords(in)
+	if err != nil {
+		return err
+	}
+
+	if mmFile != "" {
+		if err := readMultimedia(recs); err != nil {
+			return err
+		}
+	} else {
+		gbif.Open()
+		for _, r := range recs {
+			ls, err := gbif.OccurrenceMedia(r.id)
+			if errors.Is(err, gbif.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			r.media = ls
+		}
+	}
+
+	if dlDir != "" {
+		if err := os.MkdirAll(dlDir, 0755); err != nil {
+			return err
+		}
+		if err := download(recs); err != nil {
+			return err
+		}
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := writeMedia(out, recs); err != nil {
+		return err
+	}
+	return nil
+}
+
+func readRecords(r io.Reader) ([]*record, error) {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+	idCol := -1
+	catCol := -1
+	for i, h := range header {
+		h = strings.ToLower(h)
+		if h == "gbifid" {
+			idCol = i
+		}
+		if h == "catalognumber" {
+			catCol = i
+		}
+	}
+	if idCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q field", input, "gbifID")
+	}
+
+	var recs []*record
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		id := strings.TrimSpace(row[idCol])
+		if id == "" {
+			continue
+		}
+		if err := memory.Add(int64(len(id) + 64)); err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		r := &record{id: id}
+		if catCol >= 0 {
+			r.catalog = strings.TrimSpace(row[catCol])
+		}
+		recs = append(recs, r)
+	}
+	return recs, nil
+}
+
+func readMultimedia(recs []*record) error {
+	ids := make(map[string]*record, len(recs))
+	for _, r := range recs {
+		ids[r.id] = r
+	}
+
+	f, err := os.Open(mmFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tab := tabfile.NewReader(f)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return fmt.Errorf("when reading %q header: %v", mmFile, err)
+	}
+	fields := make(map[string]int, len(header))
+	for i, h := range header {
+		fields[strings.ToLower(h)] = i
+	}
+	if _, ok := fields["gbifid"]; !ok {
+		return fmt.Errorf("input data %q without %q field", mmFile, "gbifID")
+	}
+	if _, ok := fields["identifier"]; !ok {
+		return fmt.Errorf("input data %q without %q field", mmFile, "identifier")
+	}
+	field := func(row []string, name string) string {
+		i, ok := fields[name]
+		if !ok {
+			return ""
+		}
+		return row[i]
+	}
+
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return fmt.Errorf("table %q: row %d: %v", mmFile, ln, err)
+		}
+
+		r, ok := ids[strings.TrimSpace(field(row, "gbifid"))]
+		if !ok {
+			continue
+		}
+		r.media = append(r.media, &gbif.Media{
+			Type:         field(row, "type"),
+			Format:       field(row, "format"),
+			Identifier:   field(row, "identifier"),
+			References:   field(row, "references"),
+			Title:        field(row, "title"),
+			License:      field(row, "license"),
+			RightsHolder: field(row, "rightsholder"),
+		})
+	}
+	return nil
+}
+
+func writeMedia(w io.Writer, recs []*record) error {
+	tab := tsv.NewWriter(w)
+	tab.Comma = '\t'
+	tab.UseCRLF = true
+
+	header := []string{
+		"gbifID",
+		"catalogNumber",
+		"type",
+		"format",
+		"identifier",
+		"license",
+		"rightsHolder",
+	}
+	if err := tab.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	for _, r := range recs {
+		for _, m := range r.media {
+			row := []string{
+				r.id,
+				r.catalog,
+				m.Type,
+				m.Format,
+				m.Identifier,
+				m.License,
+				m.RightsHolder,
+			}
+			if err := tab.Write(row); err != nil {
+				return fmt.Errorf("when writing on %q: %v", output, err)
+			}
+			logger.WriteRows(1)
+		}
+	}
+
+	tab.Flush()
+	if err := tab.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+// download downloads the images of the records.
+// Download errors are reported in stderr,
+// and the image is skipped.
+func download(recs []*record) error {
+	for _, r := range recs {
+		n := 0
+		for _, m := range r.media {
+			if !strings.EqualFold(m.Type, "StillImage") || m.Identifier == "" {
+				continue
+			}
+			n++
+
+			name := r.id
+			if r.catalog != "" {
+				name += "-" + fileName(r.catalog)
+			}
+			name += "-" + strconv.Itoa(n) + extension(m)
+			if err := getFile(filepath.Join(dlDir, name), m.Identifier); err != nil {
+				logger.Printf("record %s: %v", r.id, err)
+			}
+
+			// we do not want to overload the servers.
+			time.Sleep(gbif.Wait)
+		}
+	}
+	return nil
+}
+
+func getFile(name, url string) (err error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("when downloading %q: %s", url, resp.Status)
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		e := f.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		return fmt.Errorf("when downloading %q: %v", url, err)
+	}
+	return nil
+}
+
+// fileName returns a string
+// that can be used as part of a file name.
+func fileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r == '-', r == '_', r == '.':
+			return r
+		}
+		return '-'
+	}, s)
+}
+
+// extension returns the file extension
+// of a media file.
+func extension(m *gbif.Media) string {
+	if m.Format != "" {
+		ext, _ := mime.ExtensionsByType(m.Format)
+		if slices.Contains(ext, ".jpg") {
+			return ".jpg"
+		}
+		if len(ext) > 0 {
+			return ext[0]
+		}
+	}
+	if ext := path.Ext(m.Identifier); len(ext) > 1 && len(ext) <= 5 {
+		return strings.ToLower(ext)
+	}
+	return ".jpg"
+}
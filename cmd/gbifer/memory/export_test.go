@@ -0,0 +1,12 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package memory
+
+// Reset removes the limit
+// and the size of the kept data.
+func Reset() {
+	limit.Store(0)
+	used.Store(0)
+}
@@ -0,0 +1,132 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package memory implements a guard
+// for the commands that keep data in memory.
+//
+// Most commands process a table row by row,
+// and use a constant amount of memory.
+// Commands that must keep data in memory
+// (for example, sort)
+// report the approximate size of the kept data,
+// so they fail as soon as they exceed the limit,
+// instead of exhausting the memory of the system.
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrLimit is the error returned
+// when the memory limit is exceeded.
+var ErrLimit = errors.New("memory limit exceeded")
+
+var limit, used atomic.Int64
+
+// SetLimit sets the maximum size,
+// in bytes,
+// of the data kept in memory.
+// If n is 0,
+// there is no limit.
+//
+// The limit is not used as the memory limit
+// of the Go runtime,
+// as the heap is always larger than the accounted data,
+// and the garbage collector would run continuously
+// before the limit is reached.
+func SetLimit(n int64) {
+	limit.Store(n)
+}
+
+// Check returns an error
+// if keeping n additional bytes in memory
+// would exceed the limit.
+func Check(n int64) error {
+	l := limit.Load()
+	if l == 0 {
+		return nil
+	}
+	if used.Load()+n > l {
+		return fmt.Errorf("%w: the command keeps the data in memory and requires more than %s (as set by --max-memory)", ErrLimit, FormatSize(l))
+	}
+	return nil
+}
+
+// Add adds n bytes
+// to the size of the data kept in memory.
+// It returns an error
+// if the limit is exceeded.
+func Add(n int64) error {
+	if limit.Load() == 0 {
+		return nil
+	}
+	if err := Check(n); err != nil {
+		return err
+	}
+	used.Add(n)
+	return nil
+}
+
+// Row returns the approximate size
+// of a row kept in memory.
+func Row(row []string) int64 {
+	// slice and string headers
+	n := int64(24 + 16*len(row))
+	for _, f := range row {
+		n += int64(len(f))
+	}
+	return n
+}
+
+// ParseSize parses a size in bytes,
+// with an optional suffix
+// K, M, G, or T
+// (with an optional B, for example "512MB").
+// Suffixes use powers of 1024.
+func ParseSize(s string) (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	v = strings.TrimSuffix(v, "B")
+	mult := 1.0
+	if len(v) > 0 {
+		switch v[len(v)-1] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		case 'T':
+			mult = 1 << 40
+		}
+		if mult > 1 {
+			v = v[:len(v)-1]
+		}
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || f < 0 || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(f * mult), nil
+}
+
+// FormatSize returns a size in bytes
+// in a human readable form.
+func FormatSize(n int64) string {
+	units := []string{"", "K", "M", "G", "T"}
+	v := float64(n)
+	i := 0
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	if i == 0 {
+		return strconv.FormatInt(n, 10) + "B"
+	}
+	return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64) + units[i]
+}
@@ -0,0 +1,171 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package memory_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+// synthetic returns a reader
+// with a table of n rows,
+// in which each row has a distinct key.
+// The reader must be closed
+// to stop the generation of rows.
+func synthetic(n int) io.ReadCloser {
+	r, w := io.Pipe()
+	go func() {
+		tab := tsv.NewWriter(w)
+		tab.Write([]string{"gbifID", "species", "locality"})
+		for i := 0; i < n; i++ {
+			tab.Write([]string{
+				fmt.Sprintf("%d", i+1),
+				fmt.Sprintf("Species %d", i%1000),
+				fmt.Sprintf("Locality number %d of a large table", i),
+			})
+		}
+		tab.Flush()
+		w.CloseWithError(tab.Error())
+	}()
+	return r
+}
+
+// keep reads a table
+// and keeps each row in memory,
+// as a buffering command does.
+// It returns the number of kept rows.
+func keep(r io.Reader) (int, error) {
+	tab := tsv.NewReader(r)
+	if _, err := tab.Read(); err != nil {
+		return 0, err
+	}
+	var rows [][]string
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			return len(rows), nil
+		}
+		if err != nil {
+			return len(rows), err
+		}
+		if err := memory.Add(memory.Row(row)); err != nil {
+			return len(rows), err
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	tests := map[string]struct {
+		limit int64
+		rows  int
+		fail  bool
+	}{
+		"without limit": {
+			rows: 100_000,
+		},
+		"under the limit": {
+			limit: 64 << 20,
+			rows:  100_000,
+		},
+		"over the limit": {
+			limit: 1 << 20,
+			rows:  1_000_000,
+			fail:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			memory.Reset()
+			defer memory.Reset()
+			memory.SetLimit(test.limit)
+
+			r := synthetic(test.rows)
+			defer r.Close()
+
+			n, err := keep(r)
+			if !test.fail {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if n != test.rows {
+					t.Errorf("kept rows: got %d, want %d", n, test.rows)
+				}
+				return
+			}
+
+			if !errors.Is(err, memory.ErrLimit) {
+				t.Fatalf("error: got %v, want %v", err, memory.ErrLimit)
+			}
+			// the guard must fail fast,
+			// as soon as the limit is reached
+			if n >= test.rows/10 {
+				t.Errorf("kept rows: got %d, want less than %d", n, test.rows/10)
+			}
+		})
+	}
+}
+
+func TestAddRelease(t *testing.T) {
+	memory.Reset()
+	defer memory.Reset()
+	memory.SetLimit(1000)
+
+	// a ring buffer,
+	// as used by tail,
+	// releases the replaced rows
+	row := []string{"a", "b", "c"}
+	for i := 0; i < 100_000; i++ {
+		if err := memory.Add(memory.Row(row)); err != nil {
+			t.Fatalf("row %d: unexpected error: %v", i, err)
+		}
+		if err := memory.Add(-memory.Row(row)); err != nil {
+			t.Fatalf("row %d: unexpected error: %v", i, err)
+		}
+	}
+
+	if err := memory.Add(2000); !errors.Is(err, memory.ErrLimit) {
+		t.Errorf("error: got %v, want %v", err, memory.ErrLimit)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]struct {
+		size string
+		want int64
+		err  bool
+	}{
+		"bytes":     {size: "512", want: 512},
+		"kilobytes": {size: "2K", want: 2 << 10},
+		"megabytes": {size: "512MB", want: 512 << 20},
+		"gigabytes": {size: "1.5g", want: 3 << 29},
+		"negative":  {size: "-1M", err: true},
+		"invalid":   {size: "lots", err: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			n, err := memory.ParseSize(test.size)
+			if test.err {
+				if err == nil {
+					t.Errorf("expecting error for %q", test.size)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if n != test.want {
+				t.Errorf("size %q: got %d, want %d", test.size, n, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package parallel implements the concurrent processing
+// of the rows of an occurrence table.
+package parallel
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+// BlockSize is the number of lines
+// processed in each block.
+var BlockSize = 1024
+
+// A Func process a row of a table.
+// The line is the line number of the row in the input.
+// It returns the row that will be written,
+// or nil if the row must be dropped.
+//
+// A Func can be called concurrently.
+type Func func(row []string, line int) ([]string, error)
+
+// Rows reads the rows of a table,
+// process each row with fn,
+// and writes the resulting rows,
+// preserving the order of the input rows.
+// The rows are counted
+// as rows of the main input and output
+// of the command
+// (see logger.ReadRows).
+// The header of the table must be already read.
+//
+// Input and output are the names
+// of the input and output,
+// used in error messages.
+//
+// Jobs is the number of concurrent jobs.
+// If jobs is 0,
+// it will use the number of available CPUs.
+// If jobs is 1,
+// rows are processed sequentially.
+func Rows(tab *tsv.Reader, input string, out *tsv.Writer, output string, jobs int, fn Func) error {
+	if jobs == 0 {
+		jobs = runtime.NumCPU()
+	}
+	if jobs < 2 {
+		return sequential(tab, input, out, output, fn)
+	}
+
+	type result struct {
+		rows [][]string
+		err  error
+	}
+	type block struct {
+		lines [][]byte
+		first int
+		res   chan result
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	work := make(chan block, jobs)
+	order := make(chan chan result, 2*jobs)
+
+	fields := tab.FieldsPerRecord()
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range work {
+				var res result
+				for i, ln := range b.lines {
+					row := tsv.ParseLine(ln)
+					if row == nil {
+						continue
+					}
+					line := b.first + i
+					if len(row) != fields {
+						res.err = fmt.Errorf("table %q: row %d: %w: got %d fields, want %d", input, line, tsv.ErrFieldCount, len(row), fields)
+						break
+					}
+					logger.ReadRows(1)
+					row, err := fn(row, line)
+					if err != nil {
+						res.err = err
+						break
+					}
+					if row != nil {
+						res.rows = append(res.rows, row)
+					}
+				}
+				b.res <- res
+			}
+		}()
+	}
+
+	// read blocks
+	go func() {
+		defer close(order)
+		defer close(work)
+		for {
+			lines, first, err := tab.ReadLines(BlockSize)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			res := make(chan result, 1)
+			if err != nil {
+				res <- result{err: fmt.Errorf("table %q: row %d: %v", input, first, err)}
+				select {
+				case order <- res:
+				case <-done:
+				}
+				return
+			}
+
+			b := block{lines: lines, first: first, res: res}
+			select {
+			case order <- res:
+			case <-done:
+				return
+			}
+			select {
+			case work <- b:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var err error
+	for res := range order {
+		r := <-res
+		if r.err != nil {
+			err = r.err
+			break
+		}
+		for _, row := range r.rows {
+			if err = out.Write(row); err != nil {
+				err = fmt.Errorf("when writing on %q: %v", output, err)
+				break
+			}
+			logger.WriteRows(1)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err != nil {
+		// the reader and the workers
+		// will finish when done is closed.
+		return err
+	}
+	wg.Wait()
+	return nil
+}
+
+func sequential(tab *tsv.Reader, input string, out *tsv.Writer, output string, fn Func) error {
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		row, err = fn(row, ln)
+		if err != nil {
+			return err
+		}
+		if row == nil {
+			continue
+		}
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+}
+
+// Each calls fn for each index from 0 to n-1,
+// using the indicated number of concurrent jobs.
+// If jobs is 0,
+// it will use the number of available CPUs.
+// If fn returns an error,
+// no more indexes will be processed,
+// and the first error found will be returned.
+//
+// Fn can be called concurrently.
+func Each(n, jobs int, fn func(i int) error) error {
+	if jobs == 0 {
+		jobs = runtime.NumCPU()
+	}
+	if jobs > n {
+		jobs = n
+	}
+	if jobs < 2 {
+		for i := 0; i < n; i++ {
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var mu sync.Mutex
+	var err error
+	next := 0
+	var wg sync.WaitGroup
+	for j := 0; j < jobs; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				if err != nil || next >= n {
+					mu.Unlock()
+					return
+				}
+				i := next
+				next++
+				mu.Unlock()
+
+				if e := fn(i); e != nil {
+					mu.Lock()
+					if err == nil {
+						err = e
+					}
+					mu.Unlock()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return err
+}
@@ -0,0 +1,297 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package pivot implements a command to build
+// a species by site matrix
+// from a GBIF occurrence table.
+package pivot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `pivot [--site <column> | --grid <value>] [--abundance]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "build a species by site matrix",
+	Long: `
+Command pivot reads a GBIF occurrence table from the standard input and builds
+a matrix of sites (as rows) by species (as columns), the usual input of
+community ecology packages.
+
+The species of each record is taken from the "species" column. Records
+without species are ignored.
+
+By default, the sites are the countries, as defined in the "countryCode"
+column. Use the flag --site to use a different column as the site (for
+example, "locality" or a column produced by the pixel or geohash commands).
+If the flag --grid is defined, the sites will be the cells of a grid of the
+indicated size (in degrees), using the "decimalLatitude" and
+"decimalLongitude" columns. The site of a grid cell is identified by the
+coordinates of the center of the cell, for example "-34.5_-58.5". Records
+without a site are ignored.
+
+By default, the matrix is a presence-absence matrix (1 for presence, and 0 for
+absence). If the flag --abundance is defined, the matrix will contain the
+number of records of each species in each site.
+
+The first column of the output matrix is "site", followed by a column for
+each species, sorted alphabetically.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+var siteCol string
+var grid float64
+var abundance bool
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().StringVar(&siteCol, "site", "", "")
+	c.Flags().Float64Var(&grid, "grid", 0, "")
+	c.Flags().BoolVar(&abundance, "abundance", false, "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if grid < 0 || grid > 90 {
+		return c.UsageError(fmt.Sprintf("invalid grid size: %.6f", grid))
+	}
+	if grid > 0 && siteCol != "" {
+		return c.UsageError("flags --site and --grid are incompatible")
+	}
+	if grid == 0 && siteCol == "" {
+		siteCol = "countryCode"
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	m, err := readTable(in)
+	if err != nil {
+		return err
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := m.write(out); err != nil {
+		return err
+	}
+	return nil
+}
+
+// A matrix is a site by species matrix.
+type matrix struct {
+	species map[string]bool
+	sites   map[string]map[string]int
+}
+
+func readTable(r io.Reader) (*matrix, error) {
+	tab := tabfile.NewReader(r, "species", "decimalLatitude", "decimalLongitude", siteCol)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+
+	spCol := -1
+	sCol := -1
+	latCol := -1
+	lonCol := -1
+	for i, h := range header {
+		h = strings.ToLower(h)
+		switch h {
+		case "species":
+			spCol = i
+		case "decimallatitude":
+			latCol = i
+		case "decimallongitude":
+			lonCol = i
+		}
+		if siteCol != "" && h == strings.ToLower(siteCol) {
+			sCol = i
+		}
+	}
+	if spCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q field", input, "species")
+	}
+	if siteCol != "" && sCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q field", input, siteCol)
+	}
+	if grid > 0 && (latCol < 0 || lonCol < 0) {
+		return nil, fmt.Errorf("input data %q without %q or %q fields", input, "decimalLatitude", "decimalLongitude")
+	}
+
+	m := &matrix{
+		species: make(map[string]bool),
+		sites:   make(map[string]map[string]int),
+	}
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		sp := strings.Join(strings.Fields(row[spCol]), " ")
+		if sp == "" {
+			continue
+		}
+
+		var site string
+		if grid > 0 {
+			site, err = cell(row[latCol], row[lonCol])
+			if err != nil {
+				return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			}
+		} else {
+			site = strings.TrimSpace(row[sCol])
+		}
+		if site == "" {
+			continue
+		}
+
+		s, ok := m.sites[site]
+		if !ok {
+			if err := memory.Add(int64(len(site)) + 64); err != nil {
+				return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			}
+			s = make(map[string]int)
+			m.sites[site] = s
+		}
+		if _, ok := s[sp]; !ok {
+			if err := memory.Add(int64(len(sp)) + 24); err != nil {
+				return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			}
+		}
+		s[sp]++
+		m.species[sp] = true
+	}
+	return m, nil
+}
+
+// cell returns the ID of the grid cell
+// of a coordinate pair.
+func cell(latV, lonV string) (string, error) {
+	if latV == "" || lonV == "" {
+		return "", nil
+	}
+	lat, err := strconv.ParseFloat(latV, 64)
+	if err != nil {
+		return "", fmt.Errorf("field %q: %v", "decimalLatitude", err)
+	}
+	if lat < -90 || lat > 90 {
+		return "", fmt.Errorf("field %q: invalid latitude: %.6f", "decimalLatitude", lat)
+	}
+	lon, err := strconv.ParseFloat(lonV, 64)
+	if err != nil {
+		return "", fmt.Errorf("field %q: %v", "decimalLongitude", err)
+	}
+	if lon < -180 || lon > 180 {
+		return "", fmt.Errorf("field %q: invalid longitude: %.6f", "decimalLongitude", lon)
+	}
+
+	lat = math.Min(math.Floor(lat/grid)*grid+grid/2, 90)
+	lon = math.Min(math.Floor(lon/grid)*grid+grid/2, 180)
+	return strconv.FormatFloat(lat, 'f', -1, 64) + "_" + strconv.FormatFloat(lon, 'f', -1, 64), nil
+}
+
+func (m *matrix) write(w io.Writer) error {
+	species := make([]string, 0, len(m.species))
+	for sp := range m.species {
+		species = append(species, sp)
+	}
+	slices.Sort(species)
+
+	sites := make([]string, 0, len(m.sites))
+	for s := range m.sites {
+		sites = append(sites, s)
+	}
+	slices.Sort(sites)
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	header := append([]string{"site"}, species...)
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	row := make([]string, len(header))
+	for _, s := range sites {
+		row[0] = s
+		for i, sp := range species {
+			n := m.sites[s][sp]
+			if !abundance && n > 0 {
+				n = 1
+			}
+			row[i+1] = strconv.Itoa(n)
+		}
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package pixel implements a command to assign
+// the records of a GBIF occurrence table
+// to the pixels of an equal area pixelation.
+package pixel
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/earth"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/taxonomy"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `pixel [-e|--equator <value>] [--taxa]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "assign records to equal area pixels",
+	Long: `
+Command pixel reads a GBIF occurrence table from the standard input and
+assigns each record to a pixel of an isolatitude equal area pixelation. The
+pixelation is the one of the package github.com/js-arias/earth (used by
+PhyGeo), so the pixel IDs are the same as the IDs of a PhyGeo pixelation with
+the same number of equatorial pixels.
+
+The pixelation is defined by the number of pixels in the equatorial ring. By
+default, it uses 360 pixels (i.e., pixels of about 1 degree at the equator);
+use the flag --equator, or -e, to define a different number of pixels.
+
+By default, the occurrence table is printed with a new column, "pixel", with
+the ID of the pixel of each record. Records without coordinates will have an
+empty pixel.
+
+If the flag --taxa is defined, instead of the occurrence table, it will print
+a table of taxa and pixels, with the following columns:
+
+	- species: the species name.
+	- pixel: the pixel ID.
+	- records: the number of records of the species in the pixel.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var taxaFlag bool
+var equator int
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().BoolVar(&taxaFlag, "taxa", false, "")
+	c.Flags().IntVar(&equator, "equator", 360, "")
+	c.Flags().IntVar(&equator, "e", 360, "")
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if equator < 3 {
+		return c.UsageError(fmt.Sprintf("invalid number of equatorial pixels %d", equator))
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	pix := earth.NewPixelation(equator)
+	if taxaFlag {
+		tp, err := readTaxa(in, pix)
+		if err != nil {
+			return err
+		}
+		return writeTaxa(out, tp)
+	}
+	return readTable(in, out, pix)
+}
+
+// coordCols returns the latitude and longitude columns
+// of a header.
+func coordCols(header []string) (latCol, lonCol int, err error) {
+	latCol, lonCol = -1, -1
+	for i, h := range header {
+		h = strings.ToLower(h)
+		if h == "decimallatitude" {
+			latCol = i
+		}
+		if h == "decimallongitude" {
+			lonCol = i
+		}
+	}
+	if latCol < 0 || lonCol < 0 {
+		return 0, 0, fmt.Errorf("input data %q without %q or %q fields", input, "decimalLatitude", "decimalLongitude")
+	}
+	return latCol, lonCol, nil
+}
+
+// rowPixel returns the pixel of a row,
+// or -1 if the row has no coordinates.
+func rowPixel(row []string, latCol, lonCol int, pix *earth.Pixelation, ln int) (int, error) {
+	if row[latCol] == "" || row[lonCol] == "" {
+		return -1, nil
+	}
+	lat, err := strconv.ParseFloat(row[latCol], 64)
+	if err != nil {
+		return 0, fmt.Errorf("table %q: row %d: field %q: %v", input, ln, "decimalLatitude", err)
+	}
+	if lat < -90 || lat > 90 {
+		return 0, fmt.Errorf("table %q: row %d: field %q: invalid latitude: %.6f", input, ln, "decimalLatitude", lat)
+	}
+	lon, err := strconv.ParseFloat(row[lonCol], 64)
+	if err != nil {
+		return 0, fmt.Errorf("table %q: row %d: field %q: %v", input, ln, "decimalLongitude", err)
+	}
+	if lon < -180 || lon > 180 {
+		return 0, fmt.Errorf("table %q: row %d: field %q: invalid longitude: %.6f", input, ln, "decimalLongitude", lon)
+	}
+	return pix.Pixel(lat, lon).ID(), nil
+}
+
+func readTable(r io.Reader, w io.Writer, pix *earth.Pixelation) error {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return fmt.Errorf("when reading %q header: %v", input, err)
+	}
+	latCol, lonCol, err := coordCols(header)
+	if err != nil {
+		return err
+	}
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	// write header
+	nh := append(header[:len(header):len(header)], "pixel")
+	if err := out.Write(nh); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		px, err := rowPixel(row, latCol, lonCol, pix, ln)
+		if err != nil {
+			return err
+		}
+		var v string
+		if px >= 0 {
+			v = strconv.Itoa(px)
+		}
+		if err := out.Write(append(row, v)); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+type taxPixel struct {
+	name   string
+	pixels map[int]int
+}
+
+func readTaxa(r io.Reader, pix *earth.Pixelation) (map[string]*taxPixel, error) {
+	tab := tabfile.NewReader(r, "decimalLatitude", "decimalLongitude", "species")
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+	latCol, lonCol, err := coordCols(header)
+	if err != nil {
+		return nil, err
+	}
+	spCol := -1
+	for i, h := range header {
+		if strings.ToLower(h) == "species" {
+			spCol = i
+		}
+	}
+	if spCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q field", input, "species")
+	}
+
+	tp := make(map[string]*taxPixel)
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		name := taxonomy.Canon(row[spCol])
+		if name == "" {
+			continue
+		}
+		px, err := rowPixel(row, latCol, lonCol, pix, ln)
+		if err != nil {
+			return nil, err
+		}
+		if px < 0 {
+			continue
+		}
+
+		t, ok := tp[name]
+		if !ok {
+			if err := memory.Add(int64(len(name)) + 96); err != nil {
+				return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			}
+			t = &taxPixel{
+				name:   name,
+				pixels: make(map[int]int),
+			}
+			tp[name] = t
+		}
+		if _, ok := t.pixels[px]; !ok {
+			if err := memory.Add(16); err != nil {
+				return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			}
+		}
+		t.pixels[px]++
+	}
+	return tp, nil
+}
+
+func writeTaxa(w io.Writer, tp map[string]*taxPixel) error {
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	header := []string{
+		"species",
+		"pixel",
+		"records",
+	}
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	names := make([]string, 0, len(tp))
+	for n := range tp {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+
+	for _, n := range names {
+		t := tp[n]
+		pxs := make([]int, 0, len(t.pixels))
+		for px := range t.pixels {
+			pxs = append(pxs, px)
+		}
+		slices.SortFunc(pxs, cmp.Compare[int])
+
+		for _, px := range pxs {
+			row := []string{
+				t.name,
+				strconv.Itoa(px),
+				strconv.Itoa(t.pixels[px]),
+			}
+			if err := out.Write(row); err != nil {
+				return fmt.Errorf("when writing on %q: %v", output, err)
+			}
+			logger.WriteRows(1)
+		}
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
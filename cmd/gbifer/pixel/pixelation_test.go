@@ -0,0 +1,126 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package pixel_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/earth"
+	"github.com/js-arias/gbifer/cmd/gbifer/pixel"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var points = [][2]float64{
+	{90, 0},
+	{-90, 180},
+	{0, 0},
+	{0, -180},
+	{0, 180},
+	{-34.6, -58.4},
+	{51.5, -0.1},
+	{-33.9, 151.2},
+	{64.1, -21.9},
+	{89.9, 45},
+	{-89.9, -45},
+}
+
+func TestPixelIDs(t *testing.T) {
+	var in bytes.Buffer
+	fmt.Fprintf(&in, "gbifID\tdecimalLatitude\tdecimalLongitude\r\n")
+	for i, p := range points {
+		fmt.Fprintf(&in, "%d\t%.6f\t%.6f\r\n", i+1, p[0], p[1])
+	}
+	fmt.Fprintf(&in, "%d\t\t\r\n", len(points)+1)
+	data := in.String()
+
+	for _, eq := range []int{12, 120, 360, 1000} {
+		pix := earth.NewPixelation(eq)
+
+		var out bytes.Buffer
+		pixel.Command.SetStdin(strings.NewReader(data))
+		pixel.Command.SetStdout(&out)
+		if err := pixel.Command.Execute([]string{"--equator", strconv.Itoa(eq)}); err != nil {
+			t.Fatalf("equator %d: unexpected error: %v", eq, err)
+		}
+
+		rows, err := readRows(&out)
+		if err != nil {
+			t.Fatalf("equator %d: output: %v", eq, err)
+		}
+		if len(rows) != len(points)+2 {
+			t.Fatalf("equator %d: got %d rows, want %d", eq, len(rows), len(points)+2)
+		}
+		for i, p := range points {
+			want := strconv.Itoa(pix.Pixel(p[0], p[1]).ID())
+			if got := rows[i+1][3]; got != want {
+				t.Errorf("equator %d: point %.3f %.3f: got pixel %s, want %s", eq, p[0], p[1], got, want)
+			}
+		}
+		if got := rows[len(rows)-1][3]; got != "" {
+			t.Errorf("equator %d: record without coordinates: got pixel %q, want empty", eq, got)
+		}
+	}
+}
+
+func TestPixelTaxa(t *testing.T) {
+	pix := earth.NewPixelation(360)
+
+	var in bytes.Buffer
+	fmt.Fprintf(&in, "species\tdecimalLatitude\tdecimalLongitude\r\n")
+	for _, p := range points {
+		fmt.Fprintf(&in, "Puma concolor\t%.6f\t%.6f\r\n", p[0], p[1])
+	}
+
+	var out bytes.Buffer
+	pixel.Command.SetStdin(&in)
+	pixel.Command.SetStdout(&out)
+	if err := pixel.Command.Execute([]string{"--taxa"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := make(map[string]int)
+	for _, p := range points {
+		want[strconv.Itoa(pix.Pixel(p[0], p[1]).ID())]++
+	}
+
+	rows, err := readRows(&out)
+	if err != nil {
+		t.Fatalf("output: %v", err)
+	}
+	if len(rows)-1 != len(want) {
+		t.Errorf("got %d pixels, want %d", len(rows)-1, len(want))
+	}
+	for _, row := range rows[1:] {
+		if row[0] != "Puma concolor" {
+			t.Errorf("pixel %s: got species %q, want %q", row[1], row[0], "Puma concolor")
+		}
+		if n := strconv.Itoa(want[row[1]]); row[2] != n {
+			t.Errorf("pixel %s: got %s records, want %s", row[1], row[2], n)
+		}
+	}
+}
+
+func readRows(r io.Reader) ([][]string, error) {
+	tab := tsv.NewReader(r)
+	tab.Comma = '\t'
+
+	var rows [][]string
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+}
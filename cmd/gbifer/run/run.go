@@ -0,0 +1,300 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package run implements a command to execute
+// a pipeline of GBIFer commands
+// defined in a file.
+package run
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+)
+
+var Command = &command.Command{
+	Usage: `run [-i|--input <file>] [-o|--output <file>]
+	<pipeline-file>`,
+	Short: "run a pipeline of commands",
+	Long: `
+Command run reads a pipeline file and executes the sequence of GBIFer commands
+defined in the file. A pipeline file can be used to record a data cleaning
+protocol, so it can be shared and repeated.
+
+The pipeline file is a YAML file with the following fields:
+
+	- name: an optional name of the pipeline.
+	- steps: the sequence of steps of the pipeline.
+
+Each step is a mapping with the following fields:
+
+	- command: the command to be executed (for example "filter" or
+	  "tax fill"). It is required.
+	- name: an optional name of the step.
+	- input: the file used as the input of the step. If it is not defined,
+	  the step will use the output of the previous step.
+	- output: the file used as the output of the step. If it is not
+	  defined, a temporary file will be used.
+	- params: a mapping of flags of the command and their values. If the
+	  value is "true" the flag is used without a value, if the value is
+	  "false", the flag is ignored.
+	- args: a sequence with the arguments of the command.
+
+The input and output are given to the command as its standard input and
+output. Commands are executed in the directory of the pipeline file, so the
+file names in the pipeline file are relative to the pipeline file.
+
+Here is an example of a pipeline file:
+
+	name: Felidae cleaning
+	steps:
+	  - command: filter
+	    input: occurrences.tab
+	    params:
+	      tax: felidae-taxonomy.tab
+	  - command: withsp
+	    output: felidae.tab
+	  - command: export
+	    params:
+	      tax: felidae-taxonomy.tab
+
+The steps are run in order. If a step fails, the pipeline is stopped.
+
+If the input of the first step is not defined, it will read the data from the
+standard input; use the flag --input, or -i, to select a particular file.
+
+If the output of the last step is not defined, the results will be printed in
+the standard output; use the flag --output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+// A step is a step of a pipeline.
+type step struct {
+	name   string
+	cmd    []string
+	input  string
+	output string
+	flags  []string
+	args   []string
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if len(args) < 1 {
+		return c.UsageError("expecting pipeline file")
+	}
+
+	steps, err := readPipeline(args[0])
+	if err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(args[0])
+
+	tmp, err := os.MkdirTemp("", "gbifer-run-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	prev := ""
+	for i, st := range steps {
+		var in io.Reader
+		switch {
+		case st.input != "":
+			f, err := os.Open(filepath.Join(dir, st.input))
+			if err != nil {
+				return fmt.Errorf("step %d (%s): %v", i+1, st.name, err)
+			}
+			defer f.Close()
+			in = f
+		case prev != "":
+			f, err := os.Open(prev)
+			if err != nil {
+				return fmt.Errorf("step %d (%s): %v", i+1, st.name, err)
+			}
+			defer f.Close()
+			in = f
+		case input != "":
+			f, err := os.Open(input)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		case i == 0:
+			in = c.Stdin()
+		}
+
+		out := c.Stdout()
+		switch {
+		case st.output != "":
+			prev = filepath.Join(dir, st.output)
+		case i < len(steps)-1:
+			prev = filepath.Join(tmp, fmt.Sprintf("step-%d.tab", i+1))
+		case output != "":
+			prev = output
+		default:
+			prev = ""
+		}
+		if prev != "" {
+			f, err := os.Create(prev)
+			if err != nil {
+				return fmt.Errorf("step %d (%s): %v", i+1, st.name, err)
+			}
+			defer f.Close()
+			out = f
+		}
+
+		if err := st.exec(exe, dir, in, out, c.Stderr()); err != nil {
+			return fmt.Errorf("step %d (%s): %v", i+1, st.name, err)
+		}
+	}
+	return nil
+}
+
+func (st step) exec(exe, dir string, in io.Reader, out, stderr io.Writer) error {
+	var args []string
+	switch logger.Verbosity() {
+	case logger.Quiet:
+		args = append(args, "--quiet")
+	case logger.Verbose:
+		args = append(args, "--verbose")
+	}
+	args = append(args, st.cmd...)
+	args = append(args, st.flags...)
+	args = append(args, st.args...)
+	logger.Verbosef("%s: gbifer %s", st.name, strings.Join(args, " "))
+
+	cmd := exec.Command(exe, args...)
+	cmd.Dir = dir
+	cmd.Stdin = in
+	cmd.Stdout = out
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
+
+func readPipeline(name string) ([]step, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	doc, err := parseYAML(f)
+	if err != nil {
+		return nil, fmt.Errorf("pipeline %q: %v", name, err)
+	}
+	if !doc.isMap {
+		return nil, fmt.Errorf("pipeline %q: expecting a mapping", name)
+	}
+	for _, k := range doc.keys {
+		switch k {
+		case "name", "steps":
+		default:
+			return nil, fmt.Errorf("pipeline %q: line %d: unknown field %q", name, doc.mapping[k].line, k)
+		}
+	}
+
+	ls, ok := doc.mapping["steps"]
+	if !ok || !ls.isSeq || len(ls.seq) == 0 {
+		return nil, fmt.Errorf("pipeline %q: without steps", name)
+	}
+
+	steps := make([]step, 0, len(ls.seq))
+	for _, n := range ls.seq {
+		st, err := newStep(n)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline %q: line %d: %v", name, n.line, err)
+		}
+		steps = append(steps, st)
+	}
+	return steps, nil
+}
+
+func newStep(n *node) (step, error) {
+	if !n.isMap {
+		return step{}, fmt.Errorf("expecting a step mapping")
+	}
+
+	var st step
+	for _, k := range n.keys {
+		v := n.mapping[k]
+		switch k {
+		case "name", "command", "input", "output":
+			if v.isMap || v.isSeq {
+				return step{}, fmt.Errorf("field %q: expecting a value", k)
+			}
+		}
+
+		switch k {
+		case "name":
+			st.name = v.scalar
+		case "command":
+			st.cmd = strings.Fields(v.scalar)
+		case "input":
+			st.input = v.scalar
+		case "output":
+			st.output = v.scalar
+		case "params":
+			if !v.isMap {
+				return step{}, fmt.Errorf("field %q: expecting a mapping", k)
+			}
+			for _, p := range v.keys {
+				pv := v.mapping[p]
+				if pv.isMap || pv.isSeq {
+					return step{}, fmt.Errorf("param %q: expecting a value", p)
+				}
+				flag := "--" + strings.TrimLeft(p, "-")
+				switch strings.ToLower(pv.scalar) {
+				case "true":
+					st.flags = append(st.flags, flag)
+				case "false":
+				default:
+					st.flags = append(st.flags, flag, pv.scalar)
+				}
+			}
+		case "args":
+			if !v.isSeq {
+				return step{}, fmt.Errorf("field %q: expecting a sequence", k)
+			}
+			for _, a := range v.seq {
+				if a.isMap || a.isSeq {
+					return step{}, fmt.Errorf("field %q: expecting a value", k)
+				}
+				st.args = append(st.args, a.scalar)
+			}
+		default:
+			return step{}, fmt.Errorf("unknown field %q", k)
+		}
+	}
+	if len(st.cmd) == 0 {
+		return step{}, fmt.Errorf("step without command")
+	}
+	if st.name == "" {
+		st.name = strings.Join(st.cmd, " ")
+	}
+	return st, nil
+}
@@ -0,0 +1,297 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package run
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A node is a node of a YAML document.
+//
+// Only the subset of YAML used by pipeline files is supported:
+// block mappings,
+// block sequences,
+// flow sequences of scalars,
+// plain and quoted scalars,
+// and comments.
+type node struct {
+	line int
+
+	scalar  string
+	isSeq   bool
+	seq     []*node
+	isMap   bool
+	keys    []string
+	mapping map[string]*node
+}
+
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseYAML parses a YAML document.
+func parseYAML(r io.Reader) (*node, error) {
+	p := &yamlParser{}
+	s := bufio.NewScanner(r)
+	for ln := 1; s.Scan(); ln++ {
+		t := strings.TrimRight(stripComment(s.Text()), " \t\r")
+		if strings.TrimSpace(t) == "" || t == "---" {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimLeft(t, " "), "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", ln)
+		}
+		txt := strings.TrimLeft(t, " ")
+		p.lines = append(p.lines, yamlLine{
+			num:    ln,
+			indent: len(t) - len(txt),
+			text:   txt,
+		})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(p.lines) == 0 {
+		return &node{}, nil
+	}
+
+	n, err := p.block(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return n, nil
+}
+
+// block parses a block node
+// at the given indentation.
+func (p *yamlParser) block(indent int) (*node, error) {
+	l := p.lines[p.pos]
+	if l.text == "-" || strings.HasPrefix(l.text, "- ") {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) sequence(indent int) (*node, error) {
+	n := &node{line: p.lines[p.pos].num, isSeq: true}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", l.num)
+		}
+		if l.text != "-" && !strings.HasPrefix(l.text, "- ") {
+			return nil, fmt.Errorf("line %d: expecting a sequence item", l.num)
+		}
+
+		rest := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+		if rest == "" {
+			p.pos++
+			if p.pos >= len(p.lines) || p.lines[p.pos].indent <= indent {
+				n.seq = append(n.seq, &node{line: l.num})
+				continue
+			}
+			item, err := p.block(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			n.seq = append(n.seq, item)
+			continue
+		}
+
+		if !isMapEntry(rest) {
+			v, err := scalar(rest, l.num)
+			if err != nil {
+				return nil, err
+			}
+			n.seq = append(n.seq, v)
+			p.pos++
+			continue
+		}
+
+		// the item is a mapping
+		// that starts in the same line.
+		p.lines[p.pos] = yamlLine{
+			num:    l.num,
+			indent: l.indent + len(l.text) - len(rest),
+			text:   rest,
+		}
+		item, err := p.mapping(p.lines[p.pos].indent)
+		if err != nil {
+			return nil, err
+		}
+		n.seq = append(n.seq, item)
+	}
+	return n, nil
+}
+
+func (p *yamlParser) mapping(indent int) (*node, error) {
+	n := &node{
+		line:    p.lines[p.pos].num,
+		isMap:   true,
+		mapping: make(map[string]*node),
+	}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", l.num)
+		}
+		if !isMapEntry(l.text) {
+			return nil, fmt.Errorf("line %d: expecting a key-value pair", l.num)
+		}
+
+		k, v, _ := strings.Cut(l.text, ":")
+		k = unquote(strings.TrimSpace(k))
+		if _, dup := n.mapping[k]; dup {
+			return nil, fmt.Errorf("line %d: repeated key %q", l.num, k)
+		}
+		v = strings.TrimSpace(v)
+		p.pos++
+
+		var val *node
+		switch {
+		case v != "":
+			var err error
+			val, err = scalar(v, l.num)
+			if err != nil {
+				return nil, err
+			}
+		case p.pos < len(p.lines) && p.lines[p.pos].indent > indent:
+			var err error
+			val, err = p.block(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+		case p.pos < len(p.lines) && p.lines[p.pos].indent == indent && strings.HasPrefix(p.lines[p.pos].text, "-"):
+			// a sequence can be at the same indentation
+			// of its key.
+			var err error
+			val, err = p.sequence(indent)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			val = &node{line: l.num}
+		}
+		n.keys = append(n.keys, k)
+		n.mapping[k] = val
+	}
+	return n, nil
+}
+
+// isMapEntry returns true if a text
+// is a key-value pair.
+func isMapEntry(s string) bool {
+	if s == "" || s[0] == '"' || s[0] == '\'' || s[0] == '[' {
+		k, _, ok := strings.Cut(s, ":")
+		if !ok || s[0] == '[' {
+			return false
+		}
+		// quoted key
+		k = strings.TrimSpace(k)
+		return len(k) > 1 && k[len(k)-1] == k[0]
+	}
+	i := strings.Index(s, ":")
+	if i < 0 {
+		return false
+	}
+	return i == len(s)-1 || s[i+1] == ' '
+}
+
+// scalar returns a scalar node,
+// or a sequence node
+// if the value is a flow sequence.
+func scalar(v string, ln int) (*node, error) {
+	if !strings.HasPrefix(v, "[") {
+		return &node{line: ln, scalar: unquote(v)}, nil
+	}
+	if !strings.HasSuffix(v, "]") {
+		return nil, fmt.Errorf("line %d: unclosed sequence %q", ln, v)
+	}
+	n := &node{line: ln, isSeq: true}
+	v = strings.TrimSpace(v[1 : len(v)-1])
+	if v == "" {
+		return n, nil
+	}
+	for _, f := range splitFlow(v) {
+		n.seq = append(n.seq, &node{line: ln, scalar: unquote(strings.TrimSpace(f))})
+	}
+	return n, nil
+}
+
+// splitFlow splits the elements of a flow sequence.
+func splitFlow(s string) []string {
+	var ls []string
+	var quote rune
+	start := 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"', r == '\'':
+			quote = r
+		case r == ',':
+			ls = append(ls, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(ls, s[start:])
+}
+
+// unquote removes the quotes of a scalar.
+func unquote(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+	switch {
+	case s[0] == '"' && s[len(s)-1] == '"':
+		r := strings.NewReplacer(`\"`, `"`, `\\`, `\`, `\t`, "\t", `\n`, "\n")
+		return r.Replace(s[1 : len(s)-1])
+	case s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	return s
+}
+
+// stripComment removes a comment
+// that is not inside a quoted string.
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case (r == '"' || r == '\'') && (i == 0 || strings.ContainsRune(" \t[,:", rune(line[i-1]))):
+			quote = r
+		case r == '#':
+			if i == 0 || line[i-1] == ' ' || line[i-1] == '\t' {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
@@ -0,0 +1,109 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+)
+
+// A Column is the definition of a column
+// in a schema file.
+type Column struct {
+	Name     string
+	Type     string // integer, float, date, enum, or text
+	Nullable bool
+
+	// Min and Max are the limits of the values
+	// (the length of the values in enum and text columns).
+	// An empty string is an undefined limit.
+	Min, Max string
+
+	// Values are the valid values
+	// of an enum column.
+	Values []string
+}
+
+// types are the valid column types.
+var types = map[string]bool{
+	"integer": true,
+	"float":   true,
+	"date":    true,
+	"enum":    true,
+	"text":    true,
+}
+
+// Read reads a schema file,
+// as written by the schema command,
+// and returns the columns
+// indexed by the lower case name of the column.
+func Read(r io.Reader) (map[string]Column, error) {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("header: %v", err)
+	}
+	fields := make(map[string]int, len(header))
+	for i, h := range header {
+		fields[strings.ToLower(h)] = i
+	}
+	for _, f := range []string{"column", "type"} {
+		if _, ok := fields[f]; !ok {
+			return nil, fmt.Errorf("without %q field", f)
+		}
+	}
+
+	cols := make(map[string]Column)
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", ln, err)
+		}
+
+		c := Column{
+			Name: strings.TrimSpace(row[fields["column"]]),
+			Type: strings.ToLower(strings.TrimSpace(row[fields["type"]])),
+		}
+		if c.Name == "" {
+			continue
+		}
+		if !types[c.Type] {
+			return nil, fmt.Errorf("row %d: field %q: unknown type %q", ln, "type", c.Type)
+		}
+		if f, ok := fields["nullable"]; ok && strings.TrimSpace(row[f]) != "" {
+			c.Nullable, err = strconv.ParseBool(strings.TrimSpace(row[f]))
+			if err != nil {
+				return nil, fmt.Errorf("row %d: field %q: %v", ln, "nullable", err)
+			}
+		}
+		if f, ok := fields["min"]; ok {
+			c.Min = strings.TrimSpace(row[f])
+		}
+		if f, ok := fields["max"]; ok {
+			c.Max = strings.TrimSpace(row[f])
+		}
+		if f, ok := fields["values"]; ok && c.Type == "enum" {
+			for _, v := range strings.Split(row[f], "|") {
+				if v == "" {
+					continue
+				}
+				c.Values = append(c.Values, v)
+			}
+		}
+		cols[strings.ToLower(c.Name)] = c
+	}
+	return cols, nil
+}
@@ -0,0 +1,364 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package schema implements a command to infer
+// the schema of a GBIF occurrence table.
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `schema [--enum <number>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "infer the schema of a table",
+	Long: `
+Command schema reads a GBIF occurrence table from the standard input and
+infers the type of each column. The schema is printed as a TSV table with the
+following columns:
+
+	- column: the name of the column.
+	- type: the inferred type of the column.
+	- nullable: "true" if the column has empty values.
+	- min: the minimum value of the column.
+	- max: the maximum value of the column.
+	- values: the valid values of an enum column, separated by "|".
+
+The inferred types are:
+
+	integer  all the values are integers.
+	float    all the values are numbers.
+	date     all the values are ISO 8601 dates, or date ranges.
+	enum     a text column with few distinct values.
+	text     any other column.
+
+For text columns, min and max are the minimum and maximum length of the
+values. By default, a text column with 20 or fewer distinct values, in which
+at least one value is repeated, is considered an enum; use the flag --enum to
+define a different number of values (0 to disable enums).
+
+The schema can be used to validate a table (with the flag --schema of the
+command check), or to define the types of the columns exported to a database
+(with the flag --schema of the command export). The schema file can be edited
+before its use, for example, to remove a limit (by leaving the min or max
+field empty), or to add a valid value to an enum column.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+var maxEnum int
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().IntVar(&maxEnum, "enum", 20, "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if maxEnum < 0 {
+		return c.UsageError(fmt.Sprintf("invalid number of enum values: %d", maxEnum))
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	cols, err := readTable(in)
+	if err != nil {
+		return err
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f *os.File
+		f, err = os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := writeSchema(out, cols); err != nil {
+		return err
+	}
+	return nil
+}
+
+// A column stores the values
+// found in a column.
+type column struct {
+	name string
+
+	empty    int
+	nonEmpty int
+
+	isInt   bool
+	isFloat bool
+	isDate  bool
+
+	minNum, maxNum   float64
+	minDate, maxDate string
+	minLen, maxLen   int
+
+	// distinct values,
+	// up to maxEnum+1 values
+	values map[string]int
+}
+
+func newColumn(name string) *column {
+	return &column{
+		name:    name,
+		isInt:   true,
+		isFloat: true,
+		isDate:  true,
+		values:  make(map[string]int),
+	}
+}
+
+func (c *column) add(v string) error {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		c.empty++
+		return nil
+	}
+	first := c.nonEmpty == 0
+	c.nonEmpty++
+
+	if l := utf8.RuneCountInString(v); first || l < c.minLen {
+		c.minLen = l
+	}
+	if l := utf8.RuneCountInString(v); l > c.maxLen {
+		c.maxLen = l
+	}
+
+	if c.isInt {
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			c.isInt = false
+		}
+	}
+	if c.isFloat {
+		if n, err := strconv.ParseFloat(v, 64); err != nil {
+			c.isFloat = false
+		} else {
+			if first || n < c.minNum {
+				c.minNum = n
+			}
+			if first || n > c.maxNum {
+				c.maxNum = n
+			}
+		}
+	}
+	if c.isDate {
+		if !isDate(v) {
+			c.isDate = false
+		} else {
+			if first || v < c.minDate {
+				c.minDate = v
+			}
+			if first || v > c.maxDate {
+				c.maxDate = v
+			}
+		}
+	}
+
+	if _, ok := c.values[v]; ok {
+		c.values[v]++
+	} else if len(c.values) <= maxEnum {
+		if err := memory.Add(int64(len(v)) + 24); err != nil {
+			return err
+		}
+		c.values[v] = 1
+	}
+	return nil
+}
+
+// kind returns the inferred type
+// of the column.
+func (c *column) kind() string {
+	if c.nonEmpty == 0 {
+		return "text"
+	}
+	switch {
+	case c.isInt:
+		return "integer"
+	case c.isFloat:
+		return "float"
+	case c.isDate:
+		return "date"
+	}
+	if len(c.values) <= maxEnum && c.nonEmpty > len(c.values) {
+		return "enum"
+	}
+	return "text"
+}
+
+func (c *column) row() []string {
+	k := c.kind()
+	row := []string{
+		c.name,
+		k,
+		strconv.FormatBool(c.empty > 0),
+		"",
+		"",
+		"",
+	}
+	if c.nonEmpty == 0 {
+		return row
+	}
+	switch k {
+	case "integer", "float":
+		row[3] = strconv.FormatFloat(c.minNum, 'f', -1, 64)
+		row[4] = strconv.FormatFloat(c.maxNum, 'f', -1, 64)
+	case "date":
+		row[3] = c.minDate
+		row[4] = c.maxDate
+	case "enum":
+		vs := make([]string, 0, len(c.values))
+		for v := range c.values {
+			vs = append(vs, v)
+		}
+		slices.Sort(vs)
+		row[5] = strings.Join(vs, "|")
+		fallthrough
+	default:
+		row[3] = strconv.Itoa(c.minLen)
+		row[4] = strconv.Itoa(c.maxLen)
+	}
+	return row
+}
+
+func readTable(r io.Reader) ([]*column, error) {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+	cols := make([]*column, len(header))
+	for i, h := range header {
+		cols[i] = newColumn(h)
+	}
+
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		for i, v := range row {
+			if err := cols[i].add(v); err != nil {
+				return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			}
+		}
+	}
+	return cols, nil
+}
+
+func writeSchema(w io.Writer, cols []*column) error {
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	header := []string{
+		"column",
+		"type",
+		"nullable",
+		"min",
+		"max",
+		"values",
+	}
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	for _, c := range cols {
+		if err := out.Write(c.row()); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+// dateLayouts are the accepted layouts
+// for ISO 8601 dates.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// isDate returns true
+// if the value is an ISO 8601 date,
+// or a date range.
+func isDate(v string) bool {
+	for _, p := range strings.Split(v, "/") {
+		ok := false
+		for _, l := range dateLayouts {
+			if _, err := time.Parse(l, p); err == nil {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
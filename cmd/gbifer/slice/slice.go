@@ -0,0 +1,148 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package slice implements a command to select
+// a range of rows of a GBIF occurrence table.
+package slice
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `slice [--from <number>] [--to <number>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "select a range of rows of a table",
+	Long: `
+Command slice reads a GBIF occurrence table from the standard input and prints
+a range of rows of the table, always preserving the header.
+
+The rows are numbered from 1 (the header is not counted). Use the flag --from
+to define the first row of the range (default 1), and the flag --to to define
+the last row of the range (inclusive). If --to is not defined, all the rows
+after the first row will be printed.
+
+As the command stops reading after the last row of the range, it can be used
+to preview, or to test pipelines, on very large files.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+var from int
+var to int
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().IntVar(&from, "from", 1, "")
+	c.Flags().IntVar(&to, "to", 0, "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if from < 1 {
+		return c.UsageError(fmt.Sprintf("invalid first row: %d", from))
+	}
+	if to != 0 && to < from {
+		return c.UsageError(fmt.Sprintf("invalid last row: %d", to))
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := slice(in, out); err != nil {
+		return err
+	}
+	return nil
+}
+
+func slice(r io.Reader, w io.Writer) error {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return fmt.Errorf("when reading %q header: %v", input, err)
+	}
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	// write header
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	for i := 1; to == 0 || i <= to; i++ {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+		if i < from {
+			continue
+		}
+
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
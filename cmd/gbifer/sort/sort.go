@@ -15,22 +15,46 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/config"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/parallel"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
 	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/taxonomy"
 	"github.com/js-arias/gbifer/tsv"
 )
 
 var Command = &command.Command{
-	Usage: `sort [--species]
-	[-i|--input <file>] [-o|--output <file>]`,
+	Usage: `sort [--species] [--tax <file>] [--jobs <number>]
+	[--progress] [-i|--input <file>] [-o|--output <file>]`,
 	Short: "sort rows by its speciesKey",
 	Long: `
 Command sort reads a GBIF occurrence table from the standard input and sorts
 the rows by the GBIF species identifier and then by the GBIF occurrence ID.
 
 If flag --species is defined, it will sort using the valid species name. This
-option requires an internet connection.
+option requires an internet connection. Each species identifier is searched
+only once, and the identifiers of the synonyms found while searching for a
+valid name are kept, so they are not searched again.
+
+If flag --tax is defined with a taxonomy file, the valid names will be taken
+from the taxonomy, and only the species identifiers not found in the taxonomy
+will be searched in GBIF. If a default taxonomy is defined in the
+configuration (see "gbifer help"), it will be used as the value of --tax. The
+taxonomy is only read if the flag --species is defined.
+
+If the flag --jobs is defined with a value greater than 1, the valid names
+will be searched by the indicated number of concurrent jobs; with 0, it will
+use the number of available CPUs.
+
+If flag --progress is defined, it will print the progress of the reading of
+the input table in the standard error. If the input is a file, the progress
+is reported as a percentage of the file size, otherwise, only the number of
+read rows is reported.
 
 By default, it will read the data from the standard input; use the flag
 --input, or -i, to select a particular file.
@@ -43,11 +67,17 @@ By default, the results will be printed in the standard output; use the flag
 }
 
 var spFlag bool
+var progress bool
+var jobs int
+var taxFile string
 var input string
 var output string
 
 func setFlags(c *command.Command) {
 	c.Flags().BoolVar(&spFlag, "species", false, "")
+	c.Flags().BoolVar(&progress, "progress", false, "")
+	c.Flags().IntVar(&jobs, "jobs", 1, "")
+	c.Flags().StringVar(&taxFile, "tax", config.Taxonomy(), "")
 	c.Flags().StringVar(&input, "input", "", "")
 	c.Flags().StringVar(&input, "i", "", "")
 	c.Flags().StringVar(&output, "output", "", "")
@@ -55,6 +85,15 @@ func setFlags(c *command.Command) {
 }
 
 func run(c *command.Command, args []string) (err error) {
+	var tx *taxonomy.Taxonomy
+	if spFlag && taxFile != "" {
+		var err error
+		tx, err = readTaxonomy()
+		if err != nil {
+			return err
+		}
+	}
+
 	in := c.Stdin()
 	if input != "" {
 		f, err := os.Open(input)
@@ -62,6 +101,11 @@ func run(c *command.Command, args []string) (err error) {
 			return err
 		}
 		defer f.Close()
+		if st, err := f.Stat(); err == nil {
+			if err := memory.Check(st.Size()); err != nil {
+				return fmt.Errorf("table %q: %v", input, err)
+			}
+		}
 		in = f
 	} else {
 		input = "stdin"
@@ -69,8 +113,8 @@ func run(c *command.Command, args []string) (err error) {
 
 	out := c.Stdout()
 	if output != "" {
-		var f *os.File
-		f, err = os.Create(output)
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
 		if err != nil {
 			return err
 		}
@@ -85,14 +129,25 @@ func run(c *command.Command, args []string) (err error) {
 		output = "stdout"
 	}
 
+	var p *logger.Progress
+	if progress {
+		p = logger.NewProgress(in)
+		defer p.Stop()
+		in = p
+	}
+
 	data, err := readTable(in)
 	if err != nil {
 		return err
 	}
+	if progress {
+		p.Stop()
+		logger.Printf("sorting %d rows", len(data.data))
+	}
 
 	// sort
 	if spFlag {
-		if err := sortBySpecies(data); err != nil {
+		if err := sortBySpecies(data, tx); err != nil {
 			return err
 		}
 	} else {
@@ -104,6 +159,9 @@ func run(c *command.Command, args []string) (err error) {
 		})
 	}
 
+	if progress {
+		logger.Printf("writing %d rows", len(data.data))
+	}
 	if err := writeTable(out, data); err != nil {
 		return err
 	}
@@ -118,7 +176,7 @@ type occData struct {
 }
 
 func readTable(r io.Reader) (*occData, error) {
-	tab := tsv.NewReader(r)
+	tab := tabfile.NewReader(r)
 	tab.Comma = '\t'
 
 	header, err := tab.Read()
@@ -145,6 +203,9 @@ func readTable(r io.Reader) (*occData, error) {
 	}
 
 	// read data
+	// repeated values are interned
+	// to reduce the memory used by the table.
+	dict := tsv.NewDict()
 	var data [][]string
 	for {
 		row, err := tab.Read()
@@ -155,7 +216,13 @@ func readTable(r io.Reader) (*occData, error) {
 		if err != nil {
 			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
 		}
+		logger.ReadRows(1)
 
+		size := memory.Row(row)
+		row, saved := dict.Row(row)
+		if err := memory.Add(size - int64(saved)); err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
 		data = append(data, row)
 	}
 
@@ -167,26 +234,52 @@ func readTable(r io.Reader) (*occData, error) {
 	}, nil
 }
 
-func sortBySpecies(data *occData) error {
-	gbif.Open()
+func readTaxonomy() (*taxonomy.Taxonomy, error) {
+	f, err := os.Open(taxFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
 
-	// set the map of IDs to accepted names
+	tx, err := taxonomy.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", taxFile, err)
+	}
+	return tx, nil
+}
+
+func sortBySpecies(data *occData, tx *taxonomy.Taxonomy) error {
+	// distinct species IDs
 	ids := make(map[string]string)
+	var keys []string
 	for _, d := range data.data {
 		id := d[data.spCol]
 		if id == "" {
 			continue
 		}
-
-		sp, ok := ids[id]
-		if ok {
+		if _, ok := ids[id]; ok {
 			continue
 		}
-		sp, err := searchAcceptedName(id)
+		ids[id] = ""
+		keys = append(keys, id)
+	}
+
+	// set the map of IDs to accepted names
+	n := &names{tx: tx, ids: make(map[string]string)}
+	acc := make([]string, len(keys))
+	err := parallel.Each(len(keys), jobs, func(i int) error {
+		sp, err := n.accepted(keys[i])
 		if err != nil {
 			return err
 		}
-		ids[id] = sp
+		acc[i] = sp
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i, id := range keys {
+		ids[id] = acc[i]
 	}
 
 	// sort
@@ -203,14 +296,56 @@ func sortBySpecies(data *occData) error {
 	return nil
 }
 
-func searchAcceptedName(id string) (string, error) {
+// invalidName is the name used for invalid names
+// without a senior synonym,
+// so they are sorted at the end.
+const invalidName = "zzzzzzzz invalid"
+
+// names stores the accepted names
+// of the species IDs.
+type names struct {
+	tx *taxonomy.Taxonomy
+
+	mu  sync.Mutex
+	ids map[string]string
+}
+
+// accepted returns the accepted name of a species ID.
+func (n *names) accepted(id string) (string, error) {
+	if n.tx != nil {
+		if key, err := strconv.ParseInt(id, 10, 64); err == nil {
+			if tax := n.tx.Taxon(key); tax.ID != 0 {
+				acc := n.tx.Accepted(key)
+				if acc.ID == 0 {
+					return invalidName, nil
+				}
+				return acc.Name, nil
+			}
+		}
+	}
+
+	gbif.Open()
+
+	// IDs of the synonyms in the chain
+	var chain []string
+	name := invalidName
 	for {
+		n.mu.Lock()
+		v, ok := n.ids[id]
+		n.mu.Unlock()
+		if ok {
+			name = v
+			break
+		}
+
 		sp, err := gbif.SpeciesID(id)
 		if err != nil {
 			return "", err
 		}
+		chain = append(chain, id)
 		if sp.TaxonomicStatus == "ACCEPTED" {
-			return sp.CanonicalName, nil
+			name = sp.CanonicalName
+			break
 		}
 		acceptedKey := sp.AcceptedKey
 		if acceptedKey == 0 {
@@ -218,11 +353,22 @@ func searchAcceptedName(id string) (string, error) {
 		}
 		if acceptedKey == 0 {
 			// invalid names without a senior synonym
-			return "zzzzzzzz invalid", nil
+			break
 		}
 
 		id = strconv.FormatInt(acceptedKey, 10)
+		if slices.Contains(chain, id) {
+			// a synonym cycle
+			break
+		}
+	}
+
+	n.mu.Lock()
+	for _, c := range chain {
+		n.ids[c] = name
 	}
+	n.mu.Unlock()
+	return name, nil
 }
 
 func writeTable(w io.Writer, data *occData) error {
@@ -238,6 +384,7 @@ func writeTable(w io.Writer, data *occData) error {
 		if err := out.Write(d); err != nil {
 			return fmt.Errorf("when writing on %q: %v", output, err)
 		}
+		logger.WriteRows(1)
 	}
 	out.Flush()
 	if err := out.Error(); err != nil {
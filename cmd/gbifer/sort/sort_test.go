@@ -0,0 +1,102 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package sort_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/sort"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var occTable = "gbifID\tspeciesKey\tspecies\r\n" +
+	"3\t20\tBeta beta\r\n" +
+	"2\t10\tAlpha alpha\r\n" +
+	"1\t20\tBeta beta\r\n"
+
+func TestSort(t *testing.T) {
+	// the taxonomy is only read with --species
+	missing := filepath.Join(t.TempDir(), "missing.tab")
+
+	var buf bytes.Buffer
+	sort.Command.SetStdin(strings.NewReader(occTable))
+	sort.Command.SetStdout(&buf)
+	if err := sort.Command.Execute([]string{"--tax", missing}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "gbifID\tspeciesKey\tspecies\r\n" +
+		"2\t10\tAlpha alpha\r\n" +
+		"1\t20\tBeta beta\r\n" +
+		"3\t20\tBeta beta\r\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// synthetic returns a reader
+// with a table of n rows,
+// and a counter of the written rows.
+// The reader must be closed
+// to stop the generation of rows.
+func synthetic(n int) (io.ReadCloser, *atomic.Int64) {
+	var written atomic.Int64
+	r, w := io.Pipe()
+	go func() {
+		tab := tsv.NewWriter(w)
+		tab.Comma = '\t'
+		tab.Write([]string{"gbifID", "speciesKey", "locality"})
+		for i := 0; i < n; i++ {
+			tab.Write([]string{
+				fmt.Sprintf("%d", n-i),
+				fmt.Sprintf("%d", i%1000),
+				fmt.Sprintf("Locality number %d of a large table", i),
+			})
+			if tab.Error() != nil {
+				break
+			}
+			written.Add(1)
+		}
+		tab.Flush()
+		w.CloseWithError(tab.Error())
+	}()
+	return r, &written
+}
+
+func TestSortMemoryLimit(t *testing.T) {
+	const rows = 1_000_000
+	memory.SetLimit(1 << 20)
+	defer memory.SetLimit(0)
+
+	r, written := synthetic(rows)
+	defer r.Close()
+
+	var buf bytes.Buffer
+	sort.Command.SetStdin(r)
+	sort.Command.SetStdout(&buf)
+	err := sort.Command.Execute([]string{"--tax", ""})
+	if err == nil {
+		t.Fatalf("expecting a memory limit error")
+	}
+	if !strings.Contains(err.Error(), memory.ErrLimit.Error()) {
+		t.Errorf("got error %v, want %v", err, memory.ErrLimit)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("got %d bytes of output, want none", buf.Len())
+	}
+
+	// the guard must fail fast,
+	// as soon as the limit is reached
+	if n := written.Load(); n >= rows/10 {
+		t.Errorf("read rows: got %d, want less than %d", n, rows/10)
+	}
+}
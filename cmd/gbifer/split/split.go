@@ -0,0 +1,296 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package split implements a command to split
+// a GBIF occurrence table
+// into several tables
+// using the values of a column.
+package split
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `split --by <column> [-d|--dir <directory>]
+	[-i|--input <file>]`,
+	Short: "split a table by the values of a column",
+	Long: `
+Command split reads a GBIF occurrence table from the standard input and writes
+a table for each distinct value of a column (for example, "species",
+"countryCode", or "datasetKey"). Each table includes the header of the input
+table. The input table is read in a single pass.
+
+The flag --by is required and defines the column used to split the table.
+Rows with an empty value in the column are ignored.
+
+The tables are written in the current directory; use the flag --dir, or -d,
+to define a different directory (it will be created if it does not exist).
+Each table will be named with the value of the column, replacing the
+characters not valid in a file name with "_", and with the extension ".tsv",
+for example:
+
+	Puma_concolor.tsv
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var byCol string
+var dir string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&byCol, "by", "", "")
+	c.Flags().StringVar(&dir, "dir", "", "")
+	c.Flags().StringVar(&dir, "d", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if byCol == "" {
+		return c.UsageError("flag --by must be defined")
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	fs := newFiles()
+	defer func() {
+		e := fs.closeAll()
+		if e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	if err := split(in, fs); err != nil {
+		return err
+	}
+	return nil
+}
+
+func split(r io.Reader, fs *files) error {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return fmt.Errorf("when reading %q header: %v", input, err)
+	}
+	col := -1
+	for i, h := range header {
+		if strings.EqualFold(h, byCol) {
+			col = i
+		}
+	}
+	if col < 0 {
+		return fmt.Errorf("input data %q without %q field", input, byCol)
+	}
+	fs.header = header
+
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		v := strings.TrimSpace(row[col])
+		if v == "" {
+			continue
+		}
+		if err := fs.write(v, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxOpen is the maximum number of files
+// open at the same time.
+const maxOpen = 128
+
+// A file is an output table.
+type file struct {
+	name string
+	f    *os.File
+	w    *tsv.Writer
+	last int // last use of the file
+}
+
+// Files is the set of output tables.
+type files struct {
+	header []string
+	values map[string]*file
+	names  map[string]bool
+	open   int
+	clock  int
+}
+
+func newFiles() *files {
+	return &files{
+		values: make(map[string]*file),
+		names:  make(map[string]bool),
+	}
+}
+
+// write writes a row in the table
+// of the given value.
+func (fs *files) write(v string, row []string) error {
+	fl, ok := fs.values[v]
+	if !ok {
+		fl = &file{name: fs.newName(v)}
+		fs.values[v] = fl
+	}
+	if fl.w == nil {
+		if err := fs.openFile(fl, !ok); err != nil {
+			return err
+		}
+	}
+	fs.clock++
+	fl.last = fs.clock
+
+	if err := fl.w.Write(row); err != nil {
+		return fmt.Errorf("when writing on %q: %v", fl.name, err)
+	}
+	logger.WriteRows(1)
+	return nil
+}
+
+// newName returns a new file name
+// for a value.
+func (fs *files) newName(v string) string {
+	base := fileName(v)
+	name := filepath.Join(dir, base+".tsv")
+	for i := 2; fs.names[name]; i++ {
+		name = filepath.Join(dir, base+"-"+strconv.Itoa(i)+".tsv")
+	}
+	fs.names[name] = true
+	return name
+}
+
+// openFile opens a file.
+// If the file is new,
+// the file will be created
+// and the header will be written,
+// otherwise,
+// the rows will be appended to the file.
+// If there are too many open files,
+// the least recently used file is closed.
+func (fs *files) openFile(fl *file, isNew bool) error {
+	if fs.open >= maxOpen {
+		var lru *file
+		for _, o := range fs.values {
+			if o.w == nil {
+				continue
+			}
+			if lru == nil || o.last < lru.last {
+				lru = o
+			}
+		}
+		if err := lru.close(); err != nil {
+			return err
+		}
+		fs.open--
+	}
+
+	var err error
+	if isNew {
+		fl.f, err = os.Create(fl.name)
+	} else {
+		fl.f, err = os.OpenFile(fl.name, os.O_WRONLY|os.O_APPEND, 0644)
+	}
+	if err != nil {
+		return err
+	}
+	fl.w = tsv.NewWriter(fl.f)
+	fl.w.Comma = '\t'
+	fl.w.UseCRLF = true
+	fs.open++
+
+	if isNew {
+		if err := fl.w.Write(fs.header); err != nil {
+			return fmt.Errorf("when writing on %q: %v", fl.name, err)
+		}
+	}
+	return nil
+}
+
+// closeAll closes all open files.
+func (fs *files) closeAll() error {
+	var err error
+	for _, fl := range fs.values {
+		if fl.w == nil {
+			continue
+		}
+		if e := fl.close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	fs.open = 0
+	return err
+}
+
+func (fl *file) close() error {
+	fl.w.Flush()
+	err := fl.w.Error()
+	if err != nil {
+		err = fmt.Errorf("when writing on %q: %v", fl.name, err)
+	}
+	if e := fl.f.Close(); e != nil && err == nil {
+		err = e
+	}
+	fl.f = nil
+	fl.w = nil
+	return err
+}
+
+// fileName returns a string
+// that can be used as a file name.
+func fileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r == '-', r == '.':
+			return r
+		}
+		return '_'
+	}, s)
+}
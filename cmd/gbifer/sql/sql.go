@@ -0,0 +1,184 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package sql implements a command to query
+// a GBIF occurrence table with SQL.
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/occurrence"
+	"github.com/js-arias/gbifer/query"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `sql [-i|--input <file>] [-o|--output <file>]
+	<query>`,
+	Short: "query a table with SQL",
+	Long: `
+Command sql reads a GBIF occurrence table from the standard input and prints
+the result of a SQL query on the table.
+
+The argument of the command is the query. Only SELECT queries are supported,
+with the following syntax:
+
+	SELECT [DISTINCT] <item> [, <item>...]
+	[FROM <table>]
+	[WHERE <condition>]
+	[GROUP BY <expression> [, <expression>...]]
+	[HAVING <condition>]
+	[ORDER BY <expression> [ASC|DESC] [, <expression> [ASC|DESC]...]]
+	[LIMIT <number> [OFFSET <number>]]
+
+The table name in the FROM clause is ignored, as the queried table is always
+the input table. Column names are case insensitive, and can be quoted with
+double quotes (for example, "order", as ORDER is a keyword). Strings are quoted
+with single quotes. Empty values are NULL. Values are compared as numbers if
+both values are numbers, otherwise they are compared as strings.
+
+Expressions support arithmetic operators (+, -, *, /, %), string concatenation
+(||), comparisons (=, <>, !=, <, <=, >, >=), logical operators (AND, OR, NOT),
+IS [NOT] NULL, [NOT] LIKE (case insensitive), [NOT] IN, and [NOT] BETWEEN. The
+available functions are LOWER, UPPER, LENGTH, TRIM, SUBSTR, COALESCE, ROUND,
+and ABS; and the aggregate functions are COUNT, SUM, AVG, MIN, and MAX. Items
+in GROUP BY and ORDER BY can be a column number of the result (starting at 1),
+or the alias of a result column.
+
+For example, to count the records of each species:
+
+	gbifer sql -i occurrences.tsv \
+	  "select species, count(*) as records from t group by 1 order by 2 desc"
+
+Queries without aggregates or ORDER BY are processed as the table is read.
+Queries with GROUP BY or aggregates keep a row for each group in memory, and
+queries with ORDER BY keep all the resulting rows in memory.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+	
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if len(args) == 0 {
+		return c.UsageError("expecting query")
+	}
+	q, err := query.Parse(strings.Join(args, " "))
+	if err != nil {
+		return fmt.Errorf("invalid query: %v", err)
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := readTable(in, out, q); err != nil {
+		return err
+	}
+	return nil
+}
+
+func readTable(r io.Reader, w io.Writer, q *query.Query) error {
+	tab, err := occurrence.NewTableReader(tabfile.NewReader(r))
+	if err != nil {
+		return fmt.Errorf("when reading %q %v", input, err)
+	}
+
+	res, err := q.Run(counter{tab})
+	if err != nil {
+		return fmt.Errorf("invalid query: %v", err)
+	}
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	// write header
+	if err := out.Write(res.Header()); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	// write data
+	for {
+		row, err := res.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("table %q: %v", input, err)
+		}
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+// A counter counts the rows
+// read from the input table.
+type counter struct {
+	occurrence.Reader
+}
+
+func (c counter) Read() ([]string, error) {
+	row, err := c.Reader.Read()
+	if err == nil {
+		logger.ReadRows(1)
+	}
+	return row, err
+}
@@ -0,0 +1,169 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package tabfile implements the opening
+// of the tables read by the commands,
+// either as text tables
+// (optionally compressed with gzip),
+// or as columnar cache files,
+// the creation of the output tables,
+// and the backup of the files modified in place.
+package tabfile
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/columnar"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+// NewReader returns a reader of the table in r.
+//
+// If r is a columnar cache file
+// (as created by the command "cache build"),
+// the rows will be read from the cache file,
+// and if cols is defined,
+// only the indicated columns
+// (case insensitive)
+// will be decoded.
+// Columns that are not in the file are ignored,
+// so the command can report them as missing.
+//
+// Otherwise,
+// the table is read as a text table
+// (decompressed, if it is compressed with gzip),
+// and cols is ignored.
+func NewReader(r io.Reader, cols ...string) *tsv.Reader {
+	if f, ok := r.(*os.File); ok {
+		if src := columnarSource(f, cols); src != nil {
+			return tsv.NewSourceReader(src)
+		}
+		if st, err := f.Stat(); err == nil && st.Mode().IsRegular() && !isGzip(f) {
+			// a regular file can be memory mapped
+			return tsv.NewReader(f)
+		}
+	}
+
+	br := bufio.NewReader(r)
+	if b, err := br.Peek(len(gzipMagic)); err == nil && string(b) == gzipMagic {
+		if z, err := gzip.NewReader(br); err == nil {
+			return tsv.NewReader(z)
+		}
+	}
+	return tsv.NewReader(br)
+}
+
+// gzipMagic is the start of a gzip file.
+const gzipMagic = "\x1f\x8b"
+
+// isGzip returns true if a file,
+// at its current offset,
+// starts with the gzip magic bytes.
+func isGzip(f *os.File) bool {
+	off, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false
+	}
+	b := make([]byte, len(gzipMagic))
+	if _, err := f.ReadAt(b, off); err != nil {
+		return false
+	}
+	return string(b) == gzipMagic
+}
+
+// Gzip sets the compression of the output tables.
+// If it is true,
+// the tables created with Create
+// are compressed with gzip.
+var Gzip bool
+
+// Create creates an output table.
+// If Gzip is true,
+// or the name of the file ends with ".gz",
+// the table will be compressed with gzip.
+func Create(name string) (io.WriteCloser, error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	if !Gzip && !strings.HasSuffix(strings.ToLower(name), ".gz") {
+		return f, nil
+	}
+	return &gzipFile{Writer: gzip.NewWriter(f), f: f}, nil
+}
+
+// Backup renames a file,
+// adding a timestamp to its name,
+// so the previous version of the file is kept
+// (for example "taxonomy.tab.20231016-150405.bak").
+// If the file does not exist,
+// it does nothing.
+func Backup(name string) error {
+	if _, err := os.Stat(name); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	bak := name + "." + time.Now().Format("20060102-150405") + ".bak"
+	if err := os.Rename(name, bak); err != nil {
+		return err
+	}
+	logger.Printf("file %q saved as %q", name, bak)
+	return nil
+}
+
+// A gzipFile is a file
+// compressed with gzip.
+type gzipFile struct {
+	*gzip.Writer
+	f *os.File
+}
+
+func (z *gzipFile) Close() error {
+	err := z.Writer.Close()
+	if e := z.f.Close(); e != nil && err == nil {
+		err = e
+	}
+	return err
+}
+
+// columnarSource returns the reader
+// of a columnar file,
+// or nil if the file is not a columnar file.
+func columnarSource(f *os.File, cols []string) *columnar.Reader {
+	if off, err := f.Seek(0, io.SeekCurrent); err != nil || off != 0 {
+		return nil
+	}
+	if !columnar.IsColumnar(f) {
+		return nil
+	}
+	st, err := f.Stat()
+	if err != nil {
+		return nil
+	}
+	cf, err := columnar.NewFile(f, st.Size())
+	if err != nil {
+		return nil
+	}
+
+	var sel []string
+	for _, h := range cf.Header() {
+		for _, c := range cols {
+			if strings.EqualFold(h, c) {
+				sel = append(sel, h)
+				break
+			}
+		}
+	}
+	rd, err := cf.Reader(sel...)
+	if err != nil {
+		return nil
+	}
+	return rd
+}
@@ -0,0 +1,193 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package tabfile_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/columnar"
+)
+
+const occText = "gbifID\tspecies\tcountryCode\r\n1\tPuma concolor\tAR\r\n2\tFelis catus\tUY\r\n"
+
+func TestNewReader(t *testing.T) {
+	header := []string{"gbifID", "species", "countryCode"}
+	rows := [][]string{
+		{"1", "Puma concolor", "AR"},
+		{"2", "Felis catus", "UY"},
+	}
+
+	dir := t.TempDir()
+	text := filepath.Join(dir, "occ.tab")
+	if err := os.WriteFile(text, []byte(occText), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	gz := filepath.Join(dir, "occ.tab.gz")
+	zf, err := tabfile.Create(gz)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	io.WriteString(zf, occText)
+	if err := zf.Close(); err != nil {
+		t.Fatalf("close: unexpected error: %v", err)
+	}
+	cache := filepath.Join(dir, "occ.gbc")
+	f, err := os.Create(cache)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w, err := columnar.NewWriter(f, header)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, r := range rows {
+		if err := w.Write(r); err != nil {
+			t.Fatalf("write: unexpected error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: unexpected error: %v", err)
+	}
+	f.Close()
+
+	tests := map[string]struct {
+		file string
+		cols []string
+		want [][]string
+	}{
+		"text": {
+			file: text,
+			want: append([][]string{header}, rows...),
+		},
+		"text with columns": {
+			file: text,
+			cols: []string{"countryCode"},
+			want: append([][]string{header}, rows...),
+		},
+		"gzip": {
+			file: gz,
+			cols: []string{"countryCode"},
+			want: append([][]string{header}, rows...),
+		},
+		"cache": {
+			file: cache,
+			want: append([][]string{header}, rows...),
+		},
+		"cache with columns": {
+			file: cache,
+			cols: []string{"COUNTRYCODE", "gbifid", "year"},
+			want: [][]string{
+				{"gbifID", "countryCode"},
+				{"1", "AR"},
+				{"2", "UY"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Open(test.file)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer f.Close()
+
+			tab := tabfile.NewReader(f, test.cols...)
+			var got [][]string
+			for {
+				row, err := tab.Read()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				got = append(got, row)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewReaderStream(t *testing.T) {
+	var buf bytes.Buffer
+	z := gzip.NewWriter(&buf)
+	io.WriteString(z, occText)
+	z.Close()
+
+	tests := map[string]io.Reader{
+		"text": bytes.NewBufferString(occText),
+		"gzip": &buf,
+	}
+	for name, r := range tests {
+		t.Run(name, func(t *testing.T) {
+			tab := tabfile.NewReader(r)
+			var n int
+			for {
+				_, err := tab.Read()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				n++
+			}
+			if n != 3 {
+				t.Errorf("rows: got %d, want %d", n, 3)
+			}
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	defer func() {
+		tabfile.Gzip = false
+	}()
+
+	dir := t.TempDir()
+	tests := map[string]struct {
+		name string
+		gzip bool
+		want bool
+	}{
+		"text":          {name: "occ.tab"},
+		"gz extension":  {name: "occ.tab.gz", want: true},
+		"gzip settings": {name: "occ-gzip.tab", gzip: true, want: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tabfile.Gzip = test.gzip
+			name := filepath.Join(dir, test.name)
+			f, err := tabfile.Create(name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			io.WriteString(f, occText)
+			if err := f.Close(); err != nil {
+				t.Fatalf("close: unexpected error: %v", err)
+			}
+
+			b, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := bytes.HasPrefix(b, []byte{0x1f, 0x8b}); got != test.want {
+				t.Errorf("compressed: got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package tail implements a command to select
+// the last rows of a GBIF occurrence table.
+package tail
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `tail [-n <number>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "select the last rows of a table",
+	Long: `
+Command tail reads a GBIF occurrence table from the standard input and prints
+the last rows of the table, always preserving the header. By default, it
+prints 10 rows; use the flag -n to define a different number of rows.
+
+Only the indicated number of rows is kept in memory, so it can be used with
+very large files.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+var numRows int
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().IntVar(&numRows, "n", 10, "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if numRows < 0 {
+		return c.UsageError(fmt.Sprintf("invalid number of rows: %d", numRows))
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := tail(in, out); err != nil {
+		return err
+	}
+	return nil
+}
+
+func tail(r io.Reader, w io.Writer) error {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return fmt.Errorf("when reading %q header: %v", input, err)
+	}
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	// write header
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	// rows is a circular buffer
+	// with the last read rows.
+	rows := make([][]string, numRows)
+	n := 0
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+		if numRows == 0 {
+			continue
+		}
+		// only the last rows are kept
+		size := memory.Row(row)
+		if old := rows[n%numRows]; old != nil {
+			size -= memory.Row(old)
+		}
+		if err := memory.Add(size); err != nil {
+			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		rows[n%numRows] = row
+		n++
+	}
+
+	start := 0
+	if n > numRows {
+		start = n - numRows
+	}
+	for i := start; i < n; i++ {
+		if err := out.Write(rows[i%numRows]); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
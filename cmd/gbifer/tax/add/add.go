@@ -7,6 +7,7 @@
 package add
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
 	"io"
@@ -15,14 +16,17 @@ import (
 	"strings"
 
 	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
 	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/journal"
 	"github.com/js-arias/gbifer/taxonomy"
-	"github.com/js-arias/gbifer/tsv"
 )
 
 var Command = &command.Command{
-	Usage: `add [--rank <rank>]
-	[--file <file>] [-i|--input <file>]`,
+	Usage: `add [--rank <rank>] [--names <file>] [--backbone <path>]
+	[--checklist <key>] [--interactive] [--choices <file>] [--journal <file>]
+	[--file <file>] [--dry-run] [--backup] [-i|--input <file>]`,
 	Short: "add taxons to a taxonomy",
 	Long: `
 Command add reads a GBIF occurrence table from the standard input and extracts
@@ -33,6 +37,11 @@ taxon.
 If the input taxon is a synonym, it will add it along with the valid name as
 stored in GBIF.
 
+If the flag --names is defined, instead of an occurrence table, the indicated
+file will be read as a list of taxon names, one name per line, for example a
+species list, or the terminals of a phylogeny. Lines starting with '#' and
+blank lines are ignored.
+
 By default, the taxa will be added up to the genus rank; to use another rank,
 use the flag --rank with one of the following values:
 
@@ -45,14 +54,55 @@ use the flag --rank with one of the following values:
 	genus
 	species
 
+When searching by name, if a name has multiple possible resolutions in GBIF,
+and they can not be resolved automatically, the name will be ignored, and the
+candidate IDs will be printed in the standard error. If the flag
+--interactive is defined, the candidates (with their classification) will be
+presented, and the user will be asked to choose one of them (or none). As
+answers are read from the standard input, the flag --interactive requires an
+input file (flag --input, or --names).
+
+If the flag --choices is defined, the indicated file will be used to store
+the choices made for ambiguous names. If the file already exists, the stored
+choices will be used, so reruns are deterministic. The choices file is a TSV
+file with the columns "name" and "taxonKey" (with 0 for ignored names), and
+can be edited by hand.
+
+If the flag --journal is defined, the progress of the command (i.e., the
+processed names or IDs, and the added taxa) will be stored in the indicated
+file. If the command is interrupted (for example, by a network failure),
+running the command again with the same journal file will resume the process
+where it was left off. A journal can only be used with the same input and
+flags, and the journal file is removed when the command finishes successfully.
+
 By default, a new taxonomy will be created and printed in the standard output.
 To add to an existing taxonomy file, or to write to a taxonomy file, use the
 flag --file with the name of the taxonomy file.
 
+If the flag --dry-run is defined, the taxonomy file will not be modified, and
+the changes that would be made to the taxonomy will be printed in the standard
+output. In a dry run, the choices file is read, but not written, and the
+journal is not used. If the flag --backup is defined, before writing the
+taxonomy file, the previous version of the file will be kept, renamed with a
+timestamp (for example "taxonomy.tab.20231016-150405.bak").
+
 By default, it will read the data from the standard input; use the flag
 --input, or -i, to select a particular file.
 
-This command requires an internet connection.
+By default, the taxa are retrieved from the GBIF API, so this command
+requires an internet connection. If the flag --backbone is defined, the taxa
+will be retrieved from a local copy of the GBIF backbone. The value can be the
+backbone Darwin Core Archive (a zip file), a directory with the uncompressed
+archive, or a taxon table with the same columns of the backbone Taxon.tsv file
+(for example a subset of the backbone).
+
+If the flag --checklist is defined, the taxa will be retrieved from the
+indicated GBIF checklist dataset (for example, a particular version of a
+published catalogue), instead of the current GBIF backbone, so the taxonomy
+can be reproduced later. The value is the dataset key of the checklist, and
+the IDs of the taxonomy will be the IDs of the taxa in that checklist. The
+flag --checklist can not be used with the flag --backbone (to use a
+particular release of the backbone, use a local copy of that release).
 	`,
 	SetFlags: setFlags,
 	Run:      run,
@@ -60,18 +110,45 @@ This command requires an internet connection.
 
 var input string
 var taxFile string
+var namesFile string
 var rankFlag string
+var backboneFile string
+var checklist string
+var choicesFile string
+var journalFile string
+var interactive bool
+var dryRun bool
+var backupFlag bool
 
 func setFlags(c *command.Command) {
+	c.Flags().BoolVar(&interactive, "interactive", false, "")
+	c.Flags().StringVar(&backboneFile, "backbone", "", "")
+	c.Flags().StringVar(&checklist, "checklist", "", "")
+	c.Flags().StringVar(&choicesFile, "choices", "", "")
+	c.Flags().StringVar(&journalFile, "journal", "", "")
 	c.Flags().StringVar(&rankFlag, "rank", taxonomy.Genus.String(), "")
 	c.Flags().StringVar(&input, "input", "", "")
 	c.Flags().StringVar(&input, "i", "", "")
 	c.Flags().StringVar(&taxFile, "file", "", "")
+	c.Flags().StringVar(&namesFile, "names", "", "")
+	c.Flags().BoolVar(&dryRun, "dry-run", false, "")
+	c.Flags().BoolVar(&backupFlag, "backup", false, "")
 }
 
 func run(c *command.Command, args []string) (err error) {
+	if checklist != "" && backboneFile != "" {
+		return c.UsageError("flags --checklist and --backbone can not be used together")
+	}
 	in := c.Stdin()
-	if input != "" {
+	if namesFile != "" {
+		f, err := os.Open(namesFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+		input = namesFile
+	} else if input != "" {
 		f, err := os.Open(input)
 		if err != nil {
 			return err
@@ -81,6 +158,9 @@ func run(c *command.Command, args []string) (err error) {
 	} else {
 		input = "stdin"
 	}
+	if interactive && input == "stdin" {
+		return c.UsageError("flag --interactive requires an input file")
+	}
 	if rankFlag == "" {
 		rankFlag = taxonomy.Genus.String()
 	}
@@ -95,15 +175,87 @@ func run(c *command.Command, args []string) (err error) {
 	} else {
 		tx = taxonomy.NewTaxonomy()
 	}
-	gbif.Open()
+	old := taxonomy.NewTaxonomy()
+	if dryRun && taxFile != "" {
+		var err error
+		old, err = readTaxonomy()
+		if err != nil {
+			return err
+		}
+	}
+	if backboneFile != "" {
+		if err := gbif.OpenBackbone(backboneFile); err != nil {
+			return err
+		}
+	} else {
+		gbif.Checklist = checklist
+		gbif.Open()
+	}
 
-	if err := readTable(in, c.Stderr(), tx); err != nil {
+	rs := &resolver{
+		stderr: c.Stderr(),
+	}
+	if interactive {
+		rs.answers = bufio.NewReader(c.Stdin())
+	}
+	if err := rs.readChoices(); err != nil {
+		return err
+	}
+
+	var jr *journal.Journal
+	if journalFile != "" && !dryRun {
+		var file string
+		if input != "stdin" {
+			file = input
+		}
+		var hash string
+		hash, err = journal.Hash(file, taxFile, rankFlag, backboneFile, checklist)
+		if err != nil {
+			return err
+		}
+		jr, err = journal.Open(journalFile, hash)
+		if err != nil {
+			return err
+		}
+		tx.Record()
+		defer func() {
+			// the journal is only removed
+			// if the command was successful.
+			if err != nil {
+				jr.Close()
+				return
+			}
+			err = jr.Remove()
+		}()
+	}
+
+	if namesFile != "" {
+		if err := readNames(in, rs, jr, tx); err != nil {
+			return err
+		}
+	} else if err := readTable(in, rs, jr, tx); err != nil {
 		return err
 	}
 	tx.Stage()
 
+	if dryRun {
+		for _, ch := range taxonomy.Compare(old, tx) {
+			fmt.Fprintf(c.Stdout(), "%s\n", ch)
+		}
+		return nil
+	}
+
+	if err := rs.writeChoices(); err != nil {
+		return err
+	}
+
 	out := c.Stdout()
 	if taxFile != "" {
+		if backupFlag {
+			if err := tabfile.Backup(taxFile); err != nil {
+				return err
+			}
+		}
 		var f *os.File
 		f, err = os.Create(taxFile)
 		if err != nil {
@@ -151,8 +303,8 @@ func readTaxonomy() (*taxonomy.Taxonomy, error) {
 	return tx, nil
 }
 
-func readTable(r io.Reader, stderr io.Writer, tx *taxonomy.Taxonomy) error {
-	tab := tsv.NewReader(r)
+func readTable(r io.Reader, rs *resolver, jr *journal.Journal, tx *taxonomy.Taxonomy) error {
+	tab := tabfile.NewReader(r, "speciesKey", "taxonKey", "species")
 	tab.Comma = '\t'
 
 	header, err := tab.Read()
@@ -189,6 +341,7 @@ func readTable(r io.Reader, stderr io.Writer, tx *taxonomy.Taxonomy) error {
 		if err != nil {
 			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
 		}
+		logger.ReadRows(1)
 		if keyCol >= 0 || taxCol >= 0 {
 			var key string
 			if keyCol >= 0 {
@@ -203,6 +356,13 @@ func readTable(r io.Reader, stderr io.Writer, tx *taxonomy.Taxonomy) error {
 			if key == "" {
 				continue
 			}
+			done, err := restore(tx, jr, key)
+			if err != nil {
+				return err
+			}
+			if done {
+				continue
+			}
 
 			id, err := strconv.ParseInt(key, 10, 64)
 			if err != nil {
@@ -211,24 +371,74 @@ func readTable(r io.Reader, stderr io.Writer, tx *taxonomy.Taxonomy) error {
 			if err := tx.AddFromGBIF(id, rank); err != nil {
 				return err
 			}
+			if err := jr.Commit(key, tx.Recorded()...); err != nil {
+				return err
+			}
 			continue
 		}
-		name := strings.Join(strings.Fields(row[spCol]), " ")
-		if name == "" {
+		name := row[spCol]
+		done, err := restore(tx, jr, name)
+		if err != nil {
+			return err
+		}
+		if done {
 			continue
 		}
-		if err := tx.AddNameFromGBIF(name, rank); err != nil {
-			var ambErr *taxonomy.ErrAmbiguous
-			if errors.As(err, &ambErr) {
-				fmt.Fprintf(stderr, "# ambiguous taxon name %q\n", taxonomy.Canon(name))
-				for _, v := range ambErr.IDs {
-					fmt.Fprintf(stderr, "# \t%d\n", v)
-				}
-				continue
-			}
+		if err := rs.addName(tx, name, rank); err != nil {
 			return err
 		}
+		if err := jr.Commit(name, tx.Recorded()...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restore adds to the taxonomy
+// the taxa stored in the journal
+// for a processed key.
+// It returns false if the key was not processed.
+func restore(tx *taxonomy.Taxonomy, jr *journal.Journal, key string) (bool, error) {
+	values, ok := jr.Values(key)
+	if !ok {
+		return false, nil
+	}
+	if _, err := tx.Restore(values); err != nil {
+		return true, fmt.Errorf("journal %q: key %q: %v", journalFile, key, err)
 	}
+	return true, nil
+}
 
+func readNames(r io.Reader, rs *resolver, jr *journal.Journal, tx *taxonomy.Taxonomy) error {
+	rank := taxonomy.GetRank(rankFlag)
+
+	br := bufio.NewReader(r)
+	for i := 1; ; i++ {
+		ln, err := br.ReadString('\n')
+		if err != nil && len(ln) == 0 {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("on file %q: line %d: %v", input, i, err)
+		}
+		ln = strings.TrimSpace(ln)
+		if ln == "" || strings.HasPrefix(ln, "#") {
+			continue
+		}
+		done, err := restore(tx, jr, ln)
+		if err != nil {
+			return err
+		}
+		if done {
+			continue
+		}
+		if err := rs.addName(tx, ln, rank); err != nil {
+			return err
+		}
+		if err := jr.Commit(ln, tx.Recorded()...); err != nil {
+			return err
+		}
+	}
 	return nil
 }
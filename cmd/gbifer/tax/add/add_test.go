@@ -0,0 +1,110 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package add_test
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/tax/add"
+	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/taxonomy"
+)
+
+func TestAddNames(t *testing.T) {
+	gbif.Client = &http.Client{Transport: gbif.NewReplayer("testdata/gbif")}
+	gbif.Wait = 0
+
+	names := filepath.Join(t.TempDir(), "names.txt")
+	if err := os.WriteFile(names, []byte("# species\nPuma concolor\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	add.Command.SetStdout(&buf)
+	if err := add.Command.Execute([]string{"--names", names, "--rank", "family"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tx, err := taxonomy.Read(&buf)
+	if err != nil {
+		t.Fatalf("taxonomy: unexpected error: %v", err)
+	}
+	tests := []struct {
+		id     int64
+		name   string
+		rank   taxonomy.Rank
+		parent int64
+	}{
+		{9703, "Felidae", taxonomy.Family, 0},
+		{2435098, "Puma", taxonomy.Genus, 9703},
+		{2435099, "Puma concolor", taxonomy.Species, 2435098},
+	}
+	if ids := tx.IDs(); len(ids) != len(tests) {
+		t.Errorf("got %d taxa, want %d", len(ids), len(tests))
+	}
+	for _, test := range tests {
+		tax := tx.Taxon(test.id)
+		if tax.ID != test.id {
+			t.Errorf("taxon %d: not found", test.id)
+			continue
+		}
+		if tax.Name != test.name {
+			t.Errorf("taxon %d: name: got %q, want %q", test.id, tax.Name, test.name)
+		}
+		if tax.Rank != test.rank {
+			t.Errorf("taxon %d: rank: got %v, want %v", test.id, tax.Rank, test.rank)
+		}
+		if tax.Parent != test.parent {
+			t.Errorf("taxon %d: parent: got %d, want %d", test.id, tax.Parent, test.parent)
+		}
+	}
+}
+
+func TestAddDryRun(t *testing.T) {
+	gbif.Client = &http.Client{Transport: gbif.NewReplayer("testdata/gbif")}
+	gbif.Wait = 0
+
+	dir := t.TempDir()
+	names := filepath.Join(dir, "names.txt")
+	if err := os.WriteFile(names, []byte("# species\nPuma concolor\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	taxFile := filepath.Join(dir, "taxonomy.tab")
+	choices := filepath.Join(dir, "choices.tab")
+
+	// a journal left by an interrupted run
+	// should not be used, nor removed, by a dry run.
+	journal := filepath.Join(dir, "journal.tab")
+	if err := os.WriteFile(journal, nil, 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	add.Command.SetStdout(&buf)
+	args := []string{"--names", names, "--rank", "family", "--file", taxFile, "--choices", choices, "--journal", journal, "--dry-run"}
+	if err := add.Command.Execute(args); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if buf.Len() == 0 {
+		t.Errorf("dry run: empty output")
+	}
+	for _, f := range []string{taxFile, choices} {
+		if _, err := os.Stat(f); err == nil {
+			t.Errorf("dry run: file %q written", filepath.Base(f))
+		}
+	}
+	st, err := os.Stat(journal)
+	if err != nil {
+		t.Fatalf("dry run: journal: %v", err)
+	}
+	if st.Size() != 0 {
+		t.Errorf("dry run: journal: got %d bytes, want 0", st.Size())
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package add
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/taxonomy"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+// A resolver resolves ambiguous names.
+type resolver struct {
+	stderr io.Writer
+
+	// answers is the source of the user answers
+	// in interactive mode
+	// (nil if not interactive).
+	answers *bufio.Reader
+
+	// choices made for ambiguous names
+	choices map[string]int64
+	changed bool
+}
+
+// addName adds a taxon name from GBIF,
+// resolving ambiguous names.
+func (rs *resolver) addName(tx *taxonomy.Taxonomy, name string, rank taxonomy.Rank) error {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return nil
+	}
+	err := tx.AddNameFromGBIF(name, rank)
+	if err == nil {
+		return nil
+	}
+
+	var ambErr *taxonomy.ErrAmbiguous
+	if !errors.As(err, &ambErr) {
+		return err
+	}
+
+	name = taxonomy.Canon(name)
+	id, ok := rs.choices[name]
+	if !ok && rs.answers != nil {
+		id, err = rs.ask(name, ambErr.IDs)
+		if err != nil {
+			return err
+		}
+		ok = true
+	}
+	if !ok {
+		ids := make([]string, 0, len(ambErr.IDs))
+		for _, v := range ambErr.IDs {
+			ids = append(ids, strconv.FormatInt(v, 10))
+		}
+		logger.Printf("ambiguous taxon name %q: %s", name, strings.Join(ids, ", "))
+		return nil
+	}
+	if id == 0 {
+		return nil
+	}
+	return tx.AddFromGBIF(id, rank)
+}
+
+// ask asks the user to choose one of the candidate IDs
+// of an ambiguous name.
+func (rs *resolver) ask(name string, ids []int64) (int64, error) {
+	fmt.Fprintf(rs.stderr, "ambiguous taxon name %q:\n", name)
+	for i, id := range ids {
+		sp, err := gbif.SpeciesID(strconv.FormatInt(id, 10))
+		if err != nil {
+			return 0, err
+		}
+		fmt.Fprintf(rs.stderr, "  [%d] %d %s [%s] %s", i+1, sp.NubKey, sp.ScientificName, strings.ToLower(sp.Rank), strings.ToLower(sp.TaxonomicStatus))
+		if sp.AcceptedKey != 0 {
+			fmt.Fprintf(rs.stderr, " (accepted: %d)", sp.AcceptedKey)
+		}
+		fmt.Fprintf(rs.stderr, "\n")
+		var class []string
+		for _, n := range []string{sp.Kingdom, sp.Phylum, sp.Class, sp.Order, sp.Family, sp.Genus} {
+			if n != "" {
+				class = append(class, n)
+			}
+		}
+		if len(class) > 0 {
+			fmt.Fprintf(rs.stderr, "      %s\n", strings.Join(class, " > "))
+		}
+	}
+
+	for {
+		fmt.Fprintf(rs.stderr, "choose [1-%d], or 0 to ignore the name: ", len(ids))
+		ln, err := rs.answers.ReadString('\n')
+		if err != nil && len(ln) == 0 {
+			if errors.Is(err, io.EOF) {
+				return 0, fmt.Errorf("ambiguous taxon name %q: no answer", name)
+			}
+			return 0, err
+		}
+		v, err := strconv.Atoi(strings.TrimSpace(ln))
+		if err != nil || v < 0 || v > len(ids) {
+			continue
+		}
+
+		var id int64
+		if v > 0 {
+			id = ids[v-1]
+		}
+		if rs.choices == nil {
+			rs.choices = make(map[string]int64)
+		}
+		rs.choices[name] = id
+		rs.changed = true
+		return id, nil
+	}
+}
+
+func (rs *resolver) readChoices() error {
+	if choicesFile == "" {
+		return nil
+	}
+
+	f, err := os.Open(choicesFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tab := tsv.NewReader(f)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return fmt.Errorf("choices file %q: header: %v", choicesFile, err)
+	}
+	nameCol := -1
+	keyCol := -1
+	for i, h := range header {
+		h = strings.ToLower(h)
+		if h == "name" {
+			nameCol = i
+		}
+		if h == "taxonkey" {
+			keyCol = i
+		}
+	}
+	if nameCol < 0 || keyCol < 0 {
+		return fmt.Errorf("choices file %q: without %q or %q fields", choicesFile, "name", "taxonKey")
+	}
+
+	rs.choices = make(map[string]int64)
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return fmt.Errorf("choices file %q: row %d: %v", choicesFile, ln, err)
+		}
+
+		name := taxonomy.Canon(row[nameCol])
+		if name == "" {
+			continue
+		}
+		var id int64
+		if k := strings.TrimSpace(row[keyCol]); k != "" {
+			id, err = strconv.ParseInt(k, 10, 64)
+			if err != nil {
+				return fmt.Errorf("choices file %q: row %d: %q: %v", choicesFile, ln, "taxonKey", err)
+			}
+		}
+		rs.choices[name] = id
+	}
+	return nil
+}
+
+func (rs *resolver) writeChoices() (err error) {
+	if choicesFile == "" || !rs.changed {
+		return nil
+	}
+
+	f, err := os.Create(choicesFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		e := f.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	out := tsv.NewWriter(f)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	if err := out.Write([]string{"name", "taxonKey"}); err != nil {
+		return fmt.Errorf("when writing on %q: %v", choicesFile, err)
+	}
+	names := make([]string, 0, len(rs.choices))
+	for n := range rs.choices {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	for _, n := range names {
+		row := []string{
+			n,
+			strconv.FormatInt(rs.choices[n], 10),
+		}
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", choicesFile, err)
+		}
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", choicesFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package countries implements a command to create
+// a template of a country file
+// from a taxonomy.
+package countries
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/country"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/taxonomy"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `countries [--occurrences <file>] [--min <value>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "create a country file template",
+	Long: `
+Command countries reads a taxonomy from the standard input and prints a
+template of a country file, with a row for each accepted species of the
+taxonomy, that can be edited by hand and used with the flag --country of the
+filter command.
+
+The country file has the following columns:
+
+	- name: the name of the species.
+	- countryCode: an ISO 3166-1 alpha-2 code of the country.
+	- country: name of the country.
+	- records: the number of records of the species in the country.
+
+By default, the country code of each species is empty. If the flag
+--occurrences is defined with a GBIF occurrence table, the file will be
+pre-filled with the countries in which each species has records (using the
+"speciesKey", or "taxonKey", and "countryCode" columns). Use the flag --min to
+define the minimum number of records of a species required to include a
+country (by default, 1). Species without any country with enough records will
+be printed with an empty country code.
+
+Rows with an empty country code are ignored by the filter command.
+
+By default, it will read the taxonomy from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var minRecords int
+var occFile string
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().IntVar(&minRecords, "min", 1, "")
+	c.Flags().StringVar(&occFile, "occurrences", "", "")
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if minRecords < 1 {
+		return c.UsageError(fmt.Sprintf("invalid --min value %d", minRecords))
+	}
+
+	tx, err := readTaxonomy(c.Stdin())
+	if err != nil {
+		return err
+	}
+
+	var counts map[int64]map[string]int
+	if occFile != "" {
+		counts, err = readOccurrences(tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := writeCountries(out, tx, counts); err != nil {
+		return err
+	}
+	return nil
+}
+
+func readTaxonomy(r io.Reader) (*taxonomy.Taxonomy, error) {
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	} else {
+		input = "stdin"
+	}
+
+	tx, err := taxonomy.Read(r)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", input, err)
+	}
+	return tx, nil
+}
+
+// readOccurrences returns the number of records
+// of each accepted species
+// in each country.
+func readOccurrences(tx *taxonomy.Taxonomy) (map[int64]map[string]int, error) {
+	f, err := os.Open(occFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tab := tabfile.NewReader(f, "speciesKey", "taxonKey", "countryCode")
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", occFile, err)
+	}
+
+	keyCol := -1
+	taxCol := -1
+	cCol := -1
+	for i, h := range header {
+		switch strings.ToLower(h) {
+		case "specieskey":
+			keyCol = i
+		case "taxonkey":
+			taxCol = i
+		case "countrycode":
+			cCol = i
+		}
+	}
+	if cCol < 0 || (keyCol < 0 && taxCol < 0) {
+		return nil, fmt.Errorf("input data %q without %q or %q fields", occFile, "countryCode", "taxonKey")
+	}
+
+	counts := make(map[int64]map[string]int)
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", occFile, ln, err)
+		}
+		logger.ReadRows(1)
+
+		cc := strings.TrimSpace(strings.ToUpper(row[cCol]))
+		if cc == "" {
+			continue
+		}
+		if country.Name(cc) == "" {
+			return nil, fmt.Errorf("table %q: row %d: invalid country code: %q", occFile, ln, cc)
+		}
+
+		var key string
+		if keyCol >= 0 {
+			key = row[keyCol]
+		}
+		if key == "" && taxCol >= 0 {
+			key = row[taxCol]
+		}
+		if key == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: key: %v", occFile, ln, err)
+		}
+
+		tax := tx.AcceptedAndRanked(id)
+		if tax.ID == 0 || tax.Rank != taxonomy.Species {
+			continue
+		}
+		c, ok := counts[tax.ID]
+		if !ok {
+			c = make(map[string]int)
+			counts[tax.ID] = c
+		}
+		c[cc]++
+	}
+	return counts, nil
+}
+
+func writeCountries(w io.Writer, tx *taxonomy.Taxonomy, counts map[int64]map[string]int) error {
+	var spp []taxonomy.Taxon
+	for _, id := range tx.IDs() {
+		tax := tx.Taxon(id)
+		if tax.Status != "accepted" || tax.Rank != taxonomy.Species {
+			continue
+		}
+		spp = append(spp, tax)
+	}
+	slices.SortFunc(spp, func(a, b taxonomy.Taxon) int {
+		if c := cmp.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	header := []string{
+		"name",
+		"countryCode",
+		"country",
+		"records",
+	}
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	for _, sp := range spp {
+		var ccs []string
+		for cc, n := range counts[sp.ID] {
+			if n < minRecords {
+				continue
+			}
+			ccs = append(ccs, cc)
+		}
+		slices.SortFunc(ccs, func(a, b string) int {
+			return cmp.Compare(country.Name(a), country.Name(b))
+		})
+
+		if len(ccs) == 0 {
+			if err := out.Write([]string{sp.Name, "", "", ""}); err != nil {
+				return fmt.Errorf("when writing on %q: %v", output, err)
+			}
+			logger.WriteRows(1)
+			continue
+		}
+		for _, cc := range ccs {
+			row := []string{
+				sp.Name,
+				cc,
+				country.Name(cc),
+				strconv.Itoa(counts[sp.ID][cc]),
+			}
+			if err := out.Write(row); err != nil {
+				return fmt.Errorf("when writing on %q: %v", output, err)
+			}
+			logger.WriteRows(1)
+		}
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package diff implements a command to compare
+// two taxonomy files.
+package diff
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/taxonomy"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `diff [--tsv] [-o|--output <file>]
+	<old-taxonomy> <new-taxonomy>`,
+	Short: "compare two taxonomies",
+	Long: `
+Command diff reads two taxonomy files and prints the differences between them.
+Taxa are compared using their GBIF IDs. The reported changes are:
+
+	- added: the taxon is only in the new taxonomy.
+	- removed: the taxon is only in the old taxonomy.
+	- renamed: the taxon has a different name.
+	- reparented: the taxon has a different parent.
+	- status: the taxon has a different taxonomic status.
+	- rank: the taxon has a different rank.
+
+The first argument is the old taxonomy file, and the second argument is the
+new taxonomy file.
+
+By default, the changes are printed in a human readable form. If the flag
+--tsv is defined, the changes will be printed as a TSV table with the
+following columns:
+
+	- change: the type of change.
+	- taxonKey: the GBIF ID of the taxon.
+	- name: the name of the taxon (in the new taxonomy if available).
+	- old: the old value.
+	- new: the new value.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var tsvFlag bool
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().BoolVar(&tsvFlag, "tsv", false, "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if len(args) < 2 {
+		return c.UsageError("expecting old and new taxonomy files")
+	}
+
+	oldTx, err := readTaxonomy(args[0])
+	if err != nil {
+		return err
+	}
+	newTx, err := readTaxonomy(args[1])
+	if err != nil {
+		return err
+	}
+
+	changes := taxonomy.Compare(oldTx, newTx)
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		if tsvFlag {
+			f, err = tabfile.Create(output)
+		} else {
+			f, err = os.Create(output)
+		}
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if tsvFlag {
+		return writeTSV(out, changes)
+	}
+	for _, ch := range changes {
+		fmt.Fprintf(out, "%s\n", ch)
+	}
+	return nil
+}
+
+func readTaxonomy(name string) (*taxonomy.Taxonomy, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tx, err := taxonomy.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", name, err)
+	}
+	return tx, nil
+}
+
+func writeTSV(w io.Writer, changes []taxonomy.Change) error {
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	header := []string{
+		"change",
+		"taxonKey",
+		"name",
+		"old",
+		"new",
+	}
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	for _, ch := range changes {
+		row := []string{
+			ch.Kind,
+			strconv.FormatInt(ch.ID, 10),
+			ch.Name,
+			ch.Old,
+			ch.New,
+		}
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package edit implements a command to apply
+// scripted edits to a taxonomy file.
+package edit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/taxonomy"
+)
+
+var Command = &command.Command{
+	Usage: `edit --file <file> [--patch <file>]
+	[--rename <id>=<name>] [--move <id>=<parent>]
+	[--status <id>=<status>]`,
+	Short: "edit a taxonomy",
+	Long: `
+Command edit applies a set of edits to a taxonomy file, so manual corrections
+can be reapplied each time the taxonomy is rebuilt.
+
+The taxonomy file is required and must be defined with the flag --file. The
+file will be overwritten with the edited taxonomy.
+
+The following edits are available, each one can be given multiple times:
+
+	--rename <id>=<name>      changes the name of the taxon with the given
+	                          GBIF ID.
+	--move <id>=<parent>      changes the parent of the taxon with the given
+	                          GBIF ID. Use 0 as the parent to make the taxon a
+	                          root taxon.
+	--status <id>=<status>    changes the taxonomic status of the taxon with
+	                          the given GBIF ID (for example "accepted" or
+	                          "synonym").
+
+The edits can also be read from a patch file, using the flag --patch. In a
+patch file, each line is an edit, using the name of the edit followed by its
+value, for example:
+
+	# fix the name of the genus
+	rename 2435098=Puma
+	move 2435099=2435098
+	status 2435100=synonym
+
+Lines starting with '#' and blank lines are ignored.
+
+The edits in the patch file are applied first, then the edits given as flags,
+in the order they were given.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var taxFile string
+var patchFile string
+var edits editList
+
+func setFlags(c *command.Command) {
+	edits = nil
+	c.Flags().StringVar(&taxFile, "file", "", "")
+	c.Flags().StringVar(&patchFile, "patch", "", "")
+	c.Flags().Var(editFlag{op: "rename", ls: &edits}, "rename", "")
+	c.Flags().Var(editFlag{op: "move", ls: &edits}, "move", "")
+	c.Flags().Var(editFlag{op: "status", ls: &edits}, "status", "")
+}
+
+// An edit is a single edit operation.
+type edit struct {
+	op    string
+	id    int64
+	value string
+}
+
+type editList []edit
+
+// EditFlag is a flag value
+// that appends edits to an edit list.
+type editFlag struct {
+	op string
+	ls *editList
+}
+
+func (f editFlag) String() string { return "" }
+
+func (f editFlag) Set(v string) error {
+	e, err := parseEdit(f.op, v)
+	if err != nil {
+		return err
+	}
+	*f.ls = append(*f.ls, e)
+	return nil
+}
+
+func parseEdit(op, v string) (edit, error) {
+	id, value, ok := strings.Cut(v, "=")
+	if !ok {
+		return edit{}, fmt.Errorf("%s: expecting <id>=<value>, got %q", op, v)
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(id), 10, 64)
+	if err != nil {
+		return edit{}, fmt.Errorf("%s: invalid ID %q: %v", op, id, err)
+	}
+	return edit{
+		op:    op,
+		id:    n,
+		value: strings.TrimSpace(value),
+	}, nil
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if taxFile == "" {
+		return c.UsageError("expecting taxonomy file, flag --file")
+	}
+
+	tx, err := readTaxonomy()
+	if err != nil {
+		return err
+	}
+
+	var ls editList
+	if patchFile != "" {
+		ls, err = readPatch(patchFile)
+		if err != nil {
+			return err
+		}
+	}
+	ls = append(ls, edits...)
+	if len(ls) == 0 {
+		return c.UsageError("expecting edits")
+	}
+
+	for _, e := range ls {
+		if err := apply(tx, e); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Create(taxFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		e := f.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	if err := tx.Write(f); err != nil {
+		return fmt.Errorf("when writing to %q: %v", taxFile, err)
+	}
+	return nil
+}
+
+func readTaxonomy() (*taxonomy.Taxonomy, error) {
+	f, err := os.Open(taxFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tx, err := taxonomy.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", taxFile, err)
+	}
+	return tx, nil
+}
+
+func readPatch(name string) (editList, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("patch file %q: %v", name, err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var ls editList
+	for i := 1; ; i++ {
+		ln, err := r.ReadString('\n')
+		if err != nil && len(ln) == 0 {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("on file %q: line %d: %v", name, i, err)
+		}
+		ln = strings.TrimSpace(ln)
+		if ln == "" || strings.HasPrefix(ln, "#") {
+			continue
+		}
+
+		op, v, _ := strings.Cut(ln, " ")
+		op = strings.ToLower(op)
+		switch op {
+		case "rename", "move", "status":
+		default:
+			return nil, fmt.Errorf("on file %q: line %d: unknown edit %q", name, i, op)
+		}
+		e, err := parseEdit(op, v)
+		if err != nil {
+			return nil, fmt.Errorf("on file %q: line %d: %v", name, i, err)
+		}
+		ls = append(ls, e)
+	}
+	return ls, nil
+}
+
+func apply(tx *taxonomy.Taxonomy, e edit) error {
+	switch e.op {
+	case "rename":
+		return tx.Rename(e.id, e.value)
+	case "move":
+		p, err := strconv.ParseInt(e.value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("move: taxon %d: invalid parent %q: %v", e.id, e.value, err)
+		}
+		return tx.Move(e.id, p)
+	case "status":
+		return tx.SetStatus(e.id, e.value)
+	}
+	return fmt.Errorf("unknown edit %q", e.op)
+}
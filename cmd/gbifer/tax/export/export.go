@@ -0,0 +1,121 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package export implements a command to export
+// a taxonomy file
+// into other formats.
+package export
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/taxonomy"
+)
+
+var Command = &command.Command{
+	Usage: `export [--format <format>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "export a taxonomy to other formats",
+	Long: `
+Command export reads a taxonomy from the standard input and prints it using
+a different format.
+
+By default, the taxonomy is exported as a JSON file. Use the flag --format to
+define the output format. Valid formats are:
+
+	checklist  a Darwin Core taxon table (tab delimited), that can be
+	           used as the core of a checklist Darwin Core Archive.
+	csv        a comma separated table (RFC 4180), with the same columns
+	           of the taxonomy file.
+	json       a JSON array of nested taxa.
+	newick     a tree in Newick format, using the accepted taxa.
+
+By default, it will read the taxonomy from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var formatFlag string
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&formatFlag, "format", "json", "")
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	var write func(*taxonomy.Taxonomy, io.Writer) error
+	switch strings.ToLower(formatFlag) {
+	case "checklist":
+		write = (*taxonomy.Taxonomy).WriteChecklist
+	case "csv":
+		write = (*taxonomy.Taxonomy).WriteCSV
+	case "json":
+		write = (*taxonomy.Taxonomy).WriteJSON
+	case "newick":
+		write = (*taxonomy.Taxonomy).WriteNewick
+	default:
+		return c.UsageError(fmt.Sprintf("unknown format %q", formatFlag))
+	}
+
+	tx, err := readTaxonomy(c.Stdin())
+	if err != nil {
+		return err
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f *os.File
+		f, err = os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := write(tx, out); err != nil {
+		return fmt.Errorf("when writing to %q: %v", output, err)
+	}
+	return nil
+}
+
+func readTaxonomy(r io.Reader) (*taxonomy.Taxonomy, error) {
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	} else {
+		input = "stdin"
+	}
+
+	tx, err := taxonomy.Read(r)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", input, err)
+	}
+	return tx, nil
+}
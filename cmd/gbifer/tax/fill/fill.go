@@ -9,24 +9,76 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/js-arias/command"
 	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/journal"
 	"github.com/js-arias/gbifer/taxonomy"
 )
 
 var Command = &command.Command{
-	Usage: `fill [--rank <rank>]
+	Usage: `fill [--rank <rank>] [--accepted] [--infra <number>]
+	[--backbone <path>] [--checklist <key>] [--journal <file>]
+	[--progress] [--max-taxa <number>]
 	[-i|--input <file>] [-o|--output <file>]`,
 	Short: "fill a taxonomy",
 	Long: `
 Command fill reads a taxonomy from the standard input and fills the taxa in
 the taxonomy with all the children and synonyms found in GBIF.
 
-By default, only the taxa at or below species level. To use another rank, use
-the flag --rank with one of the following values:
-	
-This command requires an internet connection.
+By default, only the taxa at species level will be filled. To fill taxa at a
+more inclusive rank (and all the ranks below it), use the flag --rank with
+one of the following values:
+
+	kingdom
+	phylum
+	class
+	order
+	family
+	genus
+	species
+
+By default, children and synonyms will be added. If the flag --accepted is
+defined, only accepted children will be added, and synonyms will be ignored.
+
+By default, only a single level of infraspecific taxa (for example,
+subspecies) will be added. Use the flag --infra to set the number of
+infraspecific levels to be added (for example, --infra 2 will add subspecies,
+and the varieties and forms of a subspecies). If --infra is 0, no
+infraspecific taxa will be added.
+
+By default, the taxa are retrieved from the GBIF API, so this command
+requires an internet connection. If the flag --backbone is defined, the taxa
+will be retrieved from a local copy of the GBIF backbone. The value can be the
+backbone Darwin Core Archive (a zip file), a directory with the uncompressed
+archive, or a taxon table with the same columns of the backbone Taxon.tsv file
+(for example a subset of the backbone).
+
+If the flag --checklist is defined, the taxa will be retrieved from the
+indicated GBIF checklist dataset (for example, a particular version of a
+published catalogue), instead of the current GBIF backbone, so the taxonomy
+can be reproduced later. The value is the dataset key of the checklist, and
+the IDs of the taxonomy will be the IDs of the taxa in that checklist. The
+flag --checklist can not be used with the flag --backbone (to use a
+particular release of the backbone, use a local copy of that release).
+
+If the flag --journal is defined, the progress of the command (i.e., the
+processed taxa, and the added children and synonyms) will be stored in the
+indicated file. If the command is interrupted (for example, by a network
+failure), running the command again with the same journal file will resume
+the process where it was left off. A journal can only be used with the same
+input taxonomy and flags, and the journal file is removed when the command
+finishes successfully.
+
+If the flag --progress is defined, the number of processed and queued taxa,
+the number of requests made to the GBIF API, and the estimated remaining time,
+will be reported periodically in the standard error.
+
+Filling a large taxonomy (for example, an order) can take hours. Use the flag
+--max-taxa to set a maximum number of taxa in the filled taxonomy. If the
+number of taxa is exceeded, the command will stop with an error.
 	`,
 	SetFlags: setFlags,
 	Run:      run,
@@ -35,8 +87,22 @@ This command requires an internet connection.
 var input string
 var output string
 var rankFlag string
+var backboneFile string
+var checklist string
+var journalFile string
+var acceptedFlag bool
+var infraFlag int
+var maxTaxa int
+var progressFlag bool
 
 func setFlags(c *command.Command) {
+	c.Flags().BoolVar(&acceptedFlag, "accepted", false, "")
+	c.Flags().IntVar(&infraFlag, "infra", 1, "")
+	c.Flags().StringVar(&backboneFile, "backbone", "", "")
+	c.Flags().StringVar(&checklist, "checklist", "", "")
+	c.Flags().BoolVar(&progressFlag, "progress", false, "")
+	c.Flags().IntVar(&maxTaxa, "max-taxa", 0, "")
+	c.Flags().StringVar(&journalFile, "journal", "", "")
 	c.Flags().StringVar(&rankFlag, "rank", taxonomy.Species.String(), "")
 	c.Flags().StringVar(&input, "input", "", "")
 	c.Flags().StringVar(&input, "i", "", "")
@@ -45,6 +111,9 @@ func setFlags(c *command.Command) {
 }
 
 func run(c *command.Command, args []string) (err error) {
+	if checklist != "" && backboneFile != "" {
+		return c.UsageError("flags --checklist and --backbone can not be used together")
+	}
 	tx, err := readTaxonomy(c.Stdin())
 	if err != nil {
 		return err
@@ -54,8 +123,50 @@ func run(c *command.Command, args []string) (err error) {
 		rankFlag = taxonomy.Species.String()
 	}
 
-	gbif.Open()
-	if err := fillTax(tx); err != nil {
+	if backboneFile != "" {
+		if err := gbif.OpenBackbone(backboneFile); err != nil {
+			return err
+		}
+	} else {
+		gbif.Checklist = checklist
+		gbif.Open()
+	}
+
+	var jr *journal.Journal
+	if journalFile != "" {
+		var file string
+		if input != "stdin" {
+			file = input
+		}
+		var hash string
+		hash, err = journal.Hash(file, rankFlag, backboneFile, checklist, strconv.FormatBool(acceptedFlag), strconv.Itoa(infraFlag))
+		if err != nil {
+			return err
+		}
+		jr, err = journal.Open(journalFile, hash)
+		if err != nil {
+			return err
+		}
+		tx.Record()
+		defer func() {
+			// the journal is only removed
+			// if the command was successful.
+			if err != nil {
+				jr.Close()
+				return
+			}
+			err = jr.Remove()
+		}()
+	}
+
+	var prog *progress
+	if progressFlag {
+		prog = &progress{
+			w:     c.Stderr(),
+			start: time.Now(),
+		}
+	}
+	if err := fillTax(tx, jr, prog); err != nil {
 		return err
 	}
 	tx.Stage()
@@ -104,15 +215,15 @@ func readTaxonomy(r io.Reader) (*taxonomy.Taxonomy, error) {
 	return tx, nil
 }
 
-func fillTax(tx *taxonomy.Taxonomy) error {
+func fillTax(tx *taxonomy.Taxonomy, jr *journal.Journal, prog *progress) error {
 	rank := taxonomy.GetRank(rankFlag)
 
 	ids := tx.IDs()
 	toAdd := make(map[int64]bool, len(ids))
+	added := make(map[int64]bool, len(ids))
 	for _, id := range ids {
 		toAdd[id] = true
 	}
-	added := make(map[int64]bool, len(ids))
 	for {
 		if len(toAdd) == 0 {
 			break
@@ -123,13 +234,32 @@ func fillTax(tx *taxonomy.Taxonomy) error {
 				continue
 			}
 
+			// the children of a processed taxon
+			// are restored from the journal
+			key := strconv.FormatInt(id, 10)
+			if values, ok := jr.Values(key); ok {
+				ls, err := tx.Restore(values)
+				if err != nil {
+					return fmt.Errorf("journal %q: key %q: %v", journalFile, key, err)
+				}
+				for _, c := range ls {
+					if !added[c] {
+						toAdd[c] = true
+					}
+				}
+				delete(toAdd, id)
+				added[id] = true
+				continue
+			}
+
 			r := tx.Rank(id)
-			if r == taxonomy.Unranked {
+			lvl := infraLevel(tx, id)
+			if r == taxonomy.Unranked && (lvl < 0 || lvl >= infraFlag) {
 				added[id] = true
 				delete(toAdd, id)
 				continue
 			}
-			if r < rank {
+			if r != taxonomy.Unranked && r < rank {
 				added[id] = true
 				delete(toAdd, id)
 				continue
@@ -143,13 +273,28 @@ func fillTax(tx *taxonomy.Taxonomy) error {
 				if added[sp.NubKey] {
 					continue
 				}
+				if acceptedFlag && sp.TaxonomicStatus != "ACCEPTED" {
+					continue
+				}
+				if lvl >= 0 && taxonomy.GetRank(sp.Rank) == taxonomy.Unranked && lvl >= infraFlag {
+					continue
+				}
 				toAdd[sp.NubKey] = true
 				tx.AddSpecies(sp)
 			}
+			if err := jr.Commit(key, tx.Recorded()...); err != nil {
+				return err
+			}
 			delete(toAdd, id)
 			added[id] = true
+
+			prog.report(len(added), len(toAdd), false)
+			if maxTaxa > 0 && len(added)+len(toAdd) > maxTaxa {
+				return fmt.Errorf("taxonomy with more than %d taxa", maxTaxa)
+			}
 		}
 	}
+	prog.report(len(added), 0, true)
 	return nil
 }
 
@@ -159,6 +304,10 @@ func children(id int64) ([]*gbif.Species, error) {
 		return nil, err
 	}
 
+	if acceptedFlag {
+		return ls, nil
+	}
+
 	syn, err := gbif.Synonym(id)
 	if err != nil {
 		return nil, err
@@ -166,3 +315,53 @@ func children(id int64) ([]*gbif.Species, error) {
 	ls = append(ls, syn...)
 	return ls, nil
 }
+
+// infraLevel returns the infraspecific level of a taxon
+// (0 for a species).
+// If the taxon is not at or below species level
+// it returns -1.
+func infraLevel(tx *taxonomy.Taxonomy, id int64) int {
+	for lvl := 0; ; lvl++ {
+		tax := tx.Taxon(id)
+		if tax.ID == 0 {
+			return -1
+		}
+		if tax.Rank == taxonomy.Species {
+			return lvl
+		}
+		if tax.Rank != taxonomy.Unranked {
+			return -1
+		}
+		id = tax.Parent
+	}
+}
+
+// A progress reports the progress
+// of the fill process.
+type progress struct {
+	w     io.Writer
+	start time.Time
+	last  time.Time
+}
+
+// report prints the progress
+// at most once per second,
+// or always, if final is true.
+func (p *progress) report(processed, queued int, final bool) {
+	if p == nil {
+		return
+	}
+	now := time.Now()
+	if !final && now.Sub(p.last) < time.Second {
+		return
+	}
+	p.last = now
+
+	elapsed := now.Sub(p.start)
+	fmt.Fprintf(p.w, "# fill: processed %d, queued %d, requests %d, elapsed %v", processed, queued, gbif.Requests(), elapsed.Round(time.Second))
+	if !final && processed > 0 {
+		eta := time.Duration(float64(elapsed) / float64(processed) * float64(queued))
+		fmt.Fprintf(p.w, ", eta %v", eta.Round(time.Second))
+	}
+	fmt.Fprintf(p.w, "\n")
+}
@@ -0,0 +1,348 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package iucn implements a command to retrieve
+// the IUCN Red List category
+// of the species in a taxonomy file.
+package iucn
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/journal"
+	"github.com/js-arias/gbifer/taxonomy"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `iucn [--occ <file>] [--journal <file>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "retrieve IUCN Red List categories",
+	Long: `
+Command iucn reads a taxonomy from the standard input and retrieves the IUCN
+Red List category of all the accepted species in the taxonomy, using the GBIF
+API. The categories are printed as a TSV file with the following columns:
+
+	- taxonKey: the GBIF ID of the species.
+	- name: the name of the species.
+	- iucnRedListCategory: the Red List category (for example
+	  "LEAST_CONCERN").
+	- code: the code of the category (for example "LC").
+
+Species without a Red List evaluation are printed with the category
+"NOT_EVALUATED" and the code "NE".
+
+If the flag --occ is defined, the indicated file will be read as a GBIF
+occurrence table, and instead of the table of categories, the occurrence
+table will be printed, with the additional column "iucnRedListCategory". The
+category of each occurrence is the category of its accepted species in the
+taxonomy, so records of synonyms or subspecies use the category of the
+species. Records without a species in the taxonomy will have an empty value.
+
+If the flag --journal is defined, the categories retrieved from GBIF will be
+recorded in the indicated file. If the command is interrupted (for example,
+by a network failure), running the command again with the same journal file
+will only retrieve the categories that were not retrieved before. A journal
+can only be used with the same taxonomy, and the journal file is removed when
+the command finishes successfully.
+
+By default, it will read the taxonomy from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+
+This command requires an internet connection.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var occFile string
+var journalFile string
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&occFile, "occ", "", "")
+	c.Flags().StringVar(&journalFile, "journal", "", "")
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+// notEvaluated is the category of species
+// without an evaluation.
+var notEvaluated = &gbif.RedList{
+	Category: "NOT_EVALUATED",
+	Code:     "NE",
+}
+
+func run(c *command.Command, args []string) (err error) {
+	tx, err := readTaxonomy(c.Stdin())
+	if err != nil {
+		return err
+	}
+
+	var jr *journal.Journal
+	if journalFile != "" {
+		var file string
+		if input != "stdin" {
+			file = input
+		}
+		hash, err := journal.Hash(file)
+		if err != nil {
+			return err
+		}
+		jr, err = journal.Open(journalFile, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	gbif.Open()
+	cats, err := categories(tx, jr)
+	if err != nil {
+		if jr != nil {
+			// the journal is only removed
+			// if the process finished successfully
+			jr.Close()
+		}
+		return err
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if occFile != "" {
+		f, err := os.Open(occFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if err := appendCategory(f, out, tx, cats); err != nil {
+			return err
+		}
+	} else if err := writeCategories(out, tx, cats); err != nil {
+		return err
+	}
+
+	if jr != nil {
+		if err := jr.Remove(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// categories returns the Red List categories
+// of the accepted species in the taxonomy.
+func categories(tx *taxonomy.Taxonomy, jr *journal.Journal) (map[int64]*gbif.RedList, error) {
+	cats := make(map[int64]*gbif.RedList)
+	for _, id := range tx.IDs() {
+		tax := tx.Taxon(id)
+		if tax.Status != "accepted" || tax.Rank != taxonomy.Species {
+			continue
+		}
+
+		key := strconv.FormatInt(id, 10)
+		if v, ok := jr.Values(key); ok {
+			if len(v) != 2 {
+				return nil, fmt.Errorf("journal %q: taxon %d: got %d values, want 2", journalFile, id, len(v))
+			}
+			cats[id] = &gbif.RedList{
+				Category: v[0],
+				Code:     v[1],
+				UsageKey: id,
+			}
+			continue
+		}
+
+		rl, err := gbif.IUCNCategory(id)
+		if err != nil {
+			return nil, err
+		}
+		if rl == nil {
+			rl = notEvaluated
+		}
+		if err := jr.Commit(key, rl.Category, rl.Code); err != nil {
+			return nil, err
+		}
+		cats[id] = rl
+	}
+	return cats, nil
+}
+
+func readTaxonomy(r io.Reader) (*taxonomy.Taxonomy, error) {
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	} else {
+		input = "stdin"
+	}
+
+	tx, err := taxonomy.Read(r)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", input, err)
+	}
+	return tx, nil
+}
+
+func writeCategories(w io.Writer, tx *taxonomy.Taxonomy, cats map[int64]*gbif.RedList) error {
+	tab := tsv.NewWriter(w)
+	tab.Comma = '\t'
+	tab.UseCRLF = true
+
+	if err := tab.Write([]string{"taxonKey", "name", "iucnRedListCategory", "code"}); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	for _, id := range tx.IDs() {
+		rl, ok := cats[id]
+		if !ok {
+			continue
+		}
+		row := []string{
+			strconv.FormatInt(id, 10),
+			tx.Taxon(id).Name,
+			rl.Category,
+			rl.Code,
+		}
+		if err := tab.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	tab.Flush()
+	if err := tab.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+func appendCategory(r io.Reader, w io.Writer, tx *taxonomy.Taxonomy, cats map[int64]*gbif.RedList) error {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return fmt.Errorf("when reading %q header: %v", occFile, err)
+	}
+
+	keyCol := -1
+	taxCol := -1
+	for i, h := range header {
+		h = strings.ToLower(h)
+		if h == "specieskey" {
+			keyCol = i
+		}
+		if h == "taxonkey" {
+			taxCol = i
+		}
+	}
+	if keyCol < 0 && taxCol < 0 {
+		return fmt.Errorf("input data %q without %q or %q fields", occFile, "speciesKey", "taxonKey")
+	}
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	header = append(header, "iucnRedListCategory")
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return fmt.Errorf("table %q: row %d: %v", occFile, ln, err)
+		}
+		logger.ReadRows(1)
+
+		var cat string
+		if id := rowKey(row, keyCol, taxCol); id != 0 {
+			if rl, ok := cats[speciesOf(tx, id)]; ok {
+				cat = rl.Category
+			}
+		}
+
+		row = append(row, cat)
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+// rowKey returns the taxon ID of an occurrence row,
+// or 0 if the row has no valid ID.
+func rowKey(row []string, keyCol, taxCol int) int64 {
+	var key string
+	if keyCol >= 0 {
+		key = row[keyCol]
+		if key == "" {
+			return 0
+		}
+	}
+	if taxCol >= 0 {
+		key = row[taxCol]
+	}
+	id, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// speciesOf returns the ID of the accepted species
+// of a taxon,
+// or 0 if the taxon is not at or below species level.
+func speciesOf(tx *taxonomy.Taxonomy, id int64) int64 {
+	tax := tx.AcceptedAndRanked(id)
+	if tax.Rank != taxonomy.Species {
+		return 0
+	}
+	return tax.ID
+}
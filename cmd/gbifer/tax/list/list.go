@@ -0,0 +1,125 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package list implements a command to display
+// a taxonomy as a tree.
+package list
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/taxonomy"
+)
+
+var Command = &command.Command{
+	Usage: `list [--accepted] [--depth <value>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "display a taxonomy as a tree",
+	Long: `
+Command list reads a taxonomy from the standard input and prints it as an
+indented tree. Each line contains the name of the taxon, its rank, its
+taxonomic status, and its GBIF ID.
+
+If the flag --accepted is defined, synonyms (and any other taxon without an
+accepted status) will not be displayed.
+
+By default, the whole tree is displayed. Use the flag --depth to limit the
+number of levels printed.
+
+By default, it will read the taxonomy from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var acceptedFlag bool
+var depthFlag int
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().BoolVar(&acceptedFlag, "accepted", false, "")
+	c.Flags().IntVar(&depthFlag, "depth", 0, "")
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	tx, err := readTaxonomy(c.Stdin())
+	if err != nil {
+		return err
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f *os.File
+		f, err = os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	bw := bufio.NewWriter(out)
+	for _, id := range tx.Roots() {
+		printTaxon(bw, tx, id, 0)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+func readTaxonomy(r io.Reader) (*taxonomy.Taxonomy, error) {
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	} else {
+		input = "stdin"
+	}
+
+	tx, err := taxonomy.Read(r)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", input, err)
+	}
+	return tx, nil
+}
+
+func printTaxon(w io.Writer, tx *taxonomy.Taxonomy, id int64, depth int) {
+	tax := tx.Taxon(id)
+	if acceptedFlag && tax.Status != "accepted" {
+		return
+	}
+
+	fmt.Fprintf(w, "%s%s [%s] %s %d\n", strings.Repeat("  ", depth), tax.Name, tax.Rank, tax.Status, tax.ID)
+	if depthFlag > 0 && depth+1 >= depthFlag {
+		return
+	}
+	for _, c := range tx.Children(id) {
+		printTaxon(w, tx, c, depth+1)
+	}
+}
@@ -7,21 +7,27 @@
 package match
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
 	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/journal"
 	"github.com/js-arias/gbifer/taxonomy"
 	"github.com/js-arias/gbifer/tsv"
 )
 
 var Command = &command.Command{
-	Usage: "match --file <file> [-i|--input <file>]",
+	Usage: `match --file <file> [--journal <file>] [--report <file>]
+	[--dry-run] [--backup] [-i|--input <file>]`,
 	Short: "match taxons to taxonomy",
 	Long: `
 Command match reads a taxonomy and a GBIF occurrence table and extracts the
@@ -34,6 +40,32 @@ By default, it will read the data from the standard input; use the flag
 --input, or -i, to select a particular file.
 
 This command requires an internet connection.
+
+If the flag --journal is defined, the progress of the command (i.e., the
+processed IDs, and the added taxa) will be stored in the indicated file. If
+the command is interrupted (for example, by a network failure), running the
+command again with the same journal file will resume the process where it was
+left off. A journal can only be used with the same input and taxonomy file,
+and the journal file is removed when the command finishes successfully.
+
+If the flag --report is defined, the keys of the occurrence table that do not
+match any taxon in the taxonomy will be written in the indicated file, as a
+TSV file with the following columns:
+
+	key      the unmatched key
+	field    the field of the key (either "speciesKey", or "taxonKey")
+	name     the name of the taxon (from the "species", or
+	         "scientificName" fields of the occurrence table)
+	records  the number of records with the key
+
+Rows are sorted by the number of records. These are the records that will be
+dropped when the occurrence table is filtered with the taxonomy.
+
+If the flag --dry-run is defined, the taxonomy file will not be modified, and
+the changes that would be made to the taxonomy will be printed in the standard
+output. If the flag --backup is defined, before writing the taxonomy file,
+the previous version of the file will be kept, renamed with a timestamp (for
+example "taxonomy.tab.20231016-150405.bak").
 	`,
 	SetFlags: setFlags,
 	Run:      run,
@@ -41,11 +73,19 @@ This command requires an internet connection.
 
 var input string
 var taxFile string
+var journalFile string
+var reportFile string
+var dryRun bool
+var backupFlag bool
 
 func setFlags(c *command.Command) {
 	c.Flags().StringVar(&input, "input", "", "")
 	c.Flags().StringVar(&input, "i", "", "")
 	c.Flags().StringVar(&taxFile, "file", "", "")
+	c.Flags().StringVar(&journalFile, "journal", "", "")
+	c.Flags().StringVar(&reportFile, "report", "", "")
+	c.Flags().BoolVar(&dryRun, "dry-run", false, "")
+	c.Flags().BoolVar(&backupFlag, "backup", false, "")
 }
 
 func run(c *command.Command, args []string) (err error) {
@@ -53,6 +93,13 @@ func run(c *command.Command, args []string) (err error) {
 	if err != nil {
 		return err
 	}
+	var old *taxonomy.Taxonomy
+	if dryRun {
+		old, err = readTaxonomy()
+		if err != nil {
+			return err
+		}
+	}
 	gbif.Open()
 
 	in := c.Stdin()
@@ -67,11 +114,60 @@ func run(c *command.Command, args []string) (err error) {
 		input = "stdin"
 	}
 
-	if err := readTable(in, tx); err != nil {
+	var jr *journal.Journal
+	if journalFile != "" && !dryRun {
+		var file string
+		if input != "stdin" {
+			file = input
+		}
+		var hash string
+		hash, err = journal.Hash(file, taxFile)
+		if err != nil {
+			return err
+		}
+		jr, err = journal.Open(journalFile, hash)
+		if err != nil {
+			return err
+		}
+		tx.Record()
+		defer func() {
+			// the journal is only removed
+			// if the command was successful.
+			if err != nil {
+				jr.Close()
+				return
+			}
+			err = jr.Remove()
+		}()
+	}
+
+	var rep map[int64]*unmatched
+	if reportFile != "" {
+		rep = make(map[int64]*unmatched)
+	}
+	if err := readTable(in, jr, rep, tx); err != nil {
 		return err
 	}
 	tx.Stage()
 
+	if rep != nil {
+		if err := writeReport(rep); err != nil {
+			return err
+		}
+	}
+
+	if dryRun {
+		for _, ch := range taxonomy.Compare(old, tx) {
+			fmt.Fprintf(c.Stdout(), "%s\n", ch)
+		}
+		return nil
+	}
+
+	if backupFlag {
+		if err := tabfile.Backup(taxFile); err != nil {
+			return err
+		}
+	}
 	var f *os.File
 	f, err = os.Create(taxFile)
 	if err != nil {
@@ -105,8 +201,17 @@ func readTaxonomy() (*taxonomy.Taxonomy, error) {
 	return tx, nil
 }
 
-func readTable(r io.Reader, tx *taxonomy.Taxonomy) error {
-	tab := tsv.NewReader(r)
+// An unmatched is an occurrence key
+// without a match in the taxonomy.
+type unmatched struct {
+	key     int64
+	field   string
+	name    string
+	records int
+}
+
+func readTable(r io.Reader, jr *journal.Journal, rep map[int64]*unmatched, tx *taxonomy.Taxonomy) error {
+	tab := tabfile.NewReader(r, "speciesKey", "taxonKey", "species", "scientificName")
 	tab.Comma = '\t'
 
 	header, err := tab.Read()
@@ -116,6 +221,8 @@ func readTable(r io.Reader, tx *taxonomy.Taxonomy) error {
 
 	keyCol := -1
 	taxCol := -1
+	spCol := -1
+	nameCol := -1
 	for i, h := range header {
 		h = strings.ToLower(h)
 		if h == "specieskey" {
@@ -124,10 +231,23 @@ func readTable(r io.Reader, tx *taxonomy.Taxonomy) error {
 		if h == "taxonkey" {
 			taxCol = i
 		}
+		if h == "species" {
+			spCol = i
+		}
+		if h == "scientificname" {
+			nameCol = i
+		}
 	}
 	if keyCol < 0 && taxCol < 0 {
 		return fmt.Errorf("input data %q without %q or %q fields", input, "speciesKey", "taxonKey")
 	}
+	field := "speciesKey"
+	if taxCol >= 0 {
+		field = "taxonKey"
+		spCol = nameCol
+	} else if spCol < 0 {
+		spCol = nameCol
+	}
 
 	unMatch := make(map[int64]bool)
 	for {
@@ -139,6 +259,7 @@ func readTable(r io.Reader, tx *taxonomy.Taxonomy) error {
 		if err != nil {
 			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
 		}
+		logger.ReadRows(1)
 
 		var key string
 		if keyCol >= 0 {
@@ -158,13 +279,38 @@ func readTable(r io.Reader, tx *taxonomy.Taxonomy) error {
 			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
 		}
 
-		ls, err := searchID(id, tx, unMatch)
-		if err != nil {
-			return err
+		if values, ok := jr.Values(key); ok {
+			if _, err := tx.Restore(values); err != nil {
+				return fmt.Errorf("journal %q: key %q: %v", journalFile, key, err)
+			}
+		} else {
+			ls, err := searchID(id, tx, unMatch)
+			if err != nil {
+				return err
+			}
+			for _, sp := range ls {
+				tx.AddSpecies(sp)
+			}
+			if err := jr.Commit(key, tx.Recorded()...); err != nil {
+				return err
+			}
+		}
+
+		if rep == nil || tx.Taxon(id).ID == id {
+			continue
+		}
+		u, ok := rep[id]
+		if !ok {
+			u = &unmatched{
+				key:   id,
+				field: field,
+			}
+			rep[id] = u
 		}
-		for _, sp := range ls {
-			tx.AddSpecies(sp)
+		if u.name == "" && spCol >= 0 {
+			u.name = row[spCol]
 		}
+		u.records++
 	}
 
 	return nil
@@ -215,3 +361,52 @@ func searchID(id int64, tx *taxonomy.Taxonomy, unMatch map[int64]bool) ([]*gbif.
 	}
 	return nil, nil
 }
+
+func writeReport(rep map[int64]*unmatched) (err error) {
+	ls := make([]*unmatched, 0, len(rep))
+	for _, u := range rep {
+		ls = append(ls, u)
+	}
+	slices.SortFunc(ls, func(a, b *unmatched) int {
+		if c := cmp.Compare(b.records, a.records); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.key, b.key)
+	})
+
+	f, err := os.Create(reportFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		e := f.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	w := tsv.NewWriter(f)
+	w.Comma = '\t'
+	w.UseCRLF = true
+
+	if err := w.Write([]string{"key", "field", "name", "records"}); err != nil {
+		return fmt.Errorf("when writing on %q: %v", reportFile, err)
+	}
+	for _, u := range ls {
+		row := []string{
+			strconv.FormatInt(u.key, 10),
+			u.field,
+			u.name,
+			strconv.Itoa(u.records),
+		}
+		if err := w.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", reportFile, err)
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", reportFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package namemap implements a command to map
+// the names of an external dataset
+// to the accepted names of a taxonomy.
+package namemap
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/taxonomy"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `namemap --file <file> [--distance <value>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "map a list of names to a taxonomy",
+	Long: `
+Command namemap reads a list of names (for example, the terminals of a
+phylogeny) from the standard input, and maps each name to the accepted names
+of a taxonomy. This is the step required to harmonize the names of an external
+dataset with the names used in a GBIF occurrence table.
+
+A taxonomy file is required and must be defined with the flag --file.
+
+The list of names is a text file with a name per line. Empty lines, and lines
+starting with '#', are ignored. Underscores are read as spaces, so names like
+"Puma_concolor" are also accepted.
+
+The output is a TSV table with the following columns:
+
+	name         the name in the list
+	matched      the name matched in the taxonomy
+	taxonKey     the GBIF ID of the matched name
+	accepted     the accepted name
+	acceptedKey  the GBIF ID of the accepted name
+	match        the type of the match
+
+The types of matches are:
+
+	exact      the name is an accepted name in the taxonomy
+	synonym    the name is a synonym in the taxonomy
+	fuzzy      the name is similar to a name in the taxonomy
+	ambiguous  the name resolves to more than one accepted name
+	unmatched  the name is not in the taxonomy
+
+By default, only exact names are matched. Use the flag --distance to define
+the maximum number of edits (insertions, deletions, or substitutions of
+characters) allowed for fuzzy matches; the nearest name will be used. Fuzzy
+matches should be always checked by hand.
+
+By default, it will read the list of names from the standard input; use the
+flag --input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var distance int
+var input string
+var output string
+var taxFile string
+
+func setFlags(c *command.Command) {
+	c.Flags().IntVar(&distance, "distance", 0, "")
+	c.Flags().StringVar(&taxFile, "file", "", "")
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if taxFile == "" {
+		return c.UsageError("flag --file undefined")
+	}
+	if distance < 0 {
+		return c.UsageError(fmt.Sprintf("invalid --distance value %d", distance))
+	}
+	tx, err := readTaxonomy()
+	if err != nil {
+		return err
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := mapNames(in, out, tx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func readTaxonomy() (*taxonomy.Taxonomy, error) {
+	f, err := os.Open(taxFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tx, err := taxonomy.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", taxFile, err)
+	}
+	return tx, nil
+}
+
+func mapNames(r io.Reader, w io.Writer, tx *taxonomy.Taxonomy) error {
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	header := []string{
+		"name",
+		"matched",
+		"taxonKey",
+		"accepted",
+		"acceptedKey",
+		"match",
+	}
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		name := strings.TrimSpace(s.Text())
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+
+		m := tx.MatchName(name, distance)
+		var id, accID string
+		if m.ID != 0 {
+			id = strconv.FormatInt(m.ID, 10)
+		}
+		if m.Accepted.ID != 0 {
+			accID = strconv.FormatInt(m.Accepted.ID, 10)
+		}
+		row := []string{
+			m.Name,
+			m.Matched,
+			id,
+			m.Accepted.Name,
+			accID,
+			m.Type,
+		}
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("when reading %q: %v", input, err)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
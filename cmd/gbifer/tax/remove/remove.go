@@ -0,0 +1,148 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package remove implements a command to remove taxa
+// from a taxonomy file
+// using their GBIF IDs.
+package remove
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/taxonomy"
+)
+
+var Command = &command.Command{
+	Usage: `remove --file <file> [--ids <file>] [<id>...]`,
+	Short: "remove taxa by ID",
+	Long: `
+Command remove removes taxa from a taxonomy file using their GBIF IDs. When a
+taxon is removed, all of its descendants (including synonyms) are also
+removed.
+
+The taxonomy file is required and must be defined with the flag --file. The
+file will be overwritten with the resulting taxonomy.
+
+The arguments of the command are the IDs of the taxa to be removed. If the
+flag --ids is defined, the IDs will be read from the indicated file, with an
+ID per line. Lines starting with '#' and blank lines are ignored.
+
+IDs not found in the taxonomy (for example, the ID of a taxon already removed
+as a descendant of another removed taxon) are ignored.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var taxFile string
+var idsFile string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&taxFile, "file", "", "")
+	c.Flags().StringVar(&idsFile, "ids", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if taxFile == "" {
+		return c.UsageError("expecting taxonomy file, flag --file")
+	}
+
+	var ids []int64
+	if idsFile != "" {
+		ids, err = readIDs(idsFile)
+		if err != nil {
+			return err
+		}
+	}
+	for _, a := range args {
+		id, err := strconv.ParseInt(a, 10, 64)
+		if err != nil {
+			return c.UsageError(fmt.Sprintf("invalid ID %q", a))
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return c.UsageError("expecting taxon IDs")
+	}
+
+	tx, err := readTaxonomy()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if tx.Taxon(id).ID == 0 {
+			continue
+		}
+		if err := tx.Remove(id); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Create(taxFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		e := f.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	if err := tx.Write(f); err != nil {
+		return fmt.Errorf("when writing to %q: %v", taxFile, err)
+	}
+	return nil
+}
+
+func readTaxonomy() (*taxonomy.Taxonomy, error) {
+	f, err := os.Open(taxFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tx, err := taxonomy.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", taxFile, err)
+	}
+	return tx, nil
+}
+
+func readIDs(name string) ([]int64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("ID file %q: %v", name, err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var ids []int64
+	for i := 1; ; i++ {
+		ln, err := r.ReadString('\n')
+		if err != nil && len(ln) == 0 {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("on file %q: line %d: %v", name, i, err)
+		}
+		ln = strings.TrimSpace(ln)
+		if ln == "" || strings.HasPrefix(ln, "#") {
+			continue
+		}
+		id, err := strconv.ParseInt(ln, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("on file %q: line %d: %v", name, i, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
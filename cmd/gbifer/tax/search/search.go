@@ -0,0 +1,160 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package search implements a command to search
+// a taxon name in GBIF.
+package search
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/taxonomy"
+)
+
+var Command = &command.Command{
+	Usage: `search [--file <file>] [--add <key>] [--rank <rank>]
+	<name>...`,
+	Short: "search a taxon name in GBIF",
+	Long: `
+Command search queries GBIF for a taxon name and prints the candidate usages
+found in the GBIF backbone, with their key, rank, taxonomic status, accepted
+key, and classification.
+
+The arguments of the command are the words of the taxon name.
+
+To add one of the candidates to a taxonomy file, use the flag --file with the
+name of the taxonomy file, and the flag --add with the GBIF key of the chosen
+usage (i.e., the key printed for the candidate, which is the key of the usage
+in the GBIF backbone). The taxon will be added along with its parents up to
+the genus rank; to use another rank, use the flag --rank with one of the
+following values:
+
+	unranked
+	kingdom
+	phylum
+	class
+	order
+	family
+	genus
+	species
+
+This command requires an internet connection.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var addKey int64
+var taxFile string
+var rankFlag string
+
+func setFlags(c *command.Command) {
+	c.Flags().Int64Var(&addKey, "add", 0, "")
+	c.Flags().StringVar(&rankFlag, "rank", taxonomy.Genus.String(), "")
+	c.Flags().StringVar(&taxFile, "file", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	name := strings.Join(args, " ")
+	if name == "" {
+		return c.UsageError("expecting taxon name")
+	}
+	if addKey != 0 && taxFile == "" {
+		return c.UsageError("flag --add requires a taxonomy file, flag --file")
+	}
+
+	gbif.Open()
+	ls, err := gbif.TaxonName(name)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, sp := range ls {
+		printSpecies(c.Stdout(), sp)
+		if sp.NubKey == addKey {
+			found = true
+		}
+	}
+	if len(ls) == 0 {
+		logger.Printf("taxon name %q not found", taxonomy.Canon(name))
+	}
+	if addKey == 0 {
+		return nil
+	}
+	if !found {
+		return fmt.Errorf("key %d is not a candidate for name %q", addKey, taxonomy.Canon(name))
+	}
+
+	tx, err := readTaxonomy()
+	if err != nil {
+		return err
+	}
+	if rankFlag == "" {
+		rankFlag = taxonomy.Genus.String()
+	}
+	if err := tx.AddFromGBIF(addKey, taxonomy.GetRank(rankFlag)); err != nil {
+		return err
+	}
+	tx.Stage()
+
+	f, err := os.Create(taxFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		e := f.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	if err := tx.Write(f); err != nil {
+		return fmt.Errorf("when writing to %q: %v", taxFile, err)
+	}
+	return nil
+}
+
+func readTaxonomy() (*taxonomy.Taxonomy, error) {
+	f, err := os.Open(taxFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return taxonomy.NewTaxonomy(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tx, err := taxonomy.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", taxFile, err)
+	}
+	return tx, nil
+}
+
+func printSpecies(w io.Writer, sp *gbif.Species) {
+	fmt.Fprintf(w, "%d\t%s\t%s\t%s", sp.NubKey, sp.ScientificName, strings.ToLower(sp.Rank), strings.ToLower(sp.TaxonomicStatus))
+	if sp.AcceptedKey != 0 {
+		fmt.Fprintf(w, "\taccepted: %d", sp.AcceptedKey)
+	}
+	fmt.Fprintf(w, "\n")
+
+	var class []string
+	for _, n := range []string{sp.Kingdom, sp.Phylum, sp.Class, sp.Order, sp.Family, sp.Genus} {
+		if n == "" {
+			continue
+		}
+		class = append(class, n)
+	}
+	if len(class) > 0 {
+		fmt.Fprintf(w, "\t%s\n", strings.Join(class, " > "))
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package search_test
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/gbifer/cmd/gbifer/tax/search"
+	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/taxonomy"
+)
+
+func TestSearchAdd(t *testing.T) {
+	gbif.Client = &http.Client{Transport: gbif.NewReplayer("testdata/gbif")}
+	gbif.Wait = 0
+
+	dir := t.TempDir()
+	tests := map[string]struct {
+		key  string
+		err  bool
+		want []int64
+	}{
+		"backbone key": {
+			key:  "2435099",
+			want: []int64{2435098, 2435099},
+		},
+		"not a candidate": {
+			key: "2435098",
+			err: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(dir, strings.ReplaceAll(name, " ", "-")+".tab")
+
+			var buf bytes.Buffer
+			search.Command.SetStdout(&buf)
+			err := search.Command.Execute([]string{"--file", file, "--add", test.key, "Puma", "concolor"})
+			if test.err {
+				if err == nil {
+					t.Errorf("expecting error")
+				}
+				if _, err := os.Stat(file); err == nil {
+					t.Errorf("taxonomy file %q should not be created", file)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.HasPrefix(buf.String(), "2435099\t") {
+				t.Errorf("output: got %q, want the candidate %d", buf.String(), 2435099)
+			}
+
+			f, err := os.Open(file)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer f.Close()
+			tx, err := taxonomy.Read(f)
+			if err != nil {
+				t.Fatalf("taxonomy: unexpected error: %v", err)
+			}
+			ids := tx.IDs()
+			if len(ids) != len(test.want) {
+				t.Fatalf("got %d taxa, want %d", len(ids), len(test.want))
+			}
+			for _, id := range test.want {
+				if tx.Taxon(id).ID != id {
+					t.Errorf("taxon %d: not found", id)
+				}
+			}
+		})
+	}
+}
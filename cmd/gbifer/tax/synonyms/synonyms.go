@@ -0,0 +1,169 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package synonyms implements a command to print
+// a table of synonyms and accepted names
+// from a taxonomy file.
+package synonyms
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/taxonomy"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `synonyms [--all]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "print a synonym to accepted name table",
+	Long: `
+Command synonyms reads a taxonomy from the standard input and prints a table
+that maps each synonym to its accepted name. The table has the following
+columns:
+
+	- name: the name of the synonym.
+	- taxonKey: the GBIF ID of the synonym.
+	- accepted: the accepted name.
+	- acceptedKey: the GBIF ID of the accepted name.
+
+The table is sorted by the synonym name.
+
+If the flag --all is defined, the accepted names will be also included,
+mapped to themselves, so the table can be used to harmonize all the names in
+an external dataset.
+
+By default, it will read the taxonomy from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var allFlag bool
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().BoolVar(&allFlag, "all", false, "")
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	tx, err := readTaxonomy(c.Stdin())
+	if err != nil {
+		return err
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := writeSynonyms(out, tx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func readTaxonomy(r io.Reader) (*taxonomy.Taxonomy, error) {
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	} else {
+		input = "stdin"
+	}
+
+	tx, err := taxonomy.Read(r)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", input, err)
+	}
+	return tx, nil
+}
+
+func writeSynonyms(w io.Writer, tx *taxonomy.Taxonomy) error {
+	var syn []taxonomy.Taxon
+	for _, id := range tx.IDs() {
+		tax := tx.Taxon(id)
+		if tax.Status == "accepted" && !allFlag {
+			continue
+		}
+		syn = append(syn, tax)
+	}
+	slices.SortFunc(syn, func(a, b taxonomy.Taxon) int {
+		if c := cmp.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	header := []string{
+		"name",
+		"taxonKey",
+		"accepted",
+		"acceptedKey",
+	}
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	for _, tax := range syn {
+		acc := tx.Accepted(tax.ID)
+		var accID string
+		if acc.ID != 0 {
+			accID = strconv.FormatInt(acc.ID, 10)
+		}
+		row := []string{
+			tax.Name,
+			strconv.FormatInt(tax.ID, 10),
+			acc.Name,
+			accID,
+		}
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
@@ -9,8 +9,21 @@ package tax
 import (
 	"github.com/js-arias/command"
 	"github.com/js-arias/gbifer/cmd/gbifer/tax/add"
+	"github.com/js-arias/gbifer/cmd/gbifer/tax/countries"
+	"github.com/js-arias/gbifer/cmd/gbifer/tax/diff"
+	"github.com/js-arias/gbifer/cmd/gbifer/tax/edit"
+	"github.com/js-arias/gbifer/cmd/gbifer/tax/export"
 	"github.com/js-arias/gbifer/cmd/gbifer/tax/fill"
+	"github.com/js-arias/gbifer/cmd/gbifer/tax/iucn"
+	"github.com/js-arias/gbifer/cmd/gbifer/tax/list"
 	"github.com/js-arias/gbifer/cmd/gbifer/tax/match"
+	"github.com/js-arias/gbifer/cmd/gbifer/tax/namemap"
+	"github.com/js-arias/gbifer/cmd/gbifer/tax/remove"
+	"github.com/js-arias/gbifer/cmd/gbifer/tax/search"
+	"github.com/js-arias/gbifer/cmd/gbifer/tax/synonyms"
+	"github.com/js-arias/gbifer/cmd/gbifer/tax/update"
+	"github.com/js-arias/gbifer/cmd/gbifer/tax/validate"
+	"github.com/js-arias/gbifer/cmd/gbifer/tax/vernacular"
 )
 
 var Command = &command.Command{
@@ -20,6 +33,19 @@ var Command = &command.Command{
 
 func init() {
 	Command.Add(add.Command)
+	Command.Add(countries.Command)
+	Command.Add(diff.Command)
+	Command.Add(edit.Command)
+	Command.Add(export.Command)
 	Command.Add(fill.Command)
+	Command.Add(iucn.Command)
+	Command.Add(list.Command)
 	Command.Add(match.Command)
+	Command.Add(namemap.Command)
+	Command.Add(remove.Command)
+	Command.Add(search.Command)
+	Command.Add(synonyms.Command)
+	Command.Add(update.Command)
+	Command.Add(validate.Command)
+	Command.Add(vernacular.Command)
 }
@@ -0,0 +1,482 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package update implements a command to update
+// the taxa of a taxonomy file
+// after a new release of the GBIF backbone.
+package update
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/journal"
+	"github.com/js-arias/gbifer/taxonomy"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `update --file <file> [--backbone <path>] [--map <file>]
+	[--checklist <key>] [--journal <file>] [-o|--output <file>]`,
+	Short: "update taxa after a backbone release",
+	Long: `
+Command update reads a taxonomy file and searches each taxon ID in GBIF, to
+detect the changes produced by a new release of the GBIF backbone. The
+taxonomy file is required and must be defined with the flag --file. The file
+will be overwritten with the updated taxonomy.
+
+The following changes are detected and applied:
+
+	- merged: the ID is now a different ID in GBIF (for example, two
+	  usages were merged). The taxon is updated to the new ID. If the new
+	  ID is already in the taxonomy, the children of the taxon will be
+	  moved to the taxon with the new ID.
+	- rekeyed: the ID was deleted from GBIF, but the name of the taxon is
+	  found with a different ID. The taxon is updated to the new ID.
+	- deleted: the ID was deleted from GBIF, and the name of the taxon
+	  can not be resolved. The taxon is kept unchanged.
+	- renamed: the taxon has a different name in GBIF.
+	- status: the taxon has a different taxonomic status in GBIF.
+
+The changes are printed as a TSV table with the following columns:
+
+	- change: the type of change.
+	- taxonKey: the GBIF ID of the taxon (the updated ID, if it was
+	  changed).
+	- name: the name of the taxon.
+	- old: the old value.
+	- new: the new value.
+
+By default, the changes are printed in the standard output; use the flag
+--output, or -o, to define an output file.
+
+If the flag --map is defined, the IDs that were changed will be written in
+the indicated file, as a TSV file with the columns "oldKey" and "newKey". This
+file can be used to update the keys of an occurrence table.
+
+By default, the taxa are retrieved from the GBIF API, so this command
+requires an internet connection. If the flag --backbone is defined, the taxa
+will be retrieved from a local copy of the GBIF backbone. The value can be the
+backbone Darwin Core Archive (a zip file), a directory with the uncompressed
+archive, or a taxon table with the same columns of the backbone Taxon.tsv file
+(for example a subset of the backbone).
+
+If the flag --checklist is defined, the taxa will be retrieved from the
+indicated GBIF checklist dataset (for example, a particular version of a
+published catalogue), instead of the current GBIF backbone, so the taxonomy
+can be reproduced later. The value is the dataset key of the checklist, and
+the IDs of the taxonomy will be the IDs of the taxa in that checklist. The
+flag --checklist can not be used with the flag --backbone (to use a
+particular release of the backbone, use a local copy of that release).
+
+If the flag --journal is defined, the taxa retrieved from GBIF will be
+recorded in the indicated file. If the command is interrupted (for example,
+by a network failure), running the command again with the same journal file
+will only retrieve the taxa that were not retrieved before. A journal can only
+be used with the same taxonomy file and backbone, and the journal file is
+removed when the command finishes successfully.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var taxFile string
+var mapFile string
+var backboneFile string
+var checklist string
+var output string
+var journalFile string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&taxFile, "file", "", "")
+	c.Flags().StringVar(&mapFile, "map", "", "")
+	c.Flags().StringVar(&backboneFile, "backbone", "", "")
+	c.Flags().StringVar(&checklist, "checklist", "", "")
+	c.Flags().StringVar(&journalFile, "journal", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+// A change is a change in a taxon.
+type change struct {
+	kind string
+	id   int64
+	name string
+	old  string
+	new  string
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if checklist != "" && backboneFile != "" {
+		return c.UsageError("flags --checklist and --backbone can not be used together")
+	}
+	if taxFile == "" {
+		return c.UsageError("flag --file undefined")
+	}
+
+	tx, err := readTaxonomy()
+	if err != nil {
+		return err
+	}
+
+	if backboneFile != "" {
+		if err := gbif.OpenBackbone(backboneFile); err != nil {
+			return err
+		}
+	} else {
+		gbif.Checklist = checklist
+		gbif.Open()
+	}
+
+	var jr *journal.Journal
+	if journalFile != "" {
+		hash, err := journal.Hash(taxFile, backboneFile, checklist)
+		if err != nil {
+			return err
+		}
+		jr, err = journal.Open(journalFile, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	changes, keys, err := update(tx, jr)
+	if err != nil {
+		if jr != nil {
+			// the journal is only removed
+			// if the process finished successfully
+			jr.Close()
+		}
+		return err
+	}
+
+	if err := writeTaxonomy(tx); err != nil {
+		return err
+	}
+	if jr != nil {
+		// the taxonomy file was updated,
+		// so the journal is no longer valid
+		if err := jr.Remove(); err != nil {
+			return err
+		}
+	}
+	if mapFile != "" {
+		if err := writeMap(keys); err != nil {
+			return err
+		}
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f *os.File
+		f, err = os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := writeChanges(out, changes); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+func readTaxonomy() (*taxonomy.Taxonomy, error) {
+	f, err := os.Open(taxFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tx, err := taxonomy.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", taxFile, err)
+	}
+	return tx, nil
+}
+
+func writeTaxonomy(tx *taxonomy.Taxonomy) (err error) {
+	f, err := os.Create(taxFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		e := f.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	if err := tx.Write(f); err != nil {
+		return fmt.Errorf("when writing to %q: %v", taxFile, err)
+	}
+	return nil
+}
+
+// A keyPair is an old ID
+// and its new ID.
+type keyPair struct {
+	old int64
+	new int64
+}
+
+func update(tx *taxonomy.Taxonomy, jr *journal.Journal) ([]change, []keyPair, error) {
+	var changes []change
+	var keys []keyPair
+	for _, id := range tx.IDs() {
+		tax := tx.Taxon(id)
+		if tax.ID == 0 {
+			// already merged
+			continue
+		}
+
+		sp, found, err := lookup(tax, jr)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !found {
+			if sp == nil {
+				changes = append(changes, change{
+					kind: "deleted",
+					id:   id,
+					name: tax.Name,
+					old:  strconv.FormatInt(id, 10),
+				})
+				continue
+			}
+			newID := key(sp)
+			if err := tx.Rekey(id, newID); err != nil {
+				return nil, nil, err
+			}
+			changes = append(changes, change{
+				kind: "rekeyed",
+				id:   newID,
+				name: tax.Name,
+				old:  strconv.FormatInt(id, 10),
+				new:  strconv.FormatInt(newID, 10),
+			})
+			keys = append(keys, keyPair{old: id, new: newID})
+			id = newID
+		} else if newID := key(sp); newID != id {
+			if err := tx.Rekey(id, newID); err != nil {
+				return nil, nil, err
+			}
+			changes = append(changes, change{
+				kind: "merged",
+				id:   newID,
+				name: tax.Name,
+				old:  strconv.FormatInt(id, 10),
+				new:  strconv.FormatInt(newID, 10),
+			})
+			keys = append(keys, keyPair{old: id, new: newID})
+			id = newID
+		}
+
+		// the taxon was merged
+		// into an existing taxon
+		if tx.Taxon(id).Name != tax.Name {
+			continue
+		}
+
+		name := sp.CanonicalName
+		if name == "" {
+			name = sp.Species
+		}
+		if name != "" && taxonomy.Canon(name) != tax.Name {
+			if err := tx.Rename(id, name); err != nil {
+				return nil, nil, err
+			}
+			changes = append(changes, change{
+				kind: "renamed",
+				id:   id,
+				name: taxonomy.Canon(name),
+				old:  tax.Name,
+				new:  taxonomy.Canon(name),
+			})
+			name = taxonomy.Canon(name)
+		} else {
+			name = tax.Name
+		}
+
+		status := strings.ToLower(sp.TaxonomicStatus)
+		if status != "" && status != tax.Status {
+			if err := tx.SetStatus(id, status); err != nil {
+				return nil, nil, err
+			}
+			changes = append(changes, change{
+				kind: "status",
+				id:   id,
+				name: name,
+				old:  tax.Status,
+				new:  status,
+			})
+		}
+	}
+	return changes, keys, nil
+}
+
+// lookup searches a taxon in GBIF.
+// Found is false if the ID of the taxon is no longer in GBIF,
+// in which case the taxon is searched by its name
+// (and sp is nil if the name can not be resolved).
+// If the taxon is in the journal,
+// the values stored in the journal are returned.
+func lookup(tax taxonomy.Taxon, jr *journal.Journal) (sp *gbif.Species, found bool, err error) {
+	id := strconv.FormatInt(tax.ID, 10)
+
+	// a taxon is stored as the search type
+	// ("id" or "name"),
+	// and the key, name, and status of the GBIF taxon.
+	// A deleted taxon is stored without values.
+	if v, ok := jr.Values(id); ok {
+		if len(v) == 0 {
+			return nil, false, nil
+		}
+		if len(v) != 4 {
+			return nil, false, fmt.Errorf("journal %q: taxon %s: got %d values, want 4", journalFile, id, len(v))
+		}
+		key, err := strconv.ParseInt(v[1], 10, 64)
+		if err != nil {
+			return nil, false, fmt.Errorf("journal %q: taxon %s: %v", journalFile, id, err)
+		}
+		sp := &gbif.Species{
+			Key:             key,
+			CanonicalName:   v[2],
+			TaxonomicStatus: v[3],
+		}
+		return sp, v[0] == "id", nil
+	}
+
+	search := "id"
+	sp, err = gbif.SpeciesID(id)
+	if errors.Is(err, gbif.ErrNotFound) {
+		search = "name"
+		sp, err = byName(tax)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if sp == nil {
+		if err := jr.Commit(id); err != nil {
+			return nil, false, err
+		}
+		return nil, false, nil
+	}
+
+	name := sp.CanonicalName
+	if name == "" {
+		name = sp.Species
+	}
+	if err := jr.Commit(id, search, strconv.FormatInt(key(sp), 10), name, sp.TaxonomicStatus); err != nil {
+		return nil, false, err
+	}
+	return sp, search == "id", nil
+}
+
+// byName search a taxon by its name.
+// It returns nil
+// if the name is not found,
+// or it is ambiguous.
+func byName(tax taxonomy.Taxon) (*gbif.Species, error) {
+	ls, err := gbif.TaxonName(tax.Name)
+	if err != nil {
+		return nil, err
+	}
+	var found *gbif.Species
+	for _, sp := range ls {
+		if taxonomy.GetRank(sp.Rank) != tax.Rank {
+			continue
+		}
+		if found != nil {
+			return nil, nil
+		}
+		found = sp
+	}
+	return found, nil
+}
+
+func key(sp *gbif.Species) int64 {
+	if sp.NubKey != 0 {
+		return sp.NubKey
+	}
+	return sp.Key
+}
+
+func writeChanges(w io.Writer, changes []change) error {
+	tab := tsv.NewWriter(w)
+	tab.Comma = '\t'
+	tab.UseCRLF = true
+
+	if err := tab.Write([]string{"change", "taxonKey", "name", "old", "new"}); err != nil {
+		return err
+	}
+	for _, c := range changes {
+		row := []string{
+			c.kind,
+			strconv.FormatInt(c.id, 10),
+			c.name,
+			c.old,
+			c.new,
+		}
+		if err := tab.Write(row); err != nil {
+			return err
+		}
+		logger.WriteRows(1)
+	}
+
+	tab.Flush()
+	return tab.Error()
+}
+
+func writeMap(keys []keyPair) (err error) {
+	f, err := os.Create(mapFile)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		e := f.Close()
+		if e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	tab := tsv.NewWriter(f)
+	tab.Comma = '\t'
+	tab.UseCRLF = true
+
+	if err := tab.Write([]string{"oldKey", "newKey"}); err != nil {
+		return fmt.Errorf("when writing on %q: %v", mapFile, err)
+	}
+	for _, k := range keys {
+		row := []string{
+			strconv.FormatInt(k.old, 10),
+			strconv.FormatInt(k.new, 10),
+		}
+		if err := tab.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", mapFile, err)
+		}
+	}
+
+	tab.Flush()
+	if err := tab.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", mapFile, err)
+	}
+	return nil
+}
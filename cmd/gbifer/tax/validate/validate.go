@@ -0,0 +1,84 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package validate implements a command to check
+// the consistency of a taxonomy file.
+package validate
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/taxonomy"
+)
+
+var Command = &command.Command{
+	Usage: "validate [-i|--input <file>]",
+	Short: "check the consistency of a taxonomy",
+	Long: `
+Command validate reads a taxonomy from the standard input and checks its
+consistency. The following problems are reported:
+
+	- broken parent: the parent of the taxon is not in the taxonomy.
+	- parent cycle: the taxon is its own ancestor.
+	- rank inversion: an accepted taxon has a rank equal to or more
+	  inclusive than the rank of its parent.
+	- dangling synonym: a taxon that is not accepted, and is not
+	  associated with any accepted taxon.
+	- duplicated name: two or more accepted taxa have the same name.
+
+Each problem is printed in the standard output, along with the row of the
+taxon in the taxonomy file. If any problem is found, the command ends with an
+error.
+
+By default, it will read the taxonomy from the standard input; use the flag
+--input, or -i, to select a particular file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+}
+
+func run(c *command.Command, args []string) error {
+	tx, err := readTaxonomy(c.Stdin())
+	if err != nil {
+		return err
+	}
+
+	ps := tx.Validate()
+	for _, p := range ps {
+		fmt.Fprintf(c.Stdout(), "%s\n", p)
+	}
+	if len(ps) > 0 {
+		return fmt.Errorf("taxonomy %q: found %d problems", input, len(ps))
+	}
+	return nil
+}
+
+func readTaxonomy(r io.Reader) (*taxonomy.Taxonomy, error) {
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	} else {
+		input = "stdin"
+	}
+
+	tx, err := taxonomy.Read(r)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", input, err)
+	}
+	return tx, nil
+}
@@ -0,0 +1,270 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package vernacular implements a command to retrieve
+// the vernacular names of the taxa in a taxonomy file.
+package vernacular
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/journal"
+	"github.com/js-arias/gbifer/taxonomy"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `vernacular [--lang <language>[,<language>...]] [--journal <file>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "retrieve vernacular names of taxa",
+	Long: `
+Command vernacular reads a taxonomy from the standard input and retrieves the
+vernacular (common) names of all the accepted taxa in the taxonomy from GBIF.
+The names are printed as a TSV file with the following columns:
+
+	- taxonKey: the GBIF ID of the taxon.
+	- name: the scientific name of the taxon.
+	- vernacularName: the vernacular name.
+	- language: the language of the vernacular name.
+	- country: the country of the vernacular name (if any).
+	- source: the source of the vernacular name.
+
+By default, all vernacular names are retrieved. Use the flag --lang to select
+one or more languages, separated by commas. Languages are defined using ISO
+639-2 three letter codes (for example, "eng" for English, or "spa" for
+Spanish).
+
+If the flag --journal is defined, the names retrieved from GBIF will be
+recorded in the indicated file. If the command is interrupted (for example,
+by a network failure), running the command again with the same journal file
+will only retrieve the names of the taxa that were not retrieved before. A
+journal can only be used with the same taxonomy, and the journal file is
+removed when the command finishes successfully.
+
+By default, it will read the taxonomy from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+
+This command requires an internet connection.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var langFlag string
+var journalFile string
+var input string
+var output string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&langFlag, "lang", "", "")
+	c.Flags().StringVar(&journalFile, "journal", "", "")
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	tx, err := readTaxonomy(c.Stdin())
+	if err != nil {
+		return err
+	}
+
+	var langs map[string]bool
+	if langFlag != "" {
+		langs = make(map[string]bool)
+		for _, l := range strings.Split(langFlag, ",") {
+			l = strings.ToLower(strings.TrimSpace(l))
+			if l == "" {
+				continue
+			}
+			langs[l] = true
+		}
+	}
+
+	var jr *journal.Journal
+	if journalFile != "" {
+		var file string
+		if input != "stdin" {
+			file = input
+		}
+		hash, err := journal.Hash(file)
+		if err != nil {
+			return err
+		}
+		jr, err = journal.Open(journalFile, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	gbif.Open()
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := writeVernacular(out, tx, langs, jr); err != nil {
+		if jr != nil {
+			// the journal is only removed
+			// if the process finished successfully
+			jr.Close()
+		}
+		return err
+	}
+	if jr != nil {
+		if err := jr.Remove(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readTaxonomy(r io.Reader) (*taxonomy.Taxonomy, error) {
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	} else {
+		input = "stdin"
+	}
+
+	tx, err := taxonomy.Read(r)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", input, err)
+	}
+	return tx, nil
+}
+
+func writeVernacular(w io.Writer, tx *taxonomy.Taxonomy, langs map[string]bool, jr *journal.Journal) error {
+	tab := tsv.NewWriter(w)
+	tab.Comma = '\t'
+	tab.UseCRLF = true
+
+	header := []string{
+		"taxonKey",
+		"name",
+		"vernacularName",
+		"language",
+		"country",
+		"source",
+	}
+	if err := tab.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	for _, id := range tx.IDs() {
+		tax := tx.Taxon(id)
+		if tax.Status != "accepted" {
+			continue
+		}
+
+		ls, err := vernacular(id, jr)
+		if err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		for _, v := range ls {
+			name := strings.Join(strings.Fields(v.VernacularName), " ")
+			if name == "" {
+				continue
+			}
+			lang := strings.ToLower(v.Language)
+			if langs != nil && !langs[lang] {
+				continue
+			}
+			k := strings.ToLower(name) + "\t" + lang
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+
+			row := []string{
+				strconv.FormatInt(id, 10),
+				tax.Name,
+				name,
+				lang,
+				v.Country,
+				v.Source,
+			}
+			if err := tab.Write(row); err != nil {
+				return fmt.Errorf("when writing on %q: %v", output, err)
+			}
+			logger.WriteRows(1)
+		}
+	}
+
+	tab.Flush()
+	if err := tab.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+// vernacular returns the vernacular names
+// of a taxon.
+// If the taxon is in the journal,
+// the names stored in the journal are returned.
+func vernacular(id int64, jr *journal.Journal) ([]*gbif.VernacularName, error) {
+	key := strconv.FormatInt(id, 10)
+
+	// each name is stored as four values:
+	// name, language, country, and source.
+	if v, ok := jr.Values(key); ok {
+		if len(v)%4 != 0 {
+			return nil, fmt.Errorf("journal %q: taxon %d: invalid number of values", journalFile, id)
+		}
+		ls := make([]*gbif.VernacularName, 0, len(v)/4)
+		for i := 0; i < len(v); i += 4 {
+			ls = append(ls, &gbif.VernacularName{
+				VernacularName: v[i],
+				Language:       v[i+1],
+				Country:        v[i+2],
+				Source:         v[i+3],
+			})
+		}
+		return ls, nil
+	}
+
+	ls, err := gbif.Vernacular(id)
+	if err != nil {
+		return nil, err
+	}
+	v := make([]string, 0, 4*len(ls))
+	for _, n := range ls {
+		v = append(v, n.VernacularName, n.Language, n.Country, n.Source)
+	}
+	if err := jr.Commit(key, v...); err != nil {
+		return nil, err
+	}
+	return ls, nil
+}
@@ -0,0 +1,430 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package temporal implements a command to report
+// the number of records of each species
+// by year.
+package temporal
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/config"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/taxonomy"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `temporal [--decade] [--older <year>] [--tax <file>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "report records per species and year",
+	Long: `
+Command temporal reads a GBIF occurrence table from the standard input and
+prints the number of records of each species by year.
+
+The year of each record is taken from the "year" column, or, if the column is
+not present or is empty, from the "eventDate" column. Records without a year
+are ignored, and its number is reported in the standard error.
+
+By default, the species of each record is taken from the "species" column. If
+the flag --tax is defined, the indicated taxonomy file will be used to resolve
+the species of each record (using the "speciesKey" and "taxonKey" columns),
+and synonyms are resolved to their accepted names. If a default taxonomy is
+defined in the configuration (see "gbifer help"), it will be used as the value
+of --tax.
+
+The output is a TSV table with the following columns:
+
+	- species: the name of the species.
+	- taxonKey: the GBIF ID of the species (only when using a taxonomy).
+	- year: the year, or the first year of the decade if the flag --decade
+	  is defined.
+	- records: the number of records of the species in the year (or
+	  decade).
+
+Rows are sorted by species, and then by year.
+
+If the flag --older is defined with a year, only the species in which all the
+records are older than the indicated year will be printed (the flag --decade
+is ignored), with the following columns:
+
+	- species: the name of the species.
+	- taxonKey: the GBIF ID of the species (only when using a taxonomy).
+	- records: the number of records of the species.
+	- first: the year of the oldest record.
+	- last: the year of the most recent record.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var decadeFlag bool
+var olderFlag int
+var input string
+var output string
+var taxFile string
+
+func setFlags(c *command.Command) {
+	c.Flags().BoolVar(&decadeFlag, "decade", false, "")
+	c.Flags().IntVar(&olderFlag, "older", 0, "")
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().StringVar(&taxFile, "tax", config.Taxonomy(), "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if olderFlag < 0 {
+		return c.UsageError(fmt.Sprintf("invalid --older value %d", olderFlag))
+	}
+
+	var tx *taxonomy.Taxonomy
+	if taxFile != "" {
+		tx, err = readTaxonomy()
+		if err != nil {
+			return err
+		}
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	spp, err := readTable(in, tx)
+	if err != nil {
+		return err
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if olderFlag > 0 {
+		err = writeOlder(out, spp, tx != nil)
+	} else {
+		err = writeYears(out, spp, tx != nil)
+	}
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func readTaxonomy() (*taxonomy.Taxonomy, error) {
+	f, err := os.Open(taxFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tx, err := taxonomy.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("on file %q: %v", taxFile, err)
+	}
+	return tx, nil
+}
+
+// A species stores the records
+// of a species
+// by year.
+type species struct {
+	name  string
+	id    int64
+	years map[int]int
+}
+
+func readTable(r io.Reader, tx *taxonomy.Taxonomy) ([]*species, error) {
+	tab := tabfile.NewReader(r, "speciesKey", "taxonKey", "species", "year", "eventDate")
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+
+	keyCol := -1
+	taxCol := -1
+	spCol := -1
+	yCol := -1
+	dCol := -1
+	for i, h := range header {
+		switch strings.ToLower(h) {
+		case "specieskey":
+			keyCol = i
+		case "taxonkey":
+			taxCol = i
+		case "species":
+			spCol = i
+		case "year":
+			yCol = i
+		case "eventdate":
+			dCol = i
+		}
+	}
+	if yCol < 0 && dCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q or %q fields", input, "year", "eventDate")
+	}
+	if tx != nil {
+		if keyCol < 0 && taxCol < 0 {
+			return nil, fmt.Errorf("input data %q without %q or %q fields", input, "speciesKey", "taxonKey")
+		}
+	} else if spCol < 0 {
+		return nil, fmt.Errorf("input data %q without %q field", input, "species")
+	}
+
+	spp := make(map[string]*species)
+	var noYear, noSpecies int
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		var y int
+		if yCol >= 0 {
+			y, _ = strconv.Atoi(strings.TrimSpace(row[yCol]))
+		}
+		if y == 0 && dCol >= 0 {
+			y = dateYear(row[dCol])
+		}
+		if y == 0 {
+			noYear++
+			continue
+		}
+
+		var name string
+		var id int64
+		if tx != nil {
+			tax := tx.AcceptedAndRanked(rowKey(row, keyCol, taxCol))
+			if tax.ID == 0 || tax.Rank != taxonomy.Species {
+				noSpecies++
+				continue
+			}
+			name = tax.Name
+			id = tax.ID
+		} else {
+			name = taxonomy.Canon(row[spCol])
+			if name == "" {
+				noSpecies++
+				continue
+			}
+		}
+
+		sp, ok := spp[name]
+		if !ok {
+			if err := memory.Add(int64(len(name)) + 96); err != nil {
+				return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			}
+			sp = &species{
+				name:  name,
+				id:    id,
+				years: make(map[int]int),
+			}
+			spp[name] = sp
+		}
+		if _, ok := sp.years[y]; !ok {
+			if err := memory.Add(16); err != nil {
+				return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			}
+		}
+		sp.years[y]++
+	}
+	if noYear > 0 {
+		logger.Printf("%d records without year", noYear)
+	}
+	if noSpecies > 0 {
+		logger.Printf("%d records without species", noSpecies)
+	}
+
+	ls := make([]*species, 0, len(spp))
+	for _, sp := range spp {
+		ls = append(ls, sp)
+	}
+	slices.SortFunc(ls, func(a, b *species) int {
+		if c := cmp.Compare(a.name, b.name); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.id, b.id)
+	})
+	return ls, nil
+}
+
+// dateYear returns the year of an ISO 8601 date,
+// or the first year of an interval,
+// or 0 if the date is not valid.
+func dateYear(date string) int {
+	date = strings.TrimSpace(date)
+	if len(date) < 4 {
+		return 0
+	}
+	if len(date) > 4 && date[4] != '-' && date[4] != '/' {
+		return 0
+	}
+	y, err := strconv.Atoi(date[:4])
+	if err != nil {
+		return 0
+	}
+	return y
+}
+
+// rowKey returns the taxon ID of an occurrence row,
+// or 0 if the row has no valid ID.
+func rowKey(row []string, keyCol, taxCol int) int64 {
+	var key string
+	if keyCol >= 0 {
+		key = row[keyCol]
+		if key == "" {
+			return 0
+		}
+	}
+	if taxCol >= 0 {
+		key = row[taxCol]
+	}
+	id, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+func writeYears(w io.Writer, spp []*species, withID bool) error {
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	header := []string{"species"}
+	if withID {
+		header = append(header, "taxonKey")
+	}
+	header = append(header, "year", "records")
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	for _, sp := range spp {
+		counts := sp.years
+		if decadeFlag {
+			counts = make(map[int]int)
+			for y, n := range sp.years {
+				counts[y-y%10] += n
+			}
+		}
+		years := make([]int, 0, len(counts))
+		for y := range counts {
+			years = append(years, y)
+		}
+		slices.Sort(years)
+
+		for _, y := range years {
+			row := []string{sp.name}
+			if withID {
+				row = append(row, strconv.FormatInt(sp.id, 10))
+			}
+			row = append(row, strconv.Itoa(y), strconv.Itoa(counts[y]))
+			if err := out.Write(row); err != nil {
+				return fmt.Errorf("when writing on %q: %v", output, err)
+			}
+			logger.WriteRows(1)
+		}
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
+
+func writeOlder(w io.Writer, spp []*species, withID bool) error {
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	header := []string{"species"}
+	if withID {
+		header = append(header, "taxonKey")
+	}
+	header = append(header, "records", "first", "last")
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	var old int
+	for _, sp := range spp {
+		first, last, recs := 0, 0, 0
+		for y, n := range sp.years {
+			if first == 0 || y < first {
+				first = y
+			}
+			last = max(last, y)
+			recs += n
+		}
+		if last >= olderFlag {
+			continue
+		}
+		old++
+
+		row := []string{sp.name}
+		if withID {
+			row = append(row, strconv.FormatInt(sp.id, 10))
+		}
+		row = append(row, strconv.Itoa(recs), strconv.Itoa(first), strconv.Itoa(last))
+		if err := out.Write(row); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	logger.Printf("%d of %d species with records older than %d", old, len(spp), olderFlag)
+	return nil
+}
@@ -0,0 +1,181 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package unique implements a command to list
+// the distinct values of a column
+// of a GBIF occurrence table.
+package unique
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/memory"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `unique --col <column>
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "list distinct values of a column",
+	Long: `
+Command unique reads a GBIF occurrence table from the standard input and
+prints the distinct values of a column, with the number of records of each
+value. It is useful to discover the values of loosely defined fields (for
+example, "basisOfRecord", "samplingProtocol", or "license").
+
+The flag --col is required and defines the column.
+
+The output is a TSV table with the columns "value" and "records", sorted from
+the value with more records to the value with fewer records. Empty values are
+included.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+var colFlag string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().StringVar(&colFlag, "col", "", "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	if colFlag == "" {
+		return c.UsageError("flag --col must be defined")
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	counts, err := readValues(in)
+	if err != nil {
+		return err
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	if err := writeValues(out, counts); err != nil {
+		return err
+	}
+	return nil
+}
+
+func readValues(r io.Reader) (map[string]int, error) {
+	tab := tabfile.NewReader(r, colFlag)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+	col := -1
+	for i, h := range header {
+		if strings.EqualFold(h, colFlag) {
+			col = i
+		}
+	}
+	if col < 0 {
+		return nil, fmt.Errorf("input data %q without %q field", input, colFlag)
+	}
+
+	counts := make(map[string]int)
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+		v := row[col]
+		if _, ok := counts[v]; !ok {
+			if err := memory.Add(int64(len(v) + 24)); err != nil {
+				return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			}
+		}
+		counts[v]++
+	}
+	return counts, nil
+}
+
+func writeValues(w io.Writer, counts map[string]int) error {
+	values := make([]string, 0, len(counts))
+	for v := range counts {
+		values = append(values, v)
+	}
+	slices.SortFunc(values, func(a, b string) int {
+		if counts[a] != counts[b] {
+			return counts[b] - counts[a]
+		}
+		return strings.Compare(a, b)
+	})
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	if err := out.Write([]string{"value", "records"}); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	for _, v := range values {
+		if err := out.Write([]string{v, strconv.Itoa(counts[v])}); err != nil {
+			return fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return nil
+}
@@ -0,0 +1,391 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package updaterecords implements a command to update
+// the records of a GBIF occurrence table
+// with the current records in GBIF.
+package updaterecords
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/journal"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `update-records [--cols <column>[,<column>...]]
+	[--report <file>] [--journal <file>]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "update records from GBIF",
+	Long: `
+Command update-records reads a GBIF occurrence table from the standard input,
+retrieves each record from GBIF (using the "gbifID" column), and updates the
+values of the selected columns, so the table reflects the current
+interpretation of the records in GBIF.
+
+By default, the taxonomy, issues, and coordinate columns are updated. Use the
+flag --cols to select the columns, separated by commas. Besides column names,
+the following values can be used to select a group of columns:
+
+	taxonomy     kingdom, phylum, class, order, family, genus, species,
+	             scientificName, acceptedScientificName, taxonRank,
+	             taxonomicStatus, taxonKey, speciesKey, and
+	             acceptedTaxonKey.
+	issues       issue.
+	coordinates  decimalLatitude, decimalLongitude,
+	             coordinateUncertaintyInMeters, coordinatePrecision, and
+	             countryCode.
+
+Only the columns already in the table are updated. Records that are no longer
+in GBIF are kept unchanged.
+
+The number of updated and deleted records is reported in the standard error.
+If the flag --report is defined, each change will be written in the indicated
+file, as a TSV file with the columns "gbifID", "change" (either "updated" or
+"deleted"), "field", "old", and "new".
+
+If the flag --journal is defined, the records retrieved from GBIF will be
+recorded in the indicated file. If the command is interrupted (for example,
+by a network failure), running the command again with the same journal file
+will only retrieve the records that were not retrieved before. A journal can
+only be used with the same input file and columns, and the journal file is
+removed when the command finishes successfully. If the input is the standard
+input, the content of the input can not be checked, so be careful to use the
+same input.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+
+This command requires an internet connection.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+var colsFlag string
+var reportFile string
+var journalFile string
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().StringVar(&colsFlag, "cols", "taxonomy,issues,coordinates", "")
+	c.Flags().StringVar(&reportFile, "report", "", "")
+	c.Flags().StringVar(&journalFile, "journal", "", "")
+}
+
+// groups are the groups of columns.
+var groups = map[string][]string{
+	"taxonomy": {
+		"kingdom",
+		"phylum",
+		"class",
+		"order",
+		"family",
+		"genus",
+		"species",
+		"scientificName",
+		"acceptedScientificName",
+		"taxonRank",
+		"taxonomicStatus",
+		"taxonKey",
+		"speciesKey",
+		"acceptedTaxonKey",
+	},
+	"issues": {
+		"issue",
+	},
+	"coordinates": {
+		"decimalLatitude",
+		"decimalLongitude",
+		"coordinateUncertaintyInMeters",
+		"coordinatePrecision",
+		"countryCode",
+	},
+}
+
+// apiNames are the names used in the GBIF API
+// for columns with a different name
+// in the occurrence tables.
+var apiNames = map[string]string{
+	"issue": "issues",
+}
+
+func run(c *command.Command, args []string) (err error) {
+	var cols []string
+	for _, v := range strings.Split(colsFlag, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if g, ok := groups[strings.ToLower(v)]; ok {
+			cols = append(cols, g...)
+			continue
+		}
+		cols = append(cols, v)
+	}
+	if len(cols) == 0 {
+		return c.UsageError("flag --cols without columns")
+	}
+
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	var rep *tsv.Writer
+	if reportFile != "" {
+		var f *os.File
+		f, err = os.Create(reportFile)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		rep = tsv.NewWriter(f)
+		rep.Comma = '\t'
+		rep.UseCRLF = true
+		if err := rep.Write([]string{"gbifID", "change", "field", "old", "new"}); err != nil {
+			return fmt.Errorf("when writing on %q: %v", reportFile, err)
+		}
+	}
+
+	var jr *journal.Journal
+	if journalFile != "" {
+		var file string
+		if input != "stdin" {
+			file = input
+		}
+		hash, err := journal.Hash(file, cols...)
+		if err != nil {
+			return err
+		}
+		jr, err = journal.Open(journalFile, hash)
+		if err != nil {
+			return err
+		}
+		if n := jr.Len(); n > 0 {
+			logger.Printf("resuming from journal %q: %d records already retrieved", journalFile, n)
+		}
+	}
+
+	gbif.Open()
+	updated, deleted, err := update(in, out, rep, cols, jr)
+	if err != nil {
+		if jr != nil {
+			// the journal is only removed
+			// if the process finished successfully
+			jr.Close()
+		}
+		return err
+	}
+	logger.Printf("%d updated records, %d deleted records", updated, deleted)
+
+	if rep != nil {
+		rep.Flush()
+		if err := rep.Error(); err != nil {
+			return fmt.Errorf("when writing on %q: %v", reportFile, err)
+		}
+	}
+	if jr != nil {
+		if err := jr.Remove(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func update(r io.Reader, w io.Writer, rep *tsv.Writer, cols []string, jr *journal.Journal) (updated, deleted int, err error) {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return 0, 0, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+	idCol := -1
+	fields := make(map[string]int, len(header))
+	for i, h := range header {
+		h = strings.ToLower(h)
+		if h == "gbifid" {
+			idCol = i
+		}
+		fields[h] = i
+	}
+	if idCol < 0 {
+		return 0, 0, fmt.Errorf("input data %q without %q field", input, "gbifID")
+	}
+
+	// names of the columns in the API
+	api := make([]string, len(cols))
+	for i, c := range cols {
+		api[i] = c
+		if n, ok := apiNames[strings.ToLower(c)]; ok {
+			api[i] = n
+		}
+	}
+
+	// update columns in the table
+	type column struct {
+		col  int
+		name string
+		val  int // index of the value in the record
+	}
+	var upd []column
+	for j, c := range cols {
+		i, ok := fields[strings.ToLower(c)]
+		if !ok {
+			continue
+		}
+		upd = append(upd, column{col: i, name: header[i], val: j})
+	}
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	if err := out.Write(header); err != nil {
+		return 0, 0, fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return 0, 0, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		id := strings.TrimSpace(row[idCol])
+		if id != "" && len(upd) > 0 {
+			rec, err := record(id, api, jr)
+			if err != nil {
+				return 0, 0, err
+			}
+			if rec == nil {
+				deleted++
+				if rep != nil {
+					if err := rep.Write([]string{id, "deleted", "", "", ""}); err != nil {
+						return 0, 0, fmt.Errorf("when writing on %q: %v", reportFile, err)
+					}
+				}
+			} else {
+				changed := false
+				for _, c := range upd {
+					v := rec[c.val]
+					if v == row[c.col] {
+						continue
+					}
+					if rep != nil {
+						if err := rep.Write([]string{id, "updated", c.name, row[c.col], v}); err != nil {
+							return 0, 0, fmt.Errorf("when writing on %q: %v", reportFile, err)
+						}
+					}
+					row[c.col] = v
+					changed = true
+				}
+				if changed {
+					updated++
+				}
+			}
+		}
+
+		if err := out.Write(row); err != nil {
+			return 0, 0, fmt.Errorf("when writing on %q: %v", output, err)
+		}
+		logger.WriteRows(1)
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return 0, 0, fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return updated, deleted, nil
+}
+
+// record returns the values of the given fields
+// of a GBIF record,
+// or nil if the record is no longer in GBIF.
+// If the record is in the journal,
+// the values stored in the journal are returned.
+func record(id string, fields []string, jr *journal.Journal) ([]string, error) {
+	if v, ok := jr.Values(id); ok {
+		if len(v) == 0 {
+			return nil, nil
+		}
+		if len(v) != len(fields) {
+			return nil, fmt.Errorf("journal %q: record %q: got %d values, want %d", journalFile, id, len(v), len(fields))
+		}
+		return v, nil
+	}
+
+	rec, err := gbif.Occurrence(id)
+	if errors.Is(err, gbif.ErrNotFound) {
+		// deleted records are stored without values
+		if err := jr.Commit(id); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	v := make([]string, len(fields))
+	for i, f := range fields {
+		v[i] = rec[f]
+	}
+	if err := jr.Commit(id, v...); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
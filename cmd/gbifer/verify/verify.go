@@ -0,0 +1,202 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package verify implements a command to check
+// if the records of a GBIF occurrence table
+// are still in GBIF.
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/tsv"
+)
+
+var Command = &command.Command{
+	Usage: `verify [--missing]
+	[-i|--input <file>] [-o|--output <file>]`,
+	Short: "check that records are still in GBIF",
+	Long: `
+Command verify reads a GBIF occurrence table from the standard input and
+checks if each record (using the "gbifID" column) is still in GBIF. Records
+are deleted from GBIF when they are removed by the publisher, or when they are
+absorbed into another record (for example, after a republication with new
+identifiers).
+
+The records are checked in batches of 100 records per request, and the
+requests are rate-limited, so the GBIF server is not overloaded.
+
+The output table will include the column "gbifStatus", with the value
+"present" for records found in GBIF, and "missing" for records not found in
+GBIF. If the flag --missing is defined, only the missing records will be
+printed. The number of missing records is reported in the standard error.
+
+By default, it will read the data from the standard input; use the flag
+--input, or -i, to select a particular file.
+
+By default, the results will be printed in the standard output; use the flag
+--output, or -o, to define an output file.
+
+This command requires an internet connection.
+	`,
+	SetFlags: setFlags,
+	Run:      run,
+}
+
+var input string
+var output string
+var missingFlag bool
+
+func setFlags(c *command.Command) {
+	c.Flags().StringVar(&input, "input", "", "")
+	c.Flags().StringVar(&input, "i", "", "")
+	c.Flags().StringVar(&output, "output", "", "")
+	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().BoolVar(&missingFlag, "missing", false, "")
+}
+
+func run(c *command.Command, args []string) (err error) {
+	in := c.Stdin()
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		input = "stdin"
+	}
+
+	out := c.Stdout()
+	if output != "" {
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e := f.Close()
+			if e != nil && err == nil {
+				err = e
+			}
+		}()
+		out = f
+	} else {
+		output = "stdout"
+	}
+
+	gbif.Open()
+	missing, err := verify(in, out)
+	if err != nil {
+		return err
+	}
+	logger.Printf("%d missing records", missing)
+	return nil
+}
+
+// A batch is a set of rows
+// to be checked in a single request.
+type batch struct {
+	rows [][]string
+	ids  []int64
+}
+
+func verify(r io.Reader, w io.Writer) (int, error) {
+	tab := tabfile.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return 0, fmt.Errorf("when reading %q header: %v", input, err)
+	}
+	idCol := -1
+	for i, h := range header {
+		if strings.ToLower(h) == "gbifid" {
+			idCol = i
+		}
+	}
+	if idCol < 0 {
+		return 0, fmt.Errorf("input data %q without %q field", input, "gbifID")
+	}
+
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	header = append(header, "gbifStatus")
+	if err := out.Write(header); err != nil {
+		return 0, fmt.Errorf("when writing on %q: %v", output, err)
+	}
+
+	missing := 0
+	b := &batch{}
+	flush := func() error {
+		found, err := gbif.Exist(b.ids)
+		if err != nil {
+			return err
+		}
+		for i, row := range b.rows {
+			status := "present"
+			if !found[b.ids[i]] {
+				status = "missing"
+				missing++
+			} else if missingFlag {
+				logger.Add("dropped-present", 1)
+				continue
+			}
+			row = append(row, status)
+			if err := out.Write(row); err != nil {
+				return fmt.Errorf("when writing on %q: %v", output, err)
+			}
+			logger.WriteRows(1)
+		}
+		b.rows = b.rows[:0]
+		b.ids = b.ids[:0]
+		return nil
+	}
+
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return 0, fmt.Errorf("table %q: row %d: %v", input, ln, err)
+		}
+		logger.ReadRows(1)
+
+		id, err := strconv.ParseInt(strings.TrimSpace(row[idCol]), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("table %q: row %d: %q: %v", input, ln, "gbifID", err)
+		}
+		b.rows = append(b.rows, row)
+		b.ids = append(b.ids, id)
+		if len(b.ids) < gbif.MaxBatch {
+			continue
+		}
+		if err := flush(); err != nil {
+			return 0, err
+		}
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return 0, fmt.Errorf("when writing on %q: %v", output, err)
+	}
+	return missing, nil
+}
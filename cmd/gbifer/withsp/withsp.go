@@ -7,24 +7,31 @@
 package withsp
 
 import (
-	"errors"
 	"fmt"
 	"io"
 	"os"
-	"strings"
 
 	"github.com/js-arias/command"
+	"github.com/js-arias/gbifer/cmd/gbifer/logger"
+	"github.com/js-arias/gbifer/cmd/gbifer/parallel"
+	"github.com/js-arias/gbifer/cmd/gbifer/tabfile"
+	"github.com/js-arias/gbifer/occurrence"
 	"github.com/js-arias/gbifer/tsv"
 )
 
 var Command = &command.Command{
-	Usage: "withsp [-i|--input <file>] [-o|--output <file>]",
+	Usage: `withsp [--jobs <number>]
+	[-i|--input <file>] [-o|--output <file>]`,
 	Short: "select rows associated with species",
 	Long: `
 Command withsp reads a GBIF occurrence table from the standard input and
 selects the rows in which the occurrence is associated with a taxon identified
 up to species level.
 
+If the flag --jobs is defined with a value greater than 1, the rows will be
+processed by the indicated number of concurrent jobs; with 0, it will use the
+number of available CPUs. The order of the rows is always preserved.
+
 By default, it will read the data from the standard input; use the flag
 --input, or -i, to select a particular file.
 	
@@ -37,12 +44,14 @@ By default, the results will be printed in the standard output; use the flag
 
 var input string
 var output string
+var jobs int
 
 func setFlags(c *command.Command) {
 	c.Flags().StringVar(&input, "input", "", "")
 	c.Flags().StringVar(&input, "i", "", "")
 	c.Flags().StringVar(&output, "output", "", "")
 	c.Flags().StringVar(&output, "o", "", "")
+	c.Flags().IntVar(&jobs, "jobs", 1, "")
 }
 
 func run(c *command.Command, args []string) (err error) {
@@ -60,8 +69,8 @@ func run(c *command.Command, args []string) (err error) {
 
 	out := c.Stdout()
 	if output != "" {
-		var f *os.File
-		f, err = os.Create(output)
+		var f io.WriteCloser
+		f, err = tabfile.Create(output)
 		if err != nil {
 			return err
 		}
@@ -83,7 +92,7 @@ func run(c *command.Command, args []string) (err error) {
 }
 
 func readTable(r io.Reader, w io.Writer) error {
-	tab := tsv.NewReader(r)
+	tab := tabfile.NewReader(r)
 	tab.Comma = '\t'
 
 	header, err := tab.Read()
@@ -91,16 +100,9 @@ func readTable(r io.Reader, w io.Writer) error {
 		return fmt.Errorf("when reading %q header: %v", input, err)
 	}
 
-	spCol := -1
-	for i, h := range header {
-		h = strings.ToLower(h)
-		if h == "specieskey" {
-			spCol = i
-			break
-		}
-	}
-	if spCol < 0 {
-		return fmt.Errorf("input data %q with %q field", input, "speciesKey")
+	sp, err := occurrence.WithSpecies(header)
+	if err != nil {
+		return fmt.Errorf("input data %q %v", input, err)
 	}
 
 	out := tsv.NewWriter(w)
@@ -113,22 +115,19 @@ func readTable(r io.Reader, w io.Writer) error {
 	}
 
 	// write data
-	for {
-		row, err := tab.Read()
-		if errors.Is(err, io.EOF) {
-			break
-		}
-		ln, _ := tab.FieldPos(0)
+	fn := func(row []string, ln int) ([]string, error) {
+		ok, err := sp.Keep(row)
 		if err != nil {
-			return fmt.Errorf("table %q: row %d: %v", input, ln, err)
+			return nil, fmt.Errorf("table %q: row %d: %v", input, ln, err)
 		}
-
-		if strings.TrimSpace(row[spCol]) == "" {
-			continue
-		}
-		if err := out.Write(row); err != nil {
-			return fmt.Errorf("when writing on %q: %v", output, err)
+		if !ok {
+			logger.Add("dropped-no-species", 1)
+			return nil, nil
 		}
+		return row, nil
+	}
+	if err := parallel.Rows(tab, input, out, output, jobs, fn); err != nil {
+		return err
 	}
 	out.Flush()
 	if err := out.Error(); err != nil {
@@ -0,0 +1,333 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package columnar implements a binary,
+// column oriented,
+// format to store occurrence tables,
+// so a table can be read many times
+// without parsing the text of the table,
+// and reading only the required columns.
+//
+// A file starts with a magic string,
+// followed by the blocks of each column,
+// and ends with a footer
+// with the number of rows,
+// the column names,
+// and the encoding and position of each column block,
+// followed by the position of the footer
+// (as an 8 byte little-endian integer).
+//
+// Each column is encoded using one of the following encodings:
+//
+//   - dictionary: used for columns with few distinct values.
+//     The block stores the distinct values,
+//     and for each row,
+//     the index of its value.
+//   - delta: used for integer columns
+//     (for example, IDs).
+//     For each row,
+//     the block stores the difference with the previous value.
+//   - plain: any other column.
+//     The block stores each value.
+//
+// All integers are stored as variable length integers.
+package columnar
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Magic is the string
+// at the start of a columnar file.
+const Magic = "GBIFERC\x01"
+
+// ErrFormat is the error returned
+// when a file is not a valid columnar file.
+var ErrFormat = errors.New("invalid columnar file")
+
+// MaxDictValues is the maximum number of distinct values
+// of a column stored with a dictionary.
+const MaxDictValues = 4096
+
+// Column encodings.
+const (
+	plain byte = iota
+	dictionary
+	delta
+)
+
+// maxDelta is the maximum absolute value
+// of an integer stored with delta encoding.
+const maxDelta = 1 << 60
+
+// A Writer writes an occurrence table
+// as a columnar file.
+//
+// As the columns are stored in blocks,
+// the values of each column are stored
+// in temporary files,
+// and the columnar file is written
+// when the Writer is closed.
+type Writer struct {
+	w      io.Writer
+	header []string
+	rows   int
+	dir    string
+	cols   []*colWriter
+}
+
+type colWriter struct {
+	f *os.File
+	w *bufio.Writer
+
+	dict  map[string]int
+	isInt bool
+}
+
+// NewWriter returns a Writer
+// that writes a table with the given header
+// into w.
+func NewWriter(w io.Writer, header []string) (*Writer, error) {
+	dir, err := os.MkdirTemp("", "gbifer-columnar-")
+	if err != nil {
+		return nil, err
+	}
+
+	cw := &Writer{
+		w:      w,
+		header: header,
+		dir:    dir,
+	}
+	for i := range header {
+		f, err := os.Create(filepath.Join(dir, "col-"+strconv.Itoa(i)))
+		if err != nil {
+			cw.remove()
+			return nil, err
+		}
+		cw.cols = append(cw.cols, &colWriter{
+			f:     f,
+			w:     bufio.NewWriterSize(f, 16<<10),
+			dict:  make(map[string]int),
+			isInt: true,
+		})
+	}
+	return cw, nil
+}
+
+// Write writes a row.
+func (w *Writer) Write(row []string) error {
+	if len(row) != len(w.header) {
+		return fmt.Errorf("got %d fields, want %d", len(row), len(w.header))
+	}
+	for i, v := range row {
+		c := w.cols[i]
+		if err := writeString(c.w, v); err != nil {
+			return err
+		}
+		if c.dict != nil {
+			if _, ok := c.dict[v]; !ok {
+				if len(c.dict) >= MaxDictValues {
+					c.dict = nil
+				} else {
+					c.dict[v] = len(c.dict)
+				}
+			}
+		}
+		if c.isInt && v != "" {
+			if _, ok := parseInt(v); !ok {
+				c.isInt = false
+			}
+		}
+	}
+	w.rows++
+	return nil
+}
+
+// Close encodes the columns
+// and writes the columnar file.
+// It does not close the underlying io.Writer.
+func (w *Writer) Close() error {
+	defer w.remove()
+
+	for _, c := range w.cols {
+		if err := c.w.Flush(); err != nil {
+			return err
+		}
+		if _, err := c.f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	out := &countWriter{w: bufio.NewWriter(w.w)}
+	if _, err := out.Write([]byte(Magic)); err != nil {
+		return err
+	}
+
+	var footer bytes.Buffer
+	putUvarint(&footer, uint64(w.rows))
+	putUvarint(&footer, uint64(len(w.header)))
+	for i, c := range w.cols {
+		enc := plain
+		switch {
+		case c.dict != nil:
+			enc = dictionary
+		case c.isInt:
+			enc = delta
+		}
+
+		start := out.n
+		if err := c.encode(out, enc, w.rows); err != nil {
+			return fmt.Errorf("column %q: %v", w.header[i], err)
+		}
+		writeString(&footer, w.header[i])
+		footer.WriteByte(enc)
+		putUvarint(&footer, uint64(start))
+		putUvarint(&footer, uint64(out.n-start))
+	}
+
+	pos := out.n
+	if _, err := out.Write(footer.Bytes()); err != nil {
+		return err
+	}
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(pos))
+	if _, err := out.Write(b[:]); err != nil {
+		return err
+	}
+	return out.w.Flush()
+}
+
+// Abort removes the temporary files
+// without writing the columnar file.
+// It should be used if the table
+// can not be completed.
+func (w *Writer) Abort() {
+	w.remove()
+}
+
+// remove removes the temporary files.
+func (w *Writer) remove() {
+	for _, c := range w.cols {
+		c.f.Close()
+	}
+	os.RemoveAll(w.dir)
+}
+
+// encode writes the column block.
+func (c *colWriter) encode(w io.Writer, enc byte, rows int) error {
+	r := bufio.NewReader(c.f)
+	var buf bytes.Buffer
+
+	var dict map[string]int
+	if enc == dictionary {
+		values := make([]string, len(c.dict))
+		for v, i := range c.dict {
+			values[i] = v
+		}
+		putUvarint(&buf, uint64(len(values)))
+		for _, v := range values {
+			writeString(&buf, v)
+		}
+		dict = c.dict
+	}
+
+	var prev int64
+	for i := 0; i < rows; i++ {
+		v, err := readString(r)
+		if err != nil {
+			return err
+		}
+		switch enc {
+		case plain:
+			writeString(&buf, v)
+		case dictionary:
+			putUvarint(&buf, uint64(dict[v]))
+		case delta:
+			// empty values are stored as 0,
+			// and other values
+			// as the zigzag encoded difference
+			// with a bit set.
+			if v == "" {
+				buf.WriteByte(0)
+				break
+			}
+			n, _ := parseInt(v)
+			d := n - prev
+			prev = n
+			putUvarint(&buf, (uint64(d<<1)^uint64(d>>63))<<1|1)
+		}
+
+		if buf.Len() > 32<<10 {
+			if _, err := w.Write(buf.Bytes()); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// parseInt returns the value of an integer,
+// if the string is the canonical form of the integer
+// (so the value can be recovered exactly).
+func parseInt(s string) (int64, bool) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n > maxDelta || n < -maxDelta {
+		return 0, false
+	}
+	if strconv.FormatInt(n, 10) != s {
+		return 0, false
+	}
+	return n, true
+}
+
+type countWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func putUvarint(w io.Writer, v uint64) error {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	_, err := w.Write(b[:n])
+	return err
+}
+
+func writeString(w io.Writer, s string) error {
+	if err := putUvarint(w, uint64(len(s))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, s)
+	return err
+}
+
+func readString(r *bufio.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if n > uint64(r.Size())<<16 {
+		return "", ErrFormat
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
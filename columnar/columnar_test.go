@@ -0,0 +1,97 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package columnar_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/js-arias/gbifer/columnar"
+)
+
+func TestColumnar(t *testing.T) {
+	header := []string{"gbifID", "countryCode", "year", "locality", "elevation"}
+	rows := [][]string{
+		{"4011", "AR", "1990", "Río Negro", "-12"},
+		{"4005", "AR", "", "Bariloche\tcentro", "007"},
+		{"9000000001", "BR", "2001", "", ""},
+		{"-3", "", "2001", "São Paulo", "850"},
+	}
+	// many rows to use the different encodings
+	for i := 0; i < 2*columnar.MaxDictValues; i++ {
+		rows = append(rows, []string{strconv.Itoa(10000 + i), "UY", "2023", "loc " + strconv.Itoa(i), strconv.Itoa(i)})
+	}
+
+	var buf bytes.Buffer
+	w, err := columnar.NewWriter(&buf, header)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, r := range rows {
+		if err := w.Write(r); err != nil {
+			t.Fatalf("write: unexpected error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: unexpected error: %v", err)
+	}
+
+	data := bytes.NewReader(buf.Bytes())
+	if !columnar.IsColumnar(data) {
+		t.Fatalf("expecting a columnar file")
+	}
+	f, err := columnar.NewFile(data, int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.Len() != len(rows) {
+		t.Errorf("len: got %d, want %d", f.Len(), len(rows))
+	}
+	if got := f.Header(); !reflect.DeepEqual(got, header) {
+		t.Errorf("header: got %q, want %q", got, header)
+	}
+
+	tests := map[string]struct {
+		cols []string
+		idx  []int
+	}{
+		"all":      {nil, []int{0, 1, 2, 3, 4}},
+		"selected": {[]string{"LOCALITY", "gbifid"}, []int{3, 0}},
+		"single":   {[]string{"elevation"}, []int{4}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r, err := f.Reader(test.cols...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i, want := range rows {
+				got, err := r.Read()
+				if err != nil {
+					t.Fatalf("row %d: unexpected error: %v", i+1, err)
+				}
+				for j, c := range test.idx {
+					if got[j] != want[c] {
+						t.Fatalf("row %d: column %q: got %q, want %q", i+1, header[c], got[j], want[c])
+					}
+				}
+			}
+			if _, err := r.Read(); !errors.Is(err, io.EOF) {
+				t.Errorf("end: got error %v, want %v", err, io.EOF)
+			}
+		})
+	}
+
+	if _, err := f.Reader("unknown"); err == nil {
+		t.Errorf("unknown column: expecting error")
+	}
+	if _, err := columnar.NewFile(bytes.NewReader([]byte("gbifID\n")), 7); !errors.Is(err, columnar.ErrFormat) {
+		t.Errorf("text file: got error %v, want %v", err, columnar.ErrFormat)
+	}
+}
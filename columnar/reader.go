@@ -0,0 +1,267 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package columnar
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A File is an open columnar file.
+type File struct {
+	r    io.ReaderAt
+	f    *os.File
+	rows int
+	cols []column
+}
+
+type column struct {
+	name   string
+	enc    byte
+	offset int64
+	size   int64
+}
+
+// IsColumnar returns true
+// if the content of r starts with
+// the columnar file magic string.
+func IsColumnar(r io.ReaderAt) bool {
+	b := make([]byte, len(Magic))
+	if _, err := r.ReadAt(b, 0); err != nil {
+		return false
+	}
+	return string(b) == Magic
+}
+
+// Open opens a columnar file.
+func Open(name string) (*File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	cf, err := NewFile(f, st.Size())
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("on file %q: %v", name, err)
+	}
+	cf.f = f
+	return cf, nil
+}
+
+// NewFile returns a File
+// that reads from r,
+// with the given size.
+func NewFile(r io.ReaderAt, size int64) (*File, error) {
+	if size < int64(len(Magic))+8 || !IsColumnar(r) {
+		return nil, ErrFormat
+	}
+
+	var b [8]byte
+	if _, err := r.ReadAt(b[:], size-8); err != nil {
+		return nil, err
+	}
+	pos := int64(binary.LittleEndian.Uint64(b[:]))
+	if pos < int64(len(Magic)) || pos > size-8 {
+		return nil, ErrFormat
+	}
+
+	fr := bufio.NewReader(io.NewSectionReader(r, pos, size-8-pos))
+	rows, err := binary.ReadUvarint(fr)
+	if err != nil {
+		return nil, ErrFormat
+	}
+	n, err := binary.ReadUvarint(fr)
+	if err != nil {
+		return nil, ErrFormat
+	}
+
+	cf := &File{r: r, rows: int(rows)}
+	for i := uint64(0); i < n; i++ {
+		var c column
+		c.name, err = readString(fr)
+		if err != nil {
+			return nil, ErrFormat
+		}
+		c.enc, err = fr.ReadByte()
+		if err != nil || c.enc > delta {
+			return nil, ErrFormat
+		}
+		off, err := binary.ReadUvarint(fr)
+		if err != nil {
+			return nil, ErrFormat
+		}
+		sz, err := binary.ReadUvarint(fr)
+		if err != nil {
+			return nil, ErrFormat
+		}
+		c.offset, c.size = int64(off), int64(sz)
+		if c.offset < int64(len(Magic)) || c.offset+c.size > pos {
+			return nil, ErrFormat
+		}
+		cf.cols = append(cf.cols, c)
+	}
+	return cf, nil
+}
+
+// Close closes the file.
+func (f *File) Close() error {
+	if f.f == nil {
+		return nil
+	}
+	return f.f.Close()
+}
+
+// Header returns the column names of the table.
+func (f *File) Header() []string {
+	h := make([]string, len(f.cols))
+	for i, c := range f.cols {
+		h[i] = c.name
+	}
+	return h
+}
+
+// Len returns the number of rows of the table.
+func (f *File) Len() int {
+	return f.rows
+}
+
+// Reader returns a Reader
+// of the indicated columns.
+// Column names are case insensitive.
+// If no column is given,
+// all the columns are read.
+func (f *File) Reader(cols ...string) (*Reader, error) {
+	var sel []int
+	if len(cols) == 0 {
+		for i := range f.cols {
+			sel = append(sel, i)
+		}
+	}
+	for _, name := range cols {
+		found := false
+		for i, c := range f.cols {
+			if strings.EqualFold(c.name, name) {
+				sel = append(sel, i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("column %q not found", name)
+		}
+	}
+
+	r := &Reader{rows: f.rows}
+	for _, i := range sel {
+		c := f.cols[i]
+		cr := &colReader{
+			enc: c.enc,
+			r:   bufio.NewReader(io.NewSectionReader(f.r, c.offset, c.size)),
+		}
+		if c.enc == dictionary {
+			n, err := binary.ReadUvarint(cr.r)
+			if err != nil || n > MaxDictValues {
+				return nil, fmt.Errorf("column %q: %v", c.name, ErrFormat)
+			}
+			cr.dict = make([]string, n)
+			for j := range cr.dict {
+				cr.dict[j], err = readString(cr.r)
+				if err != nil {
+					return nil, fmt.Errorf("column %q: %v", c.name, ErrFormat)
+				}
+			}
+		}
+		r.header = append(r.header, c.name)
+		r.cols = append(r.cols, cr)
+	}
+	return r, nil
+}
+
+// A Reader reads the rows of a columnar file.
+type Reader struct {
+	header []string
+	cols   []*colReader
+	rows   int
+	row    int
+}
+
+type colReader struct {
+	enc  byte
+	r    *bufio.Reader
+	dict []string
+	prev int64
+}
+
+// Header returns the names
+// of the columns read by the Reader.
+func (r *Reader) Header() []string {
+	return r.header
+}
+
+// Read returns the next row.
+// At the end of the table,
+// it returns io.EOF.
+func (r *Reader) Read() ([]string, error) {
+	if r.row >= r.rows {
+		return nil, io.EOF
+	}
+	row := make([]string, len(r.cols))
+	for i, c := range r.cols {
+		v, err := c.read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("column %q: row %d: %v", r.header[i], r.row+1, err)
+		}
+		row[i] = v
+	}
+	r.row++
+	return row, nil
+}
+
+// Row returns the number of the last row read,
+// starting from 1.
+func (r *Reader) Row() int {
+	return r.row
+}
+
+func (c *colReader) read() (string, error) {
+	switch c.enc {
+	case dictionary:
+		i, err := binary.ReadUvarint(c.r)
+		if err != nil {
+			return "", err
+		}
+		if i >= uint64(len(c.dict)) {
+			return "", ErrFormat
+		}
+		return c.dict[i], nil
+	case delta:
+		v, err := binary.ReadUvarint(c.r)
+		if err != nil {
+			return "", err
+		}
+		if v == 0 {
+			return "", nil
+		}
+		z := v >> 1
+		d := int64(z>>1) ^ -int64(z&1)
+		c.prev += d
+		return strconv.FormatInt(c.prev, 10), nil
+	}
+	return readString(c.r)
+}
@@ -0,0 +1,243 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package gbif
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/gbifer/tsv"
+)
+
+// backbone is a local copy of the GBIF backbone.
+// If defined,
+// species requests will be resolved
+// using the local backbone
+// instead of the GBIF API.
+var backbone *localBackbone
+
+type localBackbone struct {
+	ids      map[int64]*Species
+	names    map[string][]int64
+	children map[int64][]int64
+	synonyms map[int64][]int64
+}
+
+// OpenBackbone reads a local copy of the GBIF backbone
+// so species requests
+// (SpeciesID, TaxonName, Children, and Synonym)
+// will be resolved without an internet connection.
+//
+// The path can be the GBIF backbone Darwin Core Archive
+// (a zip file),
+// a directory with the uncompressed archive,
+// or a taxon table
+// (for example a subset of the backbone Taxon.tsv file)
+// with, at least, the following columns:
+// taxonID, parentNameUsageID, acceptedNameUsageID,
+// scientificName, canonicalName, taxonRank,
+// and taxonomicStatus.
+func OpenBackbone(path string) error {
+	st, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("gbif: backbone: %v", err)
+	}
+
+	var r io.ReadCloser
+	switch {
+	case st.IsDir():
+		r, err = os.Open(filepath.Join(path, "Taxon.tsv"))
+	case strings.ToLower(filepath.Ext(path)) == ".zip":
+		r, err = openZipTaxon(path)
+	default:
+		r, err = os.Open(path)
+	}
+	if err != nil {
+		return fmt.Errorf("gbif: backbone: %v", err)
+	}
+	defer r.Close()
+
+	bb, err := readBackbone(r)
+	if err != nil {
+		return fmt.Errorf("gbif: backbone %q: %v", path, err)
+	}
+	backbone = bb
+	return nil
+}
+
+type zipFile struct {
+	io.ReadCloser
+	z *zip.ReadCloser
+}
+
+func (zf zipFile) Close() error {
+	zf.ReadCloser.Close()
+	return zf.z.Close()
+}
+
+func openZipTaxon(path string) (io.ReadCloser, error) {
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range z.File {
+		if !strings.EqualFold(filepath.Base(f.Name), "Taxon.tsv") {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			z.Close()
+			return nil, err
+		}
+		return zipFile{ReadCloser: r, z: z}, nil
+	}
+	z.Close()
+	return nil, errors.New("file \"Taxon.tsv\" not found in archive")
+}
+
+func readBackbone(r io.Reader) (*localBackbone, error) {
+	tab := tsv.NewReader(r)
+	tab.Comma = '\t'
+
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("header: %v", err)
+	}
+	fields := make(map[string]int, len(header))
+	for i, h := range header {
+		fields[strings.ToLower(h)] = i
+	}
+	for _, h := range []string{"taxonID", "parentNameUsageID", "acceptedNameUsageID", "scientificName", "canonicalName", "taxonRank", "taxonomicStatus"} {
+		if _, ok := fields[strings.ToLower(h)]; !ok {
+			return nil, fmt.Errorf("header: expecting %q field", h)
+		}
+	}
+	field := func(row []string, name string) string {
+		i, ok := fields[name]
+		if !ok {
+			return ""
+		}
+		return row[i]
+	}
+	key := func(row []string, name string) (int64, error) {
+		v := field(row, name)
+		if v == "" {
+			return 0, nil
+		}
+		return strconv.ParseInt(v, 10, 64)
+	}
+
+	bb := &localBackbone{
+		ids:      make(map[int64]*Species),
+		names:    make(map[string][]int64),
+		children: make(map[int64][]int64),
+		synonyms: make(map[int64][]int64),
+	}
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		ln, _ := tab.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", ln, err)
+		}
+
+		id, err := key(row, "taxonid")
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %q: %v", ln, "taxonID", err)
+		}
+		if id == 0 {
+			continue
+		}
+		parent, err := key(row, "parentnameusageid")
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %q: %v", ln, "parentNameUsageID", err)
+		}
+		accepted, err := key(row, "acceptednameusageid")
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %q: %v", ln, "acceptedNameUsageID", err)
+		}
+		basionym, err := key(row, "originalnameusageid")
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %q: %v", ln, "originalNameUsageID", err)
+		}
+
+		sp := &Species{
+			Key:             id,
+			NubKey:          id,
+			AcceptedKey:     accepted,
+			ParentKey:       parent,
+			BasionymKey:     basionym,
+			CanonicalName:   field(row, "canonicalname"),
+			ScientificName:  field(row, "scientificname"),
+			Authorship:      field(row, "scientificnameauthorship"),
+			Rank:            strings.ToUpper(field(row, "taxonrank")),
+			TaxonomicStatus: strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(field(row, "taxonomicstatus")), " ", "_")),
+			DatasetKey:      field(row, "datasetid"),
+			PublishedIn:     field(row, "namepublishedin"),
+			Kingdom:         field(row, "kingdom"),
+			Phylum:          field(row, "phylum"),
+			Class:           field(row, "class"),
+			Order:           field(row, "order"),
+			Family:          field(row, "family"),
+			Genus:           field(row, "genus"),
+		}
+		if sp.AcceptedKey == id {
+			sp.AcceptedKey = 0
+		}
+		bb.ids[id] = sp
+
+		name := strings.ToLower(strings.Join(strings.Fields(sp.CanonicalName), " "))
+		if name != "" {
+			bb.names[name] = append(bb.names[name], id)
+		}
+		if sp.AcceptedKey != 0 {
+			bb.synonyms[sp.AcceptedKey] = append(bb.synonyms[sp.AcceptedKey], id)
+			continue
+		}
+		if parent != 0 {
+			bb.children[parent] = append(bb.children[parent], id)
+		}
+	}
+
+	// fill the parent keys of synonyms
+	// as in the GBIF API,
+	// synonyms use the accepted name as parent.
+	for _, sp := range bb.ids {
+		if sp.AcceptedKey != 0 && sp.ParentKey == 0 {
+			sp.ParentKey = sp.AcceptedKey
+		}
+	}
+	return bb, nil
+}
+
+func (bb *localBackbone) species(id string) (*Species, error) {
+	k, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("gbif: species: invalid ID %q", id)
+	}
+	sp, ok := bb.ids[k]
+	if !ok {
+		return nil, fmt.Errorf("gbif: species: ID %d: backbone: %w", k, ErrNotFound)
+	}
+	c := *sp
+	return &c, nil
+}
+
+func (bb *localBackbone) list(ids []int64) []*Species {
+	ls := make([]*Species, 0, len(ids))
+	for _, id := range ids {
+		c := *bb.ids[id]
+		ls = append(ls, &c)
+	}
+	return ls
+}
@@ -0,0 +1,63 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package gbif_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/js-arias/gbifer/gbif"
+)
+
+const backboneTaxa = "taxonID\tparentNameUsageID\tacceptedNameUsageID\tscientificName\tscientificNameAuthorship\tcanonicalName\ttaxonRank\ttaxonomicStatus\n" +
+	"2435098\t9703\t\tPuma Jardine, 1834\tJardine, 1834\tPuma\tgenus\taccepted\n" +
+	"2435099\t2435098\t\tPuma concolor (Linnaeus, 1771)\t(Linnaeus, 1771)\tPuma concolor\tspecies\taccepted\n" +
+	"5219426\t\t2435099\tFelis concolor Linnaeus, 1771\tLinnaeus, 1771\tFelis concolor\tspecies\theterotypic synonym\n"
+
+func TestBackbone(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "Taxon.tsv")
+	if err := os.WriteFile(name, []byte(backboneTaxa), 0644); err != nil {
+		t.Fatalf("unable to write backbone: %v", err)
+	}
+	if err := gbif.OpenBackbone(name); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sp, err := gbif.SpeciesID("5219426")
+	if err != nil {
+		t.Fatalf("species: unexpected error: %v", err)
+	}
+	if sp.AcceptedKey != 2435099 || sp.ParentKey != 2435099 {
+		t.Errorf("species: got accepted %d, parent %d, want %d", sp.AcceptedKey, sp.ParentKey, 2435099)
+	}
+	if sp.TaxonomicStatus != "HETEROTYPIC_SYNONYM" {
+		t.Errorf("species: got status %q, want %q", sp.TaxonomicStatus, "HETEROTYPIC_SYNONYM")
+	}
+
+	ls, err := gbif.TaxonName("puma  concolor")
+	if err != nil {
+		t.Fatalf("name: unexpected error: %v", err)
+	}
+	if len(ls) != 1 || ls[0].Key != 2435099 {
+		t.Errorf("name: got %d taxa, want %d", len(ls), 1)
+	}
+
+	ls, err = gbif.Children(2435098)
+	if err != nil {
+		t.Fatalf("children: unexpected error: %v", err)
+	}
+	if len(ls) != 1 || ls[0].Key != 2435099 {
+		t.Errorf("children: got %d taxa, want %d", len(ls), 1)
+	}
+
+	ls, err = gbif.Synonym(2435099)
+	if err != nil {
+		t.Fatalf("synonym: unexpected error: %v", err)
+	}
+	if len(ls) != 1 || ls[0].Key != 5219426 {
+		t.Errorf("synonym: got %d taxa, want %d", len(ls), 1)
+	}
+}
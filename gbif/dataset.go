@@ -0,0 +1,199 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package gbif
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Dataset stores the information of a GBIF dataset.
+type Dataset struct {
+	Key      string // dataset key
+	Title    string
+	DOI      string
+	License  string
+	PubDate  string // publication date
+	Citation struct {
+		Text string
+	}
+
+	Type                        string // e.g. OCCURRENCE, or CHECKLIST
+	PublishingOrganizationKey   string
+	PublishingOrganizationTitle string // only filled by DatasetSearch
+}
+
+// DatasetKey returns a Dataset from a GBIF dataset key.
+// If the dataset is not found
+// it returns an error that wraps ErrNotFound.
+func DatasetKey(key string) (*Dataset, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, errors.New("gbif: dataset: search an empty key")
+	}
+
+	var err error
+	for r := 0; r < Retry; r++ {
+		req := newRequest("dataset/"+key, r)
+		select {
+		case err = <-req.err:
+			continue
+		case a := <-req.ans:
+			if a.StatusCode == http.StatusNotFound {
+				a.Body.Close()
+				return nil, fmt.Errorf("gbif: dataset: key %s: %w", key, ErrNotFound)
+			}
+			d := json.NewDecoder(a.Body)
+			ds := &Dataset{}
+			err = d.Decode(ds)
+			a.Body.Close()
+			if err != nil {
+				continue
+			}
+			return ds, nil
+		}
+	}
+	if err == nil {
+		return nil, fmt.Errorf("gbif: dataset: no answer after %d retries", Retry)
+	}
+	return nil, fmt.Errorf("gbif: dataset: %v", err)
+}
+
+type dsAnswer struct {
+	Offset, Limit int64
+	EndOfRecords  bool
+	Results       []*Dataset
+}
+
+// dsLimit is the number of datasets
+// requested in each page of a dataset search.
+const dsLimit = 100
+
+// DatasetSearch returns the datasets
+// that match a full text query.
+// If tp is not empty,
+// only the datasets of the given type
+// (OCCURRENCE, CHECKLIST, METADATA, or SAMPLING_EVENT)
+// are returned.
+// If publishingOrg is not empty,
+// only the datasets published by the organization
+// with the given key
+// are returned.
+// At least one of the search terms must be defined.
+//
+// It requires an internet connection.
+func DatasetSearch(query, tp, publishingOrg string) ([]*Dataset, error) {
+	query = strings.Join(strings.Fields(query), " ")
+	tp = strings.ToUpper(strings.TrimSpace(tp))
+	publishingOrg = strings.TrimSpace(publishingOrg)
+	if query == "" && tp == "" && publishingOrg == "" {
+		return nil, errors.New("gbif: dataset: search without terms")
+	}
+
+	param := url.Values{}
+	if query != "" {
+		param.Add("q", query)
+	}
+	if tp != "" {
+		param.Add("type", tp)
+	}
+	if publishingOrg != "" {
+		param.Add("publishingOrg", publishingOrg)
+	}
+	param.Add("limit", strconv.Itoa(dsLimit))
+	param.Add("offset", "0")
+
+	var ls []*Dataset
+	var err error
+	end := false
+	for off := int64(0); !end; {
+		if off > 0 {
+			param.Set("offset", strconv.FormatInt(off, 10))
+		}
+		retryErr := true
+		for r := 0; r < Retry; r++ {
+			req := newRequest("dataset/search?"+param.Encode(), r)
+			select {
+			case err = <-req.err:
+				continue
+			case a := <-req.ans:
+				d := json.NewDecoder(a.Body)
+				resp := &dsAnswer{}
+				err = d.Decode(resp)
+				a.Body.Close()
+				if err != nil {
+					continue
+				}
+				ls = append(ls, resp.Results...)
+				if resp.EndOfRecords || resp.Limit == 0 {
+					end = true
+				}
+				off += resp.Limit
+				if !end && MaxRecords > 0 && off >= int64(MaxRecords) {
+					return nil, fmt.Errorf("gbif: dataset: %w: more than %d records", ErrTooMany, MaxRecords)
+				}
+				r = Retry
+				retryErr = false
+			}
+		}
+		if retryErr {
+			if err == nil {
+				return nil, fmt.Errorf("gbif: dataset: no answer after %d retries", Retry)
+			}
+			return nil, fmt.Errorf("gbif: dataset: %v", err)
+		}
+	}
+	return ls, nil
+}
+
+// Organization stores the information
+// of a GBIF publishing organization.
+type Organization struct {
+	Key     string
+	Title   string
+	Country string // ISO 3166-1 alpha-2 code
+}
+
+// OrganizationKey returns an Organization
+// from a GBIF publishing organization key.
+// If the organization is not found
+// it returns an error that wraps ErrNotFound.
+func OrganizationKey(key string) (*Organization, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, errors.New("gbif: organization: search an empty key")
+	}
+
+	var err error
+	for r := 0; r < Retry; r++ {
+		req := newRequest("organization/"+key, r)
+		select {
+		case err = <-req.err:
+			continue
+		case a := <-req.ans:
+			if a.StatusCode == http.StatusNotFound {
+				a.Body.Close()
+				return nil, fmt.Errorf("gbif: organization: key %s: %w", key, ErrNotFound)
+			}
+			d := json.NewDecoder(a.Body)
+			org := &Organization{}
+			err = d.Decode(org)
+			a.Body.Close()
+			if err != nil {
+				continue
+			}
+			return org, nil
+		}
+	}
+	if err == nil {
+		return nil, fmt.Errorf("gbif: organization: no answer after %d retries", Retry)
+	}
+	return nil, fmt.Errorf("gbif: organization: %v", err)
+}
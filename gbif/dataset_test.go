@@ -0,0 +1,81 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package gbif_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/js-arias/gbifer/gbif"
+)
+
+func TestDatasetSearch(t *testing.T) {
+	setTransport(t, apiTransport{
+		"dataset/search?limit=100&offset=0&q=herbarium&type=OCCURRENCE": `{"offset":0,"limit":2,"endOfRecords":false,"results":[{"key":"ds-1","title":"Herbarium A","type":"OCCURRENCE"},{"key":"ds-2","title":"Herbarium B","type":"OCCURRENCE"}]}`,
+		"dataset/search?limit=100&offset=2&q=herbarium&type=OCCURRENCE": `{"offset":2,"limit":2,"endOfRecords":true,"results":[{"key":"ds-3","title":"Herbarium C","type":"OCCURRENCE"}]}`,
+		"dataset/search?limit=100&offset=0&publishingOrg=org-1":         `{"offset":0,"limit":100,"endOfRecords":true,"results":[{"key":"ds-4","title":"Checklist","type":"CHECKLIST","publishingOrganizationKey":"org-1","publishingOrganizationTitle":"Museum"}]}`,
+	})
+
+	tests := map[string]struct {
+		query, tp, org string
+		want           []string
+	}{
+		"query and type": {
+			query: " herbarium ",
+			tp:    "occurrence",
+			want:  []string{"ds-1", "ds-2", "ds-3"},
+		},
+		"publisher": {
+			org:  "org-1",
+			want: []string{"ds-4"},
+		},
+	}
+
+	for name, test := range tests {
+		ls, err := gbif.DatasetSearch(test.query, test.tp, test.org)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		if len(ls) != len(test.want) {
+			t.Errorf("%s: got %d datasets, want %d", name, len(ls), len(test.want))
+			continue
+		}
+		for i, ds := range ls {
+			if ds.Key != test.want[i] {
+				t.Errorf("%s: dataset %d: got %q, want %q", name, i, ds.Key, test.want[i])
+			}
+		}
+	}
+
+	if _, err := gbif.DatasetSearch("", "", ""); err == nil {
+		t.Errorf("empty search: expecting error")
+	}
+
+	max := gbif.MaxRecords
+	defer func() { gbif.MaxRecords = max }()
+	gbif.MaxRecords = 2
+	if _, err := gbif.DatasetSearch("herbarium", "occurrence", ""); !errors.Is(err, gbif.ErrTooMany) {
+		t.Errorf("max records: got error %v, want %v", err, gbif.ErrTooMany)
+	}
+}
+
+func TestOrganizationKey(t *testing.T) {
+	setTransport(t, apiTransport{
+		"organization/org-1": `{"key":"org-1","title":"Museum","country":"AR"}`,
+	})
+
+	org, err := gbif.OrganizationKey(" org-1 ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if org.Title != "Museum" || org.Country != "AR" {
+		t.Errorf("got %+v, want title %q and country %q", org, "Museum", "AR")
+	}
+
+	if _, err := gbif.OrganizationKey("org-2"); !errors.Is(err, gbif.ErrNotFound) {
+		t.Errorf("missing: got error %v, want %v", err, gbif.ErrNotFound)
+	}
+}
@@ -0,0 +1,12 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package gbif
+
+// RemoveHooks removes all the hooks.
+func RemoveHooks() {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks = nil
+}
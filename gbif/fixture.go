@@ -0,0 +1,177 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package gbif
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// A fixture is a recorded answer
+// of the GBIF API.
+//
+// The body is stored as raw bytes
+// (encoded as base64 in the JSON file),
+// so answers that are not valid UTF-8
+// are replayed without changes.
+type fixture struct {
+	URL    string `json:"url"`
+	Status int    `json:"status"`
+	Type   string `json:"contentType,omitempty"`
+	Body   []byte `json:"body"`
+}
+
+// fixtureName returns the name of the file
+// of the fixture of a request.
+func fixtureName(dir string, req *http.Request) string {
+	h := sha256.Sum256([]byte(req.Method + " " + req.URL.String()))
+	return filepath.Join(dir, hex.EncodeToString(h[:16])+".json")
+}
+
+// A Recorder is a transport
+// that stores the answers of the GBIF API
+// in a directory,
+// so they can be used later
+// with a Replayer.
+//
+// To record the answers,
+// set the Recorder as the Transport
+// before calling Open.
+type Recorder struct {
+	dir  string
+	next http.RoundTripper
+	once sync.Once
+}
+
+// NewRecorder returns a new Recorder
+// that stores the answers in the indicated directory,
+// and uses the given transport to make the requests.
+// If the transport is nil,
+// the default transport will be used.
+func NewRecorder(dir string, next http.RoundTripper) *Recorder {
+	return &Recorder{dir: dir, next: next}
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (rc *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	rc.once.Do(func() {
+		if rc.next == nil {
+			rc.next = newTransport()
+		}
+	})
+
+	// fixtures are stored uncompressed,
+	// so they do not depend on the compression
+	// used by the server
+	r := req.Clone(req.Context())
+	r.Header.Del("Accept-Encoding")
+	answer, err := rc.next.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	var body io.Reader = answer.Body
+	if strings.EqualFold(answer.Header.Get("Content-Encoding"), "gzip") {
+		// the server ignored the request
+		z, err := gzip.NewReader(answer.Body)
+		if err != nil {
+			answer.Body.Close()
+			return nil, err
+		}
+		body = z
+	}
+	b, err := io.ReadAll(body)
+	answer.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	fx := fixture{
+		URL:    req.URL.String(),
+		Status: answer.StatusCode,
+		Type:   answer.Header.Get("Content-Type"),
+		Body:   b,
+	}
+	if err := os.MkdirAll(rc.dir, 0755); err != nil {
+		return nil, fmt.Errorf("gbif: record: %v", err)
+	}
+	data, err := json.MarshalIndent(fx, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("gbif: record: %v", err)
+	}
+	if err := os.WriteFile(fixtureName(rc.dir, req), data, 0644); err != nil {
+		return nil, fmt.Errorf("gbif: record: %v", err)
+	}
+
+	answer.Body = io.NopCloser(bytes.NewReader(b))
+	answer.Header.Del("Content-Encoding")
+	answer.ContentLength = int64(len(b))
+	return answer, nil
+}
+
+// ErrNoFixture is the error returned by a Replayer
+// when there is no recorded answer
+// for a request.
+var ErrNoFixture = errors.New("no recorded answer")
+
+// A Replayer is a transport
+// that answers the requests
+// with the answers stored by a Recorder,
+// so the requests can be reproduced,
+// for example in tests,
+// without an internet connection.
+type Replayer struct {
+	dir string
+}
+
+// NewReplayer returns a new Replayer
+// that reads the answers from the indicated directory.
+func NewReplayer(dir string) *Replayer {
+	return &Replayer{dir: dir}
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+// If there is no recorded answer for the request,
+// it returns an error that wraps ErrNoFixture.
+func (rp *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	data, err := os.ReadFile(fixtureName(rp.dir, req))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("gbif: replay: %s: %w", req.URL, ErrNoFixture)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gbif: replay: %v", err)
+	}
+
+	var fx fixture
+	if err := json.Unmarshal(data, &fx); err != nil {
+		return nil, fmt.Errorf("gbif: replay: %s: %v", req.URL, err)
+	}
+
+	h := make(http.Header)
+	if fx.Type != "" {
+		h.Set("Content-Type", fx.Type)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fx.Status, http.StatusText(fx.Status)),
+		StatusCode:    fx.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(fx.Body)),
+		ContentLength: int64(len(fx.Body)),
+		Request:       req,
+	}, nil
+}
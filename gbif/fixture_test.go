@@ -0,0 +1,101 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package gbif_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/js-arias/gbifer/gbif"
+)
+
+func TestRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	key := "50c9509d-22c7-4a22-a47d-8c48425ef4a7"
+
+	// record a compressed answer
+	tr := &gzipTransport{}
+	setTransport(t, gbif.NewRecorder(dir, tr))
+
+	ds, err := gbif.DatasetKey(key)
+	if err != nil {
+		t.Fatalf("record: unexpected error: %v", err)
+	}
+	if tr.encoding != "" {
+		t.Errorf("record: accept encoding: got %q, want %q", tr.encoding, "")
+	}
+	want := "iNaturalist Research-grade Observations"
+	if ds.Title != want {
+		t.Errorf("record: title: got %q, want %q", ds.Title, want)
+	}
+
+	// replay without a connection
+	setTransport(t, gbif.NewReplayer(dir))
+	ds, err = gbif.DatasetKey(key)
+	if err != nil {
+		t.Fatalf("replay: unexpected error: %v", err)
+	}
+	if ds.Title != want {
+		t.Errorf("replay: title: got %q, want %q", ds.Title, want)
+	}
+
+	rp := gbif.NewReplayer(dir)
+	req, _ := http.NewRequest(http.MethodGet, "https://api.gbif.org/v1/dataset/unknown", nil)
+	if _, err := rp.RoundTrip(req); !errors.Is(err, gbif.ErrNoFixture) {
+		t.Errorf("replay: got error %v, want %v", err, gbif.ErrNoFixture)
+	}
+}
+
+// rawTransport answers all requests
+// with the same body.
+type rawTransport []byte
+
+func (t rawTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	h := make(http.Header)
+	h.Set("Content-Type", "text/plain; charset=ISO-8859-1")
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     h,
+		Body:       io.NopCloser(bytes.NewReader(t)),
+		Request:    req,
+	}, nil
+}
+
+func TestRecordReplayRaw(t *testing.T) {
+	dir := t.TempDir()
+
+	// "Pérez" in ISO-8859-1,
+	// that is not valid UTF-8
+	body := []byte{'P', 0xe9, 'r', 'e', 'z', '\n', 0xff, 0xfe}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.gbif.org/v1/species/1/name", nil)
+
+	rc := gbif.NewRecorder(dir, rawTransport(body))
+	a, err := rc.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("record: unexpected error: %v", err)
+	}
+	got, _ := io.ReadAll(a.Body)
+	a.Body.Close()
+	if !bytes.Equal(got, body) {
+		t.Errorf("record: body: got %q, want %q", got, body)
+	}
+
+	rp := gbif.NewReplayer(dir)
+	a, err = rp.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("replay: unexpected error: %v", err)
+	}
+	got, _ = io.ReadAll(a.Body)
+	a.Body.Close()
+	if !bytes.Equal(got, body) {
+		t.Errorf("replay: body: got %q, want %q", got, body)
+	}
+	if want := "text/plain; charset=ISO-8859-1"; a.Header.Get("Content-Type") != want {
+		t.Errorf("replay: content type: got %q, want %q", a.Header.Get("Content-Type"), want)
+	}
+}
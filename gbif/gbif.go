@@ -7,8 +7,14 @@
 package gbif
 
 import (
+	"compress/gzip"
+	"errors"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -19,11 +25,57 @@ var Retry = 5
 // Timeout is the timeout of the http request.
 var Timeout = 20 * time.Second
 
+// Client is the HTTP client used for the requests.
+// If it is nil when Open is called,
+// the client returned by NewClient
+// will be used.
+var Client *http.Client
+
+// Transport is the transport used by the client
+// returned by NewClient.
+// If it is nil,
+// a transport that keeps the connections alive,
+// and uses Proxy,
+// will be used.
+var Transport http.RoundTripper
+
+// Proxy is the URL of the proxy
+// used by the default transport.
+// If it is nil,
+// the proxy is defined by the environment variables
+// HTTPS_PROXY, HTTP_PROXY, and NO_PROXY.
+var Proxy *url.URL
+
+// User and Password are the credentials
+// of a GBIF account.
+// If User is defined,
+// the requests are authenticated
+// with HTTP basic authentication.
+var (
+	User     string
+	Password string
+)
+
 // Wait is the waiting time for a new request
 // (we don't want to overload the GBIF server!).
 var Wait = time.Millisecond * 300
 
-// Buffer is the maximum number of requests in the request queue.
+// MaxRecords is the maximum number of records
+// (for example, taxa or datasets)
+// retrieved by a request that returns a list of records
+// in several pages.
+// If a request would return more records,
+// it fails with an error that wraps ErrTooMany.
+// If it is 0,
+// the number of records is not limited.
+var MaxRecords = 10_000
+
+// ErrTooMany is the error returned
+// when a request would return more than MaxRecords records.
+var ErrTooMany = errors.New("too many records")
+
+// Buffer is the maximum number of requests in the request queue,
+// and the maximum number of concurrent requests.
 var Buffer = 10
 
 // Open opens GBIF requests.
@@ -31,20 +83,24 @@ func Open() {
 	once.Do(initReqs)
 }
 
-const wsHead = "http://api.gbif.org/v1/"
+const wsHead = "https://api.gbif.org/v1/"
 
 type request struct {
-	req string
-	ans chan *http.Response
-	err chan error
+	req     string
+	attempt int
+	ans     chan *http.Response
+	err     chan error
 }
 
 // NewRequest sends a request to the request channel.
-func newRequest(req string) request {
+// Attempt is the number of the attempt
+// (0 for the first attempt).
+func newRequest(req string, attempt int) request {
 	r := request{
-		req: wsHead + req,
-		ans: make(chan *http.Response),
-		err: make(chan error),
+		req:     wsHead + req,
+		attempt: attempt,
+		ans:     make(chan *http.Response),
+		err:     make(chan error),
 	}
 	reqChan.cReqs <- r
 	return r
@@ -59,21 +115,139 @@ var once sync.Once
 var reqChan *reqChanType
 
 func initReqs() {
-	http.DefaultClient.Timeout = Timeout
+	if Client == nil {
+		Client = NewClient()
+	}
 	reqChan = &reqChanType{cReqs: make(chan request, Buffer)}
 	go reqChan.reqs()
 }
 
+// requests is the number of requests
+// made to the GBIF API.
+var requests atomic.Int64
+
+// Requests returns the number of requests
+// made to the GBIF API.
+func Requests() int64 {
+	return requests.Load()
+}
+
 func (rc *reqChanType) reqs() {
+	// requests are made concurrently,
+	// up to Buffer requests at the same time,
+	// but a new request is only started
+	// after the waiting time.
+	inFlight := make(chan struct{}, Buffer)
 	for r := range rc.cReqs {
-		answer, err := http.Get(r.req)
-		if err != nil {
-			r.err <- err
-			continue
-		}
-		r.ans <- answer
+		inFlight <- struct{}{}
+		requests.Add(1)
+		go func(r request) {
+			defer func() { <-inFlight }()
+			hooksRequest(r.req, r.attempt)
+			st := time.Now()
+			answer, err := get(r.req)
+			status := 0
+			if answer != nil {
+				status = answer.StatusCode
+			}
+			hooksResponse(r.req, status, time.Since(st), err)
+			if err != nil {
+				r.err <- err
+				return
+			}
+			r.ans <- answer
+		}(r)
 
 		// we do not want to overload the gbif server.
 		time.Sleep(Wait)
 	}
 }
+
+// NewClient returns an HTTP client
+// with the given Timeout
+// that uses Transport,
+// or, if Transport is nil,
+// a transport that reuses the connections to the server,
+// and uses Proxy.
+func NewClient() *http.Client {
+	if Transport != nil {
+		return &http.Client{
+			Transport: Transport,
+			Timeout:   Timeout,
+		}
+	}
+
+	return &http.Client{
+		Transport: newTransport(),
+		Timeout:   Timeout,
+	}
+}
+
+// newTransport returns a transport
+// that reuses the connections to the server,
+// and uses Proxy.
+func newTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.ForceAttemptHTTP2 = true
+	t.MaxIdleConnsPerHost = Buffer
+	if Proxy != nil {
+		t.Proxy = http.ProxyURL(Proxy)
+	}
+	return t
+}
+
+// get makes a GET request
+// that accepts compressed answers.
+func get(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+	if User != "" {
+		req.SetBasicAuth(User, Password)
+	}
+
+	answer, err := Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(answer.Header.Get("Content-Encoding"), "gzip") {
+		answer.Body = &body{r: answer.Body, c: answer.Body}
+		return answer, nil
+	}
+
+	z, err := gzip.NewReader(answer.Body)
+	if err != nil {
+		answer.Body.Close()
+		return nil, err
+	}
+	answer.Header.Del("Content-Encoding")
+	answer.Header.Del("Content-Length")
+	answer.ContentLength = -1
+	answer.Uncompressed = true
+	answer.Body = &body{r: z, c: answer.Body}
+	return answer, nil
+}
+
+// maxDrain is the maximum number of bytes
+// read from an unread answer
+// before closing it.
+const maxDrain = 64 << 10
+
+// A body is the body of an answer
+// that drains the connection when closed,
+// so the connection can be reused.
+type body struct {
+	r io.Reader
+	c io.Closer
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	return b.r.Read(p)
+}
+
+func (b *body) Close() error {
+	io.CopyN(io.Discard, b.r, maxDrain)
+	return b.c.Close()
+}
@@ -0,0 +1,169 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package gbif_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/js-arias/gbifer/gbif"
+)
+
+// The GBIF requests are opened only once,
+// so the client and the waiting time
+// are set before any test,
+// and each test sets the transport it uses
+// with setTransport.
+func TestMain(m *testing.M) {
+	gbif.Client = &http.Client{Transport: transport}
+	gbif.Wait = 0
+	gbif.Open()
+	os.Exit(m.Run())
+}
+
+// transport is the transport
+// of the client used in the tests.
+var transport = &testTransport{}
+
+// testTransport is a transport
+// that sends the requests
+// to the transport of the current test.
+type testTransport struct {
+	mu sync.Mutex
+	tr http.RoundTripper
+}
+
+func (t *testTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	tr := t.tr
+	t.mu.Unlock()
+	if tr == nil {
+		return nil, errors.New("test without transport")
+	}
+	return tr.RoundTrip(req)
+}
+
+// setTransport sets the transport
+// used by the requests of a test.
+func setTransport(t testing.TB, tr http.RoundTripper) {
+	transport.mu.Lock()
+	transport.tr = tr
+	transport.mu.Unlock()
+
+	t.Cleanup(func() {
+		transport.mu.Lock()
+		transport.tr = nil
+		transport.mu.Unlock()
+	})
+}
+
+const datasetAnswer = `{"key":"50c9509d-22c7-4a22-a47d-8c48425ef4a7","title":"iNaturalist Research-grade Observations","license":"http://creativecommons.org/licenses/by-nc/4.0/legalcode"}`
+
+// gzipTransport answers all requests
+// with a compressed dataset.
+type gzipTransport struct {
+	encoding string
+	auth     string
+}
+
+func (t *gzipTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.encoding = req.Header.Get("Accept-Encoding")
+	t.auth = req.Header.Get("Authorization")
+
+	var buf bytes.Buffer
+	z := gzip.NewWriter(&buf)
+	io.WriteString(z, datasetAnswer)
+	z.Close()
+
+	h := make(http.Header)
+	h.Set("Content-Encoding", "gzip")
+	h.Set("Content-Type", "application/json")
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     h,
+		Body:       io.NopCloser(&buf),
+		Request:    req,
+	}, nil
+}
+
+func TestClient(t *testing.T) {
+	tr := &gzipTransport{}
+	setTransport(t, tr)
+
+	ds, err := gbif.DatasetKey("50c9509d-22c7-4a22-a47d-8c48425ef4a7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tr.encoding != "gzip" {
+		t.Errorf("accept encoding: got %q, want %q", tr.encoding, "gzip")
+	}
+	if want := "iNaturalist Research-grade Observations"; ds.Title != want {
+		t.Errorf("title: got %q, want %q", ds.Title, want)
+	}
+	if tr.auth != "" {
+		t.Errorf("authorization: got %q, want none", tr.auth)
+	}
+}
+
+func TestClientCredentials(t *testing.T) {
+	defer func() {
+		gbif.User = ""
+		gbif.Password = ""
+	}()
+
+	tr := &gzipTransport{}
+	gbif.User = "user"
+	gbif.Password = "secret"
+	setTransport(t, tr)
+
+	if _, err := gbif.DatasetKey("50c9509d-22c7-4a22-a47d-8c48425ef4a7"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.gbif.org", nil)
+	req.SetBasicAuth("user", "secret")
+	if want := req.Header.Get("Authorization"); tr.auth != want {
+		t.Errorf("authorization: got %q, want %q", tr.auth, want)
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	defer func() {
+		gbif.Transport = nil
+		gbif.Proxy = nil
+	}()
+
+	proxy, _ := url.Parse("http://proxy.example.org:3128")
+	gbif.Proxy = proxy
+	c := gbif.NewClient()
+	tr, ok := c.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("proxy: unexpected transport %T", c.Transport)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.gbif.org/v1/species/1", nil)
+	u, err := tr.Proxy(req)
+	if err != nil {
+		t.Fatalf("proxy: unexpected error: %v", err)
+	}
+	if u == nil || u.String() != proxy.String() {
+		t.Errorf("proxy: got %v, want %v", u, proxy)
+	}
+
+	custom := &gzipTransport{}
+	gbif.Transport = custom
+	c = gbif.NewClient()
+	if c.Transport != custom {
+		t.Errorf("transport: got %T, want custom transport", c.Transport)
+	}
+	if c.Timeout != gbif.Timeout {
+		t.Errorf("timeout: got %v, want %v", c.Timeout, gbif.Timeout)
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package gbif
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// A Hook receives the events
+// of the requests made to the GBIF API,
+// for example,
+// to log the requests,
+// or to measure the time spent on them.
+//
+// Requests are made concurrently,
+// so a Hook must be safe for concurrent use.
+type Hook interface {
+	// Request is called before a request is sent,
+	// with the URL of the request,
+	// and the number of the attempt
+	// (0 for the first attempt,
+	// and greater than 0 for retries).
+	Request(url string, attempt int)
+
+	// Response is called after a request is answered,
+	// with the URL of the request,
+	// the status code of the answer
+	// (0 if there is no answer),
+	// the time elapsed since the request was sent,
+	// and the error of the request,
+	// if any.
+	Response(url string, status int, elapsed time.Duration, err error)
+}
+
+var (
+	hooksMu sync.RWMutex
+	hooks   []Hook
+)
+
+// AddHook adds a hook
+// that will receive the events
+// of all the requests to the GBIF API.
+func AddHook(h Hook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks = append(hooks, h)
+}
+
+func hooksRequest(url string, attempt int) {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	for _, h := range hooks {
+		h.Request(url, attempt)
+	}
+}
+
+func hooksResponse(url string, status int, elapsed time.Duration, err error) {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	for _, h := range hooks {
+		h.Response(url, status, elapsed, err)
+	}
+}
+
+// Metrics is a Hook that collects,
+// for each endpoint of the GBIF API,
+// the number of requests,
+// retries,
+// and failures,
+// and the time spent on the requests.
+type Metrics struct {
+	mu  sync.Mutex
+	eps map[string]*EndpointStats
+}
+
+// EndpointStats are the metrics
+// of an endpoint of the GBIF API.
+type EndpointStats struct {
+	// Endpoint is the path of the endpoint
+	// without IDs or parameters,
+	// for example "species/children".
+	Endpoint string
+
+	Requests int64         // number of requests
+	Retries  int64         // requests that are retries
+	Failures int64         // requests without answer, or with a server error
+	Time     time.Duration // total time of the requests
+}
+
+// NewMetrics returns a new Metrics hook.
+func NewMetrics() *Metrics {
+	return &Metrics{eps: make(map[string]*EndpointStats)}
+}
+
+// Request implements the Hook interface.
+func (m *Metrics) Request(url string, attempt int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ep := m.endpoint(url)
+	ep.Requests++
+	if attempt > 0 {
+		ep.Retries++
+	}
+}
+
+// Response implements the Hook interface.
+func (m *Metrics) Response(url string, status int, elapsed time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ep := m.endpoint(url)
+	ep.Time += elapsed
+	if err != nil || status >= http.StatusInternalServerError {
+		ep.Failures++
+	}
+}
+
+func (m *Metrics) endpoint(url string) *EndpointStats {
+	name := endpointName(url)
+	ep, ok := m.eps[name]
+	if !ok {
+		ep = &EndpointStats{Endpoint: name}
+		m.eps[name] = ep
+	}
+	return ep
+}
+
+// Stats returns the metrics of each endpoint,
+// sorted by the name of the endpoint.
+func (m *Metrics) Stats() []EndpointStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ls := make([]EndpointStats, 0, len(m.eps))
+	for _, ep := range m.eps {
+		ls = append(ls, *ep)
+	}
+	slices.SortFunc(ls, func(a, b EndpointStats) int {
+		return strings.Compare(a.Endpoint, b.Endpoint)
+	})
+	return ls
+}
+
+// endpointName returns the name of the endpoint
+// of a request URL,
+// i.e., the path without parameters,
+// and without the elements that contain digits
+// (IDs, dataset keys, or DOIs).
+func endpointName(url string) string {
+	url = strings.TrimPrefix(url, wsHead)
+	if i := strings.IndexByte(url, '?'); i >= 0 {
+		url = url[:i]
+	}
+	var path []string
+	for _, p := range strings.Split(url, "/") {
+		if p == "" || strings.IndexFunc(p, unicode.IsDigit) >= 0 {
+			continue
+		}
+		path = append(path, p)
+	}
+	return strings.Join(path, "/")
+}
@@ -0,0 +1,88 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package gbif_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/js-arias/gbifer/gbif"
+)
+
+// failTransport fails the first request
+// and answers the other requests
+// with a dataset.
+type failTransport struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (t *failTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.calls++
+	n := t.calls
+	t.mu.Unlock()
+	if n == 1 {
+		return nil, errors.New("connection reset")
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader(datasetAnswer)),
+		Request:    req,
+	}, nil
+}
+
+// logHook records the requested URLs.
+type logHook struct {
+	mu   sync.Mutex
+	urls []string
+}
+
+func (h *logHook) Request(url string, attempt int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.urls = append(h.urls, url)
+}
+
+func (h *logHook) Response(url string, status int, elapsed time.Duration, err error) {}
+
+func TestHooks(t *testing.T) {
+	setTransport(t, &failTransport{})
+
+	t.Cleanup(gbif.RemoveHooks)
+
+	m := gbif.NewMetrics()
+	gbif.AddHook(m)
+	lh := &logHook{}
+	gbif.AddHook(lh)
+
+	key := "50c9509d-22c7-4a22-a47d-8c48425ef4a7"
+	if _, err := gbif.DatasetKey(key); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "https://api.gbif.org/v1/dataset/" + key
+	if len(lh.urls) != 2 || lh.urls[0] != want || lh.urls[1] != want {
+		t.Errorf("urls: got %v, want %q twice", lh.urls, want)
+	}
+
+	st := m.Stats()
+	if len(st) != 1 {
+		t.Fatalf("stats: got %d endpoints, want %d", len(st), 1)
+	}
+	ep := st[0]
+	if ep.Endpoint != "dataset" {
+		t.Errorf("endpoint: got %q, want %q", ep.Endpoint, "dataset")
+	}
+	if ep.Requests != 2 || ep.Retries != 1 || ep.Failures != 1 {
+		t.Errorf("stats: got %d requests, %d retries, %d failures, want 2, 1, 1", ep.Requests, ep.Retries, ep.Failures)
+	}
+}
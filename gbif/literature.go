@@ -0,0 +1,172 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package gbif
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// A Publication is a publication
+// (for example, a journal article)
+// that cites GBIF data,
+// as tracked by the GBIF literature service.
+type Publication struct {
+	ID             string
+	Title          string
+	Authors        []Author
+	Year           int
+	Source         string // e.g. the journal name
+	LiteratureType string // e.g. JOURNAL
+	Identifiers    struct {
+		DOI string
+	}
+	Websites        []string
+	GBIFDownloadKey []string // keys of the cited downloads
+	PeerReview      bool
+	OpenAccess      bool
+}
+
+// An Author is an author of a publication.
+type Author struct {
+	FirstName string
+	LastName  string
+}
+
+type litAnswer struct {
+	Offset, Limit int64
+	EndOfRecords  bool
+	Results       []*Publication
+}
+
+// LiteratureDataset returns the publications
+// that cite a dataset.
+//
+// It requires an internet connection.
+func LiteratureDataset(key string) ([]*Publication, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, errors.New("gbif: literature: search an empty dataset key")
+	}
+	param := url.Values{}
+	param.Add("gbifDatasetKey", key)
+	return literature(param)
+}
+
+// LiteratureDownload returns the publications
+// that cite a download.
+// The download can be defined by its key
+// (for example, 0001234-230810091245214)
+// or its DOI
+// (for example, 10.15468/dl.abcd12).
+//
+// If the download is defined by a DOI
+// and the DOI is not found
+// it returns an error that wraps ErrNotFound.
+//
+// It requires an internet connection.
+func LiteratureDownload(key string) ([]*Publication, error) {
+	key = strings.TrimSpace(key)
+	key = strings.TrimPrefix(key, "https://doi.org/")
+	key = strings.TrimPrefix(key, "doi:")
+	if key == "" {
+		return nil, errors.New("gbif: literature: search an empty download key")
+	}
+	if strings.HasPrefix(key, "10.") {
+		k, err := downloadKey(key)
+		if err != nil {
+			return nil, err
+		}
+		key = k
+	}
+
+	param := url.Values{}
+	param.Add("gbifDownloadKey", key)
+	return literature(param)
+}
+
+// downloadKey returns the key of a download
+// from its DOI.
+func downloadKey(doi string) (string, error) {
+	var err error
+	for r := 0; r < Retry; r++ {
+		req := newRequest("occurrence/download/"+doi, r)
+		select {
+		case err = <-req.err:
+			continue
+		case a := <-req.ans:
+			if a.StatusCode == http.StatusNotFound {
+				a.Body.Close()
+				return "", fmt.Errorf("gbif: literature: download %s: %w", doi, ErrNotFound)
+			}
+			d := json.NewDecoder(a.Body)
+			var dl struct {
+				Key string
+			}
+			err = d.Decode(&dl)
+			a.Body.Close()
+			if err != nil {
+				continue
+			}
+			return dl.Key, nil
+		}
+	}
+	if err == nil {
+		return "", fmt.Errorf("gbif: literature: no answer after %d retries", Retry)
+	}
+	return "", fmt.Errorf("gbif: literature: %v", err)
+}
+
+func literature(param url.Values) ([]*Publication, error) {
+	request := "literature/search?"
+	param.Set("offset", "0")
+
+	var ls []*Publication
+	var err error
+	end := false
+	for off := int64(0); !end; {
+		if off > 0 {
+			param.Set("offset", strconv.FormatInt(off, 10))
+		}
+		retryErr := true
+		for r := 0; r < Retry; r++ {
+			req := newRequest(request+param.Encode(), r)
+			select {
+			case err = <-req.err:
+				continue
+			case a := <-req.ans:
+				d := json.NewDecoder(a.Body)
+				resp := &litAnswer{}
+				err = d.Decode(resp)
+				a.Body.Close()
+				if err != nil {
+					continue
+				}
+				ls = append(ls, resp.Results...)
+				if resp.EndOfRecords || resp.Limit == 0 {
+					end = true
+				}
+				off += resp.Limit
+				if !end && MaxRecords > 0 && off >= int64(MaxRecords) {
+					return nil, fmt.Errorf("gbif: literature: %w: more than %d records", ErrTooMany, MaxRecords)
+				}
+				r = Retry
+				retryErr = false
+			}
+		}
+		if retryErr {
+			if err == nil {
+				return nil, fmt.Errorf("gbif: literature: no answer after %d retries", Retry)
+			}
+			return nil, fmt.Errorf("gbif: literature: %v", err)
+		}
+	}
+	return ls, nil
+}
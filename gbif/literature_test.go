@@ -0,0 +1,89 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package gbif_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/gbifer/gbif"
+)
+
+// apiTransport answers the requests
+// with the answer of the request URL.
+type apiTransport map[string]string
+
+func (t apiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	u := strings.TrimPrefix(req.URL.String(), "https://api.gbif.org/v1/")
+	ans, ok := t[u]
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader(ans)),
+		Request:    req,
+	}, nil
+}
+
+func TestLiterature(t *testing.T) {
+	setTransport(t, apiTransport{
+		"literature/search?gbifDatasetKey=ds-1&offset=0":                     `{"offset":0,"limit":1,"endOfRecords":false,"results":[{"id":"a","title":"First","year":2021,"authors":[{"firstName":"Ana","lastName":"Díaz"}],"identifiers":{"doi":"10.1/a"}}]}`,
+		"literature/search?gbifDatasetKey=ds-1&offset=1":                     `{"offset":1,"limit":1,"endOfRecords":true,"results":[{"id":"b","title":"Second","year":2022}]}`,
+		"occurrence/download/10.15468/dl.abcd12":                             `{"key":"0001234-230810091245214","doi":"10.15468/dl.abcd12"}`,
+		"literature/search?gbifDownloadKey=0001234-230810091245214&offset=0": `{"offset":0,"limit":20,"endOfRecords":true,"results":[{"id":"c","title":"Third","gbifDownloadKey":["0001234-230810091245214"]}]}`,
+	})
+
+	tests := map[string]struct {
+		search func(string) ([]*gbif.Publication, error)
+		key    string
+		want   []string
+	}{
+		"dataset": {
+			search: gbif.LiteratureDataset,
+			key:    "ds-1",
+			want:   []string{"First", "Second"},
+		},
+		"download key": {
+			search: gbif.LiteratureDownload,
+			key:    "0001234-230810091245214",
+			want:   []string{"Third"},
+		},
+		"download DOI": {
+			search: gbif.LiteratureDownload,
+			key:    "https://doi.org/10.15468/dl.abcd12",
+			want:   []string{"Third"},
+		},
+	}
+
+	for name, test := range tests {
+		ls, err := test.search(test.key)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		if len(ls) != len(test.want) {
+			t.Errorf("%s: got %d publications, want %d", name, len(ls), len(test.want))
+			continue
+		}
+		for i, p := range ls {
+			if p.Title != test.want[i] {
+				t.Errorf("%s: publication %d: got %q, want %q", name, i, p.Title, test.want[i])
+			}
+		}
+	}
+
+	ls, _ := gbif.LiteratureDataset("ds-1")
+	if a := ls[0].Authors; len(a) != 1 || a[0].LastName != "Díaz" {
+		t.Errorf("authors: got %v", a)
+	}
+	if doi := ls[0].Identifiers.DOI; doi != "10.1/a" {
+		t.Errorf("doi: got %q, want %q", doi, "10.1/a")
+	}
+}
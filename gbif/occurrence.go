@@ -0,0 +1,408 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package gbif
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Occurrence returns the fields of an occurrence record
+// from a GBIF ID.
+// The fields are returned as strings,
+// using the names of the GBIF API
+// (which are the same names used in occurrence tables
+// for most Darwin Core terms).
+// Array fields
+// (for example, issues)
+// are returned as a list of values separated by ';'.
+//
+// If the record is not found
+// it returns an error that wraps ErrNotFound.
+func Occurrence(id string) (map[string]string, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil, errors.New("gbif: occurrence: search an empty ID")
+	}
+
+	var err error
+	for r := 0; r < Retry; r++ {
+		req := newRequest("occurrence/"+id, r)
+		select {
+		case err = <-req.err:
+			continue
+		case a := <-req.ans:
+			if a.StatusCode == http.StatusNotFound {
+				a.Body.Close()
+				return nil, fmt.Errorf("gbif: occurrence: ID %s: %w", id, ErrNotFound)
+			}
+			d := json.NewDecoder(a.Body)
+			d.UseNumber()
+			var v map[string]any
+			err = d.Decode(&v)
+			a.Body.Close()
+			if err != nil {
+				continue
+			}
+			return occFields(v), nil
+		}
+	}
+	if err == nil {
+		return nil, fmt.Errorf("gbif: occurrence: no answer after %d retries", Retry)
+	}
+	return nil, fmt.Errorf("gbif: occurrence: %v", err)
+}
+
+func occFields(v map[string]any) map[string]string {
+	rec := make(map[string]string, len(v))
+	for k, x := range v {
+		switch x := x.(type) {
+		case string:
+			rec[k] = x
+		case json.Number:
+			rec[k] = x.String()
+		case bool:
+			if x {
+				rec[k] = "true"
+			} else {
+				rec[k] = "false"
+			}
+		case []any:
+			var ls []string
+			for _, e := range x {
+				if s, ok := e.(string); ok {
+					ls = append(ls, s)
+				}
+			}
+			rec[k] = strings.Join(ls, ";")
+		}
+	}
+	return rec
+}
+
+type occAnswer struct {
+	Offset, Limit int64
+	EndOfRecords  bool
+	Results       []struct {
+		Key int64
+	}
+}
+
+// MaxBatch is the maximum number of IDs
+// in a single Exist request.
+const MaxBatch = 100
+
+// Exist checks if the given occurrence IDs
+// are still in GBIF,
+// using a single request.
+// It returns the set of found IDs.
+// At most MaxBatch IDs can be checked in a request.
+func Exist(ids []int64) (map[int64]bool, error) {
+	if len(ids) > MaxBatch {
+		return nil, fmt.Errorf("gbif: occurrence: too many IDs (%d)", len(ids))
+	}
+	found := make(map[int64]bool, len(ids))
+	if len(ids) == 0 {
+		return found, nil
+	}
+
+	param := url.Values{}
+	for _, id := range ids {
+		param.Add("gbifId", strconv.FormatInt(id, 10))
+	}
+	param.Add("limit", strconv.Itoa(MaxBatch))
+
+	var err error
+	for r := 0; r < Retry; r++ {
+		req := newRequest("occurrence/search?"+param.Encode(), r)
+		select {
+		case err = <-req.err:
+			continue
+		case a := <-req.ans:
+			d := json.NewDecoder(a.Body)
+			resp := &occAnswer{}
+			err = d.Decode(resp)
+			a.Body.Close()
+			if err != nil {
+				continue
+			}
+			for _, o := range resp.Results {
+				found[o.Key] = true
+			}
+			return found, nil
+		}
+	}
+	if err == nil {
+		return nil, fmt.Errorf("gbif: occurrence: no answer after %d retries", Retry)
+	}
+	return nil, fmt.Errorf("gbif: occurrence: %v", err)
+}
+
+// An OccurrenceQuery is a query
+// of the occurrence search.
+// Empty fields are ignored.
+type OccurrenceQuery struct {
+	TaxonKey   int64
+	DatasetKey string
+	Country    string // ISO 3166 country code
+
+	// Geometry is a POLYGON or MULTIPOLYGON
+	// in WKT format.
+	// As required by GBIF,
+	// the points of the outer rings
+	// must be in counter-clockwise order.
+	Geometry string
+
+	// Year is a year,
+	// or a range of years
+	// (for example "1990,2000").
+	Year string
+
+	// If true,
+	// only records with coordinates
+	// and without geospatial issues
+	// are returned.
+	HasCoordinate bool
+}
+
+// occLimit is the number of records
+// requested in each page of an occurrence search.
+const occLimit = 300
+
+// maxOffset is the maximum offset
+// accepted by the occurrence search.
+const maxOffset = 100_000
+
+type occSearchAnswer struct {
+	Offset, Limit int64
+	EndOfRecords  bool
+	Count         int64
+	Results       []map[string]any
+}
+
+// An OccurrencePage is a page of records
+// of an occurrence search.
+type OccurrencePage struct {
+	Offset int64 // offset of the first record of the page
+	Next   int64 // offset of the next page
+	End    bool  // true if it is the last page
+	Count  int64 // number of records that match the query
+
+	// Records are the records of the page,
+	// as returned by Occurrence.
+	Records []map[string]string
+}
+
+// OccurrenceSearch returns the records
+// that match an occurrence query.
+// The records are returned as in Occurrence.
+//
+// The occurrence search of GBIF
+// can not retrieve more than 100 000 records;
+// for larger queries use a download
+// (see DownloadRequest).
+//
+// It requires an internet connection.
+func OccurrenceSearch(q OccurrenceQuery) ([]map[string]string, error) {
+	var ls []map[string]string
+	err := OccurrenceSearchPages(q, 0, func(p *OccurrencePage) error {
+		ls = append(ls, p.Records...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ls, nil
+}
+
+// OccurrenceSearchPages calls fn
+// with each page of the records
+// that match an occurrence query,
+// starting at the given offset
+// (for example, to resume an interrupted search).
+// If fn returns an error,
+// the search stops
+// and the error is returned.
+//
+// The occurrence search of GBIF
+// can not retrieve more than 100 000 records;
+// for larger queries use a download
+// (see DownloadRequest).
+//
+// It requires an internet connection.
+func OccurrenceSearchPages(q OccurrenceQuery, offset int64, fn func(*OccurrencePage) error) error {
+	param := url.Values{}
+	if q.TaxonKey != 0 {
+		param.Add("taxonKey", strconv.FormatInt(q.TaxonKey, 10))
+	}
+	if k := strings.TrimSpace(q.DatasetKey); k != "" {
+		param.Add("datasetKey", k)
+	}
+	if c := strings.TrimSpace(q.Country); c != "" {
+		param.Add("country", strings.ToUpper(c))
+	}
+	if g := strings.TrimSpace(q.Geometry); g != "" {
+		if err := checkGeometry(g); err != nil {
+			return fmt.Errorf("gbif: occurrence: %v", err)
+		}
+		param.Add("geometry", g)
+	}
+	if y := strings.TrimSpace(q.Year); y != "" {
+		param.Add("year", y)
+	}
+	if len(param) == 0 {
+		return errors.New("gbif: occurrence: search without terms")
+	}
+	if q.HasCoordinate {
+		param.Add("hasCoordinate", "true")
+		param.Add("hasGeospatialIssue", "false")
+	}
+	param.Add("limit", strconv.Itoa(occLimit))
+
+	for off := offset; ; {
+		param.Set("offset", strconv.FormatInt(off, 10))
+		p, err := occurrencePage(param)
+		if err != nil {
+			return err
+		}
+		p.Offset = off
+		p.Next = off + int64(len(p.Records))
+		if p.End {
+			return fn(p)
+		}
+		if MaxRecords > 0 && p.Next >= int64(MaxRecords) {
+			return fmt.Errorf("gbif: occurrence: %w: more than %d records", ErrTooMany, MaxRecords)
+		}
+		if p.Next >= maxOffset {
+			return fmt.Errorf("gbif: occurrence: %w: more than %d records (use a download)", ErrTooMany, maxOffset)
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+		off = p.Next
+	}
+}
+
+// occurrencePage returns a page
+// of an occurrence search.
+func occurrencePage(param url.Values) (*OccurrencePage, error) {
+	var err error
+	for r := 0; r < Retry; r++ {
+		req := newRequest("occurrence/search?"+param.Encode(), r)
+		select {
+		case err = <-req.err:
+			continue
+		case a := <-req.ans:
+			if a.StatusCode != http.StatusOK {
+				err = statusError(a)
+				if a.StatusCode >= http.StatusInternalServerError {
+					// a server error can be transient
+					continue
+				}
+				return nil, fmt.Errorf("gbif: occurrence: %v", err)
+			}
+			d := json.NewDecoder(a.Body)
+			d.UseNumber()
+			resp := &occSearchAnswer{}
+			err = d.Decode(resp)
+			a.Body.Close()
+			if err != nil {
+				continue
+			}
+			p := &OccurrencePage{
+				End:     resp.EndOfRecords || len(resp.Results) == 0,
+				Count:   resp.Count,
+				Records: make([]map[string]string, 0, len(resp.Results)),
+			}
+			for _, v := range resp.Results {
+				p.Records = append(p.Records, occFields(v))
+			}
+			return p, nil
+		}
+	}
+	if err == nil {
+		return nil, fmt.Errorf("gbif: occurrence: no answer after %d retries", Retry)
+	}
+	return nil, fmt.Errorf("gbif: occurrence: %v", err)
+}
+
+// maxErrBody is the maximum number of bytes
+// read from the body of an error answer.
+const maxErrBody = 1024
+
+// statusError returns an error
+// with the status and the body
+// of an answer,
+// and closes the body.
+func statusError(a *http.Response) error {
+	defer a.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(a.Body, maxErrBody))
+	msg := strings.Join(strings.Fields(string(b)), " ")
+	if msg == "" {
+		return fmt.Errorf("status %d %s", a.StatusCode, http.StatusText(a.StatusCode))
+	}
+	return fmt.Errorf("status %d %s: %s", a.StatusCode, http.StatusText(a.StatusCode), msg)
+}
+
+// A Media is a media item
+// (for example, an image)
+// associated with an occurrence record.
+type Media struct {
+	Type         string
+	Format       string
+	Identifier   string // URL of the media file
+	References   string
+	Title        string
+	License      string
+	RightsHolder string
+}
+
+type mediaAnswer struct {
+	Media []*Media
+}
+
+// OccurrenceMedia returns the media items
+// of an occurrence record.
+//
+// If the record is not found
+// it returns an error that wraps ErrNotFound.
+func OccurrenceMedia(id string) ([]*Media, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil, errors.New("gbif: media: search an empty ID")
+	}
+
+	var err error
+	for r := 0; r < Retry; r++ {
+		req := newRequest("occurrence/"+id, r)
+		select {
+		case err = <-req.err:
+			continue
+		case a := <-req.ans:
+			if a.StatusCode == http.StatusNotFound {
+				a.Body.Close()
+				return nil, fmt.Errorf("gbif: media: ID %s: %w", id, ErrNotFound)
+			}
+			d := json.NewDecoder(a.Body)
+			resp := &mediaAnswer{}
+			err = d.Decode(resp)
+			a.Body.Close()
+			if err != nil {
+				continue
+			}
+			return resp.Media, nil
+		}
+	}
+	if err == nil {
+		return nil, fmt.Errorf("gbif: media: no answer after %d retries", Retry)
+	}
+	return nil, fmt.Errorf("gbif: media: %v", err)
+}
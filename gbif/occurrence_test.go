@@ -0,0 +1,148 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package gbif_test
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/gbifer/gbif"
+)
+
+func TestOccurrenceSearch(t *testing.T) {
+	polygon := "POLYGON((-60 -35, -58 -35, -58 -33, -60 -33, -60 -35))"
+	param := func(off string) string {
+		p := url.Values{}
+		p.Add("taxonKey", "2435099")
+		p.Add("geometry", polygon)
+		p.Add("hasCoordinate", "true")
+		p.Add("hasGeospatialIssue", "false")
+		p.Add("limit", "300")
+		p.Add("offset", off)
+		return "occurrence/search?" + p.Encode()
+	}
+	setTransport(t, apiTransport{
+		param("0"): `{"offset":0,"limit":1,"endOfRecords":false,"results":[{"key":1,"species":"Puma concolor","decimalLatitude":-34.5,"decimalLongitude":-58.4,"issues":["A","B"]}]}`,
+		param("1"): `{"offset":1,"limit":1,"endOfRecords":true,"results":[{"key":2,"species":"Puma concolor","decimalLatitude":-33.5,"decimalLongitude":-59.1}]}`,
+	})
+
+	ls, err := gbif.OccurrenceSearch(gbif.OccurrenceQuery{
+		TaxonKey:      2435099,
+		Geometry:      polygon,
+		HasCoordinate: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ls) != 2 {
+		t.Fatalf("got %d records, want %d", len(ls), 2)
+	}
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"key", "1"},
+		{"species", "Puma concolor"},
+		{"decimalLatitude", "-34.5"},
+		{"issues", "A;B"},
+	}
+	for _, test := range tests {
+		if got := ls[0][test.field]; got != test.want {
+			t.Errorf("%s: got %q, want %q", test.field, got, test.want)
+		}
+	}
+
+	// clockwise polygon
+	_, err = gbif.OccurrenceSearch(gbif.OccurrenceQuery{
+		Geometry: "POLYGON((-60 -35, -60 -33, -58 -33, -58 -35, -60 -35))",
+	})
+	if err == nil {
+		t.Errorf("clockwise polygon: expecting error")
+	}
+}
+
+func TestOccurrenceSearchPages(t *testing.T) {
+	param := func(off string) string {
+		p := url.Values{}
+		p.Add("country", "AR")
+		p.Add("limit", "300")
+		p.Add("offset", off)
+		return "occurrence/search?" + p.Encode()
+	}
+	setTransport(t, apiTransport{
+		param("0"): `{"offset":0,"limit":300,"count":3,"endOfRecords":false,"results":[{"key":1},{"key":2}]}`,
+		param("2"): `{"offset":2,"limit":300,"count":3,"endOfRecords":true,"results":[{"key":3}]}`,
+	})
+
+	tests := map[string]struct {
+		offset int64
+		want   []string
+	}{
+		"from start": {0, []string{"1", "2", "3"}},
+		"resume":     {2, []string{"3"}},
+	}
+	for name, test := range tests {
+		var got []string
+		err := gbif.OccurrenceSearchPages(gbif.OccurrenceQuery{Country: "ar"}, test.offset, func(p *gbif.OccurrencePage) error {
+			if p.Count != 3 {
+				t.Errorf("%s: count: got %d, want %d", name, p.Count, 3)
+			}
+			if p.Next != p.Offset+int64(len(p.Records)) {
+				t.Errorf("%s: next: got %d, want %d", name, p.Next, p.Offset+int64(len(p.Records)))
+			}
+			for _, r := range p.Records {
+				got = append(got, r["key"])
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		if strings.Join(got, " ") != strings.Join(test.want, " ") {
+			t.Errorf("%s: got %v, want %v", name, got, test.want)
+		}
+	}
+}
+
+// errTransport answers all requests
+// with an error status.
+type errTransport struct {
+	status int
+	body   string
+	calls  int
+}
+
+func (t *errTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.calls++
+	return &http.Response{
+		StatusCode: t.status,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader(t.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestOccurrenceSearchStatus(t *testing.T) {
+	tr := &errTransport{
+		status: http.StatusBadRequest,
+		body:   `{"message":"Invalid geometry"}`,
+	}
+	setTransport(t, tr)
+
+	_, err := gbif.OccurrenceSearch(gbif.OccurrenceQuery{Country: "AR"})
+	if err == nil {
+		t.Fatalf("expecting error")
+	}
+	if !strings.Contains(err.Error(), "Invalid geometry") {
+		t.Errorf("error %q without the answer body", err)
+	}
+	if tr.calls != 1 {
+		t.Errorf("got %d requests, want %d", tr.calls, 1)
+	}
+}
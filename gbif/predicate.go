@@ -0,0 +1,362 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package gbif
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A Predicate is a predicate
+// of a GBIF occurrence download request.
+//
+// Predicates should be built
+// with the predicate functions
+// (for example, And, Equals, or Within),
+// and are validated when encoded as JSON.
+type Predicate struct {
+	Type       string       `json:"type"`
+	Key        string       `json:"key,omitempty"`
+	Value      string       `json:"value,omitempty"`
+	Values     []string     `json:"values,omitempty"`
+	Parameter  string       `json:"parameter,omitempty"`
+	Geometry   string       `json:"geometry,omitempty"`
+	Predicates []*Predicate `json:"predicates,omitempty"`
+	Predicate  *Predicate   `json:"predicate,omitempty"`
+}
+
+// Types of predicates.
+const (
+	PredAnd       = "and"
+	PredOr        = "or"
+	PredNot       = "not"
+	PredEquals    = "equals"
+	PredIn        = "in"
+	PredWithin    = "within"
+	PredIsNotNull = "isNotNull"
+	PredGreaterEq = "greaterThanOrEquals"
+	PredLessEq    = "lessThanOrEquals"
+)
+
+// And returns a predicate
+// that is true if all the given predicates are true.
+func And(ps ...*Predicate) *Predicate {
+	return &Predicate{Type: PredAnd, Predicates: ps}
+}
+
+// Or returns a predicate
+// that is true if any of the given predicates is true.
+func Or(ps ...*Predicate) *Predicate {
+	return &Predicate{Type: PredOr, Predicates: ps}
+}
+
+// Not returns a predicate
+// that is true if the given predicate is false.
+func Not(p *Predicate) *Predicate {
+	return &Predicate{Type: PredNot, Predicate: p}
+}
+
+// Equals returns a predicate
+// that is true if the value of the search key
+// (for example, TAXON_KEY, or COUNTRY)
+// is the given value.
+func Equals(key, value string) *Predicate {
+	return &Predicate{Type: PredEquals, Key: key, Value: value}
+}
+
+// In returns a predicate
+// that is true if the value of the search key
+// is any of the given values.
+func In(key string, values ...string) *Predicate {
+	return &Predicate{Type: PredIn, Key: key, Values: values}
+}
+
+// Within returns a predicate
+// that is true if the record is inside the given geometry,
+// a POLYGON or MULTIPOLYGON in WKT format.
+// As required by GBIF,
+// the points of the outer rings
+// must be in counter-clockwise order.
+func Within(geometry string) *Predicate {
+	return &Predicate{Type: PredWithin, Geometry: geometry}
+}
+
+// IsNotNull returns a predicate
+// that is true if the search key has a value.
+func IsNotNull(key string) *Predicate {
+	return &Predicate{Type: PredIsNotNull, Parameter: key}
+}
+
+// GreaterThanOrEquals returns a predicate
+// that is true if the value of the search key
+// is greater than or equal to the given value.
+func GreaterThanOrEquals(key, value string) *Predicate {
+	return &Predicate{Type: PredGreaterEq, Key: key, Value: value}
+}
+
+// LessThanOrEquals returns a predicate
+// that is true if the value of the search key
+// is less than or equal to the given value.
+func LessThanOrEquals(key, value string) *Predicate {
+	return &Predicate{Type: PredLessEq, Key: key, Value: value}
+}
+
+// searchKeys are the valid search keys
+// of an occurrence download predicate.
+var searchKeys = map[string]bool{
+	"BASIS_OF_RECORD":                  true,
+	"CATALOG_NUMBER":                   true,
+	"CLASS_KEY":                        true,
+	"COLLECTION_CODE":                  true,
+	"CONTINENT":                        true,
+	"COORDINATE_UNCERTAINTY_IN_METERS": true,
+	"COUNTRY":                          true,
+	"DATASET_KEY":                      true,
+	"DECIMAL_LATITUDE":                 true,
+	"DECIMAL_LONGITUDE":                true,
+	"DEPTH":                            true,
+	"ELEVATION":                        true,
+	"ESTABLISHMENT_MEANS":              true,
+	"EVENT_DATE":                       true,
+	"FAMILY_KEY":                       true,
+	"GADM_GID":                         true,
+	"GENUS_KEY":                        true,
+	"GEOMETRY":                         true,
+	"HAS_COORDINATE":                   true,
+	"HAS_GEOSPATIAL_ISSUE":             true,
+	"INSTITUTION_CODE":                 true,
+	"ISSUE":                            true,
+	"IUCN_RED_LIST_CATEGORY":           true,
+	"KINGDOM_KEY":                      true,
+	"LICENSE":                          true,
+	"MEDIA_TYPE":                       true,
+	"MONTH":                            true,
+	"OCCURRENCE_STATUS":                true,
+	"ORDER_KEY":                        true,
+	"PHYLUM_KEY":                       true,
+	"PROTOCOL":                         true,
+	"PUBLISHING_COUNTRY":               true,
+	"PUBLISHING_ORG":                   true,
+	"RECORDED_BY":                      true,
+	"SCIENTIFIC_NAME":                  true,
+	"SPECIES_KEY":                      true,
+	"STATE_PROVINCE":                   true,
+	"TAXON_KEY":                        true,
+	"TAXONOMIC_STATUS":                 true,
+	"YEAR":                             true,
+}
+
+// Validate checks that a predicate,
+// and all its sub-predicates,
+// are valid.
+func (p *Predicate) Validate() error {
+	if err := p.check(); err != nil {
+		return err
+	}
+	for _, sp := range p.Predicates {
+		if err := sp.Validate(); err != nil {
+			return err
+		}
+	}
+	if p.Predicate != nil {
+		return p.Predicate.Validate()
+	}
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// It returns an error if the predicate is not valid.
+func (p *Predicate) MarshalJSON() ([]byte, error) {
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	type predicate Predicate
+	return json.Marshal((*predicate)(p))
+}
+
+// check validates a predicate
+// without validating its sub-predicates.
+func (p *Predicate) check() error {
+	if p == nil {
+		return errors.New("gbif: predicate: nil predicate")
+	}
+	switch p.Type {
+	case PredAnd, PredOr:
+		if len(p.Predicates) == 0 {
+			return fmt.Errorf("gbif: predicate: %s: without predicates", p.Type)
+		}
+		for _, sp := range p.Predicates {
+			if sp == nil {
+				return fmt.Errorf("gbif: predicate: %s: nil predicate", p.Type)
+			}
+		}
+	case PredNot:
+		if p.Predicate == nil {
+			return fmt.Errorf("gbif: predicate: %s: without predicate", p.Type)
+		}
+	case PredEquals, PredGreaterEq, PredLessEq:
+		if err := checkKey(p.Type, p.Key); err != nil {
+			return err
+		}
+		if strings.TrimSpace(p.Value) == "" {
+			return fmt.Errorf("gbif: predicate: %s: %s: empty value", p.Type, p.Key)
+		}
+	case PredIn:
+		if err := checkKey(p.Type, p.Key); err != nil {
+			return err
+		}
+		if len(p.Values) == 0 {
+			return fmt.Errorf("gbif: predicate: %s: %s: without values", p.Type, p.Key)
+		}
+		for _, v := range p.Values {
+			if strings.TrimSpace(v) == "" {
+				return fmt.Errorf("gbif: predicate: %s: %s: empty value", p.Type, p.Key)
+			}
+		}
+	case PredIsNotNull:
+		if err := checkKey(p.Type, p.Parameter); err != nil {
+			return err
+		}
+	case PredWithin:
+		if err := checkGeometry(p.Geometry); err != nil {
+			return fmt.Errorf("gbif: predicate: %s: %v", p.Type, err)
+		}
+	default:
+		return fmt.Errorf("gbif: predicate: unknown type %q", p.Type)
+	}
+	return nil
+}
+
+func checkKey(tp, key string) error {
+	if key == "" {
+		return fmt.Errorf("gbif: predicate: %s: without key", tp)
+	}
+	if !searchKeys[key] {
+		return fmt.Errorf("gbif: predicate: %s: unknown key %q", tp, key)
+	}
+	return nil
+}
+
+// checkGeometry checks that a geometry
+// is a valid WKT POLYGON or MULTIPOLYGON
+// with counter-clockwise outer rings.
+func checkGeometry(wkt string) error {
+	g := strings.ToUpper(strings.TrimSpace(wkt))
+	var ringDepth int
+	switch {
+	case strings.HasPrefix(g, "MULTIPOLYGON"):
+		g = g[len("MULTIPOLYGON"):]
+		ringDepth = 3
+	case strings.HasPrefix(g, "POLYGON"):
+		g = g[len("POLYGON"):]
+		ringDepth = 2
+	default:
+		return errors.New("geometry is not a POLYGON or MULTIPOLYGON")
+	}
+
+	depth := 0
+	rings := 0
+	outer := false
+	start := -1
+	for i, r := range g {
+		switch r {
+		case '(':
+			depth++
+			if depth > ringDepth {
+				return errors.New("invalid geometry: unexpected '('")
+			}
+			if depth == ringDepth-1 {
+				outer = true
+			}
+			if depth == ringDepth {
+				start = i + 1
+			}
+		case ')':
+			if depth == 0 {
+				return errors.New("invalid geometry: unexpected ')'")
+			}
+			if depth == ringDepth {
+				if err := checkRing(g[start:i], outer); err != nil {
+					return err
+				}
+				rings++
+				outer = false
+			}
+			depth--
+		default:
+			if depth == 0 && r != ' ' {
+				return fmt.Errorf("invalid geometry: unexpected %q", r)
+			}
+		}
+	}
+	if depth != 0 {
+		return errors.New("invalid geometry: unbalanced parenthesis")
+	}
+	if rings == 0 {
+		return errors.New("invalid geometry: empty geometry")
+	}
+	return nil
+}
+
+// checkRing checks a ring of a polygon.
+func checkRing(ring string, outer bool) error {
+	var pts [][2]float64
+	for _, p := range strings.Split(ring, ",") {
+		f := strings.Fields(p)
+		if len(f) != 2 {
+			return fmt.Errorf("invalid point %q", strings.TrimSpace(p))
+		}
+		lon, err := strconv.ParseFloat(f[0], 64)
+		if err != nil || lon < -180 || lon > 180 {
+			return fmt.Errorf("invalid longitude %q", f[0])
+		}
+		lat, err := strconv.ParseFloat(f[1], 64)
+		if err != nil || lat < -90 || lat > 90 {
+			return fmt.Errorf("invalid latitude %q", f[1])
+		}
+		pts = append(pts, [2]float64{lon, lat})
+	}
+	if len(pts) < 4 {
+		return errors.New("ring with less than 4 points")
+	}
+	if pts[0] != pts[len(pts)-1] {
+		return errors.New("ring is not closed")
+	}
+
+	// shoelace formula
+	var area float64
+	for i := 1; i < len(pts); i++ {
+		area += pts[i-1][0]*pts[i][1] - pts[i][0]*pts[i-1][1]
+	}
+	if area == 0 {
+		return errors.New("ring without area")
+	}
+	if outer && area < 0 {
+		return errors.New("outer ring in clockwise order")
+	}
+	return nil
+}
+
+// Formats of an occurrence download.
+const (
+	FormatSimpleCSV     = "SIMPLE_CSV"
+	FormatDwCA          = "DWCA"
+	FormatSpeciesList   = "SPECIES_LIST"
+	FormatSimpleParquet = "SIMPLE_PARQUET"
+)
+
+// A DownloadRequest is the request
+// of an occurrence download,
+// as expected by the GBIF download API.
+type DownloadRequest struct {
+	Creator               string     `json:"creator,omitempty"`
+	NotificationAddresses []string   `json:"notificationAddresses,omitempty"`
+	SendNotification      bool       `json:"sendNotification"`
+	Format                string     `json:"format"`
+	Predicate             *Predicate `json:"predicate"`
+}
@@ -0,0 +1,113 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package gbif_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/js-arias/gbifer/gbif"
+)
+
+func TestPredicate(t *testing.T) {
+	tests := map[string]struct {
+		p    *gbif.Predicate
+		want string
+	}{
+		"equals": {
+			p:    gbif.Equals("TAXON_KEY", "2435099"),
+			want: `{"type":"equals","key":"TAXON_KEY","value":"2435099"}`,
+		},
+		"in": {
+			p:    gbif.In("COUNTRY", "AR", "UY"),
+			want: `{"type":"in","key":"COUNTRY","values":["AR","UY"]}`,
+		},
+		"within": {
+			p:    gbif.Within("POLYGON((-60 -35, -58 -35, -58 -33, -60 -33, -60 -35))"),
+			want: `{"type":"within","geometry":"POLYGON((-60 -35, -58 -35, -58 -33, -60 -33, -60 -35))"}`,
+		},
+		"and": {
+			p: gbif.And(
+				gbif.Equals("TAXON_KEY", "2435099"),
+				gbif.Not(gbif.Equals("BASIS_OF_RECORD", "FOSSIL_SPECIMEN")),
+				gbif.IsNotNull("COORDINATE_UNCERTAINTY_IN_METERS"),
+			),
+			want: `{"type":"and","predicates":[{"type":"equals","key":"TAXON_KEY","value":"2435099"},{"type":"not","predicate":{"type":"equals","key":"BASIS_OF_RECORD","value":"FOSSIL_SPECIMEN"}},{"type":"isNotNull","parameter":"COORDINATE_UNCERTAINTY_IN_METERS"}]}`,
+		},
+		"or": {
+			p: gbif.Or(
+				gbif.GreaterThanOrEquals("YEAR", "2000"),
+				gbif.LessThanOrEquals("YEAR", "1900"),
+			),
+			want: `{"type":"or","predicates":[{"type":"greaterThanOrEquals","key":"YEAR","value":"2000"},{"type":"lessThanOrEquals","key":"YEAR","value":"1900"}]}`,
+		},
+	}
+
+	for name, test := range tests {
+		if err := test.p.Validate(); err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		b, err := json.Marshal(test.p)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+			continue
+		}
+		if string(b) != test.want {
+			t.Errorf("%s: got %s, want %s", name, b, test.want)
+		}
+	}
+}
+
+func TestPredicateErrors(t *testing.T) {
+	tests := map[string]*gbif.Predicate{
+		"unknown key":      gbif.Equals("TAXON", "2435099"),
+		"empty value":      gbif.Equals("COUNTRY", " "),
+		"in without value": gbif.In("COUNTRY"),
+		"empty and":        gbif.And(),
+		"nested error":     gbif.And(gbif.Equals("COUNTRY", "AR"), gbif.Not(gbif.In("YEAR", "2000", ""))),
+		"not a polygon":    gbif.Within("POINT(-60 -35)"),
+		"open ring":        gbif.Within("POLYGON((-60 -35, -58 -35, -58 -33, -60 -33))"),
+		"clockwise":        gbif.Within("POLYGON((-60 -35, -60 -33, -58 -33, -58 -35, -60 -35))"),
+		"bad latitude":     gbif.Within("POLYGON((-60 -35, -58 -35, -58 -93, -60 -33, -60 -35))"),
+		"unbalanced":       gbif.Within("POLYGON((-60 -35, -58 -35, -58 -33, -60 -33, -60 -35)"),
+		"unknown type":     {Type: "like", Key: "COUNTRY", Value: "A*"},
+	}
+
+	for name, p := range tests {
+		if err := p.Validate(); err == nil {
+			t.Errorf("%s: expecting error", name)
+		}
+	}
+
+	// JSON encoding also validates
+	p := gbif.And(gbif.Equals("COUNTRY", "AR"), gbif.Equals("TAXON", "1"))
+	if _, err := json.Marshal(p); err == nil {
+		t.Errorf("marshal: expecting error")
+	}
+
+	// a multipolygon with a hole
+	mp := gbif.Within("MULTIPOLYGON(((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 4, 4 4, 4 2, 2 2)), ((20 20, 30 20, 30 30, 20 20)))")
+	if err := mp.Validate(); err != nil {
+		t.Errorf("multipolygon: unexpected error: %v", err)
+	}
+}
+
+func TestDownloadRequest(t *testing.T) {
+	req := gbif.DownloadRequest{
+		Creator:          "user",
+		SendNotification: false,
+		Format:           gbif.FormatSimpleCSV,
+		Predicate:        gbif.Equals("DATASET_KEY", "50c9509d-22c7-4a22-a47d-8c48425ef4a7"),
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"creator":"user","sendNotification":false,"format":"SIMPLE_CSV","predicate":{"type":"equals","key":"DATASET_KEY","value":"50c9509d-22c7-4a22-a47d-8c48425ef4a7"}}`
+	if string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+}
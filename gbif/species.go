@@ -8,6 +8,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
@@ -51,20 +53,69 @@ type Species struct {
 	Species string
 }
 
+// Checklist is the key of the checklist dataset
+// used in species requests.
+// If empty,
+// the requests use the live GBIF backbone.
+//
+// If a checklist is defined,
+// name searches,
+// children,
+// and synonyms,
+// only return usages of the checklist,
+// and the NubKey of the usages of the checklist
+// is set to the key of the usage,
+// so the taxonomy can be built
+// with the keys of the checklist.
+// A local backbone
+// (see OpenBackbone)
+// takes precedence over the checklist.
+var Checklist string
+
+// inChecklist returns true if a species
+// is a usage of the requested checklist,
+// or the backbone,
+// if no checklist is defined.
+// For checklist usages
+// the NubKey is set to the usage key.
+func inChecklist(sp *Species) bool {
+	if Checklist == "" {
+		return sp.Key == sp.NubKey
+	}
+	if !strings.EqualFold(sp.DatasetKey, Checklist) {
+		return false
+	}
+	sp.NubKey = sp.Key
+	return true
+}
+
+// ErrNotFound is the error returned
+// when a species ID is not found in GBIF.
+var ErrNotFound = errors.New("not found")
+
 // SpeciesID return a Species from a GBIF species ID.
+// If the ID is not found
+// it returns an error that wraps ErrNotFound.
 func SpeciesID(id string) (*Species, error) {
 	id = strings.TrimSpace(id)
 	if id == "" {
 		return nil, errors.New("gbif: species: search an empty ID")
 	}
+	if backbone != nil {
+		return backbone.species(id)
+	}
 
 	var err error
 	for r := 0; r < Retry; r++ {
-		req := newRequest("species/" + id)
+		req := newRequest("species/"+id, r)
 		select {
 		case err = <-req.err:
 			continue
 		case a := <-req.ans:
+			if a.StatusCode == http.StatusNotFound {
+				a.Body.Close()
+				return nil, fmt.Errorf("gbif: species: ID %s: %w", id, ErrNotFound)
+			}
 			d := json.NewDecoder(a.Body)
 			sp := &Species{}
 			err = d.Decode(sp)
@@ -72,6 +123,7 @@ func SpeciesID(id string) (*Species, error) {
 			if err != nil {
 				continue
 			}
+			inChecklist(sp)
 			return sp, nil
 		}
 	}
@@ -87,36 +139,48 @@ func TaxonName(name string) ([]*Species, error) {
 	if name == "" {
 		return nil, errors.New("gbif: taxonomy: search an empty taxon")
 	}
+	if backbone != nil {
+		return backbone.list(backbone.names[strings.ToLower(name)]), nil
+	}
 	request := "species?"
 	param := url.Values{}
 	param.Add("name", name)
+	if Checklist != "" {
+		param.Add("datasetKey", Checklist)
+	}
 	ls, err := taxonList(request, param)
 	if err != nil {
-		return nil, fmt.Errorf("taxonomy: gbif: taxon: %v", err)
+		return nil, fmt.Errorf("taxonomy: gbif: taxon: %w", err)
 	}
 	return ls, nil
 }
 
 // Children returns an list with the children of a given taxon ID.
 func Children(id int64) ([]*Species, error) {
+	if backbone != nil {
+		return backbone.list(backbone.children[id]), nil
+	}
 	request := "species/" + strconv.FormatInt(id, 10) + "/children?"
 	param := url.Values{}
 	param.Add("offset", "0")
 	ls, err := taxonList(request, param)
 	if err != nil {
-		return nil, fmt.Errorf("taxonomy: gbif: taxon: %v", err)
+		return nil, fmt.Errorf("taxonomy: gbif: taxon: %w", err)
 	}
 	return ls, nil
 }
 
 // Synonym returns a slice of synonyms of a taxon ID.
 func Synonym(id int64) ([]*Species, error) {
+	if backbone != nil {
+		return backbone.list(backbone.synonyms[id]), nil
+	}
 	request := "species/" + strconv.FormatInt(id, 10) + "/synonyms?"
 	param := url.Values{}
 	param.Add("offset", "0")
 	ls, err := taxonList(request, param)
 	if err != nil {
-		return nil, fmt.Errorf("taxonomy: gbif: taxon: %v", err)
+		return nil, fmt.Errorf("taxonomy: gbif: taxon: %w", err)
 	}
 	return ls, nil
 }
@@ -131,7 +195,7 @@ func taxonList(request string, param url.Values) ([]*Species, error) {
 		}
 		retryErr := true
 		for r := 0; r < Retry; r++ {
-			req := newRequest(request + param.Encode())
+			req := newRequest(request+param.Encode(), r)
 			select {
 			case err = <-req.err:
 				continue
@@ -144,12 +208,12 @@ func taxonList(request string, param url.Values) ([]*Species, error) {
 					continue
 				}
 				for _, sp := range resp.Results {
-					if sp.Key != sp.NubKey {
+					if !inChecklist(sp) {
 						continue
 					}
 					ls = append(ls, sp)
 				}
-				if resp.EndOfRecords {
+				if resp.EndOfRecords || resp.Limit == 0 {
 					// end retry loop
 					end = true
 					r = Retry
@@ -157,6 +221,9 @@ func taxonList(request string, param url.Values) ([]*Species, error) {
 					continue
 				}
 				off += resp.Limit
+				if MaxRecords > 0 && off >= int64(MaxRecords) {
+					return nil, fmt.Errorf("%w: more than %d records", ErrTooMany, MaxRecords)
+				}
 				r = Retry
 				retryErr = false
 			}
@@ -170,3 +237,120 @@ func taxonList(request string, param url.Values) ([]*Species, error) {
 	}
 	return ls, nil
 }
+
+// A VernacularName is a common name of a taxon.
+type VernacularName struct {
+	VernacularName string // the common name
+	Language       string // ISO 639-2 language code
+	Country        string // ISO 3166 country code
+	Source         string // reference
+}
+
+type vernAnswer struct {
+	Offset, Limit int64
+	EndOfRecords  bool
+	Results       []*VernacularName
+}
+
+// Vernacular returns the vernacular names
+// of a given taxon ID.
+//
+// It requires an internet connection
+// (the local backbone does not include vernacular names).
+func Vernacular(id int64) ([]*VernacularName, error) {
+	request := "species/" + strconv.FormatInt(id, 10) + "/vernacularNames?"
+	param := url.Values{}
+	param.Add("offset", "0")
+
+	var ls []*VernacularName
+	var err error
+	end := false
+	for off := int64(0); !end; {
+		if off > 0 {
+			param.Set("offset", strconv.FormatInt(off, 10))
+		}
+		retryErr := true
+		for r := 0; r < Retry; r++ {
+			req := newRequest(request+param.Encode(), r)
+			select {
+			case err = <-req.err:
+				continue
+			case a := <-req.ans:
+				d := json.NewDecoder(a.Body)
+				resp := &vernAnswer{}
+				err = d.Decode(resp)
+				a.Body.Close()
+				if err != nil {
+					continue
+				}
+				ls = append(ls, resp.Results...)
+				if resp.EndOfRecords || resp.Limit == 0 {
+					end = true
+				}
+				off += resp.Limit
+				if !end && MaxRecords > 0 && off >= int64(MaxRecords) {
+					return nil, fmt.Errorf("gbif: vernacular: %w: more than %d records", ErrTooMany, MaxRecords)
+				}
+				r = Retry
+				retryErr = false
+			}
+		}
+		if retryErr {
+			if err == nil {
+				return nil, fmt.Errorf("gbif: vernacular: no answer after %d retries", Retry)
+			}
+			return nil, fmt.Errorf("gbif: vernacular: %v", err)
+		}
+	}
+	return ls, nil
+}
+
+// A RedList is the IUCN Red List category of a taxon.
+type RedList struct {
+	Category       string // e.g. LEAST_CONCERN
+	Code           string // e.g. LC
+	UsageKey       int64  // the taxon ID
+	ScientificName string
+}
+
+// IUCNCategory returns the IUCN Red List category
+// of a given taxon ID.
+// If the taxon is not evaluated
+// it returns nil.
+//
+// It requires an internet connection.
+func IUCNCategory(id int64) (*RedList, error) {
+	request := "species/" + strconv.FormatInt(id, 10) + "/iucnRedListCategory"
+
+	var err error
+	for r := 0; r < Retry; r++ {
+		req := newRequest(request, r)
+		select {
+		case err = <-req.err:
+			continue
+		case a := <-req.ans:
+			if a.StatusCode == http.StatusNotFound || a.StatusCode == http.StatusNoContent {
+				a.Body.Close()
+				return nil, nil
+			}
+			d := json.NewDecoder(a.Body)
+			rl := &RedList{}
+			err = d.Decode(rl)
+			a.Body.Close()
+			if errors.Is(err, io.EOF) {
+				return nil, nil
+			}
+			if err != nil {
+				continue
+			}
+			if rl.Category == "" {
+				return nil, nil
+			}
+			return rl, nil
+		}
+	}
+	if err == nil {
+		return nil, fmt.Errorf("gbif: iucn: no answer after %d retries", Retry)
+	}
+	return nil, fmt.Errorf("gbif: iucn: %v", err)
+}
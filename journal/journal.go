@@ -0,0 +1,311 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package journal implements a journal
+// to record the progress of long running processes
+// (for example, processes that use the GBIF API)
+// so an interrupted process can be resumed.
+//
+// A journal is a TSV file
+// in which each processed item is recorded by a key
+// (for example, a GBIF ID),
+// with the values obtained for that item.
+// All rows have the same number of fields,
+// and the values of an item are only valid
+// after the row that marks the item as done,
+// so rows truncated by an interrupted process
+// can be detected and ignored.
+//
+// The first row of a journal stores a hash
+// of the input of the process,
+// so a journal can not be used
+// to resume a process with a different input.
+package journal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/js-arias/gbifer/tsv"
+)
+
+// ErrInput is the error returned
+// when a journal is opened with a different input.
+var ErrInput = errors.New("journal of a different input")
+
+// A Journal records the processed items
+// of a long running process.
+type Journal struct {
+	name string
+	f    *os.File
+	w    *tsv.Writer
+	done map[string][]string
+	err  error
+}
+
+const (
+	rowInput = "input"
+	rowBegin = "begin"
+	rowValue = "value"
+	rowDone  = "done"
+)
+
+// Open opens a journal file,
+// and reads the items already processed.
+// If the file does not exist,
+// it will be created.
+//
+// Hash is the hash of the input of the process
+// (see Hash).
+// If the journal was created with a different hash,
+// it returns ErrInput.
+func Open(name, hash string) (*Journal, error) {
+	j := &Journal{
+		name: name,
+		done: make(map[string][]string),
+	}
+
+	exists := false
+	f, err := os.Open(name)
+	if err == nil {
+		exists, err = j.read(f, hash)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("journal %q: %w", name, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	j.f, err = os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	j.w = tsv.NewWriter(j.f)
+	j.w.Comma = '\t'
+	j.w.UseCRLF = true
+
+	if !exists {
+		if err := j.write(rowInput, hash, ""); err != nil {
+			j.f.Close()
+			return nil, err
+		}
+		return j, nil
+	}
+
+	// complete a row truncated by an interrupted process,
+	// so it will not be merged with the next row.
+	if !endsInNewLine(name) {
+		if _, err := j.f.WriteString("\r\n"); err != nil {
+			j.f.Close()
+			return nil, fmt.Errorf("journal %q: %v", name, err)
+		}
+	}
+	return j, nil
+}
+
+// endsInNewLine returns true
+// if the last byte of a file is a new line.
+func endsInNewLine(name string) bool {
+	f, err := os.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil || st.Size() == 0 {
+		return false
+	}
+	b := make([]byte, 1)
+	if _, err := f.ReadAt(b, st.Size()-1); err != nil {
+		return false
+	}
+	return b[0] == '\n'
+}
+
+// read reads a journal file.
+// It returns false if the journal is empty.
+func (j *Journal) read(r io.Reader, hash string) (bool, error) {
+	tab := tsv.NewReader(r)
+	tab.Comma = '\t'
+
+	exists := false
+	pending := make(map[string][]string)
+	for {
+		row, err := tab.Read()
+		if errors.Is(err, io.EOF) {
+			return exists, nil
+		}
+		ln, _ := tab.FieldPos(0)
+		if errors.Is(err, tsv.ErrFieldCount) {
+			// a truncated row
+			// from an interrupted process
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("row %d: %v", ln, err)
+		}
+		if len(row) != 3 {
+			continue
+		}
+
+		if !exists {
+			if row[0] != rowInput {
+				return false, fmt.Errorf("row %d: expecting %q entry", ln, rowInput)
+			}
+			if row[1] != hash {
+				return false, ErrInput
+			}
+			exists = true
+			continue
+		}
+
+		switch row[0] {
+		case rowBegin:
+			pending[row[1]] = []string{}
+		case rowValue:
+			v, ok := pending[row[1]]
+			if !ok {
+				continue
+			}
+			pending[row[1]] = append(v, row[2])
+		case rowDone:
+			v, ok := pending[row[1]]
+			if !ok {
+				continue
+			}
+			j.done[row[1]] = v
+			delete(pending, row[1])
+		default:
+			return false, fmt.Errorf("row %d: unknown entry %q", ln, row[0])
+		}
+	}
+}
+
+// Done returns true if the given key
+// was already processed.
+// A nil journal
+// has no processed keys.
+func (j *Journal) Done(key string) bool {
+	if j == nil {
+		return false
+	}
+	_, ok := j.done[key]
+	return ok
+}
+
+// Values returns the values stored
+// with a processed key.
+// It returns false if the key
+// was not processed.
+func (j *Journal) Values(key string) ([]string, bool) {
+	if j == nil {
+		return nil, false
+	}
+	v, ok := j.done[key]
+	return v, ok
+}
+
+// Len returns the number of processed keys.
+func (j *Journal) Len() int {
+	if j == nil {
+		return 0
+	}
+	return len(j.done)
+}
+
+// Commit marks the given key as processed,
+// and stores the given values with the key.
+// The journal is flushed
+// after each commit.
+// Commit on a nil journal
+// does nothing.
+func (j *Journal) Commit(key string, values ...string) error {
+	if j == nil {
+		return nil
+	}
+	if err := j.write(rowBegin, key, ""); err != nil {
+		return err
+	}
+	for _, v := range values {
+		if err := j.write(rowValue, key, v); err != nil {
+			return err
+		}
+	}
+	if err := j.write(rowDone, key, ""); err != nil {
+		return err
+	}
+	j.w.Flush()
+	if err := j.w.Error(); err != nil {
+		j.err = err
+		return fmt.Errorf("journal %q: %v", j.name, err)
+	}
+	j.done[key] = append([]string{}, values...)
+	return nil
+}
+
+func (j *Journal) write(kind, key, value string) error {
+	if j.err != nil {
+		return fmt.Errorf("journal %q: %v", j.name, j.err)
+	}
+	if err := j.w.Write([]string{kind, key, value}); err != nil {
+		j.err = err
+		return fmt.Errorf("journal %q: %v", j.name, err)
+	}
+	return nil
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	j.w.Flush()
+	err := j.w.Error()
+	if e := j.f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return fmt.Errorf("journal %q: %v", j.name, err)
+	}
+	return nil
+}
+
+// Remove closes and removes the journal file.
+// It should be used
+// when the process is successfully finished.
+func (j *Journal) Remove() error {
+	if err := j.Close(); err != nil {
+		return err
+	}
+	return os.Remove(j.name)
+}
+
+// Hash returns a hash of the input of a process,
+// made from the content of the given file,
+// and the given parameters
+// (for example, the value of flags
+// that modify the results of the process).
+// If the file name is empty,
+// only the parameters are used.
+func Hash(file string, params ...string) (string, error) {
+	h := sha256.New()
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		if _, err := io.Copy(h, f); err != nil {
+			return "", fmt.Errorf("on file %q: %v", file, err)
+		}
+	}
+	for _, p := range params {
+		// the length avoids ambiguous concatenations
+		fmt.Fprintf(h, "\x00%d:%s", len(p), p)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,119 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package journal_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/js-arias/gbifer/journal"
+)
+
+func TestJournal(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "journal.tab")
+
+	jr, err := journal.Open(name, "abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := jr.Commit("1", "Puma concolor", "accepted"); err != nil {
+		t.Fatalf("commit: unexpected error: %v", err)
+	}
+	if err := jr.Commit("2"); err != nil {
+		t.Fatalf("commit: unexpected error: %v", err)
+	}
+	if err := jr.Close(); err != nil {
+		t.Fatalf("close: unexpected error: %v", err)
+	}
+
+	// simulate an interrupted write
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.WriteString("begin\t3\t\r\nvalue\t3\tFelis concolor\r\nvalue\t3\tsyn")
+	f.Close()
+
+	jr, err = journal.Open(name, "abc")
+	if err != nil {
+		t.Fatalf("resume: unexpected error: %v", err)
+	}
+	if jr.Len() != 2 {
+		t.Errorf("resume: len: got %d, want %d", jr.Len(), 2)
+	}
+	if jr.Done("3") {
+		t.Errorf("resume: key %q done", "3")
+	}
+	if err := jr.Commit("3", "Felis concolor", "synonym"); err != nil {
+		t.Fatalf("resume: commit: unexpected error: %v", err)
+	}
+	if err := jr.Close(); err != nil {
+		t.Fatalf("resume: close: unexpected error: %v", err)
+	}
+
+	jr, err = journal.Open(name, "abc")
+	if err != nil {
+		t.Fatalf("reopen: unexpected error: %v", err)
+	}
+	tests := map[string]struct {
+		key  string
+		ok   bool
+		want []string
+	}{
+		"values":    {"1", true, []string{"Puma concolor", "accepted"}},
+		"no values": {"2", true, []string{}},
+		"resumed":   {"3", true, []string{"Felis concolor", "synonym"}},
+		"undone":    {"4", false, nil},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, ok := jr.Values(test.key)
+			if ok != test.ok {
+				t.Fatalf("key %q: got %v, want %v", test.key, ok, test.ok)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("key %q: got %q, want %q", test.key, got, test.want)
+			}
+		})
+	}
+	if err := jr.Close(); err != nil {
+		t.Fatalf("reopen: close: unexpected error: %v", err)
+	}
+
+	if _, err := journal.Open(name, "xyz"); !errors.Is(err, journal.ErrInput) {
+		t.Errorf("different input: got error %v, want %v", err, journal.ErrInput)
+	}
+}
+
+func TestHash(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "input.tab")
+	if err := os.WriteFile(name, []byte("gbifID\n1\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	h1, err := journal.Hash(name, "taxonomy")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	h2, err := journal.Hash(name, "taxonomy")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h1 != h2 {
+		t.Errorf("same input: got %q and %q", h1, h2)
+	}
+
+	h3, _ := journal.Hash(name, "issues")
+	if h1 == h3 {
+		t.Errorf("different parameters: got the same hash %q", h1)
+	}
+	h4, _ := journal.Hash(name, "tax", "onomy")
+	if h1 == h4 {
+		t.Errorf("split parameters: got the same hash %q", h1)
+	}
+}
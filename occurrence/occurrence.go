@@ -0,0 +1,280 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package occurrence implements the processing
+// of the rows of a GBIF occurrence table,
+// so the operations of GBIFer
+// can be composed in a Go program.
+//
+// A Reader provides the rows of a table,
+// and the rows are written into a Writer.
+// The rows can be selected with a Filter,
+// and modified with a Transform.
+// For example,
+// to select the rows with a species,
+// and only keep some columns:
+//
+//	tab, err := occurrence.NewTable(r)
+//	if err != nil {
+//		return err
+//	}
+//	sp, err := occurrence.WithSpecies(tab.Header())
+//	if err != nil {
+//		return err
+//	}
+//	var rd occurrence.Reader = occurrence.Select(tab, sp)
+//	rd = occurrence.Apply(rd, occurrence.Columns(rd.Header(), []string{"gbifID", "species"}, false))
+//
+//	out, err := occurrence.NewTableWriter(w, rd.Header())
+//	if err != nil {
+//		return err
+//	}
+//	if _, err := occurrence.Copy(out, rd); err != nil {
+//		return err
+//	}
+//	return out.Flush()
+package occurrence
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/js-arias/gbifer/tsv"
+)
+
+// A Reader reads the rows of an occurrence table.
+type Reader interface {
+	// Header returns the column names of the table.
+	Header() []string
+
+	// Read returns the next row of the table.
+	// At the end of the table,
+	// it returns io.EOF.
+	Read() ([]string, error)
+}
+
+// A Writer writes the rows of an occurrence table.
+type Writer interface {
+	Write(row []string) error
+}
+
+// A Filter selects the rows of a table.
+//
+// The filters implemented in this package
+// can be used concurrently.
+type Filter interface {
+	// Keep returns true
+	// if the row must be kept.
+	Keep(row []string) (bool, error)
+}
+
+// FilterFunc is an adapter
+// to use a function as a Filter.
+type FilterFunc func(row []string) (bool, error)
+
+// Keep calls f(row).
+func (f FilterFunc) Keep(row []string) (bool, error) {
+	return f(row)
+}
+
+// A Transform modifies the rows of a table.
+//
+// The transforms implemented in this package
+// can be used concurrently.
+type Transform interface {
+	// Header returns the column names
+	// of the transformed table.
+	Header() []string
+
+	// Transform returns the modified row.
+	// If the returned row is nil,
+	// the row is dropped.
+	Transform(row []string) ([]string, error)
+}
+
+// A Table is a Reader
+// of an occurrence table
+// stored as a TSV file.
+type Table struct {
+	tab    *tsv.Reader
+	header []string
+	line   int
+}
+
+// NewTable returns a Table
+// that reads from r.
+// The header of the table is read
+// when the Table is created.
+func NewTable(r io.Reader) (*Table, error) {
+	tab := tsv.NewReader(r)
+	tab.Comma = '\t'
+	return NewTableReader(tab)
+}
+
+// NewTableReader returns a Table
+// that reads from a TSV reader.
+// The header of the table is read
+// when the Table is created.
+func NewTableReader(tab *tsv.Reader) (*Table, error) {
+	header, err := tab.Read()
+	if err != nil {
+		return nil, fmt.Errorf("header: %v", err)
+	}
+	return &Table{tab: tab, header: header}, nil
+}
+
+// Header returns the column names of the table.
+func (t *Table) Header() []string {
+	return t.header
+}
+
+// Read returns the next row of the table.
+func (t *Table) Read() ([]string, error) {
+	row, err := t.tab.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	}
+	t.line, _ = t.tab.FieldPos(0)
+	if err != nil {
+		return nil, fmt.Errorf("row %d: %v", t.line, err)
+	}
+	return row, nil
+}
+
+// Line returns the line number
+// of the last row read.
+func (t *Table) Line() int {
+	return t.line
+}
+
+// A TableWriter is a Writer
+// that writes an occurrence table
+// as a TSV file.
+type TableWriter struct {
+	w *tsv.Writer
+}
+
+// NewTableWriter returns a TableWriter
+// that writes to w,
+// and writes the header of the table.
+func NewTableWriter(w io.Writer, header []string) (*TableWriter, error) {
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	if err := out.Write(header); err != nil {
+		return nil, err
+	}
+	return &TableWriter{w: out}, nil
+}
+
+// Write writes a row.
+func (t *TableWriter) Write(row []string) error {
+	return t.w.Write(row)
+}
+
+// Flush writes any buffered data
+// into the underlying io.Writer.
+func (t *TableWriter) Flush() error {
+	t.w.Flush()
+	return t.w.Error()
+}
+
+// Select returns a Reader
+// with the rows of r
+// that are kept by the filter.
+func Select(r Reader, f Filter) Reader {
+	return &selected{r: r, f: f}
+}
+
+type selected struct {
+	r Reader
+	f Filter
+}
+
+func (s *selected) Header() []string {
+	return s.r.Header()
+}
+
+func (s *selected) Read() ([]string, error) {
+	for {
+		row, err := s.r.Read()
+		if err != nil {
+			return nil, err
+		}
+		ok, err := s.f.Keep(row)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return row, nil
+		}
+	}
+}
+
+// Apply returns a Reader
+// with the rows of r
+// modified by the transform.
+func Apply(r Reader, t Transform) Reader {
+	return &applied{r: r, t: t}
+}
+
+type applied struct {
+	r Reader
+	t Transform
+}
+
+func (a *applied) Header() []string {
+	return a.t.Header()
+}
+
+func (a *applied) Read() ([]string, error) {
+	for {
+		row, err := a.r.Read()
+		if err != nil {
+			return nil, err
+		}
+		row, err = a.t.Transform(row)
+		if err != nil {
+			return nil, err
+		}
+		if row != nil {
+			return row, nil
+		}
+	}
+}
+
+// Copy writes all the rows of r into w.
+// It returns the number of rows written.
+func Copy(w Writer, r Reader) (int, error) {
+	n := 0
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if err := w.Write(row); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// Col returns the index of a column
+// in a header,
+// or -1 if the column is not found.
+// Column names are case insensitive.
+func Col(header []string, name string) int {
+	for i, h := range header {
+		if strings.EqualFold(h, name) {
+			return i
+		}
+	}
+	return -1
+}
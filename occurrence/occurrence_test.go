@@ -0,0 +1,189 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package occurrence_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/gbifer/occurrence"
+	"github.com/js-arias/gbifer/taxonomy"
+)
+
+const occTable = "gbifID\ttaxonKey\tspeciesKey\tcountryCode\r\n" +
+	"1\t2\t2\tAR\r\n" +
+	"2\t1\t\tBR\r\n" +
+	"3\t3\t2\tAR\r\n" +
+	"4\t9\t9\tUY\r\n"
+
+const taxTable = "name\tauthor\ttaxonKey\trank\tstatus\tparent\n" +
+	"Puma\t\t1\tgenus\taccepted\t\n" +
+	"Puma concolor\t\t2\tspecies\taccepted\t1\n" +
+	"Felis concolor\t\t3\tspecies\tsynonym\t2\n"
+
+func TestPipeline(t *testing.T) {
+	tx, err := taxonomy.Read(strings.NewReader(taxTable))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := map[string]struct {
+		pipe func(occurrence.Reader) (occurrence.Reader, error)
+		want string
+	}{
+		"copy": {
+			pipe: func(r occurrence.Reader) (occurrence.Reader, error) {
+				return r, nil
+			},
+			want: occTable,
+		},
+		"with species": {
+			pipe: func(r occurrence.Reader) (occurrence.Reader, error) {
+				f, err := occurrence.WithSpecies(r.Header())
+				if err != nil {
+					return nil, err
+				}
+				return occurrence.Select(r, f), nil
+			},
+			want: "gbifID\ttaxonKey\tspeciesKey\tcountryCode\r\n" +
+				"1\t2\t2\tAR\r\n" +
+				"3\t3\t2\tAR\r\n" +
+				"4\t9\t9\tUY\r\n",
+		},
+		"in taxonomy": {
+			pipe: func(r occurrence.Reader) (occurrence.Reader, error) {
+				f, err := occurrence.InTaxonomy(r.Header(), tx)
+				if err != nil {
+					return nil, err
+				}
+				return occurrence.Select(r, f), nil
+			},
+			want: "gbifID\ttaxonKey\tspeciesKey\tcountryCode\r\n" +
+				"1\t2\t2\tAR\r\n" +
+				"3\t3\t2\tAR\r\n",
+		},
+		"in countries": {
+			pipe: func(r occurrence.Reader) (occurrence.Reader, error) {
+				f, err := occurrence.InCountries(r.Header(), tx, map[int64]map[string]bool{
+					2: {"AR": true},
+				})
+				if err != nil {
+					return nil, err
+				}
+				return occurrence.Select(r, f), nil
+			},
+			want: "gbifID\ttaxonKey\tspeciesKey\tcountryCode\r\n" +
+				"1\t2\t2\tAR\r\n" +
+				"3\t3\t2\tAR\r\n",
+		},
+		"columns": {
+			pipe: func(r occurrence.Reader) (occurrence.Reader, error) {
+				return occurrence.Apply(r, occurrence.Columns(r.Header(), []string{"countrycode", "GBIFID"}, false)), nil
+			},
+			want: "gbifID\tcountryCode\r\n" +
+				"1\tAR\r\n" +
+				"2\tBR\r\n" +
+				"3\tAR\r\n" +
+				"4\tUY\r\n",
+		},
+		"delete columns": {
+			pipe: func(r occurrence.Reader) (occurrence.Reader, error) {
+				return occurrence.Apply(r, occurrence.Columns(r.Header(), []string{"taxonKey", "speciesKey"}, true)), nil
+			},
+			want: "gbifID\tcountryCode\r\n" +
+				"1\tAR\r\n" +
+				"2\tBR\r\n" +
+				"3\tAR\r\n" +
+				"4\tUY\r\n",
+		},
+		"composed": {
+			pipe: func(r occurrence.Reader) (occurrence.Reader, error) {
+				ar := occurrence.FilterFunc(func(row []string) (bool, error) {
+					return row[3] == "AR", nil
+				})
+				r = occurrence.Select(r, ar)
+				return occurrence.Apply(r, occurrence.Columns(r.Header(), []string{"gbifID"}, false)), nil
+			},
+			want: "gbifID\r\n" +
+				"1\r\n" +
+				"3\r\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tab, err := occurrence.NewTable(strings.NewReader(occTable))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			r, err := test.pipe(tab)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var buf bytes.Buffer
+			w, err := occurrence.NewTableWriter(&buf, r.Header())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := occurrence.Copy(w, r); err != nil {
+				t.Fatalf("copy: unexpected error: %v", err)
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("flush: unexpected error: %v", err)
+			}
+			if got := buf.String(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWithSpeciesWithoutField(t *testing.T) {
+	if _, err := occurrence.WithSpecies([]string{"gbifID", "taxonKey"}); err == nil {
+		t.Errorf("expecting error")
+	}
+}
+
+func TestTaxonFilterReason(t *testing.T) {
+	tx, err := taxonomy.Read(strings.NewReader(taxTable))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	header := []string{"gbifID", "taxonKey", "speciesKey", "countryCode"}
+	f, err := occurrence.InCountries(header, tx, map[int64]map[string]bool{
+		2: {"AR": true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := map[string]struct {
+		row  []string
+		want string
+	}{
+		"kept":            {row: []string{"1", "2", "2", "AR"}},
+		"synonym":         {row: []string{"2", "3", "2", "ar"}},
+		"no species":      {row: []string{"3", "1", "", "AR"}, want: occurrence.NoSpecies},
+		"not in taxonomy": {row: []string{"4", "9", "9", "AR"}, want: occurrence.NotInTaxonomy},
+		"out of country":  {row: []string{"5", "2", "2", "BR"}, want: occurrence.OutOfCountries},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := f.Reason(test.row)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	if _, err := occurrence.InCountries(header[:3], tx, nil); err == nil {
+		t.Errorf("without countryCode: expecting error")
+	}
+}
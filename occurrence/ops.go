@@ -0,0 +1,196 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package occurrence
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/gbifer/taxonomy"
+)
+
+// WithSpecies returns a Filter
+// that keeps the rows associated with a taxon
+// identified up to species level
+// (i.e., with a non-empty "speciesKey").
+func WithSpecies(header []string) (Filter, error) {
+	col := Col(header, "speciesKey")
+	if col < 0 {
+		return nil, fmt.Errorf("without %q field", "speciesKey")
+	}
+	return FilterFunc(func(row []string) (bool, error) {
+		return strings.TrimSpace(row[col]) != "", nil
+	}), nil
+}
+
+// Reasons given by a TaxonFilter
+// to reject a row.
+const (
+	// The row is not identified up to species level.
+	NoSpecies = "no-species"
+
+	// The taxon is not in the taxonomy.
+	NotInTaxonomy = "taxonomy"
+
+	// The taxon is above species level.
+	AboveSpecies = "rank"
+
+	// The taxon is not in the country list.
+	NoCountries = "country-file"
+
+	// The country of the row
+	// is not in the countries of the taxon.
+	OutOfCountries = "country"
+)
+
+// A TaxonFilter is a Filter
+// that keeps the rows of taxa in a taxonomy
+// at or below species level,
+// and optionally,
+// of the countries of each taxon.
+//
+// The taxon of a row is defined by the "taxonKey",
+// and if it is absent,
+// by the "speciesKey".
+// Rows with an empty "speciesKey" are always rejected.
+type TaxonFilter struct {
+	tx        *taxonomy.Taxonomy
+	countries map[int64]map[string]bool
+
+	keyCol     int
+	taxCol     int
+	countryCol int
+}
+
+// InTaxonomy returns a TaxonFilter
+// that keeps the rows of taxa in the taxonomy
+// at or below species level.
+func InTaxonomy(header []string, tx *taxonomy.Taxonomy) (*TaxonFilter, error) {
+	keyCol := Col(header, "speciesKey")
+	taxCol := Col(header, "taxonKey")
+	if keyCol < 0 && taxCol < 0 {
+		return nil, fmt.Errorf("without %q or %q fields", "speciesKey", "taxonKey")
+	}
+	return &TaxonFilter{
+		tx:         tx,
+		keyCol:     keyCol,
+		taxCol:     taxCol,
+		countryCol: -1,
+	}, nil
+}
+
+// InCountries returns a TaxonFilter
+// that keeps the rows of taxa in the taxonomy
+// at or below species level,
+// that are in the countries defined for the taxon.
+// Countries is a map of accepted taxon IDs
+// to a set of ISO 3166-1 alpha-2 country codes,
+// in upper case.
+func InCountries(header []string, tx *taxonomy.Taxonomy, countries map[int64]map[string]bool) (*TaxonFilter, error) {
+	f, err := InTaxonomy(header, tx)
+	if err != nil {
+		return nil, err
+	}
+	f.countryCol = Col(header, "countryCode")
+	if f.countryCol < 0 {
+		return nil, fmt.Errorf("without %q field", "countryCode")
+	}
+	f.countries = countries
+	return f, nil
+}
+
+// Keep returns true if a row is kept by the filter.
+func (f *TaxonFilter) Keep(row []string) (bool, error) {
+	reason, err := f.Reason(row)
+	if err != nil {
+		return false, err
+	}
+	return reason == "", nil
+}
+
+// Reason returns the reason to reject a row,
+// or an empty string if the row is kept.
+func (f *TaxonFilter) Reason(row []string) (string, error) {
+	var key string
+	if f.keyCol >= 0 {
+		key = row[f.keyCol]
+		if key == "" {
+			return NoSpecies, nil
+		}
+	}
+	if f.taxCol >= 0 {
+		key = row[f.taxCol]
+	}
+	if key == "" {
+		return NoSpecies, nil
+	}
+	id, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return "", err
+	}
+	if f.tx.Taxon(id).ID != id {
+		return NotInTaxonomy, nil
+	}
+	if f.tx.Rank(id) < taxonomy.Species {
+		return AboveSpecies, nil
+	}
+	if f.countries == nil {
+		return "", nil
+	}
+
+	v := f.tx.AcceptedAndRanked(id).ID
+	if v == 0 {
+		return NotInTaxonomy, nil
+	}
+	cs, ok := f.countries[v]
+	if !ok {
+		return NoCountries, nil
+	}
+	country := strings.TrimSpace(strings.ToUpper(row[f.countryCol]))
+	if !cs[country] {
+		return OutOfCountries, nil
+	}
+	return "", nil
+}
+
+// Columns returns a Transform
+// that selects the indicated columns
+// in the order of the header.
+// If del is true,
+// the indicated columns are removed.
+func Columns(header, names []string, del bool) Transform {
+	cols := make(map[string]bool, len(names))
+	for _, n := range names {
+		cols[strings.ToLower(n)] = true
+	}
+
+	c := &columns{}
+	for i, h := range header {
+		if cols[strings.ToLower(h)] == del {
+			continue
+		}
+		c.keep = append(c.keep, i)
+		c.header = append(c.header, h)
+	}
+	return c
+}
+
+type columns struct {
+	header []string
+	keep   []int
+}
+
+func (c *columns) Header() []string {
+	return c.header
+}
+
+func (c *columns) Transform(row []string) ([]string, error) {
+	nr := make([]string, len(c.keep))
+	for i, k := range c.keep {
+		nr[i] = row[k]
+	}
+	return nr, nil
+}
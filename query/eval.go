@@ -0,0 +1,546 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type kind int
+
+const (
+	null kind = iota
+	num
+	str
+	boolean
+)
+
+// A value is the result of an expression.
+type value struct {
+	k kind
+	n float64
+	s string
+	b bool
+}
+
+var nullValue = value{}
+
+func numValue(n float64) value {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return nullValue
+	}
+	return value{k: num, n: n}
+}
+
+func strValue(s string) value {
+	return value{k: str, s: s}
+}
+
+func boolValue(b bool) value {
+	return value{k: boolean, b: b}
+}
+
+// String returns the value
+// as it is written in a table.
+func (v value) String() string {
+	switch v.k {
+	case num:
+		return strconv.FormatFloat(v.n, 'f', -1, 64)
+	case str:
+		return v.s
+	case boolean:
+		if v.b {
+			return "true"
+		}
+		return "false"
+	}
+	return ""
+}
+
+// number returns the value as a number.
+func (v value) number() (float64, bool) {
+	switch v.k {
+	case num:
+		return v.n, true
+	case str:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v.s), 64)
+		if err != nil {
+			return 0, false
+		}
+		return n, true
+	case boolean:
+		if v.b {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// truth returns the value as a boolean.
+// Null values are false.
+func (v value) truth() bool {
+	switch v.k {
+	case boolean:
+		return v.b
+	case num:
+		return v.n != 0
+	case str:
+		if n, ok := v.number(); ok {
+			return n != 0
+		}
+		return v.s != "" && !strings.EqualFold(v.s, "false")
+	}
+	return false
+}
+
+// compare compares two non-null values.
+// If both values are numbers,
+// they are compared as numbers,
+// otherwise as strings.
+func compare(a, b value) int {
+	if x, ok := a.number(); ok {
+		if y, ok := b.number(); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(a.String(), b.String())
+}
+
+// compareOrder compares two values
+// for sorting.
+// Null values are sorted first.
+func compareOrder(a, b value) int {
+	switch {
+	case a.k == null && b.k == null:
+		return 0
+	case a.k == null:
+		return -1
+	case b.k == null:
+		return 1
+	}
+	return compare(a, b)
+}
+
+// A context is the data used
+// to evaluate an expression.
+type context struct {
+	row   []string
+	group *group
+}
+
+// An expr is an expression.
+type expr interface {
+	eval(c *context) value
+}
+
+type colExpr struct {
+	name string
+	idx  int
+}
+
+func (e *colExpr) eval(c *context) value {
+	v := c.row[e.idx]
+	if v == "" {
+		return nullValue
+	}
+	return strValue(v)
+}
+
+type litExpr struct {
+	v value
+}
+
+func (e *litExpr) eval(c *context) value {
+	return e.v
+}
+
+type unaryExpr struct {
+	op string
+	x  expr
+}
+
+func (e *unaryExpr) eval(c *context) value {
+	v := e.x.eval(c)
+	if v.k == null {
+		return nullValue
+	}
+	if e.op == "NOT" {
+		return boolValue(!v.truth())
+	}
+	n, ok := v.number()
+	if !ok {
+		return nullValue
+	}
+	return numValue(-n)
+}
+
+type binExpr struct {
+	op   string
+	x, y expr
+}
+
+func (e *binExpr) eval(c *context) value {
+	switch e.op {
+	case "AND":
+		x := e.x.eval(c)
+		if x.k != null && !x.truth() {
+			return boolValue(false)
+		}
+		y := e.y.eval(c)
+		if y.k != null && !y.truth() {
+			return boolValue(false)
+		}
+		if x.k == null || y.k == null {
+			return nullValue
+		}
+		return boolValue(true)
+	case "OR":
+		x := e.x.eval(c)
+		if x.truth() {
+			return boolValue(true)
+		}
+		y := e.y.eval(c)
+		if y.truth() {
+			return boolValue(true)
+		}
+		if x.k == null || y.k == null {
+			return nullValue
+		}
+		return boolValue(false)
+	case "||":
+		return strValue(e.x.eval(c).String() + e.y.eval(c).String())
+	}
+
+	x, y := e.x.eval(c), e.y.eval(c)
+	if x.k == null || y.k == null {
+		return nullValue
+	}
+	switch e.op {
+	case "=":
+		return boolValue(compare(x, y) == 0)
+	case "<>":
+		return boolValue(compare(x, y) != 0)
+	case "<":
+		return boolValue(compare(x, y) < 0)
+	case "<=":
+		return boolValue(compare(x, y) <= 0)
+	case ">":
+		return boolValue(compare(x, y) > 0)
+	case ">=":
+		return boolValue(compare(x, y) >= 0)
+	}
+
+	a, ok := x.number()
+	if !ok {
+		return nullValue
+	}
+	b, ok := y.number()
+	if !ok {
+		return nullValue
+	}
+	switch e.op {
+	case "+":
+		return numValue(a + b)
+	case "-":
+		return numValue(a - b)
+	case "*":
+		return numValue(a * b)
+	case "/":
+		if b == 0 {
+			return nullValue
+		}
+		return numValue(a / b)
+	case "%":
+		if b == 0 {
+			return nullValue
+		}
+		return numValue(math.Mod(a, b))
+	}
+	return nullValue
+}
+
+type isNullExpr struct {
+	x   expr
+	not bool
+}
+
+func (e *isNullExpr) eval(c *context) value {
+	isNull := e.x.eval(c).k == null
+	return boolValue(isNull != e.not)
+}
+
+type likeExpr struct {
+	x, pattern expr
+	not        bool
+}
+
+func (e *likeExpr) eval(c *context) value {
+	x, p := e.x.eval(c), e.pattern.eval(c)
+	if x.k == null || p.k == null {
+		return nullValue
+	}
+	m := like(strings.ToLower(x.String()), strings.ToLower(p.String()))
+	return boolValue(m != e.not)
+}
+
+// like returns true if a string matches a pattern,
+// in which "%" matches any sequence of characters,
+// and "_" matches a single character.
+func like(s, p string) bool {
+	for len(p) > 0 {
+		switch p[0] {
+		case '%':
+			p = strings.TrimLeft(p, "%")
+			if p == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if like(s[i:], p) {
+					return true
+				}
+			}
+			return false
+		case '_':
+			if s == "" {
+				return false
+			}
+			_, size := utf8.DecodeRuneInString(s)
+			s, p = s[size:], p[1:]
+		default:
+			if s == "" || s[0] != p[0] {
+				return false
+			}
+			s, p = s[1:], p[1:]
+		}
+	}
+	return s == ""
+}
+
+type inExpr struct {
+	x    expr
+	list []expr
+	not  bool
+}
+
+func (e *inExpr) eval(c *context) value {
+	x := e.x.eval(c)
+	if x.k == null {
+		return nullValue
+	}
+	for _, l := range e.list {
+		v := l.eval(c)
+		if v.k != null && compare(x, v) == 0 {
+			return boolValue(!e.not)
+		}
+	}
+	return boolValue(e.not)
+}
+
+type betweenExpr struct {
+	x, lo, hi expr
+	not       bool
+}
+
+func (e *betweenExpr) eval(c *context) value {
+	x, lo, hi := e.x.eval(c), e.lo.eval(c), e.hi.eval(c)
+	if x.k == null || lo.k == null || hi.k == null {
+		return nullValue
+	}
+	in := compare(x, lo) >= 0 && compare(x, hi) <= 0
+	return boolValue(in != e.not)
+}
+
+// scalar functions
+// and their number of arguments
+// (-1 for any number).
+var functions = map[string][2]int{
+	"LOWER":    {1, 1},
+	"UPPER":    {1, 1},
+	"LENGTH":   {1, 1},
+	"TRIM":     {1, 1},
+	"SUBSTR":   {2, 3},
+	"COALESCE": {1, -1},
+	"ROUND":    {1, 2},
+	"ABS":      {1, 1},
+}
+
+type funcExpr struct {
+	name string
+	args []expr
+}
+
+func (e *funcExpr) eval(c *context) value {
+	if e.name == "COALESCE" {
+		for _, a := range e.args {
+			if v := a.eval(c); v.k != null {
+				return v
+			}
+		}
+		return nullValue
+	}
+
+	x := e.args[0].eval(c)
+	if x.k == null {
+		return nullValue
+	}
+	switch e.name {
+	case "LOWER":
+		return strValue(strings.ToLower(x.String()))
+	case "UPPER":
+		return strValue(strings.ToUpper(x.String()))
+	case "LENGTH":
+		return numValue(float64(utf8.RuneCountInString(x.String())))
+	case "TRIM":
+		return strValue(strings.TrimSpace(x.String()))
+	case "SUBSTR":
+		r := []rune(x.String())
+		start, ok := e.args[1].eval(c).number()
+		if !ok {
+			return nullValue
+		}
+		// positions start at 1
+		i := int(start) - 1
+		if i < 0 {
+			i = 0
+		}
+		if i > len(r) {
+			i = len(r)
+		}
+		j := len(r)
+		if len(e.args) > 2 {
+			n, ok := e.args[2].eval(c).number()
+			if !ok {
+				return nullValue
+			}
+			if i+int(n) < j {
+				j = i + int(n)
+			}
+			if j < i {
+				j = i
+			}
+		}
+		return strValue(string(r[i:j]))
+	case "ROUND":
+		n, ok := x.number()
+		if !ok {
+			return nullValue
+		}
+		d := 0.0
+		if len(e.args) > 1 {
+			d, ok = e.args[1].eval(c).number()
+			if !ok {
+				return nullValue
+			}
+		}
+		p := math.Pow(10, math.Trunc(d))
+		return numValue(math.Round(n*p) / p)
+	case "ABS":
+		n, ok := x.number()
+		if !ok {
+			return nullValue
+		}
+		return numValue(math.Abs(n))
+	}
+	return nullValue
+}
+
+// aggregate functions
+var aggregates = map[string]bool{
+	"COUNT": true,
+	"SUM":   true,
+	"AVG":   true,
+	"MIN":   true,
+	"MAX":   true,
+}
+
+type aggExpr struct {
+	name     string
+	arg      expr // nil for COUNT(*)
+	distinct bool
+	idx      int
+}
+
+func (e *aggExpr) eval(c *context) value {
+	return c.group.aggs[e.idx].result(e)
+}
+
+// A group is a group of rows
+// in an aggregated query.
+type group struct {
+	row  []string // the first row of the group
+	aggs []aggState
+}
+
+type aggState struct {
+	count int
+	sum   float64
+	nums  int
+	best  value
+	seen  map[string]bool
+}
+
+func (s *aggState) add(e *aggExpr, c *context) {
+	if e.arg == nil {
+		s.count++
+		return
+	}
+	v := e.arg.eval(c)
+	if v.k == null {
+		return
+	}
+	if e.distinct {
+		if s.seen == nil {
+			s.seen = make(map[string]bool)
+		}
+		k := v.String()
+		if s.seen[k] {
+			return
+		}
+		s.seen[k] = true
+	}
+	s.count++
+	if n, ok := v.number(); ok {
+		s.sum += n
+		s.nums++
+	}
+	switch e.name {
+	case "MIN":
+		if s.best.k == null || compare(v, s.best) < 0 {
+			s.best = v
+		}
+	case "MAX":
+		if s.best.k == null || compare(v, s.best) > 0 {
+			s.best = v
+		}
+	}
+}
+
+func (s *aggState) result(e *aggExpr) value {
+	switch e.name {
+	case "COUNT":
+		return numValue(float64(s.count))
+	case "SUM":
+		if s.nums == 0 {
+			return nullValue
+		}
+		return numValue(s.sum)
+	case "AVG":
+		if s.nums == 0 {
+			return nullValue
+		}
+		return numValue(s.sum / float64(s.nums))
+	}
+	return s.best
+}
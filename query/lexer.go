@@ -0,0 +1,167 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tkEOF tokenKind = iota
+	tkIdent
+	tkKeyword
+	tkNumber
+	tkString
+	tkSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string // keywords are in upper case
+	pos  int
+}
+
+var keywords = map[string]bool{
+	"SELECT":   true,
+	"DISTINCT": true,
+	"FROM":     true,
+	"WHERE":    true,
+	"GROUP":    true,
+	"BY":       true,
+	"HAVING":   true,
+	"ORDER":    true,
+	"ASC":      true,
+	"DESC":     true,
+	"LIMIT":    true,
+	"OFFSET":   true,
+	"AS":       true,
+	"AND":      true,
+	"OR":       true,
+	"NOT":      true,
+	"IS":       true,
+	"NULL":     true,
+	"LIKE":     true,
+	"IN":       true,
+	"BETWEEN":  true,
+	"TRUE":     true,
+	"FALSE":    true,
+}
+
+// lex splits a query into tokens.
+func lex(s string) ([]token, error) {
+	var tks []token
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '-' && strings.HasPrefix(s[i:], "--"):
+			// comment
+			j := strings.IndexByte(s[i:], '\n')
+			if j < 0 {
+				i = len(s)
+			} else {
+				i += j
+			}
+		case r == '\'':
+			v, n, err := quoted(s[i:], '\'')
+			if err != nil {
+				return nil, fmt.Errorf("at %d: %v", i+1, err)
+			}
+			tks = append(tks, token{kind: tkString, text: v, pos: i})
+			i += n
+		case r == '"' || r == '`':
+			v, n, err := quoted(s[i:], byte(r))
+			if err != nil {
+				return nil, fmt.Errorf("at %d: %v", i+1, err)
+			}
+			tks = append(tks, token{kind: tkIdent, text: v, pos: i})
+			i += n
+		case r >= '0' && r <= '9' || r == '.' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9':
+			j := i
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.') {
+				j++
+			}
+			if j < len(s) && (s[j] == 'e' || s[j] == 'E') {
+				k := j + 1
+				if k < len(s) && (s[k] == '+' || s[k] == '-') {
+					k++
+				}
+				if k < len(s) && s[k] >= '0' && s[k] <= '9' {
+					j = k
+					for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+						j++
+					}
+				}
+			}
+			tks = append(tks, token{kind: tkNumber, text: s[i:j], pos: i})
+			i = j
+		case r == '_' || unicode.IsLetter(r):
+			j := i
+			for j < len(s) {
+				r, size := utf8.DecodeRuneInString(s[j:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				j += size
+			}
+			w := s[i:j]
+			if keywords[strings.ToUpper(w)] {
+				tks = append(tks, token{kind: tkKeyword, text: strings.ToUpper(w), pos: i})
+			} else {
+				tks = append(tks, token{kind: tkIdent, text: w, pos: i})
+			}
+			i = j
+		default:
+			sym := ""
+			for _, op := range []string{"<=", ">=", "<>", "!=", "||", "==", "=", "<", ">", "+", "-", "*", "/", "%", "(", ")", ",", ";"} {
+				if strings.HasPrefix(s[i:], op) {
+					sym = op
+					break
+				}
+			}
+			if sym == "" {
+				return nil, fmt.Errorf("at %d: unexpected character %q", i+1, r)
+			}
+			start := i
+			i += len(sym)
+			switch sym {
+			case "==":
+				sym = "="
+			case "!=":
+				sym = "<>"
+			}
+			tks = append(tks, token{kind: tkSymbol, text: sym, pos: start})
+		}
+	}
+	tks = append(tks, token{kind: tkEOF, pos: len(s)})
+	return tks, nil
+}
+
+// quoted returns the content of a quoted string,
+// and the number of bytes used,
+// including the quotes.
+// A doubled quote is an escaped quote.
+func quoted(s string, q byte) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != q {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == q {
+			b.WriteByte(q)
+			i++
+			continue
+		}
+		return b.String(), i + 1, nil
+	}
+	return "", 0, fmt.Errorf("unclosed quote")
+}
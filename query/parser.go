@@ -0,0 +1,505 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type parser struct {
+	src  string
+	tks  []token
+	pos  int
+	cols []*colExpr
+	aggs []*aggExpr
+
+	// inAgg is true
+	// when parsing the argument of an aggregate function
+	inAgg bool
+}
+
+func (p *parser) peek() token {
+	return p.tks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tks[p.pos]
+	if t.kind != tkEOF {
+		p.pos++
+	}
+	return t
+}
+
+// isKeyword returns true
+// if the next token is the given keyword.
+func (p *parser) isKeyword(kw string) bool {
+	t := p.peek()
+	return t.kind == tkKeyword && t.text == kw
+}
+
+// isSymbol returns true
+// if the next token is the given symbol.
+func (p *parser) isSymbol(sym string) bool {
+	t := p.peek()
+	return t.kind == tkSymbol && t.text == sym
+}
+
+// accept consumes the next token
+// if it is the given keyword or symbol.
+func (p *parser) accept(s string) bool {
+	if p.isKeyword(s) || p.isSymbol(s) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.accept(s) {
+		return p.errorf("expecting %q", s)
+	}
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	t := p.peek()
+	near := t.text
+	if t.kind == tkEOF {
+		near = "end of query"
+	}
+	return fmt.Errorf("at %d (near %q): %s", t.pos+1, near, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) parseQuery() (*Query, error) {
+	q := &Query{limit: -1}
+	if err := p.expect("SELECT"); err != nil {
+		return nil, err
+	}
+	q.distinct = p.accept("DISTINCT")
+
+	// select items
+	for {
+		if p.accept("*") {
+			q.items = append(q.items, selItem{star: true})
+		} else {
+			start := p.peek().pos
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			it := selItem{expr: e, name: strings.TrimSpace(p.src[start:p.peek().pos])}
+			if c, ok := e.(*colExpr); ok {
+				it.name = c.name
+			}
+			if p.accept("AS") || p.peek().kind == tkIdent {
+				t := p.next()
+				if t.kind != tkIdent {
+					return nil, p.errorf("expecting an alias")
+				}
+				it.name = t.text
+				it.alias = true
+			}
+			q.items = append(q.items, it)
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	if p.accept("FROM") {
+		// the table is always the input table
+		if t := p.next(); t.kind != tkIdent {
+			return nil, p.errorf("expecting a table name")
+		}
+	}
+
+	if p.accept("WHERE") {
+		n := len(p.aggs)
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if len(p.aggs) > n {
+			return nil, fmt.Errorf("aggregate functions are not allowed in WHERE")
+		}
+		q.where = e
+	}
+
+	if p.accept("GROUP") {
+		if err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			e, err := p.parseRef()
+			if err != nil {
+				return nil, err
+			}
+			q.groupBy = append(q.groupBy, e)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if p.accept("HAVING") {
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		q.having = e
+	}
+
+	if p.accept("ORDER") {
+		if err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			e, err := p.parseRef()
+			if err != nil {
+				return nil, err
+			}
+			it := orderItem{expr: e}
+			if p.accept("DESC") {
+				it.desc = true
+			} else {
+				p.accept("ASC")
+			}
+			q.orderBy = append(q.orderBy, it)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if p.accept("LIMIT") {
+		n, err := p.parseInt()
+		if err != nil {
+			return nil, err
+		}
+		q.limit = n
+		if p.accept("OFFSET") {
+			n, err := p.parseInt()
+			if err != nil {
+				return nil, err
+			}
+			q.offset = n
+		}
+	}
+
+	p.accept(";")
+	if p.peek().kind != tkEOF {
+		return nil, p.errorf("unexpected token")
+	}
+
+	q.cols = p.cols
+	q.aggs = p.aggs
+	q.aggregate = len(q.aggs) > 0 || len(q.groupBy) > 0
+	if q.having != nil && !q.aggregate {
+		return nil, fmt.Errorf("HAVING without GROUP BY or aggregate functions")
+	}
+	return q, nil
+}
+
+func (p *parser) parseInt() (int, error) {
+	t := p.next()
+	if t.kind != tkNumber {
+		return 0, p.errorf("expecting a number")
+	}
+	n, err := strconv.Atoi(t.text)
+	if err != nil || n < 0 {
+		return 0, p.errorf("invalid number %q", t.text)
+	}
+	return n, nil
+}
+
+// parseRef parses an expression
+// of a GROUP BY or ORDER BY clause,
+// that can be a reference
+// to a select item.
+func (p *parser) parseRef() (expr, error) {
+	t := p.peek()
+	e, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t.kind == tkNumber && p.tks[p.pos-1] == t {
+		n, err := strconv.Atoi(t.text)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("at %d: invalid column number %q", t.pos+1, t.text)
+		}
+		return &refExpr{item: n}, nil
+	}
+	if c, ok := e.(*colExpr); ok {
+		return &refExpr{alias: c.name, col: c}, nil
+	}
+	return e, nil
+}
+
+func (p *parser) parseExpr() (expr, error) {
+	return p.parseOr()
+}
+
+func (p *parser) parseOr() (expr, error) {
+	x, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("OR") {
+		y, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		x = &binExpr{op: "OR", x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	x, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("AND") {
+		y, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		x = &binExpr{op: "AND", x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseNot() (expr, error) {
+	if p.accept("NOT") {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{op: "NOT", x: x}, nil
+	}
+	return p.parseCmp()
+}
+
+func (p *parser) parseCmp() (expr, error) {
+	x, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	if t.kind == tkSymbol {
+		switch t.text {
+		case "=", "<>", "<", "<=", ">", ">=":
+			p.next()
+			y, err := p.parseAdd()
+			if err != nil {
+				return nil, err
+			}
+			return &binExpr{op: t.text, x: x, y: y}, nil
+		}
+	}
+
+	if p.accept("IS") {
+		not := p.accept("NOT")
+		if err := p.expect("NULL"); err != nil {
+			return nil, err
+		}
+		return &isNullExpr{x: x, not: not}, nil
+	}
+
+	not := p.accept("NOT")
+	switch {
+	case p.accept("LIKE"):
+		y, err := p.parseAdd()
+		if err != nil {
+			return nil, err
+		}
+		return &likeExpr{x: x, pattern: y, not: not}, nil
+	case p.accept("IN"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		e := &inExpr{x: x, not: not}
+		for {
+			y, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			e.list = append(e.list, y)
+			if !p.accept(",") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return e, nil
+	case p.accept("BETWEEN"):
+		lo, err := p.parseAdd()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("AND"); err != nil {
+			return nil, err
+		}
+		hi, err := p.parseAdd()
+		if err != nil {
+			return nil, err
+		}
+		return &betweenExpr{x: x, lo: lo, hi: hi, not: not}, nil
+	}
+	if not {
+		return nil, p.errorf("expecting LIKE, IN, or BETWEEN")
+	}
+	return x, nil
+}
+
+func (p *parser) parseAdd() (expr, error) {
+	x, err := p.parseMul()
+	if err != nil {
+		return nil, err
+	}
+	for p.isSymbol("+") || p.isSymbol("-") || p.isSymbol("||") {
+		op := p.next().text
+		y, err := p.parseMul()
+		if err != nil {
+			return nil, err
+		}
+		x = &binExpr{op: op, x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseMul() (expr, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isSymbol("*") || p.isSymbol("/") || p.isSymbol("%") {
+		op := p.next().text
+		y, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		x = &binExpr{op: op, x: x, y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	if p.accept("-") {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{op: "-", x: x}, nil
+	}
+	if p.accept("+") {
+		return p.parseUnary()
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tkNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("at %d: invalid number %q", t.pos+1, t.text)
+		}
+		return &litExpr{v: numValue(n)}, nil
+	case tkString:
+		return &litExpr{v: strValue(t.text)}, nil
+	case tkKeyword:
+		switch t.text {
+		case "NULL":
+			return &litExpr{v: nullValue}, nil
+		case "TRUE":
+			return &litExpr{v: boolValue(true)}, nil
+		case "FALSE":
+			return &litExpr{v: boolValue(false)}, nil
+		}
+	case tkSymbol:
+		if t.text == "(" {
+			e, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return e, nil
+		}
+	case tkIdent:
+		if p.isSymbol("(") {
+			return p.parseFunc(t)
+		}
+		c := &colExpr{name: t.text}
+		p.cols = append(p.cols, c)
+		return c, nil
+	}
+	p.pos--
+	return nil, p.errorf("unexpected token")
+}
+
+func (p *parser) parseFunc(t token) (expr, error) {
+	name := strings.ToUpper(t.text)
+	p.next() // (
+
+	if aggregates[name] {
+		if p.inAgg {
+			return nil, fmt.Errorf("at %d: nested aggregate function %s", t.pos+1, name)
+		}
+		e := &aggExpr{name: name, idx: len(p.aggs)}
+		if name == "COUNT" && p.accept("*") {
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			p.aggs = append(p.aggs, e)
+			return e, nil
+		}
+		e.distinct = p.accept("DISTINCT")
+		p.inAgg = true
+		arg, err := p.parseExpr()
+		p.inAgg = false
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		e.arg = arg
+		p.aggs = append(p.aggs, e)
+		return e, nil
+	}
+
+	n, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("at %d: unknown function %q", t.pos+1, t.text)
+	}
+	e := &funcExpr{name: name}
+	if !p.isSymbol(")") {
+		for {
+			a, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			e.args = append(e.args, a)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if len(e.args) < n[0] || (n[1] >= 0 && len(e.args) > n[1]) {
+		return nil, fmt.Errorf("at %d: function %s: invalid number of arguments", t.pos+1, name)
+	}
+	return e, nil
+}
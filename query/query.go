@@ -0,0 +1,463 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+// Package query implements SQL queries
+// on occurrence tables.
+//
+// Only SELECT queries are supported,
+// with the following syntax:
+//
+//	SELECT [DISTINCT] <item> [, <item>...]
+//	[FROM <table>]
+//	[WHERE <condition>]
+//	[GROUP BY <expression> [, <expression>...]]
+//	[HAVING <condition>]
+//	[ORDER BY <expression> [ASC|DESC] [, <expression> [ASC|DESC]...]]
+//	[LIMIT <number> [OFFSET <number>]]
+//
+// The table is always the queried table,
+// so the table name in the FROM clause is ignored.
+// Column names are case insensitive,
+// and can be quoted with double quotes
+// (for example, "order", as ORDER is a keyword).
+// Strings are quoted with single quotes.
+//
+// Empty values are NULL.
+// Values are compared as numbers
+// if both values are numbers,
+// otherwise they are compared as strings.
+//
+// Expressions support
+// arithmetic operators (+, -, *, /, %),
+// string concatenation (||),
+// comparisons (=, <>, !=, <, <=, >, >=),
+// logical operators (AND, OR, NOT),
+// IS [NOT] NULL,
+// [NOT] LIKE (case insensitive),
+// [NOT] IN,
+// [NOT] BETWEEN,
+// the functions LOWER, UPPER, LENGTH, TRIM, SUBSTR,
+// COALESCE, ROUND, and ABS,
+// and the aggregate functions COUNT, SUM, AVG, MIN, and MAX.
+// Items in GROUP BY and ORDER BY
+// can be a column number of the result (starting at 1),
+// or the alias of a result column.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/js-arias/gbifer/occurrence"
+)
+
+// A Query is a parsed SQL query.
+type Query struct {
+	distinct  bool
+	items     []selItem
+	where     expr
+	groupBy   []expr
+	having    expr
+	orderBy   []orderItem
+	limit     int
+	offset    int
+	aggregate bool
+
+	cols []*colExpr
+	aggs []*aggExpr
+}
+
+type selItem struct {
+	expr  expr
+	name  string
+	alias bool
+	star  bool
+}
+
+type orderItem struct {
+	expr expr
+	desc bool
+}
+
+// A refExpr is an expression of a GROUP BY
+// or ORDER BY clause
+// that can be a reference to a select item.
+type refExpr struct {
+	item  int // the number of the item (starting at 1)
+	alias string
+	col   *colExpr
+
+	expr expr // the resolved expression
+}
+
+func (e *refExpr) eval(c *context) value {
+	return e.expr.eval(c)
+}
+
+// Parse parses a SQL query.
+func Parse(sql string) (*Query, error) {
+	tks, err := lex(sql)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: sql, tks: tks}
+	return p.parseQuery()
+}
+
+// Run runs a query on the rows of a Reader,
+// and returns a Reader with the result of the query.
+//
+// If the query does not aggregate or sort the rows,
+// the rows are read as the result is read.
+// Otherwise,
+// all the rows are read in the first call to Read,
+// and the groups,
+// or the resulting rows,
+// are kept in memory.
+//
+// A Query can be run only once.
+func (q *Query) Run(r occurrence.Reader) (occurrence.Reader, error) {
+	if err := q.bind(r.Header()); err != nil {
+		return nil, err
+	}
+	res := &result{q: q, r: r}
+	for _, it := range q.items {
+		res.header = append(res.header, it.name)
+	}
+	return res, nil
+}
+
+// bind binds the column names
+// to the columns of the table.
+func (q *Query) bind(header []string) error {
+	fields := make(map[string]int, len(header))
+	for i, h := range header {
+		h = strings.ToLower(h)
+		if _, dup := fields[h]; !dup {
+			fields[h] = i
+		}
+	}
+
+	// expand stars
+	var items []selItem
+	for _, it := range q.items {
+		if !it.star {
+			items = append(items, it)
+			continue
+		}
+		for i, h := range header {
+			items = append(items, selItem{expr: &colExpr{name: h, idx: i}, name: h})
+		}
+	}
+	q.items = items
+
+	// references
+	for i, e := range q.groupBy {
+		ref, ok := e.(*refExpr)
+		if !ok {
+			continue
+		}
+		if err := q.resolve(ref, fields, false); err != nil {
+			return fmt.Errorf("GROUP BY: %v", err)
+		}
+		if ref.item > 0 && q.hasAgg(ref.expr) {
+			return fmt.Errorf("GROUP BY: column %d is an aggregate", ref.item)
+		}
+		q.groupBy[i] = ref.expr
+	}
+	for _, o := range q.orderBy {
+		ref, ok := o.expr.(*refExpr)
+		if !ok {
+			continue
+		}
+		if err := q.resolve(ref, fields, true); err != nil {
+			return fmt.Errorf("ORDER BY: %v", err)
+		}
+	}
+
+	for _, c := range q.cols {
+		if c.idx < 0 {
+			// a reference to an alias
+			continue
+		}
+		i, ok := fields[strings.ToLower(c.name)]
+		if !ok {
+			return fmt.Errorf("unknown column %q", c.name)
+		}
+		c.idx = i
+	}
+
+	// use the column names of the table
+	for i, it := range q.items {
+		if c, ok := it.expr.(*colExpr); ok && !it.alias {
+			q.items[i].name = header[c.idx]
+		}
+	}
+	return nil
+}
+
+// resolve resolves a reference
+// of a GROUP BY or ORDER BY clause.
+func (q *Query) resolve(ref *refExpr, fields map[string]int, aliasFirst bool) error {
+	if ref.col == nil {
+		if ref.item > len(q.items) {
+			return fmt.Errorf("invalid column number %d", ref.item)
+		}
+		ref.expr = q.items[ref.item-1].expr
+		return nil
+	}
+
+	_, isCol := fields[strings.ToLower(ref.alias)]
+	if !aliasFirst && isCol {
+		ref.expr = ref.col
+		return nil
+	}
+	for i, it := range q.items {
+		if it.alias && strings.EqualFold(it.name, ref.alias) {
+			ref.item = i + 1
+			ref.expr = it.expr
+			// the column will not be bound
+			ref.col.idx = -1
+			return nil
+		}
+	}
+	ref.expr = ref.col
+	return nil
+}
+
+// hasAgg returns true
+// if an expression is,
+// or is part of,
+// a select item with aggregate functions.
+func (q *Query) hasAgg(e expr) bool {
+	switch x := e.(type) {
+	case *aggExpr:
+		return true
+	case *unaryExpr:
+		return q.hasAgg(x.x)
+	case *binExpr:
+		return q.hasAgg(x.x) || q.hasAgg(x.y)
+	case *isNullExpr:
+		return q.hasAgg(x.x)
+	case *likeExpr:
+		return q.hasAgg(x.x) || q.hasAgg(x.pattern)
+	case *betweenExpr:
+		return q.hasAgg(x.x) || q.hasAgg(x.lo) || q.hasAgg(x.hi)
+	case *inExpr:
+		if q.hasAgg(x.x) {
+			return true
+		}
+		for _, l := range x.list {
+			if q.hasAgg(l) {
+				return true
+			}
+		}
+	case *funcExpr:
+		for _, a := range x.args {
+			if q.hasAgg(a) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// A result is the Reader of the result of a query.
+type result struct {
+	q      *Query
+	r      occurrence.Reader
+	header []string
+
+	// buffered result
+	buffered bool
+	rows     [][]string
+
+	seen    map[string]bool
+	skipped int
+	written int
+}
+
+// An outRow is a row of the result
+// with its sorting keys.
+type outRow struct {
+	row  []string
+	keys []value
+}
+
+func (res *result) Header() []string {
+	return res.header
+}
+
+func (res *result) Read() ([]string, error) {
+	q := res.q
+	if q.aggregate || len(q.orderBy) > 0 {
+		if !res.buffered {
+			if err := res.fill(); err != nil {
+				return nil, err
+			}
+			res.buffered = true
+		}
+		if len(res.rows) == 0 {
+			return nil, io.EOF
+		}
+		row := res.rows[0]
+		res.rows = res.rows[1:]
+		return row, nil
+	}
+
+	// streaming
+	for {
+		if q.limit >= 0 && res.written >= q.limit {
+			return nil, io.EOF
+		}
+		row, err := res.r.Read()
+		if err != nil {
+			return nil, err
+		}
+		c := &context{row: row}
+		if q.where != nil && !q.where.eval(c).truth() {
+			continue
+		}
+		out := q.output(c)
+		if !res.keep(out) {
+			continue
+		}
+		res.written++
+		return out, nil
+	}
+}
+
+// keep returns true
+// if a result row must be written,
+// after DISTINCT and OFFSET.
+func (res *result) keep(out []string) bool {
+	if res.q.distinct {
+		if res.seen == nil {
+			res.seen = make(map[string]bool)
+		}
+		k := strings.Join(out, "\x00")
+		if res.seen[k] {
+			return false
+		}
+		res.seen[k] = true
+	}
+	if res.skipped < res.q.offset {
+		res.skipped++
+		return false
+	}
+	return true
+}
+
+// output returns the values
+// of the select items.
+func (q *Query) output(c *context) []string {
+	out := make([]string, len(q.items))
+	for i, it := range q.items {
+		out[i] = it.expr.eval(c).String()
+	}
+	return out
+}
+
+// fill reads all the rows
+// of an aggregated or sorted query.
+func (res *result) fill() error {
+	q := res.q
+
+	var out []outRow
+	add := func(c *context) {
+		o := outRow{row: q.output(c)}
+		for _, it := range q.orderBy {
+			if ref, ok := it.expr.(*refExpr); ok && ref.item > 0 {
+				v := strValue(o.row[ref.item-1])
+				if o.row[ref.item-1] == "" {
+					v = nullValue
+				}
+				o.keys = append(o.keys, v)
+				continue
+			}
+			o.keys = append(o.keys, it.expr.eval(c))
+		}
+		out = append(out, o)
+	}
+
+	var groups []*group
+	index := make(map[string]*group)
+	for {
+		row, err := res.r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		c := &context{row: row}
+		if q.where != nil && !q.where.eval(c).truth() {
+			continue
+		}
+		if !q.aggregate {
+			add(c)
+			continue
+		}
+
+		var key strings.Builder
+		for _, e := range q.groupBy {
+			key.WriteString(e.eval(c).String())
+			key.WriteByte(0)
+		}
+		g, ok := index[key.String()]
+		if !ok {
+			g = &group{row: row, aggs: make([]aggState, len(q.aggs))}
+			index[key.String()] = g
+			groups = append(groups, g)
+		}
+		for i, a := range q.aggs {
+			g.aggs[i].add(a, c)
+		}
+	}
+
+	if q.aggregate {
+		if len(groups) == 0 && len(q.groupBy) == 0 {
+			// an aggregate without groups
+			// always returns a row
+			empty := make([]string, len(res.r.Header()))
+			groups = append(groups, &group{row: empty, aggs: make([]aggState, len(q.aggs))})
+		}
+		for _, g := range groups {
+			c := &context{row: g.row, group: g}
+			if q.having != nil && !q.having.eval(c).truth() {
+				continue
+			}
+			add(c)
+		}
+	}
+
+	if len(q.orderBy) > 0 {
+		slices.SortStableFunc(out, func(a, b outRow) int {
+			for i, it := range q.orderBy {
+				c := compareOrder(a.keys[i], b.keys[i])
+				if it.desc {
+					c = -c
+				}
+				if c != 0 {
+					return c
+				}
+			}
+			return 0
+		})
+	}
+
+	for _, o := range out {
+		if q.limit >= 0 && len(res.rows) >= q.limit {
+			break
+		}
+		if !res.keep(o.row) {
+			continue
+		}
+		res.rows = append(res.rows, o.row)
+	}
+	return nil
+}
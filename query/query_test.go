@@ -0,0 +1,183 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package query_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/gbifer/occurrence"
+	"github.com/js-arias/gbifer/query"
+)
+
+const occTable = "gbifID\tspecies\tcountryCode\tyear\r\n" +
+	"1\tPuma concolor\tAR\t2001\r\n" +
+	"2\tPuma concolor\tBR\t1990\r\n" +
+	"3\tPanthera onca\tAR\t\r\n" +
+	"4\tPuma concolor\tAR\t2010\r\n" +
+	"5\tLeopardus wiedii\tUY\t1985\r\n"
+
+func TestQuery(t *testing.T) {
+	tests := map[string]struct {
+		sql  string
+		want string
+	}{
+		"star": {
+			sql:  "SELECT * FROM occurrences",
+			want: occTable,
+		},
+		"columns": {
+			sql: "select gbifid, countrycode as country from t where year > 2000",
+			want: "gbifID\tcountry\r\n" +
+				"1\tAR\r\n" +
+				"4\tAR\r\n",
+		},
+		"null": {
+			sql: "SELECT gbifID FROM t WHERE year IS NULL",
+			want: "gbifID\r\n" +
+				"3\r\n",
+		},
+		"like and in": {
+			sql: "SELECT gbifID FROM t WHERE species LIKE 'puma%' AND countryCode NOT IN ('BR', 'UY')",
+			want: "gbifID\r\n" +
+				"1\r\n" +
+				"4\r\n",
+		},
+		"between": {
+			sql: "SELECT gbifID, year FROM t WHERE year BETWEEN 1985 AND 2001",
+			want: "gbifID\tyear\r\n" +
+				"1\t2001\r\n" +
+				"2\t1990\r\n" +
+				"5\t1985\r\n",
+		},
+		"expressions": {
+			sql: "SELECT upper(countryCode) || '-' || gbifID AS id, year / 10 decade FROM t LIMIT 2",
+			want: "id\tdecade\r\n" +
+				"AR-1\t200.1\r\n" +
+				"BR-2\t199\r\n",
+		},
+		"distinct": {
+			sql: "SELECT DISTINCT species FROM t",
+			want: "species\r\n" +
+				"Puma concolor\r\n" +
+				"Panthera onca\r\n" +
+				"Leopardus wiedii\r\n",
+		},
+		"limit offset": {
+			sql: "SELECT gbifID FROM t LIMIT 2 OFFSET 1",
+			want: "gbifID\r\n" +
+				"2\r\n" +
+				"3\r\n",
+		},
+		"order": {
+			sql: "SELECT gbifID, year FROM t ORDER BY year DESC, 1",
+			want: "gbifID\tyear\r\n" +
+				"4\t2010\r\n" +
+				"1\t2001\r\n" +
+				"2\t1990\r\n" +
+				"5\t1985\r\n" +
+				"3\t\r\n",
+		},
+		"count": {
+			sql: "SELECT count(*), count(year), min(year), max(year) FROM t",
+			want: "count(*)\tcount(year)\tmin(year)\tmax(year)\r\n" +
+				"5\t4\t1985\t2010\r\n",
+		},
+		"count without rows": {
+			sql: "SELECT count(*) AS n FROM t WHERE year > 3000",
+			want: "n\r\n" +
+				"0\r\n",
+		},
+		"group": {
+			sql: "SELECT countryCode, count(*) AS n, avg(year) FROM t GROUP BY countryCode ORDER BY n DESC, countryCode",
+			want: "countryCode\tn\tavg(year)\r\n" +
+				"AR\t3\t2005.5\r\n" +
+				"BR\t1\t1990\r\n" +
+				"UY\t1\t1985\r\n",
+		},
+		"having": {
+			sql: "SELECT species, count(DISTINCT countryCode) AS countries FROM t GROUP BY 1 HAVING count(*) > 1",
+			want: "species\tcountries\r\n" +
+				"Puma concolor\t2\r\n",
+		},
+		"group by alias": {
+			sql: "SELECT year / 10 AS decade, count(*) AS n FROM t WHERE year IS NOT NULL GROUP BY decade ORDER BY decade",
+			want: "decade\tn\r\n" +
+				"198.5\t1\r\n" +
+				"199\t1\r\n" +
+				"200.1\t1\r\n" +
+				"201\t1\r\n",
+		},
+		"quoted column": {
+			sql: `SELECT "countryCode" FROM t WHERE gbifID = 5`,
+			want: "countryCode\r\n" +
+				"UY\r\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			q, err := query.Parse(test.sql)
+			if err != nil {
+				t.Fatalf("parse: unexpected error: %v", err)
+			}
+			tab, err := occurrence.NewTable(strings.NewReader(occTable))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			r, err := q.Run(tab)
+			if err != nil {
+				t.Fatalf("run: unexpected error: %v", err)
+			}
+
+			var buf bytes.Buffer
+			w, err := occurrence.NewTableWriter(&buf, r.Header())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := occurrence.Copy(w, r); err != nil {
+				t.Fatalf("copy: unexpected error: %v", err)
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("flush: unexpected error: %v", err)
+			}
+			if got := buf.String(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	tests := map[string]string{
+		"not a select":       "DELETE FROM t",
+		"unclosed string":    "SELECT * FROM t WHERE species = 'Puma",
+		"aggregate in where": "SELECT * FROM t WHERE count(*) > 1",
+		"nested aggregate":   "SELECT max(count(*)) FROM t",
+		"unknown function":   "SELECT foo(year) FROM t",
+		"having":             "SELECT * FROM t HAVING year > 1",
+		"trailing tokens":    "SELECT * FROM t year",
+		"unknown column":     "SELECT genus FROM t",
+		"column number":      "SELECT year FROM t ORDER BY 2",
+		"group by aggregate": "SELECT count(*) FROM t GROUP BY 1",
+	}
+
+	for name, sql := range tests {
+		t.Run(name, func(t *testing.T) {
+			q, err := query.Parse(sql)
+			if err != nil {
+				return
+			}
+			tab, err := occurrence.NewTable(strings.NewReader(occTable))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := q.Run(tab); err == nil {
+				t.Errorf("query %q: expecting error", sql)
+			}
+		})
+	}
+}
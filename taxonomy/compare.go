@@ -0,0 +1,85 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package taxonomy
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// A Change is a difference
+// between two taxonomies.
+type Change struct {
+	// Kind is the type of change.
+	// It can be:
+	// "added",
+	// "removed",
+	// "renamed",
+	// "reparented",
+	// "status",
+	// or "rank".
+	Kind string
+
+	ID   int64
+	Name string
+
+	// Old and New are the old and new values
+	// of the changed field.
+	Old string
+	New string
+}
+
+func (ch Change) String() string {
+	switch ch.Kind {
+	case "added", "removed":
+		return fmt.Sprintf("%s: %s [%d]", ch.Kind, ch.Name, ch.ID)
+	}
+	return fmt.Sprintf("%s: %s [%d]: %s -> %s", ch.Kind, ch.Name, ch.ID, ch.Old, ch.New)
+}
+
+// Compare returns the changes
+// between an old and a new taxonomy.
+// Taxa are compared using their IDs.
+func Compare(oldTx, newTx *Taxonomy) []Change {
+	var changes []Change
+	for _, id := range oldTx.IDs() {
+		o := oldTx.Taxon(id)
+		n := newTx.Taxon(id)
+		if n.ID == 0 {
+			changes = append(changes, Change{Kind: "removed", ID: id, Name: o.Name})
+			continue
+		}
+		if o.Name != n.Name {
+			changes = append(changes, Change{Kind: "renamed", ID: id, Name: n.Name, Old: o.Name, New: n.Name})
+		}
+		if o.Parent != n.Parent {
+			changes = append(changes, Change{Kind: "reparented", ID: id, Name: n.Name, Old: parentName(oldTx, o.Parent), New: parentName(newTx, n.Parent)})
+		}
+		if o.Status != n.Status {
+			changes = append(changes, Change{Kind: "status", ID: id, Name: n.Name, Old: o.Status, New: n.Status})
+		}
+		if o.Rank != n.Rank {
+			changes = append(changes, Change{Kind: "rank", ID: id, Name: n.Name, Old: o.Rank.String(), New: n.Rank.String()})
+		}
+	}
+	for _, id := range newTx.IDs() {
+		if oldTx.Taxon(id).ID != 0 {
+			continue
+		}
+		changes = append(changes, Change{Kind: "added", ID: id, Name: newTx.Taxon(id).Name})
+	}
+	return changes
+}
+
+func parentName(tx *Taxonomy, id int64) string {
+	if id == 0 {
+		return "<root>"
+	}
+	p := tx.Taxon(id)
+	if p.ID == 0 {
+		return strconv.FormatInt(id, 10)
+	}
+	return fmt.Sprintf("%s [%d]", p.Name, p.ID)
+}
@@ -0,0 +1,53 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package taxonomy_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/gbifer/taxonomy"
+)
+
+func TestCompare(t *testing.T) {
+	oldTx, err := taxonomy.Read(strings.NewReader(pumaTax))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	newTx, err := taxonomy.Read(strings.NewReader(pumaTax))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := taxonomy.Compare(oldTx, newTx); got != nil {
+		t.Errorf("same taxonomy: got %v, want %v", got, nil)
+	}
+
+	if err := newTx.Move(2, 4); err != nil {
+		t.Fatalf("move: unexpected error: %v", err)
+	}
+	if err := newTx.Remove(3); err != nil {
+		t.Fatalf("remove: unexpected error: %v", err)
+	}
+
+	want := []taxonomy.Change{
+		{Kind: "reparented", ID: 2, Name: "Puma concolor", Old: "Puma [1]", New: "Herpailurus [4]"},
+		{Kind: "removed", ID: 3, Name: "Felis concolor"},
+	}
+	got := taxonomy.Compare(oldTx, newTx)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	want = []taxonomy.Change{
+		{Kind: "reparented", ID: 2, Name: "Puma concolor", Old: "Herpailurus [4]", New: "Puma [1]"},
+		{Kind: "added", ID: 3, Name: "Felis concolor"},
+	}
+	got = taxonomy.Compare(newTx, oldTx)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("reverse: got %v, want %v", got, want)
+	}
+}
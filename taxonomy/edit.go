@@ -0,0 +1,213 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package taxonomy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Move changes the parent of a taxon.
+// If parent is 0,
+// the taxon will be a root taxon.
+// It returns an error if the parent is not in the taxonomy,
+// or if the parent is the taxon itself
+// or one of its descendants.
+func (tx *Taxonomy) Move(id, parent int64) error {
+	tx.Stage()
+
+	tax, ok := tx.ids[id]
+	if !ok {
+		return fmt.Errorf("taxonomy: move: taxon %d not found", id)
+	}
+	var p *taxon
+	if parent != 0 {
+		p, ok = tx.ids[parent]
+		if !ok {
+			return fmt.Errorf("taxonomy: move: parent %d not found", parent)
+		}
+		visited := make(map[int64]bool)
+		for a := parent; a != 0 && !visited[a]; {
+			if a == id {
+				return fmt.Errorf("taxonomy: move: taxon %d: parent %d is a descendant", id, parent)
+			}
+			visited[a] = true
+			t, ok := tx.ids[a]
+			if !ok {
+				break
+			}
+			a = t.data.Parent
+		}
+	}
+
+	tx.unlink(tax)
+	tax.data.Parent = parent
+	if p == nil {
+		tx.root = append(tx.root, tax)
+	} else {
+		p.children = append(p.children, tax)
+	}
+	tx.sort()
+	tx.cacheLineages()
+	return nil
+}
+
+// Rekey changes the ID of a taxon.
+// If the new ID is already in the taxonomy,
+// the taxon will be merged with the taxon with the new ID:
+// its children will be moved to that taxon,
+// and the taxon will be removed.
+func (tx *Taxonomy) Rekey(id, newID int64) error {
+	tx.Stage()
+
+	tax, ok := tx.ids[id]
+	if !ok {
+		return fmt.Errorf("taxonomy: rekey: taxon %d not found", id)
+	}
+	if newID == 0 {
+		return fmt.Errorf("taxonomy: rekey: taxon %d: invalid ID %d", id, newID)
+	}
+	if id == newID {
+		return nil
+	}
+
+	if to, ok := tx.ids[newID]; ok {
+		visited := make(map[int64]bool)
+		for a := to.data.Parent; a != 0 && !visited[a]; {
+			if a == id {
+				return fmt.Errorf("taxonomy: rekey: taxon %d: taxon %d is a descendant", id, newID)
+			}
+			visited[a] = true
+			t, ok := tx.ids[a]
+			if !ok {
+				break
+			}
+			a = t.data.Parent
+		}
+
+		for _, c := range tax.children {
+			c.data.Parent = newID
+			to.children = append(to.children, c)
+		}
+		tax.children = nil
+		tx.unlink(tax)
+		tx.remove(tax)
+		tx.sort()
+		tx.cacheLineages()
+		return nil
+	}
+
+	delete(tx.ids, id)
+	tax.data.ID = newID
+	tx.ids[newID] = tax
+	ids := tx.names[tax.data.Name]
+	if i := slices.Index(ids, id); i >= 0 {
+		ids[i] = newID
+	}
+	for _, c := range tax.children {
+		c.data.Parent = newID
+	}
+	tx.sort()
+	tx.cacheLineages()
+	return nil
+}
+
+// Remove removes a taxon,
+// and all of its descendants,
+// from the taxonomy.
+func (tx *Taxonomy) Remove(id int64) error {
+	tx.Stage()
+
+	tax, ok := tx.ids[id]
+	if !ok {
+		return fmt.Errorf("taxonomy: remove: taxon %d not found", id)
+	}
+	tx.unlink(tax)
+	tx.remove(tax)
+	return nil
+}
+
+func (tx *Taxonomy) remove(tax *taxon) {
+	for _, c := range tax.children {
+		tx.remove(c)
+	}
+
+	id := tax.data.ID
+	delete(tx.ids, id)
+	ids := tx.names[tax.data.Name]
+	if i := slices.Index(ids, id); i >= 0 {
+		ids = slices.Delete(ids, i, i+1)
+	}
+	if len(ids) == 0 {
+		delete(tx.names, tax.data.Name)
+	} else {
+		tx.names[tax.data.Name] = ids
+	}
+}
+
+// Rename changes the name of a taxon.
+func (tx *Taxonomy) Rename(id int64, name string) error {
+	tx.Stage()
+
+	tax, ok := tx.ids[id]
+	if !ok {
+		return fmt.Errorf("taxonomy: rename: taxon %d not found", id)
+	}
+	name = Canon(name)
+	if name == "" {
+		return fmt.Errorf("taxonomy: rename: taxon %d: empty name", id)
+	}
+
+	old := tax.data.Name
+	ids := tx.names[old]
+	if i := slices.Index(ids, id); i >= 0 {
+		ids = slices.Delete(ids, i, i+1)
+	}
+	if len(ids) == 0 {
+		delete(tx.names, old)
+	} else {
+		tx.names[old] = ids
+	}
+
+	tax.data.Name = name
+	tx.names[name] = append(tx.names[name], id)
+	tx.sort()
+	return nil
+}
+
+// SetStatus changes the taxonomic status of a taxon.
+func (tx *Taxonomy) SetStatus(id int64, status string) error {
+	tx.Stage()
+
+	tax, ok := tx.ids[id]
+	if !ok {
+		return fmt.Errorf("taxonomy: status: taxon %d not found", id)
+	}
+	status = strings.ToLower(strings.TrimSpace(status))
+	if status == "" {
+		return fmt.Errorf("taxonomy: status: taxon %d: empty status", id)
+	}
+
+	tax.data.Status = status
+	tx.sort()
+	tx.cacheLineages()
+	return nil
+}
+
+// unlink removes a taxon from the list of children
+// of its parent,
+// or from the list of root taxa.
+func (tx *Taxonomy) unlink(tax *taxon) {
+	if p, ok := tx.ids[tax.data.Parent]; ok && tax.data.Parent != 0 {
+		if i := slices.Index(p.children, tax); i >= 0 {
+			p.children = slices.Delete(p.children, i, i+1)
+			return
+		}
+	}
+	if i := slices.Index(tx.root, tax); i >= 0 {
+		tx.root = slices.Delete(tx.root, i, i+1)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package taxonomy_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/gbifer/taxonomy"
+)
+
+const pumaTax = taxHeader +
+	"Puma\t\t1\tgenus\taccepted\t\n" +
+	"Puma concolor\t\t2\tspecies\taccepted\t1\n" +
+	"Felis concolor\t\t3\tspecies\tsynonym\t2\n" +
+	"Herpailurus\t\t4\tgenus\taccepted\t\n"
+
+func TestEdit(t *testing.T) {
+	tx, err := taxonomy.Read(strings.NewReader(pumaTax))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := tx.Move(2, 4); err != nil {
+		t.Fatalf("move: unexpected error: %v", err)
+	}
+	if got := tx.Children(4); !reflect.DeepEqual(got, []int64{2}) {
+		t.Errorf("move: children: got %v, want %v", got, []int64{2})
+	}
+	if got := tx.Children(1); got != nil {
+		t.Errorf("move: children: got %v, want %v", got, nil)
+	}
+	if err := tx.Move(4, 3); err == nil {
+		t.Errorf("move: expecting error when moving into a descendant")
+	}
+
+	if err := tx.Rename(2, "herpailurus  concolor"); err != nil {
+		t.Fatalf("rename: unexpected error: %v", err)
+	}
+	if got := tx.ByName("Herpailurus concolor"); !reflect.DeepEqual(got, []int64{2}) {
+		t.Errorf("rename: got %v, want %v", got, []int64{2})
+	}
+	if got := tx.ByName("Puma concolor"); got != nil {
+		t.Errorf("rename: old name: got %v, want %v", got, nil)
+	}
+
+	if err := tx.SetStatus(3, "Accepted"); err != nil {
+		t.Fatalf("status: unexpected error: %v", err)
+	}
+	if got := tx.Accepted(3).ID; got != 3 {
+		t.Errorf("status: accepted: got %d, want %d", got, 3)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	tx, err := taxonomy.Read(strings.NewReader(pumaTax))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := tx.Remove(1); err != nil {
+		t.Fatalf("remove: unexpected error: %v", err)
+	}
+	if got := tx.IDs(); !reflect.DeepEqual(got, []int64{4}) {
+		t.Errorf("remove: got %v, want %v", got, []int64{4})
+	}
+	if got := tx.ByName("Felis concolor"); got != nil {
+		t.Errorf("remove: name: got %v, want %v", got, nil)
+	}
+	if err := tx.Remove(1); err == nil {
+		t.Errorf("remove: expecting error on a removed taxon")
+	}
+}
+
+func TestRekey(t *testing.T) {
+	tx, err := taxonomy.Read(strings.NewReader(pumaTax))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := tx.Rekey(2, 20); err != nil {
+		t.Fatalf("rekey: unexpected error: %v", err)
+	}
+	if got := tx.Children(1); !reflect.DeepEqual(got, []int64{20}) {
+		t.Errorf("rekey: children: got %v, want %v", got, []int64{20})
+	}
+	if got := tx.ByName("Puma concolor"); !reflect.DeepEqual(got, []int64{20}) {
+		t.Errorf("rekey: name: got %v, want %v", got, []int64{20})
+	}
+	if got := tx.Accepted(3).ID; got != 20 {
+		t.Errorf("rekey: accepted: got %d, want %d", got, 20)
+	}
+
+	// merge
+	if err := tx.Rekey(1, 4); err != nil {
+		t.Fatalf("merge: unexpected error: %v", err)
+	}
+	if got := tx.IDs(); !reflect.DeepEqual(got, []int64{3, 4, 20}) {
+		t.Errorf("merge: got %v, want %v", got, []int64{3, 4, 20})
+	}
+	if got := tx.Children(4); !reflect.DeepEqual(got, []int64{20}) {
+		t.Errorf("merge: children: got %v, want %v", got, []int64{20})
+	}
+	if err := tx.Rekey(4, 3); err == nil {
+		t.Errorf("merge: expecting error when merging into a descendant")
+	}
+}
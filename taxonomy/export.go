@@ -0,0 +1,254 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package taxonomy
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/js-arias/gbifer/tsv"
+)
+
+// WriteCSV writes a taxonomy as a comma separated table
+// (RFC 4180),
+// using the same columns of the TSV format.
+func (tx *Taxonomy) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.UseCRLF = true
+
+	header := append(slices.Clip(taxonCols), lineageCols...)
+	if err := out.Write(header); err != nil {
+		return fmt.Errorf("when writing taxonomy: %v", err)
+	}
+	for _, tax := range tx.root {
+		if err := tax.walk(func(t *taxon) error {
+			return out.Write(append(t.record(), tx.getLineage(t).keys.record()...))
+		}); err != nil {
+			return fmt.Errorf("when writing taxonomy: %v", err)
+		}
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing taxonomy: %v", err)
+	}
+	return nil
+}
+
+// checklistCols are the columns
+// of a Darwin Core taxon table.
+var checklistCols = []string{
+	"taxonID",
+	"parentNameUsageID",
+	"acceptedNameUsageID",
+	"scientificName",
+	"scientificNameAuthorship",
+	"taxonRank",
+	"taxonomicStatus",
+}
+
+// WriteChecklist writes a taxonomy
+// as a Darwin Core taxon table
+// (tab delimited),
+// that can be used as the core
+// of a checklist Darwin Core Archive.
+func (tx *Taxonomy) WriteChecklist(w io.Writer) error {
+	out := tsv.NewWriter(w)
+	out.Comma = '\t'
+	out.UseCRLF = true
+
+	if err := out.Write(checklistCols); err != nil {
+		return fmt.Errorf("when writing taxonomy: %v", err)
+	}
+	for _, tax := range tx.root {
+		if err := tax.walk(func(t *taxon) error {
+			id := strconv.FormatInt(t.data.ID, 10)
+			var parent, accepted string
+			if t.data.Status == "accepted" {
+				accepted = id
+				if t.data.Parent != 0 {
+					parent = strconv.FormatInt(t.data.Parent, 10)
+				}
+			} else if t.data.Parent != 0 {
+				accepted = strconv.FormatInt(t.data.Parent, 10)
+			}
+			name := t.data.Name
+			author := t.data.Authorship()
+			if author != "" {
+				name += " " + author
+			}
+			row := []string{
+				id,
+				parent,
+				accepted,
+				name,
+				author,
+				t.data.Rank.String(),
+				t.data.Status,
+			}
+			return out.Write(row)
+		}); err != nil {
+			return fmt.Errorf("when writing taxonomy: %v", err)
+		}
+	}
+
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return fmt.Errorf("when writing taxonomy: %v", err)
+	}
+	return nil
+}
+
+type jsonTaxon struct {
+	Name     string       `json:"name"`
+	Author   string       `json:"author,omitempty"`
+	Year     int          `json:"year,omitempty"`
+	ID       int64        `json:"taxonKey"`
+	Rank     string       `json:"rank"`
+	Status   string       `json:"status"`
+	Children []*jsonTaxon `json:"children,omitempty"`
+}
+
+func (tax *taxon) toJSON() *jsonTaxon {
+	jt := &jsonTaxon{
+		Name:   tax.data.Name,
+		Author: tax.data.Author,
+		Year:   tax.data.Year,
+		ID:     tax.data.ID,
+		Rank:   tax.data.Rank.String(),
+		Status: tax.data.Status,
+	}
+	for _, c := range tax.children {
+		jt.Children = append(jt.Children, c.toJSON())
+	}
+	return jt
+}
+
+// WriteJSON writes a taxonomy
+// as a JSON array of nested taxa.
+func (tx *Taxonomy) WriteJSON(w io.Writer) error {
+	ls := make([]*jsonTaxon, 0, len(tx.root))
+	for _, tax := range tx.root {
+		ls = append(ls, tax.toJSON())
+	}
+
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	if err := e.Encode(ls); err != nil {
+		return fmt.Errorf("when writing taxonomy: %v", err)
+	}
+	return nil
+}
+
+// WriteNewick writes the accepted taxa of a taxonomy
+// as a tree in Newick format.
+// Synonyms are ignored.
+// Taxon names are used as node labels.
+// If the taxonomy has multiple root taxa,
+// they will be joined in a single polytomy.
+func (tx *Taxonomy) WriteNewick(w io.Writer) error {
+	var roots []*taxon
+	for _, tax := range tx.root {
+		if tax.data.Status != "accepted" {
+			continue
+		}
+		roots = append(roots, tax)
+	}
+
+	bw := bufio.NewWriter(w)
+	if len(roots) == 1 {
+		roots[0].newick(bw)
+	} else {
+		bw.WriteString("(")
+		for i, tax := range roots {
+			if i > 0 {
+				bw.WriteString(",")
+			}
+			tax.newick(bw)
+		}
+		bw.WriteString(")")
+	}
+	bw.WriteString(";\n")
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("when writing taxonomy: %v", err)
+	}
+	return nil
+}
+
+func (tax *taxon) newick(w *bufio.Writer) {
+	var children []*taxon
+	for _, c := range tax.children {
+		if c.data.Status != "accepted" {
+			continue
+		}
+		children = append(children, c)
+	}
+
+	if len(children) > 0 {
+		w.WriteString("(")
+		for i, c := range children {
+			if i > 0 {
+				w.WriteString(",")
+			}
+			c.newick(w)
+		}
+		w.WriteString(")")
+	}
+	w.WriteString(newickLabel(tax.data.Name))
+}
+
+// newickLabel returns a taxon name
+// as a valid Newick label.
+func newickLabel(name string) string {
+	if strings.ContainsAny(name, "()[]':;,_") {
+		return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+	}
+	return strings.ReplaceAll(name, " ", "_")
+}
+
+// walk calls fn on the taxon
+// and all of its descendants,
+// in pre-order.
+func (tax *taxon) walk(fn func(*taxon) error) error {
+	if err := fn(tax); err != nil {
+		return err
+	}
+	for _, c := range tax.children {
+		if err := c.walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record returns a taxon as a row
+// of the TSV format,
+// without the lineage columns.
+func (tax *taxon) record() []string {
+	parent := ""
+	if tax.data.Parent != 0 {
+		parent = strconv.FormatInt(tax.data.Parent, 10)
+	}
+	year := ""
+	if tax.data.Year != 0 {
+		year = strconv.Itoa(tax.data.Year)
+	}
+	return []string{
+		tax.data.Name,
+		tax.data.Author,
+		year,
+		strconv.FormatInt(tax.data.ID, 10),
+		tax.data.Rank.String(),
+		tax.data.Status,
+		parent,
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package taxonomy_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/gbifer/taxonomy"
+)
+
+func TestWriteNewick(t *testing.T) {
+	tests := map[string]struct {
+		input  string
+		output string
+	}{
+		"single root": {
+			input: "Puma\t\t1\tgenus\taccepted\t\n" +
+				"Puma concolor\t\t2\tspecies\taccepted\t1\n" +
+				"Felis concolor\t\t3\tspecies\tsynonym\t2\n" +
+				"Puma yagouaroundi\t\t4\tspecies\taccepted\t1\n",
+			output: "(Puma_concolor,Puma_yagouaroundi)Puma;\n",
+		},
+		"multiple roots": {
+			input: "Puma\t\t1\tgenus\taccepted\t\n" +
+				"Herpailurus\t\t5\tgenus\taccepted\t\n",
+			output: "(Herpailurus,Puma);\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tx, err := taxonomy.Read(strings.NewReader(taxHeader + test.input))
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			var buf bytes.Buffer
+			if err := tx.WriteNewick(&buf); err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			if got := buf.String(); got != test.output {
+				t.Errorf("%s: got %q, want %q", name, got, test.output)
+			}
+		})
+	}
+}
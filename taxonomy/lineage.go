@@ -0,0 +1,169 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package taxonomy
+
+import "strconv"
+
+// A Lineage stores the IDs of the accepted taxa
+// of each rank
+// in the ancestry of a taxon
+// (the taxon included),
+// as the kingdomKey to speciesKey fields
+// of GBIF.
+// The lineage is indexed by rank,
+// so the genus of a taxon is
+//
+//	l[taxonomy.Genus]
+//
+// A value of 0 means that there is no taxon
+// of that rank in the ancestry.
+type Lineage [Species + 1]int64
+
+// lineageCols are the columns
+// of the lineage of a taxon
+// in a written taxonomy file.
+var lineageCols = []string{
+	"kingdomKey",
+	"phylumKey",
+	"classKey",
+	"orderKey",
+	"familyKey",
+	"genusKey",
+	"speciesKey",
+}
+
+// record returns a lineage as a row.
+func (l Lineage) record() []string {
+	row := make([]string, 0, len(lineageCols))
+	for _, id := range l[Kingdom:] {
+		if id == 0 {
+			row = append(row, "")
+			continue
+		}
+		row = append(row, strconv.FormatInt(id, 10))
+	}
+	return row
+}
+
+// lineage is the cached ancestry of a taxon.
+type lineage struct {
+	accepted int64   // ID of the accepted taxon
+	ranked   int64   // ID of the accepted and ranked taxon
+	rank     Rank    // first defined rank
+	keys     Lineage // IDs of the accepted taxa of each rank
+	parents  []int64 // IDs of the ancestors
+}
+
+// Lineage returns the lineage of a taxon.
+func (tx *Taxonomy) Lineage(id int64) Lineage {
+	tax, ok := tx.ids[id]
+	if !ok {
+		return Lineage{}
+	}
+	if tax.lin != nil {
+		return tax.lin.keys
+	}
+	return tx.walkLineage(tax).keys
+}
+
+// cacheLineages stores the lineage of each taxon
+// in the taxonomy,
+// so the ancestry of a taxon
+// can be resolved without walking the tree.
+// It must be called each time
+// the taxonomy tree is modified.
+func (tx *Taxonomy) cacheLineages() {
+	for _, tax := range tx.root {
+		// the parent of a root taxon,
+		// if any,
+		// is unknown or part of a cycle
+		tax.cacheLineage(tx.walkLineage(tax))
+	}
+}
+
+func (tax *taxon) cacheLineage(lin *lineage) {
+	tax.lin = lin
+	for _, c := range tax.children {
+		c.cacheLineage(lin.child(c))
+	}
+}
+
+// child returns the lineage
+// of a child of a taxon
+// with the given lineage.
+func (p *lineage) child(tax *taxon) *lineage {
+	lin := &lineage{
+		accepted: p.accepted,
+		ranked:   p.ranked,
+		rank:     p.rank,
+		keys:     p.keys,
+		parents:  make([]int64, 0, len(p.parents)+1),
+	}
+	lin.parents = append(lin.parents, tax.data.Parent)
+	lin.parents = append(lin.parents, p.parents...)
+
+	if tax.data.Rank != Unranked {
+		lin.rank = tax.data.Rank
+	}
+	if tax.data.Status == "accepted" {
+		lin.accepted = tax.data.ID
+		if tax.data.Rank != Unranked {
+			lin.ranked = tax.data.ID
+			lin.keys[tax.data.Rank] = tax.data.ID
+		}
+	}
+	return lin
+}
+
+// walkLineage returns the lineage of a taxon
+// walking over its ancestors.
+func (tx *Taxonomy) walkLineage(tax *taxon) *lineage {
+	lin := &lineage{rank: tax.data.Rank}
+
+	// a taxonomy with parent cycles
+	// can not be walked indefinitely
+	visited := map[int64]bool{tax.data.ID: true}
+	for t := tax; ; {
+		if t.data.Status == "accepted" {
+			if lin.accepted == 0 {
+				lin.accepted = t.data.ID
+			}
+			if r := t.data.Rank; r != Unranked {
+				if lin.ranked == 0 {
+					lin.ranked = t.data.ID
+				}
+				if lin.keys[r] == 0 {
+					lin.keys[r] = t.data.ID
+				}
+			}
+		}
+		if lin.rank == Unranked {
+			lin.rank = t.data.Rank
+		}
+
+		p := t.data.Parent
+		if p == 0 || visited[p] {
+			break
+		}
+		visited[p] = true
+		var ok bool
+		t, ok = tx.ids[p]
+		if !ok {
+			break
+		}
+		lin.parents = append(lin.parents, p)
+	}
+	return lin
+}
+
+// getLineage returns the lineage of a taxon,
+// using the cached lineage,
+// if available.
+func (tx *Taxonomy) getLineage(tax *taxon) *lineage {
+	if tax.lin != nil {
+		return tax.lin
+	}
+	return tx.walkLineage(tax)
+}
@@ -0,0 +1,65 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package taxonomy_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/js-arias/gbifer/taxonomy"
+)
+
+func TestLineage(t *testing.T) {
+	tx, err := taxonomy.Read(strings.NewReader(pumaTax))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := map[string]struct {
+		id    int64
+		genus int64
+		sp    int64
+	}{
+		"genus":   {id: 1, genus: 1},
+		"species": {id: 2, genus: 1, sp: 2},
+		"synonym": {id: 3, genus: 1, sp: 2},
+		"missing": {id: 10},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			l := tx.Lineage(test.id)
+			if l[taxonomy.Genus] != test.genus {
+				t.Errorf("genus: got %d, want %d", l[taxonomy.Genus], test.genus)
+			}
+			if l[taxonomy.Species] != test.sp {
+				t.Errorf("species: got %d, want %d", l[taxonomy.Species], test.sp)
+			}
+			if l[taxonomy.Kingdom] != 0 {
+				t.Errorf("kingdom: got %d, want %d", l[taxonomy.Kingdom], 0)
+			}
+		})
+	}
+
+	// lineages are updated after edits
+	if err := tx.Move(2, 4); err != nil {
+		t.Fatalf("move: unexpected error: %v", err)
+	}
+	if got := tx.Lineage(3)[taxonomy.Genus]; got != 4 {
+		t.Errorf("move: genus: got %d, want %d", got, 4)
+	}
+	if ps := tx.Parents(3); len(ps) != 2 || ps[1].ID != 4 {
+		t.Errorf("move: parents: got %v, want parents 2 and 4", ps)
+	}
+	if err := tx.SetStatus(2, "synonym"); err != nil {
+		t.Fatalf("status: unexpected error: %v", err)
+	}
+	if got := tx.Accepted(3).ID; got != 4 {
+		t.Errorf("status: accepted: got %d, want %d", got, 4)
+	}
+	if got := tx.Lineage(3)[taxonomy.Species]; got != 0 {
+		t.Errorf("status: species: got %d, want %d", got, 0)
+	}
+}
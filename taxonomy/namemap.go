@@ -0,0 +1,175 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package taxonomy
+
+import (
+	"slices"
+	"strings"
+)
+
+// Types of name matches.
+const (
+	MatchExact     = "exact"
+	MatchSynonym   = "synonym"
+	MatchFuzzy     = "fuzzy"
+	MatchAmbiguous = "ambiguous"
+	MatchNone      = "unmatched"
+)
+
+// A NameMatch is the result of matching
+// an external name with the taxonomy.
+type NameMatch struct {
+	Name     string // the external name
+	Matched  string // the name matched in the taxonomy
+	ID       int64  // ID of the matched taxon
+	Accepted Taxon  // the accepted taxon
+	Type     string // the type of the match
+}
+
+// MatchName matches an external name
+// (for example,
+// a terminal of a phylogeny)
+// with the names in the taxonomy.
+// Underscores in the name are read as spaces.
+//
+// If the name is the name of an accepted taxon,
+// it is an exact match.
+// If the name is a name of a non-accepted taxon,
+// it is a synonym match,
+// and the accepted taxon will be the accepted taxon of the synonym.
+// If there is no such name,
+// and maxDist is greater than 0,
+// the nearest name with an edit distance
+// up to maxDist will be used as a fuzzy match.
+// If the name resolves to more than one accepted taxon,
+// the match will be ambiguous.
+func (tx *Taxonomy) MatchName(name string, maxDist int) NameMatch {
+	m := NameMatch{Name: name, Type: MatchNone}
+	name = Canon(strings.ReplaceAll(name, "_", " "))
+	if name == "" {
+		return m
+	}
+
+	if ids := tx.names[name]; len(ids) > 0 {
+		return tx.resolveMatch(m, name, ids, false)
+	}
+	if maxDist <= 0 {
+		return m
+	}
+
+	best := maxDist
+	var names []string
+	for n := range tx.names {
+		if abs(len(n)-len(name)) > best {
+			continue
+		}
+		d := editDistance(name, n)
+		if d > best {
+			continue
+		}
+		if d < best {
+			best = d
+			names = names[:0]
+		}
+		names = append(names, n)
+	}
+	if len(names) == 0 {
+		return m
+	}
+	slices.Sort(names)
+
+	var ids []int64
+	for _, n := range names {
+		ids = append(ids, tx.names[n]...)
+	}
+	return tx.resolveMatch(m, strings.Join(names, "|"), ids, true)
+}
+
+// resolveMatch sets the accepted taxon
+// of a match with the given taxon IDs.
+func (tx *Taxonomy) resolveMatch(m NameMatch, matched string, ids []int64, fuzzy bool) NameMatch {
+	m.Matched = matched
+
+	// accepted names take precedence
+	// over synonyms
+	var acc []int64
+	for _, id := range ids {
+		if tx.ids[id].data.Status == "accepted" {
+			acc = append(acc, id)
+		}
+	}
+	syn := len(acc) == 0
+	if syn {
+		for _, id := range ids {
+			a := tx.Accepted(id).ID
+			if a == 0 || slices.Contains(acc, a) {
+				continue
+			}
+			acc = append(acc, a)
+		}
+	}
+
+	switch {
+	case len(acc) == 0:
+		// synonyms without an accepted taxon
+		m.Type = MatchNone
+		return m
+	case len(acc) > 1:
+		m.Type = MatchAmbiguous
+		return m
+	}
+
+	m.Accepted = tx.Taxon(acc[0])
+	m.ID = acc[0]
+	if syn {
+		for _, id := range ids {
+			if tx.Accepted(id).ID == acc[0] {
+				m.ID = id
+				break
+			}
+		}
+	}
+	m.Matched = tx.Taxon(m.ID).Name
+
+	switch {
+	case fuzzy:
+		m.Type = MatchFuzzy
+	case syn:
+		m.Type = MatchSynonym
+	default:
+		m.Type = MatchExact
+	}
+	return m
+}
+
+// editDistance returns the Levenshtein distance
+// between two strings.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,58 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package taxonomy_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/js-arias/gbifer/taxonomy"
+)
+
+func TestMatchName(t *testing.T) {
+	tx, err := taxonomy.Read(strings.NewReader(pumaTax +
+		"Puma yagouaroundi\t\t5\tspecies\taccepted\t1\n" +
+		"Felis yagouaroundi\t\t6\tspecies\tsynonym\t5\n" +
+		"Puma discolor\t\t7\tspecies\taccepted\t1\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := map[string]struct {
+		name     string
+		dist     int
+		tp       string
+		id       int64
+		accepted int64
+	}{
+		"exact":           {name: "Puma concolor", tp: taxonomy.MatchExact, id: 2, accepted: 2},
+		"underscore":      {name: "Puma_concolor", tp: taxonomy.MatchExact, id: 2, accepted: 2},
+		"synonym":         {name: "felis concolor", tp: taxonomy.MatchSynonym, id: 3, accepted: 2},
+		"fuzzy":           {name: "Puma concolr", dist: 2, tp: taxonomy.MatchFuzzy, id: 2, accepted: 2},
+		"fuzzy synonym":   {name: "Felis concolar", dist: 2, tp: taxonomy.MatchFuzzy, id: 3, accepted: 2},
+		"too far":         {name: "Puma concolr", dist: 0, tp: taxonomy.MatchNone},
+		"fuzzy ambiguous": {name: "Puma color", dist: 3, tp: taxonomy.MatchAmbiguous},
+		"unmatched":       {name: "Lynx lynx", dist: 2, tp: taxonomy.MatchNone},
+		"empty":           {name: "  ", tp: taxonomy.MatchNone},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := tx.MatchName(test.name, test.dist)
+			if m.Name != test.name {
+				t.Errorf("name: got %q, want %q", m.Name, test.name)
+			}
+			if m.Type != test.tp {
+				t.Errorf("type: got %q, want %q", m.Type, test.tp)
+			}
+			if m.ID != test.id {
+				t.Errorf("ID: got %d, want %d", m.ID, test.id)
+			}
+			if m.Accepted.ID != test.accepted {
+				t.Errorf("accepted: got %d, want %d", m.Accepted.ID, test.accepted)
+			}
+		})
+	}
+}
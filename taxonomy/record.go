@@ -0,0 +1,88 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package taxonomy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Record starts the recording
+// of the taxa added with AddSpecies,
+// so they can be stored
+// (for example, in a journal)
+// and added again to a taxonomy
+// with Restore.
+func (tx *Taxonomy) Record() {
+	tx.recording = true
+}
+
+// Recorded returns the taxa added
+// since the last call to Recorded,
+// as a list of values,
+// in which each taxon is stored
+// as the fields of a taxonomy file
+// (without the lineage columns).
+func (tx *Taxonomy) Recorded() []string {
+	values := make([]string, 0, len(tx.recorded)*len(taxonCols))
+	for _, tax := range tx.recorded {
+		values = append(values, tax.record()...)
+	}
+	tx.recorded = tx.recorded[:0]
+	return values
+}
+
+// Restore adds the taxa in a list of values
+// returned by Recorded
+// to the temporal space of the taxonomy.
+// Taxa already in the taxonomy are ignored.
+// It returns the IDs of the taxa in the list.
+func (tx *Taxonomy) Restore(values []string) ([]int64, error) {
+	if len(values)%len(taxonCols) != 0 {
+		return nil, fmt.Errorf("invalid number of values: %d", len(values))
+	}
+
+	ids := make([]int64, 0, len(values)/len(taxonCols))
+	for i := 0; i < len(values); i += len(taxonCols) {
+		row := values[i : i+len(taxonCols)]
+		id, err := strconv.ParseInt(row[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("taxon %q: %q: %v", row[0], "taxonKey", err)
+		}
+		ids = append(ids, id)
+		if _, ok := tx.ids[id]; ok {
+			continue
+		}
+		var year int
+		if row[2] != "" {
+			year, err = strconv.Atoi(row[2])
+			if err != nil {
+				return nil, fmt.Errorf("taxon %q: %q: %v", row[0], "year", err)
+			}
+		}
+		var parent int64
+		if row[6] != "" {
+			parent, err = strconv.ParseInt(row[6], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("taxon %q: %q: %v", row[0], "parent", err)
+			}
+		}
+		data := Taxon{
+			Name:   Canon(row[0]),
+			Author: row[1],
+			Year:   year,
+			ID:     id,
+			Rank:   GetRank(row[4]),
+			Status: strings.ToLower(row[5]),
+			Parent: parent,
+		}
+		tax := &taxon{data: data}
+		tx.tmp = append(tx.tmp, tax)
+		tx.ids[id] = tax
+		tx.names[data.Name] = append(tx.names[data.Name], id)
+	}
+	return ids, nil
+}
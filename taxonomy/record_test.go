@@ -0,0 +1,67 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package taxonomy_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/js-arias/gbifer/gbif"
+	"github.com/js-arias/gbifer/taxonomy"
+)
+
+func TestRecord(t *testing.T) {
+	tx := taxonomy.NewTaxonomy()
+	tx.AddSpecies(&gbif.Species{NubKey: 10, CanonicalName: "Felidae", Rank: "FAMILY", TaxonomicStatus: "ACCEPTED"})
+	tx.Record()
+	tx.AddSpecies(&gbif.Species{NubKey: 1, CanonicalName: "Puma", Authorship: "Jardine, 1834", Rank: "GENUS", TaxonomicStatus: "ACCEPTED", ParentKey: 10})
+	tx.AddSpecies(&gbif.Species{NubKey: 2, CanonicalName: "Puma concolor", Rank: "SPECIES", TaxonomicStatus: "ACCEPTED", ParentKey: 1})
+	first := tx.Recorded()
+	tx.AddSpecies(&gbif.Species{NubKey: 3, CanonicalName: "Felis concolor", Rank: "SPECIES", TaxonomicStatus: "SYNONYM", AcceptedKey: 2})
+	second := tx.Recorded()
+	if got := tx.Recorded(); len(got) != 0 {
+		t.Errorf("recorded: got %v, want no values", got)
+	}
+
+	rt := taxonomy.NewTaxonomy()
+	rt.AddSpecies(&gbif.Species{NubKey: 10, CanonicalName: "Felidae", Rank: "FAMILY", TaxonomicStatus: "ACCEPTED"})
+	ids, err := rt.Restore(first)
+	if err != nil {
+		t.Fatalf("restore: unexpected error: %v", err)
+	}
+	if want := []int64{1, 2}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("restore: got %v, want %v", ids, want)
+	}
+	ids, err = rt.Restore(second)
+	if err != nil {
+		t.Fatalf("restore: unexpected error: %v", err)
+	}
+	if want := []int64{3}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("restore: got %v, want %v", ids, want)
+	}
+
+	// restoring a taxon twice is ignored
+	if _, err := rt.Restore(first); err != nil {
+		t.Fatalf("restore: unexpected error: %v", err)
+	}
+	rt.Stage()
+	tx.Stage()
+
+	if got, want := rt.IDs(), []int64{1, 2, 3, 10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("restore: got %v, want %v", got, want)
+	}
+	for _, id := range tx.IDs() {
+		if got, want := rt.Taxon(id), tx.Taxon(id); got != want {
+			t.Errorf("taxon %d: got %v, want %v", id, got, want)
+		}
+	}
+	if got := rt.Accepted(3).ID; got != 2 {
+		t.Errorf("accepted: got %d, want %d", got, 2)
+	}
+
+	if _, err := rt.Restore(first[:3]); err == nil {
+		t.Errorf("restore: expecting error on truncated values")
+	}
+}
@@ -78,16 +78,87 @@ func (r Rank) String() string {
 // A Taxon stores the taxon information.
 type Taxon struct {
 	Name   string // taxon name
-	Author string // author of the name
+	Author string // author of the name, without the year
+	Year   int    // year of the name, 0 if unknown
 	ID     int64  // ID of the taxon
 	Rank   Rank   // taxon rank
 	Status string // taxon status
 	Parent int64  // ID of the parent taxon
 }
 
+// Authorship returns the full authorship of a taxon name,
+// i.e., the author and the year,
+// as used in zoological names,
+// for example "(Linnaeus, 1758)".
+func (t Taxon) Authorship() string {
+	if t.Year == 0 {
+		return t.Author
+	}
+	yr := strconv.Itoa(t.Year)
+	if t.Author == "" {
+		return yr
+	}
+	if inner, ok := parenthesized(t.Author); ok {
+		return "(" + inner + ", " + yr + ")"
+	}
+	return t.Author + ", " + yr
+}
+
+// ParseAuthorship splits an authorship string
+// into the author and the year of the name.
+// If the authorship is in parenthesis
+// (i.e., a name in a new combination),
+// the parenthesis are kept in the author,
+// so "(Linnaeus, 1758)" returns "(Linnaeus)" and 1758.
+// If the authorship does not end with a year,
+// it returns the authorship
+// and 0.
+func ParseAuthorship(s string) (author string, year int) {
+	s = strings.Join(strings.Fields(s), " ")
+	body, paren := parenthesized(s)
+	if !paren {
+		body = s
+	}
+	if strings.ContainsAny(body, "()") {
+		return s, 0
+	}
+
+	i := strings.LastIndexAny(body, ", ")
+	yr := body[i+1:]
+	if len(yr) != 4 {
+		return s, 0
+	}
+	year, err := strconv.Atoi(yr)
+	if err != nil || year <= 0 {
+		return s, 0
+	}
+
+	author = strings.TrimRight(body[:max(i, 0)], ", ")
+	if paren && author != "" {
+		author = "(" + author + ")"
+	}
+	return author, year
+}
+
+// parenthesized returns the content of a string
+// enclosed in a single pair of parenthesis.
+func parenthesized(s string) (string, bool) {
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+		return "", false
+	}
+	inner := s[1 : len(s)-1]
+	if strings.ContainsAny(inner, "()") {
+		return "", false
+	}
+	return inner, true
+}
+
 type taxon struct {
 	data     Taxon
 	children []*taxon
+	row      int // row in the source file
+
+	lin *lineage // cached lineage
 }
 
 // A Taxonomy stores taxon IDs
@@ -96,6 +167,9 @@ type Taxonomy struct {
 	root  []*taxon           // list parent-less of taxa
 	tmp   []*taxon           // temporal list of taxons
 	names map[string][]int64 // map of taxon names to IDs
+
+	recording bool     // record added taxa
+	recorded  []*taxon // taxa added since the last Recorded call
 }
 
 // NewTaxonomy creates a new empty taxonomy.
@@ -106,6 +180,8 @@ func NewTaxonomy() *Taxonomy {
 	}
 }
 
+// headerCols are the required columns
+// of a taxonomy file.
 var headerCols = []string{
 	"name",
 	"author",
@@ -115,7 +191,26 @@ var headerCols = []string{
 	"parent",
 }
 
+// taxonCols are the columns
+// of a written taxonomy file.
+// The year column is optional,
+// in older files,
+// the year is part of the author.
+var taxonCols = []string{
+	"name",
+	"author",
+	"year",
+	"taxonKey",
+	"rank",
+	"status",
+	"parent",
+}
+
 // Read reads a taxonomy from a TSV-encoded file.
+// The taxa are linked to their parents
+// after all the rows are read,
+// so a taxon can be listed
+// before its parent.
 func Read(r io.Reader) (*Taxonomy, error) {
 	tab := tsv.NewReader(r)
 	tab.Comma = '\t'
@@ -167,15 +262,29 @@ func Read(r io.Reader) (*Taxonomy, error) {
 			}
 		}
 
+		author := strings.Join(strings.Fields(row[fields["author"]]), " ")
+		var year int
+		if i, ok := fields["year"]; ok {
+			if y := strings.TrimSpace(row[i]); y != "" {
+				year, err = strconv.Atoi(y)
+				if err != nil {
+					return nil, fmt.Errorf("taxonomy: row %d: %q: %v", ln, "year", err)
+				}
+			}
+		} else {
+			author, year = ParseAuthorship(author)
+		}
+
 		data := Taxon{
 			Name:   Canon(row[fields["name"]]),
-			Author: strings.Join(strings.Fields(row[fields["author"]]), " "),
+			Author: author,
+			Year:   year,
 			ID:     id,
 			Rank:   GetRank(row[fields["rank"]]),
 			Status: strings.ToLower(strings.TrimSpace(row[fields["status"]])),
 			Parent: parent,
 		}
-		tax := &taxon{data: data}
+		tax := &taxon{data: data, row: ln}
 		tx.tmp = append(tx.tmp, tax)
 		tx.ids[id] = tax
 		tx.names[data.Name] = append(tx.names[data.Name], id)
@@ -187,30 +296,20 @@ func Read(r io.Reader) (*Taxonomy, error) {
 
 // Accepted return the accepted taxon from a given ID.
 func (tx *Taxonomy) Accepted(id int64) Taxon {
-	for {
-		tax, ok := tx.ids[id]
-		if !ok {
-			return Taxon{}
-		}
-		if tax.data.Status == "accepted" {
-			return tax.data
-		}
-		id = tax.data.Parent
+	tax, ok := tx.ids[id]
+	if !ok {
+		return Taxon{}
 	}
+	return tx.Taxon(tx.getLineage(tax).accepted)
 }
 
 // AcceptedAndRanked return the accepted and ranked taxon from a given ID.
 func (tx *Taxonomy) AcceptedAndRanked(id int64) Taxon {
-	for {
-		tax, ok := tx.ids[id]
-		if !ok {
-			return Taxon{}
-		}
-		if tax.data.Status == "accepted" && tax.data.Rank != Unranked {
-			return tax.data
-		}
-		id = tax.data.Parent
+	tax, ok := tx.ids[id]
+	if !ok {
+		return Taxon{}
 	}
+	return tx.Taxon(tx.getLineage(tax).ranked)
 }
 
 // AddFromGBIF add a taxon from a GBIF ID,
@@ -365,9 +464,11 @@ func (tx *Taxonomy) AddSpecies(sp *gbif.Species) {
 		return
 	}
 
+	author, year := ParseAuthorship(sp.Authorship)
 	data := Taxon{
 		Name:   sp.CanonicalName,
-		Author: sp.Authorship,
+		Author: author,
+		Year:   year,
 		ID:     sp.NubKey,
 		Rank:   GetRank(sp.Rank),
 		Status: strings.ToLower(sp.TaxonomicStatus),
@@ -404,6 +505,9 @@ func (tx *Taxonomy) AddSpecies(sp *gbif.Species) {
 	tx.tmp = append(tx.tmp, tax)
 	tx.ids[data.ID] = tax
 	tx.names[data.Name] = append(tx.names[data.Name], data.ID)
+	if tx.recording {
+		tx.recorded = append(tx.recorded, tax)
+	}
 }
 
 // ByName returns the IDs of all the taxons with a given name.
@@ -428,6 +532,26 @@ func (tx *Taxonomy) ByName(name string) []int64 {
 	return v
 }
 
+// Children returns the IDs of the children
+// of a given taxon.
+// Accepted taxa are listed first,
+// then taxa are sorted by name.
+func (tx *Taxonomy) Children(id int64) []int64 {
+	tax, ok := tx.ids[id]
+	if !ok {
+		return nil
+	}
+	if len(tax.children) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(tax.children))
+	for _, c := range tax.children {
+		ids = append(ids, c.data.ID)
+	}
+	return ids
+}
+
 // IDs return the ID of all taxons in the taxonomy.
 func (tx *Taxonomy) IDs() []int64 {
 	ids := make([]int64, 0, len(tx.ids))
@@ -479,41 +603,132 @@ func (tax *taxon) minRank() Rank {
 	return minRank
 }
 
+// Parents returns the ancestors of a taxon,
+// from its parent up to the root.
+func (tx *Taxonomy) Parents(id int64) []Taxon {
+	tax, ok := tx.ids[id]
+	if !ok {
+		return nil
+	}
+
+	lin := tx.getLineage(tax)
+	if len(lin.parents) == 0 {
+		return nil
+	}
+	ls := make([]Taxon, 0, len(lin.parents))
+	for _, p := range lin.parents {
+		t, ok := tx.ids[p]
+		if !ok {
+			break
+		}
+		ls = append(ls, t.data)
+	}
+	return ls
+}
+
 // Rank returns the first defined rank
 // of a taxon,
 // or any of its parents.
 func (tx *Taxonomy) Rank(id int64) Rank {
-	for id != 0 {
-		tax, ok := tx.ids[id]
-		if !ok {
-			return Unranked
-		}
-		if tax.data.Rank != Unranked {
-			return tax.data.Rank
-		}
-		id = tax.data.Parent
+	tax, ok := tx.ids[id]
+	if !ok {
+		return Unranked
 	}
-	return Unranked
+	return tx.getLineage(tax).rank
+}
+
+// Roots returns the IDs of the taxa without parents,
+// sorted by name.
+func (tx *Taxonomy) Roots() []int64 {
+	ids := make([]int64, 0, len(tx.root))
+	for _, tax := range tx.root {
+		ids = append(ids, tax.data.ID)
+	}
+	return ids
 }
 
 // Stage add the taxa in the temporal space
-// to the taxonomy,
+// to the taxonomy.
+//
+// Taxa are linked to their parents
+// after all the taxa are added,
+// so the order in which the taxa were added
+// is irrelevant.
+// Taxa with an unknown parent,
+// or in a parent cycle,
+// are kept as roots
+// (see Validate).
 func (tx *Taxonomy) Stage() {
 	if tx.tmp == nil {
 		return
 	}
 
+	// roots with a parent
+	// that was unknown in a previous stage
+	var roots []*taxon
+	for _, tax := range tx.root {
+		if tax.data.Parent == 0 {
+			roots = append(roots, tax)
+			continue
+		}
+		tx.tmp = append(tx.tmp, tax)
+	}
+	tx.root = roots
+
 	for _, tax := range tx.tmp {
 		if tax.data.Parent == 0 {
 			tx.root = append(tx.root, tax)
 			continue
 		}
-		p := tx.ids[tax.data.Parent]
+		p, ok := tx.ids[tax.data.Parent]
+		if !ok || tx.inCycle(tax) {
+			tx.root = append(tx.root, tax)
+			continue
+		}
 		p.children = append(p.children, tax)
 	}
 	tx.tmp = nil
 
-	// sort
+	tx.sort()
+	tx.cacheLineages()
+}
+
+// inCycle returns true
+// if a taxon is its own ancestor.
+func (tx *Taxonomy) inCycle(tax *taxon) bool {
+	// Floyd's cycle detection
+	slow, fast := tax, tax
+	for {
+		fast = tx.ids[fast.data.Parent]
+		if fast == nil {
+			return false
+		}
+		fast = tx.ids[fast.data.Parent]
+		if fast == nil {
+			return false
+		}
+		slow = tx.ids[slow.data.Parent]
+		if slow == fast {
+			break
+		}
+	}
+
+	// check if the taxon is in the cycle
+	for c := slow; ; {
+		if c == tax {
+			return true
+		}
+		c = tx.ids[c.data.Parent]
+		if c == slow {
+			return false
+		}
+	}
+}
+
+// sort sorts the root taxa by name,
+// and the children of each taxon
+// by status (accepted first) and name.
+func (tx *Taxonomy) sort() {
 	slices.SortFunc(tx.root, func(a, b *taxon) int {
 		if c := cmp.Compare(a.data.Name, b.data.Name); c != 0 {
 			return c
@@ -548,17 +763,22 @@ func (tx *Taxonomy) Taxon(id int64) Taxon {
 }
 
 // Write writes a taxonomy into a TSV table.
+// The table includes the lineage of each taxon
+// (from kingdomKey to speciesKey).
+// As the lineage is defined by the parents,
+// these columns are ignored when reading the table.
 func (tx *Taxonomy) Write(w io.Writer) error {
 	// write data
 	out := tsv.NewWriter(w)
 	out.Comma = '\t'
 	out.UseCRLF = true
 
-	if err := out.Write(headerCols); err != nil {
+	header := append(slices.Clip(taxonCols), lineageCols...)
+	if err := out.Write(header); err != nil {
 		return fmt.Errorf("when writing taxonomy: %v", err)
 	}
 	for _, tax := range tx.root {
-		if err := tax.write(out); err != nil {
+		if err := tx.write(out, tax); err != nil {
 			return err
 		}
 	}
@@ -571,25 +791,14 @@ func (tx *Taxonomy) Write(w io.Writer) error {
 	return nil
 }
 
-func (tax *taxon) write(w *tsv.Writer) error {
-	parent := ""
-	if tax.data.Parent != 0 {
-		parent = strconv.FormatInt(tax.data.Parent, 10)
-	}
-	row := []string{
-		tax.data.Name,
-		tax.data.Author,
-		strconv.FormatInt(tax.data.ID, 10),
-		tax.data.Rank.String(),
-		tax.data.Status,
-		parent,
-	}
+func (tx *Taxonomy) write(w *tsv.Writer, tax *taxon) error {
+	row := append(tax.record(), tx.getLineage(tax).keys.record()...)
 	if err := w.Write(row); err != nil {
 		return fmt.Errorf("when writing taxonomy: %v", err)
 	}
 
 	for _, c := range tax.children {
-		if err := c.write(w); err != nil {
+		if err := tx.write(w, c); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,189 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package taxonomy_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/gbifer/taxonomy"
+)
+
+func TestParents(t *testing.T) {
+	tx, err := taxonomy.Read(strings.NewReader(pumaTax))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := map[string]struct {
+		id   int64
+		want []int64
+	}{
+		"root":    {id: 1},
+		"species": {id: 2, want: []int64{1}},
+		"synonym": {id: 3, want: []int64{2, 1}},
+		"missing": {id: 10},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ls := tx.Parents(test.id)
+			if len(ls) != len(test.want) {
+				t.Fatalf("got %d parents, want %d", len(ls), len(test.want))
+			}
+			for i, p := range ls {
+				if p.ID != test.want[i] {
+					t.Errorf("parent %d: got %d, want %d", i, p.ID, test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadOrder(t *testing.T) {
+	var want bytes.Buffer
+	tx, err := taxonomy.Read(strings.NewReader(pumaTax))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tx.Write(&want); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := map[string]string{
+		"children first": taxHeader +
+			"Felis concolor\t\t3\tspecies\tsynonym\t2\n" +
+			"Puma concolor\t\t2\tspecies\taccepted\t1\n" +
+			"Herpailurus\t\t4\tgenus\taccepted\t\n" +
+			"Puma\t\t1\tgenus\taccepted\t\n",
+		"synonym first": taxHeader +
+			"Felis concolor\t\t3\tspecies\tsynonym\t2\n" +
+			"Puma\t\t1\tgenus\taccepted\t\n" +
+			"Herpailurus\t\t4\tgenus\taccepted\t\n" +
+			"Puma concolor\t\t2\tspecies\taccepted\t1\n",
+	}
+
+	for name, in := range tests {
+		t.Run(name, func(t *testing.T) {
+			tx, err := taxonomy.Read(strings.NewReader(in))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if r := tx.Rank(3); r != taxonomy.Species {
+				t.Errorf("rank: got %v, want %v", r, taxonomy.Species)
+			}
+			if acc := tx.Accepted(3); acc.ID != 2 {
+				t.Errorf("accepted: got %d, want %d", acc.ID, 2)
+			}
+
+			var got bytes.Buffer
+			if err := tx.Write(&got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String() != want.String() {
+				t.Errorf("got %q, want %q", got.String(), want.String())
+			}
+		})
+	}
+}
+
+func TestReadCycle(t *testing.T) {
+	in := taxHeader +
+		"Puma concolor\t\t2\tspecies\tsynonym\t3\n" +
+		"Felis concolor\t\t3\tspecies\tsynonym\t2\n" +
+		"Puma concolor concolor\t\t5\tsubspecies\taccepted\t2\n"
+	tx, err := taxonomy.Read(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if acc := tx.Accepted(2); acc.ID != 0 {
+		t.Errorf("accepted: got %d, want %d", acc.ID, 0)
+	}
+
+	var buf bytes.Buffer
+	if err := tx.Write(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 4 {
+		t.Errorf("got %d rows, want %d:\n%s", n, 4, buf.String())
+	}
+}
+
+func TestParseAuthorship(t *testing.T) {
+	tests := map[string]struct {
+		in     string
+		author string
+		year   int
+		full   string // if different from in
+	}{
+		"author and year": {in: "Jardine, 1834", author: "Jardine", year: 1834},
+		"parenthesis":     {in: "(Linnaeus, 1771)", author: "(Linnaeus)", year: 1771},
+		"without comma":   {in: "Hodgson 1842", author: "Hodgson", year: 1842, full: "Hodgson, 1842"},
+		"several authors": {in: "Smith & Jones, 1901", author: "Smith & Jones", year: 1901},
+		"without year":    {in: "L.", author: "L."},
+		"botanical":       {in: "(L.) Mill.", author: "(L.) Mill."},
+		"only year":       {in: "1758", year: 1758},
+		"empty":           {},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			author, year := taxonomy.ParseAuthorship(test.in)
+			if author != test.author {
+				t.Errorf("author: got %q, want %q", author, test.author)
+			}
+			if year != test.year {
+				t.Errorf("year: got %d, want %d", year, test.year)
+			}
+
+			full := test.in
+			if test.full != "" {
+				full = test.full
+			}
+			tax := taxonomy.Taxon{Author: author, Year: year}
+			if got := tax.Authorship(); got != full {
+				t.Errorf("authorship: got %q, want %q", got, full)
+			}
+		})
+	}
+}
+
+func TestReadYear(t *testing.T) {
+	tests := map[string]string{
+		"year column": "name\tauthor\tyear\ttaxonKey\trank\tstatus\tparent\n" +
+			"Puma concolor\t(Linnaeus)\t1771\t2\tspecies\taccepted\t\n",
+		"author with year": taxHeader +
+			"Puma concolor\t(Linnaeus, 1771)\t2\tspecies\taccepted\t\n",
+	}
+
+	for name, in := range tests {
+		t.Run(name, func(t *testing.T) {
+			tx, err := taxonomy.Read(strings.NewReader(in))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tax := tx.Taxon(2)
+			if tax.Author != "(Linnaeus)" {
+				t.Errorf("author: got %q, want %q", tax.Author, "(Linnaeus)")
+			}
+			if tax.Year != 1771 {
+				t.Errorf("year: got %d, want %d", tax.Year, 1771)
+			}
+
+			var w bytes.Buffer
+			if err := tx.Write(&w); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := "name\tauthor\tyear\ttaxonKey\trank\tstatus\tparent\t" +
+				"kingdomKey\tphylumKey\tclassKey\torderKey\tfamilyKey\tgenusKey\tspeciesKey\r\n" +
+				"Puma concolor\t(Linnaeus)\t1771\t2\tspecies\taccepted\t\t\t\t\t\t\t\t2\r\n"
+			if w.String() != want {
+				t.Errorf("write: got %q, want %q", w.String(), want)
+			}
+		})
+	}
+}
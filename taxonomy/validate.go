@@ -0,0 +1,161 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package taxonomy
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// Kinds of problems found when validating a taxonomy.
+const (
+	BrokenParent    = "broken parent"
+	ParentCycle     = "parent cycle"
+	RankInversion   = "rank inversion"
+	DanglingSynonym = "dangling synonym"
+	DuplicatedName  = "duplicated name"
+)
+
+// A Problem is an inconsistency found in a taxonomy.
+type Problem struct {
+	ID   int64  // ID of the taxon
+	Name string // name of the taxon
+	Row  int    // row of the taxon in the source file (0 if unknown)
+	Kind string // kind of problem
+	Msg  string // a description of the problem
+}
+
+func (p Problem) String() string {
+	if p.Row > 0 {
+		return fmt.Sprintf("row %d: taxon %q [%d]: %s: %s", p.Row, p.Name, p.ID, p.Kind, p.Msg)
+	}
+	return fmt.Sprintf("taxon %q [%d]: %s: %s", p.Name, p.ID, p.Kind, p.Msg)
+}
+
+// Validate checks the consistency of the taxonomy
+// and returns the list of problems found,
+// sorted by row, and then by ID.
+//
+// The checks are:
+//   - broken parents: a parent ID not present in the taxonomy;
+//   - parent cycles: a taxon that is its own ancestor;
+//   - rank inversions: an accepted taxon
+//     with a rank equal or more inclusive than the rank of its parent;
+//   - dangling synonyms: a non accepted taxon
+//     without an accepted ancestor;
+//   - duplicated names: two or more accepted taxa with the same name.
+func (tx *Taxonomy) Validate() []Problem {
+	var ps []Problem
+	add := func(tax *taxon, kind, msg string) {
+		ps = append(ps, Problem{
+			ID:   tax.data.ID,
+			Name: tax.data.Name,
+			Row:  tax.row,
+			Kind: kind,
+			Msg:  msg,
+		})
+	}
+
+	for _, tax := range tx.ids {
+		p := tax.data.Parent
+		if p != 0 {
+			if _, ok := tx.ids[p]; !ok {
+				add(tax, BrokenParent, fmt.Sprintf("parent %d not in taxonomy", p))
+				continue
+			}
+		}
+
+		found, member := tx.cycle(tax.data.ID)
+		if member {
+			add(tax, ParentCycle, "taxon is its own ancestor")
+			continue
+		}
+
+		if tax.data.Status == "accepted" {
+			if p == 0 || tax.data.Rank == Unranked || found {
+				continue
+			}
+			pr := tx.Rank(p)
+			if pr != Unranked && pr >= tax.data.Rank {
+				add(tax, RankInversion, fmt.Sprintf("rank %s, parent %d with rank %s", tax.data.Rank, p, pr))
+			}
+			continue
+		}
+
+		if !tx.hasAccepted(tax.data.ID) {
+			add(tax, DanglingSynonym, fmt.Sprintf("status %q without an accepted taxon", tax.data.Status))
+		}
+	}
+
+	for name, ids := range tx.names {
+		var acc []int64
+		for _, id := range ids {
+			if tx.ids[id].data.Status == "accepted" {
+				acc = append(acc, id)
+			}
+		}
+		if len(acc) < 2 {
+			continue
+		}
+		slices.Sort(acc)
+		for _, id := range acc {
+			msg := fmt.Sprintf("%d accepted taxa with name %q", len(acc), name)
+			// the authorship helps to identify homonyms
+			if a := tx.ids[id].data.Authorship(); a != "" {
+				msg += fmt.Sprintf(", authorship %q", a)
+			}
+			add(tx.ids[id], DuplicatedName, msg)
+		}
+	}
+
+	slices.SortFunc(ps, func(a, b Problem) int {
+		if c := cmp.Compare(a.Row, b.Row); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.ID, b.ID); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Kind, b.Kind)
+	})
+	return ps
+}
+
+// cycle returns true if there is a cycle
+// in the chain of parents of a taxon,
+// and if the taxon is part of the cycle.
+func (tx *Taxonomy) cycle(id int64) (found, member bool) {
+	visited := make(map[int64]bool)
+	for c := id; c != 0; {
+		if visited[c] {
+			return true, c == id
+		}
+		visited[c] = true
+		tax, ok := tx.ids[c]
+		if !ok {
+			return false, false
+		}
+		c = tax.data.Parent
+	}
+	return false, false
+}
+
+// hasAccepted returns true if a taxon
+// has an accepted taxon in its chain of parents.
+func (tx *Taxonomy) hasAccepted(id int64) bool {
+	visited := make(map[int64]bool)
+	for c := id; c != 0 && !visited[c]; {
+		visited[c] = true
+		tax, ok := tx.ids[c]
+		if !ok {
+			return false
+		}
+		if tax.data.Status == "accepted" {
+			return true
+		}
+		c = tax.data.Parent
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package taxonomy_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/js-arias/gbifer/taxonomy"
+)
+
+const taxHeader = "name\tauthor\ttaxonKey\trank\tstatus\tparent\n"
+
+func TestValidate(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		kinds []string
+	}{
+		"valid": {
+			input: "Puma\t\t1\tgenus\taccepted\t\n" +
+				"Puma concolor\t\t2\tspecies\taccepted\t1\n" +
+				"Felis concolor\t\t3\tspecies\tsynonym\t2\n",
+		},
+		"broken parent": {
+			input: "Puma\t\t1\tgenus\taccepted\t\n" +
+				"Puma concolor\t\t2\tspecies\taccepted\t10\n",
+			kinds: []string{taxonomy.BrokenParent},
+		},
+		"rank inversion": {
+			input: "Puma concolor\t\t2\tspecies\taccepted\t\n" +
+				"Puma\t\t1\tgenus\taccepted\t2\n",
+			kinds: []string{taxonomy.RankInversion},
+		},
+		"dangling synonym": {
+			input: "Felis concolor\t\t3\tspecies\tsynonym\t\n",
+			kinds: []string{taxonomy.DanglingSynonym},
+		},
+		"duplicated name": {
+			input: "Puma\t\t1\tgenus\taccepted\t\n" +
+				"Puma\t\t4\tgenus\taccepted\t\n",
+			kinds: []string{taxonomy.DuplicatedName, taxonomy.DuplicatedName},
+		},
+		"parent cycle": {
+			input: "Puma\t\t1\tgenus\taccepted\t2\n" +
+				"Felis\t\t2\tgenus\tsynonym\t1\n",
+			kinds: []string{taxonomy.ParentCycle, taxonomy.ParentCycle},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tx, err := taxonomy.Read(strings.NewReader(taxHeader + test.input))
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			var got []string
+			for _, p := range tx.Validate() {
+				got = append(got, p.Kind)
+			}
+			if !reflect.DeepEqual(got, test.kinds) {
+				t.Errorf("%s: got %q, want %q", name, got, test.kinds)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package tsv
+
+import "strings"
+
+// DictMaxValues is the default maximum number
+// of distinct values of a column in a Dict.
+const DictMaxValues = 4096
+
+// A Dict interns the values of the columns of a table,
+// so repeated values
+// (for example, country codes, or licenses)
+// share the same string.
+// It is used to reduce the memory used
+// by rows kept in memory.
+//
+// A column with more than MaxValues distinct values
+// (for example, an ID)
+// is not interned.
+//
+// A Dict is not safe for concurrent use.
+type Dict struct {
+	// MaxValues is the maximum number of distinct values
+	// of an interned column.
+	MaxValues int
+
+	cols []dictCol
+}
+
+type dictCol struct {
+	values map[string]string
+	full   bool
+}
+
+// NewDict returns a new Dict.
+func NewDict() *Dict {
+	return &Dict{MaxValues: DictMaxValues}
+}
+
+// Row interns the values of a row.
+// The returned row does not share memory
+// with the input row,
+// so the input row can be released.
+// It also returns the number of bytes saved
+// by the interned values.
+func (d *Dict) Row(row []string) ([]string, int) {
+	for len(d.cols) < len(row) {
+		d.cols = append(d.cols, dictCol{values: make(map[string]string)})
+	}
+
+	nr := make([]string, len(row))
+	saved := 0
+
+	// values of the columns that are not interned
+	// are copied in a single string.
+	var b strings.Builder
+	var pending []int
+	for i, f := range row {
+		c := &d.cols[i]
+		if c.full {
+			b.WriteString(f)
+			pending = append(pending, i)
+			continue
+		}
+		if v, ok := c.values[f]; ok {
+			nr[i] = v
+			saved += len(f)
+			continue
+		}
+		if len(c.values) >= d.MaxValues {
+			// too many values,
+			// the column will not be interned.
+			c.full = true
+			c.values = nil
+			b.WriteString(f)
+			pending = append(pending, i)
+			continue
+		}
+		v := strings.Clone(f)
+		c.values[v] = v
+		nr[i] = v
+	}
+
+	s := b.String()
+	pos := 0
+	for _, i := range pending {
+		nr[i] = s[pos : pos+len(row[i])]
+		pos += len(row[i])
+	}
+	return nr, saved
+}
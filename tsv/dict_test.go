@@ -0,0 +1,65 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+package tsv_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/js-arias/gbifer/tsv"
+)
+
+func TestDict(t *testing.T) {
+	rows := [][]string{
+		{"1", "AR", "CC_BY_4_0"},
+		{"2", "BR", "CC_BY_4_0"},
+		{"3", "AR", "CC0_1_0"},
+		{"4", "AR", "CC_BY_4_0"},
+	}
+
+	d := tsv.NewDict()
+	d.MaxValues = 3
+	var got [][]string
+	var saved []int
+	for _, r := range rows {
+		// a copy of the row that can be modified
+		in := strings.Split(strings.Join(r, "\t"), "\t")
+		row, n := d.Row(in)
+		for i := range in {
+			in[i] = ""
+		}
+		got = append(got, row)
+		saved = append(saved, n)
+	}
+	if !reflect.DeepEqual(got, rows) {
+		t.Errorf("got %q, want %q", got, rows)
+	}
+
+	// "AR" and "CC_BY_4_0" are reused,
+	// IDs are not interned
+	// after the fourth value.
+	want := []int{0, 9, 2, 11}
+	if !reflect.DeepEqual(saved, want) {
+		t.Errorf("saved: got %v, want %v", saved, want)
+	}
+	if unsafe.StringData(got[0][1]) != unsafe.StringData(got[3][1]) {
+		t.Errorf("value %q not interned", got[0][1])
+	}
+}
+
+func TestReadDict(t *testing.T) {
+	r := tsv.NewReader(strings.NewReader("a\tb\na\tc\n"))
+	r.Dict = tsv.NewDict()
+	got := readAll(t, r)
+	want := [][]string{{"a", "b"}, {"a", "c"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if unsafe.StringData(got[0][0]) != unsafe.StringData(got[1][0]) {
+		t.Errorf("value %q not interned", got[0][0])
+	}
+}
@@ -0,0 +1,20 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+//go:build !unix
+
+package tsv
+
+import (
+	"errors"
+	"os"
+)
+
+// mmap is not supported in this system,
+// so files are always read with a buffered reader.
+func mmap(f *os.File, size int) ([]byte, error) {
+	return nil, errors.New("mmap not supported")
+}
+
+func munmap(data []byte) {}
@@ -0,0 +1,23 @@
+// Copyright © 2023 J. Salvador Arias <jsalarias@gmail.com>
+// All rights reserved.
+// Distributed under BSD2 license that can be found in the LICENSE file.
+
+//go:build unix
+
+package tsv
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmap maps the content of a file
+// into memory.
+func mmap(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// munmap releases a memory mapped file.
+func munmap(data []byte) {
+	syscall.Munmap(data)
+}
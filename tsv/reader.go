@@ -16,6 +16,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"runtime"
+	"strings"
+	"unicode/utf8"
 )
 
 // Parsing errors.
@@ -30,22 +34,178 @@ type Reader struct {
 	// with standard library csv package.
 	Comma rune
 
+	// If Dict is defined,
+	// the values of the records are interned
+	// in the Dict.
+	Dict *Dict
+
 	fieldsPerRecord int
 
 	r     *bufio.Reader
 	line  int
 	col   int
 	field bytes.Buffer
+
+	// data is the content of a memory mapped file
+	// and pos is the position of the next line.
+	data []byte
+	pos  int
+
+	// src is the source of already decoded records
+	src Source
 }
 
 // NewReader returns a new Reader that reads from r.
+//
+// If r is a regular file,
+// and the system supports it,
+// the file will be memory mapped,
+// and read directly from memory
+// (starting at the current offset of the file).
+// The file must not be modified
+// while it is read.
+// The mapping is released
+// when the Reader is garbage collected.
 func NewReader(r io.Reader) *Reader {
+	if f, ok := r.(*os.File); ok {
+		if rd := newMappedReader(f); rd != nil {
+			return rd
+		}
+	}
 	return &Reader{
 		Comma: '\t',
 		r:     bufio.NewReader(r),
 	}
 }
 
+// newMappedReader returns a Reader
+// of a memory mapped file,
+// or nil if the file can not be mapped.
+func newMappedReader(f *os.File) *Reader {
+	st, err := f.Stat()
+	if err != nil || !st.Mode().IsRegular() || st.Size() == 0 {
+		return nil
+	}
+	if int64(int(st.Size())) != st.Size() {
+		return nil
+	}
+	off, err := f.Seek(0, io.SeekCurrent)
+	if err != nil || off >= st.Size() {
+		return nil
+	}
+
+	data, err := mmap(f, int(st.Size()))
+	if err != nil {
+		return nil
+	}
+	rd := &Reader{
+		Comma: '\t',
+		data:  data,
+		pos:   int(off),
+	}
+	runtime.SetFinalizer(rd, func(rd *Reader) {
+		munmap(rd.data)
+	})
+	return rd
+}
+
+// A Source is a source of records
+// already decoded,
+// for example,
+// the rows of a binary file.
+type Source interface {
+	// Header returns the header of the table.
+	Header() []string
+
+	// Read returns the next record,
+	// or io.EOF at the end of the table.
+	Read() ([]string, error)
+}
+
+// NewSourceReader returns a new Reader
+// that reads the records from a Source.
+// The first record will be the header of the table.
+func NewSourceReader(src Source) *Reader {
+	return &Reader{
+		Comma: '\t',
+		src:   src,
+	}
+}
+
+// readSource returns the next record
+// of a Source.
+// The first record is the header.
+func (r *Reader) readSource() ([]string, error) {
+	r.line++
+	if r.line == 1 {
+		h := r.src.Header()
+		return append([]string{}, h...), nil
+	}
+	row, err := r.src.Read()
+	if err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// appendLine appends a record
+// as an escaped line.
+func appendLine(dst []byte, row []string) []byte {
+	for i, f := range row {
+		if i > 0 {
+			dst = append(dst, '\t')
+		}
+		for j := 0; j < len(f); j++ {
+			switch f[j] {
+			case '\t':
+				dst = append(dst, '\\', 't')
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\\':
+				dst = append(dst, '\\', '\\')
+			default:
+				dst = append(dst, f[j])
+			}
+		}
+	}
+	return dst
+}
+
+// nextLine returns the next line
+// of a memory mapped file,
+// without the end of line.
+func (r *Reader) nextLine() ([]byte, bool) {
+	if r.pos >= len(r.data) {
+		return nil, false
+	}
+	ln := r.data[r.pos:]
+	if i := bytes.IndexByte(ln, '\n'); i >= 0 {
+		ln = ln[:i]
+		r.pos += i + 1
+	} else {
+		r.pos = len(r.data)
+	}
+	r.line++
+	return bytes.TrimSuffix(ln, []byte{'\r'}), true
+}
+
+// parseMapped returns the record
+// of a line of a memory mapped file.
+func parseMapped(ln []byte) []string {
+	if len(ln) == 0 {
+		return nil
+	}
+	if bytes.IndexByte(ln, '\\') >= 0 || !utf8.Valid(ln) {
+		// invalid bytes are replaced
+		// as in the buffered reader.
+		return ParseLine(ln)
+	}
+
+	// the fields are slices
+	// of a single copy of the line.
+	return strings.Split(string(ln), "\t")
+}
+
 // FieldPos returns the line corresponding
 // to the record most recently read by Read.
 //
@@ -71,6 +231,9 @@ func (r *Reader) Read() (record []string, err error) {
 			break
 		}
 	}
+	if r.Dict != nil {
+		record, _ = r.Dict.Row(record)
+	}
 	if r.fieldsPerRecord == 0 {
 		r.fieldsPerRecord = len(record)
 	}
@@ -80,7 +243,149 @@ func (r *Reader) Read() (record []string, err error) {
 	return record, nil
 }
 
+// FieldsPerRecord returns the number of fields
+// of the records,
+// as defined by the first record read by Read.
+func (r *Reader) FieldsPerRecord() int {
+	return r.fieldsPerRecord
+}
+
+// ReadLines reads up to n lines from r
+// without parsing them,
+// so they can be parsed with ParseLine,
+// for example,
+// in different goroutines.
+// It returns the lines,
+// and the line number of the first line.
+// Blank lines are included as empty lines,
+// so the line number of each line
+// can be recovered.
+// If there is no data left to be read,
+// ReadLines returns nil, 0, io.EOF.
+func (r *Reader) ReadLines(n int) (lines [][]byte, first int, err error) {
+	first = r.line + 1
+	if r.data != nil {
+		return r.readMappedLines(n, first)
+	}
+	if r.src != nil {
+		return r.readSourceLines(n, first)
+	}
+	for len(lines) < n {
+		ln, err := r.r.ReadBytes('\n')
+		if len(ln) > 0 {
+			r.line++
+			ln = bytes.TrimSuffix(ln, []byte{'\n'})
+			ln = bytes.TrimSuffix(ln, []byte{'\r'})
+			lines = append(lines, ln)
+		}
+		if errors.Is(err, io.EOF) {
+			if len(lines) == 0 {
+				return nil, 0, io.EOF
+			}
+			return lines, first, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return lines, first, nil
+}
+
+func (r *Reader) readMappedLines(n, first int) (lines [][]byte, _ int, err error) {
+	start := r.pos
+	for len(lines) < n {
+		ln, ok := r.nextLine()
+		if !ok {
+			break
+		}
+		lines = append(lines, ln)
+	}
+	if len(lines) == 0 {
+		return nil, 0, io.EOF
+	}
+
+	// the lines are copied
+	// so they do not depend on the mapping.
+	buf := make([]byte, r.pos-start)
+	copy(buf, r.data[start:r.pos])
+	for i, ln := range lines {
+		o := cap(r.data[start:]) - cap(ln)
+		lines[i] = buf[o : o+len(ln) : o+len(ln)]
+	}
+	runtime.KeepAlive(r)
+	return lines, first, nil
+}
+
+func (r *Reader) readSourceLines(n, first int) (lines [][]byte, _ int, err error) {
+	for len(lines) < n {
+		row, err := r.readSource()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		ln := appendLine(nil, row)
+		lines = append(lines, ln)
+	}
+	if len(lines) == 0 {
+		return nil, 0, io.EOF
+	}
+	return lines, first, nil
+}
+
+// ParseLine returns the record
+// of a line read with ReadLines.
+// If the line is empty,
+// it returns nil.
+func ParseLine(line []byte) []string {
+	if len(line) == 0 {
+		return nil
+	}
+
+	var fields []string
+	var field bytes.Buffer
+	for i := 0; i < len(line); {
+		r1, size := utf8.DecodeRune(line[i:])
+		i += size
+		if r1 == '\t' {
+			fields = append(fields, field.String())
+			field.Reset()
+			continue
+		}
+		if r1 == '\\' && i < len(line) {
+			switch line[i] {
+			case 't':
+				field.WriteRune('\t')
+				i++
+				continue
+			case 'n':
+				field.WriteRune('\n')
+				i++
+				continue
+			case '\\':
+				field.WriteRune('\\')
+				i++
+				continue
+			}
+		}
+		field.WriteRune(r1)
+	}
+	return append(fields, field.String())
+}
+
 func (r *Reader) parseRecord() (fields []string, err error) {
+	if r.src != nil {
+		return r.readSource()
+	}
+	if r.data != nil {
+		ln, ok := r.nextLine()
+		if !ok {
+			return nil, io.EOF
+		}
+		return parseMapped(ln), nil
+	}
+
 	r.line++
 	r.col = 0
 
@@ -123,9 +428,10 @@ func (r *Reader) parseField() (delim rune, err error) {
 		if r1 == '\\' {
 			r1, _, err = r.r.ReadRune()
 			if errors.Is(err, io.EOF) {
-				if r.col > 0 {
-					return '\n', nil
-				}
+				// a backslash at the end of the file
+				// is not an escape sequence.
+				r.field.WriteRune('\\')
+				return '\n', nil
 			}
 			if err != nil {
 				return 0, err
@@ -144,7 +450,10 @@ func (r *Reader) parseField() (delim rune, err error) {
 				r.col++
 				continue
 			default:
+				// not an escape sequence,
+				// keep the backslash.
 				r.r.UnreadRune()
+				r1 = '\\'
 			}
 		}
 		r.field.WriteRune(r1)
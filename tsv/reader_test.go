@@ -7,6 +7,8 @@ package tsv_test
 import (
 	"errors"
 	"io"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -113,3 +115,321 @@ func TestReadError(t *testing.T) {
 		})
 	}
 }
+
+func TestReadLines(t *testing.T) {
+	tests := map[string]struct {
+		input  string
+		output [][]string
+		lines  []int
+	}{
+		"simple": {
+			input:  "a\tb\tc\n",
+			output: [][]string{{"a", "b", "c"}},
+			lines:  []int{1},
+		},
+		"CrLn": {
+			input:  "a\tb\r\nc\td\r\n",
+			output: [][]string{{"a", "b"}, {"c", "d"}},
+			lines:  []int{1, 2},
+		},
+		"bare CR": {
+			input:  "a\tb\rc\td\r\n",
+			output: [][]string{{"a", "b\rc", "d"}},
+			lines:  []int{1},
+		},
+		"no EOL": {
+			input:  "a\tb\tc",
+			output: [][]string{{"a", "b", "c"}},
+			lines:  []int{1},
+		},
+		"blank line": {
+			input:  "a\tb\tc\n\nd\te\tf\n\n",
+			output: [][]string{{"a", "b", "c"}, {"d", "e", "f"}},
+			lines:  []int{1, 3},
+		},
+		"empty fields": {
+			input:  "\t\t\n",
+			output: [][]string{{"", "", ""}},
+			lines:  []int{1},
+		},
+		"escaped sequence": {
+			input:  `abc\tdef\\g\nh\x` + "\n",
+			output: [][]string{{"abc\tdef\\g\nh\\x"}},
+			lines:  []int{1},
+		},
+		"trailing backslash": {
+			input:  `abc\` + "\r\n",
+			output: [][]string{{`abc\`}},
+			lines:  []int{1},
+		},
+		"many blocks": {
+			input:  "a\n\nb\nc\nd\n",
+			output: [][]string{{"a"}, {"b"}, {"c"}, {"d"}},
+			lines:  []int{1, 3, 4, 5},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := tsv.NewReader(strings.NewReader(test.input))
+			var got [][]string
+			var lines []int
+			for {
+				ls, first, err := r.ReadLines(2)
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("%s: unexpected error: %q", name, err)
+				}
+				for i, ln := range ls {
+					row := tsv.ParseLine(ln)
+					if row == nil {
+						continue
+					}
+					got = append(got, row)
+					lines = append(lines, first+i)
+				}
+			}
+			if !reflect.DeepEqual(got, test.output) {
+				t.Errorf("%s: got %q, want %q", name, got, test.output)
+			}
+			if !reflect.DeepEqual(lines, test.lines) {
+				t.Errorf("%s: lines: got %v, want %v", name, lines, test.lines)
+			}
+
+			// compare with Read
+			r = tsv.NewReader(strings.NewReader(test.input))
+			var want [][]string
+			for {
+				row, err := r.Read()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				want = append(want, row)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: got %q, Read %q", name, got, want)
+			}
+		})
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	tests := map[string]string{
+		"simple":           "a\tb\tc\n",
+		"CrLn":             "a\tb\r\nc\td\r\n",
+		"bare CR":          "a\tb\rc\td\r\n",
+		"no EOL":           "a\tb\tc",
+		"blank line":       "a\tb\tc\n\nd\te\tf\n\n",
+		"empty fields":     "\t\t\n",
+		"escaped sequence": `abc\tdef\\g\nh\x` + "\n",
+		"many rows":        "a\tb\n\nc\td\ne\tf\ng\th\n",
+	}
+
+	dir := t.TempDir()
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(dir, name+".tab")
+			if err := os.WriteFile(file, []byte(input), 0644); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			want := readAll(t, tsv.NewReader(strings.NewReader(input)))
+
+			f, err := os.Open(file)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer f.Close()
+			got := readAll(t, tsv.NewReader(f))
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("read: got %q, want %q", got, want)
+			}
+
+			// read lines
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			r := tsv.NewReader(f)
+			got = nil
+			for {
+				ls, _, err := r.ReadLines(2)
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				for _, ln := range ls {
+					if row := tsv.ParseLine(ln); row != nil {
+						got = append(got, row)
+					}
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("read lines: got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func readAll(t testing.TB, r *tsv.Reader) [][]string {
+	t.Helper()
+	var rows [][]string
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return rows
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		rows = append(rows, row)
+	}
+}
+
+type rowSource struct {
+	header []string
+	rows   [][]string
+}
+
+func (s *rowSource) Header() []string { return s.header }
+
+func (s *rowSource) Read() ([]string, error) {
+	if len(s.rows) == 0 {
+		return nil, io.EOF
+	}
+	row := s.rows[0]
+	s.rows = s.rows[1:]
+	return row, nil
+}
+
+func TestSourceReader(t *testing.T) {
+	header := []string{"gbifID", "locality"}
+	rows := [][]string{
+		{"1", "Bariloche\tcentro"},
+		{"2", `C:\dir`},
+	}
+
+	r := tsv.NewSourceReader(&rowSource{header: header, rows: rows})
+	var got [][]string
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ln, _ := r.FieldPos(0); ln != len(got)+1 {
+			t.Errorf("line: got %d, want %d", ln, len(got)+1)
+		}
+		got = append(got, row)
+	}
+	want := append([][]string{header}, rows...)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("read: got %q, want %q", got, want)
+	}
+
+	// lines are escaped
+	// so they can be parsed with ParseLine
+	r = tsv.NewSourceReader(&rowSource{header: header, rows: rows})
+	got = nil
+	for {
+		ls, _, err := r.ReadLines(2)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, ln := range ls {
+			got = append(got, tsv.ParseLine(ln))
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("read lines: got %q, want %q", got, want)
+	}
+}
+
+// TestReadBackslash checks that a backslash
+// that does not start an escape sequence
+// is kept in the field
+// (it was previously dropped,
+// so "\x" was read as "x").
+func TestReadBackslash(t *testing.T) {
+	tests := map[string]struct {
+		input  string
+		output [][]string
+	}{
+		"unknown escape": {
+			input:  `a\xb` + "\tc\n",
+			output: [][]string{{`a\xb`, "c"}},
+		},
+		"escaped backslash": {
+			input:  `a\\x` + "\n",
+			output: [][]string{{`a\x`}},
+		},
+		"windows path": {
+			input:  `C:\data\occ.tab` + "\n",
+			output: [][]string{{`C:\data\occ.tab`}},
+		},
+		"before tab": {
+			input:  "a\\\tb\n",
+			output: [][]string{{`a\`, "b"}},
+		},
+		"end of line": {
+			input:  "a\\\r\nb\\\n",
+			output: [][]string{{`a\`}, {`b\`}},
+		},
+		"end of file": {
+			input:  `a\`,
+			output: [][]string{{`a\`}},
+		},
+	}
+
+	dir := t.TempDir()
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := readAll(t, tsv.NewReader(strings.NewReader(test.input)))
+			if !reflect.DeepEqual(got, test.output) {
+				t.Errorf("read: got %q, want %q", got, test.output)
+			}
+
+			// memory mapped file
+			file := filepath.Join(dir, name+".tab")
+			if err := os.WriteFile(file, []byte(test.input), 0644); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			f, err := os.Open(file)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer f.Close()
+			got = readAll(t, tsv.NewReader(f))
+			if !reflect.DeepEqual(got, test.output) {
+				t.Errorf("read file: got %q, want %q", got, test.output)
+			}
+
+			// parsed lines
+			r := tsv.NewReader(strings.NewReader(test.input))
+			got = nil
+			for {
+				ls, _, err := r.ReadLines(2)
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				for _, ln := range ls {
+					got = append(got, tsv.ParseLine(ln))
+				}
+			}
+			if !reflect.DeepEqual(got, test.output) {
+				t.Errorf("parse line: got %q, want %q", got, test.output)
+			}
+		})
+	}
+}
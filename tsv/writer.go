@@ -7,8 +7,14 @@ package tsv
 import (
 	"bufio"
 	"io"
+	"strings"
+	"unicode/utf8"
 )
 
+// writerBufferSize is the size of the buffer
+// of a Writer.
+const writerBufferSize = 64 << 10
+
 // A Writer writes records using TSV encoding.
 //
 // Tab is the field delimiter.
@@ -28,7 +34,7 @@ func NewWriter(w io.Writer) *Writer {
 		Comma:   '\t',
 		UseCRLF: true,
 
-		w: *bufio.NewWriter(w),
+		w: *bufio.NewWriterSize(w, writerBufferSize),
 	}
 }
 
@@ -58,34 +64,59 @@ func (w *Writer) Flush() {
 func (w *Writer) Write(record []string) error {
 	for i, field := range record {
 		if i > 0 {
-			if _, err := w.w.WriteRune('\t'); err != nil {
-				return err
-			}
-		}
-
-		for _, r := range field {
-			switch r {
-			case '\n':
-				if _, err := w.w.WriteString(`\n`); err != nil {
-					return err
-				}
-			case '\t':
-				if _, err := w.w.WriteString(`\t`); err != nil {
-					return err
-				}
-			case '\\':
-				if _, err := w.w.WriteString(`\\`); err != nil {
-					return err
-				}
-			default:
-				if _, err := w.w.WriteRune(r); err != nil {
-					return err
-				}
-			}
+			w.w.WriteByte('\t')
 		}
+		w.writeField(field)
 	}
+
+	// errors of the buffered writer are persistent,
+	// so any previous error will be returned.
 	if _, err := w.w.WriteString("\r\n"); err != nil {
 		return err
 	}
 	return nil
 }
+
+func (w *Writer) writeField(field string) {
+	if !utf8.ValidString(field) {
+		// invalid bytes are replaced
+		// by the unicode replacement character.
+		w.writeRunes(field)
+		return
+	}
+
+	// write the segments between escaped characters
+	// as a whole.
+	for len(field) > 0 {
+		i := strings.IndexAny(field, "\n\t\\")
+		if i < 0 {
+			w.w.WriteString(field)
+			return
+		}
+		w.w.WriteString(field[:i])
+		w.writeEscape(field[i])
+		field = field[i+1:]
+	}
+}
+
+func (w *Writer) writeRunes(field string) {
+	for _, r := range field {
+		switch r {
+		case '\n', '\t', '\\':
+			w.writeEscape(byte(r))
+		default:
+			w.w.WriteRune(r)
+		}
+	}
+}
+
+func (w *Writer) writeEscape(c byte) {
+	switch c {
+	case '\n':
+		w.w.WriteString(`\n`)
+	case '\t':
+		w.w.WriteString(`\t`)
+	case '\\':
+		w.w.WriteString(`\\`)
+	}
+}
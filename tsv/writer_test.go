@@ -40,6 +40,14 @@ func TestWrite(t *testing.T) {
 			input:  [][]string{{"", ""}},
 			output: "\t\r\n",
 		},
+		"escapes": {
+			input:  [][]string{{"a\\b\nc\t", `\`, "d"}},
+			output: `a\\b\nc\t` + "\t" + `\\` + "\td\r\n",
+		},
+		"unicode": {
+			input:  [][]string{{"Ñandú\tpiquí", "ß"}},
+			output: `Ñandú\tpiquí` + "\tß\r\n",
+		},
 	}
 
 	for name, test := range tests {
@@ -83,3 +91,17 @@ func TestWrite(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteInvalidUTF8(t *testing.T) {
+	var buf bytes.Buffer
+	w := tsv.NewWriter(&buf)
+	if err := w.Write([]string{"a\xffb\tc"}); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	w.Flush()
+
+	want := "a\uFFFDb" + `\t` + "c\r\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}